@@ -106,8 +106,8 @@ func main() {
 	// 4. 演示交易对解析功能
 	fmt.Println("\n4. 演示交易对解析功能...")
 
-	// 使用["*"]获取所有交易对
-	allSymbols, err := binanceExchange.ResolveTradingPairs(ctx, []string{"*"}, asset.Spot)
+	// 使用["*"]获取所有USDT交易对，并排除杠杆代币
+	allSymbols, err := binanceExchange.ResolveTradingPairs(ctx, []string{"*"}, asset.Spot, types.SymbolFilter{QuoteAssets: []string{"USDT"}, ExcludePatterns: []string{"*UPUSDT", "*DOWNUSDT"}})
 	if err != nil {
 		log.Printf("解析所有交易对失败: %v", err)
 		return
@@ -115,7 +115,7 @@ func main() {
 	fmt.Printf("使用['*']解析得到 %d 个交易对\n", len(allSymbols))
 
 	// 使用具体交易对列表
-	specificSymbols, err := binanceExchange.ResolveTradingPairs(ctx, []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}, asset.Spot)
+	specificSymbols, err := binanceExchange.ResolveTradingPairs(ctx, []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}, asset.Spot, types.SymbolFilter{})
 	if err != nil {
 		log.Printf("解析具体交易对失败: %v", err)
 		return
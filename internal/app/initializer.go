@@ -12,6 +12,8 @@ import (
 	"go.uber.org/zap"
 
 	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/exchanges/kraken"
+	"github.com/mooyang-code/data-miner/internal/metrics"
 	"github.com/mooyang-code/data-miner/internal/types"
 )
 
@@ -43,16 +45,43 @@ func (si *SystemInitializer) InitializeExchanges(ctx context.Context) (map[strin
 		si.logger.Info("Binance交易所初始化成功")
 
 		// 记录模式信息
-		if si.config.Exchanges.Binance.UseWebsocket {
+		switch si.config.Exchanges.Binance.CollectionMode() {
+		case types.CollectionModeWebsocket:
 			si.logger.Info("Binance配置为WebSocket模式")
-		} else {
+		case types.CollectionModeHybrid:
+			si.logger.Info("Binance配置为混合模式（WebSocket为主，不健康时改用定时API拉取）",
+				zap.Duration("fallback_after", si.config.Exchanges.Binance.FallbackThreshold()))
+		default:
 			si.logger.Info("Binance配置为定时API拉取模式")
 		}
 	}
 
+	// 初始化Kraken交易所（只支持现货公共行情REST接口，由调度任务定时拉取）
+	if si.config.Exchanges.Kraken.Enabled {
+		krakenExchange, err := si.initKraken()
+		if err != nil {
+			return nil, fmt.Errorf("初始化Kraken交易所失败: %w", err)
+		}
+		exchanges["kraken"] = krakenExchange
+		si.logger.Info("Kraken交易所初始化成功", zap.String("api_url", si.config.Exchanges.Kraken.APIURL))
+	}
+
 	return exchanges, nil
 }
 
+// initKraken 初始化Kraken交易所
+func (si *SystemInitializer) initKraken() (*kraken.Kraken, error) {
+	k, err := kraken.New()
+	if err != nil {
+		return nil, err
+	}
+	if err := k.Initialize(si.config.Exchanges.Kraken); err != nil {
+		k.Close()
+		return nil, fmt.Errorf("配置Kraken失败: %w", err)
+	}
+	return k, nil
+}
+
 // initBinance 初始化Binance交易所
 func (si *SystemInitializer) initBinance(ctx context.Context) (*binance.Binance, error) {
 	b := binance.New()
@@ -62,6 +91,18 @@ func (si *SystemInitializer) initBinance(ctx context.Context) (*binance.Binance,
 		return nil, fmt.Errorf("moox backend service配置Binance失败: %w", err)
 	}
 
+	// 注册Prometheus指标，通过监控服务的/metrics暴露
+	if err := metrics.Registry.Register(b.MetricsCollector()); err != nil {
+		si.logger.Warn("注册Binance指标失败", zap.Error(err))
+	}
+
+	// 启动服务器时间偏移监控（如果启用）
+	if si.config.Exchanges.Binance.TimeSync.Enabled {
+		if err := b.StartTimeSync(ctx); err != nil {
+			return nil, fmt.Errorf("启动服务器时间同步失败: %w", err)
+		}
+	}
+
 	// 启动交易对缓存（如果启用）
 	if si.config.Exchanges.Binance.TradablePairs.FetchFromAPI {
 		if err := si.startTradablePairsCache(ctx, b); err != nil {
@@ -85,7 +126,7 @@ func (si *SystemInitializer) startTradablePairsCache(ctx context.Context, b *bin
 		return fmt.Errorf("网络连接检查失败: %w", err)
 	}
 
-	// 使用带超时的上下文
+	// 使用带超时的上下文限制首次加载，自动更新循环不受该超时影响
 	cacheCtx, cancel := context.WithTimeout(ctx, 30*time.Second)
 	defer cancel()
 
@@ -141,16 +182,41 @@ type SystemComponents struct {
 // Shutdown 关闭系统组件
 func (sc *SystemComponents) Shutdown() error {
 	sc.Logger.Info("正在关闭系统组件...")
+	err := sc.CloseExchanges(context.Background())
+	sc.Logger.Info("系统关闭完成")
+	return err
+}
 
+// CloseExchanges 并发关闭所有交易所，某个交易所的Close阻塞时不影响其他交易所；
+// ctx结束时不再等待未完成的交易所，返回ctx.Err()
+func (sc *SystemComponents) CloseExchanges(ctx context.Context) error {
+	done := make(chan string, len(sc.Exchanges))
 	for name, exchange := range sc.Exchanges {
 		sc.Logger.Info("关闭交易所", zap.String("name", name))
-		if err := exchange.Close(); err != nil {
-			sc.Logger.Error("moox backend service关闭交易所失败",
-				zap.String("name", name), zap.Error(err))
-		}
+		go func() {
+			if err := exchange.Close(); err != nil {
+				sc.Logger.Error("moox backend service关闭交易所失败",
+					zap.String("name", name), zap.Error(err))
+			}
+			done <- name
+		}()
 	}
 
-	sc.Logger.Info("系统关闭完成")
+	pending := make(map[string]bool, len(sc.Exchanges))
+	for name := range sc.Exchanges {
+		pending[name] = true
+	}
+	for len(pending) > 0 {
+		select {
+		case name := <-done:
+			delete(pending, name)
+		case <-ctx.Done():
+			for name := range pending {
+				sc.Logger.Warn("关闭交易所超时", zap.String("name", name))
+			}
+			return ctx.Err()
+		}
+	}
 	return nil
 }
 
@@ -195,9 +261,46 @@ func (si *SystemInitializer) validateBinanceConfig() error {
 	if si.config.Exchanges.Binance.TradablePairs.FetchFromAPI {
 		si.validateTradablePairsConfig()
 	}
+
+	// 验证订单簿深度流配置
+	if si.config.Exchanges.Binance.DataTypes.Orderbook.Enabled {
+		si.validateOrderbookStreamConfig()
+	}
 	return nil
 }
 
+// validateOrderbookStreamConfig 提示订单簿深度流模式的取舍：
+// partial每条消息都是前N档的完整快照，带宽固定、断线后无需重新同步，但只覆盖前N档；
+// diff推送全部档位的变化并在本地维护完整订单簿，需要REST快照建立和重新同步（深度1000，每次权重50），
+// 带宽随交易活跃度变化，100ms推送时远高于partial
+func (si *SystemInitializer) validateOrderbookStreamConfig() {
+	orderbook := si.config.Exchanges.Binance.DataTypes.Orderbook
+	mode := orderbook.StreamMode()
+
+	if orderbook.Mode == "" {
+		si.logger.Warn("未配置订单簿深度流模式，按depth推断；partial只包含前N档完整快照，diff维护完整订单簿但带宽更高且依赖REST快照同步，建议显式配置mode",
+			zap.Int("depth", orderbook.Depth),
+			zap.String("mode", mode))
+	}
+
+	switch mode {
+	case types.OrderbookModePartial:
+		if orderbook.Depth > orderbook.StreamLevels() {
+			si.logger.Warn("partial模式的WebSocket订单簿只包含前N档，少于REST采集的深度",
+				zap.Int("levels", orderbook.StreamLevels()),
+				zap.Int("depth", orderbook.Depth))
+		}
+	case types.OrderbookModeDiff:
+		if orderbook.Levels != 0 {
+			si.logger.Warn("diff模式维护完整订单簿，levels配置不生效", zap.Int("levels", orderbook.Levels))
+		}
+		if orderbook.UpdateSpeed == "" || orderbook.UpdateSpeed == "100ms" {
+			si.logger.Warn("diff模式以100ms推送全部档位的变化，活跃交易对的带宽远高于partial模式；可将update_speed设为1000ms降低带宽",
+				zap.Int("symbols", len(orderbook.Symbols)))
+		}
+	}
+}
+
 // validateTradablePairsConfig 验证交易对配置
 func (si *SystemInitializer) validateTradablePairsConfig() {
 	if si.config.Exchanges.Binance.TradablePairs.UpdateInterval == 0 {
@@ -209,6 +312,21 @@ func (si *SystemInitializer) validateTradablePairsConfig() {
 	if len(si.config.Exchanges.Binance.TradablePairs.SupportedAssets) == 0 {
 		si.logger.Warn("未配置支持的资产类型，使用默认值[spot]")
 	}
+
+	// 按默认值计算实际生效的间隔，更新间隔大于缓存TTL时缓存会在自动更新前过期
+	tradablePairs := si.config.Exchanges.Binance.TradablePairs
+	updateInterval, cacheTTL := tradablePairs.UpdateInterval, tradablePairs.CacheTTL
+	if updateInterval == 0 {
+		updateInterval = time.Hour
+	}
+	if cacheTTL == 0 {
+		cacheTTL = 2 * time.Hour
+	}
+	if tradablePairs.AutoUpdate && updateInterval > cacheTTL {
+		si.logger.Warn("交易对更新间隔大于缓存TTL，缓存会在自动更新前过期并在查询时同步刷新，更新间隔将调整为TTL的一半",
+			zap.Duration("update_interval", updateInterval),
+			zap.Duration("cache_ttl", cacheTTL))
+	}
 }
 
 // GetSystemStatus 获取系统状态
@@ -226,6 +344,10 @@ func (sc *SystemComponents) GetSystemStatus() map[string]interface{} {
 		// 如果是Binance交易所，获取额外信息
 		if binanceExchange, ok := exchange.(*binance.Binance); ok {
 			exchangeInfo["tradable_pairs_stats"] = binanceExchange.GetTradablePairsStats()
+			exchangeInfo["rest_api"] = binanceExchange.RestAPI.GetStatus()
+			if sc.Config != nil && sc.Config.Exchanges.Binance.UsesWebsocket() {
+				exchangeInfo["websocket"] = binanceExchange.GetWsStreamStats()
+			}
 		}
 
 		exchangeStatus[name] = exchangeInfo
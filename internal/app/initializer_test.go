@@ -0,0 +1,28 @@
+package app
+
+import (
+	"context"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+func TestInitializeExchangesKraken(t *testing.T) {
+	config := &types.Config{}
+	config.Exchanges.Kraken = types.KrakenConfig{Enabled: true, APIURL: "http://127.0.0.1:0"}
+
+	exchanges, err := NewSystemInitializer(zap.NewNop(), config).InitializeExchanges(context.Background())
+	if err != nil {
+		t.Fatalf("初始化交易所失败: %v", err)
+	}
+	exchange, ok := exchanges["kraken"]
+	if !ok || exchange.GetName() != types.ExchangeKraken {
+		t.Fatalf("启用Kraken后应创建kraken交易所，实际: %v", exchanges)
+	}
+	if _, ok := exchanges["binance"]; ok {
+		t.Error("未启用的Binance不应被创建")
+	}
+	exchange.Close()
+}
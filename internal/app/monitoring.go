@@ -0,0 +1,263 @@
+package app
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/ipmanager"
+	"github.com/mooyang-code/data-miner/internal/metrics"
+	"github.com/mooyang-code/data-miner/internal/scheduler"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// monitoringHandler 监控服务的路由
+//
+//	GET /health                                             健康检查
+//	GET /ready                                              就绪检查，系统已初始化且各域名的IP管理器有足够的健康IP
+//	GET /status                                             系统、任务、频控、IP故障转移和本地订单簿同步状态
+//	GET /metrics                                            Prometheus格式的指标
+//	GET /jobs                                               任务状态（含最近的执行记录）
+//	POST /jobs/{name}/run                                   立即执行一次任务
+//	POST /jobs/{name}/pause                                 暂停任务
+//	POST /jobs/{name}/resume                                恢复暂停的任务
+//	GET /coverage?threshold=                                最近一次成功采集超过阈值的交易对，默认使用stale_threshold
+//	GET /api/ticker?exchange=&symbol=&max_age=              即时查询ticker，max_age内的缓存数据直接返回
+//	GET /api/kline?exchange=&symbol=&interval=&max_age=     即时查询最新K线
+func (sm *ServiceManager) monitoringHandler() http.Handler {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /health", sm.handleHealth)
+	mux.HandleFunc("GET /ready", sm.handleReady)
+	mux.HandleFunc("GET /status", sm.handleStatus)
+	mux.Handle("GET /metrics", metrics.Handler())
+	mux.HandleFunc("GET /jobs", sm.handleJobs)
+	mux.HandleFunc("POST /jobs/{name}/run", sm.handleRunJob)
+	mux.HandleFunc("POST /jobs/{name}/pause", sm.handlePauseJob)
+	mux.HandleFunc("POST /jobs/{name}/resume", sm.handleResumeJob)
+	mux.HandleFunc("GET /coverage", sm.handleCoverage)
+	mux.HandleFunc("GET /api/ticker", sm.handleTicker)
+	mux.HandleFunc("GET /api/kline", sm.handleKline)
+	return mux
+}
+
+// handleHealth 健康检查
+func (sm *ServiceManager) handleHealth(w http.ResponseWriter, r *http.Request) {
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"status":    "ok",
+		"timestamp": time.Now(),
+	})
+}
+
+// handleReady 就绪检查：系统组件已初始化，且所有共享IP管理器的健康IP数量达到配置的最小值
+func (sm *ServiceManager) handleReady(w http.ResponseWriter, r *http.Request) {
+	ipManagers := ipmanager.GetReadiness()
+	ready := sm.components != nil
+	for _, ok := range ipManagers {
+		ready = ready && ok
+	}
+
+	status := http.StatusOK
+	if !ready {
+		status = http.StatusServiceUnavailable
+	}
+	writeJSON(w, status, map[string]interface{}{
+		"ready":       ready,
+		"ip_managers": ipManagers,
+		"timestamp":   time.Now(),
+	})
+}
+
+// handleStatus 系统状态
+func (sm *ServiceManager) handleStatus(w http.ResponseWriter, r *http.Request) {
+	status := make(map[string]interface{})
+	if sm.components != nil {
+		status = sm.components.GetSystemStatus()
+		if b, err := sm.components.GetBinanceExchange(); err == nil {
+			// 增量深度流维护的本地订单簿的同步状态，resyncing的订单簿正在重新快照，不可信
+			status["orderbook_sync"] = b.GetOrderbookSyncStatus()
+		}
+	}
+	status["ip_failover"] = ipmanager.GetFailoverStats()
+	if sched := sm.getScheduler(); sched != nil {
+		status["jobs"] = sched.GetJobStatus()
+		status["rate_limit"] = sched.GetRateLimitStatus()
+		status["coverage"] = sched.GetCoverageStatus()
+		status["quarantined_symbols"] = sched.GetQuarantinedSymbols()
+	}
+	writeJSON(w, http.StatusOK, status)
+}
+
+// handleJobs 任务状态
+func (sm *ServiceManager) handleJobs(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	writeJSON(w, http.StatusOK, sched.GetJobStatus())
+}
+
+// handleRunJob 立即执行一次任务，任务在后台执行，通过GET /jobs查看结果
+func (sm *ServiceManager) handleRunJob(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	name := r.PathValue("name")
+	if err := sched.RunJobNow(name); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusAccepted, map[string]string{"job": name, "status": "triggered"})
+}
+
+// handlePauseJob 暂停任务，已暂停时直接返回成功
+func (sm *ServiceManager) handlePauseJob(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	name := r.PathValue("name")
+	if err := sched.PauseJob(name); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sched.GetJobStatus()[name])
+}
+
+// handleResumeJob 恢复暂停的任务，未暂停时直接返回成功
+func (sm *ServiceManager) handleResumeJob(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	name := r.PathValue("name")
+	if err := sched.ResumeJob(name); err != nil {
+		writeJobError(w, err)
+		return
+	}
+	writeJSON(w, http.StatusOK, sched.GetJobStatus()[name])
+}
+
+// handleCoverage 列出期望采集但长时间没有成功采集的交易对
+func (sm *ServiceManager) handleCoverage(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	threshold, err := parseMaxAge(r.URL.Query().Get("threshold"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+	stale := sched.StaleSymbols(threshold)
+	writeJSON(w, http.StatusOK, map[string]interface{}{
+		"stale_count": len(stale),
+		"stale":       stale,
+	})
+}
+
+// handleTicker 即时查询ticker
+func (sm *ServiceManager) handleTicker(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	query := r.URL.Query()
+	symbol := query.Get("symbol")
+	if symbol == "" {
+		writeError(w, http.StatusBadRequest, "symbol is required")
+		return
+	}
+	maxAge, err := parseMaxAge(query.Get("max_age"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	ticker, err := sched.GetTicker(r.Context(), exchangeParam(query.Get("exchange")), types.Symbol(symbol), maxAge)
+	if err != nil {
+		sm.logger.Warn("即时查询ticker失败", zap.String("symbol", symbol), zap.Error(err))
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, ticker)
+}
+
+// handleKline 即时查询最新K线
+func (sm *ServiceManager) handleKline(w http.ResponseWriter, r *http.Request) {
+	sched := sm.getScheduler()
+	if sched == nil {
+		writeError(w, http.StatusServiceUnavailable, "scheduler is not running")
+		return
+	}
+	query := r.URL.Query()
+	symbol, interval := query.Get("symbol"), query.Get("interval")
+	if symbol == "" || interval == "" {
+		writeError(w, http.StatusBadRequest, "symbol and interval are required")
+		return
+	}
+	maxAge, err := parseMaxAge(query.Get("max_age"))
+	if err != nil {
+		writeError(w, http.StatusBadRequest, err.Error())
+		return
+	}
+
+	kline, err := sched.GetLatestKline(r.Context(), exchangeParam(query.Get("exchange")), types.Symbol(symbol), interval, maxAge)
+	if err != nil {
+		sm.logger.Warn("即时查询K线失败", zap.String("symbol", symbol), zap.String("interval", interval), zap.Error(err))
+		writeError(w, http.StatusBadGateway, err.Error())
+		return
+	}
+	writeJSON(w, http.StatusOK, kline)
+}
+
+// exchangeParam 交易所参数，默认binance
+func exchangeParam(exchange string) string {
+	if exchange == "" {
+		return "binance"
+	}
+	return exchange
+}
+
+// parseMaxAge 解析时长参数（如5s、1m），为空时返回0
+func parseMaxAge(raw string) (time.Duration, error) {
+	if raw == "" {
+		return 0, nil
+	}
+	return time.ParseDuration(raw)
+}
+
+// writeJSON 以JSON格式写入响应
+func writeJSON(w http.ResponseWriter, status int, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	w.WriteHeader(status)
+	json.NewEncoder(w).Encode(v)
+}
+
+// writeJobError 根据任务操作的错误类型写入对应状态码的错误响应
+func writeJobError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, scheduler.ErrJobNotFound):
+		writeError(w, http.StatusNotFound, err.Error())
+	case errors.Is(err, scheduler.ErrJobRunning):
+		writeError(w, http.StatusConflict, err.Error())
+	case errors.Is(err, scheduler.ErrSchedulerStopped):
+		writeError(w, http.StatusServiceUnavailable, err.Error())
+	default:
+		writeError(w, http.StatusInternalServerError, err.Error())
+	}
+}
+
+// writeError 以JSON格式写入错误响应
+func writeError(w http.ResponseWriter, status int, message string) {
+	writeJSON(w, status, map[string]string{"error": message})
+}
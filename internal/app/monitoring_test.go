@@ -0,0 +1,42 @@
+package app
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+func TestStatusIncludesOrderbookSync(t *testing.T) {
+	components := &SystemComponents{
+		Exchanges: map[string]types.ExchangeInterface{"binance": binance.New()},
+		Logger:    zap.NewNop(),
+		Config:    &types.Config{},
+	}
+	sm := NewServiceManager(zap.NewNop(), components)
+
+	rec := httptest.NewRecorder()
+	sm.monitoringHandler().ServeHTTP(rec, httptest.NewRequest(http.MethodGet, "/status", nil))
+	if rec.Code != http.StatusOK {
+		t.Fatalf("/status返回%d", rec.Code)
+	}
+
+	var status map[string]interface{}
+	if err := json.Unmarshal(rec.Body.Bytes(), &status); err != nil {
+		t.Fatalf("解析/status失败: %v", err)
+	}
+	sync, ok := status["orderbook_sync"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("/status应包含orderbook_sync，实际: %v", status)
+	}
+	for _, key := range []string{"books", "in_sync", "resyncing", "desync_total"} {
+		if _, ok := sync[key]; !ok {
+			t.Errorf("orderbook_sync应包含%s，实际: %v", key, sync)
+		}
+	}
+}
@@ -0,0 +1,18 @@
+package app
+
+import (
+	"net/http"
+	"net/http/pprof"
+)
+
+// pprofHandler 返回net/http/pprof的性能分析接口（/debug/pprof/），用于排查协程和内存增长。
+// 显式注册到独立的mux，不使用http.DefaultServeMux
+func pprofHandler() *http.ServeMux {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/debug/pprof/", pprof.Index)
+	mux.HandleFunc("/debug/pprof/cmdline", pprof.Cmdline)
+	mux.HandleFunc("/debug/pprof/profile", pprof.Profile)
+	mux.HandleFunc("/debug/pprof/symbol", pprof.Symbol)
+	mux.HandleFunc("/debug/pprof/trace", pprof.Trace)
+	return mux
+}
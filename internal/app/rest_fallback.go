@@ -0,0 +1,151 @@
+package app
+
+import (
+	"slices"
+	"sync"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/scheduler"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// restFallbackCheckInterval hybrid模式下检查WebSocket健康状态的间隔
+const restFallbackCheckInterval = 5 * time.Second
+
+// wsHealthChecker 可报告WebSocket健康状态的交易所
+type wsHealthChecker interface {
+	IsWsHealthy() bool
+}
+
+// restFallback hybrid模式下的采集来源切换：WebSocket健康时暂停可由推送采集的Binance REST任务，
+// WebSocket持续不健康超过阈值后恢复这些任务改用REST拉取，WebSocket恢复健康后再次暂停，
+// 保证同一数据类型同一时间只有一种来源在采集。只恢复由自己暂停的任务，不影响手动暂停的任务
+type restFallback struct {
+	logger    *zap.Logger
+	threshold time.Duration
+	exchange  wsHealthChecker
+
+	mu             sync.Mutex
+	unhealthySince time.Time            // WebSocket开始不健康的时间，健康时为零值
+	restActive     bool                 // 当前是否由REST拉取采集
+	sched          *scheduler.Scheduler // 最近一次处理的调度器，调度器重建后暂停记录失效
+	held           map[string]bool      // 由切换逻辑暂停的任务
+	cancel         chan struct{}
+	wg             sync.WaitGroup
+}
+
+// newRestFallback 创建采集来源切换器，Binance交易所不支持健康检查时返回nil
+func newRestFallback(logger *zap.Logger, config types.BinanceConfig, exchange types.ExchangeInterface) *restFallback {
+	checker, ok := exchange.(wsHealthChecker)
+	if !ok {
+		return nil
+	}
+	return &restFallback{
+		logger:    logger,
+		threshold: config.FallbackThreshold(),
+		exchange:  checker,
+		held:      make(map[string]bool),
+	}
+}
+
+// setThreshold 修改切换到REST拉取的不健康时长阈值（重载配置时使用）
+func (f *restFallback) setThreshold(threshold time.Duration) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+	f.threshold = threshold
+}
+
+// start 启动后台检查协程，current返回当前运行的调度器
+func (f *restFallback) start(current func() *scheduler.Scheduler) {
+	f.cancel = make(chan struct{})
+	f.wg.Add(1)
+	go func() {
+		defer f.wg.Done()
+		ticker := time.NewTicker(restFallbackCheckInterval)
+		defer ticker.Stop()
+		for {
+			select {
+			case <-f.cancel:
+				return
+			case <-ticker.C:
+				if sched := current(); sched != nil {
+					f.apply(sched)
+				}
+			}
+		}
+	}()
+}
+
+// stop 停止后台检查协程并等待退出
+func (f *restFallback) stop() {
+	if f.cancel == nil {
+		return
+	}
+	close(f.cancel)
+	f.wg.Wait()
+	f.cancel = nil
+}
+
+// evaluate 按WebSocket健康状态更新当前采集来源，返回是否应由REST拉取采集（调用时需要持有锁）
+func (f *restFallback) evaluate(now time.Time) bool {
+	if f.exchange.IsWsHealthy() {
+		if f.restActive {
+			f.logger.Info("WebSocket已恢复健康，切回WebSocket采集",
+				zap.Duration("unhealthy_for", now.Sub(f.unhealthySince)))
+		}
+		f.unhealthySince = time.Time{}
+		f.restActive = false
+		return false
+	}
+
+	if f.unhealthySince.IsZero() {
+		f.unhealthySince = now
+	}
+	if !f.restActive && now.Sub(f.unhealthySince) >= f.threshold {
+		f.logger.Warn("WebSocket持续不健康，切换到REST拉取采集",
+			zap.Duration("unhealthy_for", now.Sub(f.unhealthySince)),
+			zap.Duration("threshold", f.threshold))
+		f.restActive = true
+	}
+	return f.restActive
+}
+
+// apply 按当前采集来源暂停或恢复调度器中可由推送采集的Binance任务
+func (f *restFallback) apply(sched *scheduler.Scheduler) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	if f.sched != sched {
+		// 新调度器的任务都未暂停，旧调度器的暂停记录不再适用
+		f.sched = sched
+		clear(f.held)
+	}
+	restActive := f.evaluate(time.Now())
+
+	for name, job := range sched.GetJobStatus() {
+		if !isStreamJob(job.Config) {
+			continue
+		}
+		switch {
+		case !restActive && !job.Paused:
+			if err := sched.PauseJob(name); err != nil {
+				f.logger.Error("暂停REST任务失败", zap.String("job", name), zap.Error(err))
+				continue
+			}
+			f.held[name] = true
+		case restActive && f.held[name]:
+			if err := sched.ResumeJob(name); err != nil {
+				f.logger.Error("恢复REST任务失败", zap.String("job", name), zap.Error(err))
+				continue
+			}
+			delete(f.held, name)
+		}
+	}
+}
+
+// isStreamJob 判断任务是否采集可由WebSocket推送的Binance数据
+func isStreamJob(job types.JobConfig) bool {
+	return job.Exchange == "binance" && slices.Contains(types.StreamDataTypes, types.DataType(job.DataType))
+}
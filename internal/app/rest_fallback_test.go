@@ -0,0 +1,200 @@
+package app
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/mock"
+	"github.com/mooyang-code/data-miner/internal/scheduler"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// fakeWsExchange 可控制WebSocket健康状态的模拟交易所
+type fakeWsExchange struct {
+	*mock.Exchange
+	healthy atomic.Bool
+}
+
+func (e *fakeWsExchange) IsWsHealthy() bool {
+	return e.healthy.Load()
+}
+
+func newFakeWsExchange(healthy bool) *fakeWsExchange {
+	exchange := &fakeWsExchange{Exchange: mock.New(types.ExchangeBinance)}
+	exchange.healthy.Store(healthy)
+	return exchange
+}
+
+// fallbackTestJobs ticker和trades可由推送采集，avg_price始终由REST拉取
+var fallbackTestJobs = []types.JobConfig{
+	{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker), Cron: "@every 1h"},
+	{Name: "trades", Exchange: "binance", DataType: string(types.DataTypeTrades), Cron: "@every 1h"},
+	{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines), Cron: "@every 1h"},
+	{Name: "avg_price", Exchange: "binance", DataType: string(types.DataTypeAvgPrice), Cron: "@every 1h"},
+}
+
+func newFallbackTestScheduler(t *testing.T, exchange types.ExchangeInterface) *scheduler.Scheduler {
+	t.Helper()
+	sched := scheduler.New(zap.NewNop(), map[string]types.ExchangeInterface{"binance": exchange},
+		func(types.MarketData) error { return nil }, &types.Config{})
+	for _, job := range fallbackTestJobs {
+		if err := sched.AddJob(job); err != nil {
+			t.Fatalf("添加任务%s失败: %v", job.Name, err)
+		}
+	}
+	return sched
+}
+
+// pausedJobs 返回调度器中已暂停的任务
+func pausedJobs(sched *scheduler.Scheduler) map[string]bool {
+	paused := make(map[string]bool)
+	for name, job := range sched.GetJobStatus() {
+		if job.Paused {
+			paused[name] = true
+		}
+	}
+	return paused
+}
+
+func TestRestFallbackEvaluate(t *testing.T) {
+	exchange := newFakeWsExchange(true)
+	f := &restFallback{logger: zap.NewNop(), threshold: 10 * time.Second, exchange: exchange, held: make(map[string]bool)}
+	start := time.Now()
+
+	steps := []struct {
+		name     string
+		healthy  bool
+		elapsed  time.Duration
+		wantRest bool
+	}{
+		{"健康", true, 0, false},
+		{"开始不健康", false, time.Second, false},
+		{"未达到阈值", false, 11*time.Second - time.Millisecond, false},
+		{"恰好达到阈值", false, 11 * time.Second, true},
+		{"持续不健康", false, 30 * time.Second, true},
+		{"恢复健康", true, 31 * time.Second, false},
+		{"再次不健康重新计时", false, 32 * time.Second, false},
+		{"再次不健康未达到阈值", false, 41 * time.Second, false},
+		{"再次达到阈值", false, 42 * time.Second, true},
+	}
+	for _, step := range steps {
+		exchange.healthy.Store(step.healthy)
+		if got := f.evaluate(start.Add(step.elapsed)); got != step.wantRest {
+			t.Errorf("%s: evaluate() = %v, want %v", step.name, got, step.wantRest)
+		}
+	}
+}
+
+func TestRestFallbackApply(t *testing.T) {
+	exchange := newFakeWsExchange(true)
+	sched := newFallbackTestScheduler(t, exchange)
+	f := &restFallback{logger: zap.NewNop(), exchange: exchange, held: make(map[string]bool)}
+
+	// 手动暂停的任务不由切换逻辑管理
+	if err := sched.PauseJob("klines"); err != nil {
+		t.Fatalf("暂停任务失败: %v", err)
+	}
+
+	steps := []struct {
+		name       string
+		healthy    bool
+		wantPaused []string
+		wantHeld   []string
+	}{
+		{"WebSocket健康时暂停推送覆盖的任务", true, []string{"ticker", "trades", "klines"}, []string{"ticker", "trades"}},
+		{"不健康时只恢复由切换逻辑暂停的任务", false, []string{"klines"}, nil},
+		{"恢复健康后再次暂停", true, []string{"ticker", "trades", "klines"}, []string{"ticker", "trades"}},
+	}
+	for _, step := range steps {
+		exchange.healthy.Store(step.healthy)
+		f.apply(sched)
+
+		paused := pausedJobs(sched)
+		if len(paused) != len(step.wantPaused) {
+			t.Errorf("%s: 暂停的任务为%v，期望%v", step.name, paused, step.wantPaused)
+		}
+		for _, name := range step.wantPaused {
+			if !paused[name] {
+				t.Errorf("%s: 任务%s应处于暂停状态", step.name, name)
+			}
+		}
+		if len(f.held) != len(step.wantHeld) {
+			t.Errorf("%s: held为%v，期望%v", step.name, f.held, step.wantHeld)
+		}
+		for _, name := range step.wantHeld {
+			if !f.held[name] {
+				t.Errorf("%s: 任务%s应记录在held中", step.name, name)
+			}
+		}
+	}
+}
+
+func TestRestFallbackSchedulerSwap(t *testing.T) {
+	exchange := newFakeWsExchange(true)
+	f := &restFallback{logger: zap.NewNop(), exchange: exchange, held: make(map[string]bool)}
+
+	old := newFallbackTestScheduler(t, exchange)
+	f.apply(old)
+
+	// 重建调度器后旧的暂停记录失效，按新调度器重新暂停
+	reloaded := newFallbackTestScheduler(t, exchange)
+	if err := reloaded.PauseJob("ticker"); err != nil {
+		t.Fatalf("暂停任务失败: %v", err)
+	}
+	f.apply(reloaded)
+	if f.sched != reloaded || len(f.held) != 2 || f.held["ticker"] {
+		t.Errorf("切换调度器后held应只包含新调度器中由切换逻辑暂停的任务，实际: %v", f.held)
+	}
+
+	// 不健康时只恢复新调度器中由切换逻辑暂停的任务
+	exchange.healthy.Store(false)
+	f.apply(reloaded)
+	if paused := pausedJobs(reloaded); len(paused) != 1 || !paused["ticker"] {
+		t.Errorf("新调度器中只有手动暂停的ticker应保持暂停，实际: %v", paused)
+	}
+	if paused := pausedJobs(old); len(paused) != 3 {
+		t.Errorf("旧调度器不应被修改，实际暂停: %v", paused)
+	}
+}
+
+func TestReloadRebuildsRestFallbackOnModeChange(t *testing.T) {
+	exchange := newFakeWsExchange(true)
+	config := &types.Config{}
+	config.Scheduler.Enabled = true
+	config.Scheduler.Jobs = fallbackTestJobs
+	config.Exchanges.Binance.Mode = types.CollectionModeHybrid
+
+	sm := NewSchedulerManager(zap.NewNop())
+	sched, err := sm.Setup(config, map[string]types.ExchangeInterface{"binance": exchange})
+	if err != nil {
+		t.Fatalf("设置调度器失败: %v", err)
+	}
+	defer sm.Stop(context.Background())
+	if sm.fallback == nil || len(pausedJobs(sched)) != 3 {
+		t.Fatalf("hybrid模式下应创建切换器并暂停推送覆盖的任务，实际暂停: %v", pausedJobs(sched))
+	}
+
+	// 切换到rest模式后停止切换器，新调度器的任务不被暂停
+	restConfig := *config
+	restConfig.Exchanges.Binance.Mode = types.CollectionModeREST
+	sched, err = sm.Reload(context.Background(), &restConfig)
+	if err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+	if sm.fallback != nil || len(pausedJobs(sched)) != 0 {
+		t.Errorf("rest模式下不应保留切换器或暂停任务，实际暂停: %v", pausedJobs(sched))
+	}
+
+	// 切换回hybrid模式后重新创建切换器
+	sched, err = sm.Reload(context.Background(), config)
+	if err != nil {
+		t.Fatalf("重载失败: %v", err)
+	}
+	if sm.fallback == nil || len(pausedJobs(sched)) != 3 {
+		t.Errorf("切回hybrid模式后应重新创建切换器并暂停任务，实际暂停: %v", pausedJobs(sched))
+	}
+}
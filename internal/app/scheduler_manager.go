@@ -1,17 +1,27 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
 
 	"go.uber.org/zap"
 
 	"github.com/mooyang-code/data-miner/internal/scheduler"
+	"github.com/mooyang-code/data-miner/internal/storage"
 	"github.com/mooyang-code/data-miner/internal/types"
 )
 
-// SchedulerManager 调度器管理器
+// SchedulerManager 调度器管理器，持有当前运行的调度器，支持停止和按新配置重建
 type SchedulerManager struct {
 	logger *zap.Logger
+	sink   storage.Sink // 数据存储输出，为nil时只打印数据
+
+	mu        sync.Mutex
+	sched     *scheduler.Scheduler               // 当前运行的调度器，未启用时为nil
+	exchanges map[string]types.ExchangeInterface // Setup时传入的交易所，重建调度器时复用
+	fallback  *restFallback                      // hybrid模式下的采集来源切换，其他模式为nil
 }
 
 // NewSchedulerManager 创建新的调度器管理器
@@ -21,55 +31,191 @@ func NewSchedulerManager(logger *zap.Logger) *SchedulerManager {
 	}
 }
 
-// Setup 设置调度器
+// SetSink 设置采集数据的存储输出，需要在Setup之前调用
+func (sm *SchedulerManager) SetSink(sink storage.Sink) {
+	sm.sink = sink
+}
+
+// Scheduler 返回当前运行的调度器，未启用时返回nil
+func (sm *SchedulerManager) Scheduler() *scheduler.Scheduler {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	return sm.sched
+}
+
+// Setup 设置并启动调度器，添加失败的任务只记录日志
 func (sm *SchedulerManager) Setup(config *types.Config, exchanges map[string]types.ExchangeInterface) (*scheduler.Scheduler, error) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.sched != nil {
+		return nil, fmt.Errorf("scheduler already set up")
+	}
+	sm.exchanges = exchanges
+
+	sched, _ := sm.buildScheduler(config)
+	if sched == nil {
+		return nil, nil
+	}
+	if config.Exchanges.Binance.CollectionMode() == types.CollectionModeHybrid {
+		sm.fallback = newRestFallback(sm.logger, config.Exchanges.Binance, exchanges["binance"])
+	}
+	if sm.fallback != nil {
+		// 启动前按当前WebSocket状态暂停推送已覆盖的任务，避免首次调度时两种来源重复采集
+		sm.fallback.apply(sched)
+	}
+
+	// 启动调度器
+	sm.logger.Info("启动调度器...")
+	if err := sched.Start(); err != nil {
+		sm.logger.Error("启动调度器失败", zap.Error(err))
+		return nil, err
+	}
+	sm.logger.Info("调度器启动成功")
+	sm.sched = sched
+	if sm.fallback != nil {
+		sm.fallback.start(sm.schedulerFor(sm.fallback))
+	}
+	return sched, nil
+}
+
+// Reload 按新配置重建调度器：先创建新调度器并添加全部任务，任一任务添加失败时放弃重载，旧调度器继续运行；
+// 全部成功后停止旧调度器（等待执行中的任务完成，直到ctx结束），再启动新调度器。
+// 采集模式在hybrid与其他模式之间切换时创建或停止采集来源切换器。
+// 新调度器的最新值缓存、成交去重和K线游标等运行状态从空开始
+func (sm *SchedulerManager) Reload(ctx context.Context, config *types.Config) (*scheduler.Scheduler, error) {
+	sm.mu.Lock()
+	sched, stale, err := sm.reload(ctx, config)
+	sm.mu.Unlock()
+
+	// 检查协程会获取锁读取当前调度器，需要在释放锁之后停止
+	if stale != nil {
+		stale.stop()
+	}
+	return sched, err
+}
+
+// reload 执行重载，返回不再需要的采集来源切换器（调用时需要持有锁）
+func (sm *SchedulerManager) reload(ctx context.Context, config *types.Config) (*scheduler.Scheduler, *restFallback, error) {
+	sched, jobErrs := sm.buildScheduler(config)
+	if len(jobErrs) > 0 {
+		return sm.sched, nil, fmt.Errorf("reload aborted, old scheduler kept running: %w", errors.Join(jobErrs...))
+	}
+
+	var stale, created *restFallback
+	hybrid := config.Exchanges.Binance.CollectionMode() == types.CollectionModeHybrid
+	switch {
+	case hybrid && sm.fallback == nil:
+		created = newRestFallback(sm.logger, config.Exchanges.Binance, sm.exchanges["binance"])
+		sm.fallback = created
+	case !hybrid && sm.fallback != nil:
+		// 切换出hybrid模式后旧的切换器不再暂停任务，由调用方在释放锁后停止
+		stale, sm.fallback = sm.fallback, nil
+	}
+	if sm.fallback != nil && sched != nil {
+		sm.fallback.setThreshold(config.Exchanges.Binance.FallbackThreshold())
+		sm.fallback.apply(sched)
+	}
+
+	if sm.sched != nil {
+		if err := sm.sched.Stop(ctx); err != nil {
+			// 旧调度器已停止调度新任务，执行中的任务超时未完成，继续切换以免长时间停止采集
+			sm.logger.Warn("等待旧调度器的任务完成超时", zap.Error(err))
+		}
+		sm.sched = nil
+	}
+
+	if sched != nil {
+		if err := sched.Start(); err != nil {
+			return nil, stale, fmt.Errorf("failed to start reloaded scheduler: %w", err)
+		}
+	}
+	sm.sched = sched
+	if created != nil {
+		created.start(sm.schedulerFor(created))
+	}
+	sm.logger.Info("调度器已按新配置重载", zap.Bool("running", sched != nil), zap.Bool("rest_fallback", sm.fallback != nil))
+	return sched, stale, nil
+}
+
+// schedulerFor 返回供采集来源切换器读取当前调度器的函数；f已被重载或停止替换时返回nil，避免旧的切换器继续暂停任务
+func (sm *SchedulerManager) schedulerFor(f *restFallback) func() *scheduler.Scheduler {
+	return func() *scheduler.Scheduler {
+		sm.mu.Lock()
+		defer sm.mu.Unlock()
+		if sm.fallback != f {
+			return nil
+		}
+		return sm.sched
+	}
+}
+
+// Stop 停止当前调度器，等待执行中的任务完成直到ctx结束
+func (sm *SchedulerManager) Stop(ctx context.Context) error {
+	sm.mu.Lock()
+	fallback := sm.fallback
+	sm.fallback = nil
+	sm.mu.Unlock()
+	// 检查协程会获取锁读取当前调度器，需要在持有锁之前停止
+	if fallback != nil {
+		fallback.stop()
+	}
+
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.sched == nil {
+		return nil
+	}
+	err := sm.sched.Stop(ctx)
+	sm.sched = nil
+	return err
+}
+
+// buildScheduler 按配置创建调度器并添加任务（不启动），调度器未启用或websocket采集模式下返回nil。
+// 返回添加失败的任务错误（调用时需要持有锁）
+func (sm *SchedulerManager) buildScheduler(config *types.Config) (*scheduler.Scheduler, []error) {
 	sm.logger.Info("开始设置调度器...",
 		zap.Bool("scheduler_enabled", config.Scheduler.Enabled),
-		zap.Bool("use_websocket", config.Exchanges.Binance.UseWebsocket))
+		zap.String("mode", config.Exchanges.Binance.CollectionMode()))
+
+	// 初始化调度器（websocket采集模式下不启动，hybrid模式下REST任务作为备用来源）
+	mode := config.Exchanges.Binance.CollectionMode()
+	if !config.Scheduler.Enabled || mode == types.CollectionModeWebsocket {
+		if mode == types.CollectionModeWebsocket {
+			sm.logger.Info("WebSocket模式下跳过调度器启动")
+		} else {
+			sm.logger.Info("调度器未启用或条件不满足",
+				zap.Bool("scheduler_enabled", config.Scheduler.Enabled),
+				zap.String("mode", mode))
+		}
+		return nil, nil
+	}
 
 	// 创建数据处理回调函数
 	dataCallback := sm.createDataCallback(config)
 
-	// 初始化调度器（仅在非websocket模式下启动）
-	var sched *scheduler.Scheduler
-	if config.Scheduler.Enabled && !config.Exchanges.Binance.UseWebsocket {
-		sm.logger.Info("创建调度器实例...")
-		sched = scheduler.New(sm.logger, exchanges, dataCallback, config)
-
-		// 添加任务
-		sm.logger.Info("开始添加任务...", zap.Int("job_count", len(config.Scheduler.Jobs)))
-		for _, job := range config.Scheduler.Jobs {
-			sm.logger.Info("正在添加任务",
-				zap.String("job_name", job.Name),
-				zap.String("exchange", job.Exchange),
-				zap.String("data_type", job.DataType),
-				zap.String("cron", job.Cron))
-
-			if err := sched.AddJob(job); err != nil {
-				sm.logger.Error("添加任务失败",
-					zap.String("job", job.Name),
-					zap.Error(err))
-			} else {
-				sm.logger.Info("添加任务成功", zap.String("job", job.Name))
-			}
-		}
+	sm.logger.Info("创建调度器实例...")
+	sched := scheduler.New(sm.logger, sm.exchanges, dataCallback, config)
 
-		// 启动调度器
-		sm.logger.Info("启动调度器...")
-		if err := sched.Start(); err != nil {
-			sm.logger.Fatal("启动调度器失败", zap.Error(err))
-			return nil, err
+	// 添加任务
+	sm.logger.Info("开始添加任务...", zap.Int("job_count", len(config.Scheduler.Jobs)))
+	var jobErrs []error
+	for _, job := range config.Scheduler.Jobs {
+		sm.logger.Info("正在添加任务",
+			zap.String("job_name", job.Name),
+			zap.String("exchange", job.Exchange),
+			zap.String("data_type", job.DataType),
+			zap.String("cron", job.Cron))
+
+		if err := sched.AddJob(job); err != nil {
+			sm.logger.Error("添加任务失败",
+				zap.String("job", job.Name),
+				zap.Error(err))
+			jobErrs = append(jobErrs, fmt.Errorf("job %s: %w", job.Name, err))
+		} else {
+			sm.logger.Info("添加任务成功", zap.String("job", job.Name))
 		}
-		sm.logger.Info("调度器启动成功")
-	} else if config.Exchanges.Binance.UseWebsocket {
-		sm.logger.Info("WebSocket模式下跳过调度器启动")
-	} else {
-		sm.logger.Info("调度器未启用或条件不满足",
-			zap.Bool("scheduler_enabled", config.Scheduler.Enabled),
-			zap.Bool("use_websocket", config.Exchanges.Binance.UseWebsocket))
 	}
-
-	return sched, nil
+	return sched, jobErrs
 }
 
 // createDataCallback 创建数据处理回调函数
@@ -81,18 +227,14 @@ func (sm *SchedulerManager) createDataCallback(config *types.Config) func(types.
 			zap.String("type", string(data.GetDataType())),
 			zap.Time("timestamp", data.GetTimestamp()))
 
-		// 这里可以添加数据存储逻辑
-		return sm.saveData(data, config.Storage)
+		return sm.saveData(data)
 	}
 }
 
-// saveData 保存数据
-func (sm *SchedulerManager) saveData(data types.MarketData, storageConfig types.StorageConfig) error {
-	// 这里可以实现具体的数据存储逻辑
-	// 例如保存到文件、数据库等
-	if storageConfig.File.Enabled {
-		// 简单的文件存储实现
-		// TODO: 实现具体的文件存储逻辑
+// saveData 保存数据到存储输出，未启用存储时只打印数据
+func (sm *SchedulerManager) saveData(data types.MarketData) error {
+	if sm.sink != nil {
+		return sm.sink.Write(data)
 	}
 	fmt.Printf("###data:%+v\n", data)
 	return nil
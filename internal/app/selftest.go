@@ -0,0 +1,442 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// 自检的默认参数
+const (
+	DefaultSelfTestTimeout = 10 * time.Second // 每项检查的默认超时时间
+
+	selfTestDefaultSymbol   = "BTCUSDT" // 配置中没有具体交易对（如["*"]）时使用的交易对
+	selfTestDefaultInterval = "1m"      // 配置中没有K线周期时使用的周期
+	selfTestDefaultDepth    = 5         // 配置中没有订单簿深度时使用的深度
+)
+
+// 自检方式
+const (
+	SelfTestModeREST      = "rest"      // REST请求
+	SelfTestModeWebsocket = "websocket" // WebSocket订阅，收到第一条数据视为通过
+	SelfTestModeSigned    = "signed"    // 签名REST请求，校验API Key和Secret
+)
+
+// selfTestDataTypeAccount 签名检查在报告中显示的数据类型
+const selfTestDataTypeAccount types.DataType = "account"
+
+// SelfTestResult 单项自检结果
+type SelfTestResult struct {
+	Exchange string         // 交易所名称
+	DataType types.DataType // 数据类型
+	Mode     string         // 自检方式：rest或websocket
+	Symbol   types.Symbol   // 使用的交易对
+	Latency  time.Duration  // 请求耗时，WebSocket为订阅到收到第一条数据的时间
+	Err      error          // 失败原因，通过时为nil
+}
+
+// Passed 返回该项检查是否通过
+func (r SelfTestResult) Passed() bool {
+	return r.Err == nil
+}
+
+// selfTestCase 一个数据类型的检查参数
+type selfTestCase struct {
+	dataType types.DataType
+	symbol   types.Symbol
+	interval string // K线周期
+	depth    int    // 订单簿深度
+}
+
+// SelfTester 启动自检：对每个启用的交易所和数据类型发起一次真实请求（WebSocket模式下再订阅一次），
+// 在依赖调度器之前一次性验证密钥、网络、交易对和WebSocket连通性
+type SelfTester struct {
+	logger    *zap.Logger
+	config    *types.Config
+	exchanges map[string]types.ExchangeInterface
+	timeout   time.Duration // 每项检查的超时时间
+}
+
+// NewSelfTester 创建自检器，timeout<=0时使用DefaultSelfTestTimeout
+func NewSelfTester(logger *zap.Logger, config *types.Config, exchanges map[string]types.ExchangeInterface, timeout time.Duration) *SelfTester {
+	if timeout <= 0 {
+		timeout = DefaultSelfTestTimeout
+	}
+	return &SelfTester{
+		logger:    logger,
+		config:    config,
+		exchanges: exchanges,
+		timeout:   timeout,
+	}
+}
+
+// Run 按交易所名称顺序依次执行全部检查并返回结果，单项失败不影响其他检查
+func (st *SelfTester) Run(ctx context.Context) []SelfTestResult {
+	names := make([]string, 0, len(st.exchanges))
+	for name := range st.exchanges {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var results []SelfTestResult
+	for _, name := range names {
+		exchange := st.exchanges[name]
+
+		// 配置了API Key时发起一次签名请求，只有行情检查时密钥错误要到下单或用户数据流时才会暴露
+		if b, ok := exchange.(*binance.Binance); ok && name == "binance" && st.config.Exchanges.Binance.APIKey != "" {
+			result := st.runSigned(ctx, name, b)
+			st.logResult(result)
+			results = append(results, result)
+		}
+
+		cases := st.casesFor(name)
+		if len(cases) == 0 {
+			st.logger.Warn("交易所没有启用的数据类型，跳过自检", zap.String("exchange", name))
+			continue
+		}
+
+		for _, c := range cases {
+			result := st.runREST(ctx, name, exchange, c)
+			st.logResult(result)
+			results = append(results, result)
+		}
+
+		if b, ok := exchange.(*binance.Binance); ok && name == "binance" && st.config.Exchanges.Binance.UsesWebsocket() {
+			for _, result := range st.runWebsocket(ctx, name, b, cases) {
+				st.logResult(result)
+				results = append(results, result)
+			}
+		}
+	}
+	return results
+}
+
+// casesFor 获取交易所需要检查的数据类型：Binance取data_types中启用的类型，
+// 其他交易所取调度任务中配置的类型
+func (st *SelfTester) casesFor(name string) []selfTestCase {
+	if name == "binance" {
+		return binanceSelfTestCases(st.config.Exchanges.Binance.DataTypes)
+	}
+
+	seen := make(map[types.DataType]bool)
+	var cases []selfTestCase
+	for _, job := range st.config.Scheduler.Jobs {
+		dataType := types.DataType(job.DataType)
+		if job.Exchange != name || seen[dataType] {
+			continue
+		}
+		seen[dataType] = true
+		cases = append(cases, selfTestCase{
+			dataType: dataType,
+			symbol:   selfTestDefaultSymbol,
+			interval: selfTestDefaultInterval,
+			depth:    selfTestDefaultDepth,
+		})
+	}
+	return cases
+}
+
+// binanceSelfTestCases 按Binance数据类型配置生成检查参数，使用每个类型配置的第一个具体交易对
+func binanceSelfTestCases(dataTypes types.BinanceDataTypes) []selfTestCase {
+	var cases []selfTestCase
+	add := func(enabled bool, dataType types.DataType, symbols []string) {
+		if enabled {
+			cases = append(cases, selfTestCase{
+				dataType: dataType,
+				symbol:   selfTestSymbol(symbols),
+				interval: selfTestDefaultInterval,
+				depth:    selfTestDefaultDepth,
+			})
+		}
+	}
+	add(dataTypes.Ticker.Enabled, types.DataTypeTicker, dataTypes.Ticker.Symbols)
+	add(dataTypes.Orderbook.Enabled, types.DataTypeOrderbook, dataTypes.Orderbook.Symbols)
+	add(dataTypes.Trades.Enabled, types.DataTypeTrades, dataTypes.Trades.Symbols)
+	add(dataTypes.Klines.Enabled, types.DataTypeKlines, dataTypes.Klines.Symbols)
+	add(dataTypes.AvgPrice.Enabled, types.DataTypeAvgPrice, dataTypes.AvgPrice.Symbols)
+	add(dataTypes.Stats24h.Enabled, types.DataTypeStats24h, dataTypes.Stats24h.Symbols)
+
+	for i := range cases {
+		switch cases[i].dataType {
+		case types.DataTypeOrderbook:
+			if depth := dataTypes.Orderbook.Depth; depth > 0 {
+				cases[i].depth = depth
+			}
+		case types.DataTypeKlines:
+			if len(dataTypes.Klines.Intervals) > 0 {
+				cases[i].interval = dataTypes.Klines.Intervals[0]
+			}
+		}
+	}
+	return cases
+}
+
+// selfTestSymbol 返回配置中的第一个具体交易对，只有通配符时返回默认交易对
+func selfTestSymbol(symbols []string) types.Symbol {
+	for _, symbol := range symbols {
+		if symbol != "" && symbol != "*" {
+			return types.Symbol(symbol)
+		}
+	}
+	return selfTestDefaultSymbol
+}
+
+// runREST 使用交易所的REST方法获取一次数据
+func (st *SelfTester) runREST(ctx context.Context, name string, exchange types.ExchangeInterface, c selfTestCase) SelfTestResult {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+
+	result := SelfTestResult{Exchange: name, DataType: c.dataType, Mode: SelfTestModeREST, Symbol: c.symbol}
+	start := time.Now()
+	result.Err = fetchOnce(ctx, exchange, c)
+	result.Latency = time.Since(start)
+	return result
+}
+
+// runSigned 获取一次账户信息，校验API Key、Secret和签名使用的时间偏移
+func (st *SelfTester) runSigned(ctx context.Context, name string, exchange *binance.Binance) SelfTestResult {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+
+	result := SelfTestResult{Exchange: name, DataType: selfTestDataTypeAccount, Mode: SelfTestModeSigned}
+	start := time.Now()
+	_, result.Err = exchange.GetAccount(ctx)
+	result.Latency = time.Since(start)
+	return result
+}
+
+// fetchOnce 按数据类型调用对应的REST方法，返回空数据也视为失败
+func fetchOnce(ctx context.Context, exchange types.ExchangeInterface, c selfTestCase) error {
+	var count int
+	switch c.dataType {
+	case types.DataTypeTicker:
+		if _, err := exchange.GetTicker(ctx, c.symbol); err != nil {
+			return err
+		}
+		return nil
+	case types.DataTypeOrderbook:
+		if _, err := exchange.GetOrderbook(ctx, c.symbol, c.depth); err != nil {
+			return err
+		}
+		return nil
+	case types.DataTypeTrades:
+		trades, err := exchange.GetTrades(ctx, c.symbol, 1)
+		if err != nil {
+			return err
+		}
+		count = len(trades)
+	case types.DataTypeKlines:
+		klines, err := exchange.GetKlines(ctx, c.symbol, c.interval, 1)
+		if err != nil {
+			return err
+		}
+		count = len(klines)
+	case types.DataTypeAvgPrice:
+		provider, ok := exchange.(types.StatsProvider)
+		if !ok {
+			return fmt.Errorf("exchange %s does not support avg_price data", exchange.GetName())
+		}
+		if _, err := provider.GetAvgPrice(ctx, c.symbol); err != nil {
+			return err
+		}
+		return nil
+	case types.DataTypeStats24h:
+		provider, ok := exchange.(types.StatsProvider)
+		if !ok {
+			return fmt.Errorf("exchange %s does not support stats_24h data", exchange.GetName())
+		}
+		stats, err := provider.GetMultipleStats24h(ctx, []types.Symbol{c.symbol})
+		if err != nil {
+			return err
+		}
+		count = len(stats)
+	default:
+		return fmt.Errorf("unsupported data type: %s", c.dataType)
+	}
+	if count == 0 {
+		return errors.New("no data returned")
+	}
+	return nil
+}
+
+// runWebsocket 建立WebSocket连接，依次订阅支持推送的数据类型并等待第一条数据，结束后取消订阅并关闭连接
+func (st *SelfTester) runWebsocket(ctx context.Context, name string, exchange *binance.Binance, cases []selfTestCase) []SelfTestResult {
+	var streamCases []selfTestCase
+	for _, c := range cases {
+		switch c.dataType {
+		case types.DataTypeTicker, types.DataTypeOrderbook, types.DataTypeTrades, types.DataTypeKlines:
+			streamCases = append(streamCases, c)
+		}
+	}
+	if len(streamCases) == 0 {
+		return nil
+	}
+
+	start := time.Now()
+	if err := st.connectWebsocket(ctx, exchange); err != nil {
+		err = fmt.Errorf("WebSocket连接失败: %w", err)
+		results := make([]SelfTestResult, len(streamCases))
+		for i, c := range streamCases {
+			results[i] = SelfTestResult{Exchange: name, DataType: c.dataType, Mode: SelfTestModeWebsocket,
+				Symbol: c.symbol, Latency: time.Since(start), Err: err}
+		}
+		return results
+	}
+	defer st.closeWebsocket(exchange)
+
+	results := make([]SelfTestResult, 0, len(streamCases))
+	for _, c := range streamCases {
+		results = append(results, st.subscribeOnce(ctx, name, exchange, c))
+	}
+	return results
+}
+
+// subscribeOnce 订阅一个数据类型并等待第一条数据，超时视为失败
+func (st *SelfTester) subscribeOnce(ctx context.Context, name string, exchange *binance.Binance, c selfTestCase) SelfTestResult {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+
+	received := make(chan struct{}, 1)
+	callback := func(data types.MarketData) error {
+		select {
+		case received <- struct{}{}:
+		default:
+		}
+		return nil
+	}
+
+	result := SelfTestResult{Exchange: name, DataType: c.dataType, Mode: SelfTestModeWebsocket, Symbol: c.symbol}
+	symbols := []types.Symbol{c.symbol}
+	start := time.Now()
+	var err error
+	switch c.dataType {
+	case types.DataTypeTicker:
+		err = exchange.SubscribeTicker(symbols, callback)
+	case types.DataTypeOrderbook:
+		orderbook := st.config.Exchanges.Binance.DataTypes.Orderbook
+		updateSpeed := orderbook.UpdateSpeed
+		if updateSpeed == "" {
+			updateSpeed = "100ms"
+		}
+		err = exchange.SubscribeOrderbookWithDepth(symbols, orderbook.StreamDepth(), updateSpeed, callback)
+	case types.DataTypeTrades:
+		err = exchange.SubscribeTrades(symbols, callback)
+	case types.DataTypeKlines:
+		err = exchange.SubscribeKlines(symbols, []string{c.interval}, callback)
+	}
+	if err != nil {
+		result.Latency = time.Since(start)
+		result.Err = fmt.Errorf("订阅失败: %w", err)
+		return result
+	}
+
+	select {
+	case <-received:
+	case <-ctx.Done():
+		result.Err = fmt.Errorf("等待推送数据超时: %w", ctx.Err())
+	}
+	result.Latency = time.Since(start)
+	return result
+}
+
+// connectWebsocket 建立WebSocket连接，超过检查超时时间后不再等待；
+// 超时之后才建立成功的连接在后台关闭，避免自检结束后残留连接和读协程
+func (st *SelfTester) connectWebsocket(ctx context.Context, exchange *binance.Binance) error {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- exchange.WsConnect()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		go func() {
+			if err := <-done; err == nil {
+				st.logger.Warn("自检WebSocket在超时后才连接成功，关闭连接")
+				st.closeWebsocket(exchange)
+			}
+		}()
+		return ctx.Err()
+	}
+}
+
+// closeWebsocket 取消自检的订阅并关闭WebSocket连接
+func (st *SelfTester) closeWebsocket(exchange *binance.Binance) {
+	if err := st.withTimeout(context.Background(), exchange.UnsubscribeAll); err != nil {
+		st.logger.Warn("取消自检订阅失败", zap.Error(err))
+	}
+	if err := exchange.WsClose(); err != nil {
+		st.logger.Warn("关闭自检WebSocket失败", zap.Error(err))
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), st.timeout)
+	defer cancel()
+	if err := exchange.WsWait(ctx); err != nil {
+		st.logger.Warn("等待自检WebSocket协程退出失败", zap.Error(err))
+	}
+}
+
+// withTimeout 在后台执行不支持ctx的操作（如取消订阅），超过检查超时时间后不再等待
+func (st *SelfTester) withTimeout(ctx context.Context, fn func() error) error {
+	ctx, cancel := context.WithTimeout(ctx, st.timeout)
+	defer cancel()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- fn()
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// logResult 记录单项检查结果
+func (st *SelfTester) logResult(result SelfTestResult) {
+	fields := []zap.Field{
+		zap.String("exchange", result.Exchange),
+		zap.String("data_type", string(result.DataType)),
+		zap.String("mode", result.Mode),
+		zap.String("symbol", string(result.Symbol)),
+		zap.Duration("latency", result.Latency),
+	}
+	if result.Passed() {
+		st.logger.Info("自检通过", fields...)
+		return
+	}
+	st.logger.Error("自检失败", append(fields, zap.Error(result.Err))...)
+}
+
+// PrintSelfTestReport 输出自检报告，返回失败的检查数
+func PrintSelfTestReport(w io.Writer, results []SelfTestResult) int {
+	failed := 0
+	fmt.Fprintln(w, "自检结果:")
+	for _, result := range results {
+		status := "PASS"
+		if !result.Passed() {
+			status = "FAIL"
+			failed++
+		}
+		fmt.Fprintf(w, "  [%s] %-8s %-10s %-9s %-12s %8v", status, result.Exchange, result.DataType,
+			result.Mode, result.Symbol, result.Latency.Round(time.Millisecond))
+		if result.Err != nil {
+			fmt.Fprintf(w, "  %v", result.Err)
+		}
+		fmt.Fprintln(w)
+	}
+	fmt.Fprintf(w, "共%d项，通过%d项，失败%d项\n", len(results), len(results)-failed, failed)
+	return failed
+}
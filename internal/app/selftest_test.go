@@ -0,0 +1,59 @@
+package app
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+func TestSelfTestSignedCheck(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusOK)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/api/v3/account" || r.Header.Get("X-MBX-APIKEY") != "test-key" || r.URL.Query().Get("signature") == "" {
+			t.Errorf("签名请求不正确: %s %s", r.Method, r.URL)
+		}
+		w.WriteHeader(int(status.Load()))
+		if status.Load() == http.StatusOK {
+			w.Write([]byte(`{"canTrade":true,"balances":[]}`))
+			return
+		}
+		w.Write([]byte(`{"code":-2015,"msg":"Invalid API-key, IP, or permissions for action."}`))
+	}))
+	defer server.Close()
+
+	config := &types.Config{}
+	config.Exchanges.Binance = types.BinanceConfig{Enabled: true, APIURL: server.URL, APIKey: "test-key", APISecret: "test-secret"}
+	exchange := binance.New()
+	if err := exchange.Initialize(config.Exchanges.Binance); err != nil {
+		t.Fatalf("初始化Binance失败: %v", err)
+	}
+	defer exchange.Close()
+
+	tester := NewSelfTester(zap.NewNop(), config, map[string]types.ExchangeInterface{"binance": exchange}, 5*time.Second)
+	results := tester.Run(context.Background())
+	if len(results) != 1 || results[0].Mode != SelfTestModeSigned || !results[0].Passed() {
+		t.Fatalf("配置API Key时应执行签名检查并通过，实际: %+v", results)
+	}
+
+	// 密钥无效时签名检查失败
+	status.Store(http.StatusUnauthorized)
+	results = tester.Run(context.Background())
+	if len(results) != 1 || results[0].Passed() {
+		t.Errorf("密钥无效时签名检查应失败，实际: %+v", results)
+	}
+
+	// 未配置API Key时不执行签名检查
+	config.Exchanges.Binance.APIKey = ""
+	if results := tester.Run(context.Background()); len(results) != 0 {
+		t.Errorf("未配置API Key时不应执行签名检查，实际: %+v", results)
+	}
+}
@@ -1,40 +1,236 @@
 package app
 
 import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"strconv"
+	"sync"
+	"time"
+
 	"go.uber.org/zap"
 
+	"github.com/mooyang-code/data-miner/internal/scheduler"
+	"github.com/mooyang-code/data-miner/internal/storage"
 	"github.com/mooyang-code/data-miner/internal/types"
 )
 
-// ServiceManager 服务管理器
+// defaultMonitoringListenAddr 未配置monitoring.listen_addr时的监听地址
+const defaultMonitoringListenAddr = "127.0.0.1"
+
+// ServiceManager 服务管理器，负责存储输出和监控HTTP服务（含即时查询API）的启动与关闭
 type ServiceManager struct {
-	logger *zap.Logger
+	logger     *zap.Logger
+	components *SystemComponents
+
+	mu      sync.RWMutex
+	started bool
+	sink    storage.Sink
+	servers []*http.Server
+	sched   *scheduler.Scheduler
 }
 
 // NewServiceManager 创建新的服务管理器
-func NewServiceManager(logger *zap.Logger) *ServiceManager {
+func NewServiceManager(logger *zap.Logger, components *SystemComponents) *ServiceManager {
 	return &ServiceManager{
-		logger: logger,
+		logger:     logger,
+		components: components,
 	}
 }
 
-// Start 启动各种服务
+// Start 按顺序启动各种服务：先打开存储，保证调度器产生数据前存储已就绪，再启动监控HTTP服务
 func (sm *ServiceManager) Start(config *types.Config) error {
-	// 启动健康检查服务（如果启用）
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	if sm.started {
+		return fmt.Errorf("service manager already started")
+	}
+
+	sink, err := storage.New(config.Storage)
+	if err != nil {
+		return fmt.Errorf("failed to open storage: %w", err)
+	}
+	if sink != nil && config.Exchanges.Binance.CollectionMode() == types.CollectionModeHybrid {
+		// hybrid模式下REST拉取和WebSocket推送写入同一存储，切换来源的重叠窗口内同一成交或K线只写入一次
+		sink = storage.NewDedupSink(sink)
+	}
+	sm.sink = sink
+	if sink != nil {
+		sm.logger.Info("存储服务启动",
+			zap.String("base_path", config.Storage.File.BasePath),
+			zap.String("format", config.Storage.File.Format))
+	}
+
 	if config.Monitoring.Enabled {
-		go sm.startHealthCheck(config.Monitoring.HealthCheckPort)
-		sm.logger.Info("健康检查服务启动",
-			zap.Int("port", config.Monitoring.HealthCheckPort))
+		if err := sm.startMonitoring(config.Monitoring); err != nil {
+			for _, server := range sm.servers {
+				server.Close()
+			}
+			sm.servers = nil
+			if sink != nil {
+				sink.Close()
+			}
+			sm.sink = nil
+			return err
+		}
 	}
 
-	// 这里可以添加其他服务的启动逻辑
-	// 例如：HTTP API服务、监控服务等
+	sm.started = true
+	return nil
+}
+
+// Stop 按与启动相反的顺序关闭服务：先停止监控HTTP服务，不再接受查询，再关闭存储。
+// 调用前应先停止调度器，避免关闭存储后仍有数据写入
+func (sm *ServiceManager) Stop(ctx context.Context) error {
+	return errors.Join(sm.StopMonitoring(ctx), sm.CloseStorage(ctx))
+}
 
+// StopMonitoring 关闭监控HTTP服务，等待处理中的请求完成直到ctx结束
+func (sm *ServiceManager) StopMonitoring(ctx context.Context) error {
+	sm.mu.Lock()
+	servers := sm.servers
+	sm.servers = nil
+	sm.mu.Unlock()
+
+	// 在锁外关闭，处理中的请求需要读取调度器
+	return sm.shutdownServers(ctx, servers)
+}
+
+// FlushStorage 将存储缓冲中的数据写出，不关闭存储；未启用存储时直接返回
+func (sm *ServiceManager) FlushStorage(ctx context.Context) error {
+	sink := sm.Sink()
+	if sink == nil {
+		return nil
+	}
+	if err := sink.Flush(ctx); err != nil {
+		return fmt.Errorf("failed to flush storage: %w", err)
+	}
+	sm.logger.Info("存储缓冲数据已写出")
 	return nil
 }
 
-// startHealthCheck 启动健康检查服务
-func (sm *ServiceManager) startHealthCheck(port int) {
-	// TODO: 实现HTTP健康检查服务
-	sm.logger.Info("健康检查服务占位符", zap.Int("port", port))
+// CloseStorage 将缓冲的数据写出并关闭存储，之后的写入返回storage.ErrClosed。
+// ctx结束时返回ctx.Err()，不再等待关闭完成（关闭在后台继续）
+func (sm *ServiceManager) CloseStorage(ctx context.Context) error {
+	sm.mu.Lock()
+	sink := sm.sink
+	sm.sink = nil
+	sm.started = false
+	sm.mu.Unlock()
+
+	if sink == nil {
+		return nil
+	}
+
+	// 先按ctx写出缓冲数据，Close不接受ctx，缓冲已写出时只释放文件等资源
+	if err := sink.Flush(ctx); err != nil {
+		sm.logger.Warn("关闭存储前写出缓冲数据失败", zap.Error(err))
+	}
+	done := make(chan error, 1)
+	go func() {
+		done <- sink.Close()
+	}()
+	select {
+	case err := <-done:
+		if err != nil {
+			return fmt.Errorf("failed to close storage: %w", err)
+		}
+	case <-ctx.Done():
+		return fmt.Errorf("failed to close storage: %w", ctx.Err())
+	}
+	sm.logger.Info("存储服务已关闭")
+	return nil
+}
+
+// Sink 返回存储输出，未启用存储时返回nil
+func (sm *ServiceManager) Sink() storage.Sink {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.sink
+}
+
+// SetScheduler 设置调度器，供监控服务查询任务状态和即时查询行情；调度器未设置时相关接口返回503
+func (sm *ServiceManager) SetScheduler(sched *scheduler.Scheduler) {
+	sm.mu.Lock()
+	defer sm.mu.Unlock()
+	sm.sched = sched
+}
+
+// getScheduler 获取当前设置的调度器
+func (sm *ServiceManager) getScheduler() *scheduler.Scheduler {
+	sm.mu.RLock()
+	defer sm.mu.RUnlock()
+	return sm.sched
+}
+
+// startMonitoring 启动监控HTTP服务：指标端口提供全部接口，健康检查端口与其不同时单独提供/health和/ready（调用时需要持有锁）
+func (sm *ServiceManager) startMonitoring(config types.MonitoringConfig) error {
+	handler := sm.monitoringHandler()
+	separatePprof := config.EnablePprof && config.PprofPort > 0 && config.PprofPort != config.MetricsPort
+	if config.EnablePprof && !separatePprof {
+		mux := pprofHandler()
+		mux.Handle("/", handler)
+		handler = mux
+		sm.logger.Warn("pprof性能分析接口已挂载在监控服务上，请勿对外暴露", zap.Int("port", config.MetricsPort))
+	}
+	if err := sm.serve("监控服务", config.ListenAddr, config.MetricsPort, handler); err != nil {
+		return err
+	}
+	if separatePprof {
+		if err := sm.serve("pprof调试服务", config.ListenAddr, config.PprofPort, pprofHandler()); err != nil {
+			return err
+		}
+		sm.logger.Warn("pprof性能分析接口已开启，请勿对外暴露", zap.Int("port", config.PprofPort))
+	}
+	if config.HealthCheckPort > 0 && config.HealthCheckPort != config.MetricsPort {
+		mux := http.NewServeMux()
+		mux.HandleFunc("GET /health", sm.handleHealth)
+		mux.HandleFunc("GET /ready", sm.handleReady)
+		if err := sm.serve("健康检查服务", config.ListenAddr, config.HealthCheckPort, mux); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// serve 监听端口并在后台提供HTTP服务，监听失败时直接返回错误（调用时需要持有锁）
+// 监控服务包含未鉴权的任务控制接口和pprof，未配置监听地址时只监听本机
+func (sm *ServiceManager) serve(name, addr string, port int, handler http.Handler) error {
+	if addr == "" {
+		addr = defaultMonitoringListenAddr
+	}
+	listener, err := net.Listen("tcp", net.JoinHostPort(addr, strconv.Itoa(port)))
+	if err != nil {
+		return fmt.Errorf("failed to listen %s on port %d: %w", name, port, err)
+	}
+
+	server := &http.Server{
+		Handler:           handler,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+	sm.servers = append(sm.servers, server)
+	go func() {
+		if err := server.Serve(listener); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			sm.logger.Error("HTTP服务异常退出", zap.String("service", name), zap.Error(err))
+		}
+	}()
+
+	sm.logger.Info(name+"启动", zap.String("addr", listener.Addr().String()))
+	return nil
+}
+
+// shutdownServers 关闭HTTP服务，等待处理中的请求完成直到ctx超时
+func (sm *ServiceManager) shutdownServers(ctx context.Context, servers []*http.Server) error {
+	var errs []error
+	for _, server := range servers {
+		if err := server.Shutdown(ctx); err != nil {
+			errs = append(errs, fmt.Errorf("failed to shutdown HTTP server: %w", err))
+		}
+	}
+	if len(servers) > 0 {
+		sm.logger.Info("监控服务已关闭")
+	}
+	return errors.Join(errs...)
 }
@@ -0,0 +1,131 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+)
+
+// ShutdownStep 关闭流程中的一个步骤
+type ShutdownStep struct {
+	Name      string                          // 步骤名称，用于日志
+	Timeout   time.Duration                   // 步骤的时间预算
+	Run       func(ctx context.Context) error // 执行关闭，ctx在超出时间预算时结束
+	DependsOn []string                        // 必须已经结束的前序步骤，前序步骤超时仍在执行时在本步骤的时间预算内等待
+}
+
+// 优雅关闭各步骤的时间预算
+const (
+	intakeShutdownTimeout    = 5 * time.Second  // 停止WebSocket推送和监控HTTP服务
+	schedulerShutdownTimeout = 15 * time.Second // 等待执行中的任务完成
+	flushShutdownTimeout     = 10 * time.Second // 写出存储缓冲中的数据
+	storageShutdownTimeout   = 10 * time.Second // 写出剩余数据并关闭存储
+	exchangeShutdownTimeout  = 5 * time.Second  // 关闭交易所连接
+)
+
+// 关闭步骤名称
+const (
+	stepStopIntake     = "stop intake"
+	stepStopScheduler  = "stop scheduler"
+	stepFlushStorage   = "flush storage"
+	stepCloseStorage   = "close storage"
+	stepCloseExchanges = "close exchanges"
+)
+
+// errDependencyRunning 前序步骤超时后仍在执行，在时间预算内未结束，依赖它的步骤被跳过
+var errDependencyRunning = errors.New("dependent step is still running")
+
+// ShutdownHooks 优雅关闭时各组件的关闭函数
+type ShutdownHooks struct {
+	StopIntake     func(ctx context.Context) error // 关闭WebSocket和监控服务，停止接收推送数据和查询请求
+	StopScheduler  func(ctx context.Context) error // 停止调度器，等待执行中的任务完成
+	FlushStorage   func(ctx context.Context) error // 写出存储缓冲中的数据，不关闭存储
+	CloseStorage   func(ctx context.Context) error // 写出剩余数据并关闭存储
+	CloseExchanges func(ctx context.Context) error // 关闭交易所连接
+}
+
+// Steps 按停止数据输入 → 停止调度器 → 写出存储缓冲 → 关闭存储 → 关闭交易所的顺序返回关闭步骤。
+// 写出缓冲不依赖生产者已停止（Flush可与写入并发），即使停止调度器超时，已采集的数据也会写出；
+// 关闭存储和交易所必须等WebSocket和调度器真正停止，否则仍在执行的任务会写入已关闭的存储或使用已关闭的连接
+func (h ShutdownHooks) Steps() []ShutdownStep {
+	producers := []string{stepStopIntake, stepStopScheduler}
+	return []ShutdownStep{
+		{Name: stepStopIntake, Timeout: intakeShutdownTimeout, Run: h.StopIntake},
+		{Name: stepStopScheduler, Timeout: schedulerShutdownTimeout, Run: h.StopScheduler},
+		{Name: stepFlushStorage, Timeout: flushShutdownTimeout, Run: h.FlushStorage},
+		{Name: stepCloseStorage, Timeout: storageShutdownTimeout, Run: h.CloseStorage, DependsOn: producers},
+		{Name: stepCloseExchanges, Timeout: exchangeShutdownTimeout, Run: h.CloseExchanges, DependsOn: producers},
+	}
+}
+
+// RunShutdown 按顺序执行关闭步骤，每个步骤使用独立的超时。步骤超出时间预算时记录日志并继续下一步，
+// 不再等待该步骤（仍在后台执行），避免一个阻塞的关闭耗尽整体时间导致后续步骤（如存储落盘）无法执行；
+// 依赖该步骤的后续步骤在自己的时间预算内等待它结束，仍未结束时跳过
+func RunShutdown(logger *zap.Logger, steps []ShutdownStep) error {
+	var errs []error
+	running := make(map[string]<-chan struct{}) // 各步骤结束时关闭
+	for _, step := range steps {
+		start := time.Now()
+		done, err := runShutdownStep(step, running)
+		running[step.Name] = done
+		if err != nil {
+			switch {
+			case errors.Is(err, errDependencyRunning):
+				logger.Error("前序步骤仍在执行，跳过关闭步骤",
+					zap.String("step", step.Name),
+					zap.Strings("depends_on", step.DependsOn),
+					zap.Error(err))
+			case errors.Is(err, context.DeadlineExceeded):
+				logger.Error("关闭步骤超出时间预算，跳过继续关闭",
+					zap.String("step", step.Name),
+					zap.Duration("timeout", step.Timeout),
+					zap.Error(err))
+			default:
+				logger.Error("关闭步骤失败",
+					zap.String("step", step.Name),
+					zap.Duration("elapsed", time.Since(start)),
+					zap.Error(err))
+			}
+			errs = append(errs, fmt.Errorf("%s: %w", step.Name, err))
+			continue
+		}
+		logger.Info("关闭步骤完成", zap.String("step", step.Name), zap.Duration("elapsed", time.Since(start)))
+	}
+	return errors.Join(errs...)
+}
+
+// runShutdownStep 等待依赖的步骤结束后在后台执行步骤，并等待其完成或超时，超时时返回context.DeadlineExceeded。
+// 返回的channel在步骤执行结束（或被跳过）时关闭
+func runShutdownStep(step ShutdownStep, running map[string]<-chan struct{}) (<-chan struct{}, error) {
+	ctx, cancel := context.WithTimeout(context.Background(), step.Timeout)
+	defer cancel()
+
+	finished := make(chan struct{})
+	for _, name := range step.DependsOn {
+		dependency, ok := running[name]
+		if !ok {
+			continue
+		}
+		select {
+		case <-dependency:
+		case <-ctx.Done():
+			close(finished)
+			return finished, fmt.Errorf("%w: %s", errDependencyRunning, name)
+		}
+	}
+
+	done := make(chan error, 1)
+	go func() {
+		defer close(finished)
+		done <- step.Run(ctx)
+	}()
+	select {
+	case err := <-done:
+		return finished, err
+	case <-ctx.Done():
+		return finished, ctx.Err()
+	}
+}
@@ -0,0 +1,198 @@
+package app
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/storage"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// bufferedSink 缓冲写入的数据，Flush或Close时才写出到persisted，模拟批量写入的存储
+type bufferedSink struct {
+	mu        sync.Mutex
+	buffer    []types.MarketData
+	persisted []types.MarketData
+	closed    bool
+}
+
+func (s *bufferedSink) Write(data types.MarketData) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return storage.ErrClosed
+	}
+	s.buffer = append(s.buffer, data)
+	return nil
+}
+
+func (s *bufferedSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.persisted = append(s.persisted, s.buffer...)
+	s.buffer = nil
+	return nil
+}
+
+func (s *bufferedSink) Close() error {
+	s.Flush(context.Background())
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	s.closed = true
+	return nil
+}
+
+func (s *bufferedSink) persistedCount() int {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return len(s.persisted)
+}
+
+func TestGracefulShutdownFlushesBufferedRows(t *testing.T) {
+	sink := &bufferedSink{}
+	sm := NewServiceManager(zap.NewNop(), nil)
+	sm.sink = sink
+
+	write := func(n int) {
+		for i := 0; i < n; i++ {
+			ticker := &types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: float64(i), Timestamp: time.Now()}
+			if err := sm.Sink().Write(ticker); err != nil {
+				t.Errorf("写入失败: %v", err)
+			}
+		}
+	}
+	// 关闭前已采集、仍在缓冲中的数据
+	write(5)
+
+	var mu sync.Mutex
+	var ran []string
+	record := func(name string) {
+		mu.Lock()
+		defer mu.Unlock()
+		ran = append(ran, name)
+	}
+
+	var flushedAfterScheduler int
+	schedulerStopped := make(chan struct{})
+	hooks := ShutdownHooks{
+		StopIntake: func(ctx context.Context) error {
+			record("stop intake")
+			return nil
+		},
+		StopScheduler: func(ctx context.Context) error {
+			defer close(schedulerStopped)
+			record("stop scheduler")
+			// 执行中的任务又写入数据后卡住，超出时间预算后才结束
+			write(3)
+			<-ctx.Done()
+			time.Sleep(50 * time.Millisecond)
+			write(2)
+			return nil
+		},
+		FlushStorage: func(ctx context.Context) error {
+			record("flush storage")
+			err := sm.FlushStorage(ctx)
+			flushedAfterScheduler = sink.persistedCount()
+			return err
+		},
+		CloseStorage: func(ctx context.Context) error {
+			record("close storage")
+			select {
+			case <-schedulerStopped:
+			default:
+				t.Error("关闭存储时调度器仍在执行")
+			}
+			return sm.CloseStorage(ctx)
+		},
+		CloseExchanges: func(ctx context.Context) error {
+			record("close exchanges")
+			return nil
+		},
+	}
+
+	steps := hooks.Steps()
+	for i := range steps {
+		steps[i].Timeout = 100 * time.Millisecond
+	}
+	err := RunShutdown(zap.NewNop(), steps)
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("卡住的步骤应报告超时，实际: %v", err)
+	}
+
+	expected := []string{"stop intake", "stop scheduler", "flush storage", "close storage", "close exchanges"}
+	mu.Lock()
+	defer mu.Unlock()
+	if !reflect.DeepEqual(ran, expected) {
+		t.Errorf("关闭步骤顺序不正确: %v", ran)
+	}
+	// 停止调度器超时后写出缓冲不等待调度器，已采集的数据先写出
+	if flushedAfterScheduler != 8 {
+		t.Errorf("停止调度器超时后应写出已缓冲的8条数据，实际%d条", flushedAfterScheduler)
+	}
+	// 关闭存储等待调度器结束，之后写入的数据也被写出
+	if n := sink.persistedCount(); n != 10 {
+		t.Errorf("关闭后应写出全部10条数据，实际%d条", n)
+	}
+	if err := sink.Write(&types.Ticker{}); !errors.Is(err, storage.ErrClosed) {
+		t.Errorf("关闭后的写入应返回ErrClosed，实际: %v", err)
+	}
+}
+
+func TestRunShutdownSkipsStepsWhileDependencyRuns(t *testing.T) {
+	release := make(chan struct{})
+	var closed atomic.Bool
+	steps := []ShutdownStep{
+		{Name: "producer", Timeout: 50 * time.Millisecond, Run: func(ctx context.Context) error {
+			<-release
+			return nil
+		}},
+		{Name: "independent", Timeout: 50 * time.Millisecond, Run: func(ctx context.Context) error { return nil }},
+		{Name: "consumer", Timeout: 50 * time.Millisecond, DependsOn: []string{"producer"}, Run: func(ctx context.Context) error {
+			closed.Store(true)
+			return nil
+		}},
+	}
+	err := RunShutdown(zap.NewNop(), steps)
+	close(release)
+
+	if !errors.Is(err, context.DeadlineExceeded) || !errors.Is(err, errDependencyRunning) {
+		t.Errorf("应报告生产者超时并跳过依赖它的步骤，实际: %v", err)
+	}
+	if closed.Load() {
+		t.Error("生产者仍在执行时不应执行依赖它的步骤")
+	}
+}
+
+// slowCloseSink 关闭时阻塞直到release关闭
+type slowCloseSink struct {
+	bufferedSink
+	release chan struct{}
+}
+
+func (s *slowCloseSink) Close() error {
+	<-s.release
+	return s.bufferedSink.Close()
+}
+
+func TestCloseStorageHonoursContext(t *testing.T) {
+	sink := &slowCloseSink{release: make(chan struct{})}
+	defer close(sink.release)
+	sm := NewServiceManager(zap.NewNop(), nil)
+	sm.sink = sink
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	if err := sm.CloseStorage(ctx); !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("关闭超时时应返回context.DeadlineExceeded，实际: %v", err)
+	}
+	if sm.Sink() != nil {
+		t.Error("关闭后不应再返回存储输出")
+	}
+}
@@ -1,16 +1,25 @@
 package app
 
 import (
+	"context"
+	"errors"
 	"fmt"
+	"sync"
+
 	"go.uber.org/zap"
 
 	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/storage"
 	"github.com/mooyang-code/data-miner/internal/types"
 )
 
 // WebsocketManager WebSocket管理器
 type WebsocketManager struct {
 	logger *zap.Logger
+	sink   storage.Sink // 数据存储输出，为nil时只记录日志
+
+	mu      sync.Mutex
+	started []*binance.Binance // 已启动WebSocket的交易所，Stop时关闭
 }
 
 // NewWebsocketManager 创建新的WebSocket管理器
@@ -20,10 +29,15 @@ func NewWebsocketManager(logger *zap.Logger) *WebsocketManager {
 	}
 }
 
+// SetSink 设置推送数据的存储输出，需要在Start之前调用
+func (wm *WebsocketManager) SetSink(sink storage.Sink) {
+	wm.sink = sink
+}
+
 // Start 启动WebSocket连接
 func (wm *WebsocketManager) Start(config *types.Config, exchanges map[string]types.ExchangeInterface) error {
 	// 启动Binance WebSocket（如果启用）
-	if config.Exchanges.Binance.Enabled && config.Exchanges.Binance.UseWebsocket {
+	if config.Exchanges.Binance.Enabled && config.Exchanges.Binance.UsesWebsocket() {
 		if binanceExchange, ok := exchanges["binance"].(*binance.Binance); ok {
 			wm.logger.Info("启动Binance WebSocket模式")
 			if err := wm.startBinanceWebsocket(binanceExchange, config.Exchanges.Binance); err != nil {
@@ -47,6 +61,10 @@ func (wm *WebsocketManager) startBinanceWebsocket(exchange *binance.Binance, con
 	}
 	wm.logger.Info("WebSocket连接成功")
 
+	wm.mu.Lock()
+	wm.started = append(wm.started, exchange)
+	wm.mu.Unlock()
+
 	// 使用封装好的订阅方法
 	if err := wm.subscribeToDataTypes(exchange, config); err != nil {
 		wm.logger.Error("订阅数据类型失败", zap.Error(err))
@@ -60,6 +78,51 @@ func (wm *WebsocketManager) startBinanceWebsocket(exchange *binance.Binance, con
 	return nil
 }
 
+// Stop 取消所有订阅并关闭已启动的WebSocket连接，等待读取、重连等后台协程退出；
+// ctx结束时不再等待，直接返回ctx.Err()
+func (wm *WebsocketManager) Stop(ctx context.Context) error {
+	wm.mu.Lock()
+	started := wm.started
+	wm.started = nil
+	wm.mu.Unlock()
+
+	var errs []error
+	for _, exchange := range started {
+		// 发送取消订阅和关闭帧可能因网络问题阻塞，在后台执行以遵循ctx的截止时间
+		done := make(chan error, 1)
+		go func() {
+			done <- wm.stopBinanceWebsocket(ctx, exchange)
+		}()
+		select {
+		case err := <-done:
+			if err != nil {
+				errs = append(errs, err)
+			}
+		case <-ctx.Done():
+			wm.logger.Warn("关闭WebSocket超时", zap.String("exchange", string(exchange.GetName())))
+			return ctx.Err()
+		}
+	}
+	if len(started) > 0 {
+		wm.logger.Info("WebSocket已关闭")
+	}
+	return errors.Join(errs...)
+}
+
+// stopBinanceWebsocket 取消订阅并关闭Binance WebSocket，取消订阅失败不影响关闭
+func (wm *WebsocketManager) stopBinanceWebsocket(ctx context.Context, exchange *binance.Binance) error {
+	if err := exchange.UnsubscribeAll(); err != nil {
+		wm.logger.Warn("取消WebSocket订阅失败", zap.Error(err))
+	}
+	if err := exchange.WsClose(); err != nil {
+		return fmt.Errorf("关闭WebSocket失败: %w", err)
+	}
+	if err := exchange.WsWait(ctx); err != nil {
+		return fmt.Errorf("等待WebSocket后台协程退出失败: %w", err)
+	}
+	return nil
+}
+
 // subscribeToDataTypes 使用封装好的方法订阅各种数据类型
 func (wm *WebsocketManager) subscribeToDataTypes(exchange *binance.Binance, config types.BinanceConfig) error {
 	// 订阅行情数据
@@ -77,10 +140,15 @@ func (wm *WebsocketManager) subscribeToDataTypes(exchange *binance.Binance, conf
 		symbols := wm.convertToSymbolTypes(config.DataTypes.Orderbook.Symbols)
 		wm.logger.Info("订阅订单簿数据",
 			zap.Strings("symbols", config.DataTypes.Orderbook.Symbols),
-			zap.Int("depth", config.DataTypes.Orderbook.Depth))
+			zap.String("mode", config.DataTypes.Orderbook.StreamMode()),
+			zap.Int("levels", config.DataTypes.Orderbook.StreamLevels()))
 
-		// 使用自定义深度订阅
-		if err := exchange.SubscribeOrderbookWithDepth(symbols, config.DataTypes.Orderbook.Depth, "100ms", wm.createOrderbookCallback()); err != nil {
+		// 按配置的深度流模式和推送频率订阅：partial订阅有限档深度流，diff订阅增量深度流
+		updateSpeed := config.DataTypes.Orderbook.UpdateSpeed
+		if updateSpeed == "" {
+			updateSpeed = "100ms"
+		}
+		if err := exchange.SubscribeOrderbookWithDepth(symbols, config.DataTypes.Orderbook.StreamDepth(), updateSpeed, wm.createOrderbookCallback()); err != nil {
 			return fmt.Errorf("订阅订单簿数据失败: %v", err)
 		}
 	}
@@ -126,8 +194,7 @@ func (wm *WebsocketManager) createTickerCallback() types.DataCallback {
 			zap.String("exchange", string(data.GetExchange())),
 			zap.String("symbol", string(data.GetSymbol())),
 			zap.String("type", string(data.GetDataType())))
-		// 这里可以添加数据处理逻辑，如保存到数据库等
-		return nil
+		return wm.saveData(data)
 	}
 }
 
@@ -138,8 +205,7 @@ func (wm *WebsocketManager) createOrderbookCallback() types.DataCallback {
 			zap.String("exchange", string(data.GetExchange())),
 			zap.String("symbol", string(data.GetSymbol())),
 			zap.String("type", string(data.GetDataType())))
-		// 这里可以添加数据处理逻辑，如保存到数据库等
-		return nil
+		return wm.saveData(data)
 	}
 }
 
@@ -150,8 +216,7 @@ func (wm *WebsocketManager) createKlineCallback() types.DataCallback {
 			zap.String("exchange", string(data.GetExchange())),
 			zap.String("symbol", string(data.GetSymbol())),
 			zap.String("type", string(data.GetDataType())))
-		// 这里可以添加数据处理逻辑，如保存到数据库等
-		return nil
+		return wm.saveData(data)
 	}
 }
 
@@ -162,7 +227,14 @@ func (wm *WebsocketManager) createTradeCallback() types.DataCallback {
 			zap.String("exchange", string(data.GetExchange())),
 			zap.String("symbol", string(data.GetSymbol())),
 			zap.String("type", string(data.GetDataType())))
-		// 这里可以添加数据处理逻辑，如保存到数据库等
+		return wm.saveData(data)
+	}
+}
+
+// saveData 保存推送数据到存储输出，未启用存储时不保存
+func (wm *WebsocketManager) saveData(data types.MarketData) error {
+	if wm.sink == nil {
 		return nil
 	}
+	return wm.sink.Write(data)
 }
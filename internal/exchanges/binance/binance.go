@@ -4,6 +4,7 @@ package binance
 import (
 	"context"
 	"fmt"
+	"slices"
 	"strconv"
 	"sync"
 	"time"
@@ -15,6 +16,9 @@ import (
 	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
 )
 
+// supportedAssetTypes 可通过exchangeInfo获取交易对的资产类型
+var supportedAssetTypes = asset.Items{asset.Spot, asset.Margin, asset.CrossMargin}
+
 // Binance 主要的交易所结构体，包含REST API和WebSocket客户端
 type Binance struct {
 	RestAPI   *BinanceRestAPI     // REST API 客户端
@@ -30,6 +34,13 @@ type Binance struct {
 	Verbose      bool             // 详细日志
 	HTTPTimeout  time.Duration    // HTTP超时时间
 
+	userDataKey    string             // 当前用户数据流的listenKey
+	userDataCancel context.CancelFunc // 停止listenKey保活
+	userDataWg     sync.WaitGroup     // 跟踪listenKey保活协程
+
+	timeSyncCancel context.CancelFunc // 停止服务器时间同步
+	timeSyncDone   chan struct{}      // 服务器时间同步协程退出后关闭
+
 	tradablePairsCache *TradablePairsCache // 交易对缓存管理器
 	logger             *zap.Logger
 }
@@ -51,8 +62,9 @@ func New() *Binance {
 	// 初始化REST API客户端
 	b.RestAPI = NewRestAPI()
 
-	// 初始化WebSocket客户端
+	// 初始化WebSocket客户端，增量深度流通过REST快照建立本地订单簿
 	b.WebSocket = NewWebSocket()
+	b.WebSocket.SetOrderbookSnapshotFetcher(b.fetchOrderbookSnapshot)
 
 	// 初始化日志记录器（默认使用nop logger）
 	b.logger = zap.NewNop()
@@ -74,6 +86,26 @@ func (b *Binance) Initialize(config interface{}) error {
 		b.config = binanceConfig
 	}
 
+	if b.RestAPI != nil {
+		if err := b.RestAPI.Initialize(b.config); err != nil {
+			return fmt.Errorf("failed to initialize REST API: %w", err)
+		}
+	}
+
+	if b.WebSocket != nil && b.config.ProxyURL != "" {
+		if err := b.WebSocket.SetProxy(b.config.ProxyURL); err != nil {
+			return fmt.Errorf("failed to configure WebSocket proxy: %w", err)
+		}
+	}
+	if b.WebSocket != nil {
+		if err := b.WebSocket.SetTLSConfig(httpTLSConfig(b.config.TLS)); err != nil {
+			return fmt.Errorf("failed to configure WebSocket TLS: %w", err)
+		}
+		b.WebSocket.SetReconnectConfig(b.config.WebsocketReconnect)
+		b.WebSocket.SetSubscribeConfirmTimeout(b.config.SubscribeConfirmTimeout)
+		b.WebSocket.SetCompression(!b.config.DisableWebsocketCompression)
+	}
+
 	// 初始化交易对缓存管理器（如果配置启用）
 	if b.config.TradablePairs.FetchFromAPI {
 		if err := b.initializeTradablePairsCache(); err != nil {
@@ -96,13 +128,13 @@ func (b *Binance) initializeTradablePairsCache() error {
 	// 解析支持的资产类型
 	supportedAssets := make([]asset.Item, 0, len(b.config.TradablePairs.SupportedAssets))
 	for _, assetStr := range b.config.TradablePairs.SupportedAssets {
-		switch assetStr {
-		case "spot":
-			supportedAssets = append(supportedAssets, asset.Spot)
-		case "margin":
-			supportedAssets = append(supportedAssets, asset.Margin)
-		default:
+		assetType, err := asset.New(assetStr)
+		if err != nil || !b.IsAssetSupported(assetType) {
 			b.logger.Warn("Unsupported asset type in config", zap.String("asset", assetStr))
+			continue
+		}
+		if !slices.Contains(supportedAssets, assetType) {
+			supportedAssets = append(supportedAssets, assetType)
 		}
 	}
 
@@ -113,10 +145,12 @@ func (b *Binance) initializeTradablePairsCache() error {
 
 	// 创建缓存配置
 	cacheConfig := TradablePairsCacheConfig{
-		UpdateInterval:  b.config.TradablePairs.UpdateInterval,
-		CacheTTL:        b.config.TradablePairs.CacheTTL,
-		SupportedAssets: supportedAssets,
-		AutoUpdate:      b.config.TradablePairs.AutoUpdate,
+		UpdateInterval:      b.config.TradablePairs.UpdateInterval,
+		CacheTTL:            b.config.TradablePairs.CacheTTL,
+		SupportedAssets:     supportedAssets,
+		AutoUpdate:          b.config.TradablePairs.AutoUpdate,
+		MissRefreshInterval: b.config.TradablePairs.MissRefreshInterval,
+		RecheckOnMiss:       b.config.TradablePairs.RecheckOnMiss,
 	}
 
 	// 设置默认值
@@ -127,11 +161,31 @@ func (b *Binance) initializeTradablePairsCache() error {
 		cacheConfig.CacheTTL = 2 * time.Hour // 默认缓存2小时
 	}
 
+	// 更新间隔大于缓存TTL时缓存会在自动更新前过期，每次查询都触发同步刷新；
+	// 调整为TTL的一半，为刷新耗时和一次失败的刷新留出余量
+	if cacheConfig.AutoUpdate && cacheConfig.UpdateInterval > cacheConfig.CacheTTL {
+		b.logger.Info("Tradable pairs update interval exceeds cache TTL, adjusting update interval",
+			zap.Duration("update_interval", cacheConfig.UpdateInterval),
+			zap.Duration("cache_ttl", cacheConfig.CacheTTL),
+			zap.Duration("adjusted_update_interval", cacheConfig.CacheTTL/2))
+		cacheConfig.UpdateInterval = cacheConfig.CacheTTL / 2
+	}
+
 	// 创建缓存管理器
 	b.tradablePairsCache = NewTradablePairsCache(b, b.logger, cacheConfig)
 	return nil
 }
 
+// GetSupportedAssets 返回可获取交易对的资产类型列表
+func (b *Binance) GetSupportedAssets() asset.Items {
+	return supportedAssetTypes
+}
+
+// IsAssetSupported 检查资产类型是否可获取交易对
+func (b *Binance) IsAssetSupported(assetType asset.Item) bool {
+	return supportedAssetTypes.Contains(assetType)
+}
+
 // IsEnabled 返回交易所是否启用
 func (b *Binance) IsEnabled() bool {
 	return b.Enabled
@@ -144,6 +198,10 @@ func (b *Binance) Close() error {
 		b.tradablePairsCache.Stop()
 	}
 
+	// 停止服务器时间同步和用户数据流（需要在关闭WebSocket和REST客户端之前）
+	b.stopTimeSync()
+	b.stopUserDataStream()
+
 	// 关闭WebSocket连接
 	if b.WebSocket != nil {
 		if err := b.WebSocket.WsClose(); err != nil {
@@ -209,10 +267,65 @@ func (b *Binance) GetTicker(ctx context.Context, symbol types.Symbol) (*types.Ti
 	return ticker, nil
 }
 
+// GetAvgPrice 获取平均价格数据
+func (b *Binance) GetAvgPrice(ctx context.Context, symbol types.Symbol) (*types.AvgPrice, error) {
+	avgPrice, err := b.RestAPI.GetAveragePrice(ctx, string(symbol))
+	if err != nil {
+		return nil, err
+	}
+
+	timestamp := avgPrice.CloseTime.Time()
+	if timestamp.IsZero() {
+		timestamp = time.Now()
+	}
+	return &types.AvgPrice{
+		Exchange:  types.ExchangeBinance,
+		Symbol:    symbol,
+		Minutes:   avgPrice.Mins,
+		Price:     avgPrice.Price,
+		Timestamp: timestamp,
+	}, nil
+}
+
+// GetMultipleStats24h 批量获取24小时统计数据，部分交易对失败时返回成功的结果和*types.BatchError
+func (b *Binance) GetMultipleStats24h(ctx context.Context, symbols []types.Symbol) ([]types.Stats24h, error) {
+	symbolStrings := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		symbolStrings[i] = string(symbol)
+	}
+
+	binanceStats, err := b.RestAPI.GetMultipleTickers(ctx, symbolStrings)
+	if err != nil && len(binanceStats) == 0 {
+		return nil, err
+	}
+
+	stats := make([]types.Stats24h, len(binanceStats))
+	for i, stat := range binanceStats {
+		stats[i] = types.Stats24h{
+			Exchange:           types.ExchangeBinance,
+			Symbol:             types.Symbol(stat.Symbol),
+			PriceChange:        stat.PriceChange.Float64(),
+			PriceChangePercent: stat.PriceChangePercent.Float64(),
+			WeightedAvgPrice:   stat.WeightedAvgPrice.Float64(),
+			PrevClosePrice:     stat.PrevClosePrice.Float64(),
+			LastPrice:          stat.LastPrice.Float64(),
+			OpenPrice:          stat.OpenPrice.Float64(),
+			HighPrice:          stat.HighPrice.Float64(),
+			LowPrice:           stat.LowPrice.Float64(),
+			Volume:             stat.Volume.Float64(),
+			QuoteVolume:        stat.QuoteVolume.Float64(),
+			TradeCount:         stat.Count,
+			OpenTime:           stat.OpenTime.Time(),
+			CloseTime:          stat.CloseTime.Time(),
+		}
+	}
+	return stats, err
+}
+
 // GetOrderbook 获取订单簿数据
 func (b *Binance) GetOrderbook(ctx context.Context, symbol types.Symbol, depth int) (*types.Orderbook, error) {
 	// 转换symbol为currency.Pair
-	pair, err := currency.NewPairFromString(string(symbol))
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, symbol)
 	if err != nil {
 		return nil, err
 	}
@@ -281,6 +394,26 @@ func (b *Binance) GetKlines(ctx context.Context, symbol types.Symbol, interval s
 	return b.RestAPI.GetKlinesForSymbol(ctx, symbol, interval, limit)
 }
 
+// GetKlinesSince 获取开盘时间不早于startTime的K线，用于增量采集
+func (b *Binance) GetKlinesSince(ctx context.Context, symbol types.Symbol, interval string, startTime time.Time, limit int) ([]types.Kline, error) {
+	return b.RestAPI.GetKlinesForSymbolSince(ctx, symbol, interval, startTime, limit)
+}
+
+// GetServerTime 获取服务器时间
+func (b *Binance) GetServerTime(ctx context.Context) (time.Time, error) {
+	return b.RestAPI.GetServerTime(ctx)
+}
+
+// GetUsedWeight 获取最近一次REST响应中的已使用权重及其记录时间
+func (b *Binance) GetUsedWeight() (int, time.Time) {
+	return b.RestAPI.GetUsedWeight()
+}
+
+// OrderbookDepthWeight 获取指定深度的订单簿请求权重，供调度器按深度计算批大小
+func (b *Binance) OrderbookDepthWeight(depth int) int {
+	return OrderbookDepthWeight(depth)
+}
+
 // GetTimeAndWeight 获取服务器时间和当前权重使用情况
 func (b *Binance) GetTimeAndWeight(ctx context.Context) (int64, int, error) {
 	return b.RestAPI.GetTimeAndWeight(ctx)
@@ -295,8 +428,9 @@ func (b *Binance) GetMultipleTickers(ctx context.Context, symbols []types.Symbol
 	}
 
 	// 调用RestAPI获取Binance特定的数据
+	// 部分交易对失败时err为*types.BatchError，仍然转换并返回成功的结果
 	binanceTickers, err := b.RestAPI.GetMultipleTickers(ctx, symbolStrings)
-	if err != nil {
+	if err != nil && len(binanceTickers) == 0 {
 		return nil, err
 	}
 
@@ -315,7 +449,39 @@ func (b *Binance) GetMultipleTickers(ctx context.Context, symbols []types.Symbol
 		}
 	}
 
-	return tickers, nil
+	return tickers, err
+}
+
+// GetMultiplePrices 通过一次全量价格请求获取多个交易对的最新价格，返回的Ticker只包含价格字段，
+// 不在返回结果中的交易对记录到*types.BatchError中
+func (b *Binance) GetMultiplePrices(ctx context.Context, symbols []types.Symbol) ([]types.Ticker, error) {
+	prices, err := b.RestAPI.GetAllSymbolPrices(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	priceMap := make(map[types.Symbol]float64, len(prices))
+	for _, price := range prices {
+		priceMap[types.Symbol(price.Symbol)] = price.Price
+	}
+
+	now := time.Now()
+	tickers := make([]types.Ticker, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		price, ok := priceMap[symbol]
+		if !ok {
+			batchErr.Add(symbol, fmt.Errorf("no price data found for symbol %s", symbol))
+			continue
+		}
+		tickers = append(tickers, types.Ticker{
+			Exchange:  types.ExchangeBinance,
+			Symbol:    symbol,
+			Price:     price,
+			Timestamp: now,
+		})
+	}
+	return tickers, batchErr.ErrOrNil()
 }
 
 // GetMultipleOrderbooks 批量获取订单簿数据
@@ -327,8 +493,9 @@ func (b *Binance) GetMultipleOrderbooks(ctx context.Context, symbols []types.Sym
 	}
 
 	// 调用RestAPI获取Binance特定的数据
+	// 部分交易对失败时err为*types.BatchError，仍然转换并返回成功的结果
 	binanceOrderbooks, err := b.RestAPI.GetMultipleOrderbooks(ctx, symbolStrings, depth)
-	if err != nil {
+	if err != nil && len(binanceOrderbooks) == 0 {
 		return nil, err
 	}
 
@@ -359,7 +526,7 @@ func (b *Binance) GetMultipleOrderbooks(ctx context.Context, symbols []types.Sym
 			}
 		}
 	}
-	return orderbooks, nil
+	return orderbooks, err
 }
 
 // 辅助函数
@@ -391,6 +558,16 @@ func (b *Binance) SubscribeTicker(symbols []types.Symbol, callback types.DataCal
 	return b.WebSocket.SubscribeTicker(symbols, callback)
 }
 
+// SubscribeBookTicker 订阅最优挂单数据
+func (b *Binance) SubscribeBookTicker(symbols []types.Symbol, callback types.DataCallback) error {
+	return b.WebSocket.SubscribeBookTicker(symbols, callback)
+}
+
+// SubscribeAllTickers 订阅全市场行情数据
+func (b *Binance) SubscribeAllTickers(callback types.DataCallback) error {
+	return b.WebSocket.SubscribeAllTickers(callback)
+}
+
 // SubscribeOrderbook 订阅订单簿数据
 func (b *Binance) SubscribeOrderbook(symbols []types.Symbol, callback types.DataCallback) error {
 	return b.WebSocket.SubscribeOrderbook(symbols, callback)
@@ -421,11 +598,26 @@ func (b *Binance) WsClose() error {
 	return b.WebSocket.WsClose()
 }
 
+// WsWait 等待WebSocket后台协程退出，应在WsClose之后调用
+func (b *Binance) WsWait(ctx context.Context) error {
+	return b.WebSocket.Wait(ctx)
+}
+
 // IsWsConnected 返回WebSocket是否已连接
 func (b *Binance) IsWsConnected() bool {
 	return b.WebSocket.IsConnected()
 }
 
+// IsWsHealthy 返回WebSocket是否健康（已连接且keepalive和数据接收未超时）
+func (b *Binance) IsWsHealthy() bool {
+	return b.WebSocket.IsHealthy()
+}
+
+// WsConnState 返回WebSocket连接状态
+func (b *Binance) WsConnState() ConnState {
+	return b.WebSocket.ConnState()
+}
+
 // Subscribe 订阅WebSocket频道
 func (b *Binance) Subscribe(channels []string) error {
 	return b.WebSocket.Subscribe(channels)
@@ -436,6 +628,16 @@ func (b *Binance) Unsubscribe(channels []string) error {
 	return b.WebSocket.Unsubscribe(channels)
 }
 
+// SubscribeChannel 为WebSocket频道注册一个回调，同一频道可注册多个回调
+func (b *Binance) SubscribeChannel(channel string, callback types.DataCallback) (CallbackID, error) {
+	return b.WebSocket.SubscribeChannel(channel, callback)
+}
+
+// UnsubscribeCallback 取消WebSocket频道上的单个回调
+func (b *Binance) UnsubscribeCallback(channel string, id CallbackID) error {
+	return b.WebSocket.UnsubscribeCallback(channel, id)
+}
+
 // GetIPManagerStatus 获取IP管理器状态信息
 func (b *Binance) GetIPManagerStatus() map[string]interface{} {
 	status := make(map[string]interface{})
@@ -483,6 +685,25 @@ func (b *Binance) SubscribeOrderbookWithDepth(symbols []types.Symbol, depth int,
 	return b.WebSocket.SubscribeOrderbookWithDepth(symbols, depth, updateSpeed, callback)
 }
 
+// fetchOrderbookSnapshot 获取用于同步本地订单簿的REST快照
+func (b *Binance) fetchOrderbookSnapshot(ctx context.Context, symbol types.Symbol) (OrderBook, error) {
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, symbol)
+	if err != nil {
+		return OrderBook{}, err
+	}
+	return b.RestAPI.GetOrderbook(ctx, pair, orderbookSnapshotDepth)
+}
+
+// GetOrderbookHealth 获取交易对本地订单簿的健康状态（in_sync或resyncing），下游可据此忽略不可信的订单簿
+func (b *Binance) GetOrderbookHealth(symbol types.Symbol) (BookHealth, bool) {
+	return b.WebSocket.GetOrderbookHealth(symbol)
+}
+
+// GetOrderbookSyncStatus 获取所有本地订单簿的健康状态和序号缺口统计
+func (b *Binance) GetOrderbookSyncStatus() map[string]interface{} {
+	return b.WebSocket.GetOrderbookSyncStatus()
+}
+
 // GetActiveSubscriptions 获取当前活跃的订阅列表
 func (b *Binance) GetActiveSubscriptions() []string {
 	return b.WebSocket.GetActiveSubscriptions()
@@ -493,12 +714,44 @@ func (b *Binance) GetSubscriptionCount() int {
 	return b.WebSocket.GetSubscriptionCount()
 }
 
+// GetWsStreamStats 获取WebSocket连接数、订阅数和按流类型统计的每秒消息数
+func (b *Binance) GetWsStreamStats() map[string]interface{} {
+	return b.WebSocket.GetStreamStats()
+}
+
 // FetchTradablePairs 获取交易所可交易的交易对列表
 func (b *Binance) FetchTradablePairs(ctx context.Context, assetType asset.Item) (currency.Pairs, error) {
-	b.logger.Info("Fetching tradable pairs", zap.String("asset", assetType.String()))
+	symbols, err := b.FetchTradablePairsDetailed(ctx, assetType)
+	if err != nil {
+		return nil, err
+	}
+
+	pairs := make(currency.Pairs, 0, len(symbols))
+	for i := range symbols {
+		pairs = append(pairs, symbols[i].Pair)
+	}
+	return pairs, nil
+}
+
+// FetchTradablePairsDetailed 获取交易所可交易的交易对及其元数据（状态、精度、订单类型等）
+func (b *Binance) FetchTradablePairsDetailed(ctx context.Context, assetType asset.Item) ([]SymbolInfo, error) {
 	if b.RestAPI == nil {
 		return nil, fmt.Errorf("REST API not initialized")
 	}
+	b.logger.Info("Fetching tradable pairs", zap.String("asset", assetType.String()))
+
+	// 根据资产类型确定过滤条件
+	var allowed func(isSpot, isMargin bool) bool
+	switch assetType {
+	case asset.Spot:
+		// 只返回允许现货交易的交易对
+		allowed = func(isSpot, _ bool) bool { return isSpot }
+	case asset.Margin, asset.CrossMargin:
+		// 只返回允许保证金交易的交易对
+		allowed = func(_, isMargin bool) bool { return isMargin }
+	default:
+		return nil, fmt.Errorf("unsupported asset type: %v", assetType)
+	}
 
 	// 获取交易所信息
 	exchangeInfo, err := b.RestAPI.GetExchangeInfo(ctx)
@@ -508,45 +761,45 @@ func (b *Binance) FetchTradablePairs(ctx context.Context, assetType asset.Item)
 	b.logger.Info("Exchange info fetched", zap.Int("symbols", len(exchangeInfo.Symbols)))
 
 	tradingStatus := "TRADING"
-	var pairs []currency.Pair
-
-	switch assetType {
-	case asset.Spot:
-		pairs = make([]currency.Pair, 0, len(exchangeInfo.Symbols))
-		for _, symbol := range exchangeInfo.Symbols {
-			// 只返回状态为TRADING且允许现货交易的交易对
-			if symbol.Status != tradingStatus || !symbol.IsSpotTradingAllowed {
-				continue
-			}
-
-			pair, err := currency.NewPairFromStrings(symbol.BaseAsset, symbol.QuoteAsset)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create pair from %s/%s: %w",
-					symbol.BaseAsset, symbol.QuoteAsset, err)
-			}
-			pairs = append(pairs, pair)
+	symbols := make([]SymbolInfo, 0, len(exchangeInfo.Symbols))
+	for _, symbol := range exchangeInfo.Symbols {
+		// 只返回状态为TRADING的交易对
+		if symbol.Status != tradingStatus || !allowed(symbol.IsSpotTradingAllowed, symbol.IsMarginTradingAllowed) {
+			continue
 		}
-	case asset.Margin:
-		pairs = make([]currency.Pair, 0, len(exchangeInfo.Symbols))
-		for _, symbol := range exchangeInfo.Symbols {
-			// 只返回状态为TRADING且允许保证金交易的交易对
-			if symbol.Status != tradingStatus || !symbol.IsMarginTradingAllowed {
-				continue
-			}
 
-			pair, err := currency.NewPairFromStrings(symbol.BaseAsset, symbol.QuoteAsset)
-			if err != nil {
-				return nil, fmt.Errorf("failed to create pair from %s/%s: %w",
-					symbol.BaseAsset, symbol.QuoteAsset, err)
-			}
-			pairs = append(pairs, pair)
+		pair, err := currency.NewPairFromStrings(symbol.BaseAsset, symbol.QuoteAsset)
+		if err != nil {
+			return nil, fmt.Errorf("failed to create pair from %s/%s: %w",
+				symbol.BaseAsset, symbol.QuoteAsset, err)
 		}
-	default:
-		return nil, fmt.Errorf("unsupported asset type: %v", assetType)
-	}
 
-	b.logger.Info("Tradable pairs fetched", zap.String("asset", assetType.String()), zap.Int("count", len(pairs)))
-	return pairs, nil
+		symbols = append(symbols, SymbolInfo{
+			Symbol:                 symbol.Symbol,
+			Pair:                   pair,
+			Status:                 symbol.Status,
+			BaseAsset:              symbol.BaseAsset,
+			QuoteAsset:             symbol.QuoteAsset,
+			BaseAssetPrecision:     symbol.BaseAssetPrecision,
+			QuotePrecision:         symbol.QuotePrecision,
+			OrderTypes:             symbol.OrderTypes,
+			IsSpotTradingAllowed:   symbol.IsSpotTradingAllowed,
+			IsMarginTradingAllowed: symbol.IsMarginTradingAllowed,
+			Permissions:            symbol.Permissions,
+		})
+	}
+
+	b.logger.Info("Tradable pairs fetched", zap.String("asset", assetType.String()), zap.Int("count", len(symbols)))
+	return symbols, nil
+}
+
+// ParseSymbolViaExchangeInfo 根据exchangeInfo中的baseAsset/quoteAsset将交易对符号解析为currency.Pair，
+// 可正确拆分1000SHIBUSDT、BTCFDUSD等交易对；交易对不存在时返回types.ErrInvalidSymbol
+func (b *Binance) ParseSymbolViaExchangeInfo(ctx context.Context, symbol types.Symbol) (currency.Pair, error) {
+	if b.RestAPI == nil {
+		return currency.EMPTYPAIR, fmt.Errorf("REST API not initialized")
+	}
+	return b.RestAPI.ParseSymbolViaExchangeInfo(ctx, symbol)
 }
 
 // StartTradablePairsCache 启动交易对缓存管理器
@@ -557,6 +810,52 @@ func (b *Binance) StartTradablePairsCache(ctx context.Context) error {
 	return b.tradablePairsCache.Start(ctx)
 }
 
+// StartTimeSync 启动服务器时间偏移监控，直到ctx取消或调用Close；测得的偏移用于校正签名请求的timestamp
+func (b *Binance) StartTimeSync(ctx context.Context) error {
+	if b.RestAPI == nil {
+		return fmt.Errorf("REST API not initialized")
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	if b.timeSyncCancel != nil {
+		return fmt.Errorf("time sync already started")
+	}
+
+	interval := b.config.TimeSync.Interval
+	if interval == 0 {
+		interval = defaultTimeSyncInterval
+	}
+	b.logger.Info("Starting server time sync", zap.Duration("interval", interval))
+
+	syncCtx, cancel := context.WithCancel(ctx)
+	done := make(chan struct{})
+	b.timeSyncCancel, b.timeSyncDone = cancel, done
+	go func() {
+		defer close(done)
+		b.RestAPI.GetTimeSync().Run(syncCtx, interval)
+	}()
+	return nil
+}
+
+// stopTimeSync 停止服务器时间同步并等待协程退出
+func (b *Binance) stopTimeSync() {
+	b.mu.Lock()
+	cancel, done := b.timeSyncCancel, b.timeSyncDone
+	b.timeSyncCancel, b.timeSyncDone = nil, nil
+	b.mu.Unlock()
+
+	if cancel != nil {
+		cancel()
+		<-done
+	}
+}
+
+// GetAccount 获取现货账户信息（签名请求），可用于校验API Key和Secret
+func (b *Binance) GetAccount(ctx context.Context) (*Account, error) {
+	return b.RestAPI.GetAccount(ctx)
+}
+
 // GetTradablePairsFromCache 从缓存获取交易对
 func (b *Binance) GetTradablePairsFromCache(ctx context.Context, assetType asset.Item) (currency.Pairs, error) {
 	if b.tradablePairsCache == nil {
@@ -594,25 +893,45 @@ func (b *Binance) IsSymbolSupported(ctx context.Context, symbol currency.Pair, a
 	return b.tradablePairsCache.IsSymbolSupported(ctx, symbol, assetType)
 }
 
-// ResolveTradingPairs 解析交易对配置，支持["*"]从API获取所有交易对
-func (b *Binance) ResolveTradingPairs(ctx context.Context, symbols []string, assetType asset.Item) ([]string, error) {
+// ResolveTradingPairs 解析交易对配置，支持["*"]从API获取指定资产类型的所有交易对，
+// 展开后的交易对按filter的计价币种和包含/排除模式过滤；明确配置的交易对原样返回
+func (b *Binance) ResolveTradingPairs(ctx context.Context, symbols []string, assetType asset.Item, filter types.SymbolFilter) ([]string, error) {
 	// 如果配置为["*"]，从API获取所有交易对
 	if len(symbols) == 1 && symbols[0] == "*" {
+		if !b.IsAssetSupported(assetType) {
+			return nil, fmt.Errorf("%w: %s", asset.ErrNotSupported, assetType)
+		}
+		if err := filter.Validate(); err != nil {
+			return nil, err
+		}
+
+		var pairs currency.Pairs
+		var err error
 		if b.config.TradablePairs.FetchFromAPI && b.tradablePairsCache != nil {
 			// 从缓存获取
-			return b.tradablePairsCache.GetSupportedSymbols(ctx, assetType)
+			pairs, err = b.tradablePairsCache.GetTradablePairs(ctx, assetType)
 		} else {
 			// 直接从API获取
-			pairs, err := b.FetchTradablePairs(ctx, assetType)
-			if err != nil {
-				return nil, err
-			}
-			result := make([]string, len(pairs))
-			for i, pair := range pairs {
-				result[i] = pair.String()
-			}
-			return result, nil
+			pairs, err = b.FetchTradablePairs(ctx, assetType)
+		}
+		if err != nil {
+			return nil, err
+		}
+
+		filtered := filter.ApplyPairs(pairs)
+		if !filter.IsEmpty() {
+			b.logger.Info("Tradable pairs filtered",
+				zap.String("asset", assetType.String()),
+				zap.Strings("quote_assets", filter.QuoteAssets),
+				zap.Int("total", len(pairs)),
+				zap.Int("matched", len(filtered)))
 		}
+
+		result := make([]string, len(filtered))
+		for i, pair := range filtered {
+			result[i] = pair.String()
+		}
+		return result, nil
 	}
 
 	// 返回原始配置的交易对
@@ -625,3 +944,8 @@ func (b *Binance) ResolveTradingPairs(ctx context.Context, symbols []string, ass
 func FormatSymbol(pair currency.Pair, assetType asset.Item) (string, error) {
 	return pair.Base.String() + pair.Quote.String(), nil
 }
+
+// FormatSymbol 按Binance规则格式化交易对符号：基础币种与计价币种直接拼接（如BTCUSDT）
+func (b *Binance) FormatSymbol(pair currency.Pair) (string, error) {
+	return FormatSymbol(pair, asset.Spot)
+}
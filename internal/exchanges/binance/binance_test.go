@@ -2,9 +2,29 @@ package binance
 
 import (
 	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"go.uber.org/zap"
 
 	"github.com/mooyang-code/data-miner/internal/exchanges/asset"
+	"github.com/mooyang-code/data-miner/internal/exchanges/httpclient"
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
 )
 
 func TestFetchTradablePairs(t *testing.T) {
@@ -105,3 +125,915 @@ func TestGetExchangeInfo(t *testing.T) {
 	t.Logf("Spot trading allowed symbols: %d", spotCount)
 	t.Logf("Margin trading allowed symbols: %d", marginCount)
 }
+
+func TestTimeSyncOffset(t *testing.T) {
+	ts := NewTimeSync(func(ctx context.Context) error { return nil }, 5*time.Second)
+
+	// 服务器时钟比本地快10秒，往返耗时200ms
+	sentAt := time.Now()
+	receivedAt := sentAt.Add(200 * time.Millisecond)
+	serverTime := sentAt.Add(100*time.Millisecond + 10*time.Second).UnixMilli()
+	ts.Record(serverTime, sentAt, receivedAt)
+
+	if offset := ts.Offset(); offset < 9990*time.Millisecond || offset > 10010*time.Millisecond {
+		t.Errorf("Expected offset around 10s, got %v", offset)
+	}
+
+	status := ts.GetStatus()
+	if exceeds, _ := status["exceeds_recv_window"].(bool); !exceeds {
+		t.Error("Expected offset to exceed recvWindow")
+	}
+
+	params := ts.SignParams(url.Values{"symbol": {"BTCUSDT"}}, "secret")
+	timestamp := params.Get("timestamp")
+	if timestamp == "" || params.Get("recvWindow") != "5000" || len(params.Get("signature")) != 64 {
+		t.Errorf("Unexpected signed params: %v", params)
+	}
+	if ts.Timestamp()-time.Now().UnixMilli() < 9900 {
+		t.Error("Expected timestamp to be corrected by the server time offset")
+	}
+}
+
+func TestParseSymbolViaExchangeInfo(t *testing.T) {
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[
+		{"symbol":"1000SHIBUSDT","status":"TRADING","baseAsset":"1000SHIB","quoteAsset":"USDT"},
+		{"symbol":"BTCFDUSD","status":"TRADING","baseAsset":"BTC","quoteAsset":"FDUSD"},
+		{"symbol":"ETHBUSD","status":"BREAK","baseAsset":"ETH","quoteAsset":"BUSD"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+
+	index := newSymbolIndex()
+	refreshes := 0
+	refresh := func(context.Context) error {
+		refreshes++
+		index.update(info)
+		return nil
+	}
+
+	tests := map[types.Symbol][2]string{
+		"1000SHIBUSDT": {"1000SHIB", "USDT"},
+		"btc/fdusd":    {"BTC", "FDUSD"},
+		"ETHBUSD":      {"ETH", "BUSD"},
+	}
+	for symbol, want := range tests {
+		pair, err := index.resolve(context.Background(), symbol, refresh)
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", symbol, err)
+		}
+		if pair.Base.Upper().String() != want[0] || pair.Quote.Upper().String() != want[1] {
+			t.Errorf("%s: got %s/%s, want %s/%s", symbol, pair.Base, pair.Quote, want[0], want[1])
+		}
+	}
+	if refreshes != 1 {
+		t.Errorf("expected exchange info to be fetched once, got %d", refreshes)
+	}
+
+	if _, err := index.resolve(context.Background(), "FOOBAR", refresh); !errors.Is(err, types.ErrInvalidSymbol) {
+		t.Errorf("expected ErrInvalidSymbol for unlisted symbol, got %v", err)
+	}
+
+	// exchangeInfo不可用时退化为按字符串规则拆分，且在重试间隔内不再重复请求
+	offline := newSymbolIndex()
+	failures := 0
+	failingRefresh := func(context.Context) error {
+		failures++
+		return errors.New("offline")
+	}
+	for i := 0; i < 3; i++ {
+		pair, err := offline.resolve(context.Background(), "BTCUSDT", failingRefresh)
+		if err != nil || pair.Base.String() != "BTC" || pair.Quote.String() != "USDT" {
+			t.Errorf("expected heuristic fallback, got %v (err: %v)", pair, err)
+		}
+	}
+	if failures != 1 {
+		t.Errorf("expected a failed exchange info refresh to back off, got %d attempts", failures)
+	}
+
+	// 重试间隔过后重新获取
+	offline.refreshFailed = time.Now().Add(-symbolRefreshRetryInterval)
+	pair, err := offline.resolve(context.Background(), "1000SHIBUSDT", func(context.Context) error {
+		offline.update(info)
+		return nil
+	})
+	if err != nil || pair.Base.Upper().String() != "1000SHIB" {
+		t.Errorf("expected exchange info to be refetched after the retry interval, got %v (err: %v)", pair, err)
+	}
+}
+
+func TestRecordUsedWeight(t *testing.T) {
+	api := &BinanceRestAPI{}
+	if _, updatedAt := api.GetUsedWeight(); !updatedAt.IsZero() {
+		t.Fatal("expected no weight recorded initially")
+	}
+
+	api.recordUsedWeight(&httpclient.Response{Headers: map[string]string{"X-Mbx-Used-Weight-1m": "42"}})
+	weight, updatedAt := api.GetUsedWeight()
+	if weight != 42 || updatedAt.IsZero() {
+		t.Errorf("expected weight 42 to be recorded, got %d at %v", weight, updatedAt)
+	}
+
+	// 没有权重响应头时保留之前的记录
+	api.recordUsedWeight(&httpclient.Response{Headers: map[string]string{}})
+	if weight, _ := api.GetUsedWeight(); weight != 42 {
+		t.Errorf("expected weight to remain 42, got %d", weight)
+	}
+}
+
+func TestNormalizeOrderbookDepth(t *testing.T) {
+	tests := []struct {
+		limit  int
+		depth  int
+		weight int
+	}{
+		{0, 100, 5},
+		{5, 5, 5},
+		{7, 10, 5},
+		{100, 100, 5},
+		{200, 500, 25},
+		{1000, 1000, 50},
+		{5000, 5000, 250},
+		{10000, 5000, 250},
+	}
+	for _, tt := range tests {
+		if depth := NormalizeOrderbookDepth(tt.limit); depth != tt.depth {
+			t.Errorf("NormalizeOrderbookDepth(%d) = %d; want %d", tt.limit, depth, tt.depth)
+		}
+		if weight := OrderbookDepthWeight(tt.limit); weight != tt.weight {
+			t.Errorf("OrderbookDepthWeight(%d) = %d; want %d", tt.limit, weight, tt.weight)
+		}
+	}
+}
+
+func TestGetKlinesRejectsInvalidInterval(t *testing.T) {
+	// 无效的周期在请求交易所之前被拒绝
+	api := &BinanceRestAPI{}
+	_, err := api.GetKlinesForSymbol(context.Background(), "BTCUSDT", "1hr", 10)
+	if err == nil || !strings.Contains(err.Error(), "valid values") {
+		t.Fatalf("Expected invalid interval error listing valid values, got %v", err)
+	}
+	_, err = api.GetKlinesForSymbolSince(context.Background(), "BTCUSDT", "1H", time.Now(), 10)
+	if err == nil || !strings.Contains(err.Error(), `"1H"`) {
+		t.Errorf("Expected invalid interval error for 1H, got %v", err)
+	}
+}
+
+func TestGetKlinesDropsFormingCandle(t *testing.T) {
+	// 返回3根1分钟K线，最后一根尚未收盘
+	minute := time.Now().Truncate(time.Minute)
+	var limits []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		limits = append(limits, r.URL.Query().Get("limit"))
+		var rows []string
+		for i := -2; i <= 0; i++ {
+			open := minute.Add(time.Duration(i) * time.Minute)
+			rows = append(rows, fmt.Sprintf(`[%d,"1","1","1","1","1",%d,"1",1,"1","1"]`,
+				open.UnixMilli(), open.Add(time.Minute).UnixMilli()-1))
+		}
+		w.Write([]byte("[" + strings.Join(rows, ",") + "]"))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.SetBaseURL(server.URL)
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[{"symbol":"BTCUSDT","baseAsset":"BTC","quoteAsset":"USDT"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+	api.symbols.update(info)
+
+	// 默认只返回已收盘K线，多请求一根以补足数量
+	klines, err := api.GetKlinesForSymbol(context.Background(), "BTCUSDT", "1m", 2)
+	if err != nil {
+		t.Fatalf("GetKlinesForSymbol returned error: %v", err)
+	}
+	if len(klines) != 2 || !klines[1].OpenTime.Equal(minute.Add(-time.Minute)) {
+		t.Errorf("expected the 2 closed klines, got %+v", klines)
+	}
+	if limits[0] != "3" {
+		t.Errorf("expected limit+1 to be requested, got limit=%s", limits[0])
+	}
+
+	// include_live时保留未收盘的K线
+	api.config.DataTypes.Klines.IncludeLive = true
+	klines, err = api.GetKlinesForSymbol(context.Background(), "BTCUSDT", "1m", 2)
+	if err != nil {
+		t.Fatalf("GetKlinesForSymbol returned error: %v", err)
+	}
+	if len(klines) != 2 || !klines[1].OpenTime.Equal(minute) || limits[1] != "2" {
+		t.Errorf("expected live kline to be kept with limit=2, got %d klines (limit=%s)", len(klines), limits[1])
+	}
+
+	// 按服务器时间判断是否收盘
+	closed := completedKlines(klines, minute.Add(time.Minute))
+	if len(closed) != 2 {
+		t.Errorf("expected all klines closed once server time passes the close time, got %d", len(closed))
+	}
+}
+
+func TestGetMultipleOrderbooksConcurrent(t *testing.T) {
+	var inFlight, maxInFlight int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		current := atomic.AddInt32(&inFlight, 1)
+		defer atomic.AddInt32(&inFlight, -1)
+		for {
+			peak := atomic.LoadInt32(&maxInFlight)
+			if current <= peak || atomic.CompareAndSwapInt32(&maxInFlight, peak, current) {
+				break
+			}
+		}
+		time.Sleep(20 * time.Millisecond)
+		w.Write([]byte(`{"lastUpdateId":1,"bids":[["100","1"]],"asks":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.config.DataTypes.Orderbook.Concurrency = 2
+	api.SetBaseURL(server.URL)
+
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[
+		{"symbol":"BTCUSDT","baseAsset":"BTC","quoteAsset":"USDT"},
+		{"symbol":"ETHUSDT","baseAsset":"ETH","quoteAsset":"USDT"},
+		{"symbol":"BNBUSDT","baseAsset":"BNB","quoteAsset":"USDT"},
+		{"symbol":"SOLUSDT","baseAsset":"SOL","quoteAsset":"USDT"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+	api.symbols.update(info)
+
+	// 未上架的交易对失败不应影响其他交易对
+	symbols := []string{"BTCUSDT", "ETHUSDT", "XYZUSDT", "BNBUSDT", "SOLUSDT"}
+	orderbooks, err := api.GetMultipleOrderbooks(context.Background(), symbols, 5)
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || !errors.Is(err, types.ErrInvalidSymbol) {
+		t.Fatalf("expected BatchError wrapping ErrInvalidSymbol, got %v", err)
+	}
+	if _, ok := batchErr.Errors["XYZUSDT"]; !ok || len(batchErr.Errors) != 1 {
+		t.Errorf("expected only XYZUSDT to fail, got %v", batchErr.Errors)
+	}
+	if len(orderbooks) != 4 {
+		t.Fatalf("expected 4 orderbooks, got %d", len(orderbooks))
+	}
+	for i, want := range []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "SOLUSDT"} {
+		if got := types.Symbol(orderbooks[i].Symbol).Normalize(); got != types.Symbol(want) {
+			t.Errorf("expected %s at index %d, got %s", want, i, got)
+		}
+	}
+	if peak := atomic.LoadInt32(&maxInFlight); peak != 2 {
+		t.Errorf("expected 2 concurrent requests, got %d", peak)
+	}
+}
+
+func TestGetMultipleOrderbooksCancel(t *testing.T) {
+	var requests int32
+	started := make(chan struct{}, 1)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		select {
+		case started <- struct{}{}:
+		default:
+		}
+		// 模拟慢请求，直到客户端取消
+		select {
+		case <-r.Context().Done():
+		case <-time.After(5 * time.Second):
+		}
+		w.Write([]byte(`{"lastUpdateId":1,"bids":[],"asks":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.config.DataTypes.Orderbook.Concurrency = 2
+	api.SetBaseURL(server.URL)
+
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[{"symbol":"BTCUSDT","baseAsset":"BTC","quoteAsset":"USDT"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+	api.symbols.update(info)
+
+	symbols := make([]string, 20)
+	for i := range symbols {
+		symbols[i] = "BTCUSDT"
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	go func() {
+		<-started
+		cancel()
+	}()
+
+	start := time.Now()
+	orderbooks, err := api.GetMultipleOrderbooks(ctx, symbols, 5)
+	if !errors.Is(err, context.Canceled) {
+		t.Fatalf("expected context.Canceled, got %v", err)
+	}
+	if orderbooks != nil {
+		t.Errorf("expected no orderbooks after cancellation, got %d", len(orderbooks))
+	}
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("expected cancellation to return promptly, took %v", elapsed)
+	}
+	if n := atomic.LoadInt32(&requests); n > 2 {
+		t.Errorf("expected no new requests after cancellation, got %d", n)
+	}
+}
+
+func TestGetMultipleTickersUsesSymbolsParam(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("symbols"))
+		// Respond with an extra symbol to make sure the result is filtered.
+		w.Write([]byte(`[{"symbol":"ETHUSDT","lastPrice":"2200"},{"symbol":"BTCUSDT","lastPrice":"42000"},{"symbol":"BNBUSDT","lastPrice":"300"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.SetBaseURL(server.URL)
+
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[
+		{"symbol":"BTCUSDT","baseAsset":"BTC","quoteAsset":"USDT"},
+		{"symbol":"ETHUSDT","baseAsset":"ETH","quoteAsset":"USDT"},
+		{"symbol":"SOLUSDT","baseAsset":"SOL","quoteAsset":"USDT"},
+		{"symbol":"BNBUSDT","baseAsset":"BNB","quoteAsset":"USDT"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+	api.symbols.update(info)
+
+	tickers, err := api.GetMultipleTickers(context.Background(), []string{"BTCUSDT", "ETHUSDT", "SOLUSDT"})
+	if len(queries) != 1 || queries[0] != `["BTCUSDT","ETHUSDT","SOLUSDT"]` {
+		t.Fatalf("expected a single request with the symbols array, got %q", queries)
+	}
+
+	// SOLUSDT is missing from the response and BNBUSDT was not requested.
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 || batchErr.Errors["SOLUSDT"] == nil {
+		t.Fatalf("expected SOLUSDT to be reported as missing, got %v", err)
+	}
+	if len(tickers) != 2 || tickers[0].Symbol != "BTCUSDT" || tickers[1].Symbol != "ETHUSDT" {
+		t.Errorf("expected BTCUSDT and ETHUSDT in request order, got %+v", tickers)
+	}
+}
+
+func TestMonitoringRequestsSkipRateLimit(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set(usedWeightHeader, "7")
+		w.Write([]byte(`{"serverTime":1700000000000}`))
+	}))
+	defer server.Close()
+
+	config := httpclient.DefaultConfig("binance-test")
+	config.RateLimit = &httpclient.RateLimitConfig{Enabled: true, RequestsPerMinute: 1}
+	client, err := httpclient.New(config)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.SetBaseURL(server.URL)
+
+	// 服务器时间和权重检查不占用速率限制配额
+	for i := 0; i < 3; i++ {
+		serverTime, weight, err := api.GetTimeAndWeight(context.Background())
+		if err != nil {
+			t.Fatalf("GetTimeAndWeight failed: %v", err)
+		}
+		if serverTime != 1700000000000 || weight != 7 {
+			t.Errorf("unexpected time/weight: %d/%d", serverTime, weight)
+		}
+	}
+	if _, err := api.GetServerTime(context.Background()); err != nil {
+		t.Fatalf("GetServerTime failed: %v", err)
+	}
+
+	// 行情请求仍有完整的配额
+	var result map[string]interface{}
+	if err := api.SendHTTPRequest(context.Background(), "/api/v3/ticker/price", &result); err != nil {
+		t.Fatalf("expected market data request to have its budget intact: %v", err)
+	}
+}
+
+func TestGetTickerUsesSymbolParam(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.RawQuery)
+		if r.URL.Query().Get("symbol") == "SOLUSDT" {
+			w.WriteHeader(http.StatusBadRequest)
+			w.Write([]byte(`{"code":-1121,"msg":"Invalid symbol."}`))
+			return
+		}
+		w.Write([]byte(`{"symbol":"BTCUSDT","lastPrice":"42000"}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	api.SetBaseURL(server.URL)
+
+	var info ExchangeInfo
+	if err := json.Unmarshal([]byte(`{"symbols":[
+		{"symbol":"BTCUSDT","baseAsset":"BTC","quoteAsset":"USDT"},
+		{"symbol":"SOLUSDT","baseAsset":"SOL","quoteAsset":"USDT"}]}`), &info); err != nil {
+		t.Fatalf("failed to parse exchange info: %v", err)
+	}
+	api.symbols.update(info)
+
+	ticker, err := api.GetTickerBySymbol(context.Background(), "BTCUSDT")
+	if err != nil {
+		t.Fatalf("GetTickerBySymbol returned error: %v", err)
+	}
+	if ticker.Symbol != "BTCUSDT" || ticker.LastPrice.Float64() != 42000 {
+		t.Errorf("unexpected ticker: %+v", ticker)
+	}
+	if len(queries) != 1 || queries[0] != "symbol=BTCUSDT" {
+		t.Fatalf("expected a single request with the symbol param, got %q", queries)
+	}
+
+	// An invalid symbol is reported clearly and is not retried.
+	queries = nil
+	if _, err := api.GetTickerBySymbol(context.Background(), "SOLUSDT"); !errors.Is(err, types.ErrInvalidSymbol) {
+		t.Errorf("expected ErrInvalidSymbol, got %v", err)
+	}
+	if len(queries) != 1 {
+		t.Errorf("expected the invalid symbol request not to be retried, got %d requests", len(queries))
+	}
+}
+
+func TestUserDataStreamRequests(t *testing.T) {
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != userAccountStream || r.Header.Get("X-MBX-APIKEY") != "test-key" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		methods = append(methods, r.Method)
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"listenKey":"listen-key"}`))
+			return
+		}
+		if r.URL.Query().Get("listenKey") != "listen-key" {
+			t.Errorf("missing listenKey in %s request", r.Method)
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client}
+	api.SetBaseURL(server.URL)
+	if _, err := api.StartUserDataStream(context.Background()); !errors.Is(err, errAPIKeyRequired) {
+		t.Fatalf("expected errAPIKeyRequired without API key, got %v", err)
+	}
+
+	api.config.APIKey = "test-key"
+	ctx := context.Background()
+	listenKey, err := api.StartUserDataStream(ctx)
+	if err != nil || listenKey != "listen-key" {
+		t.Fatalf("StartUserDataStream = %q, %v", listenKey, err)
+	}
+	if err := api.KeepAliveUserDataStream(ctx, listenKey); err != nil {
+		t.Fatalf("KeepAliveUserDataStream failed: %v", err)
+	}
+	if err := api.CloseUserDataStream(ctx, listenKey); err != nil {
+		t.Fatalf("CloseUserDataStream failed: %v", err)
+	}
+	if len(methods) != 3 || methods[0] != http.MethodPost || methods[1] != http.MethodPut || methods[2] != http.MethodDelete {
+		t.Errorf("unexpected request methods: %v", methods)
+	}
+}
+
+func TestSubscribeUserDataClosesListenKeyOnFailure(t *testing.T) {
+	var mu sync.Mutex
+	var methods []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		methods = append(methods, r.Method)
+		mu.Unlock()
+		if r.Method == http.MethodPost {
+			w.Write([]byte(`{"listenKey":"listen-key"}`))
+			return
+		}
+		w.Write([]byte(`{}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	b := New()
+	b.RestAPI.httpClient = client
+	b.RestAPI.config.APIKey = "test-key"
+	b.RestAPI.SetBaseURL(server.URL)
+
+	// WebSocket未连接时订阅失败，刚创建的listenKey应被关闭
+	if err := b.SubscribeUserData(context.Background(), func(interface{}) error { return nil }); err == nil {
+		t.Fatal("expected SubscribeUserData to fail without a WebSocket connection")
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if len(methods) != 2 || methods[0] != http.MethodPost || methods[1] != http.MethodDelete {
+		t.Errorf("expected the listenKey to be created and closed, got %v", methods)
+	}
+	if b.userDataCancel != nil || b.userDataKey != "" {
+		t.Error("expected no keep-alive to be started after a failed subscription")
+	}
+}
+
+func TestSignedRequestUsesTimeSync(t *testing.T) {
+	var rawQuery string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != accountInfo || r.Header.Get("X-MBX-APIKEY") != "test-key" {
+			t.Errorf("unexpected request: %s %s", r.Method, r.URL)
+		}
+		rawQuery = r.URL.RawQuery
+		w.Write([]byte(`{"canTrade":true,"balances":[]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	api := &BinanceRestAPI{httpClient: client, timeSync: NewTimeSync(func(context.Context) error { return nil }, 0)}
+	api.SetBaseURL(server.URL)
+	if _, err := api.GetAccount(context.Background()); !errors.Is(err, errAPISecretRequired) {
+		t.Fatalf("expected errAPISecretRequired without credentials, got %v", err)
+	}
+
+	// 服务器时钟比本地快10秒
+	sentAt := time.Now()
+	api.timeSync.Record(sentAt.Add(10*time.Second).UnixMilli(), sentAt, sentAt)
+	api.config.APIKey, api.config.APISecret = "test-key", "secret"
+	account, err := api.GetAccount(context.Background())
+	if err != nil || !account.CanTrade {
+		t.Fatalf("GetAccount = %+v, %v", account, err)
+	}
+
+	// signature放在最后，签名内容为之前的全部参数
+	unsigned, signature, ok := strings.Cut(rawQuery, "&signature=")
+	if !ok {
+		t.Fatalf("expected signature as the last parameter, got %q", rawQuery)
+	}
+	mac := hmac.New(sha256.New, []byte("secret"))
+	mac.Write([]byte(unsigned))
+	if want := hex.EncodeToString(mac.Sum(nil)); signature != want {
+		t.Errorf("signature %s does not match %s", signature, want)
+	}
+	params, _ := url.ParseQuery(unsigned)
+	timestamp, _ := strconv.ParseInt(params.Get("timestamp"), 10, 64)
+	if timestamp-time.Now().UnixMilli() < 9900 {
+		t.Errorf("expected timestamp corrected by the server time offset, got %d", timestamp)
+	}
+}
+
+func TestCloseStopsTimeSync(t *testing.T) {
+	var syncs atomic.Int32
+	b := New()
+	b.SetLogger(zap.NewNop())
+	b.RestAPI.timeSync = NewTimeSync(func(context.Context) error {
+		syncs.Add(1)
+		return nil
+	}, 0)
+	b.config.TimeSync.Interval = 10 * time.Millisecond
+
+	if err := b.StartTimeSync(context.Background()); err != nil {
+		t.Fatalf("StartTimeSync failed: %v", err)
+	}
+	if err := b.StartTimeSync(context.Background()); err == nil {
+		t.Error("expected error when time sync is already running")
+	}
+	time.Sleep(50 * time.Millisecond)
+	if err := b.Close(); err != nil {
+		t.Fatalf("Close failed: %v", err)
+	}
+
+	// Close等待同步协程退出，之后不再同步
+	stopped := syncs.Load()
+	time.Sleep(50 * time.Millisecond)
+	if stopped == 0 || syncs.Load() != stopped {
+		t.Errorf("expected time sync to stop on Close, syncs before=%d after=%d", stopped, syncs.Load())
+	}
+}
+
+func TestTradablePairsCacheRefreshMetrics(t *testing.T) {
+	cache := NewTradablePairsCache(New(), zap.NewNop(), TradablePairsCacheConfig{CacheTTL: time.Hour})
+
+	btc := currency.NewPair(currency.BTC, currency.USDT)
+	eth := currency.NewPair(currency.ETH, currency.USDT)
+	bnb := currency.NewPair(currency.BNB, currency.USDT)
+
+	// Initial load does not count as listing churn.
+	if added, removed := cache.recordRefresh(asset.Spot, time.Now(), currency.Pairs{btc, eth}, nil); added != 0 || removed != 0 {
+		t.Errorf("initial load: got added=%d removed=%d, want 0/0", added, removed)
+	}
+	cache.recordRefresh(asset.Spot, time.Now(), nil, errors.New("timeout"))
+	cache.recordRefresh(asset.Spot, time.Now(), nil, errors.New("timeout"))
+	cache.recordRefresh(asset.Margin, time.Now(), nil, errors.New("forbidden"))
+
+	spot := cache.GetCacheStats()["assets"].(map[string]interface{})["spot"].(map[string]interface{})
+	if spot["consecutive_failures"] != 2 || spot["failure_count"] != int64(2) || spot["refresh_count"] != int64(3) {
+		t.Errorf("unexpected failure metrics: %v", spot)
+	}
+	if spot["last_error"] != "timeout" || spot["count"] != 2 {
+		t.Errorf("failed refresh should keep cached pairs and record the error: %v", spot)
+	}
+
+	// A successful refresh resets consecutive failures and reports the diff.
+	if added, removed := cache.recordRefresh(asset.Spot, time.Now(), currency.Pairs{eth, bnb}, nil); added != 1 || removed != 1 {
+		t.Errorf("got added=%d removed=%d, want 1/1", added, removed)
+	}
+	assets := cache.GetCacheStats()["assets"].(map[string]interface{})
+	spot = assets["spot"].(map[string]interface{})
+	if spot["consecutive_failures"] != 0 || spot["pairs_added"] != 1 || spot["pairs_removed"] != 1 || spot["last_error"] != "" {
+		t.Errorf("unexpected metrics after recovery: %v", spot)
+	}
+
+	// Assets that never refreshed successfully are still reported.
+	margin, ok := assets["margin"].(map[string]interface{})
+	if !ok || margin["consecutive_failures"] != 1 || margin["expired"] != true {
+		t.Errorf("unexpected margin metrics: %v", assets["margin"])
+	}
+
+	// The same metrics are exported to Prometheus; a hit is counted for a cached lookup.
+	if _, err := cache.GetTradablePairs(context.Background(), asset.Spot); err != nil {
+		t.Fatalf("GetTradablePairs returned error: %v", err)
+	}
+	b := New()
+	b.tradablePairsCache = cache
+	expected := `
+# HELP data_miner_binance_pairs_cache_consecutive_failures Consecutive failed refreshes, reset on success.
+# TYPE data_miner_binance_pairs_cache_consecutive_failures gauge
+data_miner_binance_pairs_cache_consecutive_failures{asset="margin"} 1
+data_miner_binance_pairs_cache_consecutive_failures{asset="spot"} 0
+# HELP data_miner_binance_pairs_cache_hits_total Lookups served from an unexpired cache entry.
+# TYPE data_miner_binance_pairs_cache_hits_total counter
+data_miner_binance_pairs_cache_hits_total{asset="margin"} 0
+data_miner_binance_pairs_cache_hits_total{asset="spot"} 1
+# HELP data_miner_binance_pairs_cache_pairs_removed_total Pairs removed across all refreshes (delistings).
+# TYPE data_miner_binance_pairs_cache_pairs_removed_total counter
+data_miner_binance_pairs_cache_pairs_removed_total{asset="margin"} 0
+data_miner_binance_pairs_cache_pairs_removed_total{asset="spot"} 1
+# HELP data_miner_binance_pairs_cache_refresh_failures_total Failed cache refreshes.
+# TYPE data_miner_binance_pairs_cache_refresh_failures_total counter
+data_miner_binance_pairs_cache_refresh_failures_total{asset="margin"} 1
+data_miner_binance_pairs_cache_refresh_failures_total{asset="spot"} 2
+`
+	if err := testutil.CollectAndCompare(b.MetricsCollector(), strings.NewReader(expected),
+		"data_miner_binance_pairs_cache_consecutive_failures", "data_miner_binance_pairs_cache_hits_total",
+		"data_miner_binance_pairs_cache_pairs_removed_total", "data_miner_binance_pairs_cache_refresh_failures_total"); err != nil {
+		t.Errorf("unexpected cache metrics: %v", err)
+	}
+	// The last success timestamp is only reported once an asset has refreshed successfully.
+	if n := testutil.CollectAndCount(b.MetricsCollector(), "data_miner_binance_pairs_cache_last_success_timestamp_seconds"); n != 1 {
+		t.Errorf("expected last success timestamp for spot only, got %d series", n)
+	}
+}
+
+func TestIsSymbolSupportedRefreshesOnMiss(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"symbols":[
+			{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","isSpotTradingAllowed":true},
+			{"symbol":"NEWUSDT","status":"TRADING","baseAsset":"NEW","quoteAsset":"USDT","isSpotTradingAllowed":true}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	b := New()
+	b.RestAPI = &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	b.RestAPI.SetBaseURL(server.URL)
+
+	btc := currency.NewPair(currency.BTC, currency.USDT)
+	newPair := currency.NewPair(currency.NewCode("NEW"), currency.USDT)
+	unknown := currency.NewPair(currency.NewCode("XYZ"), currency.USDT)
+	ctx := context.Background()
+
+	// Synchronous recheck picks up a freshly listed symbol.
+	cache := NewTradablePairsCache(b, zap.NewNop(), TradablePairsCacheConfig{CacheTTL: time.Hour, RecheckOnMiss: true})
+	cache.recordRefresh(asset.Spot, time.Now(), currency.Pairs{btc}, nil)
+	if ok, err := cache.IsSymbolSupported(ctx, newPair, asset.Spot); err != nil || !ok {
+		t.Fatalf("expected newly listed symbol to be supported after refresh, got %v, %v", ok, err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 refresh request, got %d", n)
+	}
+
+	// Further misses within the interval must not trigger another refresh.
+	for i := 0; i < 5; i++ {
+		if ok, _ := cache.IsSymbolSupported(ctx, unknown, asset.Spot); ok {
+			t.Error("unknown symbol should not be supported")
+		}
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected misses to be rate limited, got %d requests", n)
+	}
+
+	// Without recheck the refresh runs in the background.
+	cache = NewTradablePairsCache(b, zap.NewNop(), TradablePairsCacheConfig{CacheTTL: time.Hour})
+	cache.recordRefresh(asset.Spot, time.Now(), currency.Pairs{btc}, nil)
+	if ok, _ := cache.IsSymbolSupported(ctx, newPair, asset.Spot); ok {
+		t.Error("expected false before the background refresh completes")
+	}
+	deadline := time.Now().Add(2 * time.Second)
+	for {
+		if ok, _ := cache.IsSymbolSupported(ctx, newPair, asset.Spot); ok {
+			break
+		}
+		if time.Now().After(deadline) {
+			t.Fatal("background refresh did not pick up the new symbol")
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+	if n := atomic.LoadInt32(&requests); n != 2 {
+		t.Errorf("expected 2 refresh requests in total, got %d", n)
+	}
+}
+
+func TestTradablePairsAutoUpdateOutlivesStartContext(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","isSpotTradingAllowed":true}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	b := New()
+	b.RestAPI = &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	b.RestAPI.SetBaseURL(server.URL)
+
+	cache := NewTradablePairsCache(b, zap.NewNop(), TradablePairsCacheConfig{
+		UpdateInterval:  20 * time.Millisecond,
+		CacheTTL:        time.Hour,
+		SupportedAssets: []asset.Item{asset.Spot},
+		AutoUpdate:      true,
+	})
+
+	// The init context expires right after Start, like the initializer's 30s timeout.
+	initCtx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	if err := cache.Start(initCtx); err != nil {
+		cancel()
+		t.Fatalf("Start returned error: %v", err)
+	}
+	<-initCtx.Done()
+	cancel()
+
+	afterInit := atomic.LoadInt32(&requests)
+	deadline := time.Now().Add(2 * time.Second)
+	for atomic.LoadInt32(&requests) < afterInit+2 {
+		if time.Now().After(deadline) {
+			t.Fatalf("auto update stopped after the init context expired: %d requests", atomic.LoadInt32(&requests))
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+
+	cache.Stop()
+	time.Sleep(50 * time.Millisecond)
+	stopped := atomic.LoadInt32(&requests)
+	time.Sleep(100 * time.Millisecond)
+	if n := atomic.LoadInt32(&requests); n != stopped {
+		t.Errorf("expected no refreshes after Stop, got %d more", n-stopped)
+	}
+}
+
+func TestTradablePairsCacheSyncRefresh(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		w.Write([]byte(`{"symbols":[{"symbol":"BTCUSDT","status":"TRADING","baseAsset":"BTC","quoteAsset":"USDT","isSpotTradingAllowed":true}]}`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	b := New()
+	b.RestAPI = &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	b.RestAPI.SetBaseURL(server.URL)
+
+	// An update interval longer than the TTL is clamped so the cache is refreshed before it expires.
+	b.config.TradablePairs = types.TradablePairsConfig{UpdateInterval: 2 * time.Hour, CacheTTL: time.Hour, AutoUpdate: true}
+	if err := b.initializeTradablePairsCache(); err != nil {
+		t.Fatalf("initializeTradablePairsCache returned error: %v", err)
+	}
+	if got := b.tradablePairsCache.config.UpdateInterval; got != 30*time.Minute {
+		t.Errorf("expected update interval clamped to 30m, got %v", got)
+	}
+
+	cache := NewTradablePairsCache(b, zap.NewNop(), TradablePairsCacheConfig{CacheTTL: time.Hour})
+	cache.recordRefresh(asset.Spot, time.Now(), currency.Pairs{currency.NewPair(currency.BTC, currency.USDT)}, nil)
+	if _, err := cache.GetTradablePairs(context.Background(), asset.Spot); err != nil {
+		t.Fatalf("GetTradablePairs returned error: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 0 {
+		t.Errorf("expected cached pairs without a refresh, got %d requests", n)
+	}
+
+	// An expired entry is refreshed synchronously and counted.
+	cache.mutex.Lock()
+	cache.lastUpdate[asset.Spot] = time.Now().Add(-2 * time.Hour)
+	cache.mutex.Unlock()
+	if _, err := cache.GetTradablePairs(context.Background(), asset.Spot); err != nil {
+		t.Fatalf("GetTradablePairs returned error: %v", err)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected 1 synchronous refresh request, got %d", n)
+	}
+
+	stats := cache.GetCacheStats()
+	if stats["sync_refresh_count"] != int64(1) {
+		t.Errorf("expected sync_refresh_count 1, got %v", stats["sync_refresh_count"])
+	}
+	spot := stats["assets"].(map[string]interface{})["spot"].(map[string]interface{})
+	if spot["sync_refresh_count"] != int64(1) {
+		t.Errorf("expected spot sync_refresh_count 1, got %v", spot["sync_refresh_count"])
+	}
+}
+
+func TestGetMultiplePrices(t *testing.T) {
+	var requests int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		atomic.AddInt32(&requests, 1)
+		if r.URL.Path != "/api/v3/ticker/price" || r.URL.RawQuery != "" {
+			t.Errorf("unexpected request: %s", r.URL.String())
+		}
+		w.Write([]byte(`[{"symbol":"BTCUSDT","price":"42000.50"},{"symbol":"ETHUSDT","price":"2200.10"},{"symbol":"BNBUSDT","price":"300"}]`))
+	}))
+	defer server.Close()
+
+	client, err := NewHTTPClientWithCustomConfig(false, false)
+	if err != nil {
+		t.Fatalf("failed to create HTTP client: %v", err)
+	}
+	defer client.Close()
+
+	b := New()
+	b.RestAPI = &BinanceRestAPI{httpClient: client, symbols: newSymbolIndex()}
+	b.RestAPI.SetBaseURL(server.URL)
+
+	tickers, err := b.GetMultiplePrices(context.Background(), []types.Symbol{"BTCUSDT", "XYZUSDT", "ETHUSDT"})
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 1 {
+		t.Fatalf("expected a batch error for the missing symbol, got %v", err)
+	}
+	if len(tickers) != 2 || tickers[0].Symbol != "BTCUSDT" || tickers[0].Price != 42000.50 || tickers[1].Price != 2200.10 {
+		t.Errorf("unexpected tickers: %+v", tickers)
+	}
+	if n := atomic.LoadInt32(&requests); n != 1 {
+		t.Errorf("expected a single bulk request, got %d", n)
+	}
+}
@@ -5,6 +5,7 @@ import (
 	"context"
 	"fmt"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/avast/retry-go/v4"
@@ -16,24 +17,53 @@ import (
 
 // TradablePairsCache 交易对缓存管理器
 type TradablePairsCache struct {
-	binance    *Binance                      // Binance交易所实例
-	logger     *zap.Logger                   // 日志记录器
-	cache      map[asset.Item]currency.Pairs // 缓存数据，按资产类型分组
-	lastUpdate map[asset.Item]time.Time      // 最后更新时间
-	mutex      sync.RWMutex                  // 读写锁
-	config     TradablePairsCacheConfig      // 缓存配置
-	stopChan   chan struct{}                 // 停止信号
-	running    bool                          // 是否正在运行
+	binance    *Binance                       // Binance交易所实例
+	logger     *zap.Logger                    // 日志记录器
+	cache      map[asset.Item]currency.Pairs  // 缓存数据，按资产类型分组
+	lastUpdate map[asset.Item]time.Time       // 最后更新时间
+	metrics    map[asset.Item]*refreshMetrics // 刷新结果统计，按资产类型分组
+	missState  map[asset.Item]*missRefresh    // 查询未命中触发的刷新状态，按资产类型分组
+	mutex      sync.RWMutex                   // 读写锁
+	config     TradablePairsCacheConfig       // 缓存配置
+	cancel     context.CancelFunc             // 停止自动更新循环，Start时创建
+	running    bool                           // 是否正在运行
+}
+
+// refreshMetrics 单个资产类型的缓存刷新统计，用于监控上下架变化和发现卡住的缓存
+type refreshMetrics struct {
+	lastRefresh         time.Time     // 最近一次刷新（无论成功失败）的完成时间
+	lastDuration        time.Duration // 最近一次刷新的耗时（包含重试）
+	pairsAdded          int           // 最近一次成功刷新新增的交易对数
+	pairsRemoved        int           // 最近一次成功刷新移除的交易对数
+	pairsAddedTotal     int64         // 所有刷新累计新增的交易对数
+	pairsRemovedTotal   int64         // 所有刷新累计移除的交易对数
+	refreshCount        int64         // 刷新总次数
+	failureCount        int64         // 刷新失败总次数
+	consecutiveFailures int           // 连续失败次数，成功后清零
+	lastError           string        // 最近一次失败的错误信息
+	syncRefreshCount    int64         // 查询时缓存过期或缺失而同步刷新的次数，持续增长说明缓存未起作用
+	hitCount            atomic.Int64  // 查询直接命中未过期缓存的次数，持有读锁时累加
+}
+
+// missRefresh 查询未命中触发刷新的状态，用于限制刷新频率
+type missRefresh struct {
+	inFlight    bool      // 是否有未命中触发的刷新正在进行
+	lastTrigger time.Time // 最近一次未命中触发刷新的时间
 }
 
 // TradablePairsCacheConfig 缓存配置
 type TradablePairsCacheConfig struct {
-	UpdateInterval  time.Duration // 更新间隔
-	CacheTTL        time.Duration // 缓存生存时间
-	SupportedAssets []asset.Item  // 支持的资产类型
-	AutoUpdate      bool          // 是否自动更新
+	UpdateInterval      time.Duration // 更新间隔
+	CacheTTL            time.Duration // 缓存生存时间
+	SupportedAssets     []asset.Item  // 支持的资产类型
+	AutoUpdate          bool          // 是否自动更新
+	MissRefreshInterval time.Duration // 查询未命中时触发刷新的最小间隔，避免大量未知交易对造成刷新风暴，默认1分钟
+	RecheckOnMiss       bool          // 查询未命中时是否同步刷新并重新检查（否则在后台刷新，本次返回false）
 }
 
+// defaultMissRefreshInterval 查询未命中触发刷新的默认最小间隔
+const defaultMissRefreshInterval = time.Minute
+
 // NewTradablePairsCache 创建新的交易对缓存管理器
 func NewTradablePairsCache(binance *Binance, logger *zap.Logger, config TradablePairsCacheConfig) *TradablePairsCache {
 	return &TradablePairsCache{
@@ -41,13 +71,15 @@ func NewTradablePairsCache(binance *Binance, logger *zap.Logger, config Tradable
 		logger:     logger,
 		cache:      make(map[asset.Item]currency.Pairs),
 		lastUpdate: make(map[asset.Item]time.Time),
+		metrics:    make(map[asset.Item]*refreshMetrics),
+		missState:  make(map[asset.Item]*missRefresh),
 		config:     config,
-		stopChan:   make(chan struct{}),
 		running:    false,
 	}
 }
 
-// Start 启动缓存管理器
+// Start 启动缓存管理器，ctx只用于首次加载缓存（可以带超时）；
+// 自动更新循环使用与缓存生命周期绑定的context，不受ctx超时或取消的影响，调用Stop时退出
 func (tpc *TradablePairsCache) Start(ctx context.Context) error {
 	tpc.mutex.Lock()
 	if tpc.running {
@@ -63,14 +95,16 @@ func (tpc *TradablePairsCache) Start(ctx context.Context) error {
 	}
 	tpc.logger.Info("缓存数据初始化完成")
 
-	// 启动自动更新
+	// 启动自动更新，保留ctx中的值但脱离其截止时间
+	loopCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
 	if tpc.config.AutoUpdate {
 		tpc.logger.Info("启动自动更新循环...")
-		go tpc.autoUpdateLoop(ctx)
+		go tpc.autoUpdateLoop(loopCtx)
 	}
 
 	tpc.mutex.Lock()
 	tpc.running = true
+	tpc.cancel = cancel
 	tpc.mutex.Unlock()
 
 	tpc.logger.Info("Tradable pairs cache started",
@@ -89,7 +123,7 @@ func (tpc *TradablePairsCache) Stop() {
 		return
 	}
 
-	close(tpc.stopChan)
+	tpc.cancel()
 	tpc.running = false
 	tpc.logger.Info("Tradable pairs cache stopped")
 }
@@ -103,6 +137,9 @@ func (tpc *TradablePairsCache) GetTradablePairs(ctx context.Context, assetType a
 	lastUpdate, hasUpdate := tpc.lastUpdate[assetType]
 
 	if exists && hasUpdate && time.Since(lastUpdate) < tpc.config.CacheTTL {
+		if m := tpc.metrics[assetType]; m != nil {
+			m.hitCount.Add(1)
+		}
 		tpc.mutex.RUnlock()
 		tpc.logger.Debug("Returning cached tradable pairs",
 			zap.String("asset", assetType.String()),
@@ -116,6 +153,7 @@ func (tpc *TradablePairsCache) GetTradablePairs(ctx context.Context, assetType a
 	// 缓存过期或不存在，需要刷新
 	tpc.logger.Info("Cache expired or missing, refreshing tradable pairs",
 		zap.String("asset", assetType.String()))
+	tpc.recordSyncRefresh(assetType)
 	return tpc.refreshAsset(ctx, assetType)
 }
 
@@ -123,6 +161,7 @@ func (tpc *TradablePairsCache) GetTradablePairs(ctx context.Context, assetType a
 func (tpc *TradablePairsCache) refreshAsset(ctx context.Context, assetType asset.Item) (currency.Pairs, error) {
 	var pairs currency.Pairs
 	var lastErr error
+	start := time.Now()
 
 	// 使用 retry 库进行重试
 	err := retry.Do(
@@ -157,21 +196,93 @@ func (tpc *TradablePairsCache) refreshAsset(ctx context.Context, assetType asset
 	)
 
 	if err != nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+		tpc.recordRefresh(assetType, start, nil, lastErr)
 		return nil, fmt.Errorf("moox backend service获取 %s 交易对失败，已重试3次: %w", assetType, lastErr)
 	}
 
 	// 更新缓存
-	tpc.mutex.Lock()
-	tpc.cache[assetType] = pairs
-	tpc.lastUpdate[assetType] = time.Now()
-	tpc.mutex.Unlock()
+	added, removed := tpc.recordRefresh(assetType, start, pairs, nil)
 
 	tpc.logger.Info("交易对缓存刷新成功",
 		zap.String("asset", assetType.String()),
-		zap.Int("count", len(pairs)))
+		zap.Int("count", len(pairs)),
+		zap.Int("added", added),
+		zap.Int("removed", removed),
+		zap.Duration("duration", time.Since(start)))
 	return pairs, nil
 }
 
+// recordSyncRefresh 记录一次查询触发的同步刷新
+func (tpc *TradablePairsCache) recordSyncRefresh(assetType asset.Item) {
+	tpc.mutex.Lock()
+	defer tpc.mutex.Unlock()
+	tpc.metricsLocked(assetType).syncRefreshCount++
+}
+
+// metricsLocked 返回资产类型的刷新统计，不存在时创建（调用时需要持有写锁）
+func (tpc *TradablePairsCache) metricsLocked(assetType asset.Item) *refreshMetrics {
+	m, exists := tpc.metrics[assetType]
+	if !exists {
+		m = &refreshMetrics{}
+		tpc.metrics[assetType] = m
+	}
+	return m
+}
+
+// recordRefresh 记录一次刷新的结果，成功时更新缓存并返回相比上次新增、移除的交易对数
+func (tpc *TradablePairsCache) recordRefresh(assetType asset.Item, start time.Time, pairs currency.Pairs, err error) (added, removed int) {
+	now := time.Now()
+
+	tpc.mutex.Lock()
+	defer tpc.mutex.Unlock()
+
+	m := tpc.metricsLocked(assetType)
+	m.lastRefresh = now
+	m.lastDuration = now.Sub(start)
+	m.refreshCount++
+
+	if err != nil {
+		m.failureCount++
+		m.consecutiveFailures++
+		m.lastError = err.Error()
+		return 0, 0
+	}
+
+	// 首次加载时不计入上下架变化
+	if old, cached := tpc.cache[assetType]; cached {
+		added, removed = diffPairs(old, pairs)
+	}
+	m.pairsAdded, m.pairsRemoved = added, removed
+	m.pairsAddedTotal += int64(added)
+	m.pairsRemovedTotal += int64(removed)
+	m.consecutiveFailures = 0
+	m.lastError = ""
+
+	tpc.cache[assetType] = pairs
+	tpc.lastUpdate[assetType] = now
+	return added, removed
+}
+
+// diffPairs 统计新列表相比旧列表新增和移除的交易对数
+func diffPairs(oldPairs, newPairs currency.Pairs) (added, removed int) {
+	oldSet := make(map[string]struct{}, len(oldPairs))
+	for _, pair := range oldPairs {
+		oldSet[pair.String()] = struct{}{}
+	}
+
+	for _, pair := range newPairs {
+		if _, ok := oldSet[pair.String()]; ok {
+			delete(oldSet, pair.String())
+		} else {
+			added++
+		}
+	}
+	return added, len(oldSet)
+}
+
 // refreshAllAssets 刷新所有支持的资产类型
 func (tpc *TradablePairsCache) refreshAllAssets(ctx context.Context) error {
 	tpc.logger.Info("开始刷新所有资产类型", zap.Int("asset_count", len(tpc.config.SupportedAssets)))
@@ -215,7 +326,7 @@ func (tpc *TradablePairsCache) refreshAllAssets(ctx context.Context) error {
 	return fmt.Errorf("all asset types failed with unknown errors")
 }
 
-// autoUpdateLoop 自动更新循环
+// autoUpdateLoop 自动更新循环，ctx在Stop时取消
 func (tpc *TradablePairsCache) autoUpdateLoop(ctx context.Context) {
 	ticker := time.NewTicker(tpc.config.UpdateInterval)
 	defer ticker.Stop()
@@ -227,11 +338,8 @@ func (tpc *TradablePairsCache) autoUpdateLoop(ctx context.Context) {
 			if err := tpc.refreshAllAssets(ctx); err != nil {
 				tpc.logger.Error("Failed to auto update tradable pairs cache", zap.Error(err))
 			}
-		case <-tpc.stopChan:
-			tpc.logger.Debug("Auto update loop stopped")
-			return
 		case <-ctx.Done():
-			tpc.logger.Debug("Auto update loop cancelled")
+			tpc.logger.Debug("Auto update loop stopped")
 			return
 		}
 	}
@@ -259,24 +367,160 @@ func (tpc *TradablePairsCache) GetCacheStats() map[string]interface{} {
 		}
 		assetStats[assetType.String()] = assetInfo
 	}
+
+	// 刷新统计，包括从未成功刷新过的资产类型
+	var syncRefreshCount int64
+	for assetType, m := range tpc.metrics {
+		assetInfo, exists := assetStats[assetType.String()].(map[string]interface{})
+		if !exists {
+			assetInfo = map[string]interface{}{"count": 0, "expired": true}
+			assetStats[assetType.String()] = assetInfo
+		}
+		assetInfo["last_refresh"] = m.lastRefresh
+		assetInfo["last_refresh_duration"] = m.lastDuration.String()
+		assetInfo["pairs_added"] = m.pairsAdded
+		assetInfo["pairs_removed"] = m.pairsRemoved
+		assetInfo["refresh_count"] = m.refreshCount
+		assetInfo["failure_count"] = m.failureCount
+		assetInfo["consecutive_failures"] = m.consecutiveFailures
+		assetInfo["last_error"] = m.lastError
+		assetInfo["sync_refresh_count"] = m.syncRefreshCount
+		assetInfo["hit_count"] = m.hitCount.Load()
+		syncRefreshCount += m.syncRefreshCount
+	}
 	stats["assets"] = assetStats
+	stats["sync_refresh_count"] = syncRefreshCount
 
 	return stats
 }
 
-// IsSymbolSupported 检查指定交易对是否被支持
+// assetCacheMetrics 单个资产类型的缓存统计快照，用于导出Prometheus指标
+type assetCacheMetrics struct {
+	asset               string
+	pairs               int
+	lastUpdate          time.Time // 最近一次成功刷新的时间，从未成功时为零值
+	lastRefresh         time.Time
+	lastDuration        time.Duration
+	pairsAdded          int
+	pairsRemoved        int
+	pairsAddedTotal     int64
+	pairsRemovedTotal   int64
+	refreshCount        int64
+	failureCount        int64
+	consecutiveFailures int
+	hits                int64
+	misses              int64 // 缓存过期或缺失而同步刷新的次数
+}
+
+// metricsSnapshot 返回各资产类型的缓存统计快照
+func (tpc *TradablePairsCache) metricsSnapshot() []assetCacheMetrics {
+	tpc.mutex.RLock()
+	defer tpc.mutex.RUnlock()
+
+	result := make([]assetCacheMetrics, 0, len(tpc.metrics))
+	for assetType, m := range tpc.metrics {
+		result = append(result, assetCacheMetrics{
+			asset:               assetType.String(),
+			pairs:               len(tpc.cache[assetType]),
+			lastUpdate:          tpc.lastUpdate[assetType],
+			lastRefresh:         m.lastRefresh,
+			lastDuration:        m.lastDuration,
+			pairsAdded:          m.pairsAdded,
+			pairsRemoved:        m.pairsRemoved,
+			pairsAddedTotal:     m.pairsAddedTotal,
+			pairsRemovedTotal:   m.pairsRemovedTotal,
+			refreshCount:        m.refreshCount,
+			failureCount:        m.failureCount,
+			consecutiveFailures: m.consecutiveFailures,
+			hits:                m.hitCount.Load(),
+			misses:              m.syncRefreshCount,
+		})
+	}
+	return result
+}
+
+// IsSymbolSupported 检查指定交易对是否被支持。缓存中找不到时（可能是新上架的交易对）触发一次限频的刷新，
+// 启用RecheckOnMiss时同步刷新后重新检查，否则在后台刷新，本次返回false
 func (tpc *TradablePairsCache) IsSymbolSupported(ctx context.Context, symbol currency.Pair, assetType asset.Item) (bool, error) {
 	pairs, err := tpc.GetTradablePairs(ctx, assetType)
 	if err != nil {
 		return false, err
 	}
+	if containsPair(pairs, symbol) {
+		return true, nil
+	}
+
+	if !tpc.tryStartMissRefresh(assetType) {
+		return false, nil
+	}
+	tpc.logger.Info("交易对不在缓存中，触发刷新",
+		zap.String("symbol", symbol.String()),
+		zap.String("asset", assetType.String()),
+		zap.Bool("recheck", tpc.config.RecheckOnMiss))
+
+	if !tpc.config.RecheckOnMiss {
+		go func() {
+			refreshCtx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+			defer cancel()
+			defer tpc.finishMissRefresh(assetType)
+			if _, err := tpc.refreshAsset(refreshCtx, assetType); err != nil {
+				tpc.logger.Warn("未命中触发的交易对刷新失败",
+					zap.String("asset", assetType.String()),
+					zap.Error(err))
+			}
+		}()
+		return false, nil
+	}
 
+	defer tpc.finishMissRefresh(assetType)
+	pairs, err = tpc.refreshAsset(ctx, assetType)
+	if err != nil {
+		return false, err
+	}
+	return containsPair(pairs, symbol), nil
+}
+
+// tryStartMissRefresh 检查是否允许由查询未命中触发刷新：同一资产类型同时只允许一个刷新，
+// 且两次触发之间至少间隔MissRefreshInterval。允许时标记刷新开始
+func (tpc *TradablePairsCache) tryStartMissRefresh(assetType asset.Item) bool {
+	interval := tpc.config.MissRefreshInterval
+	if interval <= 0 {
+		interval = defaultMissRefreshInterval
+	}
+
+	tpc.mutex.Lock()
+	defer tpc.mutex.Unlock()
+
+	state, exists := tpc.missState[assetType]
+	if !exists {
+		state = &missRefresh{}
+		tpc.missState[assetType] = state
+	}
+	if state.inFlight || (!state.lastTrigger.IsZero() && time.Since(state.lastTrigger) < interval) {
+		return false
+	}
+	state.inFlight = true
+	state.lastTrigger = time.Now()
+	return true
+}
+
+// finishMissRefresh 标记未命中触发的刷新已结束
+func (tpc *TradablePairsCache) finishMissRefresh(assetType asset.Item) {
+	tpc.mutex.Lock()
+	defer tpc.mutex.Unlock()
+	if state, exists := tpc.missState[assetType]; exists {
+		state.inFlight = false
+	}
+}
+
+// containsPair 检查交易对列表中是否包含指定交易对
+func containsPair(pairs currency.Pairs, symbol currency.Pair) bool {
 	for _, pair := range pairs {
 		if pair.Equal(symbol) {
-			return true, nil
+			return true
 		}
 	}
-	return false, nil
+	return false
 }
 
 // GetSupportedSymbols 获取支持的交易对列表（字符串格式）
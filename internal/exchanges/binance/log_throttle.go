@@ -0,0 +1,105 @@
+package binance
+
+import (
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// defaultLogThrottleInterval 重复错误日志的默认汇总间隔
+const defaultLogThrottleInterval = 30 * time.Second
+
+// logThrottle 重复日志节流：同一调用点（按格式字符串区分）的日志首次出现时立即输出，
+// 之后每个间隔最多输出一次，并附带间隔内被抑制的次数，避免断线重连风暴时刷屏
+type logThrottle struct {
+	mu       sync.Mutex
+	interval time.Duration
+	entries  map[string]*throttleEntry
+}
+
+// throttleEntry 单个调用点的节流状态
+type throttleEntry struct {
+	lastLog    time.Time // 最近一次输出的时间
+	suppressed int       // 最近一次输出之后被抑制的次数
+}
+
+// newLogThrottle 创建日志节流器，interval<=0时使用默认间隔
+func newLogThrottle(interval time.Duration) *logThrottle {
+	if interval <= 0 {
+		interval = defaultLogThrottleInterval
+	}
+	return &logThrottle{interval: interval, entries: make(map[string]*throttleEntry)}
+}
+
+// setInterval 修改汇总间隔，interval<=0时使用默认间隔
+func (t *logThrottle) setInterval(interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultLogThrottleInterval
+	}
+	t.mu.Lock()
+	t.interval = interval
+	t.mu.Unlock()
+}
+
+// allow 判断key对应的日志此时是否输出，输出时返回上次输出之后被抑制的次数
+func (t *logThrottle) allow(key string, now time.Time) (bool, int) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	entry, exists := t.entries[key]
+	if !exists {
+		t.entries[key] = &throttleEntry{lastLog: now}
+		return true, 0
+	}
+	if now.Sub(entry.lastLog) < t.interval {
+		entry.suppressed++
+		return false, 0
+	}
+	suppressed := entry.suppressed
+	entry.lastLog, entry.suppressed = now, 0
+	return true, suppressed
+}
+
+// flush 清空节流状态，返回各调用点尚未汇总输出的抑制次数（用于连接恢复时输出最后的汇总）
+func (t *logThrottle) flush() map[string]int {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+
+	pending := make(map[string]int)
+	for key, entry := range t.entries {
+		if entry.suppressed > 0 {
+			pending[key] = entry.suppressed
+		}
+	}
+	t.entries = make(map[string]*throttleEntry)
+	return pending
+}
+
+// logf 按节流规则输出日志，logFn为log.Errorf、log.Warnf等；t为nil时不节流
+func (t *logThrottle) logf(logFn func(*log.SubLogger, string, ...any), format string, args ...any) {
+	if t == nil {
+		logFn(log.WebsocketMgr, format, args...)
+		return
+	}
+	ok, suppressed := t.allow(format, time.Now())
+	if !ok {
+		return
+	}
+	msg := fmt.Sprintf(format, args...)
+	if suppressed > 0 {
+		msg = fmt.Sprintf("%s (此前相同日志另有%d次被抑制)", msg, suppressed)
+	}
+	logFn(log.WebsocketMgr, "%s", msg)
+}
+
+// flushSummary 输出尚未汇总的抑制次数并重置节流状态
+func (t *logThrottle) flushSummary() {
+	if t == nil {
+		return
+	}
+	for format, suppressed := range t.flush() {
+		log.Infof(log.WebsocketMgr, "连接恢复前日志 %q 另有%d次被抑制", format, suppressed)
+	}
+}
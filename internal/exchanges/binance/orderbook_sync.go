@@ -0,0 +1,366 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// 本地订单簿的同步状态
+const (
+	BookStatusInSync    = "in_sync"   // 与交易所一致，推送的订单簿可用
+	BookStatusResyncing = "resyncing" // 等待REST快照或检测到序号缺口后重新同步中，不推送订单簿
+)
+
+const (
+	orderbookSnapshotDepth   = 1000             // 重新同步时请求的快照深度
+	orderbookSnapshotTimeout = 10 * time.Second // 单次快照请求的超时时间
+	orderbookSnapshotBackoff = time.Second      // 两次快照请求的最小间隔，避免快照失败时频繁请求REST接口
+	maxBufferedDepthEvents   = 1000             // 等待快照期间缓存的增量事件上限，超出时丢弃最早的事件
+)
+
+// OrderbookSnapshotFunc 获取交易对的REST订单簿快照
+type OrderbookSnapshotFunc func(ctx context.Context, symbol types.Symbol) (OrderBook, error)
+
+// BookDesyncEvent 增量深度流出现序号缺口，本地订单簿不再可信
+type BookDesyncEvent struct {
+	Symbol           types.Symbol `json:"symbol"`             // 交易对
+	ExpectedUpdateID int64        `json:"expected_update_id"` // 期望的下一个更新ID
+	FirstUpdateID    int64        `json:"first_update_id"`    // 实际收到事件的第一个更新ID（U）
+	LastUpdateID     int64        `json:"last_update_id"`     // 实际收到事件的最后更新ID（u）
+	Time             time.Time    `json:"time"`               // 检测到缺口的时间
+}
+
+// BookHealth 本地订单簿的健康状态，下游可据此忽略重新同步期间的订单簿
+type BookHealth struct {
+	Symbol       types.Symbol `json:"symbol"`         // 交易对
+	Status       string       `json:"status"`         // in_sync或resyncing
+	LastUpdateID int64        `json:"last_update_id"` // 最近应用的更新ID
+	Desyncs      int64        `json:"desyncs"`        // 检测到序号缺口的次数
+	LastDesync   time.Time    `json:"last_desync"`    // 最近一次检测到缺口的时间，未发生时为零值
+}
+
+// sequenceGapError 增量事件与本地订单簿的更新ID不连续
+type sequenceGapError struct {
+	expected int64
+	first    int64
+}
+
+func (e *sequenceGapError) Error() string {
+	return fmt.Sprintf("depth update sequence gap: expected U <= %d, got %d", e.expected, e.first)
+}
+
+// localOrderbook 由REST快照和增量深度流维护的本地订单簿
+type localOrderbook struct {
+	symbol       types.Symbol
+	bids         map[float64]float64
+	asks         map[float64]float64
+	lastUpdateID int64
+	synced       bool // 已加载快照且增量事件连续
+	primed       bool // 快照后已应用第一条增量事件，之后要求U严格等于上一条事件的u+1
+
+	pending    []*WebsocketDepthStream // 等待快照期间缓存的增量事件
+	fetching   bool
+	lastFetch  time.Time
+	generation int // 快照请求的序号，丢弃被取代的快照结果
+
+	desyncs    int64
+	lastDesync time.Time
+	callback   types.DataCallback
+}
+
+// apply 按Binance的规则应用一条增量事件：丢弃u不大于本地更新ID的事件，快照后的第一条事件需满足
+// U <= lastUpdateId+1 <= u，之后每条事件的U必须等于上一条事件的u+1；返回事件是否被应用
+func (b *localOrderbook) apply(event *WebsocketDepthStream) (bool, error) {
+	if event.LastUpdateID <= b.lastUpdateID {
+		return false, nil
+	}
+	expected := b.lastUpdateID + 1
+	if event.FirstUpdateID > expected || (b.primed && event.FirstUpdateID != expected) {
+		return false, &sequenceGapError{expected: expected, first: event.FirstUpdateID}
+	}
+
+	updateLevels(b.bids, event.UpdateBids)
+	updateLevels(b.asks, event.UpdateAsks)
+	b.lastUpdateID = event.LastUpdateID
+	b.primed = true
+	return true, nil
+}
+
+// updateLevels 更新价格档位，数量为0时删除该档位
+func updateLevels[T interface{ Float64() float64 }](levels map[float64]float64, updates [][2]T) {
+	for _, update := range updates {
+		price, quantity := update[0].Float64(), update[1].Float64()
+		if quantity == 0 {
+			delete(levels, price)
+		} else {
+			levels[price] = quantity
+		}
+	}
+}
+
+// reset 用REST快照重置订单簿
+func (b *localOrderbook) reset(snapshot OrderBook) {
+	b.bids = make(map[float64]float64, len(snapshot.Bids))
+	b.asks = make(map[float64]float64, len(snapshot.Asks))
+	for _, bid := range snapshot.Bids {
+		b.bids[bid.Price] = bid.Quantity
+	}
+	for _, ask := range snapshot.Asks {
+		b.asks[ask.Price] = ask.Quantity
+	}
+	b.lastUpdateID = snapshot.LastUpdateID
+	b.synced = true
+	b.primed = false
+}
+
+// buffer 缓存等待快照期间的增量事件
+func (b *localOrderbook) buffer(event *WebsocketDepthStream) {
+	if len(b.pending) >= maxBufferedDepthEvents {
+		b.pending = b.pending[1:]
+	}
+	b.pending = append(b.pending, event)
+}
+
+// orderbook 生成通用订单簿数据，买单按价格降序、卖单按价格升序
+func (b *localOrderbook) orderbook(timestamp time.Time) *types.Orderbook {
+	return &types.Orderbook{
+		Exchange:  types.ExchangeBinance,
+		Symbol:    b.symbol,
+		Bids:      sortedLevels(b.bids, true),
+		Asks:      sortedLevels(b.asks, false),
+		Timestamp: timestamp,
+	}
+}
+
+// sortedLevels 将价格档位按价格排序
+func sortedLevels(levels map[float64]float64, descending bool) []types.OrderbookEntry {
+	entries := make([]types.OrderbookEntry, 0, len(levels))
+	for price, quantity := range levels {
+		entries = append(entries, types.OrderbookEntry{Price: price, Quantity: quantity})
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if descending {
+			return entries[i].Price > entries[j].Price
+		}
+		return entries[i].Price < entries[j].Price
+	})
+	return entries
+}
+
+// orderbookSyncer 按交易对维护本地订单簿：跟踪增量深度流U/u的连续性，出现缺口时标记为重新同步、
+// 发出book desync事件并重新请求REST快照；只有与交易所一致的订单簿才推送给订阅回调
+type orderbookSyncer struct {
+	ctx     context.Context
+	cancel  context.CancelFunc
+	wg      *sync.WaitGroup
+	backoff time.Duration // 两次快照请求的最小间隔
+
+	mu          sync.Mutex
+	fetch       OrderbookSnapshotFunc
+	onDesync    func(BookDesyncEvent)
+	books       map[types.Symbol]*localOrderbook
+	desyncTotal int64 // 检测到序号缺口的总次数
+	resnapshots int64 // 请求REST快照的总次数
+}
+
+// newOrderbookSyncer 创建订单簿同步器，快照请求协程计入wg
+func newOrderbookSyncer(wg *sync.WaitGroup) *orderbookSyncer {
+	ctx, cancel := context.WithCancel(context.Background())
+	return &orderbookSyncer{
+		ctx:     ctx,
+		cancel:  cancel,
+		wg:      wg,
+		backoff: orderbookSnapshotBackoff,
+		books:   make(map[types.Symbol]*localOrderbook),
+	}
+}
+
+// handle 处理一条增量深度事件，订单簿一致时以完整订单簿调用callback
+func (s *orderbookSyncer) handle(event *WebsocketDepthStream, callback types.DataCallback) error {
+	symbol := types.Symbol(event.Pair)
+
+	s.mu.Lock()
+	book, ok := s.books[symbol]
+	if !ok {
+		book = &localOrderbook{symbol: symbol}
+		s.books[symbol] = book
+	}
+	book.callback = callback
+
+	var desync *BookDesyncEvent
+	var orderbook *types.Orderbook
+	if !book.synced {
+		book.buffer(event)
+		s.requestSnapshot(book)
+	} else if applied, err := book.apply(event); err != nil {
+		desync = s.markDesync(book, event)
+		book.buffer(event)
+		s.requestSnapshot(book)
+	} else if applied {
+		orderbook = book.orderbook(event.Timestamp.Time())
+	}
+	onDesync := s.onDesync
+	s.mu.Unlock()
+
+	if desync != nil {
+		log.Warnf(log.WebsocketMgr, "订单簿序号缺口 %s: 期望U<=%d，实际U=%d u=%d，重新请求快照",
+			symbol, desync.ExpectedUpdateID, desync.FirstUpdateID, desync.LastUpdateID)
+		if onDesync != nil {
+			onDesync(*desync)
+		}
+	}
+	if orderbook != nil && callback != nil {
+		return callback(orderbook)
+	}
+	return nil
+}
+
+// markDesync 将订单簿标记为重新同步并记录缺口（调用时需要持有锁）
+func (s *orderbookSyncer) markDesync(book *localOrderbook, event *WebsocketDepthStream) *BookDesyncEvent {
+	now := time.Now()
+	desync := &BookDesyncEvent{
+		Symbol:           book.symbol,
+		ExpectedUpdateID: book.lastUpdateID + 1,
+		FirstUpdateID:    event.FirstUpdateID,
+		LastUpdateID:     event.LastUpdateID,
+		Time:             now,
+	}
+	book.synced = false
+	book.desyncs++
+	book.lastDesync = now
+	s.desyncTotal++
+	return desync
+}
+
+// requestSnapshot 在后台请求REST快照，已有请求进行中或距上次请求不足最小间隔时跳过，
+// 由后续的增量事件再次触发（调用时需要持有锁）
+func (s *orderbookSyncer) requestSnapshot(book *localOrderbook) {
+	if book.fetching || s.fetch == nil || s.ctx.Err() != nil {
+		return
+	}
+	if time.Since(book.lastFetch) < s.backoff {
+		return
+	}
+	book.fetching = true
+	book.lastFetch = time.Now()
+	book.generation++
+	s.resnapshots++
+
+	s.wg.Add(1)
+	go s.loadSnapshot(book.symbol, book.generation, s.fetch)
+}
+
+// loadSnapshot 获取快照并重放缓存的增量事件，成功后以完整订单簿调用回调
+func (s *orderbookSyncer) loadSnapshot(symbol types.Symbol, generation int, fetch OrderbookSnapshotFunc) {
+	defer s.wg.Done()
+
+	ctx, cancel := context.WithTimeout(s.ctx, orderbookSnapshotTimeout)
+	defer cancel()
+	snapshot, err := fetch(ctx, symbol)
+
+	s.mu.Lock()
+	book, ok := s.books[symbol]
+	if !ok || book.generation != generation {
+		s.mu.Unlock()
+		return
+	}
+	book.fetching = false
+	if err != nil {
+		s.mu.Unlock()
+		log.Warnf(log.WebsocketMgr, "获取 %s 订单簿快照失败: %v", symbol, err)
+		return
+	}
+
+	book.reset(snapshot)
+	pending := book.pending
+	book.pending = nil
+	for i, event := range pending {
+		if _, err := book.apply(event); err != nil {
+			// 快照早于缓存的事件，保留剩余事件等待下一次快照
+			book.synced = false
+			book.pending = pending[i:]
+			s.mu.Unlock()
+			log.Debugf(log.WebsocketMgr, "%s 订单簿快照(lastUpdateId=%d)与增量事件不连续: %v", symbol, snapshot.LastUpdateID, err)
+			return
+		}
+	}
+	orderbook := book.orderbook(time.Now())
+	callback := book.callback
+	s.mu.Unlock()
+
+	log.Debugf(log.WebsocketMgr, "%s 订单簿已同步，lastUpdateId=%d", symbol, snapshot.LastUpdateID)
+	if callback != nil {
+		if err := callback(orderbook); err != nil {
+			log.Errorf(log.WebsocketMgr, "%s 订单簿回调失败: %v", symbol, err)
+		}
+	}
+}
+
+// health 返回交易对的订单簿健康状态
+func (s *orderbookSyncer) health(symbol types.Symbol) (BookHealth, bool) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	book, ok := s.books[symbol]
+	if !ok {
+		return BookHealth{}, false
+	}
+	return book.health(), true
+}
+
+// health 订单簿的健康状态（调用时需要持有锁）
+func (b *localOrderbook) health() BookHealth {
+	status := BookStatusResyncing
+	if b.synced {
+		status = BookStatusInSync
+	}
+	return BookHealth{
+		Symbol:       b.symbol,
+		Status:       status,
+		LastUpdateID: b.lastUpdateID,
+		Desyncs:      b.desyncs,
+		LastDesync:   b.lastDesync,
+	}
+}
+
+// status 返回所有订单簿的健康状态和缺口、快照计数
+func (s *orderbookSyncer) status() map[string]interface{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	books := make([]BookHealth, 0, len(s.books))
+	inSync := 0
+	for _, book := range s.books {
+		health := book.health()
+		if health.Status == BookStatusInSync {
+			inSync++
+		}
+		books = append(books, health)
+	}
+	sort.Slice(books, func(i, j int) bool { return books[i].Symbol < books[j].Symbol })
+
+	return map[string]interface{}{
+		"books":        books,
+		"in_sync":      inSync,
+		"resyncing":    len(books) - inSync,
+		"desync_total": s.desyncTotal,
+		"resnapshots":  s.resnapshots,
+	}
+}
+
+// reset 停止维护所有订单簿
+func (s *orderbookSyncer) reset() {
+	s.mu.Lock()
+	clear(s.books)
+	s.mu.Unlock()
+}
+
+// close 取消进行中的快照请求
+func (s *orderbookSyncer) close() {
+	s.cancel()
+}
@@ -0,0 +1,127 @@
+package binance
+
+import (
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mooyang-code/data-miner/internal/metrics"
+)
+
+// Binance导出的Prometheus指标，抓取时从运行统计中读取，与/status中的数据一致
+var (
+	wsConnectionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "binance_ws", "connections"),
+		"Open Binance WebSocket connections.", nil, nil)
+	wsSubscriptionsDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "binance_ws", "subscriptions"),
+		"Active Binance WebSocket stream subscriptions.", nil, nil)
+	wsMessagesDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "binance_ws", "messages_total"),
+		"Binance WebSocket messages received by stream type.", []string{"stream_type"}, nil)
+	wsMessageRateDesc = prometheus.NewDesc(
+		prometheus.BuildFQName(metrics.Namespace, "binance_ws", "messages_per_second"),
+		"Binance WebSocket messages received per second by stream type over a 10s window.", []string{"stream_type"}, nil)
+
+	pairsCacheLabels           = []string{"asset"}
+	pairsCachePairsDesc        = pairsCacheDesc("pairs", "Tradable pairs currently cached.")
+	pairsCacheHitsDesc         = pairsCacheDesc("hits_total", "Lookups served from an unexpired cache entry.")
+	pairsCacheMissesDesc       = pairsCacheDesc("misses_total", "Lookups that found an expired or missing entry and refreshed synchronously.")
+	pairsCacheRefreshesDesc    = pairsCacheDesc("refreshes_total", "Cache refreshes, successful or not.")
+	pairsCacheFailuresDesc     = pairsCacheDesc("refresh_failures_total", "Failed cache refreshes.")
+	pairsCacheConsecutiveDesc  = pairsCacheDesc("consecutive_failures", "Consecutive failed refreshes, reset on success.")
+	pairsCacheDurationDesc     = pairsCacheDesc("last_refresh_duration_seconds", "Duration of the last refresh including retries.")
+	pairsCacheLastRefreshDesc  = pairsCacheDesc("last_refresh_timestamp_seconds", "Unix time the last refresh finished, successful or not.")
+	pairsCacheLastSuccessDesc  = pairsCacheDesc("last_success_timestamp_seconds", "Unix time of the last successful refresh; absent until the first success.")
+	pairsCacheAddedDesc        = pairsCacheDesc("last_refresh_pairs_added", "Pairs added by the last successful refresh.")
+	pairsCacheRemovedDesc      = pairsCacheDesc("last_refresh_pairs_removed", "Pairs removed by the last successful refresh.")
+	pairsCacheAddedTotalDesc   = pairsCacheDesc("pairs_added_total", "Pairs added across all refreshes (listings).")
+	pairsCacheRemovedTotalDesc = pairsCacheDesc("pairs_removed_total", "Pairs removed across all refreshes (delistings).")
+)
+
+// pairsCacheDesc 创建交易对缓存指标的描述，按资产类型区分
+func pairsCacheDesc(name, help string) *prometheus.Desc {
+	return prometheus.NewDesc(prometheus.BuildFQName(metrics.Namespace, "binance_pairs_cache", name), help, pairsCacheLabels, nil)
+}
+
+// metricsCollector 在每次抓取时读取Binance的运行统计并输出为Prometheus指标
+type metricsCollector struct {
+	b *Binance
+}
+
+// MetricsCollector 返回导出Binance运行统计的Prometheus收集器，由调用方注册到metrics.Registry
+func (b *Binance) MetricsCollector() prometheus.Collector {
+	return metricsCollector{b: b}
+}
+
+// Describe 实现prometheus.Collector
+func (c metricsCollector) Describe(ch chan<- *prometheus.Desc) {
+	ch <- wsConnectionsDesc
+	ch <- wsSubscriptionsDesc
+	ch <- wsMessagesDesc
+	ch <- wsMessageRateDesc
+	for _, desc := range []*prometheus.Desc{
+		pairsCachePairsDesc, pairsCacheHitsDesc, pairsCacheMissesDesc, pairsCacheRefreshesDesc,
+		pairsCacheFailuresDesc, pairsCacheConsecutiveDesc, pairsCacheDurationDesc, pairsCacheLastRefreshDesc,
+		pairsCacheLastSuccessDesc, pairsCacheAddedDesc, pairsCacheRemovedDesc, pairsCacheAddedTotalDesc,
+		pairsCacheRemovedTotalDesc,
+	} {
+		ch <- desc
+	}
+}
+
+// Collect 实现prometheus.Collector
+func (c metricsCollector) Collect(ch chan<- prometheus.Metric) {
+	c.collectWebsocket(ch)
+	c.collectPairsCache(ch)
+}
+
+// collectWebsocket 输出WebSocket连接数、订阅数和按流类型统计的消息数
+func (c metricsCollector) collectWebsocket(ch chan<- prometheus.Metric) {
+	ws := c.b.WebSocket
+	if ws == nil {
+		return
+	}
+	ch <- prometheus.MustNewConstMetric(wsConnectionsDesc, prometheus.GaugeValue, float64(ws.GetConnectionCount()))
+	ch <- prometheus.MustNewConstMetric(wsSubscriptionsDesc, prometheus.GaugeValue, float64(ws.GetSubscriptionCount()))
+	for streamType, r := range ws.streamStatsOrInit().rates(time.Now()) {
+		ch <- prometheus.MustNewConstMetric(wsMessagesDesc, prometheus.CounterValue, float64(r.total), streamType)
+		ch <- prometheus.MustNewConstMetric(wsMessageRateDesc, prometheus.GaugeValue, r.rate, streamType)
+	}
+}
+
+// collectPairsCache 输出各资产类型的交易对缓存命中和刷新统计，用于发现卡住的缓存（失败次数增长、最近成功时间停止更新）
+func (c metricsCollector) collectPairsCache(ch chan<- prometheus.Metric) {
+	cache := c.b.tradablePairsCache
+	if cache == nil {
+		return
+	}
+	for _, m := range cache.metricsSnapshot() {
+		metric := func(desc *prometheus.Desc, valueType prometheus.ValueType, value float64) {
+			ch <- prometheus.MustNewConstMetric(desc, valueType, value, m.asset)
+		}
+		metric(pairsCachePairsDesc, prometheus.GaugeValue, float64(m.pairs))
+		metric(pairsCacheHitsDesc, prometheus.CounterValue, float64(m.hits))
+		metric(pairsCacheMissesDesc, prometheus.CounterValue, float64(m.misses))
+		metric(pairsCacheRefreshesDesc, prometheus.CounterValue, float64(m.refreshCount))
+		metric(pairsCacheFailuresDesc, prometheus.CounterValue, float64(m.failureCount))
+		metric(pairsCacheConsecutiveDesc, prometheus.GaugeValue, float64(m.consecutiveFailures))
+		metric(pairsCacheDurationDesc, prometheus.GaugeValue, m.lastDuration.Seconds())
+		metric(pairsCacheLastRefreshDesc, prometheus.GaugeValue, unixSeconds(m.lastRefresh))
+		if !m.lastUpdate.IsZero() {
+			metric(pairsCacheLastSuccessDesc, prometheus.GaugeValue, unixSeconds(m.lastUpdate))
+		}
+		metric(pairsCacheAddedDesc, prometheus.GaugeValue, float64(m.pairsAdded))
+		metric(pairsCacheRemovedDesc, prometheus.GaugeValue, float64(m.pairsRemoved))
+		metric(pairsCacheAddedTotalDesc, prometheus.CounterValue, float64(m.pairsAddedTotal))
+		metric(pairsCacheRemovedTotalDesc, prometheus.CounterValue, float64(m.pairsRemovedTotal))
+	}
+}
+
+// unixSeconds 将时间转换为Unix秒（带小数），零值返回0
+func unixSeconds(t time.Time) float64 {
+	if t.IsZero() {
+		return 0
+	}
+	return float64(t.UnixNano()) / 1e9
+}
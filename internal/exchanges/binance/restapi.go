@@ -3,12 +3,12 @@ package binance
 
 import (
 	"context"
-	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
 	"net/http"
 	"net/url"
 	"strconv"
+	"strings"
 	"sync"
 	"time"
 
@@ -26,20 +26,34 @@ const (
 	// 基础URL
 	apiURL = "https://api.binance.com"
 
+	// usedWeightHeader 当前IP在1分钟窗口内已使用的请求权重
+	usedWeightHeader = "X-MBX-USED-WEIGHT-1M"
+
 	// 公共接口路径
-	exchangeInfo     = "/api/v3/exchangeInfo"
-	orderBookDepth   = "/api/v3/depth"
-	recentTrades     = "/api/v3/trades"
-	aggregatedTrades = "/api/v3/aggTrades"
-	candleStick      = "/api/v3/klines"
-	averagePrice     = "/api/v3/avgPrice"
-	priceChange      = "/api/v3/ticker/24hr"
-	symbolPrice      = "/api/v3/ticker/price"
-	bestPrice        = "/api/v3/ticker/bookTicker"
-	historicalTrades = "/api/v3/historicalTrades"
+	serverTimeEndpoint = "/api/v3/time"
+	exchangeInfo       = "/api/v3/exchangeInfo"
+	orderBookDepth     = "/api/v3/depth"
+	recentTrades       = "/api/v3/trades"
+	aggregatedTrades   = "/api/v3/aggTrades"
+	candleStick        = "/api/v3/klines"
+	averagePrice       = "/api/v3/avgPrice"
+	priceChange        = "/api/v3/ticker/24hr"
+	symbolPrice        = "/api/v3/ticker/price"
+	bestPrice          = "/api/v3/ticker/bookTicker"
+	historicalTrades   = "/api/v3/historicalTrades"
+
+	// maxTickerSymbols 使用symbols参数批量获取24小时统计的最大交易对数量，超过时权重与全量请求相同
+	maxTickerSymbols = 100
+
+	// singleTickerWeight 使用symbol参数获取单个交易对24小时统计的权重
+	singleTickerWeight = 2
+
+	// maxKlinesLimit 单次获取K线的最大数量
+	maxKlinesLimit = 1000
 
 	// 认证接口路径
 	userAccountStream = "/api/v3/userDataStream"
+	accountInfo       = "/api/v3/account"
 	allOrders         = "/api/v3/allOrders"
 	orderEndpoint     = "/api/v3/order"
 )
@@ -48,6 +62,13 @@ const (
 type BinanceRestAPI struct {
 	config     types.BinanceConfig // Binance配置
 	httpClient httpclient.Client   // HTTP客户端
+	timeSync   *TimeSync           // 服务器时间偏移监控
+	symbols    *symbolIndex        // 基于exchangeInfo的交易对索引
+	baseURL    string              // API地址，为空时使用默认地址
+
+	// 权重跟踪（每次响应都会更新）
+	usedWeight      int       // 最近一次响应中的已使用权重
+	weightUpdatedAt time.Time // 最近一次记录权重的时间
 
 	// 状态管理
 	mu      sync.RWMutex // 读写锁
@@ -74,10 +95,15 @@ func NewRestAPI() *BinanceRestAPI {
 	// 创建REST API实例
 	api := &BinanceRestAPI{
 		httpClient: httpClient,
+		symbols:    newSymbolIndex(),
 		Name:       "Binance",
 		Enabled:    true,
 		Verbose:    false,
 	}
+	api.timeSync = NewTimeSync(func(ctx context.Context) error {
+		_, err := api.GetServerTime(ctx)
+		return err
+	}, defaultRecvWindow)
 	log.Infof(log.ExchangeSys, "Binance REST API client created successfully")
 	return api
 }
@@ -95,6 +121,15 @@ func (b *BinanceRestAPI) Initialize(config interface{}) error {
 	} else {
 		b.config = binanceConfig
 	}
+	b.timeSync.SetRecvWindow(b.config.TimeSync.RecvWindow)
+	b.SetBaseURL(b.config.APIURL)
+
+	// 配置代理、TLS选项或在途请求上限时重新创建HTTP客户端
+	if b.config.ProxyURL != "" || !httpTLSConfig(b.config.TLS).IsEmpty() || b.config.MaxInFlightRequests > 0 {
+		if err := b.reconfigureHTTPClient(); err != nil {
+			return fmt.Errorf("failed to configure HTTP client: %w", err)
+		}
+	}
 
 	log.Infof(log.ExchangeSys, "Binance REST API initialized successfully")
 	return nil
@@ -111,26 +146,143 @@ func (b *BinanceRestAPI) Close() error {
 	return nil
 }
 
+// reconfigureHTTPClient 按配置中的代理、TLS选项和在途请求上限重新创建HTTP客户端并替换当前客户端（代理模式下不使用动态IP）
+func (b *BinanceRestAPI) reconfigureHTTPClient() error {
+	config := createBinanceHTTPConfig()
+	config.TLS = httpTLSConfig(b.config.TLS)
+	config.MaxInFlight = b.config.MaxInFlightRequests
+	if b.config.ProxyURL != "" {
+		config.ProxyURL = b.config.ProxyURL
+		config.DynamicIP.Enabled = false
+	}
+	httpClient, err := httpclient.New(config)
+	if err != nil {
+		return err
+	}
+	httpClient.SetHeaders(map[string]string{
+		"Content-Type": "application/json",
+		"User-Agent":   "crypto-data-miner/1.0.0",
+	})
+
+	if b.httpClient != nil {
+		if err := b.httpClient.Close(); err != nil {
+			log.Warnf(log.ExchangeSys, "Failed to close previous HTTP client: %v", err)
+		}
+	}
+	b.httpClient = httpClient
+	return nil
+}
+
 // IsEnabled 返回交易所是否启用
 func (b *BinanceRestAPI) IsEnabled() bool {
 	return b.Enabled
 }
 
+// SetBaseURL 设置API地址（为空时使用默认地址）
+func (b *BinanceRestAPI) SetBaseURL(baseURL string) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	b.baseURL = strings.TrimRight(baseURL, "/")
+}
+
+// getBaseURL 获取API地址
+func (b *BinanceRestAPI) getBaseURL() string {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	if b.baseURL != "" {
+		return b.baseURL
+	}
+	return apiURL
+}
+
 // SendHTTPRequest 发送未认证的HTTP请求，支持重试和超时
 func (b *BinanceRestAPI) SendHTTPRequest(ctx context.Context, path string, result interface{}) error {
-	fullURL := apiURL + path
+	_, err := b.SendHTTPRequestWithResponse(ctx, path, result)
+	return err
+}
+
+// SendHTTPRequestWithResponse 发送未认证的HTTP请求，并返回包含响应头的响应，
+// 便于调用方读取X-MBX-USED-WEIGHT-1M等权重/限频响应头
+func (b *BinanceRestAPI) SendHTTPRequestWithResponse(ctx context.Context, path string, result interface{}) (*httpclient.Response, error) {
+	fullURL := b.getBaseURL() + path
 
 	if b.Verbose {
 		log.Debugf(log.ExchangeSys, "Making GET request to %s", fullURL)
 	}
 
 	// 使用重试机制
-	return b.sendHTTPRequestWithRetry(ctx, fullURL, result, 3)
+	return b.sendHTTPRequestWithRetry(ctx, fullURL, result, 3, retryAllErrors, marketDataOptions())
+}
+
+// sendMonitoringRequest 发送服务器时间、权重检查等监控请求，不占用HTTP客户端的速率限制配额，
+// 避免监控流量挤占行情请求的权重预算。服务器时间等对延迟敏感，不与其他请求合并，
+// 否则加入在途请求的调用方会拿到更早发出的响应，测得的往返时间和时间偏移不准确
+func (b *BinanceRestAPI) sendMonitoringRequest(ctx context.Context, path string, result interface{}) (*httpclient.Response, error) {
+	options := httpclient.DefaultRequestOptions()
+	options.SkipRateLimit = true
+	return b.sendHTTPRequestWithRetry(ctx, b.getBaseURL()+path, result, 3, isRetryableRequestError, options)
+}
+
+// sendSignedRequest 发送需要签名的请求（USER_DATA等），timestamp按测得的服务器时间偏移校正，
+// 避免本地时钟偏差超过recvWindow时请求被拒绝
+func (b *BinanceRestAPI) sendSignedRequest(ctx context.Context, method, path string, params url.Values, result interface{}) error {
+	if b.config.APIKey == "" || b.config.APISecret == "" {
+		return errAPISecretRequired
+	}
+
+	// signature需要放在最后，签名内容为除signature外按键排序编码的参数
+	params = b.timeSync.SignParams(params, b.config.APISecret)
+	signature := params.Get("signature")
+	params.Del("signature")
+	query := params.Encode() + "&signature=" + signature
+
+	resp, err := b.httpClient.DoRequest(ctx, &httpclient.Request{
+		Method:  method,
+		URL:     b.getBaseURL() + path + "?" + query,
+		Headers: map[string]string{"X-MBX-APIKEY": b.config.APIKey},
+		Result:  result,
+	})
+	if err != nil {
+		return err
+	}
+	b.recordUsedWeight(resp)
+	return nil
 }
 
-// sendHTTPRequestWithRetry 使用 retry 库发送HTTP请求并支持重试
-func (b *BinanceRestAPI) sendHTTPRequestWithRetry(ctx context.Context, fullURL string, result interface{}, maxRetries int) error {
+// GetAccount 获取现货账户信息（签名接口，权重20），可用于校验API Key和Secret
+func (b *BinanceRestAPI) GetAccount(ctx context.Context) (*Account, error) {
+	var account Account
+	if err := b.sendSignedRequest(ctx, http.MethodGet, accountInfo, url.Values{"omitZeroBalances": {"true"}}, &account); err != nil {
+		return nil, fmt.Errorf("failed to get account: %w", err)
+	}
+	return &account, nil
+}
+
+// marketDataOptions 公开行情接口的请求选项：无签名，调度器与即时查询并发请求相同数据时合并为一次上游请求以节省权重
+func marketDataOptions() *httpclient.RequestOptions {
+	options := httpclient.DefaultRequestOptions()
+	options.Coalesce = true
+	return options
+}
+
+// retryAllErrors 对所有错误都重试
+func retryAllErrors(error) bool {
+	return true // 暂时对所有错误都重试
+}
+
+// isRetryableRequestError 只重试网络错误、超时、5xx和429，4xx等请求本身的错误重试也不会成功
+func isRetryableRequestError(err error) bool {
+	var httpErr *httpclient.HTTPError
+	if errors.As(err, &httpErr) && httpErr.StatusCode >= 400 && httpErr.StatusCode < 500 {
+		return httpErr.StatusCode == http.StatusTooManyRequests
+	}
+	return true
+}
+
+// sendHTTPRequestWithRetry 使用 retry 库发送HTTP请求并支持重试，retryIf决定错误是否重试
+func (b *BinanceRestAPI) sendHTTPRequestWithRetry(ctx context.Context, fullURL string, result interface{}, maxRetries int, retryIf retry.RetryIfFunc, options *httpclient.RequestOptions) (*httpclient.Response, error) {
 	var lastErr error
+	var resp *httpclient.Response
 
 	err := retry.Do(
 		func() error {
@@ -139,7 +291,13 @@ func (b *BinanceRestAPI) sendHTTPRequestWithRetry(ctx context.Context, fullURL s
 			defer cancel()
 
 			// 执行HTTP请求
-			err := b.httpClient.Get(requestCtx, fullURL, result)
+			var err error
+			resp, err = b.httpClient.DoRequest(requestCtx, &httpclient.Request{
+				Method:  http.MethodGet,
+				URL:     fullURL,
+				Result:  result,
+				Options: options,
+			})
 			if err != nil {
 				lastErr = err
 				log.Warnf(log.ExchangeSys, "Binance REST API request failed: %v", err)
@@ -160,19 +318,85 @@ func (b *BinanceRestAPI) sendHTTPRequestWithRetry(ctx context.Context, fullURL s
 			log.Warnf(log.ExchangeSys, "Binance REST API retry attempt %d/%d: %v", n+1, maxRetries, err)
 		}),
 		retry.RetryIf(func(err error) bool {
-			// 可以根据错误类型决定是否重试
-			// 例如：网络错误、超时错误等可以重试，但认证错误等不应该重试
-			return true // 暂时对所有错误都重试
+			// httpClient内部的重试预算已耗尽时不再外层重试，保证请求总耗时有上限
+			return !errors.Is(err, httpclient.ErrRetryBudgetExhausted) && retryIf(err)
 		}),
 	)
 
 	if err != nil {
-		return fmt.Errorf("httpClient 请求失败，已重试 %d 次: %w", maxRetries, lastErr)
+		return nil, fmt.Errorf("httpClient 请求失败，已重试 %d 次: %w", maxRetries, lastErr)
 	}
-	return nil
+
+	b.recordUsedWeight(resp)
+	return resp, nil
+}
+
+// recordUsedWeight 从响应头记录当前已使用的请求权重
+func (b *BinanceRestAPI) recordUsedWeight(resp *httpclient.Response) {
+	if resp == nil {
+		return
+	}
+	weightStr := resp.Headers[http.CanonicalHeaderKey(usedWeightHeader)]
+	if weightStr == "" {
+		return
+	}
+	weight, err := strconv.Atoi(weightStr)
+	if err != nil {
+		return
+	}
+
+	b.mu.Lock()
+	b.usedWeight = weight
+	b.weightUpdatedAt = time.Now()
+	b.mu.Unlock()
+}
+
+// GetUsedWeight 获取最近一次响应中的已使用权重及其记录时间
+func (b *BinanceRestAPI) GetUsedWeight() (int, time.Time) {
+	b.mu.RLock()
+	defer b.mu.RUnlock()
+	return b.usedWeight, b.weightUpdatedAt
+}
+
+// orderbookDepthLimits Binance订单簿接口允许的深度，其他值会被交易所静默调整
+var orderbookDepthLimits = []int{5, 10, 20, 50, 100, 500, 1000, 5000}
+
+// defaultOrderbookDepth 未指定深度时Binance使用的默认深度
+const defaultOrderbookDepth = 100
+
+// defaultOrderbookConcurrency 批量请求订单簿的默认并发数
+const defaultOrderbookConcurrency = 5
+
+// NormalizeOrderbookDepth 将请求的深度调整为Binance允许的深度：
+// 小于等于0时使用默认深度，否则取不小于请求值的最小允许深度，超过5000时取5000
+func NormalizeOrderbookDepth(limit int) int {
+	if limit <= 0 {
+		return defaultOrderbookDepth
+	}
+	for _, allowed := range orderbookDepthLimits {
+		if limit <= allowed {
+			return allowed
+		}
+	}
+	return orderbookDepthLimits[len(orderbookDepthLimits)-1]
 }
 
-// GetOrderbook 获取订单簿
+// OrderbookDepthWeight 获取指定深度的订单簿请求权重
+func OrderbookDepthWeight(depth int) int {
+	depth = NormalizeOrderbookDepth(depth)
+	switch {
+	case depth <= 100:
+		return 5
+	case depth <= 500:
+		return 25
+	case depth <= 1000:
+		return 50
+	default:
+		return 250
+	}
+}
+
+// GetOrderbook 获取订单簿，请求的深度会被调整为Binance允许的深度
 func (b *BinanceRestAPI) GetOrderbook(ctx context.Context, symbol currency.Pair, limit int) (OrderBook, error) {
 	var resp OrderBookData
 	urlParams := url.Values{}
@@ -183,9 +407,13 @@ func (b *BinanceRestAPI) GetOrderbook(ctx context.Context, symbol currency.Pair,
 	}
 	urlParams.Set("symbol", symbolValue)
 
-	if limit > 0 {
-		urlParams.Set("limit", strconv.Itoa(limit))
+	depth := NormalizeOrderbookDepth(limit)
+	if limit > 0 && depth != limit {
+		log.Infof(log.ExchangeSys, "Orderbook depth %d for %s is not supported by Binance, adjusted to %d", limit, symbolValue, depth)
 	}
+	urlParams.Set("limit", strconv.Itoa(depth))
+	log.Debugf(log.ExchangeSys, "Requesting orderbook for %s with depth %d (weight %d)", symbolValue, depth, OrderbookDepthWeight(depth))
+
 	path := orderBookDepth + "?" + urlParams.Encode()
 	if err := b.SendHTTPRequest(ctx, path, &resp); err != nil {
 		return OrderBook{}, err
@@ -221,6 +449,11 @@ func (b *BinanceRestAPI) GetOrderbook(ctx context.Context, symbol currency.Pair,
 
 // GetKlines 获取K线数据
 func (b *BinanceRestAPI) GetKlines(ctx context.Context, symbol currency.Pair, interval string, limit int, startTime, endTime int64) ([]CandleStick, error) {
+	// 无效的周期会被交易所拒绝或返回空数据，请求前校验并列出有效值
+	if err := types.ValidateBinanceKlineInterval(interval); err != nil {
+		return nil, err
+	}
+
 	urlParams := url.Values{}
 	symbolValue, err := FormatSymbol(symbol, asset.Spot)
 	if err != nil {
@@ -267,29 +500,47 @@ func (b *BinanceRestAPI) GetLatestSpotPrice(ctx context.Context, symbol currency
 	return resp, nil
 }
 
+// GetAllSymbolPrices 一次请求获取所有交易对的最新价格（权重4），
+// 交易对较多时比逐个或批量请求24小时统计接口节省大量权重
+func (b *BinanceRestAPI) GetAllSymbolPrices(ctx context.Context) ([]SymbolPrice, error) {
+	var resp []SymbolPrice
+	if err := b.SendHTTPRequest(ctx, symbolPrice, &resp); err != nil {
+		return nil, err
+	}
+	return resp, nil
+}
+
 // GetExchangeInfo 获取交易所信息
 func (b *BinanceRestAPI) GetExchangeInfo(ctx context.Context) (ExchangeInfo, error) {
 	var resp ExchangeInfo
 	if err := b.SendHTTPRequest(ctx, exchangeInfo, &resp); err != nil {
 		return resp, err
 	}
+	b.symbols.update(resp)
 	return resp, nil
 }
 
-// GetTickers 获取24小时价格变化统计
+// GetTickers 获取24小时价格变化统计。指定交易对时使用symbols=["A","B"]参数只获取这些交易对
+// （1-20个权重2，21-100个权重40）；未指定或超过maxTickerSymbols个时获取全部交易对（权重80）
 func (b *BinanceRestAPI) GetTickers(ctx context.Context, symbols ...currency.Pair) ([]PriceChangeStats, error) {
 	var resp []PriceChangeStats
-	urlParams := url.Values{}
+	path := priceChange
 
-	if len(symbols) == 1 {
-		symbolValue, err := FormatSymbol(symbols[0], asset.Spot)
-		if err != nil {
-			return nil, err
+	// 超过100个交易对时权重与全量请求相同，直接获取全部以避免URL过长，由调用方过滤
+	if len(symbols) > 0 && len(symbols) <= maxTickerSymbols {
+		symbolValues := make([]string, len(symbols))
+		for i, symbol := range symbols {
+			symbolValue, err := FormatSymbol(symbol, asset.Spot)
+			if err != nil {
+				return nil, err
+			}
+			symbolValues[i] = strconv.Quote(symbolValue)
 		}
-		urlParams.Set("symbol", symbolValue)
+		urlParams := url.Values{}
+		urlParams.Set("symbols", "["+strings.Join(symbolValues, ",")+"]")
+		path += "?" + urlParams.Encode()
 	}
 
-	path := priceChange + "?" + urlParams.Encode()
 	if err := b.SendHTTPRequest(ctx, path, &resp); err != nil {
 		return nil, err
 	}
@@ -313,20 +564,59 @@ func (b *BinanceRestAPI) IsConnected() bool {
 
 // GetTicker 获取单个交易对的价格统计
 func (b *BinanceRestAPI) GetTicker(ctx context.Context, symbol string) (PriceChangeStats, error) {
-	pair, err := currency.NewPairFromString(symbol)
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, types.Symbol(symbol))
 	if err != nil {
 		return PriceChangeStats{}, err
 	}
+	return b.GetSymbolTicker(ctx, pair)
+}
 
-	tickers, err := b.GetTickers(ctx, pair)
+// GetSymbolTicker 使用symbol参数获取单个交易对的24小时价格统计（权重2）。与批量请求不同，
+// 只重试网络错误、5xx和429；交易所拒绝该交易对（HTTP 400）时不重试，返回types.ErrInvalidSymbol
+func (b *BinanceRestAPI) GetSymbolTicker(ctx context.Context, pair currency.Pair) (PriceChangeStats, error) {
+	symbolValue, err := FormatSymbol(pair, asset.Spot)
 	if err != nil {
 		return PriceChangeStats{}, err
 	}
+	urlParams := url.Values{}
+	urlParams.Set("symbol", symbolValue)
+	log.Debugf(log.ExchangeSys, "Requesting 24hr ticker for %s (weight %d)", symbolValue, singleTickerWeight)
+
+	var resp PriceChangeStats
+	fullURL := b.getBaseURL() + priceChange + "?" + urlParams.Encode()
+	if _, err := b.sendHTTPRequestWithRetry(ctx, fullURL, &resp, 3, isRetryableRequestError, marketDataOptions()); err != nil {
+		var httpErr *httpclient.HTTPError
+		if errors.As(err, &httpErr) && httpErr.StatusCode == http.StatusBadRequest {
+			return PriceChangeStats{}, fmt.Errorf("%w: %s rejected by Binance: %v", types.ErrInvalidSymbol, symbolValue, err)
+		}
+		return PriceChangeStats{}, err
+	}
+	if resp.Symbol == "" {
+		return PriceChangeStats{}, fmt.Errorf("empty ticker response for symbol %s", symbolValue)
+	}
+	return resp, nil
+}
+
+// GetAveragePrice 获取交易对当前的平均价格（最近若干分钟的成交均价）
+func (b *BinanceRestAPI) GetAveragePrice(ctx context.Context, symbol string) (AveragePrice, error) {
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, types.Symbol(symbol))
+	if err != nil {
+		return AveragePrice{}, err
+	}
 
-	if len(tickers) == 0 {
-		return PriceChangeStats{}, fmt.Errorf("no ticker data found for symbol %s", symbol)
+	symbolValue, err := FormatSymbol(pair, asset.Spot)
+	if err != nil {
+		return AveragePrice{}, err
 	}
-	return tickers[0], nil
+	urlParams := url.Values{}
+	urlParams.Set("symbol", symbolValue)
+
+	var resp AveragePrice
+	path := averagePrice + "?" + urlParams.Encode()
+	if err := b.SendHTTPRequest(ctx, path, &resp); err != nil {
+		return AveragePrice{}, err
+	}
+	return resp, nil
 }
 
 // GetTrades 获取交易数据
@@ -335,41 +625,127 @@ func (b *BinanceRestAPI) GetTrades(ctx context.Context, symbol string) ([]Recent
 	return []RecentTrade{}, fmt.Errorf("GetTrades method not implemented yet")
 }
 
-// GetMultipleTickers 获取多个交易对的价格统计
+// GetMultipleTickers 获取多个交易对的价格统计，只返回请求的交易对（按请求顺序），
+// 部分交易对失败或响应中缺失时返回成功的结果和*types.BatchError
 func (b *BinanceRestAPI) GetMultipleTickers(ctx context.Context, symbols []string) ([]PriceChangeStats, error) {
 	if len(symbols) == 0 {
 		return b.GetTickers(ctx)
 	}
 
+	// 无法解析的交易对记录到BatchError中，不影响其他交易对
 	var pairs []currency.Pair
+	var requested []string
+	batchErr := types.NewBatchError()
 	for _, symbol := range symbols {
-		pair, err := currency.NewPairFromString(symbol)
-		if err != nil {
+		if err := ctx.Err(); err != nil {
 			return nil, err
 		}
+		pair, err := b.ParseSymbolViaExchangeInfo(ctx, types.Symbol(symbol))
+		if err != nil {
+			batchErr.Add(types.Symbol(symbol), err)
+			continue
+		}
 		pairs = append(pairs, pair)
+		requested = append(requested, symbol)
+	}
+	if len(pairs) == 0 {
+		return nil, batchErr.ErrOrNil()
 	}
-	return b.GetTickers(ctx, pairs...)
-}
 
-// GetMultipleOrderbooks 获取多个交易对的订单簿
-func (b *BinanceRestAPI) GetMultipleOrderbooks(ctx context.Context, symbols []string, limit int) ([]OrderBook, error) {
-	var orderbooks []OrderBook
+	tickers, err := b.GetTickers(ctx, pairs...)
+	if err != nil {
+		return nil, err
+	}
 
-	for _, symbol := range symbols {
-		pair, err := currency.NewPairFromString(symbol)
+	// 全量请求会返回所有交易对，只保留请求的交易对
+	tickerMap := make(map[string]PriceChangeStats, len(tickers))
+	for _, ticker := range tickers {
+		tickerMap[ticker.Symbol] = ticker
+	}
+	result := make([]PriceChangeStats, 0, len(requested))
+	for i, symbol := range requested {
+		key, err := FormatSymbol(pairs[i], asset.Spot)
 		if err != nil {
-			return nil, err
+			batchErr.Add(types.Symbol(symbol), err)
+			continue
+		}
+		ticker, ok := tickerMap[key]
+		if !ok {
+			batchErr.Add(types.Symbol(symbol), fmt.Errorf("no ticker data found for symbol %s", symbol))
+			continue
 		}
+		result = append(result, ticker)
+	}
+	return result, batchErr.ErrOrNil()
+}
 
-		orderbook, err := b.GetOrderbook(ctx, pair, limit)
-		if err != nil {
-			return nil, err
+// GetMultipleOrderbooks 获取多个交易对的订单簿，部分交易对失败时返回成功的结果和*types.BatchError；
+// ctx取消时不再发起新的请求，等待进行中的请求结束后返回ctx.Err()
+func (b *BinanceRestAPI) GetMultipleOrderbooks(ctx context.Context, symbols []string, limit int) ([]OrderBook, error) {
+	results := make([]OrderBook, len(symbols))
+	errs := make([]error, len(symbols))
+
+	// 使用固定大小的工作池并发请求，请求频率仍由HTTP客户端的限流器控制
+	sem := make(chan struct{}, b.orderbookConcurrency())
+	var wg sync.WaitGroup
+dispatch:
+	for i, symbol := range symbols {
+		// ctx已取消时select可能随机选中空闲的工作位，先单独检查
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case sem <- struct{}{}:
+		case <-ctx.Done():
+			break dispatch
 		}
 
-		orderbooks = append(orderbooks, orderbook)
+		wg.Add(1)
+		go func(i int, symbol string) {
+			defer wg.Done()
+			defer func() { <-sem }()
+
+			if err := ctx.Err(); err != nil {
+				errs[i] = err
+				return
+			}
+			pair, err := b.ParseSymbolViaExchangeInfo(ctx, types.Symbol(symbol))
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			orderbook, err := b.GetOrderbook(ctx, pair, limit)
+			if err != nil {
+				errs[i] = err
+				return
+			}
+			results[i] = orderbook
+		}(i, symbol)
+	}
+	wg.Wait()
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	// 按输入顺序返回成功的结果，失败的交易对汇总到BatchError中
+	orderbooks := make([]OrderBook, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for i, symbol := range symbols {
+		if errs[i] != nil {
+			batchErr.Add(types.Symbol(symbol), errs[i])
+			continue
+		}
+		orderbooks = append(orderbooks, results[i])
+	}
+	return orderbooks, batchErr.ErrOrNil()
+}
+
+// orderbookConcurrency 获取批量请求订单簿的并发数
+func (b *BinanceRestAPI) orderbookConcurrency() int {
+	if b.config.DataTypes.Orderbook.Concurrency > 0 {
+		return b.config.DataTypes.Orderbook.Concurrency
 	}
-	return orderbooks, nil
+	return defaultOrderbookConcurrency
 }
 
 // GetStatus 获取客户端状态
@@ -383,10 +759,14 @@ func (b *BinanceRestAPI) GetStatus() map[string]interface{} {
 	}
 
 	status := b.httpClient.GetStatus()
+	usedWeight, weightUpdatedAt := b.GetUsedWeight()
 	return map[string]interface{}{
-		"name":        b.Name,
-		"enabled":     b.Enabled,
-		"http_client": status,
+		"name":              b.Name,
+		"enabled":           b.Enabled,
+		"http_client":       status,
+		"time_sync":         b.timeSync.GetStatus(),
+		"used_weight":       usedWeight,
+		"weight_updated_at": weightUpdatedAt,
 	}
 }
 
@@ -398,6 +778,15 @@ func NewHTTPClient() (httpclient.Client, error) {
 	return httpclient.New(config)
 }
 
+// httpTLSConfig 将配置文件中的TLS配置转换为HTTP客户端的TLS配置
+func httpTLSConfig(config types.TLSConfig) *httpclient.TLSConfig {
+	return &httpclient.TLSConfig{
+		MinVersion:  config.MinVersion,
+		RootCAsFile: config.RootCAsFile,
+		ServerName:  config.ServerName,
+	}
+}
+
 // createBinanceHTTPConfig 创建Binance专用的HTTP客户端配置
 func createBinanceHTTPConfig() *httpclient.Config {
 	config := httpclient.DefaultConfig("binance")
@@ -407,10 +796,15 @@ func createBinanceHTTPConfig() *httpclient.Config {
 	config.DynamicIP.Hostname = "api.binance.com"
 	config.DynamicIP.IPManager = ipmanager.DefaultConfig("api.binance.com")
 
+	// 预热到最佳IP的TLS连接，避免首个请求TLS握手超时
+	config.DynamicIP.Prewarm = true
+	config.DynamicIP.PrewarmPath = "/api/v3/ping"
+
 	// 调整重试配置
 	config.Retry.MaxAttempts = 5
 	config.Retry.InitialDelay = time.Second
 	config.Retry.MaxDelay = 8 * time.Second
+	config.Retry.TotalTimeout = 20 * time.Second // 在外层每次尝试的30秒超时内结束内部重试
 
 	// 调整速率限制（Binance限制）
 	config.RateLimit.RequestsPerMinute = 1200
@@ -434,28 +828,15 @@ func NewHTTPClientWithCustomConfig(enableDynamicIP bool, debug bool) (httpclient
 
 // GetTickerBySymbol 获取单个交易对的行情数据（适配器方法）
 func (b *BinanceRestAPI) GetTickerBySymbol(ctx context.Context, symbol string) (PriceChangeStats, error) {
-	pair, err := currency.NewPairFromString(symbol)
-	if err != nil {
-		return PriceChangeStats{}, err
-	}
-
-	tickers, err := b.GetTickers(ctx, pair)
-	if err != nil {
-		return PriceChangeStats{}, err
-	}
-
-	if len(tickers) == 0 {
-		return PriceChangeStats{}, fmt.Errorf("no ticker data found for symbol %s", symbol)
-	}
-	return tickers[0], nil
+	return b.GetTicker(ctx, symbol)
 }
 
 // GetTradesBySymbol 获取交易数据（适配器方法）
 func (b *BinanceRestAPI) GetTradesBySymbol(ctx context.Context, symbol string) ([]RecentTrade, error) {
 	// 解析交易对
-	pair, err := currency.NewPairFromString(symbol)
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, types.Symbol(symbol))
 	if err != nil {
-		return nil, fmt.Errorf("无效的交易对格式: %v", err)
+		return nil, err
 	}
 
 	// 格式化交易对符号
@@ -480,63 +861,123 @@ func (b *BinanceRestAPI) GetTradesBySymbol(ctx context.Context, symbol string) (
 	return resp, nil
 }
 
-// GetTimeAndWeight 获取服务器时间和当前权重使用情况
-func (b *BinanceRestAPI) GetTimeAndWeight(ctx context.Context) (int64, int, error) {
+// GetServerTime 获取服务器时间，并记录本地与服务器的时钟偏移
+func (b *BinanceRestAPI) GetServerTime(ctx context.Context) (time.Time, error) {
 	var resp struct {
 		ServerTime int64 `json:"serverTime"`
 	}
 
-	// 创建HTTP请求
-	req, err := http.NewRequestWithContext(ctx, "GET", apiURL+"/api/v3/time", nil)
-	if err != nil {
-		return 0, 0, err
+	sentAt := time.Now()
+	if _, err := b.sendMonitoringRequest(ctx, serverTimeEndpoint, &resp); err != nil {
+		return time.Time{}, err
 	}
-
-	// 发送请求
-	httpResp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return 0, 0, err
+	if b.timeSync != nil {
+		b.timeSync.Record(resp.ServerTime, sentAt, time.Now())
 	}
-	defer httpResp.Body.Close()
+	return time.UnixMilli(resp.ServerTime), nil
+}
 
-	// 读取响应体
-	body, err := io.ReadAll(httpResp.Body)
-	if err != nil {
-		return 0, 0, err
+// GetTimeAndWeight 获取服务器时间和当前权重使用情况
+func (b *BinanceRestAPI) GetTimeAndWeight(ctx context.Context) (int64, int, error) {
+	var resp struct {
+		ServerTime int64 `json:"serverTime"`
 	}
 
-	// 解析JSON响应
-	if err := json.Unmarshal(body, &resp); err != nil {
+	sentAt := time.Now()
+	if _, err := b.sendMonitoringRequest(ctx, serverTimeEndpoint, &resp); err != nil {
 		return 0, 0, err
 	}
+	receivedAt := time.Now()
 
-	// 从响应头获取权重信息
-	weightStr := httpResp.Header.Get("X-MBX-USED-WEIGHT-1M")
-	weight := 0
-	if weightStr != "" {
-		if w, err := strconv.Atoi(weightStr); err == nil {
-			weight = w
-		}
+	// 记录本地与服务器的时钟偏移
+	if b.timeSync != nil {
+		b.timeSync.Record(resp.ServerTime, sentAt, receivedAt)
 	}
 
+	weight, _ := b.GetUsedWeight()
 	return resp.ServerTime, weight, nil
 }
 
+// GetTimeSync 获取服务器时间偏移监控器
+func (b *BinanceRestAPI) GetTimeSync() *TimeSync {
+	return b.timeSync
+}
+
 // GetKlinesForSymbol 获取K线数据（types.Symbol版本）
 func (b *BinanceRestAPI) GetKlinesForSymbol(ctx context.Context, symbol types.Symbol, interval string, limit int) ([]types.Kline, error) {
+	if err := types.ValidateBinanceKlineInterval(interval); err != nil {
+		return nil, err
+	}
+
 	// 转换符号格式
-	pair, err := currency.NewPairFromString(string(symbol))
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, symbol)
 	if err != nil {
-		return nil, fmt.Errorf("无效的交易对格式: %v", err)
+		return nil, err
+	}
+
+	// 只返回已收盘K线时多获取一根，去掉未收盘的K线后仍返回limit根
+	fetchLimit := limit
+	if !b.includeLiveKlines() && limit > 0 && limit < maxKlinesLimit {
+		fetchLimit = limit + 1
 	}
 
 	// 调用内部方法获取K线数据
-	klines, err := b.GetKlines(ctx, pair, interval, limit, 0, 0)
+	klines, err := b.GetKlines(ctx, pair, interval, fetchLimit, 0, 0)
 	if err != nil {
 		return nil, err
 	}
+	result := b.dropFormingKline(convertKlines(symbol, interval, klines))
+	if limit > 0 && len(result) > limit {
+		result = result[len(result)-limit:]
+	}
+	return result, nil
+}
 
-	// 转换为通用类型
+// GetKlinesForSymbolSince 获取开盘时间不早于startTime的K线（通过startTime参数增量获取）
+func (b *BinanceRestAPI) GetKlinesForSymbolSince(ctx context.Context, symbol types.Symbol, interval string, startTime time.Time, limit int) ([]types.Kline, error) {
+	if err := types.ValidateBinanceKlineInterval(interval); err != nil {
+		return nil, err
+	}
+	pair, err := b.ParseSymbolViaExchangeInfo(ctx, symbol)
+	if err != nil {
+		return nil, err
+	}
+
+	klines, err := b.GetKlines(ctx, pair, interval, limit, startTime.UnixMilli(), 0)
+	if err != nil {
+		return nil, err
+	}
+	return b.dropFormingKline(convertKlines(symbol, interval, klines)), nil
+}
+
+// includeLiveKlines 返回是否包含尚未收盘的K线（data_types.klines.include_live）
+func (b *BinanceRestAPI) includeLiveKlines() bool {
+	return b.config.DataTypes.Klines.IncludeLive
+}
+
+// dropFormingKline 未配置include_live时去掉末尾尚未收盘的K线，避免每次拉取都写入变化中的K线。
+// 按服务器时间判断：收盘时间不早于服务器当前时间的K线尚未收盘
+func (b *BinanceRestAPI) dropFormingKline(klines []types.Kline) []types.Kline {
+	if b.includeLiveKlines() {
+		return klines
+	}
+	now := time.Now()
+	if b.timeSync != nil {
+		now = b.timeSync.Now()
+	}
+	return completedKlines(klines, now)
+}
+
+// completedKlines 去掉末尾收盘时间不早于serverNow的K线（K线按开盘时间升序排列，只有最后一根可能未收盘）
+func completedKlines(klines []types.Kline, serverNow time.Time) []types.Kline {
+	for len(klines) > 0 && !klines[len(klines)-1].CloseTime.Before(serverNow) {
+		klines = klines[:len(klines)-1]
+	}
+	return klines
+}
+
+// convertKlines 将Binance K线转换为通用类型
+func convertKlines(symbol types.Symbol, interval string, klines []CandleStick) []types.Kline {
 	result := make([]types.Kline, len(klines))
 	for i, kline := range klines {
 		result[i] = types.Kline{
@@ -554,6 +995,5 @@ func (b *BinanceRestAPI) GetKlinesForSymbol(ctx context.Context, symbol types.Sy
 			TakerVolume: kline.TakerBuyAssetVolume.Float64(),
 		}
 	}
-
-	return result, nil
+	return result
 }
@@ -0,0 +1,124 @@
+package binance
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+const (
+	symbolIndexTTL = time.Hour // 交易对索引的有效期，过期后在下次解析时重新获取exchangeInfo
+
+	// symbolRefreshRetryInterval 获取exchangeInfo失败后的重试间隔，期间的解析直接使用旧索引或按字符串规则拆分，
+	// 避免exchangeInfo不可用时每次解析都请求一次（权重20）
+	symbolRefreshRetryInterval = time.Minute
+)
+
+// symbolIndex 根据exchangeInfo中的baseAsset/quoteAsset建立的交易对索引，
+// 用于准确拆分1000SHIBUSDT、BTCFDUSD这类无法靠字符串规则拆分的交易对
+type symbolIndex struct {
+	mu      sync.RWMutex
+	pairs   map[string]currency.Pair // 交易所交易对名称（如BTCUSDT） -> 交易对
+	updated time.Time                // 最后更新时间
+
+	refreshMu     sync.Mutex // 避免并发解析时重复请求exchangeInfo
+	refreshFailed time.Time  // 最近一次获取exchangeInfo失败的时间，由refreshMu保护
+}
+
+// newSymbolIndex 创建交易对索引
+func newSymbolIndex() *symbolIndex {
+	return &symbolIndex{pairs: make(map[string]currency.Pair)}
+}
+
+// update 使用exchangeInfo更新索引（包括所有状态的交易对）
+func (si *symbolIndex) update(info ExchangeInfo) {
+	pairs := make(map[string]currency.Pair, len(info.Symbols))
+	for _, symbol := range info.Symbols {
+		if symbol == nil || symbol.BaseAsset == "" || symbol.QuoteAsset == "" {
+			continue
+		}
+		pair, err := currency.NewPairFromStrings(symbol.BaseAsset, symbol.QuoteAsset)
+		if err != nil {
+			continue
+		}
+		pairs[strings.ToUpper(symbol.Symbol)] = pair
+	}
+	if len(pairs) == 0 {
+		return
+	}
+
+	si.mu.Lock()
+	si.pairs = pairs
+	si.updated = time.Now()
+	si.mu.Unlock()
+}
+
+// isFresh 判断索引是否可用且未过期
+func (si *symbolIndex) isFresh() bool {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	return len(si.pairs) > 0 && time.Since(si.updated) < symbolIndexTTL
+}
+
+// isEmpty 判断索引是否为空
+func (si *symbolIndex) isEmpty() bool {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	return len(si.pairs) == 0
+}
+
+// lookup 查找交易对
+func (si *symbolIndex) lookup(name string) (currency.Pair, bool) {
+	si.mu.RLock()
+	defer si.mu.RUnlock()
+	pair, ok := si.pairs[name]
+	return pair, ok
+}
+
+// resolve 将交易对符号解析为currency.Pair，索引过期时通过refresh重新获取exchangeInfo
+// 获取失败且没有可用索引时退化为按字符串规则拆分，失败后symbolRefreshRetryInterval内不再重新获取
+func (si *symbolIndex) resolve(ctx context.Context, symbol types.Symbol, refresh func(ctx context.Context) error) (currency.Pair, error) {
+	// 统一去掉分隔符，BTC/USDT、btc-usdt都按BTCUSDT查找
+	name := strings.ToUpper(strings.TrimSpace(string(symbol)))
+	name = strings.NewReplacer("/", "", "-", "", "_", "").Replace(name)
+	if name == "" {
+		return currency.EMPTYPAIR, fmt.Errorf("%w: %q", types.ErrInvalidSymbol, string(symbol))
+	}
+
+	if !si.isFresh() {
+		si.refreshMu.Lock()
+		if !si.isFresh() && time.Since(si.refreshFailed) >= symbolRefreshRetryInterval {
+			if err := refresh(ctx); err != nil {
+				si.refreshFailed = time.Now()
+				log.Warnf(log.ExchangeSys, "Failed to load exchangeInfo for symbol %s, retrying in %v: %v", symbol, symbolRefreshRetryInterval, err)
+			} else {
+				si.refreshFailed = time.Time{}
+			}
+		}
+		si.refreshMu.Unlock()
+	}
+
+	if si.isEmpty() {
+		// exchangeInfo不可用且没有旧索引
+		return symbol.ToPair()
+	}
+
+	if pair, ok := si.lookup(name); ok {
+		return pair, nil
+	}
+	return currency.EMPTYPAIR, fmt.Errorf("%w: %s is not listed in Binance exchangeInfo", types.ErrInvalidSymbol, symbol)
+}
+
+// ParseSymbolViaExchangeInfo 根据exchangeInfo中的baseAsset/quoteAsset解析交易对符号
+func (b *BinanceRestAPI) ParseSymbolViaExchangeInfo(ctx context.Context, symbol types.Symbol) (currency.Pair, error) {
+	return b.symbols.resolve(ctx, symbol, func(ctx context.Context) error {
+		_, err := b.GetExchangeInfo(ctx)
+		return err
+	})
+}
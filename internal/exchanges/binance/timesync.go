@@ -0,0 +1,167 @@
+package binance
+
+import (
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+const (
+	defaultTimeSyncInterval = time.Minute     // 默认时间同步间隔
+	defaultRecvWindow       = 5 * time.Second // 默认recvWindow（Binance默认值5000ms）
+)
+
+// TimeSync 监控本地时钟与Binance服务器时钟的偏移，并用于校正签名请求的timestamp
+type TimeSync struct {
+	mu         sync.RWMutex
+	offset     time.Duration // 服务器时间 - 本地时间
+	roundTrip  time.Duration // 最近一次同步的往返耗时
+	lastSync   time.Time     // 最近一次同步时间
+	lastError  string        // 最近一次同步错误
+	recvWindow time.Duration // 签名请求的recvWindow
+
+	// fetchServerTime 请求一次服务器时间（偏移由请求方通过Record记录）
+	fetchServerTime func(ctx context.Context) error
+}
+
+// NewTimeSync 创建时间偏移监控器
+func NewTimeSync(fetchServerTime func(ctx context.Context) error, recvWindow time.Duration) *TimeSync {
+	if recvWindow <= 0 {
+		recvWindow = defaultRecvWindow
+	}
+	return &TimeSync{
+		recvWindow:      recvWindow,
+		fetchServerTime: fetchServerTime,
+	}
+}
+
+// SetRecvWindow 设置recvWindow
+func (ts *TimeSync) SetRecvWindow(recvWindow time.Duration) {
+	if recvWindow <= 0 {
+		return
+	}
+	ts.mu.Lock()
+	ts.recvWindow = recvWindow
+	ts.mu.Unlock()
+}
+
+// Record 根据服务器时间和请求的本地发送/接收时间记录时钟偏移
+// 以请求往返的中点作为服务器时间对应的本地时间
+func (ts *TimeSync) Record(serverTimeMs int64, sentAt, receivedAt time.Time) {
+	roundTrip := receivedAt.Sub(sentAt)
+	localMid := sentAt.Add(roundTrip / 2)
+	offset := time.UnixMilli(serverTimeMs).Sub(localMid)
+
+	ts.mu.Lock()
+	ts.offset = offset
+	ts.roundTrip = roundTrip
+	ts.lastSync = receivedAt
+	ts.lastError = ""
+	recvWindow := ts.recvWindow
+	ts.mu.Unlock()
+
+	if offset.Abs() > recvWindow {
+		log.Warnf(log.ExchangeSys, "Binance server time offset %v exceeds recvWindow %v, signed requests may be rejected",
+			offset, recvWindow)
+	} else {
+		log.Debugf(log.ExchangeSys, "Binance server time offset: %v (round trip: %v)", offset, roundTrip)
+	}
+}
+
+// Sync 立即同步一次服务器时间
+func (ts *TimeSync) Sync(ctx context.Context) error {
+	if err := ts.fetchServerTime(ctx); err != nil {
+		ts.mu.Lock()
+		ts.lastError = err.Error()
+		ts.mu.Unlock()
+		return err
+	}
+	return nil
+}
+
+// Run 定期同步服务器时间，直到ctx取消
+func (ts *TimeSync) Run(ctx context.Context, interval time.Duration) {
+	if interval <= 0 {
+		interval = defaultTimeSyncInterval
+	}
+
+	if err := ts.Sync(ctx); err != nil {
+		log.Warnf(log.ExchangeSys, "Failed to sync Binance server time: %v", err)
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			log.Debugf(log.ExchangeSys, "Binance time sync stopped")
+			return
+		case <-ticker.C:
+			if err := ts.Sync(ctx); err != nil {
+				log.Warnf(log.ExchangeSys, "Failed to sync Binance server time: %v", err)
+			}
+		}
+	}
+}
+
+// Offset 获取当前的时钟偏移（服务器时间 - 本地时间）
+func (ts *TimeSync) Offset() time.Duration {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+	return ts.offset
+}
+
+// Now 获取校正后的当前时间（服务器时钟）
+func (ts *TimeSync) Now() time.Time {
+	return time.Now().Add(ts.Offset())
+}
+
+// Timestamp 获取校正后的毫秒时间戳，用于签名请求的timestamp参数
+func (ts *TimeSync) Timestamp() int64 {
+	return ts.Now().UnixMilli()
+}
+
+// SignParams 为认证请求添加timestamp、recvWindow和signature参数
+func (ts *TimeSync) SignParams(params url.Values, apiSecret string) url.Values {
+	if params == nil {
+		params = url.Values{}
+	}
+
+	ts.mu.RLock()
+	recvWindow := ts.recvWindow
+	ts.mu.RUnlock()
+
+	params.Set("timestamp", strconv.FormatInt(ts.Timestamp(), 10))
+	params.Set("recvWindow", strconv.FormatInt(recvWindow.Milliseconds(), 10))
+
+	mac := hmac.New(sha256.New, []byte(apiSecret))
+	mac.Write([]byte(params.Encode()))
+	params.Set("signature", hex.EncodeToString(mac.Sum(nil)))
+	return params
+}
+
+// GetStatus 获取时间同步状态
+func (ts *TimeSync) GetStatus() map[string]interface{} {
+	ts.mu.RLock()
+	defer ts.mu.RUnlock()
+
+	status := map[string]interface{}{
+		"offset_ms":           ts.offset.Milliseconds(),
+		"round_trip_ms":       ts.roundTrip.Milliseconds(),
+		"recv_window_ms":      ts.recvWindow.Milliseconds(),
+		"exceeds_recv_window": ts.offset.Abs() > ts.recvWindow,
+		"last_sync":           ts.lastSync,
+	}
+	if ts.lastError != "" {
+		status["last_error"] = ts.lastError
+	}
+	return status
+}
@@ -88,6 +88,21 @@ type filterData struct {
 	MaxNumOrders        int64      `json:"maxNumOrders"`          // 最大订单数
 }
 
+// SymbolInfo 可交易交易对的详细元数据
+type SymbolInfo struct {
+	Symbol                 string        // 交易对（如BTCUSDT）
+	Pair                   currency.Pair // 交易对（基础资产/计价资产）
+	Status                 string        // 状态
+	BaseAsset              string        // 基础资产
+	QuoteAsset             string        // 计价资产
+	BaseAssetPrecision     int           // 基础资产精度
+	QuotePrecision         int           // 计价精度
+	OrderTypes             []string      // 支持的订单类型
+	IsSpotTradingAllowed   bool          // 是否允许现货交易
+	IsMarginTradingAllowed bool          // 是否允许保证金交易
+	Permissions            []string      // 权限
+}
+
 // CoinInfo 存储所有支持币种的信息
 type CoinInfo struct {
 	Coin              string  `json:"coin"`              // 币种
@@ -259,6 +274,16 @@ type TickerStream struct {
 	NumberOfTrades         int64        `json:"n"` // 成交笔数
 }
 
+// BookTickerStream 保存最优挂单流数据
+type BookTickerStream struct {
+	UpdateID        int64        `json:"u"` // 更新ID
+	Symbol          string       `json:"s"` // 交易对
+	BestBidPrice    types.Number `json:"b"` // 最佳买价
+	BestBidQuantity types.Number `json:"B"` // 最佳买价数量
+	BestAskPrice    types.Number `json:"a"` // 最佳卖价
+	BestAskQuantity types.Number `json:"A"` // 最佳卖价数量
+}
+
 // HistoricalTrade 保存历史交易数据
 type HistoricalTrade struct {
 	ID            int64      `json:"id"`              // 交易ID
@@ -340,8 +365,9 @@ func (c *CandleStick) UnmarshalJSON(data []byte) error {
 
 // AveragePrice 保存当前平均交易对价格
 type AveragePrice struct {
-	Mins  int64   `json:"mins"`         // 分钟数
-	Price float64 `json:"price,string"` // 价格
+	Mins      int64      `json:"mins"`         // 分钟数
+	Price     float64    `json:"price,string"` // 价格
+	CloseTime types.Time `json:"closeTime"`    // 最后一笔成交时间
 }
 
 // PriceChangeStats 包含最近24小时交易统计信息
@@ -0,0 +1,276 @@
+package binance
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/mooyang-code/data-miner/internal/exchanges/httpclient"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/encoding/json"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// userDataKeepAliveInterval listenKey保活间隔，Binance要求60分钟内至少保活一次
+const userDataKeepAliveInterval = 30 * time.Minute
+
+// 用户数据流事件类型
+const (
+	userEventAccountPosition = "outboundAccountPosition" // 账户余额变化
+	userEventBalanceUpdate   = "balanceUpdate"           // 充值、提现或划转导致的余额更新
+	userEventExecutionReport = "executionReport"         // 订单更新
+	userEventListStatus      = "listStatus"              // OCO订单列表状态
+)
+
+// errAPIKeyRequired 用户数据流接口需要API Key
+var errAPIKeyRequired = errors.New("binance API key is required for user data stream")
+
+// errAPISecretRequired 签名接口需要API Key和Secret
+var errAPISecretRequired = errors.New("binance API key and secret are required for signed requests")
+
+// UserDataCallback 用户数据流回调函数，event为以下类型之一：
+// *WsAccountPositionData、*WsBalanceUpdateData、*WsOrderUpdateData、*WsListStatusData
+type UserDataCallback func(event interface{}) error
+
+// StartUserDataStream 创建用户数据流，返回listenKey
+func (b *BinanceRestAPI) StartUserDataStream(ctx context.Context) (string, error) {
+	var resp UserAccountStream
+	if err := b.sendAPIKeyRequest(ctx, http.MethodPost, userAccountStream, &resp); err != nil {
+		return "", fmt.Errorf("failed to start user data stream: %w", err)
+	}
+	if resp.ListenKey == "" {
+		return "", errors.New("failed to start user data stream: empty listenKey")
+	}
+	return resp.ListenKey, nil
+}
+
+// KeepAliveUserDataStream 延长listenKey的有效期（有效期60分钟）
+func (b *BinanceRestAPI) KeepAliveUserDataStream(ctx context.Context, listenKey string) error {
+	path := userAccountStream + "?" + url.Values{"listenKey": {listenKey}}.Encode()
+	if err := b.sendAPIKeyRequest(ctx, http.MethodPut, path, nil); err != nil {
+		return fmt.Errorf("failed to keep alive user data stream: %w", err)
+	}
+	return nil
+}
+
+// CloseUserDataStream 关闭用户数据流
+func (b *BinanceRestAPI) CloseUserDataStream(ctx context.Context, listenKey string) error {
+	path := userAccountStream + "?" + url.Values{"listenKey": {listenKey}}.Encode()
+	if err := b.sendAPIKeyRequest(ctx, http.MethodDelete, path, nil); err != nil {
+		return fmt.Errorf("failed to close user data stream: %w", err)
+	}
+	return nil
+}
+
+// sendAPIKeyRequest 发送只需要API Key（无需签名）的请求
+func (b *BinanceRestAPI) sendAPIKeyRequest(ctx context.Context, method, path string, result interface{}) error {
+	if b.config.APIKey == "" {
+		return errAPIKeyRequired
+	}
+
+	resp, err := b.httpClient.DoRequest(ctx, &httpclient.Request{
+		Method:  method,
+		URL:     b.getBaseURL() + path,
+		Headers: map[string]string{"X-MBX-APIKEY": b.config.APIKey},
+		Result:  result,
+	})
+	if err != nil {
+		return err
+	}
+	b.recordUsedWeight(resp)
+	return nil
+}
+
+// SubscribeUserDataStream 通过listenKey订阅用户数据流，替换之前订阅的用户数据流
+func (ws *BinanceWebSocket) SubscribeUserDataStream(listenKey string, callback UserDataCallback) error {
+	if !ws.wsConnected.Load() {
+		return errors.New("WebSocket未连接")
+	}
+
+	ws.mu.Lock()
+	oldKey := ws.userDataKey
+	ws.userDataKey = listenKey
+	ws.userDataCallback = callback
+	ws.mu.Unlock()
+
+	if oldKey != "" && oldKey != listenKey {
+		if err := ws.Unsubscribe([]string{oldKey}); err != nil {
+			log.Warnf(log.WebsocketMgr, "取消订阅旧的用户数据流失败: %v", err)
+		}
+	}
+	return ws.Subscribe([]string{listenKey})
+}
+
+// UnsubscribeUserDataStream 取消订阅用户数据流
+func (ws *BinanceWebSocket) UnsubscribeUserDataStream() error {
+	ws.mu.Lock()
+	listenKey := ws.userDataKey
+	ws.userDataKey = ""
+	ws.userDataCallback = nil
+	ws.mu.Unlock()
+
+	if listenKey == "" || !ws.wsConnected.Load() {
+		return nil
+	}
+	return ws.Unsubscribe([]string{listenKey})
+}
+
+// getUserDataCallback 获取用户数据流的回调函数，stream不是当前用户数据流时返回false
+func (ws *BinanceWebSocket) getUserDataCallback(stream string) (UserDataCallback, bool) {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	if ws.userDataKey == "" || stream != ws.userDataKey {
+		return nil, false
+	}
+	return ws.userDataCallback, true
+}
+
+// handleUserDataStream 解析用户数据流事件并调用回调
+func (ws *BinanceWebSocket) handleUserDataStream(callback UserDataCallback, data []byte) error {
+	event, err := parseUserDataEvent(data)
+	if err != nil {
+		return err
+	}
+	if event == nil || callback == nil {
+		return nil
+	}
+	return callback(event)
+}
+
+// parseUserDataEvent 根据事件类型将用户数据流数据解析为对应的结构，未知事件返回nil
+func parseUserDataEvent(data []byte) (interface{}, error) {
+	eventType, err := jsonparser.GetString(data, "e")
+	if err != nil {
+		return nil, fmt.Errorf("用户数据流缺少事件类型: %v", err)
+	}
+
+	var event interface{}
+	switch eventType {
+	case userEventAccountPosition:
+		event = &WsAccountPositionData{}
+	case userEventBalanceUpdate:
+		event = &WsBalanceUpdateData{}
+	case userEventExecutionReport:
+		event = &WsOrderUpdateData{}
+	case userEventListStatus:
+		event = &WsListStatusData{}
+	default:
+		log.Debugf(log.WebsocketMgr, "未处理的用户数据流事件: %s", eventType)
+		return nil, nil
+	}
+
+	if err := json.Unmarshal(data, event); err != nil {
+		return nil, fmt.Errorf("解析用户数据流事件%s失败: %v", eventType, err)
+	}
+	return event, nil
+}
+
+// SubscribeUserData 创建用户数据流并订阅账户、余额和订单更新事件，
+// 同时每30分钟保活listenKey，保活失败时重新创建listenKey并重新订阅
+func (b *Binance) SubscribeUserData(ctx context.Context, callback UserDataCallback) error {
+	// 先停止之前的用户数据流，避免同时保活多个listenKey
+	b.stopUserDataStream()
+
+	listenKey, err := b.RestAPI.StartUserDataStream(ctx)
+	if err != nil {
+		return err
+	}
+	if err := b.WebSocket.SubscribeUserDataStream(listenKey, callback); err != nil {
+		// 订阅失败时关闭刚创建的listenKey，避免其在服务端保留到过期
+		b.closeListenKey(listenKey)
+		return fmt.Errorf("failed to subscribe user data stream: %w", err)
+	}
+
+	keepAliveCtx, cancel := context.WithCancel(context.Background())
+	b.mu.Lock()
+	b.userDataKey = listenKey
+	b.userDataCancel = cancel
+	b.mu.Unlock()
+
+	b.userDataWg.Add(1)
+	go func() {
+		defer b.userDataWg.Done()
+		b.keepAliveUserDataStream(keepAliveCtx, callback)
+	}()
+	return nil
+}
+
+// keepAliveUserDataStream 定期保活listenKey，直到ctx被取消
+func (b *Binance) keepAliveUserDataStream(ctx context.Context, callback UserDataCallback) {
+	ticker := time.NewTicker(userDataKeepAliveInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-b.WebSocket.Done():
+			return
+		case <-ticker.C:
+			b.mu.RLock()
+			listenKey := b.userDataKey
+			b.mu.RUnlock()
+
+			err := b.RestAPI.KeepAliveUserDataStream(ctx, listenKey)
+			if err == nil || ctx.Err() != nil {
+				continue
+			}
+			log.Warnf(log.ExchangeSys, "Failed to keep alive Binance user data stream, recreating listenKey: %v", err)
+
+			newKey, err := b.RestAPI.StartUserDataStream(ctx)
+			if err != nil {
+				log.Errorf(log.ExchangeSys, "Failed to recreate Binance user data stream: %v", err)
+				continue
+			}
+			b.mu.Lock()
+			stopped := ctx.Err() != nil
+			if !stopped {
+				b.userDataKey = newKey
+			}
+			b.mu.Unlock()
+			if stopped {
+				return
+			}
+			if err := b.WebSocket.SubscribeUserDataStream(newKey, callback); err != nil {
+				log.Errorf(log.ExchangeSys, "Failed to resubscribe Binance user data stream: %v", err)
+			}
+		}
+	}
+}
+
+// stopUserDataStream 停止listenKey保活并等待保活协程退出，然后取消订阅并关闭用户数据流
+func (b *Binance) stopUserDataStream() {
+	b.mu.Lock()
+	cancel := b.userDataCancel
+	b.userDataCancel = nil
+	b.mu.Unlock()
+
+	if cancel == nil {
+		return
+	}
+	cancel()
+	b.userDataWg.Wait()
+
+	// 保活协程可能已重新创建listenKey，等待其退出后再读取
+	b.mu.Lock()
+	listenKey := b.userDataKey
+	b.userDataKey = ""
+	b.mu.Unlock()
+
+	if err := b.WebSocket.UnsubscribeUserDataStream(); err != nil {
+		log.Warnf(log.ExchangeSys, "Failed to unsubscribe Binance user data stream: %v", err)
+	}
+	b.closeListenKey(listenKey)
+}
+
+// closeListenKey 关闭listenKey，失败时只记录日志
+func (b *Binance) closeListenKey(listenKey string) {
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := b.RestAPI.CloseUserDataStream(ctx, listenKey); err != nil {
+		log.Warnf(log.ExchangeSys, "Failed to close Binance user data stream: %v", err)
+	}
+}
@@ -6,13 +6,18 @@ import (
 	"crypto/tls"
 	"errors"
 	"fmt"
+	"math/rand/v2"
+	"net"
 	"net/http"
+	"net/url"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/buger/jsonparser"
 	gws "github.com/gorilla/websocket"
+	"github.com/mooyang-code/data-miner/internal/exchanges/httpclient"
 	"github.com/mooyang-code/data-miner/internal/ipmanager"
 	"github.com/mooyang-code/data-miner/internal/types"
 	"github.com/mooyang-code/data-miner/pkg/cryptotrader/encoding/json"
@@ -21,163 +26,419 @@ import (
 
 // BinanceWebSocket WebSocket客户端
 type BinanceWebSocket struct {
-	wsConn        *gws.Conn                     // WebSocket连接
-	wsConnected   bool                          // WebSocket连接状态
-	lastPing      time.Time                     // 最后ping时间
-	ipManager     *ipmanager.Manager            // IP管理器
-	subscriptions map[string]types.DataCallback // 订阅回调映射
-	mu            sync.RWMutex                  // 读写锁
-	done          chan struct{}                 // 停止信号通道
+	wsConn        *gws.Conn                      // WebSocket连接
+	wsConnected   atomic.Bool                    // WebSocket连接状态
+	state         atomic.Int32                   // 连接状态（ConnState）
+	connectedAt   atomic.Int64                   // 当前连接建立时间（纳秒时间戳）
+	lastPing      atomic.Int64                   // 最后收到服务端ping的时间（纳秒时间戳）
+	lastData      atomic.Int64                   // 最后收到数据消息的时间（纳秒时间戳）
+	ipManager     *ipmanager.Manager             // IP管理器
+	proxyURL      *url.URL                       // 代理地址，设置后经代理连接域名，不使用IP管理器
+	tlsConfig     *tls.Config                    // 自定义TLS配置，设置后校验服务端证书
+	subscriptions map[string]callbackList        // 订阅回调映射，同一频道可注册多个回调
+	callbackSeq   atomic.Uint64                  // 回调标识序号
+	requestSeq    atomic.Int64                   // 订阅/取消订阅请求id序号
+	pending       map[int64]*pendingRequest      // 等待响应的请求，按id关联交易所的响应
+	pendingMu     sync.Mutex                     // 保护pending
+	writeMu       sync.Mutex                     // 串行化请求写入，连接不支持并发写
+	ackTimeout    time.Duration                  // 订阅后等待交易所确认的超时时间，0表示不等待
+	mu            sync.RWMutex                   // 读写锁
+	done          chan struct{}                  // 停止信号通道，WsClose时关闭
+	readDone      chan struct{}                  // 当前连接的读取协程退出信号
+	closeOnce     sync.Once                      // 保证WsClose只执行一次
+	wg            sync.WaitGroup                 // 跟踪读取、重连等后台协程
+	reconnect     types.WebsocketReconnectConfig // 断线重连退避配置
+	logs          *logThrottle                   // 连接错误日志节流，避免重连风暴时刷屏
+
+	userDataKey      string           // 用户数据流的listenKey
+	userDataCallback UserDataCallback // 用户数据流回调
+
+	books     *orderbookSyncer // 增量深度流维护的本地订单簿
+	booksOnce sync.Once
+	stats     *streamStats // 按流类型统计的消息数
+	statsOnce sync.Once
+
+	compressionOff bool         // 握手时不请求permessage-deflate压缩
+	wireBytes      atomic.Int64 // 从网络读取的字节数（压缩和TLS加密后）
+	payloadBytes   atomic.Int64 // 解压后的消息字节数
 }
 
 // NewWebSocket 创建新的WebSocket客户端
 func NewWebSocket() *BinanceWebSocket {
 	return &BinanceWebSocket{
-		ipManager:     ipmanager.New(ipmanager.DefaultConfig("stream.binance.com")),
-		subscriptions: make(map[string]types.DataCallback),
+		subscriptions: make(map[string]callbackList),
 		done:          make(chan struct{}),
+		reconnect:     reconnectConfigWithDefaults(types.WebsocketReconnectConfig{}),
+		logs:          newLogThrottle(defaultLogThrottleInterval),
 	}
 }
 
 const (
-	binanceWebsocketPort = "9443"        // Binance WebSocket端口
-	binanceWebsocketPath = "/stream"     // WebSocket路径
-	wsSubscribeMethod    = "SUBSCRIBE"   // 订阅方法
-	wsUnsubscribeMethod  = "UNSUBSCRIBE" // 取消订阅方法
+	binanceWebsocketHost = "stream.binance.com" // Binance WebSocket域名
+	binanceWebsocketPort = "9443"               // Binance WebSocket端口
+	binanceWebsocketPath = "/stream"            // WebSocket路径
+	wsSubscribeMethod    = "SUBSCRIBE"          // 订阅方法
+	wsUnsubscribeMethod  = "UNSUBSCRIBE"        // 取消订阅方法
+	wsAllTickersStream   = "!ticker@arr"        // 全市场24小时行情流
+	wsCloseTimeout       = time.Second          // 关闭连接时等待服务端确认关闭帧的时间
+
+	defaultReconnectBaseDelay   = 5 * time.Second // 默认首次重连的退避上限
+	defaultReconnectMaxDelay    = 2 * time.Minute // 默认退避上限的最大值
+	defaultReconnectMaxAttempts = 5               // 默认最大重连次数
 )
 
+// reconnectConfigWithDefaults 为未设置的重连配置项填充默认值
+func reconnectConfigWithDefaults(config types.WebsocketReconnectConfig) types.WebsocketReconnectConfig {
+	if config.BaseDelay <= 0 {
+		config.BaseDelay = defaultReconnectBaseDelay
+	}
+	if config.MaxDelay <= 0 {
+		config.MaxDelay = defaultReconnectMaxDelay
+	}
+	if config.MaxDelay < config.BaseDelay {
+		config.MaxDelay = config.BaseDelay
+	}
+	if config.MaxAttempts <= 0 {
+		config.MaxAttempts = defaultReconnectMaxAttempts
+	}
+	if config.LogThrottleInterval <= 0 {
+		config.LogThrottleInterval = defaultLogThrottleInterval
+	}
+	return config
+}
+
+// reconnectDelay 计算第attempt次（从1开始）重连前的等待时间：指数退避加全抖动，
+// 在0到min(MaxDelay, BaseDelay*2^(attempt-1))之间均匀随机，使同时断开的连接错开重连
+func reconnectDelay(config types.WebsocketReconnectConfig, attempt int) time.Duration {
+	ceiling := config.BaseDelay
+	for i := 1; i < attempt && ceiling < config.MaxDelay; i++ {
+		ceiling *= 2
+	}
+	ceiling = min(ceiling, config.MaxDelay)
+	return time.Duration(rand.Int64N(int64(ceiling) + 1))
+}
+
+// SetReconnectConfig 设置断线重连的退避配置，未设置的项使用默认值
+func (ws *BinanceWebSocket) SetReconnectConfig(config types.WebsocketReconnectConfig) {
+	ws.mu.Lock()
+	ws.reconnect = reconnectConfigWithDefaults(config)
+	ws.mu.Unlock()
+	if ws.logs != nil {
+		ws.logs.setInterval(config.LogThrottleInterval)
+	}
+}
+
+// SetProxy 设置WebSocket代理（http://、https://、socks5://），需在连接前调用
+func (ws *BinanceWebSocket) SetProxy(rawURL string) error {
+	proxyURL, err := httpclient.ParseProxyURL(rawURL)
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	ws.proxyURL = proxyURL
+	ws.mu.Unlock()
+	log.Infof(log.WebsocketMgr, "WebSocket using proxy %s, dynamic IP bypassed", proxyURL.Redacted())
+	return nil
+}
+
+// SetTLSConfig 设置TLS客户端配置（最低版本、自定义CA、SNI覆盖），需在连接前调用。
+// 未配置任何选项时保持默认行为
+func (ws *BinanceWebSocket) SetTLSConfig(config *httpclient.TLSConfig) error {
+	tlsConfig, err := config.Build()
+	if err != nil {
+		return err
+	}
+	ws.mu.Lock()
+	ws.tlsConfig = tlsConfig
+	ws.mu.Unlock()
+	return nil
+}
+
 // WsConnect 初始化WebSocket连接
 func (ws *BinanceWebSocket) WsConnect() error {
-	return ws.wsConnectWithRetry(3)
+	ws.setState(ConnStateConnecting)
+	if err := ws.wsConnectWithRetry(3); err != nil {
+		ws.setState(ConnStateDisconnected)
+		return err
+	}
+	return nil
 }
 
 // wsConnectWithRetry 尝试连接WebSocket，支持重试和IP切换
 func (ws *BinanceWebSocket) wsConnectWithRetry(maxRetries int) error {
-	// 启动IP管理器（如果还没启动）
-	if !ws.ipManager.IsRunning() {
-		ctx := context.Background() // 在实际应用中，应该传入合适的context
-		if err := ws.ipManager.Start(ctx); err != nil {
+	if ws.isClosed() {
+		return errors.New("WebSocket已关闭")
+	}
+
+	// 配置代理时经代理直接连接域名，IP替换由代理出口决定
+	ws.mu.RLock()
+	proxyURL := ws.proxyURL
+	ws.mu.RUnlock()
+	if proxyURL != nil {
+		return ws.wsConnectViaProxy(proxyURL, maxRetries)
+	}
+
+	// 获取共享的IP管理器（如果还没获取）
+	ws.mu.Lock()
+	if ws.ipManager == nil {
+		ipManager, err := ipmanager.Acquire(ipmanager.DefaultConfig(binanceWebsocketHost))
+		if err != nil {
+			ws.mu.Unlock()
 			return fmt.Errorf("failed to start IP manager: %v", err)
 		}
+		ws.ipManager = ipManager
 	}
+	ipManager := ws.ipManager
+	ws.mu.Unlock()
 
 	var lastErr error
 	for attempt := 0; attempt < maxRetries; attempt++ {
 		// 获取当前IP
-		ip, err := ws.ipManager.GetCurrentIP()
+		ip, err := ipManager.GetCurrentIP()
 		if err != nil {
-			return fmt.Errorf("failed to get IP from manager: %v", err)
+			// IP列表暂时为空时触发更新并重试，其他错误直接返回
+			if errors.Is(err, ipmanager.ErrNoAvailableIPs) && attempt < maxRetries-1 {
+				lastErr = err
+				log.Warnf(log.WebsocketMgr, "No available IPs, forcing IP update before retry: %v", err)
+				ipManager.ForceUpdate()
+				if !ws.sleep(time.Second * 2) {
+					return errors.New("WebSocket已关闭")
+				}
+				continue
+			}
+			return fmt.Errorf("failed to get IP from manager: %w", err)
 		}
 
 		// 构建WebSocket URL
 		wsURL := fmt.Sprintf("wss://%s:%s%s", ip, binanceWebsocketPort, binanceWebsocketPath)
 		log.Debugf(log.WebsocketMgr, "Attempting to connect to: %s (attempt %d/%d)", wsURL, attempt+1, maxRetries)
 
-		// 尝试连接
-		conn, resp, err := ws.dialWebSocket(wsURL)
+		// 尝试连接，连接的带宽统计从拨号前开始，包含TLS和WebSocket握手读取的字节
+		wireStart := ws.wireBytes.Load()
+		conn, resp, err := ws.dialWebSocket(wsURL, nil)
 		if err != nil {
 			lastErr = err
 			if resp != nil {
-				log.Errorf(log.WebsocketMgr, "WebSocket connection failed with status: %s", resp.Status)
+				ws.logs.logf(log.Errorf, "WebSocket connection failed with status: %s", resp.Status)
 			}
-			log.Warnf(log.WebsocketMgr, "Connection attempt %d failed: %v", attempt+1, err)
+			ws.logs.logf(log.Warnf, "Connection attempt %d failed: %v", attempt+1, err)
 
 			// 如果不是最后一次尝试，切换到下一个IP
 			if attempt < maxRetries-1 {
-				nextIP, switchErr := ws.ipManager.GetNextIP()
+				nextIP, switchErr := ipManager.GetNextIP()
 				if switchErr != nil {
 					log.Errorf(log.WebsocketMgr, "Failed to switch to next IP: %v", switchErr)
 				} else {
 					log.Infof(log.WebsocketMgr, "Switching to next IP: %s", nextIP)
 				}
-				time.Sleep(time.Second * 2) // 等待2秒后重试
+				// 等待2秒后重试
+				if !ws.sleep(time.Second * 2) {
+					return errors.New("WebSocket已关闭")
+				}
 			}
 			continue
 		}
 		if resp != nil {
 			log.Infof(log.WebsocketMgr, "WebSocket connection successful with status: %s, IP: %s", resp.Status, ip)
 		}
-
-		ws.wsConn = conn
-		ws.wsConnected = true
-		go ws.wsReadData()
-		return nil
+		ipManager.ReportSuccess(ip)
+		return ws.startConnection(conn, wireStart)
 	}
 
 	return fmt.Errorf("failed to connect after %d attempts, last error: %v", maxRetries, lastErr)
 }
 
-// dialWebSocket 执行实际的WebSocket连接
-func (ws *BinanceWebSocket) dialWebSocket(wsURL string) (*gws.Conn, *http.Response, error) {
+// wsConnectViaProxy 经代理连接WebSocket域名，失败时等待后重试
+func (ws *BinanceWebSocket) wsConnectViaProxy(proxyURL *url.URL, maxRetries int) error {
+	wsURL := fmt.Sprintf("wss://%s:%s%s", binanceWebsocketHost, binanceWebsocketPort, binanceWebsocketPath)
+
+	var lastErr error
+	for attempt := 0; attempt < maxRetries; attempt++ {
+		log.Debugf(log.WebsocketMgr, "Attempting to connect to %s via proxy %s (attempt %d/%d)",
+			wsURL, proxyURL.Redacted(), attempt+1, maxRetries)
+
+		wireStart := ws.wireBytes.Load()
+		conn, resp, err := ws.dialWebSocket(wsURL, proxyURL)
+		if err != nil {
+			lastErr = err
+			if resp != nil {
+				ws.logs.logf(log.Errorf, "WebSocket connection failed with status: %s", resp.Status)
+			}
+			ws.logs.logf(log.Warnf, "Connection attempt %d via proxy failed: %v", attempt+1, err)
+			if attempt < maxRetries-1 && !ws.sleep(time.Second*2) {
+				return errors.New("WebSocket已关闭")
+			}
+			continue
+		}
+		if resp != nil {
+			log.Infof(log.WebsocketMgr, "WebSocket connection successful with status: %s, proxy: %s", resp.Status, proxyURL.Redacted())
+		}
+		return ws.startConnection(conn, wireStart)
+	}
+
+	return fmt.Errorf("failed to connect via proxy after %d attempts, last error: %v", maxRetries, lastErr)
+}
+
+// startConnection 保存新建立的连接并启动读取协程，wireStart为拨号前从网络读取的字节数
+func (ws *BinanceWebSocket) startConnection(conn *gws.Conn, wireStart int64) error {
+	// 连接过程中WebSocket可能已被关闭，此时丢弃新连接
+	readDone := make(chan struct{})
+	ws.mu.Lock()
+	if ws.isClosed() {
+		ws.mu.Unlock()
+		conn.Close()
+		return errors.New("WebSocket已关闭")
+	}
+	ws.wsConn = conn
+	ws.readDone = readDone
+	ws.wsConnected.Store(true)
+	ws.markConnected(conn)
+	ws.clearPending()
+	ws.wg.Add(1)
+	ws.mu.Unlock()
+	go ws.wsReadData(conn, readDone, wireStart)
+	return nil
+}
+
+// dialWebSocket 执行实际的WebSocket连接，proxyURL不为空时经代理连接域名
+func (ws *BinanceWebSocket) dialWebSocket(wsURL string, proxyURL *url.URL) (*gws.Conn, *http.Response, error) {
 	// 配置拨号器的TLS设置以处理基于IP的连接
 	dialer := gws.Dialer{
-		HandshakeTimeout: 30 * time.Second,
-		Proxy:            http.ProxyFromEnvironment,
+		HandshakeTimeout:  30 * time.Second,
+		Proxy:             http.ProxyFromEnvironment,
+		EnableCompression: ws.compressionEnabled(), // 请求permessage-deflate压缩，全量订阅时显著减少带宽
 		TLSClientConfig: &tls.Config{
 			InsecureSkipVerify: true,
 			ServerName:         "stream.binance.com",
 		},
 	}
+	// 配置了TLS选项时按配置校验证书（连接IP时仍按域名校验）
+	ws.mu.RLock()
+	customTLS := ws.tlsConfig
+	ws.mu.RUnlock()
+	if customTLS != nil {
+		dialer.TLSClientConfig = customTLS.Clone()
+		if dialer.TLSClientConfig.ServerName == "" {
+			dialer.TLSClientConfig.ServerName = binanceWebsocketHost
+		}
+	}
+
+	if proxyURL != nil {
+		// 经代理连接的是域名，可以正常校验证书
+		if customTLS == nil {
+			dialer.TLSClientConfig = &tls.Config{ServerName: binanceWebsocketHost}
+		}
+		if proxyURL.Scheme == "https" {
+			// gorilla/websocket不支持https代理，自行建立到代理的TLS隧道
+			dialer.Proxy = nil
+			dialer.NetDialContext = func(ctx context.Context, network, addr string) (net.Conn, error) {
+				return httpclient.DialHTTPSProxy(ctx, proxyURL, addr)
+			}
+		} else {
+			dialer.Proxy = http.ProxyURL(proxyURL)
+		}
+	}
+
+	// 统计从网络读取的字节数，与解压后的消息字节数对比得到压缩节省的带宽
+	dialer.NetDialContext = ws.countingDialer(dialer.NetDialContext)
 
 	// 添加Binance期望的请求头
 	headers := http.Header{}
 	headers.Set("User-Agent", "crypto-data-miner/1.0.0")
 	headers.Set("Host", "stream.binance.com")
-	return dialer.Dial(wsURL, headers)
+	conn, resp, err := dialer.Dial(wsURL, headers)
+	if err == nil && dialer.EnableCompression && resp != nil {
+		log.Infof(log.WebsocketMgr, "WebSocket permessage-deflate压缩协商结果: %v",
+			negotiatedCompression(resp.Header.Get("Sec-WebSocket-Extensions")))
+	}
+	return conn, resp, err
 }
 
-// wsReadData 接收并传递WebSocket消息进行处理
-func (ws *BinanceWebSocket) wsReadData() {
+// wsReadData 接收并传递WebSocket消息进行处理，退出时关闭readDone。
+// wireStart为拨号前从网络读取的字节数，连接的带宽统计包含握手期间读取的字节
+func (ws *BinanceWebSocket) wsReadData(conn *gws.Conn, readDone chan struct{}, wireStart int64) {
+	defer ws.wg.Done()
+	payloadStart := ws.payloadBytes.Load()
 	defer func() {
-		if ws.wsConn != nil {
-			ws.wsConn.Close()
+		conn.Close()
+		close(readDone)
+		logConnectionBandwidth(ws.wireBytes.Load()-wireStart, ws.payloadBytes.Load()-payloadStart)
+
+		// 主动关闭时不再重连
+		if ws.isClosed() {
+			return
 		}
-		ws.wsConnected = false
+		ws.wsConnected.Store(false)
+		ws.setState(ConnStateReconnecting)
 
 		// 尝试重连
+		ws.wg.Add(1)
 		go ws.attemptReconnect()
 	}()
 
 	for {
-		if !ws.wsConnected {
+		select {
+		case <-ws.done:
+			return
+		default:
+		}
+		if !ws.wsConnected.Load() {
 			return
 		}
 
-		_, message, err := ws.wsConn.ReadMessage()
+		_, message, err := conn.ReadMessage()
 		if err != nil {
-			log.Errorf(log.WebsocketMgr, "WebSocket读取错误: %v", err)
+			if ws.isClosed() && gws.IsCloseError(err, gws.CloseNormalClosure) {
+				log.Debugf(log.WebsocketMgr, "WebSocket已正常关闭")
+				return
+			}
+			ws.logs.logf(log.Errorf, "WebSocket读取错误: %v", err)
 			return
 		}
+		ws.lastData.Store(time.Now().UnixNano())
+		ws.payloadBytes.Add(int64(len(message)))
 
 		err = ws.wsHandleData(message)
 		if err != nil {
-			log.Errorf(log.WebsocketMgr, "WebSocket处理数据错误: %v", err)
+			ws.logs.logf(log.Errorf, "WebSocket处理数据错误: %v", err)
 		}
 	}
 }
 
 // attemptReconnect 尝试重新连接WebSocket
 func (ws *BinanceWebSocket) attemptReconnect() {
-	maxReconnectAttempts := 5
-	baseDelay := time.Second * 5
-
-	for attempt := 1; attempt <= maxReconnectAttempts; attempt++ {
-		log.Infof(log.WebsocketMgr, "Attempting to reconnect WebSocket (attempt %d/%d)", attempt, maxReconnectAttempts)
+	defer ws.wg.Done()
+	ws.mu.RLock()
+	config := ws.reconnect
+	ws.mu.RUnlock()
 
-		// 指数退避延迟
-		delay := time.Duration(attempt) * baseDelay
-		time.Sleep(delay)
+	for attempt := 1; attempt <= config.MaxAttempts; attempt++ {
+		// 指数退避加全抖动，首次重连的延迟也随机，避免大量连接同时断开后同步重连
+		delay := reconnectDelay(config, attempt)
+		log.Infof(log.WebsocketMgr, "Attempting to reconnect WebSocket in %v (attempt %d/%d)", delay.Round(time.Millisecond), attempt, config.MaxAttempts)
+		if !ws.sleep(delay) {
+			log.Infof(log.WebsocketMgr, "WebSocket已关闭，停止重连")
+			return
+		}
 
 		// 强制更新IP列表
-		if ws.ipManager != nil {
-			ws.ipManager.ForceUpdate()
-			time.Sleep(time.Second * 2) // 等待IP更新
+		ws.mu.RLock()
+		ipManager := ws.ipManager
+		ws.mu.RUnlock()
+		if ipManager != nil {
+			ipManager.ForceUpdate()
+			// 等待IP更新
+			if !ws.sleep(time.Second * 2) {
+				log.Infof(log.WebsocketMgr, "WebSocket已关闭，停止重连")
+				return
+			}
 		}
 
 		// 尝试重连
 		err := ws.wsConnectWithRetry(2) // 每次重连尝试2个IP
 		if err == nil {
 			log.Infof(log.WebsocketMgr, "WebSocket reconnected successfully")
+			ws.logs.flushSummary()
 
 			// 重新订阅之前的频道
 			if err := ws.resubscribeChannels(); err != nil {
@@ -186,18 +447,22 @@ func (ws *BinanceWebSocket) attemptReconnect() {
 			return
 		}
 
-		log.Errorf(log.WebsocketMgr, "Reconnection attempt %d failed: %v", attempt, err)
+		ws.logs.logf(log.Errorf, "Reconnection attempt %d failed: %v", attempt, err)
 	}
-	log.Errorf(log.WebsocketMgr, "Failed to reconnect after %d attempts", maxReconnectAttempts)
+	ws.setState(ConnStateDisconnected)
+	ws.logs.logf(log.Errorf, "Failed to reconnect after %d attempts", config.MaxAttempts)
 }
 
 // resubscribeChannels 重新订阅频道
 func (ws *BinanceWebSocket) resubscribeChannels() error {
 	ws.mu.RLock()
-	channels := make([]string, 0, len(ws.subscriptions))
+	channels := make([]string, 0, len(ws.subscriptions)+1)
 	for channel := range ws.subscriptions {
 		channels = append(channels, channel)
 	}
+	if ws.userDataKey != "" {
+		channels = append(channels, ws.userDataKey)
+	}
 	ws.mu.RUnlock()
 
 	if len(channels) == 0 {
@@ -221,21 +486,10 @@ func (ws *BinanceWebSocket) wsHandleData(respRaw []byte) error {
 		return fmt.Errorf("无效的JSON数据: %v", err)
 	}
 
-	// 检查是否为订阅响应
+	// 检查是否为订阅响应，按id关联发送的请求
 	if id, err := jsonparser.GetInt(respRaw, "id"); err == nil {
 		log.Debugf(log.WebsocketMgr, "接收到订阅响应，ID: %d", id)
-		if result, err := jsonparser.GetUnsafeString(respRaw, "result"); err == nil {
-			if result == "null" {
-				log.Debugf(log.WebsocketMgr, "订阅成功，ID: %d", id)
-				return nil
-			}
-		}
-		// 检查响应中的错误
-		if errorMsg, err := jsonparser.GetUnsafeString(respRaw, "error", "msg"); err == nil {
-			log.Errorf(log.WebsocketMgr, "订阅错误: %s", errorMsg)
-			return fmt.Errorf("订阅错误: %s", errorMsg)
-		}
-		return nil
+		return ws.handleRequestResponse(id, respRaw)
 	}
 
 	// 解析流数据
@@ -247,6 +501,7 @@ func (ws *BinanceWebSocket) wsHandleData(respRaw []byte) error {
 	}
 
 	log.Debugf(log.WebsocketMgr, "处理流: %s", streamStr)
+	ws.streamStatsOrInit().record(streamTypeOf(streamStr), time.Now())
 
 	// 从流消息中提取数据
 	data, _, _, err := jsonparser.Get(respRaw, "data")
@@ -255,6 +510,16 @@ func (ws *BinanceWebSocket) wsHandleData(respRaw []byte) error {
 		return fmt.Errorf("从流中提取数据失败: %v", err)
 	}
 
+	// 用户数据流以listenKey作为流名称，不包含@
+	if callback, ok := ws.getUserDataCallback(streamStr); ok {
+		return ws.handleUserDataStream(callback, data)
+	}
+
+	// 全市场行情流的数据为JSON数组，需要单独处理
+	if streamStr == wsAllTickersStream {
+		return ws.handleAllTickersStream(streamStr, data)
+	}
+
 	// 基本流类型检测
 	streamType := strings.Split(streamStr, "@")
 	if len(streamType) <= 1 {
@@ -266,6 +531,8 @@ func (ws *BinanceWebSocket) wsHandleData(respRaw []byte) error {
 
 	// 处理不同的流类型
 	switch {
+	case streamType[1] == "bookTicker":
+		return ws.handleBookTickerStream(streamStr, data)
 	case strings.Contains(streamType[1], "trade"):
 		return ws.handleTradeStream(streamStr, data)
 	case strings.Contains(streamType[1], "ticker"):
@@ -312,6 +579,69 @@ func (ws *BinanceWebSocket) handleTickerStream(streamName string, data []byte) e
 	return nil
 }
 
+// handleBookTickerStream 处理最优挂单流数据
+func (ws *BinanceWebSocket) handleBookTickerStream(streamName string, data []byte) error {
+	log.Debugf(log.WebsocketMgr, "最优挂单流数据: %s", string(data))
+
+	callback, exists := ws.getSubscriptionCallback(streamName)
+	if !exists || callback == nil {
+		return nil
+	}
+
+	var stream BookTickerStream
+	if err := json.Unmarshal(data, &stream); err != nil {
+		return fmt.Errorf("解析最优挂单数据失败: %v", err)
+	}
+
+	bookTicker := &types.BookTicker{
+		Exchange:    types.ExchangeBinance,
+		Symbol:      types.Symbol(stream.Symbol),
+		UpdateID:    stream.UpdateID,
+		BidPrice:    stream.BestBidPrice.Float64(),
+		BidQuantity: stream.BestBidQuantity.Float64(),
+		AskPrice:    stream.BestAskPrice.Float64(),
+		AskQuantity: stream.BestAskQuantity.Float64(),
+		Timestamp:   time.Now(), // bookTicker流不携带事件时间，使用本地接收时间
+	}
+	return callback(bookTicker)
+}
+
+// handleAllTickersStream 处理全市场行情流数据，将数组中的每个行情分发给回调
+func (ws *BinanceWebSocket) handleAllTickersStream(streamName string, data []byte) error {
+	callback, exists := ws.getSubscriptionCallback(streamName)
+	if !exists || callback == nil {
+		return nil
+	}
+
+	var streams []TickerStream
+	if err := json.Unmarshal(data, &streams); err != nil {
+		return fmt.Errorf("解析全市场行情数据失败: %v", err)
+	}
+	log.Debugf(log.WebsocketMgr, "全市场行情流数据: %d 个交易对", len(streams))
+
+	var errs []error
+	for i := range streams {
+		if err := callback(convertTickerStream(&streams[i])); err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", streams[i].Symbol, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// convertTickerStream 将行情流数据转换为通用行情数据
+func convertTickerStream(stream *TickerStream) *types.Ticker {
+	return &types.Ticker{
+		Exchange:  types.ExchangeBinance,
+		Symbol:    types.Symbol(stream.Symbol),
+		Price:     stream.LastPrice.Float64(),
+		Volume:    stream.TotalTradedVolume.Float64(),
+		High24h:   stream.HighPrice.Float64(),
+		Low24h:    stream.LowPrice.Float64(),
+		Change24h: stream.PriceChangePercent.Float64(),
+		Timestamp: stream.EventTime.Time(),
+	}
+}
+
 // handleKlineStream 处理K线流数据
 func (ws *BinanceWebSocket) handleKlineStream(streamName string, data []byte) error {
 	log.Debugf(log.WebsocketMgr, "K线流数据: %s", string(data))
@@ -328,77 +658,222 @@ func (ws *BinanceWebSocket) handleKlineStream(streamName string, data []byte) er
 	return nil
 }
 
-// handleDepthStream 处理深度流数据
+// handleDepthStream 处理深度流数据：增量深度流（<symbol>@depth）用于维护本地订单簿，
+// 订单簿与交易所一致时以完整订单簿调用回调
 func (ws *BinanceWebSocket) handleDepthStream(streamName string, data []byte) error {
 	log.Debugf(log.WebsocketMgr, "深度流数据: %s", string(data))
 
-	// 查找对应的回调函数
-	if callback, exists := ws.getSubscriptionCallback(streamName); exists && callback != nil {
-		// 这里应该解析数据为 types.Orderbook 结构
-		// 为了简化，暂时直接打印
-		log.Debugf(log.WebsocketMgr, "调用深度数据回调: %s", streamName)
-		// TODO: 解析数据并调用 callback
+	callback, exists := ws.getSubscriptionCallback(streamName)
+	if !exists || callback == nil {
+		return nil
 	}
 
+	if strings.Split(streamName, "@")[1] == "depth" {
+		var stream WebsocketDepthStream
+		if err := json.Unmarshal(data, &stream); err != nil {
+			return fmt.Errorf("解析增量深度数据失败: %w", err)
+		}
+		return ws.orderbookSyncer().handle(&stream, callback)
+	}
+
+	// 这里应该解析有限档深度数据为 types.Orderbook 结构
+	log.Debugf(log.WebsocketMgr, "调用深度数据回调: %s", streamName)
+	// TODO: 解析数据并调用 callback
 	fmt.Printf("###接收到深度数据: %s\n", string(data))
 	return nil
 }
 
-// Subscribe 订阅WebSocket频道
+// orderbookSyncer 返回本地订单簿同步器，首次使用时创建
+func (ws *BinanceWebSocket) orderbookSyncer() *orderbookSyncer {
+	ws.booksOnce.Do(func() {
+		ws.books = newOrderbookSyncer(&ws.wg)
+	})
+	return ws.books
+}
+
+// SetOrderbookSnapshotFetcher 设置获取REST订单簿快照的函数，增量深度流据此建立和重新同步本地订单簿
+func (ws *BinanceWebSocket) SetOrderbookSnapshotFetcher(fetch OrderbookSnapshotFunc) {
+	books := ws.orderbookSyncer()
+	books.mu.Lock()
+	books.fetch = fetch
+	books.mu.Unlock()
+}
+
+// SetBookDesyncHandler 设置增量深度流出现序号缺口时的回调
+func (ws *BinanceWebSocket) SetBookDesyncHandler(handler func(BookDesyncEvent)) {
+	books := ws.orderbookSyncer()
+	books.mu.Lock()
+	books.onDesync = handler
+	books.mu.Unlock()
+}
+
+// GetOrderbookHealth 获取交易对本地订单簿的健康状态，未订阅增量深度流时返回false
+func (ws *BinanceWebSocket) GetOrderbookHealth(symbol types.Symbol) (BookHealth, bool) {
+	return ws.orderbookSyncer().health(types.Symbol(strings.ToUpper(string(symbol))))
+}
+
+// GetOrderbookSyncStatus 获取所有本地订单簿的健康状态和序号缺口统计
+func (ws *BinanceWebSocket) GetOrderbookSyncStatus() map[string]interface{} {
+	return ws.orderbookSyncer().status()
+}
+
+// Subscribe 订阅WebSocket频道。开启订阅确认（SetSubscribeConfirmTimeout）时等待交易所响应：
+// 拒绝时返回*SubscribeError，超时返回ErrSubscribeTimeout；未开启时发送成功即返回。
+// 无论是否等待，交易所拒绝的频道都会从订阅映射中移除，重连后不再订阅
 func (ws *BinanceWebSocket) Subscribe(channels []string) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
 	// 创建订阅消息
 	req := WsPayload{
-		ID:     time.Now().Unix(),
+		ID:     ws.requestSeq.Add(1),
 		Method: wsSubscribeMethod,
 		Params: channels,
 	}
 	log.Debugf(log.WebsocketMgr, "发送订阅请求: %+v", req)
 
-	err := ws.wsConn.WriteJSON(req)
+	pending := ws.addPending(req.ID, req.Method, channels)
+	err := ws.writeJSON(req)
 	if err != nil {
+		ws.takePending(req.ID)
 		log.Errorf(log.WebsocketMgr, "发送订阅请求失败: %v", err)
 		return fmt.Errorf("发送订阅请求失败: %v", err)
 	}
 	log.Debugf(log.WebsocketMgr, "订阅请求发送成功")
-	return nil
+	return ws.waitConfirm(req.ID, pending)
 }
 
 // Unsubscribe 取消订阅WebSocket频道
 func (ws *BinanceWebSocket) Unsubscribe(channels []string) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
-	// 创建取消订阅消息
+	// 创建取消订阅消息，响应只用于记录失败
 	req := WsPayload{
-		ID:     time.Now().Unix(),
+		ID:     ws.requestSeq.Add(1),
 		Method: wsUnsubscribeMethod,
 		Params: channels,
 	}
-	return ws.wsConn.WriteJSON(req)
+	ws.addPending(req.ID, req.Method, channels)
+	if err := ws.writeJSON(req); err != nil {
+		ws.takePending(req.ID)
+		return err
+	}
+	return nil
+}
+
+// writeJSON 向当前连接写入一条JSON消息
+func (ws *BinanceWebSocket) writeJSON(v interface{}) error {
+	ws.writeMu.Lock()
+	defer ws.writeMu.Unlock()
+	return ws.wsConn.WriteJSON(v)
 }
 
-// WsClose 关闭WebSocket连接
+// WsClose 关闭WebSocket连接：发送关闭帧并短暂等待服务端确认，通知后台协程退出后再关闭底层连接
+// 可重复调用，关闭后不能再次连接
 func (ws *BinanceWebSocket) WsClose() error {
-	ws.wsConnected = false
-	if ws.wsConn != nil {
-		return ws.wsConn.Close()
+	var err error
+	ws.closeOnce.Do(func() {
+		err = ws.close()
+	})
+	return err
+}
+
+// close 执行关闭流程
+func (ws *BinanceWebSocket) close() error {
+	ws.mu.Lock()
+	ws.wsConnected.Store(false)
+	ws.state.Store(int32(ConnStateClosed))
+	conn, readDone := ws.wsConn, ws.readDone
+	if ws.done != nil {
+		close(ws.done)
+	}
+	ws.orderbookSyncer().close()
+
+	// 释放共享的IP管理器
+	if ws.ipManager != nil {
+		ipmanager.Release(ws.ipManager)
+		ws.ipManager = nil
+	}
+	ws.mu.Unlock()
+
+	if conn == nil {
+		return nil
+	}
+
+	// 发送关闭帧，等待读取协程收到服务端的关闭确认后退出
+	closeMsg := gws.FormatCloseMessage(gws.CloseNormalClosure, "")
+	if err := conn.WriteControl(gws.CloseMessage, closeMsg, time.Now().Add(wsCloseTimeout)); err != nil {
+		log.Debugf(log.WebsocketMgr, "发送WebSocket关闭帧失败: %v", err)
+	} else if readDone != nil {
+		select {
+		case <-readDone:
+		case <-time.After(wsCloseTimeout):
+			log.Debugf(log.WebsocketMgr, "等待WebSocket关闭确认超时")
+		}
+	}
+
+	if err := conn.Close(); err != nil && !errors.Is(err, net.ErrClosed) {
+		return err
 	}
 	return nil
 }
 
-// IsConnected 返回WebSocket是否已连接
+// Wait 等待读取、重连等后台协程全部退出，应在WsClose之后调用；ctx结束时返回ctx.Err()
+func (ws *BinanceWebSocket) Wait(ctx context.Context) error {
+	stopped := make(chan struct{})
+	go func() {
+		ws.wg.Wait()
+		close(stopped)
+	}()
+	select {
+	case <-stopped:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// sleep 等待指定时间，WebSocket被关闭时提前返回false
+func (ws *BinanceWebSocket) sleep(d time.Duration) bool {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return true
+	case <-ws.done:
+		return false
+	}
+}
+
+// Done 返回WebSocket关闭信号通道，WsClose时关闭
+func (ws *BinanceWebSocket) Done() <-chan struct{} {
+	return ws.done
+}
+
+// isClosed 判断WebSocket是否已被主动关闭
+func (ws *BinanceWebSocket) isClosed() bool {
+	if ws.done == nil {
+		return false
+	}
+	select {
+	case <-ws.done:
+		return true
+	default:
+		return false
+	}
+}
+
+// IsConnected 返回WebSocket是否已连接（socket是否打开），连接是否停滞见IsHealthy
 func (ws *BinanceWebSocket) IsConnected() bool {
-	return ws.wsConnected
+	return ws.wsConnected.Load()
 }
 
-// GetLastPing 获取最后ping时间
+// GetLastPing 获取最后收到服务端ping的时间
 func (ws *BinanceWebSocket) GetLastPing() time.Time {
-	return ws.lastPing
+	return unixNanoTime(ws.lastPing.Load())
 }
 
 // GetIPManagerStatus 获取IP管理器状态信息
@@ -422,6 +897,8 @@ func (ws *BinanceWebSocket) buildChannelName(symbol, streamType, param string) s
 		return fmt.Sprintf("%s@ticker", symbol)
 	case "trade":
 		return fmt.Sprintf("%s@trade", symbol)
+	case "bookTicker":
+		return fmt.Sprintf("%s@bookTicker", symbol)
 	case "kline":
 		return fmt.Sprintf("%s@kline_%s", symbol, param)
 	case "depth", "depth5", "depth10", "depth20":
@@ -434,33 +911,9 @@ func (ws *BinanceWebSocket) buildChannelName(symbol, streamType, param string) s
 	}
 }
 
-// addSubscription 添加订阅到内部映射
-func (ws *BinanceWebSocket) addSubscription(channel string, callback types.DataCallback) {
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-	ws.subscriptions[channel] = callback
-	log.Debugf(log.WebsocketMgr, "添加订阅: %s", channel)
-}
-
-// removeSubscription 从内部映射移除订阅
-func (ws *BinanceWebSocket) removeSubscription(channel string) {
-	ws.mu.Lock()
-	defer ws.mu.Unlock()
-	delete(ws.subscriptions, channel)
-	log.Debugf(log.WebsocketMgr, "移除订阅: %s", channel)
-}
-
-// getSubscriptionCallback 获取订阅的回调函数
-func (ws *BinanceWebSocket) getSubscriptionCallback(channel string) (types.DataCallback, bool) {
-	ws.mu.RLock()
-	defer ws.mu.RUnlock()
-	callback, exists := ws.subscriptions[channel]
-	return callback, exists
-}
-
 // SubscribeTicker 订阅行情数据
 func (ws *BinanceWebSocket) SubscribeTicker(symbols []types.Symbol, callback types.DataCallback) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
@@ -473,9 +926,34 @@ func (ws *BinanceWebSocket) SubscribeTicker(symbols []types.Symbol, callback typ
 	return ws.Subscribe(channels)
 }
 
+// SubscribeBookTicker 订阅最优挂单（最佳买卖价）数据
+func (ws *BinanceWebSocket) SubscribeBookTicker(symbols []types.Symbol, callback types.DataCallback) error {
+	if !ws.wsConnected.Load() {
+		return errors.New("WebSocket未连接")
+	}
+
+	var channels []string
+	for _, symbol := range symbols {
+		channel := ws.buildChannelName(string(symbol), "bookTicker", "")
+		channels = append(channels, channel)
+		ws.addSubscription(channel, callback)
+	}
+	return ws.Subscribe(channels)
+}
+
+// SubscribeAllTickers 订阅全市场24小时行情（单一数组流，替代逐个交易对订阅）
+func (ws *BinanceWebSocket) SubscribeAllTickers(callback types.DataCallback) error {
+	if !ws.wsConnected.Load() {
+		return errors.New("WebSocket未连接")
+	}
+
+	ws.addSubscription(wsAllTickersStream, callback)
+	return ws.Subscribe([]string{wsAllTickersStream})
+}
+
 // SubscribeOrderbook 订阅订单簿数据
 func (ws *BinanceWebSocket) SubscribeOrderbook(symbols []types.Symbol, callback types.DataCallback) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
@@ -491,7 +969,7 @@ func (ws *BinanceWebSocket) SubscribeOrderbook(symbols []types.Symbol, callback
 
 // SubscribeTrades 订阅交易数据
 func (ws *BinanceWebSocket) SubscribeTrades(symbols []types.Symbol, callback types.DataCallback) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
@@ -506,10 +984,16 @@ func (ws *BinanceWebSocket) SubscribeTrades(symbols []types.Symbol, callback typ
 
 // SubscribeKlines 订阅K线数据
 func (ws *BinanceWebSocket) SubscribeKlines(symbols []types.Symbol, intervals []string, callback types.DataCallback) error {
-	if !ws.wsConnected {
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
+	for _, interval := range intervals {
+		if err := types.ValidateBinanceKlineInterval(interval); err != nil {
+			return err
+		}
+	}
+
 	var channels []string
 	for _, symbol := range symbols {
 		for _, interval := range intervals {
@@ -536,8 +1020,9 @@ func (ws *BinanceWebSocket) UnsubscribeAll() error {
 	}
 
 	// 清空订阅映射
-	ws.subscriptions = make(map[string]types.DataCallback)
-	if ws.wsConnected {
+	ws.subscriptions = make(map[string]callbackList)
+	ws.orderbookSyncer().reset()
+	if ws.wsConnected.Load() {
 		return ws.Unsubscribe(channels)
 	}
 	return nil
@@ -549,8 +1034,15 @@ func (ws *BinanceWebSocket) SubscribeTickerWithDepth(symbols []types.Symbol, cal
 }
 
 // SubscribeOrderbookWithDepth 订阅订单簿数据（自定义深度）
+// depth为5/10/20时订阅有限档深度流（depth5/10/20），其他值订阅增量深度流（depth）。
+// updateSpeed为推送频率，两种深度流都只支持100ms和1000ms：为空或1000ms时使用默认的1000ms推送，
+// 频道名称不带后缀；100ms时在频道名称后追加@100ms。其他值返回错误
 func (ws *BinanceWebSocket) SubscribeOrderbookWithDepth(symbols []types.Symbol, depth int, updateSpeed string, callback types.DataCallback) error {
-	if !ws.wsConnected {
+	speed, err := normalizeUpdateSpeed(updateSpeed)
+	if err != nil {
+		return err
+	}
+	if !ws.wsConnected.Load() {
 		return errors.New("WebSocket未连接")
 	}
 
@@ -568,13 +1060,33 @@ func (ws *BinanceWebSocket) SubscribeOrderbookWithDepth(symbols []types.Symbol,
 			streamType = "depth"
 		}
 
-		channel := ws.buildChannelName(string(symbol), streamType, updateSpeed)
+		channel := ws.buildChannelName(string(symbol), streamType, speed)
 		channels = append(channels, channel)
 		ws.addSubscription(channel, callback)
 	}
 	return ws.Subscribe(channels)
 }
 
+// updateSpeedAliases 深度流推送频率的别名，值为频道名称使用的后缀（默认的1000ms不带后缀）
+var updateSpeedAliases = map[string]string{
+	"":       "",
+	"1000ms": "",
+	"1000":   "",
+	"1s":     "",
+	"100ms":  "100ms",
+	"100":    "100ms",
+	"0.1s":   "100ms",
+}
+
+// normalizeUpdateSpeed 校验深度流推送频率并转换为频道名称使用的后缀
+func normalizeUpdateSpeed(updateSpeed string) (string, error) {
+	speed, ok := updateSpeedAliases[strings.ToLower(strings.TrimSpace(updateSpeed))]
+	if !ok {
+		return "", fmt.Errorf("不支持的深度流推送频率: %q，仅支持100ms或1000ms", updateSpeed)
+	}
+	return speed, nil
+}
+
 // GetActiveSubscriptions 获取当前活跃的订阅列表
 func (ws *BinanceWebSocket) GetActiveSubscriptions() []string {
 	ws.mu.RLock()
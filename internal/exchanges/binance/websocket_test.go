@@ -0,0 +1,917 @@
+package binance
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"strings"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+)
+
+func TestHandleBookTickerStream(t *testing.T) {
+	ws := &BinanceWebSocket{subscriptions: make(map[string]callbackList)}
+
+	var received *types.BookTicker
+	ws.addSubscription("bnbusdt@bookTicker", func(data types.MarketData) error {
+		bookTicker, ok := data.(*types.BookTicker)
+		if !ok {
+			t.Fatalf("Expected *types.BookTicker, got %T", data)
+		}
+		received = bookTicker
+		return nil
+	})
+
+	msg := []byte(`{"stream":"bnbusdt@bookTicker","data":{"u":400900217,"s":"BNBUSDT","b":"25.35190000","B":"31.21000000","a":"25.36520000","A":"40.66000000"}}`)
+	if err := ws.wsHandleData(msg); err != nil {
+		t.Fatalf("wsHandleData returned error: %v", err)
+	}
+
+	if received == nil {
+		t.Fatal("Expected bookTicker callback to be invoked")
+	}
+	if received.Symbol != "BNBUSDT" || received.UpdateID != 400900217 {
+		t.Errorf("Unexpected symbol/update ID: %s/%d", received.Symbol, received.UpdateID)
+	}
+	if received.BidPrice != 25.3519 || received.AskPrice != 25.3652 {
+		t.Errorf("Unexpected bid/ask price: %v/%v", received.BidPrice, received.AskPrice)
+	}
+	if received.BidQuantity != 31.21 || received.AskQuantity != 40.66 {
+		t.Errorf("Unexpected bid/ask quantity: %v/%v", received.BidQuantity, received.AskQuantity)
+	}
+}
+
+func TestHandleAllTickersStream(t *testing.T) {
+	ws := &BinanceWebSocket{subscriptions: make(map[string]callbackList)}
+
+	received := make(map[types.Symbol]*types.Ticker)
+	ws.addSubscription(wsAllTickersStream, func(data types.MarketData) error {
+		ticker, ok := data.(*types.Ticker)
+		if !ok {
+			t.Fatalf("Expected *types.Ticker, got %T", data)
+		}
+		received[ticker.Symbol] = ticker
+		return nil
+	})
+
+	msg := []byte(`{"stream":"!ticker@arr","data":[` +
+		`{"e":"24hrTicker","E":1672515782136,"s":"BTCUSDT","P":"1.5","c":"16500.10","h":"16600.00","l":"16200.00","v":"1200.5"},` +
+		`{"e":"24hrTicker","E":1672515782136,"s":"ETHUSDT","P":"-0.8","c":"1200.20","h":"1220.00","l":"1190.00","v":"8000"}]}`)
+	if err := ws.wsHandleData(msg); err != nil {
+		t.Fatalf("wsHandleData returned error: %v", err)
+	}
+
+	if len(received) != 2 {
+		t.Fatalf("Expected 2 tickers, got %d", len(received))
+	}
+	btc := received["BTCUSDT"]
+	if btc == nil || btc.Price != 16500.10 || btc.High24h != 16600 || btc.Change24h != 1.5 {
+		t.Errorf("Unexpected BTCUSDT ticker: %+v", btc)
+	}
+	if eth := received["ETHUSDT"]; eth == nil || eth.Volume != 8000 {
+		t.Errorf("Unexpected ETHUSDT ticker: %+v", eth)
+	}
+}
+
+func TestHandleUserDataStream(t *testing.T) {
+	ws := &BinanceWebSocket{subscriptions: make(map[string]callbackList)}
+
+	var received []interface{}
+	ws.userDataKey = "pqia91ma19a5s61cv6a81va65sdf19v8a65a1a5s61cv6a81va65sdf19v8a65a1"
+	ws.userDataCallback = func(event interface{}) error {
+		received = append(received, event)
+		return nil
+	}
+
+	messages := []string{
+		`{"e":"outboundAccountPosition","E":1564034571105,"u":1564034571073,"B":[{"a":"ETH","f":"10000.000000","l":"0.000000"}]}`,
+		`{"e":"balanceUpdate","E":1573200697110,"a":"BTC","d":"100.00000000","T":1573200697068}`,
+		`{"e":"executionReport","E":1499405658658,"s":"ETHBTC","c":"mUvoqJxFIILMdfAW5iGSOW","S":"BUY","o":"LIMIT","f":"GTC","q":"1.00000000","p":"0.10264410","P":"0.00000000","F":"0.00000000","g":-1,"C":"","x":"NEW","X":"NEW","r":"NONE","i":4293153,"l":"0.00000000","z":"0.00000000","L":"0.00000000","n":"0","N":null,"T":1499405658657,"t":-1,"I":8641984,"w":true,"m":false,"M":false,"O":1499405658657,"Z":"0.00000000","Y":"0.00000000","Q":"0.00000000"}`,
+		`{"e":"unknownEvent","E":1499405658658}`,
+	}
+	for _, data := range messages {
+		msg := []byte(`{"stream":"` + ws.userDataKey + `","data":` + data + `}`)
+		if err := ws.wsHandleData(msg); err != nil {
+			t.Fatalf("wsHandleData returned error: %v", err)
+		}
+	}
+
+	if len(received) != 3 {
+		t.Fatalf("Expected 3 user data events, got %d", len(received))
+	}
+	if position, ok := received[0].(*WsAccountPositionData); !ok || len(position.Currencies) != 1 || position.Currencies[0].Available != 10000 {
+		t.Errorf("Unexpected account position event: %+v", received[0])
+	}
+	if balance, ok := received[1].(*WsBalanceUpdateData); !ok || balance.Asset != "BTC" || balance.BalanceDelta != 100 {
+		t.Errorf("Unexpected balance update event: %+v", received[1])
+	}
+	if order, ok := received[2].(*WsOrderUpdateData); !ok || order.OrderID != 4293153 || order.Symbol != "ETHBTC" || order.Price != 0.1026441 {
+		t.Errorf("Unexpected order update event: %+v", received[2])
+	}
+}
+
+// newTestWsConn 连接到本地测试WebSocket服务，返回客户端连接和服务端收到的关闭码
+func newTestWsConn(t *testing.T) (*gws.Conn, chan int) {
+	t.Helper()
+	closeCodes := make(chan int, 1)
+	upgrader := gws.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				if closeErr, ok := err.(*gws.CloseError); ok {
+					closeCodes <- closeErr.Code
+				}
+				return
+			}
+		}
+	}))
+	t.Cleanup(server.Close)
+
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	return conn, closeCodes
+}
+
+func TestWsCloseHandshake(t *testing.T) {
+	conn, closeCodes := newTestWsConn(t)
+
+	ws := NewWebSocket()
+	readDone := make(chan struct{})
+	ws.wsConn, ws.readDone = conn, readDone
+	ws.wsConnected.Store(true)
+	ws.wg.Add(1)
+	go ws.wsReadData(conn, readDone, ws.wireBytes.Load())
+
+	if err := ws.WsClose(); err != nil {
+		t.Fatalf("WsClose returned error: %v", err)
+	}
+
+	select {
+	case code := <-closeCodes:
+		if code != gws.CloseNormalClosure {
+			t.Errorf("Expected normal closure code, got %d", code)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Server did not receive a close frame")
+	}
+	select {
+	case <-readDone:
+	case <-time.After(time.Second):
+		t.Fatal("Read goroutine did not exit")
+	}
+
+	// 重复关闭不应报错，关闭后不能再连接
+	if err := ws.WsClose(); err != nil {
+		t.Errorf("Second WsClose returned error: %v", err)
+	}
+	if err := ws.WsConnect(); err == nil {
+		t.Error("Expected WsConnect to fail after WsClose")
+	}
+}
+
+func TestWsCloseStopsGoroutines(t *testing.T) {
+	// 服务端升级后立即断开，使读取协程退出并启动重连协程
+	upgrader := gws.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if conn, err := upgrader.Upgrade(w, r, nil); err == nil {
+			conn.Close()
+		}
+	}))
+	defer server.Close()
+
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+
+	ws := NewWebSocket()
+	readDone := make(chan struct{})
+	ws.wsConn, ws.readDone = conn, readDone
+	ws.wsConnected.Store(true)
+	ws.wg.Add(1)
+	go ws.wsReadData(conn, readDone, ws.wireBytes.Load())
+
+	select {
+	case <-readDone:
+	case <-time.After(time.Second):
+		t.Fatal("Read goroutine did not exit after server disconnect")
+	}
+
+	if err := ws.WsClose(); err != nil {
+		t.Fatalf("WsClose returned error: %v", err)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+	defer cancel()
+	if err := ws.Wait(ctx); err != nil {
+		t.Fatalf("Background goroutines did not exit after WsClose: %v", err)
+	}
+
+	// 后台协程未退出时Wait在ctx结束时返回
+	stuck := NewWebSocket()
+	stuck.wg.Add(1)
+	defer stuck.wg.Done()
+	canceled, cancelStuck := context.WithCancel(context.Background())
+	cancelStuck()
+	if err := stuck.Wait(canceled); !errors.Is(err, context.Canceled) {
+		t.Errorf("Expected context.Canceled, got %v", err)
+	}
+}
+
+func TestNormalizeUpdateSpeed(t *testing.T) {
+	for input, want := range map[string]string{"": "", "1000ms": "", "1s": "", "100ms": "100ms", "100": "100ms", "100MS": "100ms"} {
+		got, err := normalizeUpdateSpeed(input)
+		if err != nil || got != want {
+			t.Errorf("normalizeUpdateSpeed(%q) = %q, %v; want %q", input, got, err, want)
+		}
+	}
+
+	ws := NewWebSocket()
+	if err := ws.SubscribeOrderbookWithDepth([]types.Symbol{"BTCUSDT"}, 20, "250ms", nil); err == nil || !strings.Contains(err.Error(), "250ms") {
+		t.Errorf("Expected invalid update speed error, got %v", err)
+	}
+	if ws.GetSubscriptionCount() != 0 {
+		t.Error("Expected no subscriptions for invalid update speed")
+	}
+
+	if channel := ws.buildChannelName("BTCUSDT", "depth20", "100ms"); channel != "btcusdt@depth20@100ms" {
+		t.Errorf("Unexpected partial depth channel: %s", channel)
+	}
+	if channel := ws.buildChannelName("BTCUSDT", "depth", ""); channel != "btcusdt@depth" {
+		t.Errorf("Unexpected diff depth channel: %s", channel)
+	}
+}
+
+func TestOrderbookSequenceGap(t *testing.T) {
+	ws := NewWebSocket()
+	ws.orderbookSyncer().backoff = 0
+
+	snapshots := make(chan OrderBook, 2)
+	snapshots <- OrderBook{
+		LastUpdateID: 100,
+		Bids:         []OrderbookItem{{Price: 99, Quantity: 1}, {Price: 98, Quantity: 2}},
+		Asks:         []OrderbookItem{{Price: 101, Quantity: 1}},
+	}
+	ws.SetOrderbookSnapshotFetcher(func(ctx context.Context, symbol types.Symbol) (OrderBook, error) {
+		if symbol != "BTCUSDT" {
+			t.Errorf("Unexpected snapshot symbol: %s", symbol)
+		}
+		select {
+		case snapshot := <-snapshots:
+			return snapshot, nil
+		case <-ctx.Done():
+			return OrderBook{}, ctx.Err()
+		}
+	})
+	desyncs := make(chan BookDesyncEvent, 1)
+	ws.SetBookDesyncHandler(func(event BookDesyncEvent) { desyncs <- event })
+
+	books := make(chan *types.Orderbook, 10)
+	ws.addSubscription("btcusdt@depth@100ms", func(data types.MarketData) error {
+		books <- data.(*types.Orderbook)
+		return nil
+	})
+	send := func(first, last int64, bids string) {
+		t.Helper()
+		msg := fmt.Sprintf(`{"stream":"btcusdt@depth@100ms","data":{"e":"depthUpdate","E":1700000000000,"s":"BTCUSDT","U":%d,"u":%d,"b":%s,"a":[]}}`, first, last, bids)
+		if err := ws.wsHandleData([]byte(msg)); err != nil {
+			t.Fatalf("wsHandleData returned error: %v", err)
+		}
+	}
+	receive := func() *types.Orderbook {
+		t.Helper()
+		select {
+		case book := <-books:
+			return book
+		case <-time.After(time.Second):
+			t.Fatal("Expected orderbook callback")
+			return nil
+		}
+	}
+
+	// 快照前的事件被缓存，快照加载后重放
+	send(95, 102, `[["98","0"],["97","3"]]`)
+	book := receive()
+	if len(book.Bids) != 2 || book.Bids[0].Price != 99 || book.Bids[1].Price != 97 {
+		t.Errorf("Unexpected bids after replay: %+v", book.Bids)
+	}
+	if health, ok := ws.GetOrderbookHealth("btcusdt"); !ok || health.Status != BookStatusInSync || health.LastUpdateID != 102 {
+		t.Errorf("Unexpected health after snapshot: %+v, %v", health, ok)
+	}
+
+	send(103, 104, `[["99","5"]]`)
+	if book := receive(); book.Bids[0].Quantity != 5 {
+		t.Errorf("Unexpected best bid: %+v", book.Bids[0])
+	}
+
+	// 序号缺口：标记为重新同步，不推送订单簿，并重新请求快照
+	send(110, 112, `[["96","1"]]`)
+	select {
+	case event := <-desyncs:
+		if event.ExpectedUpdateID != 105 || event.FirstUpdateID != 110 {
+			t.Errorf("Unexpected desync event: %+v", event)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("Expected desync event")
+	}
+	if health, _ := ws.GetOrderbookHealth("BTCUSDT"); health.Status != BookStatusResyncing || health.Desyncs != 1 {
+		t.Errorf("Unexpected health after gap: %+v", health)
+	}
+	select {
+	case book := <-books:
+		t.Fatalf("Expected no orderbook while resyncing, got %+v", book)
+	default:
+	}
+
+	snapshots <- OrderBook{LastUpdateID: 111, Bids: []OrderbookItem{{Price: 99, Quantity: 4}}}
+	book = receive()
+	if len(book.Bids) != 2 || book.Bids[1].Price != 96 {
+		t.Errorf("Unexpected bids after resync: %+v", book.Bids)
+	}
+	status := ws.GetOrderbookSyncStatus()
+	if status["desync_total"] != int64(1) || status["resnapshots"] != int64(2) || status["in_sync"] != 1 {
+		t.Errorf("Unexpected sync status: %v", status)
+	}
+
+	if err := ws.WsClose(); err != nil {
+		t.Fatalf("WsClose returned error: %v", err)
+	}
+	if err := ws.Wait(context.Background()); err != nil {
+		t.Fatalf("Wait returned error: %v", err)
+	}
+}
+
+func TestReconnectDelay(t *testing.T) {
+	config := reconnectConfigWithDefaults(types.WebsocketReconnectConfig{BaseDelay: time.Second, MaxDelay: 10 * time.Second})
+	if config.MaxAttempts != defaultReconnectMaxAttempts {
+		t.Errorf("Expected default max attempts %d, got %d", defaultReconnectMaxAttempts, config.MaxAttempts)
+	}
+
+	for attempt, ceiling := range map[int]time.Duration{1: time.Second, 2: 2 * time.Second, 4: 8 * time.Second, 5: 10 * time.Second, 100: 10 * time.Second} {
+		distinct := make(map[time.Duration]bool)
+		for range 50 {
+			delay := reconnectDelay(config, attempt)
+			if delay < 0 || delay > ceiling {
+				t.Fatalf("Attempt %d: delay %v outside [0, %v]", attempt, delay, ceiling)
+			}
+			distinct[delay] = true
+		}
+		if len(distinct) < 2 {
+			t.Errorf("Attempt %d: expected jittered delays, got %v", attempt, distinct)
+		}
+	}
+
+	// MaxDelay小于BaseDelay时以BaseDelay为上限
+	config = reconnectConfigWithDefaults(types.WebsocketReconnectConfig{BaseDelay: time.Minute, MaxDelay: time.Second})
+	if config.MaxDelay != time.Minute {
+		t.Errorf("Expected max delay raised to base delay, got %v", config.MaxDelay)
+	}
+}
+
+func TestConnStateAndHealth(t *testing.T) {
+	// 服务端连接后发送ping和一条数据消息
+	upgrader := gws.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		conn.WriteControl(gws.PingMessage, []byte("keepalive"), time.Now().Add(time.Second))
+		conn.WriteMessage(gws.TextMessage, []byte(`{"result":null,"id":1}`))
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ws := NewWebSocket()
+	if state := ws.ConnState(); state != ConnStateDisconnected {
+		t.Errorf("Expected disconnected before connect, got %s", state)
+	}
+
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	if err := ws.startConnection(conn, ws.wireBytes.Load()); err != nil {
+		t.Fatalf("startConnection returned error: %v", err)
+	}
+	if state := ws.ConnState(); state != ConnStateConnected || !ws.IsHealthy() {
+		t.Errorf("Expected healthy connected state, got %s", state)
+	}
+
+	deadline := time.Now().Add(time.Second)
+	for (ws.GetLastPing().IsZero() || ws.GetLastDataTime().IsZero()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if ws.GetLastPing().IsZero() || ws.GetLastDataTime().IsZero() {
+		t.Fatalf("Expected ping and data times to be recorded, got ping=%v data=%v", ws.GetLastPing(), ws.GetLastDataTime())
+	}
+
+	// 超过keepalive超时未收到任何帧时连接仍打开但已停滞
+	stale := time.Now().Add(-wsKeepaliveTimeout - time.Second).UnixNano()
+	ws.connectedAt.Store(stale)
+	ws.lastPing.Store(stale)
+	ws.lastData.Store(stale)
+	if state := ws.ConnState(); state != ConnStateDegraded || ws.IsHealthy() || !ws.IsConnected() {
+		t.Errorf("Expected degraded but connected state, got %s (connected=%v)", state, ws.IsConnected())
+	}
+
+	// 收到ping但有订阅时长时间未收到数据也视为停滞
+	ws.lastPing.Store(time.Now().UnixNano())
+	if !ws.IsHealthy() {
+		t.Error("Expected healthy connection without subscriptions after recent ping")
+	}
+	ws.mu.Lock()
+	ws.subscriptions["btcusdt@ticker"] = callbackList{{id: 1}}
+	ws.mu.Unlock()
+	if state := ws.ConnState(); state != ConnStateDegraded {
+		t.Errorf("Expected degraded state with stale data, got %s", state)
+	}
+	ws.lastData.Store(time.Now().UnixNano())
+	if !ws.IsHealthy() {
+		t.Error("Expected healthy connection after recent data")
+	}
+
+	if err := ws.WsClose(); err != nil {
+		t.Fatalf("WsClose returned error: %v", err)
+	}
+	ws.setState(ConnStateConnecting)
+	if state := ws.ConnState(); state != ConnStateClosed {
+		t.Errorf("Expected closed state to be final, got %s", state)
+	}
+}
+
+func TestSubscriptionCallbackFanOut(t *testing.T) {
+	ws := NewWebSocket()
+	channel := "bnbusdt@bookTicker"
+	msg := []byte(`{"stream":"bnbusdt@bookTicker","data":{"u":400900217,"s":"BNBUSDT","b":"25.35190000","B":"31.21000000","a":"25.36520000","A":"40.66000000"}}`)
+
+	var storageCalls, metricsCalls int
+	storageID := ws.addSubscription(channel, func(data types.MarketData) error {
+		storageCalls++
+		return errors.New("storage unavailable")
+	})
+	metricsID := ws.addSubscription(channel, func(data types.MarketData) error {
+		metricsCalls++
+		return nil
+	})
+	if storageID == metricsID {
+		t.Fatalf("Expected distinct callback IDs, got %d", storageID)
+	}
+	if ws.GetSubscriptionCount() != 1 {
+		t.Errorf("Expected callbacks to share one channel, got %d channels", ws.GetSubscriptionCount())
+	}
+
+	// 一个回调失败不影响其他回调
+	if err := ws.wsHandleData(msg); err == nil || !strings.Contains(err.Error(), "storage unavailable") {
+		t.Errorf("Expected storage callback error, got %v", err)
+	}
+	if storageCalls != 1 || metricsCalls != 1 {
+		t.Errorf("Expected both callbacks invoked once, got storage=%d metrics=%d", storageCalls, metricsCalls)
+	}
+
+	if err := ws.UnsubscribeCallback(channel, storageID); err != nil {
+		t.Fatalf("UnsubscribeCallback returned error: %v", err)
+	}
+	if err := ws.wsHandleData(msg); err != nil {
+		t.Errorf("Expected no error after removing failing callback, got %v", err)
+	}
+	if storageCalls != 1 || metricsCalls != 2 {
+		t.Errorf("Expected only metrics callback invoked, got storage=%d metrics=%d", storageCalls, metricsCalls)
+	}
+	if err := ws.UnsubscribeCallback(channel, storageID); err == nil {
+		t.Error("Expected error when removing an unknown callback")
+	}
+
+	if err := ws.UnsubscribeCallback(channel, metricsID); err != nil {
+		t.Fatalf("UnsubscribeCallback returned error: %v", err)
+	}
+	if ws.GetSubscriptionCount() != 0 {
+		t.Errorf("Expected channel removed after its last callback, got %v", ws.GetActiveSubscriptions())
+	}
+}
+
+func TestLogThrottle(t *testing.T) {
+	throttle := newLogThrottle(10 * time.Second)
+	start := time.Now()
+	key := "Reconnection attempt %d failed: %v"
+
+	// 首次出现立即输出
+	if ok, suppressed := throttle.allow(key, start); !ok || suppressed != 0 {
+		t.Fatalf("Expected first occurrence to be logged, got ok=%v suppressed=%d", ok, suppressed)
+	}
+	// 间隔内的重复日志被抑制，其他调用点不受影响
+	for i := 1; i <= 3; i++ {
+		if ok, _ := throttle.allow(key, start.Add(time.Duration(i)*time.Second)); ok {
+			t.Fatalf("Expected occurrence %d within interval to be suppressed", i)
+		}
+	}
+	if ok, _ := throttle.allow("WebSocket读取错误: %v", start.Add(time.Second)); !ok {
+		t.Error("Expected a different key to be logged independently")
+	}
+
+	// 间隔结束后输出一次并带上被抑制的次数
+	if ok, suppressed := throttle.allow(key, start.Add(10*time.Second)); !ok || suppressed != 3 {
+		t.Fatalf("Expected summary with 3 suppressed, got ok=%v suppressed=%d", ok, suppressed)
+	}
+	throttle.allow(key, start.Add(11*time.Second))
+
+	// flush返回未汇总的次数并重置状态
+	pending := throttle.flush()
+	if len(pending) != 1 || pending[key] != 1 {
+		t.Errorf("Expected 1 pending suppressed log for %q, got %v", key, pending)
+	}
+	if ok, suppressed := throttle.allow(key, start.Add(12*time.Second)); !ok || suppressed != 0 {
+		t.Errorf("Expected log after flush to be emitted fresh, got ok=%v suppressed=%d", ok, suppressed)
+	}
+
+	// 未初始化的节流器不抑制日志
+	var nilThrottle *logThrottle
+	nilThrottle.logf(log.Warnf, "nil throttle %d", 1)
+	nilThrottle.flushSummary()
+}
+
+func TestPartialSubscribeFailure(t *testing.T) {
+	// 服务端拒绝包含无效交易对的订阅请求，并在错误信息中指明该频道
+	requests := make(chan WsPayload, 10)
+	upgrader := gws.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			var req WsPayload
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			requests <- req
+			resp := fmt.Sprintf(`{"result":null,"id":%d}`, req.ID)
+			for _, param := range req.Params {
+				if strings.HasPrefix(param, "invalidsym@") {
+					resp = fmt.Sprintf(`{"error":{"code":2,"msg":"Invalid request: unknown stream '%s'"},"id":%d}`, param, req.ID)
+				}
+			}
+			conn.WriteMessage(gws.TextMessage, []byte(resp))
+		}
+	}))
+	defer server.Close()
+
+	ws := NewWebSocket()
+	ws.SetSubscribeConfirmTimeout(time.Second)
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	if err := ws.startConnection(conn, ws.wireBytes.Load()); err != nil {
+		t.Fatalf("startConnection returned error: %v", err)
+	}
+	defer ws.WsClose()
+
+	callback := func(types.MarketData) error { return nil }
+	if err := ws.SubscribeTicker([]types.Symbol{"BTCUSDT"}, callback); err != nil {
+		t.Fatalf("Expected valid subscription to succeed, got %v", err)
+	}
+
+	err = ws.SubscribeTicker([]types.Symbol{"ETHUSDT", "INVALIDSYM"}, callback)
+	var subErr *SubscribeError
+	if !errors.As(err, &subErr) {
+		t.Fatalf("Expected *SubscribeError, got %v", err)
+	}
+	if len(subErr.Channels) != 1 || subErr.Channels[0] != "invalidsym@ticker" || subErr.Code != 2 {
+		t.Errorf("Expected only invalidsym@ticker to be reported with code 2, got %+v", subErr)
+	}
+
+	// 被拒绝的频道从订阅映射中移除，同一请求中的有效频道重新订阅
+	deadline := time.After(time.Second)
+	var resubscribed bool
+	for !resubscribed {
+		select {
+		case req := <-requests:
+			resubscribed = len(req.Params) == 1 && req.Params[0] == "ethusdt@ticker"
+		case <-deadline:
+			t.Fatal("Expected remaining channel to be resubscribed")
+		}
+	}
+	channels := ws.GetActiveSubscriptions()
+	if len(channels) != 2 || ws.GetSubscriptionCount() != 2 {
+		t.Errorf("Expected btcusdt and ethusdt subscriptions to remain, got %v", channels)
+	}
+	for _, channel := range channels {
+		if channel == "invalidsym@ticker" {
+			t.Error("Expected rejected channel to be removed from subscriptions")
+		}
+	}
+
+	// 每个请求使用唯一的id
+	if sent := ws.requestSeq.Load(); sent != 3 {
+		t.Errorf("Expected 3 requests to be sent, got %d", sent)
+	}
+}
+
+func TestSubscribeConfirmTimeout(t *testing.T) {
+	// 服务端不响应订阅请求
+	upgrader := gws.Upgrader{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for {
+			if _, _, err := conn.ReadMessage(); err != nil {
+				return
+			}
+		}
+	}))
+	defer server.Close()
+
+	ws := NewWebSocket()
+	conn, _, err := gws.DefaultDialer.Dial("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+	if err != nil {
+		t.Fatalf("Failed to dial test server: %v", err)
+	}
+	if err := ws.startConnection(conn, ws.wireBytes.Load()); err != nil {
+		t.Fatalf("startConnection returned error: %v", err)
+	}
+	defer ws.WsClose()
+
+	// 默认不等待确认，发送成功即返回
+	if err := ws.Subscribe([]string{"btcusdt@ticker"}); err != nil {
+		t.Fatalf("Expected subscribe without confirmation to succeed, got %v", err)
+	}
+
+	ws.SetSubscribeConfirmTimeout(50 * time.Millisecond)
+	start := time.Now()
+	err = ws.Subscribe([]string{"ethusdt@ticker"})
+	if !errors.Is(err, ErrSubscribeTimeout) {
+		t.Fatalf("Expected ErrSubscribeTimeout, got %v", err)
+	}
+	if elapsed := time.Since(start); elapsed < 50*time.Millisecond || elapsed > time.Second {
+		t.Errorf("Expected to wait about the confirmation timeout, waited %v", elapsed)
+	}
+}
+
+func TestStreamStats(t *testing.T) {
+	for stream, expected := range map[string]string{
+		"btcusdt@kline_1m":      "kline",
+		"btcusdt@depth20@100ms": "depth",
+		"btcusdt@depth":         "depth",
+		"btcusdt@bookTicker":    "bookTicker",
+		"btcusdt@aggTrade":      "aggTrade",
+		wsAllTickersStream:      wsAllTickersStream,
+		"pqia91ma19a5s61cv6a81": "user_data",
+	} {
+		if got := streamTypeOf(stream); got != expected {
+			t.Errorf("streamTypeOf(%q) = %q, expected %q", stream, got, expected)
+		}
+	}
+
+	stats := newStreamStats()
+	start := time.Now()
+	for i := range 20 {
+		stats.record("ticker", start.Add(time.Duration(i)*500*time.Millisecond))
+	}
+	// 第一个窗口结束后的消息开始新窗口，每秒消息数按上一个窗口计算
+	stats.record("ticker", start.Add(streamRateWindow))
+	snapshot := stats.snapshot(start.Add(streamRateWindow + time.Second))
+	ticker, ok := snapshot["ticker"].(map[string]interface{})
+	if !ok {
+		t.Fatalf("Expected ticker stats, got %v", snapshot)
+	}
+	if ticker["messages"] != int64(21) || ticker["messages_per_second"] != 2.0 {
+		t.Errorf("Expected 21 messages at 2/s, got %v", ticker)
+	}
+
+	// 数据停止流入后每秒消息数下降
+	snapshot = stats.snapshot(start.Add(streamRateWindow + time.Minute))
+	if rate := snapshot["ticker"].(map[string]interface{})["messages_per_second"].(float64); rate >= 0.1 {
+		t.Errorf("Expected rate to decay after data stops, got %v", rate)
+	}
+
+	// wsHandleData按流类型计数
+	ws := &BinanceWebSocket{subscriptions: make(map[string]callbackList)}
+	msg := []byte(`{"stream":"bnbusdt@bookTicker","data":{"u":1,"s":"BNBUSDT","b":"1","B":"1","a":"1","A":"1"}}`)
+	if err := ws.wsHandleData(msg); err != nil {
+		t.Fatalf("wsHandleData returned error: %v", err)
+	}
+	status := ws.GetStreamStats()
+	streams := status["streams"].(map[string]interface{})
+	if bookTicker, ok := streams["bookTicker"].(map[string]interface{}); !ok || bookTicker["messages"] != int64(1) {
+		t.Errorf("Expected one bookTicker message, got %v", streams)
+	}
+	if status["connections"] != 0 || status["subscriptions"] != 0 {
+		t.Errorf("Expected no connections or subscriptions, got %v", status)
+	}
+
+	// 同样的统计以Prometheus指标导出
+	collector := (&Binance{WebSocket: ws}).MetricsCollector()
+	expected := `
+# HELP data_miner_binance_ws_messages_total Binance WebSocket messages received by stream type.
+# TYPE data_miner_binance_ws_messages_total counter
+data_miner_binance_ws_messages_total{stream_type="bookTicker"} 1
+# HELP data_miner_binance_ws_subscriptions Active Binance WebSocket stream subscriptions.
+# TYPE data_miner_binance_ws_subscriptions gauge
+data_miner_binance_ws_subscriptions 0
+`
+	if err := testutil.CollectAndCompare(collector, strings.NewReader(expected),
+		"data_miner_binance_ws_messages_total", "data_miner_binance_ws_subscriptions"); err != nil {
+		t.Errorf("Unexpected WebSocket metrics: %v", err)
+	}
+}
+
+// waitForGoroutines 等待协程数量回落到baseline以内，超时后输出仍在运行的协程堆栈
+func waitForGoroutines(t *testing.T, baseline int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("Goroutines leaked after shutdown: %d > %d\n%s", runtime.NumGoroutine(), baseline, buf[:n])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+// newConnectProxy 创建HTTP CONNECT代理，忽略请求的目标地址，将所有隧道转发到target
+func newConnectProxy(target string) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Method != http.MethodConnect {
+			http.Error(w, "CONNECT only", http.StatusMethodNotAllowed)
+			return
+		}
+		upstream, err := net.Dial("tcp", target)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadGateway)
+			return
+		}
+		client, _, err := w.(http.Hijacker).Hijack()
+		if err != nil {
+			upstream.Close()
+			return
+		}
+		client.Write([]byte("HTTP/1.1 200 Connection established\r\n\r\n"))
+
+		// 任一方向结束时关闭两端，另一方向的复制随之退出
+		done := make(chan struct{}, 2)
+		go func() { io.Copy(upstream, client); done <- struct{}{} }()
+		go func() { io.Copy(client, upstream); done <- struct{}{} }()
+		<-done
+		client.Close()
+		upstream.Close()
+		<-done
+	}))
+}
+
+func TestWsConnectCloseLeavesNoGoroutines(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	// 服务端响应订阅请求，第一个连接在响应后断开，触发读取协程退出和重连协程
+	var connections atomic.Int32
+	upgrader := gws.Upgrader{}
+	backend := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		first := connections.Add(1) == 1
+		for {
+			var req struct {
+				ID int64 `json:"id"`
+			}
+			if err := conn.ReadJSON(&req); err != nil {
+				return
+			}
+			conn.WriteMessage(gws.TextMessage, []byte(fmt.Sprintf(`{"result":null,"id":%d}`, req.ID)))
+			if first {
+				return
+			}
+		}
+	}))
+	proxy := newConnectProxy(backend.Listener.Addr().String())
+
+	ws := NewWebSocket()
+	pool := x509.NewCertPool()
+	pool.AddCert(backend.Certificate())
+	ws.tlsConfig = &tls.Config{RootCAs: pool, ServerName: "example.com"}
+	if err := ws.SetProxy(proxy.URL); err != nil {
+		t.Fatalf("SetProxy returned error: %v", err)
+	}
+	ws.SetReconnectConfig(types.WebsocketReconnectConfig{BaseDelay: 10 * time.Millisecond, MaxDelay: 20 * time.Millisecond, MaxAttempts: 3})
+
+	if err := ws.WsConnect(); err != nil {
+		t.Fatalf("WsConnect returned error: %v", err)
+	}
+	if _, err := ws.SubscribeChannel("btcusdt@ticker", func(types.MarketData) error { return nil }); err != nil {
+		t.Fatalf("SubscribeChannel returned error: %v", err)
+	}
+
+	// 等待断线重连完成
+	deadline := time.Now().Add(2 * time.Second)
+	for (connections.Load() < 2 || !ws.IsConnected()) && time.Now().Before(deadline) {
+		time.Sleep(10 * time.Millisecond)
+	}
+	if connections.Load() < 2 || !ws.IsConnected() {
+		t.Fatalf("Expected reconnect after server disconnect, connections=%d connected=%v", connections.Load(), ws.IsConnected())
+	}
+
+	if err := ws.WsClose(); err != nil {
+		t.Fatalf("WsClose returned error: %v", err)
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer cancel()
+	if err := ws.Wait(ctx); err != nil {
+		t.Fatalf("Background goroutines did not exit after WsClose: %v", err)
+	}
+
+	proxy.Close()
+	backend.Close()
+	waitForGoroutines(t, baseline, 2*time.Second)
+}
+
+func TestWebsocketCompression(t *testing.T) {
+	// 服务端支持压缩，推送重复度高的JSON消息后断开
+	message := []byte(`{"stream":"!ticker@arr","data":[` + strings.Repeat(`{"e":"24hrTicker","s":"BTCUSDT","c":"42000.00"},`, 200) + `{}]}`)
+	upgrader := gws.Upgrader{EnableCompression: true}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		conn, err := upgrader.Upgrade(w, r, nil)
+		if err != nil {
+			return
+		}
+		defer conn.Close()
+		for range 5 {
+			conn.WriteMessage(gws.TextMessage, message)
+		}
+	}))
+	defer server.Close()
+
+	for _, enabled := range []bool{true, false} {
+		ws := NewWebSocket()
+		ws.SetCompression(enabled)
+		wireStart := ws.wireBytes.Load()
+		conn, resp, err := ws.dialWebSocket("ws"+strings.TrimPrefix(server.URL, "http"), nil)
+		if err != nil {
+			t.Fatalf("Failed to dial test server: %v", err)
+		}
+		if got := negotiatedCompression(resp.Header.Get("Sec-WebSocket-Extensions")); got != enabled {
+			t.Errorf("compression %v: expected negotiated %v, got %v", enabled, enabled, got)
+		}
+
+		readDone := make(chan struct{})
+		ws.wsConn, ws.readDone = conn, readDone
+		ws.wsConnected.Store(true)
+		ws.wg.Add(1)
+		go ws.wsReadData(conn, readDone, wireStart)
+		select {
+		case <-readDone:
+		case <-time.After(time.Second):
+			t.Fatal("Read goroutine did not exit after server disconnect")
+		}
+		ws.WsClose()
+		ctx, cancel := context.WithTimeout(context.Background(), time.Second)
+		if err := ws.Wait(ctx); err != nil {
+			t.Errorf("Background goroutines did not exit after WsClose: %v", err)
+		}
+		cancel()
+
+		bandwidth := ws.GetStreamStats()["bandwidth"].(map[string]interface{})
+		wire, payload := bandwidth["wire_bytes"].(int64), bandwidth["payload_bytes"].(int64)
+		if payload != int64(5*len(message)) {
+			t.Errorf("compression %v: expected %d payload bytes, got %d", enabled, 5*len(message), payload)
+		}
+		// 压缩后网络字节数远小于解压后字节数，未压缩时包含帧头和握手开销
+		if enabled && bandwidth["savings_ratio"].(float64) < 0.5 {
+			t.Errorf("Expected compression to save over half of bandwidth, got wire=%d payload=%d", wire, payload)
+		}
+		if !enabled && wire < payload {
+			t.Errorf("Expected uncompressed wire bytes >= payload, got wire=%d payload=%d", wire, payload)
+		}
+	}
+}
@@ -0,0 +1,84 @@
+package binance
+
+import (
+	"context"
+	"net"
+	"strings"
+	"sync/atomic"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// countingConn 统计从网络读取字节数的连接，用于计算permessage-deflate压缩节省的带宽
+type countingConn struct {
+	net.Conn
+	read *atomic.Int64
+}
+
+// Read 读取数据并累计字节数
+func (c *countingConn) Read(p []byte) (int, error) {
+	n, err := c.Conn.Read(p)
+	c.read.Add(int64(n))
+	return n, err
+}
+
+// SetCompression 设置是否在握手时请求permessage-deflate压缩（默认开启），下次建立连接时生效
+func (ws *BinanceWebSocket) SetCompression(enabled bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.compressionOff = !enabled
+}
+
+// compressionEnabled 返回是否请求permessage-deflate压缩
+func (ws *BinanceWebSocket) compressionEnabled() bool {
+	ws.mu.RLock()
+	defer ws.mu.RUnlock()
+	return !ws.compressionOff
+}
+
+// countingDialer 包装拨号函数，统计连接从网络读取的字节数（压缩和TLS加密后，经代理时包含隧道内的全部流量）
+func (ws *BinanceWebSocket) countingDialer(dial func(ctx context.Context, network, addr string) (net.Conn, error)) func(ctx context.Context, network, addr string) (net.Conn, error) {
+	if dial == nil {
+		dial = (&net.Dialer{}).DialContext
+	}
+	return func(ctx context.Context, network, addr string) (net.Conn, error) {
+		conn, err := dial(ctx, network, addr)
+		if err != nil {
+			return nil, err
+		}
+		return &countingConn{Conn: conn, read: &ws.wireBytes}, nil
+	}
+}
+
+// negotiatedCompression 判断握手响应是否协商了permessage-deflate压缩
+func negotiatedCompression(extensions string) bool {
+	return strings.Contains(extensions, "permessage-deflate")
+}
+
+// savingsRatio 计算压缩节省的带宽比例（1-网络字节数/解压后字节数），包含TLS和帧头开销，未压缩时可能略小于0
+func savingsRatio(wire, payload int64) float64 {
+	if payload <= 0 {
+		return 0
+	}
+	return 1 - float64(wire)/float64(payload)
+}
+
+// bandwidthStats 返回从网络读取的字节数、解压后的消息字节数和压缩节省的带宽比例
+func (ws *BinanceWebSocket) bandwidthStats() map[string]interface{} {
+	wire, payload := ws.wireBytes.Load(), ws.payloadBytes.Load()
+	return map[string]interface{}{
+		"compression":   ws.compressionEnabled(),
+		"wire_bytes":    wire,
+		"payload_bytes": payload,
+		"savings_ratio": savingsRatio(wire, payload),
+	}
+}
+
+// logConnectionBandwidth 连接结束时输出连接期间的带宽统计，wire和payload为连接期间读取的字节数
+func logConnectionBandwidth(wire, payload int64) {
+	if payload <= 0 {
+		return
+	}
+	log.Infof(log.WebsocketMgr, "WebSocket连接期间从网络读取 %d 字节，解压后消息 %d 字节，压缩节省 %.1f%%",
+		wire, payload, savingsRatio(wire, payload)*100)
+}
@@ -0,0 +1,126 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// CallbackID 频道上注册的回调标识，用于单独取消某个回调
+type CallbackID uint64
+
+// subscriptionCallback 频道上注册的一个回调
+type subscriptionCallback struct {
+	id       CallbackID
+	callback types.DataCallback
+}
+
+// callbackList 频道上按注册顺序排列的回调
+type callbackList []subscriptionCallback
+
+// addSubscription 为频道注册回调，同一频道可注册多个回调（如存储和监控），数据到达时依次调用
+func (ws *BinanceWebSocket) addSubscription(channel string, callback types.DataCallback) CallbackID {
+	id := CallbackID(ws.callbackSeq.Add(1))
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.subscriptions[channel] = append(ws.subscriptions[channel], subscriptionCallback{id: id, callback: callback})
+	log.Debugf(log.WebsocketMgr, "添加订阅: %s (回调 %d)", channel, id)
+	return id
+}
+
+// removeSubscription 从内部映射移除频道及其全部回调
+func (ws *BinanceWebSocket) removeSubscription(channel string) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	delete(ws.subscriptions, channel)
+	log.Debugf(log.WebsocketMgr, "移除订阅: %s", channel)
+}
+
+// removeCallback 移除频道上的单个回调，返回回调是否存在以及频道是否已没有回调
+func (ws *BinanceWebSocket) removeCallback(channel string, id CallbackID) (found, empty bool) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+
+	callbacks := ws.subscriptions[channel]
+	for i, cb := range callbacks {
+		if cb.id != id {
+			continue
+		}
+		callbacks = append(callbacks[:i:i], callbacks[i+1:]...)
+		if len(callbacks) == 0 {
+			delete(ws.subscriptions, channel)
+		} else {
+			ws.subscriptions[channel] = callbacks
+		}
+		log.Debugf(log.WebsocketMgr, "移除订阅回调: %s (回调 %d)", channel, id)
+		return true, len(callbacks) == 0
+	}
+	return false, false
+}
+
+// getSubscriptionCallback 获取订阅的回调函数，频道注册了多个回调时返回依次调用全部回调的函数，
+// 每个回调的错误单独记录，互不影响
+func (ws *BinanceWebSocket) getSubscriptionCallback(channel string) (types.DataCallback, bool) {
+	ws.mu.RLock()
+	registered, exists := ws.subscriptions[channel]
+	callbacks := make([]subscriptionCallback, 0, len(registered))
+	for _, cb := range registered {
+		if cb.callback != nil {
+			callbacks = append(callbacks, cb)
+		}
+	}
+	ws.mu.RUnlock()
+
+	switch len(callbacks) {
+	case 0:
+		return nil, exists
+	case 1:
+		return callbacks[0].callback, true
+	}
+	return func(data types.MarketData) error {
+		var errs []error
+		for _, cb := range callbacks {
+			if err := cb.callback(data); err != nil {
+				log.Errorf(log.WebsocketMgr, "频道 %s 的回调 %d 处理失败: %v", channel, cb.id, err)
+				errs = append(errs, fmt.Errorf("callback %d: %w", cb.id, err))
+			}
+		}
+		return errors.Join(errs...)
+	}, true
+}
+
+// SubscribeChannel 为频道注册一个回调并返回其标识，频道首次注册时发送订阅请求。
+// 同一频道可多次调用以注册多个回调，通过UnsubscribeCallback单独取消
+func (ws *BinanceWebSocket) SubscribeChannel(channel string, callback types.DataCallback) (CallbackID, error) {
+	if !ws.wsConnected.Load() {
+		return 0, errors.New("WebSocket未连接")
+	}
+
+	ws.mu.RLock()
+	_, subscribed := ws.subscriptions[channel]
+	ws.mu.RUnlock()
+
+	id := ws.addSubscription(channel, callback)
+	if subscribed {
+		return id, nil
+	}
+	if err := ws.Subscribe([]string{channel}); err != nil {
+		ws.removeCallback(channel, id)
+		return 0, err
+	}
+	return id, nil
+}
+
+// UnsubscribeCallback 取消频道上的单个回调，频道上没有其他回调时同时取消订阅该频道
+func (ws *BinanceWebSocket) UnsubscribeCallback(channel string, id CallbackID) error {
+	found, empty := ws.removeCallback(channel, id)
+	if !found {
+		return fmt.Errorf("频道 %s 上不存在回调 %d", channel, id)
+	}
+	if !empty || !ws.wsConnected.Load() {
+		return nil
+	}
+	return ws.Unsubscribe([]string{channel})
+}
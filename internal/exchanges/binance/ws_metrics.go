@@ -0,0 +1,133 @@
+package binance
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// streamRateWindow 计算每秒消息数的统计窗口
+const streamRateWindow = 10 * time.Second
+
+// streamStats 按流类型统计收到的消息数和每秒消息数，用于确认重连和上新交易对后数据仍在流入
+type streamStats struct {
+	mu    sync.Mutex
+	types map[string]*streamCounter
+}
+
+// streamCounter 单个流类型的消息计数
+type streamCounter struct {
+	total       int64     // 累计消息数
+	windowStart time.Time // 当前统计窗口的开始时间
+	windowCount int64     // 当前统计窗口内的消息数
+	rate        float64   // 上一个完整窗口的每秒消息数
+}
+
+// newStreamStats 创建流消息统计
+func newStreamStats() *streamStats {
+	return &streamStats{types: make(map[string]*streamCounter)}
+}
+
+// record 记录一条streamType类型的消息
+func (s *streamStats) record(streamType string, now time.Time) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	counter, exists := s.types[streamType]
+	if !exists {
+		counter = &streamCounter{windowStart: now}
+		s.types[streamType] = counter
+	}
+	if elapsed := now.Sub(counter.windowStart); elapsed >= streamRateWindow {
+		counter.rate = counterRate(counter.windowCount, elapsed)
+		counter.windowStart, counter.windowCount = now, 0
+	}
+	counter.total++
+	counter.windowCount++
+}
+
+// streamRate 单个流类型的累计消息数和每秒消息数
+type streamRate struct {
+	total int64
+	rate  float64
+}
+
+// rates 返回各流类型的累计消息数和每秒消息数。当前窗口已超过统计窗口时（如数据停止流入）
+// 按当前窗口计算，数据停止后每秒消息数逐渐降为0
+func (s *streamStats) rates(now time.Time) map[string]streamRate {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	result := make(map[string]streamRate, len(s.types))
+	for streamType, counter := range s.types {
+		rate := counter.rate
+		if elapsed := now.Sub(counter.windowStart); elapsed >= streamRateWindow {
+			rate = counterRate(counter.windowCount, elapsed)
+		}
+		result[streamType] = streamRate{total: counter.total, rate: rate}
+	}
+	return result
+}
+
+// snapshot 以状态接口的格式返回各流类型的累计消息数和每秒消息数
+func (s *streamStats) snapshot(now time.Time) map[string]interface{} {
+	rates := s.rates(now)
+	result := make(map[string]interface{}, len(rates))
+	for streamType, r := range rates {
+		result[streamType] = map[string]interface{}{
+			"messages":            r.total,
+			"messages_per_second": r.rate,
+		}
+	}
+	return result
+}
+
+// counterRate 计算每秒消息数
+func counterRate(count int64, elapsed time.Duration) float64 {
+	if elapsed <= 0 {
+		return 0
+	}
+	return float64(count) / elapsed.Seconds()
+}
+
+// streamTypeOf 从流名称中提取流类型，去掉交易对、周期、档数和推送频率，
+// 如btcusdt@kline_1m为kline，btcusdt@depth20@100ms为depth
+func streamTypeOf(stream string) string {
+	if stream == wsAllTickersStream {
+		return stream
+	}
+	parts := strings.Split(stream, "@")
+	if len(parts) < 2 {
+		return "user_data" // 用户数据流以listenKey作为流名称
+	}
+	streamType, _, _ := strings.Cut(parts[1], "_")
+	return strings.TrimRight(streamType, "0123456789")
+}
+
+// streamStatsOrInit 返回流消息统计，首次使用时创建
+func (ws *BinanceWebSocket) streamStatsOrInit() *streamStats {
+	ws.statsOnce.Do(func() {
+		ws.stats = newStreamStats()
+	})
+	return ws.stats
+}
+
+// GetConnectionCount 获取当前打开的WebSocket连接数（当前所有订阅共用一个连接）
+func (ws *BinanceWebSocket) GetConnectionCount() int {
+	if ws.wsConnected.Load() {
+		return 1
+	}
+	return 0
+}
+
+// GetStreamStats 获取WebSocket连接数、订阅数、按流类型统计的消息数/每秒消息数和带宽统计，
+// 用于确认订阅在重连后仍然存在、数据仍在流入
+func (ws *BinanceWebSocket) GetStreamStats() map[string]interface{} {
+	return map[string]interface{}{
+		"state":         ws.ConnState().String(),
+		"connections":   ws.GetConnectionCount(),
+		"subscriptions": ws.GetSubscriptionCount(),
+		"streams":       ws.streamStatsOrInit().snapshot(time.Now()),
+		"bandwidth":     ws.bandwidthStats(),
+	}
+}
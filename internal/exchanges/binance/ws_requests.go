@@ -0,0 +1,163 @@
+package binance
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/buger/jsonparser"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// ErrSubscribeTimeout 开启订阅确认时，超时未收到交易所对订阅请求的响应
+var ErrSubscribeTimeout = errors.New("subscription confirmation timed out")
+
+// SubscribeError 交易所拒绝订阅请求时返回的错误，Channels为被拒绝的频道（已从订阅映射中移除）
+type SubscribeError struct {
+	Channels []string // 被拒绝的频道
+	Code     int64    // 交易所返回的错误码
+	Msg      string   // 交易所返回的错误信息
+}
+
+// Error 实现error接口
+func (e *SubscribeError) Error() string {
+	return fmt.Sprintf("订阅被拒绝 (code %d: %s)，频道: %v", e.Code, e.Msg, e.Channels)
+}
+
+// pendingRequest 已发送、尚未收到响应的订阅/取消订阅请求
+type pendingRequest struct {
+	method   string
+	channels []string
+	done     chan error // 收到响应时写入结果（成功为nil），缓冲为1，无人等待时不阻塞
+}
+
+// SetSubscribeConfirmTimeout 设置订阅后等待交易所确认的超时时间，<=0时不等待（默认）
+func (ws *BinanceWebSocket) SetSubscribeConfirmTimeout(timeout time.Duration) {
+	ws.mu.Lock()
+	defer ws.mu.Unlock()
+	ws.ackTimeout = max(timeout, 0)
+}
+
+// waitConfirm 开启订阅确认时等待请求的响应，未开启时直接返回（拒绝响应由读取协程异步处理）
+func (ws *BinanceWebSocket) waitConfirm(id int64, req *pendingRequest) error {
+	ws.mu.RLock()
+	timeout := ws.ackTimeout
+	ws.mu.RUnlock()
+	if timeout <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(timeout)
+	defer timer.Stop()
+	select {
+	case err := <-req.done:
+		return err
+	case <-timer.C:
+		return fmt.Errorf("%w after %v (ID %d, channels %v)", ErrSubscribeTimeout, timeout, id, req.channels)
+	case <-ws.done:
+		return errors.New("WebSocket已关闭")
+	}
+}
+
+// addPending 登记已发送的请求，用于按id关联交易所的响应
+func (ws *BinanceWebSocket) addPending(id int64, method string, channels []string) *pendingRequest {
+	req := &pendingRequest{method: method, channels: channels, done: make(chan error, 1)}
+	ws.pendingMu.Lock()
+	defer ws.pendingMu.Unlock()
+	if ws.pending == nil {
+		ws.pending = make(map[int64]*pendingRequest)
+	}
+	ws.pending[id] = req
+	return req
+}
+
+// takePending 取出并移除id对应的请求
+func (ws *BinanceWebSocket) takePending(id int64) (*pendingRequest, bool) {
+	ws.pendingMu.Lock()
+	defer ws.pendingMu.Unlock()
+	req, ok := ws.pending[id]
+	delete(ws.pending, id)
+	return req, ok
+}
+
+// clearPending 丢弃所有未收到响应的请求，连接重建后旧连接上的请求不会再有响应
+func (ws *BinanceWebSocket) clearPending() {
+	ws.pendingMu.Lock()
+	defer ws.pendingMu.Unlock()
+	clear(ws.pending)
+}
+
+// handleRequestResponse 处理带id的订阅/取消订阅响应：订阅被拒绝时从订阅映射中移除失败的频道，
+// 避免重连后反复订阅无效的流，并将结果通知等待中的订阅方
+func (ws *BinanceWebSocket) handleRequestResponse(id int64, respRaw []byte) error {
+	req, ok := ws.takePending(id)
+
+	errorMsg, err := jsonparser.GetString(respRaw, "error", "msg")
+	if err != nil {
+		log.Debugf(log.WebsocketMgr, "请求成功，ID: %d", id)
+		if ok {
+			req.done <- nil
+		}
+		return nil
+	}
+	code, _ := jsonparser.GetInt(respRaw, "error", "code")
+	if !ok {
+		return fmt.Errorf("请求 %d 失败 (code %d: %s)", id, code, errorMsg)
+	}
+	if req.method != wsSubscribeMethod {
+		err := fmt.Errorf("%s请求 %d 失败 (code %d: %s)，频道: %v", req.method, id, code, errorMsg, req.channels)
+		req.done <- err
+		return err
+	}
+
+	failed, rest := failedChannels(req.channels, errorMsg)
+	subErr := &SubscribeError{Channels: failed, Code: code, Msg: errorMsg}
+	for _, channel := range failed {
+		ws.removeFailedChannel(channel)
+	}
+	if len(rest) > 0 {
+		ws.resubscribeRest(rest)
+	}
+	req.done <- subErr
+	return subErr
+}
+
+// removeFailedChannel 移除被拒绝的频道，用户数据流的listenKey被拒绝时同时清除，重连后不再订阅
+func (ws *BinanceWebSocket) removeFailedChannel(channel string) {
+	ws.removeSubscription(channel)
+	ws.mu.Lock()
+	if ws.userDataKey == channel {
+		ws.userDataKey = ""
+		ws.userDataCallback = nil
+	}
+	ws.mu.Unlock()
+}
+
+// resubscribeRest 在后台重新订阅被整体拒绝的请求中有效的频道（读取协程中不能等待订阅响应）
+func (ws *BinanceWebSocket) resubscribeRest(channels []string) {
+	ws.wg.Add(1)
+	go func() {
+		defer ws.wg.Done()
+		log.Infof(log.WebsocketMgr, "移除被拒绝的频道后重新订阅 %d 个频道: %v", len(channels), channels)
+		if err := ws.Subscribe(channels); err != nil {
+			log.Errorf(log.WebsocketMgr, "重新订阅有效频道失败: %v", err)
+		}
+	}()
+}
+
+// failedChannels 从错误信息中找出被拒绝的频道，返回被拒绝的频道和其余频道；错误信息未指明具体频道时，
+// 整个请求的频道都视为失败。交易所对一次订阅请求整体接受或拒绝，其余频道需要重新订阅
+func failedChannels(channels []string, errorMsg string) (failed, rest []string) {
+	for _, channel := range channels {
+		if strings.Contains(errorMsg, channel) {
+			failed = append(failed, channel)
+		} else {
+			rest = append(rest, channel)
+		}
+	}
+	if len(failed) == 0 {
+		return channels, nil
+	}
+	return failed, rest
+}
@@ -0,0 +1,133 @@
+package binance
+
+import (
+	"errors"
+	"net"
+	"time"
+
+	gws "github.com/gorilla/websocket"
+)
+
+// ConnState WebSocket连接状态
+type ConnState int32
+
+const (
+	// ConnStateDisconnected 未连接（尚未连接或重连次数耗尽）
+	ConnStateDisconnected ConnState = iota
+	// ConnStateConnecting 正在建立连接
+	ConnStateConnecting
+	// ConnStateConnected 已连接且收发正常
+	ConnStateConnected
+	// ConnStateDegraded 连接仍打开但已停滞：超过keepalive超时未收到任何帧，或有订阅但长时间未收到数据
+	ConnStateDegraded
+	// ConnStateReconnecting 连接断开，正在重连
+	ConnStateReconnecting
+	// ConnStateClosed 已主动关闭，不再重连
+	ConnStateClosed
+)
+
+const (
+	wsKeepaliveTimeout = 4 * time.Minute  // 超过该时间未收到任何帧（数据或服务端ping）视为停滞，服务端每3分钟ping一次
+	wsDataStaleTimeout = time.Minute      // 有订阅时超过该时间未收到数据视为停滞
+	wsPongWriteTimeout = 10 * time.Second // 回复服务端ping的写超时
+)
+
+// String 返回连接状态名称
+func (s ConnState) String() string {
+	switch s {
+	case ConnStateDisconnected:
+		return "disconnected"
+	case ConnStateConnecting:
+		return "connecting"
+	case ConnStateConnected:
+		return "connected"
+	case ConnStateDegraded:
+		return "degraded"
+	case ConnStateReconnecting:
+		return "reconnecting"
+	case ConnStateClosed:
+		return "closed"
+	default:
+		return "unknown"
+	}
+}
+
+// setState 更新连接状态，已关闭后不再变更
+func (ws *BinanceWebSocket) setState(state ConnState) {
+	for {
+		current := ws.state.Load()
+		if ConnState(current) == ConnStateClosed {
+			return
+		}
+		if ws.state.CompareAndSwap(current, int32(state)) {
+			return
+		}
+	}
+}
+
+// ConnState 返回当前连接状态；连接打开但keepalive或数据超时时返回ConnStateDegraded
+func (ws *BinanceWebSocket) ConnState() ConnState {
+	state := ConnState(ws.state.Load())
+	if state == ConnStateConnected && ws.isStalled(time.Now()) {
+		return ConnStateDegraded
+	}
+	return state
+}
+
+// IsHealthy 返回连接是否健康：已连接，且keepalive和数据接收均未超时。
+// 与IsConnected不同，停滞的连接（socket仍打开但不再收到数据或ping）视为不健康
+func (ws *BinanceWebSocket) IsHealthy() bool {
+	return ws.ConnState() == ConnStateConnected
+}
+
+// isStalled 判断当前连接是否停滞，时间从连接建立时开始计算
+func (ws *BinanceWebSocket) isStalled(now time.Time) bool {
+	connectedAt := time.Unix(0, ws.connectedAt.Load())
+	lastFrame := connectedAt
+	for _, t := range []time.Time{ws.GetLastPing(), ws.GetLastDataTime()} {
+		if t.After(lastFrame) {
+			lastFrame = t
+		}
+	}
+	if now.Sub(lastFrame) > wsKeepaliveTimeout {
+		return true
+	}
+
+	if ws.GetSubscriptionCount() == 0 {
+		return false
+	}
+	lastData := ws.GetLastDataTime()
+	if lastData.Before(connectedAt) {
+		lastData = connectedAt
+	}
+	return now.Sub(lastData) > wsDataStaleTimeout
+}
+
+// GetLastDataTime 获取最近一次收到数据消息的时间，未收到过时返回零值
+func (ws *BinanceWebSocket) GetLastDataTime() time.Time {
+	return unixNanoTime(ws.lastData.Load())
+}
+
+// markConnected 记录连接建立，并在收到服务端ping时更新keepalive时间后回复pong
+func (ws *BinanceWebSocket) markConnected(conn *gws.Conn) {
+	ws.connectedAt.Store(time.Now().UnixNano())
+	conn.SetPingHandler(func(appData string) error {
+		ws.lastPing.Store(time.Now().UnixNano())
+		err := conn.WriteControl(gws.PongMessage, []byte(appData), time.Now().Add(wsPongWriteTimeout))
+		// 与默认处理一致：连接正在关闭或写超时不视为读取错误
+		var netErr net.Error
+		if errors.Is(err, gws.ErrCloseSent) || (errors.As(err, &netErr) && netErr.Timeout()) {
+			return nil
+		}
+		return err
+	})
+	ws.setState(ConnStateConnected)
+}
+
+// unixNanoTime 将纳秒时间戳转换为时间，0返回零值
+func unixNanoTime(nanos int64) time.Time {
+	if nanos == 0 {
+		return time.Time{}
+	}
+	return time.Unix(0, nanos)
+}
@@ -2,6 +2,7 @@ package httpclient
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
 	"net/http"
@@ -10,15 +11,32 @@ import (
 	"time"
 
 	"github.com/mooyang-code/data-miner/internal/ipmanager"
-	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
 )
 
 // HTTPClient HTTP客户端实现
 type HTTPClient struct {
 	config       *Config
 	httpClient   *http.Client
-	ipManager    *ipmanager.Manager
+	transport    *http.Transport
+	ipManager    atomic.Pointer[ipmanager.Manager] // Close后为nil，拨号等路径不持有mu读取
 	retryHandler *RetryHandler
+	logger       Logger
+
+	// 直连域名的HTTP客户端（RequestOptions.EnableDynamicIP为false时使用），首次使用时创建
+	directOnce sync.Once
+	direct     *http.Client
+
+	// 连接池统计
+	connStats *connPoolStats
+
+	// 取消IP列表更新监听（用于连接预热）
+	removeIPListener func()
+
+	// 合并中的并发相同请求
+	inflight requestGroup
+
+	// 在途请求数限制
+	concurrency *concurrencyLimiter
 
 	// 状态管理
 	mu             sync.RWMutex
@@ -31,8 +49,10 @@ type HTTPClient struct {
 		successRequests int64
 		failedRequests  int64
 		retryCount      int64
+		coalesced       int64
 		lastRequest     time.Time
 		lastError       string
+		errorsByType    map[ErrorType]int64 // 由mu保护
 	}
 
 	// 速率限制
@@ -58,6 +78,12 @@ func New(config *Config) (Client, error) {
 		config:         config,
 		defaultHeaders: make(map[string]string),
 		running:        true,
+		connStats:      newConnPoolStats(),
+		concurrency:    newConcurrencyLimiter(config.MaxInFlight),
+		logger:         config.Logger,
+	}
+	if client.logger == nil {
+		client.logger = defaultLogger
 	}
 
 	// 初始化HTTP客户端
@@ -72,11 +98,15 @@ func New(config *Config) (Client, error) {
 
 	// 初始化重试处理器
 	client.retryHandler = NewRetryHandler(config.Retry, config.Name)
+	client.retryHandler.logger = client.logger
 
 	// 初始化速率限制
 	client.initRateLimit()
 
-	log.Infof(log.ExchangeSys, "HTTP client '%s' initialized successfully", config.Name)
+	// 初始化错误指标
+	initErrorMetrics(config.Name)
+
+	client.getLogger().Infof("HTTP client '%s' initialized successfully", config.Name)
 	return client, nil
 }
 
@@ -92,11 +122,30 @@ func (c *HTTPClient) initHTTPClient() error {
 		ResponseHeaderTimeout: c.config.Transport.ResponseHeaderTimeout,
 		DisableKeepAlives:     c.config.Transport.DisableKeepAlives,
 		DisableCompression:    c.config.Transport.DisableCompression,
-		ForceAttemptHTTP2:     false, // 使用HTTP/1.1更稳定
+		// 默认使用HTTP/1.1；启用后通过ALPN协商HTTP/2多路复用，服务端不支持时自动回退到HTTP/1.1
+		ForceAttemptHTTP2: c.config.Transport.EnableHTTP2,
 	}
 
+	tlsConfig, err := c.config.TLS.Build()
+	if err != nil {
+		return err
+	}
+	transport.TLSClientConfig = tlsConfig
+
+	// 配置代理后由Transport通过代理建立连接（支持http、https、socks5）
+	if c.config.ProxyURL != "" {
+		proxyURL, err := ParseProxyURL(c.config.ProxyURL)
+		if err != nil {
+			return err
+		}
+		transport.Proxy = http.ProxyURL(proxyURL)
+		c.getLogger().Infof("HTTP client '%s' using proxy %s", c.config.Name, proxyURL.Redacted())
+	}
+
+	// 日志中间件在开启Debug和LogBodies时记录所有请求，否则只记录设置了RequestOptions.Verbose的请求
+	c.transport = transport
 	c.httpClient = &http.Client{
-		Transport: transport,
+		Transport: newLoggingTransport(transport, c.config),
 		Timeout:   c.config.Timeout,
 	}
 	return nil
@@ -105,20 +154,44 @@ func (c *HTTPClient) initHTTPClient() error {
 // initIPManager 初始化IP管理器
 func (c *HTTPClient) initIPManager() error {
 	if !c.config.DynamicIP.Enabled || c.config.DynamicIP.Hostname == "" {
-		log.Debugf(log.ExchangeSys, "Dynamic IP disabled for client '%s'", c.config.Name)
+		c.getLogger().Debugf("Dynamic IP disabled for client '%s'", c.config.Name)
+		return nil
+	}
+
+	// 经代理转发时实际连接的是代理服务器，不能再替换目标IP
+	if c.config.ProxyURL != "" {
+		c.getLogger().Infof("Dynamic IP bypassed for client '%s' because a proxy is configured", c.config.Name)
 		return nil
 	}
 
-	// 创建IP管理器
-	c.ipManager = ipmanager.New(c.config.DynamicIP.IPManager)
+	ipConfig := c.config.DynamicIP.IPManager
+	if ipConfig == nil {
+		ipConfig = ipmanager.DefaultConfig(c.config.DynamicIP.Hostname)
+	} else if ipConfig.Hostname == "" {
+		cfg := *ipConfig
+		cfg.Hostname = c.config.DynamicIP.Hostname
+		ipConfig = &cfg
+	}
+	if ipConfig.Logger == nil && c.config.Logger != nil {
+		cfg := *ipConfig
+		cfg.Logger = c.config.Logger
+		ipConfig = &cfg
+	}
 
-	// 启动IP管理器
-	ctx := context.Background()
-	if err := c.ipManager.Start(ctx); err != nil {
+	// 获取共享的IP管理器（同一域名的客户端复用同一个管理器）
+	ipManager, err := ipmanager.Acquire(ipConfig)
+	if err != nil {
 		return fmt.Errorf("failed to start IP manager: %w", err)
 	}
+	c.ipManager.Store(ipManager)
+
+	// 启动时及每次IP列表更新后预热连接
+	if c.config.DynamicIP.Prewarm {
+		c.removeIPListener = ipManager.AddUpdateListener(c.prewarmConnections)
+		go c.prewarmConnections(ipManager.GetAllIPs())
+	}
 
-	log.Infof(log.ExchangeSys, "IP manager started for client '%s' with hostname '%s'",
+	c.getLogger().Infof("IP manager started for client '%s' with hostname '%s'",
 		c.config.Name, c.config.DynamicIP.Hostname)
 	return nil
 }
@@ -130,6 +203,14 @@ func (c *HTTPClient) initRateLimit() {
 	c.rateLimit.lastReset = time.Now()
 }
 
+// selectIP 选择本次连接使用的IP（启用轮询时在延迟最低的前TopK个IP之间轮询）
+func (c *HTTPClient) selectIP(ipManager *ipmanager.Manager) (string, error) {
+	if c.config.DynamicIP.RoundRobin {
+		return ipManager.GetRoundRobinIP(c.config.DynamicIP.TopK, c.config.DynamicIP.LatencyBudget)
+	}
+	return ipManager.GetCurrentIP()
+}
+
 // customDialContext 自定义拨号器，用于IP替换
 func (c *HTTPClient) customDialContext(ctx context.Context, network, addr string) (net.Conn, error) {
 	host, port, err := net.SplitHostPort(addr)
@@ -140,20 +221,30 @@ func (c *HTTPClient) customDialContext(ctx context.Context, network, addr string
 	originalAddr := addr
 
 	// 如果启用了动态IP且匹配目标主机名，使用IP管理器获取IP
+	// 请求设置了EnableDynamicIP为false时直连域名
+	ipManager := c.ipManager.Load()
 	if c.config.DynamicIP.Enabled &&
-		c.ipManager != nil &&
+		!isDirectDial(ctx) &&
+		ipManager != nil &&
 		host == c.config.DynamicIP.Hostname &&
-		c.ipManager.IsRunning() {
+		ipManager.IsRunning() {
 
-		ip, err := c.ipManager.GetCurrentIP()
+		ip, ok := preferredIPFromContext(ctx)
+		if !ok {
+			ip, err = c.selectIP(ipManager)
+		}
 		if err != nil {
-			log.Warnf(log.ExchangeSys, "Failed to get IP from manager for %s, using original address: %v",
+			c.getLogger().Warnf("Failed to get IP from manager for %s, using original address: %v",
 				c.config.Name, err)
+			// IP列表为空时触发一次更新，以便后续请求能恢复使用动态IP
+			if errors.Is(err, ipmanager.ErrNoAvailableIPs) {
+				ipManager.ForceUpdate()
+			}
 		} else {
 			// 使用IP替换域名
 			addr = net.JoinHostPort(ip, port)
-			if c.config.Debug {
-				log.Debugf(log.ExchangeSys, "Client '%s': Using IP %s instead of %s for %s",
+			if c.config.Debug || isVerbose(ctx) {
+				detailLogf(ctx, c.getLogger())("Client '%s': Using IP %s instead of %s for %s",
 					c.config.Name, ip, host, originalAddr)
 			}
 		}
@@ -164,7 +255,11 @@ func (c *HTTPClient) customDialContext(ctx context.Context, network, addr string
 		Timeout:   30 * time.Second,
 		KeepAlive: 30 * time.Second,
 	}
-	return dialer.DialContext(ctx, network, addr)
+	conn, err := dialer.DialContext(ctx, network, addr)
+	if err != nil {
+		return nil, err
+	}
+	return c.connStats.trackConn(conn), nil
 }
 
 // Get 发送GET请求
@@ -236,7 +331,12 @@ func (c *HTTPClient) GetStatus() *Status {
 		SuccessRequests: atomic.LoadInt64(&c.stats.successRequests),
 		FailedRequests:  atomic.LoadInt64(&c.stats.failedRequests),
 		RetryCount:      atomic.LoadInt64(&c.stats.retryCount),
+		Coalesced:       atomic.LoadInt64(&c.stats.coalesced),
 		LastError:       c.stats.lastError,
+		ErrorsByType:    make(map[string]int64, len(errorTypes)),
+	}
+	for _, errType := range errorTypes {
+		status.ErrorsByType[errType.String()] = c.stats.errorsByType[errType]
 	}
 
 	// 速率限制状态
@@ -254,10 +354,16 @@ func (c *HTTPClient) GetStatus() *Status {
 	}
 	c.rateLimit.mu.Unlock()
 
+	// 在途请求
+	status.InFlight = c.concurrency.status()
+
 	// IP管理器状态
-	if c.ipManager != nil {
-		status.IPManager = c.ipManager.GetStatus()
+	if ipManager := c.ipManager.Load(); ipManager != nil {
+		status.IPManager = ipManager.GetStatus()
 	}
+
+	// 连接池统计
+	status.ConnPool = c.connStats.snapshot()
 	return status
 }
 
@@ -267,11 +373,18 @@ func (c *HTTPClient) Close() error {
 	defer c.mu.Unlock()
 	c.running = false
 
-	// 停止IP管理器
-	if c.ipManager != nil {
-		c.ipManager.Stop()
-		log.Infof(log.ExchangeSys, "IP manager stopped for client '%s'", c.config.Name)
+	// 释放IP管理器（最后一个使用者释放时才会真正停止）
+	if c.removeIPListener != nil {
+		c.removeIPListener()
+		c.removeIPListener = nil
+	}
+	if ipManager := c.ipManager.Swap(nil); ipManager != nil {
+		ipmanager.Release(ipManager)
+		c.getLogger().Infof("IP manager released for client '%s'", c.config.Name)
+	}
+	if c.direct != nil {
+		c.direct.CloseIdleConnections()
 	}
-	log.Infof(log.ExchangeSys, "HTTP client '%s' closed", c.config.Name)
+	c.getLogger().Infof("HTTP client '%s' closed", c.config.Name)
 	return nil
 }
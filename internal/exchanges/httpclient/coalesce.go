@@ -0,0 +1,109 @@
+package httpclient
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"sync"
+	"sync/atomic"
+)
+
+// inflightCall 在途的合并请求
+type inflightCall struct {
+	done    chan struct{}
+	resp    *Response
+	err     error
+	waiters int                // 仍在等待结果的调用方数量
+	cancel  context.CancelFunc // 取消上游请求
+}
+
+// requestGroup 合并相同key的并发请求，同一时刻每个key只有一个上游请求
+type requestGroup struct {
+	mu    sync.Mutex
+	calls map[string]*inflightCall
+}
+
+// do 执行fn并返回结果；同一key已有请求在途时等待其结果，shared表示结果来自其他调用方发起的请求。
+// fn在与调用方ctx分离的context上运行（保留ctx中的值），每个调用方只按自己的ctx停止等待，
+// 发起方取消不会让其他等待方收到context.Canceled；所有等待方都放弃后才取消上游请求
+func (g *requestGroup) do(ctx context.Context, key string, fn func(ctx context.Context) (*Response, error)) (resp *Response, shared bool, err error) {
+	g.mu.Lock()
+	if g.calls == nil {
+		g.calls = make(map[string]*inflightCall)
+	}
+	call, shared := g.calls[key]
+	if !shared {
+		callCtx, cancel := context.WithCancel(context.WithoutCancel(ctx))
+		call = &inflightCall{done: make(chan struct{}), cancel: cancel}
+		g.calls[key] = call
+		go g.run(callCtx, key, call, fn)
+	}
+	call.waiters++
+	g.mu.Unlock()
+
+	select {
+	case <-call.done:
+		return call.resp, shared, call.err
+	case <-ctx.Done():
+		g.mu.Lock()
+		call.waiters--
+		if call.waiters == 0 {
+			// 无人等待时取消上游请求，之后的相同请求重新发起
+			call.cancel()
+			if g.calls[key] == call {
+				delete(g.calls, key)
+			}
+		}
+		g.mu.Unlock()
+		return nil, shared, ctx.Err()
+	}
+}
+
+// run 执行上游请求并通知所有等待方
+func (g *requestGroup) run(ctx context.Context, key string, call *inflightCall, fn func(ctx context.Context) (*Response, error)) {
+	defer call.cancel()
+	call.resp, call.err = fn(ctx)
+
+	g.mu.Lock()
+	if g.calls[key] == call {
+		delete(g.calls, key)
+	}
+	g.mu.Unlock()
+	close(call.done)
+}
+
+// shouldCoalesce 是否合并该请求：仅合并显式开启Coalesce且无请求体的GET请求
+func shouldCoalesce(req *Request) bool {
+	return req.Options != nil && req.Options.Coalesce && req.Method == http.MethodGet && req.Body == nil
+}
+
+// doCoalescedRequest 按method+URL合并并发的相同请求，所有调用方共享同一个上游响应，
+// 各自将响应体解析到自己的Result中。上游请求不随单个调用方的ctx取消，各调用方按自己的ctx等待
+func (c *HTTPClient) doCoalescedRequest(ctx context.Context, req *Request) (*Response, error) {
+	key := req.Method + " " + req.URL
+	resp, shared, err := c.inflight.do(ctx, key, func(ctx context.Context) (*Response, error) {
+		// 上游请求不解析结果，也不再次合并
+		upstream := *req
+		upstream.Result = nil
+		options := *req.Options
+		options.Coalesce = false
+		upstream.Options = &options
+		return c.DoRequest(ctx, &upstream)
+	})
+	if shared {
+		atomic.AddInt64(&c.stats.coalesced, 1)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	if req.Result != nil && len(resp.Body) > 0 {
+		if err := json.Unmarshal(resp.Body, req.Result); err != nil {
+			return nil, NewHTTPError(ErrorTypeHTTP, resp.StatusCode, "failed to unmarshal response", req.URL, resp.IP, false, err)
+		}
+	}
+
+	// 响应体和响应头在调用方之间共享，调用方不应修改
+	response := *resp
+	return &response, nil
+}
@@ -0,0 +1,59 @@
+package httpclient
+
+import (
+	"context"
+	"sync/atomic"
+)
+
+// concurrencyLimiter 客户端级别的在途请求数上限，同一客户端实例的所有调用方共享，
+// 无论多少任务同时调用，同时进行中的上游请求数都不超过上限。limit<=0时不限制，只统计在途数
+type concurrencyLimiter struct {
+	slots   chan struct{} // 信号量，不限制时为nil
+	active  atomic.Int64  // 进行中的请求数
+	waiting atomic.Int64  // 等待空闲名额的请求数
+}
+
+// newConcurrencyLimiter 创建在途请求限制器
+func newConcurrencyLimiter(limit int) *concurrencyLimiter {
+	l := &concurrencyLimiter{}
+	if limit > 0 {
+		l.slots = make(chan struct{}, limit)
+	}
+	return l
+}
+
+// acquire 占用一个名额，已达上限时等待，ctx结束时返回ctx.Err()
+func (l *concurrencyLimiter) acquire(ctx context.Context) error {
+	if l.slots != nil {
+		select {
+		case l.slots <- struct{}{}:
+		default:
+			l.waiting.Add(1)
+			defer l.waiting.Add(-1)
+			select {
+			case l.slots <- struct{}{}:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+		}
+	}
+	l.active.Add(1)
+	return nil
+}
+
+// release 释放acquire占用的名额
+func (l *concurrencyLimiter) release() {
+	l.active.Add(-1)
+	if l.slots != nil {
+		<-l.slots
+	}
+}
+
+// status 返回在途请求统计
+func (l *concurrencyLimiter) status() *InFlightStatus {
+	return &InFlightStatus{
+		Limit:   cap(l.slots),
+		Active:  l.active.Load(),
+		Waiting: l.waiting.Load(),
+	}
+}
@@ -1,31 +1,41 @@
 package httpclient
 
 import (
+	"fmt"
 	"time"
 
 	"github.com/mooyang-code/data-miner/internal/ipmanager"
 )
 
+// defaultMaxResponseBytes 默认的响应体最大长度，足以容纳全量exchangeInfo等大响应
+const defaultMaxResponseBytes = 64 << 20
+
 // DefaultConfig 返回默认配置
 func DefaultConfig(name string) *Config {
 	return &Config{
-		Name:      name,
-		UserAgent: "crypto-data-miner/1.0.0",
-		Timeout:   30 * time.Second,
-		DynamicIP: DefaultDynamicIPConfig(),
-		Retry:     DefaultRetryConfig(),
-		RateLimit: DefaultRateLimitConfig(),
-		Transport: DefaultTransportConfig(),
-		Debug:     false,
+		Name:             name,
+		UserAgent:        "crypto-data-miner/1.0.0",
+		Timeout:          30 * time.Second,
+		MaxResponseBytes: defaultMaxResponseBytes,
+		DynamicIP:        DefaultDynamicIPConfig(),
+		Retry:            DefaultRetryConfig(),
+		RateLimit:        DefaultRateLimitConfig(),
+		Transport:        DefaultTransportConfig(),
+		Debug:            false,
 	}
 }
 
 // DefaultDynamicIPConfig 返回默认动态IP配置
 func DefaultDynamicIPConfig() *DynamicIPConfig {
 	return &DynamicIPConfig{
-		Enabled:   false,
-		Hostname:  "",
-		IPManager: ipmanager.DefaultConfig(""),
+		Enabled:       false,
+		Hostname:      "",
+		IPManager:     ipmanager.DefaultConfig(""),
+		RoundRobin:    false,
+		TopK:          3,
+		LatencyBudget: 50 * time.Millisecond,
+		Prewarm:       false,
+		PrewarmPath:   "/",
 	}
 }
 
@@ -37,6 +47,7 @@ func DefaultRetryConfig() *RetryConfig {
 		InitialDelay:  time.Second,
 		MaxDelay:      8 * time.Second,
 		BackoffFactor: 2.0,
+		TotalTimeout:  60 * time.Second,
 	}
 }
 
@@ -59,6 +70,7 @@ func DefaultTransportConfig() *TransportConfig {
 		ResponseHeaderTimeout: 15 * time.Second,
 		DisableKeepAlives:     false,
 		DisableCompression:    false,
+		EnableHTTP2:           false,
 	}
 }
 
@@ -72,6 +84,7 @@ func DefaultRequestOptions() *RequestOptions {
 		ForceIPSwitch:   false,
 		SkipRateLimit:   false,
 		Verbose:         false,
+		Coalesce:        false,
 	}
 }
 
@@ -89,10 +102,27 @@ func (c *Config) Validate() error {
 		c.Timeout = 30 * time.Second
 	}
 
+	if c.MaxResponseBytes <= 0 {
+		c.MaxResponseBytes = defaultMaxResponseBytes
+	}
+
+	if c.MaxInFlight < 0 {
+		return fmt.Errorf("max_in_flight must not be negative: %d", c.MaxInFlight)
+	}
+
 	if c.DynamicIP == nil {
 		c.DynamicIP = DefaultDynamicIPConfig()
 	}
 
+	if c.ProxyURL != "" {
+		if _, err := ParseProxyURL(c.ProxyURL); err != nil {
+			return err
+		}
+	}
+	if err := c.TLS.Validate(); err != nil {
+		return err
+	}
+
 	if c.Retry == nil {
 		c.Retry = DefaultRetryConfig()
 	}
@@ -105,6 +135,14 @@ func (c *Config) Validate() error {
 		c.Transport = DefaultTransportConfig()
 	}
 
+	// 验证动态IP配置
+	if c.DynamicIP.TopK < 1 {
+		c.DynamicIP.TopK = 3
+	}
+	if c.DynamicIP.PrewarmPath == "" {
+		c.DynamicIP.PrewarmPath = "/"
+	}
+
 	// 验证重试配置
 	if c.Retry.MaxAttempts < 1 {
 		c.Retry.MaxAttempts = 3
@@ -118,6 +156,14 @@ func (c *Config) Validate() error {
 	if c.Retry.BackoffFactor <= 0 {
 		c.Retry.BackoffFactor = 2.0
 	}
+	if c.Retry.TotalTimeout < 0 {
+		c.Retry.TotalTimeout = 0
+	}
+	if c.Retry.Jitter < 0 {
+		c.Retry.Jitter = 0
+	} else if c.Retry.Jitter > 1 {
+		c.Retry.Jitter = 1
+	}
 
 	// 验证速率限制配置
 	if c.RateLimit.RequestsPerMinute < 1 {
@@ -162,6 +208,24 @@ func (c *Config) Merge(other *Config) *Config {
 	if other.Timeout > 0 {
 		result.Timeout = other.Timeout
 	}
+	if other.ProxyURL != "" {
+		result.ProxyURL = other.ProxyURL
+	}
+	if other.TLS != nil {
+		result.TLS = other.TLS
+	}
+	if other.LogBodies {
+		result.LogBodies = true
+	}
+	if other.MaxInFlight > 0 {
+		result.MaxInFlight = other.MaxInFlight
+	}
+	if other.LogBodyLimit > 0 {
+		result.LogBodyLimit = other.LogBodyLimit
+	}
+	if len(other.RedactFields) > 0 {
+		result.RedactFields = other.RedactFields
+	}
 
 	// 合并动态IP配置
 	if other.DynamicIP != nil {
@@ -175,6 +239,17 @@ func (c *Config) Merge(other *Config) *Config {
 		if other.DynamicIP.IPManager != nil {
 			result.DynamicIP.IPManager = other.DynamicIP.IPManager
 		}
+		result.DynamicIP.RoundRobin = other.DynamicIP.RoundRobin
+		if other.DynamicIP.TopK > 0 {
+			result.DynamicIP.TopK = other.DynamicIP.TopK
+		}
+		if other.DynamicIP.LatencyBudget > 0 {
+			result.DynamicIP.LatencyBudget = other.DynamicIP.LatencyBudget
+		}
+		result.DynamicIP.Prewarm = other.DynamicIP.Prewarm
+		if other.DynamicIP.PrewarmPath != "" {
+			result.DynamicIP.PrewarmPath = other.DynamicIP.PrewarmPath
+		}
 	}
 
 	// 合并重试配置
@@ -195,6 +270,12 @@ func (c *Config) Merge(other *Config) *Config {
 		if other.Retry.BackoffFactor > 0 {
 			result.Retry.BackoffFactor = other.Retry.BackoffFactor
 		}
+		if other.Retry.Jitter > 0 {
+			result.Retry.Jitter = other.Retry.Jitter
+		}
+		if other.Retry.ClassifyFunc != nil {
+			result.Retry.ClassifyFunc = other.Retry.ClassifyFunc
+		}
 	}
 
 	// 合并速率限制配置
@@ -233,6 +314,7 @@ func (c *Config) Merge(other *Config) *Config {
 		}
 		result.Transport.DisableKeepAlives = other.Transport.DisableKeepAlives
 		result.Transport.DisableCompression = other.Transport.DisableCompression
+		result.Transport.EnableHTTP2 = other.Transport.EnableHTTP2
 	}
 	return &result
 }
@@ -0,0 +1,147 @@
+package httpclient
+
+import (
+	"context"
+	"crypto/tls"
+	"net"
+	"net/http/httptrace"
+	"sync"
+	"sync/atomic"
+)
+
+// connPoolStats 连接池统计（新建连接在拨号时统计，连接复用通过httptrace统计）
+type connPoolStats struct {
+	created    int64 // 新建连接数
+	closed     int64 // 关闭连接数（包括空闲超时关闭）
+	reused     int64 // 复用连接数
+	reusedIdle int64 // 复用的空闲连接数
+	http2      int64 // 协商为HTTP/2的TLS连接数
+	http1      int64 // 使用HTTP/1.1的TLS连接数
+
+	mu    sync.Mutex
+	perIP map[string]*IPConnStats
+}
+
+// newConnPoolStats 创建连接池统计
+func newConnPoolStats() *connPoolStats {
+	return &connPoolStats{perIP: make(map[string]*IPConnStats)}
+}
+
+// ipStats 获取指定IP的统计（调用时需要持有锁）
+func (s *connPoolStats) ipStats(ip string) *IPConnStats {
+	stats, ok := s.perIP[ip]
+	if !ok {
+		stats = &IPConnStats{}
+		s.perIP[ip] = stats
+	}
+	return stats
+}
+
+// recordCreated 记录新建连接
+func (s *connPoolStats) recordCreated(ip string) {
+	atomic.AddInt64(&s.created, 1)
+	s.mu.Lock()
+	s.ipStats(ip).Created++
+	s.mu.Unlock()
+}
+
+// recordClosed 记录连接关闭
+func (s *connPoolStats) recordClosed(ip string) {
+	atomic.AddInt64(&s.closed, 1)
+	s.mu.Lock()
+	s.ipStats(ip).Closed++
+	s.mu.Unlock()
+}
+
+// recordGotConn 记录请求获取到的连接
+func (s *connPoolStats) recordGotConn(info httptrace.GotConnInfo) {
+	if !info.Reused {
+		return
+	}
+	atomic.AddInt64(&s.reused, 1)
+	if info.WasIdle {
+		atomic.AddInt64(&s.reusedIdle, 1)
+	}
+
+	ip := remoteIP(info.Conn.RemoteAddr())
+	s.mu.Lock()
+	s.ipStats(ip).Reused++
+	s.mu.Unlock()
+}
+
+// recordTLSHandshake 记录新建TLS连接实际协商的协议
+func (s *connPoolStats) recordTLSHandshake(state tls.ConnectionState, err error) {
+	if err != nil {
+		return
+	}
+	if state.NegotiatedProtocol == "h2" {
+		atomic.AddInt64(&s.http2, 1)
+	} else {
+		atomic.AddInt64(&s.http1, 1)
+	}
+}
+
+// withTrace 为请求context添加连接追踪
+func (s *connPoolStats) withTrace(ctx context.Context) context.Context {
+	return httptrace.WithClientTrace(ctx, &httptrace.ClientTrace{
+		GotConn:          s.recordGotConn,
+		TLSHandshakeDone: s.recordTLSHandshake,
+	})
+}
+
+// trackConn 包装新建的连接，以便统计连接关闭
+func (s *connPoolStats) trackConn(conn net.Conn) net.Conn {
+	ip := remoteIP(conn.RemoteAddr())
+	s.recordCreated(ip)
+	return &trackedConn{Conn: conn, stats: s, ip: ip}
+}
+
+// snapshot 获取连接池统计快照
+func (s *connPoolStats) snapshot() *ConnPoolStatus {
+	status := &ConnPoolStatus{
+		ConnsCreated:    atomic.LoadInt64(&s.created),
+		ConnsClosed:     atomic.LoadInt64(&s.closed),
+		ConnsReused:     atomic.LoadInt64(&s.reused),
+		ConnsReusedIdle: atomic.LoadInt64(&s.reusedIdle),
+		ConnsHTTP2:      atomic.LoadInt64(&s.http2),
+		ConnsHTTP1:      atomic.LoadInt64(&s.http1),
+	}
+	status.ConnsOpen = status.ConnsCreated - status.ConnsClosed
+	if total := status.ConnsCreated + status.ConnsReused; total > 0 {
+		status.ReuseRatio = float64(status.ConnsReused) / float64(total)
+	}
+
+	s.mu.Lock()
+	status.PerIP = make(map[string]IPConnStats, len(s.perIP))
+	for ip, stats := range s.perIP {
+		status.PerIP[ip] = *stats
+	}
+	s.mu.Unlock()
+	return status
+}
+
+// trackedConn 在关闭时更新统计的连接
+type trackedConn struct {
+	net.Conn
+	stats *connPoolStats
+	ip    string
+	once  sync.Once
+}
+
+// Close 关闭连接并记录
+func (c *trackedConn) Close() error {
+	c.once.Do(func() { c.stats.recordClosed(c.ip) })
+	return c.Conn.Close()
+}
+
+// remoteIP 从远端地址中提取IP
+func remoteIP(addr net.Addr) string {
+	if addr == nil {
+		return "unknown"
+	}
+	host, _, err := net.SplitHostPort(addr.String())
+	if err != nil {
+		return addr.String()
+	}
+	return host
+}
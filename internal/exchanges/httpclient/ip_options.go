@@ -0,0 +1,64 @@
+package httpclient
+
+import (
+	"context"
+	"net/http"
+)
+
+// directDialKey 标记请求直连域名、不使用动态IP的context键
+type directDialKey struct{}
+
+// withDirectDial 标记请求直连域名（RequestOptions.EnableDynamicIP为false）
+func withDirectDial(ctx context.Context) context.Context {
+	return context.WithValue(ctx, directDialKey{}, true)
+}
+
+// isDirectDial 请求是否标记了直连域名
+func isDirectDial(ctx context.Context) bool {
+	direct, _ := ctx.Value(directDialKey{}).(bool)
+	return direct
+}
+
+// dynamicIPEnabled 客户端是否启用了动态IP
+func (c *HTTPClient) dynamicIPEnabled() bool {
+	return c.ipManager.Load() != nil && c.config.DynamicIP.Enabled
+}
+
+// directClient 返回直连域名使用的HTTP客户端，使用独立的连接池，
+// 避免复用通过动态IP建立的连接（连接池按域名索引，无法区分连接的实际IP）
+func (c *HTTPClient) directClient() *http.Client {
+	c.directOnce.Do(func() {
+		c.direct = &http.Client{
+			Transport: newLoggingTransport(c.transport.Clone(), c.config),
+			Timeout:   c.config.Timeout,
+		}
+	})
+	return c.direct
+}
+
+// freshConnClient 返回不复用连接的一次性HTTP客户端，用于强制切换IP后通过新IP建立连接
+// 返回的关闭函数释放其连接
+func (c *HTTPClient) freshConnClient() (*http.Client, func()) {
+	transport := c.transport.Clone()
+	transport.DisableKeepAlives = true
+	client := &http.Client{
+		Transport: newLoggingTransport(transport, c.config),
+		Timeout:   c.config.Timeout,
+	}
+	return client, transport.CloseIdleConnections
+}
+
+// forceIPSwitch 处理RequestOptions.ForceIPSwitch：切换到下一个IP并返回，切换失败时返回空字符串
+func (c *HTTPClient) forceIPSwitch() string {
+	ipManager := c.ipManager.Load()
+	if ipManager == nil {
+		return ""
+	}
+	ip, err := ipManager.GetNextIP()
+	if err != nil {
+		c.getLogger().Warnf("Client '%s': Failed to force IP switch: %v", c.config.Name, err)
+		return ""
+	}
+	c.getLogger().Infof("Client '%s': Forced switch to IP %s", c.config.Name, ip)
+	return ip
+}
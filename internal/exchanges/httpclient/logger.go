@@ -0,0 +1,41 @@
+package httpclient
+
+import "github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+
+// Logger HTTP客户端使用的日志接口，可通过Config.Logger注入自定义实现
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// subLogger 默认日志实现，输出到cryptotrader的log包
+type subLogger struct {
+	sl *log.SubLogger
+}
+
+func (l subLogger) Debugf(format string, args ...any) { log.Debugf(l.sl, format, args...) }
+func (l subLogger) Infof(format string, args ...any)  { log.Infof(l.sl, format, args...) }
+func (l subLogger) Warnf(format string, args ...any)  { log.Warnf(l.sl, format, args...) }
+func (l subLogger) Errorf(format string, args ...any) { log.Errorf(l.sl, format, args...) }
+
+// defaultLogger 未注入日志实现时使用的默认日志
+var defaultLogger Logger = subLogger{sl: log.ExchangeSys}
+
+// orDefault 返回l，为nil时回退到defaultLogger
+func orDefault(l Logger) Logger {
+	if l == nil {
+		return defaultLogger
+	}
+	return l
+}
+
+// getLogger 返回客户端的日志实现，未经New构造时回退到defaultLogger
+func (c *HTTPClient) getLogger() Logger { return orDefault(c.logger) }
+
+// getLogger 返回重试处理器的日志实现，未设置时回退到defaultLogger
+func (r *RetryHandler) getLogger() Logger { return orDefault(r.logger) }
+
+// getLogger 返回日志中间件的日志实现，未设置时回退到defaultLogger
+func (t *loggingTransport) getLogger() Logger { return orDefault(t.logger) }
@@ -0,0 +1,221 @@
+package httpclient
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+const (
+	defaultLogBodyLimit = 2048         // 默认日志中请求/响应体的最大长度
+	redactedValue       = "[REDACTED]" // 脱敏后的字段值
+)
+
+// defaultRedactFields 默认需要脱敏的字段（查询参数、请求头、JSON字段，不区分大小写）
+var defaultRedactFields = []string{"signature", "apiKey", "api_key", "api_secret", "secretKey", "X-MBX-APIKEY"}
+
+// verboseKey 标记单个请求需要详细日志的context键
+type verboseKey struct{}
+
+// withVerbose 标记请求需要详细日志（RequestOptions.Verbose），客户端未开启Debug时也记录该请求
+func withVerbose(ctx context.Context) context.Context {
+	return context.WithValue(ctx, verboseKey{}, true)
+}
+
+// isVerbose 请求是否标记了需要详细日志
+func isVerbose(ctx context.Context) bool {
+	verbose, _ := ctx.Value(verboseKey{}).(bool)
+	return verbose
+}
+
+// detailLogf 返回记录详细日志的函数：标记了详细日志的请求按Info级别输出，
+// 避免默认日志级别下RequestOptions.Verbose的输出被过滤；客户端Debug开启时的全量日志仍按Debug级别输出
+func detailLogf(ctx context.Context, logger Logger) func(format string, args ...any) {
+	if isVerbose(ctx) {
+		return logger.Infof
+	}
+	return logger.Debugf
+}
+
+// loggingTransport 记录完整请求/响应的http.RoundTripper中间件，敏感字段会被脱敏
+type loggingTransport struct {
+	next       http.RoundTripper
+	name       string
+	bodyLimit  int
+	redactKeys map[string]struct{}
+	logger     Logger
+	always     bool  // 记录所有请求（Debug且LogBodies），否则只记录标记了详细日志的请求
+	maxBody    int64 // 读取响应体用于记录的最大长度，超出部分留给客户端按MaxResponseBytes处理
+}
+
+// newLoggingTransport 创建日志中间件
+func newLoggingTransport(next http.RoundTripper, config *Config) *loggingTransport {
+	fields := config.RedactFields
+	if len(fields) == 0 {
+		fields = defaultRedactFields
+	}
+	redactKeys := make(map[string]struct{}, len(fields))
+	for _, field := range fields {
+		redactKeys[strings.ToLower(field)] = struct{}{}
+	}
+
+	logger := config.Logger
+	if logger == nil {
+		logger = defaultLogger
+	}
+
+	maxBody := config.MaxResponseBytes
+	if maxBody <= 0 {
+		maxBody = defaultMaxResponseBytes
+	}
+
+	bodyLimit := config.LogBodyLimit
+	if bodyLimit <= 0 {
+		bodyLimit = defaultLogBodyLimit
+	}
+
+	return &loggingTransport{
+		next:       next,
+		name:       config.Name,
+		bodyLimit:  bodyLimit,
+		redactKeys: redactKeys,
+		logger:     logger,
+		always:     config.Debug && config.LogBodies,
+		maxBody:    maxBody,
+	}
+}
+
+// RoundTrip 发送请求并记录请求和响应
+func (t *loggingTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	if !t.always && !isVerbose(req.Context()) {
+		return t.next.RoundTrip(req)
+	}
+
+	logf := detailLogf(req.Context(), t.getLogger())
+
+	var reqBody []byte
+	if req.Body != nil && req.GetBody != nil {
+		if body, err := req.GetBody(); err == nil {
+			reqBody, _ = io.ReadAll(body)
+			body.Close()
+		}
+	}
+
+	logf("Client '%s': --> %s %s headers=%v body=%s",
+		t.name, req.Method, t.redactURL(req.URL), t.redactHeaders(req.Header), t.formatBody(reqBody))
+
+	start := time.Now()
+	resp, err := t.next.RoundTrip(req)
+	duration := time.Since(start)
+	if err != nil {
+		logf("Client '%s': <-- %s %s failed after %v: %v",
+			t.name, req.Method, t.redactURL(req.URL), duration, err)
+		return nil, err
+	}
+
+	// 读取响应体用于记录，再放回响应中供调用方读取；超过maxBody的部分不读入内存，
+	// 与已读取的部分拼接后由客户端读取时按MaxResponseBytes拒绝
+	respBody, readErr := io.ReadAll(io.LimitReader(resp.Body, t.maxBody+1))
+	resp.Body = struct {
+		io.Reader
+		io.Closer
+	}{io.MultiReader(bytes.NewReader(respBody), resp.Body), resp.Body}
+	if readErr != nil {
+		resp.Body.Close()
+		logf("Client '%s': <-- %d %s %s (%v) failed to read body: %v",
+			t.name, resp.StatusCode, req.Method, t.redactURL(req.URL), duration, readErr)
+		return nil, readErr
+	}
+
+	logf("Client '%s': <-- %d %s %s (%v) body=%s",
+		t.name, resp.StatusCode, req.Method, t.redactURL(req.URL), duration, t.formatBody(respBody))
+	return resp, nil
+}
+
+// isRedacted 判断字段是否需要脱敏
+func (t *loggingTransport) isRedacted(key string) bool {
+	_, ok := t.redactKeys[strings.ToLower(key)]
+	return ok
+}
+
+// redactURL 返回查询参数脱敏后的URL
+func (t *loggingTransport) redactURL(u *url.URL) string {
+	if u.RawQuery == "" {
+		return u.String()
+	}
+
+	query := u.Query()
+	for key := range query {
+		if t.isRedacted(key) {
+			query.Set(key, redactedValue)
+		}
+	}
+	redacted := *u
+	redacted.RawQuery = query.Encode()
+	return redacted.String()
+}
+
+// redactHeaders 返回脱敏后的请求头
+func (t *loggingTransport) redactHeaders(header http.Header) map[string]string {
+	headers := make(map[string]string, len(header))
+	for key, values := range header {
+		if t.isRedacted(key) {
+			headers[key] = redactedValue
+		} else {
+			headers[key] = strings.Join(values, ",")
+		}
+	}
+	return headers
+}
+
+// formatBody 脱敏并截断请求/响应体
+func (t *loggingTransport) formatBody(body []byte) string {
+	if len(body) == 0 {
+		return "<empty>"
+	}
+
+	text := string(body)
+	var parsed interface{}
+	if err := json.Unmarshal(body, &parsed); err == nil {
+		if redacted, err := json.Marshal(t.redactJSON(parsed)); err == nil {
+			text = string(redacted)
+		}
+	} else if values, err := url.ParseQuery(text); err == nil && strings.Contains(text, "=") {
+		// 表单格式的请求体
+		for key := range values {
+			if t.isRedacted(key) {
+				values.Set(key, redactedValue)
+			}
+		}
+		text = values.Encode()
+	}
+
+	if len(text) > t.bodyLimit {
+		return text[:t.bodyLimit] + "...(truncated)"
+	}
+	return text
+}
+
+// redactJSON 递归脱敏JSON中的敏感字段
+func (t *loggingTransport) redactJSON(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if t.isRedacted(key) {
+				v[key] = redactedValue
+			} else {
+				v[key] = t.redactJSON(item)
+			}
+		}
+	case []interface{}:
+		for i, item := range v {
+			v[i] = t.redactJSON(item)
+		}
+	}
+	return value
+}
@@ -0,0 +1,26 @@
+package httpclient
+
+import (
+	"github.com/prometheus/client_golang/prometheus"
+
+	"github.com/mooyang-code/data-miner/internal/metrics"
+)
+
+// requestErrors 按客户端和错误类型统计失败的尝试，与Status.ErrorsByType一致
+var requestErrors = prometheus.NewCounterVec(prometheus.CounterOpts{
+	Namespace: metrics.Namespace,
+	Subsystem: "http_client",
+	Name:      "errors_total",
+	Help:      "Failed HTTP attempts by client and error type, including attempts that were retried successfully.",
+}, []string{"client", "error_type"})
+
+func init() {
+	metrics.Registry.MustRegister(requestErrors)
+}
+
+// initErrorMetrics 为客户端的每种错误类型创建计数，未发生的错误类型也以0输出
+func initErrorMetrics(name string) {
+	for _, errType := range errorTypes {
+		requestErrors.WithLabelValues(name, errType.String())
+	}
+}
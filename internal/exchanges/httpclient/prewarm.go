@@ -0,0 +1,101 @@
+package httpclient
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net/http"
+	"sync"
+	"time"
+)
+
+// preferredIPKey 用于在context中指定拨号时使用的IP（预热连接时使用）
+type preferredIPKey struct{}
+
+// withPreferredIP 返回指定拨号IP的context
+func withPreferredIP(ctx context.Context, ip string) context.Context {
+	return context.WithValue(ctx, preferredIPKey{}, ip)
+}
+
+// preferredIPFromContext 从context中获取指定的拨号IP
+func preferredIPFromContext(ctx context.Context) (string, bool) {
+	ip, ok := ctx.Value(preferredIPKey{}).(string)
+	return ip, ok && ip != ""
+}
+
+// prewarmTargets 选择需要预热的IP（IP列表已按延迟排序，取前N个）
+func (c *HTTPClient) prewarmTargets(ips []string) []string {
+	count := 1
+	if c.config.DynamicIP.RoundRobin {
+		count = c.config.DynamicIP.TopK
+	}
+	// 预热的连接数不能超过每个主机的最大空闲连接数，否则多余的连接会被直接关闭
+	if count > c.config.Transport.MaxIdleConnsPerHost {
+		count = c.config.Transport.MaxIdleConnsPerHost
+	}
+	if count > len(ips) {
+		count = len(ips)
+	}
+	return ips[:count]
+}
+
+// prewarmConnections 为延迟最低的几个IP预先建立TLS连接并放入空闲连接池，
+// 避免第一个真实请求承担完整的TLS握手开销
+func (c *HTTPClient) prewarmConnections(ips []string) {
+	c.mu.RLock()
+	running := c.running
+	c.mu.RUnlock()
+	if !running || !c.config.DynamicIP.Prewarm {
+		return
+	}
+
+	targets := c.prewarmTargets(ips)
+	if len(targets) == 0 {
+		return
+	}
+
+	url := fmt.Sprintf("https://%s%s", c.config.DynamicIP.Hostname, c.config.DynamicIP.PrewarmPath)
+	timeout := c.config.Transport.TLSHandshakeTimeout + c.config.Transport.ResponseHeaderTimeout
+
+	// 所有请求都拿到响应后才释放连接，确保每个IP各自新建一条连接而不是复用同一条
+	var responded sync.WaitGroup
+	var finished sync.WaitGroup
+	responded.Add(len(targets))
+	finished.Add(len(targets))
+
+	for _, ip := range targets {
+		go func(ip string) {
+			defer finished.Done()
+
+			ctx, cancel := context.WithTimeout(withPreferredIP(context.Background(), ip), timeout)
+			defer cancel()
+
+			start := time.Now()
+			resp, err := c.sendPrewarmRequest(ctx, url)
+			responded.Done()
+			if err != nil {
+				c.getLogger().Warnf("Client '%s': Failed to prewarm connection to %s: %v", c.config.Name, ip, err)
+				return
+			}
+
+			responded.Wait()
+			// 读完并关闭响应体，使连接回到空闲连接池
+			_, _ = io.Copy(io.Discard, resp.Body)
+			resp.Body.Close()
+			c.getLogger().Debugf("Client '%s': Prewarmed connection to %s in %v", c.config.Name, ip, time.Since(start))
+		}(ip)
+	}
+	finished.Wait()
+
+	c.getLogger().Infof("Client '%s': Prewarmed %d connection(s) for %s", c.config.Name, len(targets), c.config.DynamicIP.Hostname)
+}
+
+// sendPrewarmRequest 发送预热请求（不计入统计和速率限制）
+func (c *HTTPClient) sendPrewarmRequest(ctx context.Context, url string) (*http.Response, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", c.config.UserAgent)
+	return c.httpClient.Do(req)
+}
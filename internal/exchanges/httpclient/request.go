@@ -4,13 +4,14 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"net/http"
 	"sync/atomic"
 	"time"
 
-	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+	"github.com/mooyang-code/data-miner/internal/ipmanager"
 )
 
 // DoRequest 发送自定义请求
@@ -19,9 +20,15 @@ func (c *HTTPClient) DoRequest(ctx context.Context, req *Request) (*Response, er
 		return nil, fmt.Errorf("client '%s' is not running", c.config.Name)
 	}
 
+	// 合并并发的相同请求，只有实际发起上游请求的调用方计入速率限制和统计
+	if shouldCoalesce(req) {
+		return c.doCoalescedRequest(ctx, req)
+	}
+
 	// 检查速率限制
 	if req.Options == nil || !req.Options.SkipRateLimit {
 		if err := c.checkRateLimit(); err != nil {
+			c.recordErrorType(err)
 			return nil, err
 		}
 	}
@@ -32,12 +39,50 @@ func (c *HTTPClient) DoRequest(ctx context.Context, req *Request) (*Response, er
 	c.stats.lastRequest = time.Now()
 	c.mu.Unlock()
 
+	// 请求级别的动态IP选项：EnableDynamicIP为false时直连域名；
+	// ForceIPSwitch时先切换到下一个IP，第一次尝试通过新建的连接使用该IP，之后的重试按正常流程切换IP
+	client := c.httpClient
+	var forcedIP string
+	if req.Options != nil && c.dynamicIPEnabled() {
+		if !req.Options.EnableDynamicIP {
+			ctx = withDirectDial(ctx)
+			client = c.directClient()
+		} else if req.Options.ForceIPSwitch {
+			forcedIP = c.forceIPSwitch()
+		}
+	}
+
+	// 重试预算：所有尝试和重试等待共用一个截止时间，避免单个请求耗时远超调用方的超时
+	parentCtx := ctx
+	budget := c.config.Retry.TotalTimeout
+	if budget > 0 && c.config.Retry.Enabled {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, budget)
+		defer cancel()
+	}
+
 	var response *Response
+	var lastErr error
 
 	// 执行带重试的请求
 	err := c.retryHandler.Execute(ctx, func() error {
-		resp, err := c.doHTTPRequest(ctx, req)
+		attemptCtx, attemptClient := ctx, client
+		if forcedIP != "" {
+			fresh, closeFresh := c.freshConnClient()
+			defer closeFresh()
+			attemptCtx, attemptClient = withPreferredIP(ctx, forcedIP), fresh
+			forcedIP = ""
+		}
+		// 每次尝试占用一个在途名额，重试等待期间不占用
+		if err := c.concurrency.acquire(attemptCtx); err != nil {
+			lastErr = fmt.Errorf("client '%s': waiting for in-flight slot: %w", c.config.Name, err)
+			return lastErr
+		}
+		resp, err := c.doHTTPRequest(attemptCtx, attemptClient, req)
+		c.concurrency.release()
 		if err != nil {
+			lastErr = err
+			c.recordErrorType(err)
 			return err
 		}
 		response = resp
@@ -45,16 +90,27 @@ func (c *HTTPClient) DoRequest(ctx context.Context, req *Request) (*Response, er
 	}, func(attempt int, err error) {
 		// 重试回调：切换IP
 		atomic.AddInt64(&c.stats.retryCount, 1)
-		if c.ipManager != nil && c.config.DynamicIP.Enabled {
-			nextIP, switchErr := c.ipManager.GetNextIP()
+		if ipManager := c.ipManager.Load(); ipManager != nil && c.config.DynamicIP.Enabled {
+			nextIP, switchErr := ipManager.GetNextIP()
 			if switchErr != nil {
-				log.Errorf(log.ExchangeSys, "Client '%s': Failed to switch to next IP: %v", c.config.Name, switchErr)
+				c.getLogger().Errorf("Client '%s': Failed to switch to next IP: %v", c.config.Name, switchErr)
+				if errors.Is(switchErr, ipmanager.ErrNoAvailableIPs) {
+					ipManager.ForceUpdate()
+				}
 			} else {
-				log.Infof(log.ExchangeSys, "Client '%s': Switching to next IP: %s", c.config.Name, nextIP)
+				c.getLogger().Infof("Client '%s': Switching to next IP: %s", c.config.Name, nextIP)
 			}
 		}
 	})
 
+	// 预算耗尽（而不是调用方取消）时返回ErrRetryBudgetExhausted，并保留最后一次尝试的错误
+	if err != nil && ctx.Err() != nil && parentCtx.Err() == nil {
+		if lastErr == nil {
+			lastErr = err
+		}
+		err = fmt.Errorf("client '%s': %w after %v: %w", c.config.Name, ErrRetryBudgetExhausted, budget, lastErr)
+	}
+
 	if err != nil {
 		atomic.AddInt64(&c.stats.failedRequests, 1)
 		c.mu.Lock()
@@ -67,10 +123,35 @@ func (c *HTTPClient) DoRequest(ctx context.Context, req *Request) (*Response, er
 	return response, nil
 }
 
-// doHTTPRequest 执行实际的HTTP请求
-func (c *HTTPClient) doHTTPRequest(ctx context.Context, req *Request) (*Response, error) {
+// recordErrorType 按错误类型统计一次失败，非HTTPError的错误按ClassifyError分类
+func (c *HTTPClient) recordErrorType(err error) {
+	var httpErr *HTTPError
+	if !errors.As(err, &httpErr) {
+		httpErr = ClassifyError(err)
+	}
+	errType := httpErr.Type
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if c.stats.errorsByType == nil {
+		c.stats.errorsByType = make(map[ErrorType]int64)
+	}
+	c.stats.errorsByType[errType]++
+	requestErrors.WithLabelValues(c.config.Name, errType.String()).Inc()
+}
+
+// doHTTPRequest 使用指定的HTTP客户端执行实际的HTTP请求
+func (c *HTTPClient) doHTTPRequest(ctx context.Context, client *http.Client, req *Request) (*Response, error) {
 	startTime := time.Now()
 
+	// 单个请求的详细日志，客户端未开启Debug时也生效
+	verbose := c.config.Debug
+	if req.Options != nil && req.Options.Verbose {
+		verbose = true
+		ctx = withVerbose(ctx)
+	}
+	logf := detailLogf(ctx, c.getLogger())
+
 	// 准备请求体
 	var bodyReader io.Reader
 	if req.Body != nil {
@@ -82,7 +163,7 @@ func (c *HTTPClient) doHTTPRequest(ctx context.Context, req *Request) (*Response
 	}
 
 	// 创建HTTP请求
-	httpReq, err := http.NewRequestWithContext(ctx, req.Method, req.URL, bodyReader)
+	httpReq, err := http.NewRequestWithContext(c.connStats.withTrace(ctx), req.Method, req.URL, bodyReader)
 	if err != nil {
 		return nil, NewHTTPError(ErrorTypeHTTP, 0, "failed to create request", req.URL, "", false, err)
 	}
@@ -91,15 +172,15 @@ func (c *HTTPClient) doHTTPRequest(ctx context.Context, req *Request) (*Response
 	c.setRequestHeaders(httpReq, req)
 
 	// 获取当前使用的IP（用于日志）
-	currentIP := c.getCurrentIP()
+	currentIP := c.getCurrentIP(ctx)
 
-	if c.config.Debug {
-		log.Debugf(log.ExchangeSys, "Client '%s': Making %s request to %s with IP %s",
+	if verbose {
+		logf("Client '%s': Making %s request to %s with IP %s",
 			c.config.Name, req.Method, req.URL, currentIP)
 	}
 
 	// 发送请求
-	httpResp, err := c.httpClient.Do(httpReq)
+	httpResp, err := client.Do(httpReq)
 	if err != nil {
 		classifiedErr := ClassifyError(err)
 		classifiedErr.URL = req.URL
@@ -110,13 +191,17 @@ func (c *HTTPClient) doHTTPRequest(ctx context.Context, req *Request) (*Response
 
 	duration := time.Since(startTime)
 
-	if c.config.Debug {
-		log.Debugf(log.ExchangeSys, "Client '%s': Response status %d, duration %v",
+	if verbose {
+		logf("Client '%s': Response status %d, duration %v",
 			c.config.Name, httpResp.StatusCode, duration)
 	}
 
-	// 读取响应体
-	respBody, err := io.ReadAll(httpResp.Body)
+	// 读取响应体，超过MaxResponseBytes时不再继续读取
+	respBody, err := readLimited(httpResp.Body, c.config.MaxResponseBytes)
+	if errors.Is(err, ErrResponseTooLarge) {
+		return nil, NewHTTPError(ErrorTypeResponseTooLarge, httpResp.StatusCode,
+			fmt.Sprintf("response body exceeds %d bytes", c.config.MaxResponseBytes), req.URL, currentIP, false, err)
+	}
 	if err != nil {
 		return nil, NewHTTPError(ErrorTypeNetwork, httpResp.StatusCode, "failed to read response body", req.URL, currentIP, true, err)
 	}
@@ -135,6 +220,10 @@ func (c *HTTPClient) doHTTPRequest(ctx context.Context, req *Request) (*Response
 		}
 	}
 
+	if ipManager := c.ipManager.Load(); ipManager != nil && c.config.DynamicIP.Enabled {
+		ipManager.ReportSuccess(currentIP)
+	}
+
 	// 构建响应对象
 	response := &Response{
 		StatusCode: httpResp.StatusCode,
@@ -180,10 +269,28 @@ func (c *HTTPClient) setRequestHeaders(httpReq *http.Request, req *Request) {
 	}
 }
 
-// getCurrentIP 获取当前使用的IP地址
-func (c *HTTPClient) getCurrentIP() string {
-	if c.ipManager != nil && c.config.DynamicIP.Enabled && c.ipManager.IsRunning() {
-		if ip, err := c.ipManager.GetCurrentIP(); err == nil {
+// readLimited 读取最多limit字节，超出时返回ErrResponseTooLarge
+func readLimited(r io.Reader, limit int64) ([]byte, error) {
+	body, err := io.ReadAll(io.LimitReader(r, limit+1))
+	if err != nil {
+		return nil, err
+	}
+	if int64(len(body)) > limit {
+		return nil, ErrResponseTooLarge
+	}
+	return body, nil
+}
+
+// getCurrentIP 获取请求使用的IP地址：优先使用context中指定的IP，直连域名的请求返回"direct"
+func (c *HTTPClient) getCurrentIP(ctx context.Context) string {
+	if ip, ok := preferredIPFromContext(ctx); ok {
+		return ip
+	}
+	if isDirectDial(ctx) {
+		return "direct"
+	}
+	if ipManager := c.ipManager.Load(); ipManager != nil && c.config.DynamicIP.Enabled && ipManager.IsRunning() {
+		if ip, err := ipManager.GetCurrentIP(); err == nil {
 			return ip
 		}
 	}
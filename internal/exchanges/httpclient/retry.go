@@ -3,15 +3,18 @@ package httpclient
 import (
 	"context"
 	"github.com/avast/retry-go/v4"
-	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+	"math"
+	"math/rand"
 	"net"
 	"strings"
+	"time"
 )
 
 // RetryHandler 重试处理器
 type RetryHandler struct {
 	config *RetryConfig
 	name   string
+	logger Logger
 }
 
 // NewRetryHandler 创建重试处理器
@@ -22,6 +25,7 @@ func NewRetryHandler(config *RetryConfig, name string) *RetryHandler {
 	return &RetryHandler{
 		config: config,
 		name:   name,
+		logger: defaultLogger,
 	}
 }
 
@@ -36,18 +40,19 @@ func (r *RetryHandler) Execute(ctx context.Context, operation func() error, onRe
 		retry.Context(ctx),
 		retry.RetryIf(func(err error) bool {
 			if !r.isRetryableError(err) {
-				log.Warnf(log.ExchangeSys, "%s: Non-retryable error: %v", r.name, err)
+				r.getLogger().Warnf("%s: Non-retryable error: %v", r.name, err)
 				return false
 			}
 			return true
 		}),
 		retry.Attempts(uint(r.config.MaxAttempts)),
 		retry.LastErrorOnly(true),
-		retry.DelayType(retry.BackOffDelay),
-		retry.Delay(r.config.InitialDelay),
+		retry.DelayType(func(n uint, _ error, _ *retry.Config) time.Duration {
+			return r.backoffDelay(n)
+		}),
 		retry.MaxDelay(r.config.MaxDelay),
 		retry.OnRetry(func(n uint, err error) {
-			log.Warnf(log.ExchangeSys, "%s: Attempt %d failed, retrying: %v", r.name, n+1, err)
+			r.getLogger().Warnf("%s: Attempt %d failed, retrying: %v", r.name, n+1, err)
 
 			// 调用重试回调
 			if onRetry != nil {
@@ -57,11 +62,44 @@ func (r *RetryHandler) Execute(ctx context.Context, operation func() error, onRe
 	)
 }
 
+// backoffDelay 计算第n次重试（从0开始）前的等待时间：InitialDelay * BackoffFactor^n，
+// 不超过MaxDelay，并按Jitter比例随机浮动
+func (r *RetryHandler) backoffDelay(n uint) time.Duration {
+	factor := r.config.BackoffFactor
+	if factor <= 0 {
+		factor = 2.0
+	}
+
+	delay := float64(r.config.InitialDelay) * math.Pow(factor, float64(n))
+	if r.config.MaxDelay > 0 && delay > float64(r.config.MaxDelay) {
+		delay = float64(r.config.MaxDelay)
+	}
+
+	// 在[delay*(1-jitter), delay*(1+jitter)]范围内随机浮动，避免多个客户端同时重试
+	if r.config.Jitter > 0 {
+		delay += delay * r.config.Jitter * (2*rand.Float64() - 1)
+	}
+	return time.Duration(delay)
+}
+
 // isRetryableError 判断错误是否可重试
+// 配置了ClassifyFunc时以其结果为准，否则使用内置的DefaultRetryClassifier
 func (r *RetryHandler) isRetryableError(err error) bool {
 	if err == nil {
 		return false
 	}
+	if r.config.ClassifyFunc != nil {
+		return r.config.ClassifyFunc(err)
+	}
+	return DefaultRetryClassifier(err)
+}
+
+// DefaultRetryClassifier 内置的重试分类：HTTPError按IsRetryable()判断，其他错误按错误信息匹配
+// 自定义ClassifyFunc可以调用它，在内置分类的基础上只调整个别错误
+func DefaultRetryClassifier(err error) bool {
+	if err == nil {
+		return false
+	}
 
 	// 检查自定义HTTP错误
 	if httpErr, ok := err.(*HTTPError); ok {
@@ -163,6 +201,13 @@ func ClassifyError(err error) *HTTPError {
 		return httpErr
 	}
 
+	// TLS握手超时归为TLS错误，便于与其他超时区分
+	if strings.Contains(errStr, "tls handshake timeout") {
+		httpErr.Type = ErrorTypeTLS
+		httpErr.Retryable = true
+		return httpErr
+	}
+
 	// 分类超时错误
 	if strings.Contains(errStr, "timeout") ||
 		strings.Contains(errStr, "deadline exceeded") {
@@ -0,0 +1,69 @@
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"os"
+)
+
+// tlsVersions 支持配置的最低TLS版本
+var tlsVersions = map[string]uint16{
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// TLSConfig TLS客户端配置
+type TLSConfig struct {
+	MinVersion  string `yaml:"min_version" json:"min_version"`     // 最低TLS版本："1.2"或"1.3"，为空时使用Go默认值
+	RootCAsFile string `yaml:"root_cas_file" json:"root_cas_file"` // 额外信任的CA证书文件（PEM），追加到系统证书池，用于TLS检查代理
+	ServerName  string `yaml:"server_name" json:"server_name"`     // 覆盖SNI及证书校验使用的主机名，为空时使用请求的域名
+}
+
+// IsEmpty 是否未配置任何TLS选项
+func (c *TLSConfig) IsEmpty() bool {
+	return c == nil || (c.MinVersion == "" && c.RootCAsFile == "" && c.ServerName == "")
+}
+
+// Validate 检查最低TLS版本是否合法
+func (c *TLSConfig) Validate() error {
+	if c == nil || c.MinVersion == "" {
+		return nil
+	}
+	if _, ok := tlsVersions[c.MinVersion]; !ok {
+		return fmt.Errorf("unsupported TLS min version %q, expected 1.2 or 1.3", c.MinVersion)
+	}
+	return nil
+}
+
+// Build 根据配置创建tls.Config，未配置任何选项时返回nil（使用默认TLS设置）
+func (c *TLSConfig) Build() (*tls.Config, error) {
+	if c.IsEmpty() {
+		return nil, nil
+	}
+	if err := c.Validate(); err != nil {
+		return nil, err
+	}
+
+	config := &tls.Config{
+		MinVersion: tlsVersions[c.MinVersion],
+		ServerName: c.ServerName,
+	}
+
+	if c.RootCAsFile != "" {
+		pem, err := os.ReadFile(c.RootCAsFile)
+		if err != nil {
+			return nil, fmt.Errorf("failed to read root CAs file: %w", err)
+		}
+		// 在系统证书池基础上追加，系统证书池不可用时使用空证书池
+		pool, err := x509.SystemCertPool()
+		if err != nil || pool == nil {
+			pool = x509.NewCertPool()
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no valid certificates found in %s", c.RootCAsFile)
+		}
+		config.RootCAs = pool
+	}
+	return config, nil
+}
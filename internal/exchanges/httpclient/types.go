@@ -3,6 +3,7 @@ package httpclient
 
 import (
 	"context"
+	"errors"
 	"time"
 
 	"github.com/mooyang-code/data-miner/internal/ipmanager"
@@ -53,13 +54,18 @@ type RequestOptions struct {
 	RetryDelay    time.Duration `json:"retry_delay"`
 	MaxRetryDelay time.Duration `json:"max_retry_delay"`
 
-	// IP管理相关
-	EnableDynamicIP bool `json:"enable_dynamic_ip"`
-	ForceIPSwitch   bool `json:"force_ip_switch"`
+	// IP管理相关（客户端启用动态IP时生效）；设置Options时应基于DefaultRequestOptions，
+	// 否则EnableDynamicIP的零值会使请求直连域名
+	EnableDynamicIP bool `json:"enable_dynamic_ip"` // 为false时该请求直连域名，不使用动态IP
+	ForceIPSwitch   bool `json:"force_ip_switch"`   // 请求前切换到下一个IP并通过新连接发送
 
 	// 其他选项
 	SkipRateLimit bool `json:"skip_rate_limit"`
-	Verbose       bool `json:"verbose"`
+	Verbose       bool `json:"verbose"` // 记录该请求的详细日志（含请求/响应体），不受客户端Debug配置影响
+
+	// 合并并发的相同GET请求（method+URL相同），共享同一个上游请求和响应。
+	// 默认关闭，需要独立请求的调用方（如带签名或时间戳的请求）不应开启
+	Coalesce bool `json:"coalesce"`
 }
 
 // Response HTTP响应结构
@@ -83,17 +89,47 @@ type Status struct {
 	SuccessRequests int64 `json:"success_requests"`
 	FailedRequests  int64 `json:"failed_requests"`
 	RetryCount      int64 `json:"retry_count"`
+	Coalesced       int64 `json:"coalesced"` // 被合并、未单独发起上游请求的请求数
+
+	// 按错误类型统计的失败次数（每次失败的尝试都计入，包括随后重试成功的），键为ErrorType.String()
+	ErrorsByType map[string]int64 `json:"errors_by_type"`
 
 	// 速率限制
 	RateLimit *RateLimitStatus `json:"rate_limit"`
 
+	// 在途请求
+	InFlight *InFlightStatus `json:"in_flight"`
+
 	// IP管理器状态
 	IPManager map[string]interface{} `json:"ip_manager"`
 
+	// 连接池统计
+	ConnPool *ConnPoolStatus `json:"conn_pool"`
+
 	// 错误信息
 	LastError string `json:"last_error,omitempty"`
 }
 
+// ConnPoolStatus 连接池统计
+type ConnPoolStatus struct {
+	ConnsCreated    int64                  `json:"conns_created"`     // 新建连接数（每次新建都需要完整的TLS握手）
+	ConnsReused     int64                  `json:"conns_reused"`      // 复用连接的请求数
+	ConnsReusedIdle int64                  `json:"conns_reused_idle"` // 复用空闲连接的请求数
+	ConnsClosed     int64                  `json:"conns_closed"`      // 关闭连接数（包括空闲超时关闭）
+	ConnsOpen       int64                  `json:"conns_open"`        // 当前打开的连接数
+	ReuseRatio      float64                `json:"reuse_ratio"`       // 连接复用率
+	ConnsHTTP2      int64                  `json:"conns_http2"`       // 通过ALPN协商为HTTP/2的TLS连接数
+	ConnsHTTP1      int64                  `json:"conns_http1"`       // 使用HTTP/1.1的TLS连接数（未启用HTTP/2或服务端不支持）
+	PerIP           map[string]IPConnStats `json:"per_ip"`            // 按IP统计
+}
+
+// IPConnStats 单个IP的连接统计
+type IPConnStats struct {
+	Created int64 `json:"created"` // 新建连接数
+	Reused  int64 `json:"reused"`  // 复用连接的请求数
+	Closed  int64 `json:"closed"`  // 关闭连接数
+}
+
 // RateLimitStatus 速率限制状态
 type RateLimitStatus struct {
 	Enabled           bool      `json:"enabled"`
@@ -103,6 +139,13 @@ type RateLimitStatus struct {
 	Remaining         int       `json:"remaining"`
 }
 
+// InFlightStatus 在途请求统计
+type InFlightStatus struct {
+	Limit   int   `json:"limit"`   // 在途请求数上限，0表示不限制
+	Active  int64 `json:"active"`  // 进行中的请求数
+	Waiting int64 `json:"waiting"` // 等待空闲名额的请求数
+}
+
 // Config HTTP客户端配置
 type Config struct {
 	// 基本配置
@@ -110,6 +153,19 @@ type Config struct {
 	UserAgent string        `yaml:"user_agent" json:"user_agent"`
 	Timeout   time.Duration `yaml:"timeout" json:"timeout"`
 
+	// 响应体的最大长度（字节，解压后），超出时请求失败并返回ErrResponseTooLarge，避免异常响应耗尽内存
+	MaxResponseBytes int64 `yaml:"max_response_bytes" json:"max_response_bytes"` // 默认64MB
+
+	// 同时进行中的请求（每次尝试）数上限，同一客户端实例的所有调用方共享，0表示不限制；
+	// 重试等待期间不占用名额。应不大于Transport.MaxConnsPerHost，避免请求在连接池中排队
+	MaxInFlight int `yaml:"max_in_flight" json:"max_in_flight"`
+
+	// 代理配置：支持http://、https://、socks5://，设置后所有请求经代理转发，不再使用动态IP替换
+	ProxyURL string `yaml:"proxy_url" json:"proxy_url"`
+
+	// TLS配置：最低版本、自定义CA、SNI覆盖，未配置时使用默认TLS设置
+	TLS *TLSConfig `yaml:"tls" json:"tls"`
+
 	// 动态IP配置
 	DynamicIP *DynamicIPConfig `yaml:"dynamic_ip" json:"dynamic_ip"`
 
@@ -124,6 +180,14 @@ type Config struct {
 
 	// 调试配置
 	Debug bool `yaml:"debug" json:"debug"`
+
+	// 请求/响应日志：需同时开启Debug，记录方法、URL、状态码、耗时及请求/响应体；设置了RequestOptions.Verbose的单个请求总是记录
+	LogBodies    bool     `yaml:"log_bodies" json:"log_bodies"`
+	LogBodyLimit int      `yaml:"log_body_limit" json:"log_body_limit"` // 日志中请求/响应体的最大长度，默认2048
+	RedactFields []string `yaml:"redact_fields" json:"redact_fields"`   // 需要脱敏的字段，默认signature、apiKey等
+
+	// 日志实现，默认输出到cryptotrader的log包；未单独配置时同时用于动态IP的IP管理器
+	Logger Logger `yaml:"-" json:"-"`
 }
 
 // DynamicIPConfig 动态IP配置
@@ -131,6 +195,16 @@ type DynamicIPConfig struct {
 	Enabled   bool              `yaml:"enabled" json:"enabled"`
 	Hostname  string            `yaml:"hostname" json:"hostname"`
 	IPManager *ipmanager.Config `yaml:"ip_manager" json:"ip_manager"`
+
+	// 轮询配置：在延迟最低的前TopK个IP之间轮询建立连接，分散负载
+	// 注意：启用keep-alive时已建立的连接会被复用，轮询发生在新建连接时
+	RoundRobin    bool          `yaml:"round_robin" json:"round_robin"`
+	TopK          int           `yaml:"top_k" json:"top_k"`                   // 参与轮询的IP数量，默认3
+	LatencyBudget time.Duration `yaml:"latency_budget" json:"latency_budget"` // 相对最佳IP允许的额外延迟，0表示不限制
+
+	// 连接预热配置：启动时及每次IP列表更新后，为延迟最低的IP预先建立TLS连接
+	Prewarm     bool   `yaml:"prewarm" json:"prewarm"`
+	PrewarmPath string `yaml:"prewarm_path" json:"prewarm_path"` // 预热请求路径，默认"/"
 }
 
 // RetryConfig 重试配置
@@ -140,6 +214,12 @@ type RetryConfig struct {
 	InitialDelay  time.Duration `yaml:"initial_delay" json:"initial_delay"`
 	MaxDelay      time.Duration `yaml:"max_delay" json:"max_delay"`
 	BackoffFactor float64       `yaml:"backoff_factor" json:"backoff_factor"`
+	Jitter        float64       `yaml:"jitter" json:"jitter"`               // 延迟随机浮动比例（0~1），0表示不浮动
+	TotalTimeout  time.Duration `yaml:"total_timeout" json:"total_timeout"` // 包含所有重试和等待的总耗时上限，0表示不限制
+
+	// ClassifyFunc 自定义重试分类，返回true表示可重试
+	// 设置后完全取代内置分类（包括HTTPError.IsRetryable()），可调用DefaultRetryClassifier组合使用
+	ClassifyFunc func(err error) bool `yaml:"-" json:"-"`
 }
 
 // RateLimitConfig 速率限制配置
@@ -158,6 +238,7 @@ type TransportConfig struct {
 	ResponseHeaderTimeout time.Duration `yaml:"response_header_timeout" json:"response_header_timeout"`
 	DisableKeepAlives     bool          `yaml:"disable_keep_alives" json:"disable_keep_alives"`
 	DisableCompression    bool          `yaml:"disable_compression" json:"disable_compression"`
+	EnableHTTP2           bool          `yaml:"enable_http2" json:"enable_http2"` // 通过ALPN协商HTTP/2，服务端不支持时回退到HTTP/1.1，默认关闭
 }
 
 // ErrorType 错误类型
@@ -176,8 +257,43 @@ const (
 	ErrorTypeHTTP
 	// ErrorTypeRateLimit 速率限制错误
 	ErrorTypeRateLimit
+	// ErrorTypeResponseTooLarge 响应体超过MaxResponseBytes
+	ErrorTypeResponseTooLarge
 )
 
+// errorTypes 所有错误类型，用于按类型统计错误
+var errorTypes = []ErrorType{
+	ErrorTypeUnknown, ErrorTypeNetwork, ErrorTypeTimeout, ErrorTypeTLS,
+	ErrorTypeHTTP, ErrorTypeRateLimit, ErrorTypeResponseTooLarge,
+}
+
+// String 返回错误类型名称，用于状态和日志
+func (t ErrorType) String() string {
+	switch t {
+	case ErrorTypeNetwork:
+		return "network"
+	case ErrorTypeTimeout:
+		return "timeout"
+	case ErrorTypeTLS:
+		return "tls"
+	case ErrorTypeHTTP:
+		return "http"
+	case ErrorTypeRateLimit:
+		return "rate_limit"
+	case ErrorTypeResponseTooLarge:
+		return "response_too_large"
+	default:
+		return "unknown"
+	}
+}
+
+// ErrResponseTooLarge 响应体超过Config.MaxResponseBytes，可使用errors.Is判断
+var ErrResponseTooLarge = errors.New("response body too large")
+
+// ErrRetryBudgetExhausted 重试总耗时超过RetryConfig.TotalTimeout，可使用errors.Is判断，
+// 错误同时包装了最后一次尝试的错误
+var ErrRetryBudgetExhausted = errors.New("retry budget exhausted")
+
 // HTTPError HTTP错误
 type HTTPError struct {
 	Type       ErrorType `json:"type"`
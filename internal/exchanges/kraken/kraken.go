@@ -0,0 +1,248 @@
+// Package kraken 实现Kraken交易所公共接口和结构
+package kraken
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// errWebsocketNotSupported Kraken适配器暂未实现WebSocket订阅
+var errWebsocketNotSupported = errors.New("kraken adapter does not support websocket subscriptions yet")
+
+// 确保Kraken实现了通用交易所接口
+var _ types.ExchangeInterface = (*Kraken)(nil)
+
+// Kraken 交易所结构体，目前只包含现货公共行情的REST API客户端
+type Kraken struct {
+	RestAPI *KrakenRestAPI     // REST API 客户端
+	config  types.KrakenConfig // Kraken配置
+
+	rateLimit *types.RateLimit // 速率限制
+	Name      string           // 交易所名称
+	Enabled   bool             // 是否启用
+}
+
+// New 创建新的Kraken交易所实例
+func New() (*Kraken, error) {
+	restAPI, err := NewRestAPI()
+	if err != nil {
+		return nil, err
+	}
+
+	return &Kraken{
+		RestAPI: restAPI,
+		rateLimit: &types.RateLimit{
+			RequestsPerSecond: 1,
+			RequestsPerMinute: 60,
+			LastRequest:       time.Now(),
+		},
+		Name:    "Kraken",
+		Enabled: true,
+	}, nil
+}
+
+// GetName 返回交易所名称
+func (k *Kraken) GetName() types.Exchange {
+	return types.ExchangeKraken
+}
+
+// Initialize 初始化交易所
+func (k *Kraken) Initialize(config interface{}) error {
+	krakenConfig, ok := config.(types.KrakenConfig)
+	if !ok {
+		k.config = types.KrakenConfig{} // 使用默认配置
+	} else {
+		k.config = krakenConfig
+	}
+
+	k.RestAPI.SetBaseURL(k.config.APIURL)
+	return nil
+}
+
+// IsEnabled 返回交易所是否启用
+func (k *Kraken) IsEnabled() bool {
+	return k.Enabled
+}
+
+// Close 关闭交易所连接
+func (k *Kraken) Close() error {
+	if k.RestAPI != nil {
+		return k.RestAPI.Close()
+	}
+	return nil
+}
+
+// FormatSymbol 按Kraken规则格式化交易对符号，币种使用Kraken代码（如BTC/USDT -> XBTUSDT）
+func (k *Kraken) FormatSymbol(pair currency.Pair) (string, error) {
+	if pair.Base.IsEmpty() || pair.Quote.IsEmpty() {
+		return "", fmt.Errorf("%w: %q", types.ErrInvalidSymbol, pair.String())
+	}
+	return formatKrakenPair(pair), nil
+}
+
+// CheckRateLimit 检查速率限制（请求频率由HTTP客户端按配置限制）
+func (k *Kraken) CheckRateLimit() error {
+	return nil
+}
+
+// IsConnected 检查连接状态（REST客户端可用即视为已连接）
+func (k *Kraken) IsConnected() bool {
+	return k.RestAPI != nil
+}
+
+// GetLastPing 获取最后ping时间（未使用WebSocket，始终为零值）
+func (k *Kraken) GetLastPing() time.Time {
+	return time.Time{}
+}
+
+// GetRateLimit 获取速率限制信息
+func (k *Kraken) GetRateLimit() *types.RateLimit {
+	return k.rateLimit
+}
+
+// GetServerTime 获取服务器时间
+func (k *Kraken) GetServerTime(ctx context.Context) (time.Time, error) {
+	return k.RestAPI.GetServerTime(ctx)
+}
+
+// GetTicker 获取单个交易对的行情数据
+func (k *Kraken) GetTicker(ctx context.Context, symbol types.Symbol) (*types.Ticker, error) {
+	result, err := k.RestAPI.GetTickers(ctx, []types.Symbol{symbol})
+	if err != nil {
+		return nil, err
+	}
+
+	for _, info := range result {
+		ticker := convertTicker(symbol, info)
+		return &ticker, nil
+	}
+	return nil, fmt.Errorf("no ticker data found for symbol %s", symbol)
+}
+
+// GetMultipleTickers 批量获取行情数据
+func (k *Kraken) GetMultipleTickers(ctx context.Context, symbols []types.Symbol) ([]types.Ticker, error) {
+	result, err := k.RestAPI.GetTickers(ctx, symbols)
+	if err != nil {
+		return nil, err
+	}
+
+	// Kraken返回的交易对名称与请求的不同（如XBTUSD返回XXBTZUSD），按规范化后的符号匹配回请求的符号
+	requested := make(map[types.Symbol]types.Symbol, len(symbols))
+	for _, symbol := range symbols {
+		requested[symbol.Normalize()] = symbol
+	}
+
+	tickers := make([]types.Ticker, 0, len(result))
+	for krakenPair, info := range result {
+		symbol, ok := requested[PairToSymbol(krakenPair)]
+		if !ok {
+			symbol = PairToSymbol(krakenPair)
+		}
+		tickers = append(tickers, convertTicker(symbol, info))
+	}
+	return tickers, nil
+}
+
+// GetOrderbook 获取订单簿数据
+func (k *Kraken) GetOrderbook(ctx context.Context, symbol types.Symbol, depth int) (*types.Orderbook, error) {
+	krakenDepth, err := k.RestAPI.GetDepth(ctx, symbol, depth)
+	if err != nil {
+		return nil, err
+	}
+
+	return &types.Orderbook{
+		Exchange:  types.ExchangeKraken,
+		Symbol:    symbol,
+		Bids:      convertOrderbookEntries(krakenDepth.Bids),
+		Asks:      convertOrderbookEntries(krakenDepth.Asks),
+		Timestamp: time.Now(),
+	}, nil
+}
+
+// GetMultipleOrderbooks 批量获取订单簿数据（Kraken深度接口只支持单个交易对，逐个请求），
+// ctx取消时停止请求剩余的交易对并返回ctx.Err()
+func (k *Kraken) GetMultipleOrderbooks(ctx context.Context, symbols []types.Symbol, depth int) ([]types.Orderbook, error) {
+	orderbooks := make([]types.Orderbook, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		orderbook, err := k.GetOrderbook(ctx, symbol, depth)
+		if err != nil {
+			batchErr.Add(symbol, err)
+			continue
+		}
+		orderbooks = append(orderbooks, *orderbook)
+	}
+	return orderbooks, batchErr.ErrOrNil()
+}
+
+// GetTrades 获取交易数据
+func (k *Kraken) GetTrades(ctx context.Context, symbol types.Symbol, limit int) ([]types.Trade, error) {
+	rows, err := k.RestAPI.GetRecentTrades(ctx, symbol, limit)
+	if err != nil {
+		return nil, err
+	}
+
+	trades := make([]types.Trade, 0, len(rows))
+	for _, row := range rows {
+		trade, err := convertTrade(symbol, row)
+		if err != nil {
+			return nil, err
+		}
+		trades = append(trades, trade)
+	}
+	return trades, nil
+}
+
+// GetKlines 获取K线数据（Kraken OHLC接口不支持limit参数，返回最近的limit根K线）
+func (k *Kraken) GetKlines(ctx context.Context, symbol types.Symbol, interval string, limit int) ([]types.Kline, error) {
+	rows, err := k.RestAPI.GetOHLC(ctx, symbol, interval)
+	if err != nil {
+		return nil, err
+	}
+	if limit > 0 && len(rows) > limit {
+		rows = rows[len(rows)-limit:]
+	}
+
+	klines := make([]types.Kline, 0, len(rows))
+	for _, row := range rows {
+		kline, err := convertKline(symbol, interval, row)
+		if err != nil {
+			return nil, err
+		}
+		klines = append(klines, kline)
+	}
+	return klines, nil
+}
+
+// SubscribeTicker 订阅行情数据（暂不支持）
+func (k *Kraken) SubscribeTicker(symbols []types.Symbol, callback types.DataCallback) error {
+	return errWebsocketNotSupported
+}
+
+// SubscribeOrderbook 订阅订单簿数据（暂不支持）
+func (k *Kraken) SubscribeOrderbook(symbols []types.Symbol, callback types.DataCallback) error {
+	return errWebsocketNotSupported
+}
+
+// SubscribeTrades 订阅交易数据（暂不支持）
+func (k *Kraken) SubscribeTrades(symbols []types.Symbol, callback types.DataCallback) error {
+	return errWebsocketNotSupported
+}
+
+// SubscribeKlines 订阅K线数据（暂不支持）
+func (k *Kraken) SubscribeKlines(symbols []types.Symbol, intervals []string, callback types.DataCallback) error {
+	return errWebsocketNotSupported
+}
+
+// UnsubscribeAll 取消所有订阅（没有订阅，直接返回）
+func (k *Kraken) UnsubscribeAll() error {
+	return nil
+}
@@ -0,0 +1,146 @@
+package kraken
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+func TestSymbolMapping(t *testing.T) {
+	tests := []struct {
+		symbol types.Symbol
+		pair   string
+	}{
+		{"BTCUSDT", "XBTUSDT"},
+		{"BTC/USD", "XBTUSD"},
+		{"ETHUSD", "ETHUSD"},
+		{"DOGE-EUR", "XDGEUR"},
+	}
+	for _, tt := range tests {
+		pair, err := toKrakenPair(tt.symbol)
+		if err != nil || pair != tt.pair {
+			t.Errorf("toKrakenPair(%s) = %s, %v; want %s", tt.symbol, pair, err, tt.pair)
+		}
+	}
+
+	for krakenPair, want := range map[string]types.Symbol{
+		"XXBTZUSD": "BTCUSD",
+		"XBTUSDT":  "BTCUSDT",
+		"XETHZEUR": "ETHEUR",
+		"XDGUSD":   "DOGEUSD",
+		"SOLUSD":   "SOLUSD",
+	} {
+		if got := PairToSymbol(krakenPair); got != want {
+			t.Errorf("PairToSymbol(%s) = %s; want %s", krakenPair, got, want)
+		}
+	}
+	pairs := currency.Pairs{currency.NewPair(currency.BTC, currency.USDT), currency.NewPair(currency.DOGE, currency.EUR)}
+	symbols, err := types.FormatSymbols(&Kraken{}, pairs)
+	if err != nil || len(symbols) != 2 || symbols[0] != "XBTUSDT" || symbols[1] != "XDGEUR" {
+		t.Errorf("FormatSymbols = %v, %v; want [XBTUSDT XDGEUR]", symbols, err)
+	}
+	if _, err := types.FormatSymbols(&Kraken{}, currency.Pairs{currency.EMPTYPAIR}); err == nil {
+		t.Error("expected error formatting an empty pair")
+	}
+}
+
+func newTestKraken(t *testing.T, handler http.HandlerFunc) *Kraken {
+	t.Helper()
+	server := httptest.NewServer(handler)
+	t.Cleanup(server.Close)
+
+	k, err := New()
+	if err != nil {
+		t.Fatalf("failed to create Kraken: %v", err)
+	}
+	t.Cleanup(func() { k.Close() })
+
+	if err := k.Initialize(types.KrakenConfig{Enabled: true, APIURL: server.URL}); err != nil {
+		t.Fatalf("failed to initialize Kraken: %v", err)
+	}
+	return k
+}
+
+func TestGetMultipleTickers(t *testing.T) {
+	k := newTestKraken(t, func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != tickerPath || r.URL.Query().Get("pair") != "XBTUSD,ETHUSD" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"error":[],"result":{
+			"XXBTZUSD":{"a":["101.0","1","1.0"],"b":["99.0","1","1.0"],"c":["100.0","0.1"],"v":["10","20"],"p":["100","100"],"t":[5,10],"l":["90","80"],"h":["110","120"],"o":"80.0"},
+			"XETHZUSD":{"a":["11","1","1"],"b":["9","1","1"],"c":["10","1"],"v":["1","2"],"p":["10","10"],"t":[1,2],"l":["9","8"],"h":["11","12"],"o":"10"}}}`))
+	})
+
+	tickers, err := k.GetMultipleTickers(context.Background(), []types.Symbol{"BTC/USD", "ETHUSD"})
+	if err != nil {
+		t.Fatalf("GetMultipleTickers failed: %v", err)
+	}
+	if len(tickers) != 2 {
+		t.Fatalf("expected 2 tickers, got %d", len(tickers))
+	}
+
+	bySymbol := make(map[types.Symbol]types.Ticker)
+	for _, ticker := range tickers {
+		bySymbol[ticker.Symbol] = ticker
+	}
+	btc, ok := bySymbol["BTC/USD"]
+	if !ok {
+		t.Fatalf("expected ticker keyed by requested symbol, got %v", bySymbol)
+	}
+	if btc.Exchange != types.ExchangeKraken || btc.Price != 100 || btc.Volume != 20 || btc.High24h != 120 || btc.Change24h != 25 {
+		t.Errorf("unexpected BTC ticker: %+v", btc)
+	}
+}
+
+func TestGetOrderbookKlinesAndTrades(t *testing.T) {
+	k := newTestKraken(t, func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case depthPath:
+			w.Write([]byte(`{"error":[],"result":{"XXBTZUSD":{"asks":[["101.5","2.0",1700000000]],"bids":[["100.5","1.5",1700000000],["100.0","3.0",1700000000]]}}}`))
+		case ohlcPath:
+			if r.URL.Query().Get("interval") != "60" {
+				t.Errorf("unexpected interval: %s", r.URL.Query().Get("interval"))
+			}
+			w.Write([]byte(`{"error":[],"result":{"XXBTZUSD":[
+				[1700000000,"1","2","0.5","1.5","1.2","10",5],
+				[1700003600,"1.5","3","1","2","2.1","20",8]],"last":1700003600}}`))
+		case tradesPath:
+			w.Write([]byte(`{"error":[],"result":{"XXBTZUSD":[["100.1","0.5",1700000000.25,"s","l","",42]],"last":"1"}}`))
+		default:
+			w.Write([]byte(`{"error":["EGeneral:Unknown method"]}`))
+		}
+	})
+	ctx := context.Background()
+
+	orderbook, err := k.GetOrderbook(ctx, "BTCUSD", 10)
+	if err != nil {
+		t.Fatalf("GetOrderbook failed: %v", err)
+	}
+	if len(orderbook.Bids) != 2 || len(orderbook.Asks) != 1 || orderbook.Asks[0].Price != 101.5 {
+		t.Errorf("unexpected orderbook: %+v", orderbook)
+	}
+
+	klines, err := k.GetKlines(ctx, "BTCUSD", "1h", 1)
+	if err != nil {
+		t.Fatalf("GetKlines failed: %v", err)
+	}
+	if len(klines) != 1 || klines[0].ClosePrice != 2 || klines[0].TradeCount != 8 || klines[0].OpenTime.Unix() != 1700003600 {
+		t.Errorf("expected only the latest kline, got %+v", klines)
+	}
+
+	trades, err := k.GetTrades(ctx, "BTCUSD", 1)
+	if err != nil {
+		t.Fatalf("GetTrades failed: %v", err)
+	}
+	if len(trades) != 1 || trades[0].ID != "42" || trades[0].Side != "sell" || trades[0].Price != 100.1 {
+		t.Errorf("unexpected trades: %+v", trades)
+	}
+
+	if _, err := k.GetKlines(ctx, "BTCUSD", "2h", 1); err == nil {
+		t.Error("expected error for unsupported interval")
+	}
+}
@@ -0,0 +1,310 @@
+// Package kraken 实现Kraken交易所现货公共行情接口（使用通用HTTP客户端）
+package kraken
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"math"
+	"net/url"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/httpclient"
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+)
+
+// API 路径常量
+const (
+	// 基础URL
+	apiURL = "https://api.kraken.com"
+
+	// 公共接口路径
+	tickerPath = "/0/public/Ticker"
+	depthPath  = "/0/public/Depth"
+	ohlcPath   = "/0/public/OHLC"
+	tradesPath = "/0/public/Trades"
+	timePath   = "/0/public/Time"
+)
+
+// KrakenRestAPI Kraken REST API客户端
+type KrakenRestAPI struct {
+	baseURL    string            // API地址
+	httpClient httpclient.Client // HTTP客户端
+}
+
+// NewRestAPI 创建新的Kraken REST API客户端
+func NewRestAPI() (*KrakenRestAPI, error) {
+	httpClient, err := httpclient.New(createKrakenHTTPConfig())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create HTTP client for Kraken: %w", err)
+	}
+
+	httpClient.SetHeaders(map[string]string{
+		"User-Agent": "crypto-data-miner/1.0.0",
+	})
+
+	log.Infof(log.ExchangeSys, "Kraken REST API client created successfully")
+	return &KrakenRestAPI{
+		baseURL:    apiURL,
+		httpClient: httpClient,
+	}, nil
+}
+
+// createKrakenHTTPConfig 创建Kraken专用的HTTP客户端配置
+func createKrakenHTTPConfig() *httpclient.Config {
+	config := httpclient.DefaultConfig("kraken")
+
+	// 调整重试配置
+	config.Retry.MaxAttempts = 3
+	config.Retry.InitialDelay = time.Second
+	config.Retry.MaxDelay = 8 * time.Second
+
+	// 公共接口限制约为每秒1次
+	config.RateLimit.RequestsPerMinute = 60
+	return config
+}
+
+// SetBaseURL 设置API地址
+func (k *KrakenRestAPI) SetBaseURL(baseURL string) {
+	if baseURL != "" {
+		k.baseURL = strings.TrimRight(baseURL, "/")
+	}
+}
+
+// Close 关闭REST API客户端
+func (k *KrakenRestAPI) Close() error {
+	if k.httpClient != nil {
+		if err := k.httpClient.Close(); err != nil {
+			log.Errorf(log.ExchangeSys, "Failed to close HTTP client: %v", err)
+		}
+		log.Infof(log.ExchangeSys, "Kraken REST API client closed")
+	}
+	return nil
+}
+
+// SendHTTPRequest 发送公共接口请求并解析Kraken响应中的result字段
+func (k *KrakenRestAPI) SendHTTPRequest(ctx context.Context, path string, params url.Values, result interface{}) error {
+	fullURL := k.baseURL + path
+	if len(params) > 0 {
+		fullURL += "?" + params.Encode()
+	}
+
+	var resp apiResponse
+	if err := k.httpClient.Get(ctx, fullURL, &resp); err != nil {
+		return fmt.Errorf("kraken request %s failed: %w", path, err)
+	}
+	if len(resp.Error) > 0 {
+		return fmt.Errorf("kraken API error: %s", strings.Join(resp.Error, "; "))
+	}
+	if err := json.Unmarshal(resp.Result, result); err != nil {
+		return fmt.Errorf("failed to parse kraken response for %s: %w", path, err)
+	}
+	return nil
+}
+
+// GetServerTime 获取服务器时间
+func (k *KrakenRestAPI) GetServerTime(ctx context.Context) (time.Time, error) {
+	var result struct {
+		UnixTime int64 `json:"unixtime"`
+	}
+	if err := k.SendHTTPRequest(ctx, timePath, nil, &result); err != nil {
+		return time.Time{}, err
+	}
+	return time.Unix(result.UnixTime, 0), nil
+}
+
+// GetTickers 批量获取行情数据，返回以Kraken交易对名称为键的结果
+func (k *KrakenRestAPI) GetTickers(ctx context.Context, symbols []types.Symbol) (map[string]TickerInfo, error) {
+	pairs := make([]string, len(symbols))
+	for i, symbol := range symbols {
+		pair, err := toKrakenPair(symbol)
+		if err != nil {
+			return nil, fmt.Errorf("invalid symbol %s: %w", symbol, err)
+		}
+		pairs[i] = pair
+	}
+
+	params := url.Values{}
+	params.Set("pair", strings.Join(pairs, ","))
+
+	var result map[string]TickerInfo
+	if err := k.SendHTTPRequest(ctx, tickerPath, params, &result); err != nil {
+		return nil, err
+	}
+	return result, nil
+}
+
+// GetDepth 获取订单簿数据
+func (k *KrakenRestAPI) GetDepth(ctx context.Context, symbol types.Symbol, depth int) (*Depth, error) {
+	pair, err := toKrakenPair(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid symbol %s: %w", symbol, err)
+	}
+
+	params := url.Values{}
+	params.Set("pair", pair)
+	if depth > 0 {
+		params.Set("count", strconv.Itoa(depth))
+	}
+
+	var result map[string]Depth
+	if err := k.SendHTTPRequest(ctx, depthPath, params, &result); err != nil {
+		return nil, err
+	}
+	for _, d := range result {
+		return &d, nil
+	}
+	return nil, fmt.Errorf("no orderbook data found for symbol %s", symbol)
+}
+
+// GetOHLC 获取K线数据，每根K线为[时间, 开, 高, 低, 收, 加权均价, 成交量, 成交笔数]
+func (k *KrakenRestAPI) GetOHLC(ctx context.Context, symbol types.Symbol, interval string) ([][]interface{}, error) {
+	pair, err := toKrakenPair(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid symbol %s: %w", symbol, err)
+	}
+	minutes, err := toKrakenInterval(interval)
+	if err != nil {
+		return nil, err
+	}
+
+	params := url.Values{}
+	params.Set("pair", pair)
+	params.Set("interval", strconv.Itoa(minutes))
+
+	var result map[string]json.RawMessage
+	if err := k.SendHTTPRequest(ctx, ohlcPath, params, &result); err != nil {
+		return nil, err
+	}
+	return firstPairResult(result, symbol)
+}
+
+// GetRecentTrades 获取最近成交，每笔成交为[价格, 数量, 时间, 方向, 类型, 其他, 成交ID]
+func (k *KrakenRestAPI) GetRecentTrades(ctx context.Context, symbol types.Symbol, limit int) ([][]interface{}, error) {
+	pair, err := toKrakenPair(symbol)
+	if err != nil {
+		return nil, fmt.Errorf("invalid symbol %s: %w", symbol, err)
+	}
+
+	params := url.Values{}
+	params.Set("pair", pair)
+	if limit > 0 {
+		params.Set("count", strconv.Itoa(limit))
+	}
+
+	var result map[string]json.RawMessage
+	if err := k.SendHTTPRequest(ctx, tradesPath, params, &result); err != nil {
+		return nil, err
+	}
+	return firstPairResult(result, symbol)
+}
+
+// firstPairResult 解析结果中交易对对应的数组（跳过分页用的last字段）
+func firstPairResult(result map[string]json.RawMessage, symbol types.Symbol) ([][]interface{}, error) {
+	for key, raw := range result {
+		if key == "last" {
+			continue
+		}
+		var rows [][]interface{}
+		if err := json.Unmarshal(raw, &rows); err != nil {
+			return nil, fmt.Errorf("failed to parse data for %s: %w", key, err)
+		}
+		return rows, nil
+	}
+	return nil, fmt.Errorf("no data found for symbol %s", symbol)
+}
+
+// convertTicker 将Kraken行情数据转换为通用类型
+func convertTicker(symbol types.Symbol, info TickerInfo) types.Ticker {
+	last := firstFloat(info.Last, 0)
+	open := toFloat64(info.OpenPrice)
+
+	var change float64
+	if open > 0 {
+		change = (last - open) / open * 100
+	}
+
+	return types.Ticker{
+		Exchange:  types.ExchangeKraken,
+		Symbol:    symbol,
+		Price:     last,
+		Volume:    firstFloat(info.Volume, 1),
+		High24h:   firstFloat(info.High, 1),
+		Low24h:    firstFloat(info.Low, 1),
+		Change24h: change,
+		Timestamp: time.Now(),
+	}
+}
+
+// convertOrderbookEntries 转换订单簿档位
+func convertOrderbookEntries(levels [][]interface{}) []types.OrderbookEntry {
+	entries := make([]types.OrderbookEntry, 0, len(levels))
+	for _, level := range levels {
+		if len(level) < 2 {
+			continue
+		}
+		entries = append(entries, types.OrderbookEntry{
+			Price:    toFloat64(level[0]),
+			Quantity: toFloat64(level[1]),
+		})
+	}
+	return entries
+}
+
+// convertKline 转换单根K线
+func convertKline(symbol types.Symbol, interval string, row []interface{}) (types.Kline, error) {
+	if len(row) < 8 {
+		return types.Kline{}, fmt.Errorf("invalid OHLC row length: %d", len(row))
+	}
+	minutes, err := toKrakenInterval(interval)
+	if err != nil {
+		return types.Kline{}, err
+	}
+
+	openTime := time.Unix(int64(toFloat64(row[0])), 0)
+	return types.Kline{
+		Exchange:   types.ExchangeKraken,
+		Symbol:     symbol,
+		Interval:   interval,
+		OpenTime:   openTime,
+		CloseTime:  openTime.Add(time.Duration(minutes)*time.Minute - time.Millisecond),
+		OpenPrice:  toFloat64(row[1]),
+		HighPrice:  toFloat64(row[2]),
+		LowPrice:   toFloat64(row[3]),
+		ClosePrice: toFloat64(row[4]),
+		Volume:     toFloat64(row[6]),
+		TradeCount: int64(toFloat64(row[7])),
+	}, nil
+}
+
+// convertTrade 转换单笔成交
+func convertTrade(symbol types.Symbol, row []interface{}) (types.Trade, error) {
+	if len(row) < 4 {
+		return types.Trade{}, fmt.Errorf("invalid trade row length: %d", len(row))
+	}
+
+	seconds, frac := math.Modf(toFloat64(row[2]))
+	side := "buy"
+	if s, _ := row[3].(string); s == "s" {
+		side = "sell"
+	}
+
+	// 新版接口在第7个字段返回成交ID，旧版没有时使用时间戳作为ID
+	id := fmt.Sprintf("%v", row[2])
+	if len(row) >= 7 {
+		id = strconv.FormatInt(int64(toFloat64(row[6])), 10)
+	}
+
+	return types.Trade{
+		Exchange:  types.ExchangeKraken,
+		Symbol:    symbol,
+		ID:        id,
+		Price:     toFloat64(row[0]),
+		Quantity:  toFloat64(row[1]),
+		Side:      side,
+		Timestamp: time.Unix(int64(seconds), int64(frac*1e9)),
+	}, nil
+}
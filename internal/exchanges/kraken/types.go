@@ -0,0 +1,145 @@
+package kraken
+
+import (
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// apiResponse Kraken REST接口的通用响应结构
+type apiResponse struct {
+	Error  []string        `json:"error"`
+	Result json.RawMessage `json:"result"`
+}
+
+// TickerInfo Kraken行情数据（/0/public/Ticker）
+// 数组字段的含义：a/b为[价格, 整手数量, 数量]，c为[价格, 数量]，v/p/t/l/h为[今日, 最近24小时]
+type TickerInfo struct {
+	Ask       []string      `json:"a"` // 卖一
+	Bid       []string      `json:"b"` // 买一
+	Last      []string      `json:"c"` // 最新成交
+	Volume    []string      `json:"v"` // 成交量
+	VWAP      []string      `json:"p"` // 成交量加权平均价
+	Trades    []json.Number `json:"t"` // 成交笔数
+	Low       []string      `json:"l"` // 最低价
+	High      []string      `json:"h"` // 最高价
+	OpenPrice string        `json:"o"` // 今日开盘价
+}
+
+// Depth Kraken订单簿数据（/0/public/Depth），每个档位为[价格, 数量, 时间戳]
+type Depth struct {
+	Asks [][]interface{} `json:"asks"`
+	Bids [][]interface{} `json:"bids"`
+}
+
+// krakenAssetAliases 通用币种代码与Kraken币种代码的对应关系
+var krakenAssetAliases = map[string]string{
+	"BTC":  "XBT",
+	"DOGE": "XDG",
+}
+
+// knownQuotes 用于拆分Kraken交易对名称的计价币种（按长度从长到短匹配）
+var knownQuotes = []string{
+	"USDT", "USDC", "ZUSD", "ZEUR", "ZGBP", "ZJPY", "ZCAD", "ZAUD", "ZCHF",
+	"XXBT", "XETH", "DAI", "USD", "EUR", "GBP", "JPY", "CAD", "AUD", "CHF", "XBT", "ETH",
+}
+
+// toKrakenPair 将通用交易对符号（如BTCUSDT、BTC/USD）转换为Kraken请求使用的交易对名称（如XBTUSDT、XBTUSD）
+func toKrakenPair(symbol types.Symbol) (string, error) {
+	pair, err := symbol.ToPair()
+	if err != nil {
+		return "", err
+	}
+	return formatKrakenPair(pair), nil
+}
+
+// formatKrakenPair 将交易对转换为Kraken交易对名称，币种使用Kraken代码（如BTC/USD -> XBTUSD）
+func formatKrakenPair(pair currency.Pair) string {
+	return toKrakenAsset(pair.Base.Upper().String()) + toKrakenAsset(pair.Quote.Upper().String())
+}
+
+// toKrakenAsset 将通用币种代码转换为Kraken币种代码
+func toKrakenAsset(code string) string {
+	if alias, ok := krakenAssetAliases[code]; ok {
+		return alias
+	}
+	return code
+}
+
+// fromKrakenAsset 将Kraken币种代码（包括X/Z前缀的旧式代码，如XXBT、ZUSD）转换为通用币种代码
+func fromKrakenAsset(code string) string {
+	code = strings.ToUpper(code)
+	if len(code) == 4 && (code[0] == 'X' || code[0] == 'Z') {
+		code = code[1:]
+	}
+	for common, alias := range krakenAssetAliases {
+		if code == alias {
+			return common
+		}
+	}
+	return code
+}
+
+// PairToSymbol 将Kraken交易对名称（如XXBTZUSD、XBTUSDT、ETHUSD）转换为通用交易对符号（如BTCUSD）
+func PairToSymbol(krakenPair string) types.Symbol {
+	name := strings.ToUpper(krakenPair)
+	for _, quote := range knownQuotes {
+		if len(name) > len(quote) && strings.HasSuffix(name, quote) {
+			return types.Symbol(fromKrakenAsset(name[:len(name)-len(quote)]) + fromKrakenAsset(quote))
+		}
+	}
+	return types.Symbol(name)
+}
+
+// klineIntervals 通用K线周期与Kraken OHLC周期（分钟）的对应关系
+var klineIntervals = map[string]int{
+	"1m":  1,
+	"5m":  5,
+	"15m": 15,
+	"30m": 30,
+	"1h":  60,
+	"4h":  240,
+	"1d":  1440,
+	"1w":  10080,
+	"15d": 21600,
+}
+
+// toKrakenInterval 将通用K线周期转换为Kraken OHLC周期（分钟）
+func toKrakenInterval(interval string) (int, error) {
+	minutes, ok := klineIntervals[interval]
+	if !ok {
+		return 0, fmt.Errorf("unsupported kline interval for Kraken: %s", interval)
+	}
+	return minutes, nil
+}
+
+// toFloat64 将Kraken返回的数值（字符串或数字）转换为float64
+func toFloat64(v interface{}) float64 {
+	switch val := v.(type) {
+	case string:
+		f, err := strconv.ParseFloat(val, 64)
+		if err != nil {
+			return 0
+		}
+		return f
+	case float64:
+		return val
+	case json.Number:
+		f, _ := val.Float64()
+		return f
+	default:
+		return 0
+	}
+}
+
+// firstFloat 获取字符串数组中指定位置的数值
+func firstFloat(values []string, index int) float64 {
+	if index >= len(values) {
+		return 0
+	}
+	return toFloat64(values[index])
+}
@@ -0,0 +1,495 @@
+// Package mock 提供用于测试的交易所实现，返回确定性的模拟数据，
+// 支持注入错误和延迟，无需访问网络即可测试调度器和频控管理器
+package mock
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/asset"
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// 可注入错误和统计调用次数的方法名称
+const (
+	MethodGetTicker             = "GetTicker"
+	MethodGetOrderbook          = "GetOrderbook"
+	MethodGetTrades             = "GetTrades"
+	MethodGetKlines             = "GetKlines"
+	MethodGetMultipleTickers    = "GetMultipleTickers"
+	MethodGetMultipleOrderbooks = "GetMultipleOrderbooks"
+	MethodGetTimeAndWeight      = "GetTimeAndWeight"
+	MethodGetAvgPrice           = "GetAvgPrice"
+	MethodGetMultipleStats24h   = "GetMultipleStats24h"
+	MethodGetMultiplePrices     = "GetMultiplePrices"
+	MethodGetKlinesSince        = "GetKlinesSince"
+)
+
+// BaseTime 模拟数据使用的基准时间，保证每次返回的数据完全一致
+var BaseTime = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+// klineHistory GetKlinesSince可返回的模拟K线总数
+const klineHistory = 100
+
+// 确保Exchange实现了通用交易所接口
+var _ types.ExchangeInterface = (*Exchange)(nil)
+
+// 确保Exchange实现了统计数据接口
+var _ types.StatsProvider = (*Exchange)(nil)
+
+// 确保Exchange实现了批量价格接口
+var _ types.PriceProvider = (*Exchange)(nil)
+
+// 确保Exchange实现了增量K线接口
+var _ types.KlineRangeProvider = (*Exchange)(nil)
+
+// Exchange 模拟交易所
+type Exchange struct {
+	mu      sync.Mutex
+	name    types.Exchange
+	latency time.Duration             // 每次请求的模拟延迟
+	errors  map[string]error          // 方法名或"方法名:交易对" -> 注入的错误
+	calls   map[string]int            // 方法名 -> 调用次数
+	symbols map[string][]types.Symbol // 方法名 -> 按调用顺序记录的交易对
+	weight  int                       // GetTimeAndWeight返回的已使用权重
+	assets  asset.Items               // 支持的资产类型，为空时支持所有资产类型
+
+	subscriptions map[types.DataType][]types.Symbol // 订阅记录
+}
+
+// New 创建模拟交易所
+func New(name types.Exchange) *Exchange {
+	return &Exchange{
+		name:          name,
+		errors:        make(map[string]error),
+		calls:         make(map[string]int),
+		symbols:       make(map[string][]types.Symbol),
+		subscriptions: make(map[types.DataType][]types.Symbol),
+	}
+}
+
+// SetLatency 设置每次请求的模拟延迟
+func (e *Exchange) SetLatency(latency time.Duration) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.latency = latency
+}
+
+// SetError 为方法注入错误，symbol为空时对该方法的所有交易对生效，err为nil时清除
+func (e *Exchange) SetError(method string, symbol types.Symbol, err error) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	key := errorKey(method, symbol)
+	if err == nil {
+		delete(e.errors, key)
+		return
+	}
+	e.errors[key] = err
+}
+
+// SetWeight 设置GetTimeAndWeight返回的已使用权重
+func (e *Exchange) SetWeight(weight int) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.weight = weight
+}
+
+// SetSupportedAssets 设置支持的资产类型，不设置时支持所有资产类型
+func (e *Exchange) SetSupportedAssets(assets ...asset.Item) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.assets = assets
+}
+
+// IsAssetSupported 检查是否支持指定资产类型
+func (e *Exchange) IsAssetSupported(assetType asset.Item) bool {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return len(e.assets) == 0 || e.assets.Contains(assetType)
+}
+
+// CallCount 获取方法的调用次数
+func (e *Exchange) CallCount(method string) int {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.calls[method]
+}
+
+// CalledSymbols 获取方法按调用顺序请求过的交易对
+func (e *Exchange) CalledSymbols(method string) []types.Symbol {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]types.Symbol(nil), e.symbols[method]...)
+}
+
+// Subscriptions 获取指定数据类型的订阅记录
+func (e *Exchange) Subscriptions(dataType types.DataType) []types.Symbol {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return append([]types.Symbol(nil), e.subscriptions[dataType]...)
+}
+
+// errorKey 生成错误注入的键
+func errorKey(method string, symbol types.Symbol) string {
+	if symbol == "" {
+		return method
+	}
+	return method + ":" + string(symbol)
+}
+
+// call 记录调用，模拟延迟，并返回注入的错误（交易对级别的错误优先）
+func (e *Exchange) call(ctx context.Context, method string, symbol types.Symbol) error {
+	e.mu.Lock()
+	e.calls[method]++
+	if symbol != "" {
+		e.symbols[method] = append(e.symbols[method], symbol)
+	}
+	latency := e.latency
+	err, ok := e.errors[errorKey(method, symbol)]
+	if !ok {
+		err = e.errors[method]
+	}
+	e.mu.Unlock()
+
+	if latency > 0 {
+		timer := time.NewTimer(latency)
+		defer timer.Stop()
+		select {
+		case <-timer.C:
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+	return err
+}
+
+// GetName 返回交易所名称
+func (e *Exchange) GetName() types.Exchange {
+	return e.name
+}
+
+// Initialize 初始化交易所（无操作）
+func (e *Exchange) Initialize(config interface{}) error {
+	return nil
+}
+
+// Close 关闭交易所（无操作）
+func (e *Exchange) Close() error {
+	return nil
+}
+
+// GetTicker 返回模拟行情数据
+func (e *Exchange) GetTicker(ctx context.Context, symbol types.Symbol) (*types.Ticker, error) {
+	if err := e.call(ctx, MethodGetTicker, symbol); err != nil {
+		return nil, err
+	}
+	ticker := e.ticker(symbol)
+	return &ticker, nil
+}
+
+// GetOrderbook 返回指定深度的模拟订单簿
+func (e *Exchange) GetOrderbook(ctx context.Context, symbol types.Symbol, depth int) (*types.Orderbook, error) {
+	if err := e.call(ctx, MethodGetOrderbook, symbol); err != nil {
+		return nil, err
+	}
+	orderbook := e.orderbook(symbol, depth)
+	return &orderbook, nil
+}
+
+// GetTrades 返回limit条模拟成交数据
+func (e *Exchange) GetTrades(ctx context.Context, symbol types.Symbol, limit int) ([]types.Trade, error) {
+	if err := e.call(ctx, MethodGetTrades, symbol); err != nil {
+		return nil, err
+	}
+
+	trades := make([]types.Trade, limit)
+	for i := range trades {
+		side := "buy"
+		if i%2 == 1 {
+			side = "sell"
+		}
+		trades[i] = types.Trade{
+			Exchange:  e.name,
+			Symbol:    symbol,
+			ID:        fmt.Sprintf("%d", i+1),
+			Price:     100 + float64(i)*0.1,
+			Quantity:  1,
+			Side:      side,
+			Timestamp: BaseTime.Add(time.Duration(i) * time.Second),
+		}
+	}
+	return trades, nil
+}
+
+// GetKlines 返回limit根连续的模拟K线
+func (e *Exchange) GetKlines(ctx context.Context, symbol types.Symbol, interval string, limit int) ([]types.Kline, error) {
+	if err := e.call(ctx, MethodGetKlines, symbol); err != nil {
+		return nil, err
+	}
+
+	klines := make([]types.Kline, limit)
+	for i := range klines {
+		klines[i] = e.kline(symbol, interval, i)
+	}
+	return klines, nil
+}
+
+// GetKlinesSince 返回开盘时间不早于startTime的模拟K线。模拟行情共有klineHistory根K线
+// （从BaseTime开始），最新一根与GetKlines(limit=klineHistory)的最后一根相同
+func (e *Exchange) GetKlinesSince(ctx context.Context, symbol types.Symbol, interval string, startTime time.Time, limit int) ([]types.Kline, error) {
+	if err := e.call(ctx, MethodGetKlinesSince, symbol); err != nil {
+		return nil, err
+	}
+
+	var klines []types.Kline
+	for i := 0; i < klineHistory && len(klines) < limit; i++ {
+		kline := e.kline(symbol, interval, i)
+		if !kline.OpenTime.Before(startTime) {
+			klines = append(klines, kline)
+		}
+	}
+	return klines, nil
+}
+
+// GetMultipleTickers 批量返回模拟行情，单个交易对注入的错误通过*types.BatchError返回
+func (e *Exchange) GetMultipleTickers(ctx context.Context, symbols []types.Symbol) ([]types.Ticker, error) {
+	if err := e.call(ctx, MethodGetMultipleTickers, ""); err != nil {
+		return nil, err
+	}
+
+	tickers := make([]types.Ticker, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		if err := e.symbolError(MethodGetMultipleTickers, symbol); err != nil {
+			batchErr.Add(symbol, err)
+			continue
+		}
+		tickers = append(tickers, e.ticker(symbol))
+	}
+	return tickers, batchErr.ErrOrNil()
+}
+
+// GetMultipleOrderbooks 批量返回模拟订单簿，单个交易对注入的错误通过*types.BatchError返回
+func (e *Exchange) GetMultipleOrderbooks(ctx context.Context, symbols []types.Symbol, depth int) ([]types.Orderbook, error) {
+	if err := e.call(ctx, MethodGetMultipleOrderbooks, ""); err != nil {
+		return nil, err
+	}
+
+	orderbooks := make([]types.Orderbook, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		if err := e.symbolError(MethodGetMultipleOrderbooks, symbol); err != nil {
+			batchErr.Add(symbol, err)
+			continue
+		}
+		orderbooks = append(orderbooks, e.orderbook(symbol, depth))
+	}
+	return orderbooks, batchErr.ErrOrNil()
+}
+
+// GetMultiplePrices 批量返回只包含价格的模拟行情，单个交易对注入的错误通过*types.BatchError返回
+func (e *Exchange) GetMultiplePrices(ctx context.Context, symbols []types.Symbol) ([]types.Ticker, error) {
+	if err := e.call(ctx, MethodGetMultiplePrices, ""); err != nil {
+		return nil, err
+	}
+
+	tickers := make([]types.Ticker, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		if err := e.symbolError(MethodGetMultiplePrices, symbol); err != nil {
+			batchErr.Add(symbol, err)
+			continue
+		}
+		tickers = append(tickers, types.Ticker{
+			Exchange:  e.name,
+			Symbol:    symbol,
+			Price:     100,
+			Timestamp: BaseTime,
+		})
+	}
+	return tickers, batchErr.ErrOrNil()
+}
+
+// GetAvgPrice 返回模拟的5分钟平均价格
+func (e *Exchange) GetAvgPrice(ctx context.Context, symbol types.Symbol) (*types.AvgPrice, error) {
+	if err := e.call(ctx, MethodGetAvgPrice, symbol); err != nil {
+		return nil, err
+	}
+	return &types.AvgPrice{
+		Exchange:  e.name,
+		Symbol:    symbol,
+		Minutes:   5,
+		Price:     100,
+		Timestamp: BaseTime,
+	}, nil
+}
+
+// GetMultipleStats24h 批量返回模拟的24小时统计，单个交易对注入的错误通过*types.BatchError返回
+func (e *Exchange) GetMultipleStats24h(ctx context.Context, symbols []types.Symbol) ([]types.Stats24h, error) {
+	if err := e.call(ctx, MethodGetMultipleStats24h, ""); err != nil {
+		return nil, err
+	}
+
+	stats := make([]types.Stats24h, 0, len(symbols))
+	batchErr := types.NewBatchError()
+	for _, symbol := range symbols {
+		if err := e.symbolError(MethodGetMultipleStats24h, symbol); err != nil {
+			batchErr.Add(symbol, err)
+			continue
+		}
+		stats = append(stats, types.Stats24h{
+			Exchange:           e.name,
+			Symbol:             symbol,
+			PriceChange:        1.5,
+			PriceChangePercent: 1.52,
+			WeightedAvgPrice:   99.5,
+			PrevClosePrice:     98.5,
+			LastPrice:          100,
+			OpenPrice:          98.5,
+			HighPrice:          110,
+			LowPrice:           90,
+			Volume:             1000,
+			QuoteVolume:        100000,
+			TradeCount:         500,
+			OpenTime:           BaseTime.Add(-24 * time.Hour),
+			CloseTime:          BaseTime,
+		})
+	}
+	return stats, batchErr.ErrOrNil()
+}
+
+// GetTimeAndWeight 返回基准时间和设置的已使用权重，供频控管理器测试使用
+func (e *Exchange) GetTimeAndWeight(ctx context.Context) (int64, int, error) {
+	if err := e.call(ctx, MethodGetTimeAndWeight, ""); err != nil {
+		return 0, 0, err
+	}
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return BaseTime.UnixMilli(), e.weight, nil
+}
+
+// symbolError 获取为交易对注入的错误
+func (e *Exchange) symbolError(method string, symbol types.Symbol) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	return e.errors[errorKey(method, symbol)]
+}
+
+// ticker 生成模拟行情
+func (e *Exchange) ticker(symbol types.Symbol) types.Ticker {
+	return types.Ticker{
+		Exchange:  e.name,
+		Symbol:    symbol,
+		Price:     100,
+		Volume:    1000,
+		High24h:   110,
+		Low24h:    90,
+		Change24h: 1.5,
+		Timestamp: BaseTime,
+	}
+}
+
+// kline 生成第i根模拟K线（从BaseTime开始连续）
+func (e *Exchange) kline(symbol types.Symbol, interval string, i int) types.Kline {
+	duration, err := time.ParseDuration(interval)
+	if err != nil {
+		duration = time.Minute
+	}
+
+	openTime := BaseTime.Add(time.Duration(i) * duration)
+	price := 100 + float64(i)
+	return types.Kline{
+		Exchange:   e.name,
+		Symbol:     symbol,
+		Interval:   interval,
+		OpenTime:   openTime,
+		CloseTime:  openTime.Add(duration - time.Millisecond),
+		OpenPrice:  price,
+		HighPrice:  price + 1,
+		LowPrice:   price - 1,
+		ClosePrice: price + 0.5,
+		Volume:     10,
+		TradeCount: 5,
+	}
+}
+
+// orderbook 生成指定深度的模拟订单簿
+func (e *Exchange) orderbook(symbol types.Symbol, depth int) types.Orderbook {
+	orderbook := types.Orderbook{
+		Exchange:  e.name,
+		Symbol:    symbol,
+		Bids:      make([]types.OrderbookEntry, depth),
+		Asks:      make([]types.OrderbookEntry, depth),
+		Timestamp: BaseTime,
+	}
+	for i := 0; i < depth; i++ {
+		orderbook.Bids[i] = types.OrderbookEntry{Price: 100 - float64(i+1)*0.1, Quantity: 1}
+		orderbook.Asks[i] = types.OrderbookEntry{Price: 100 + float64(i+1)*0.1, Quantity: 1}
+	}
+	return orderbook
+}
+
+// SubscribeTicker 记录行情订阅
+func (e *Exchange) SubscribeTicker(symbols []types.Symbol, callback types.DataCallback) error {
+	return e.subscribe(types.DataTypeTicker, symbols)
+}
+
+// SubscribeOrderbook 记录订单簿订阅
+func (e *Exchange) SubscribeOrderbook(symbols []types.Symbol, callback types.DataCallback) error {
+	return e.subscribe(types.DataTypeOrderbook, symbols)
+}
+
+// SubscribeTrades 记录成交订阅
+func (e *Exchange) SubscribeTrades(symbols []types.Symbol, callback types.DataCallback) error {
+	return e.subscribe(types.DataTypeTrades, symbols)
+}
+
+// SubscribeKlines 记录K线订阅
+func (e *Exchange) SubscribeKlines(symbols []types.Symbol, intervals []string, callback types.DataCallback) error {
+	return e.subscribe(types.DataTypeKlines, symbols)
+}
+
+// subscribe 记录订阅
+func (e *Exchange) subscribe(dataType types.DataType, symbols []types.Symbol) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscriptions[dataType] = append(e.subscriptions[dataType], symbols...)
+	return nil
+}
+
+// UnsubscribeAll 清除所有订阅记录
+func (e *Exchange) UnsubscribeAll() error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+	e.subscriptions = make(map[types.DataType][]types.Symbol)
+	return nil
+}
+
+// IsConnected 始终返回true
+func (e *Exchange) IsConnected() bool {
+	return true
+}
+
+// GetLastPing 返回基准时间
+func (e *Exchange) GetLastPing() time.Time {
+	return BaseTime
+}
+
+// GetRateLimit 返回模拟的速率限制
+func (e *Exchange) GetRateLimit() *types.RateLimit {
+	return &types.RateLimit{RequestsPerMinute: 1200}
+}
+
+// FormatSymbol 返回统一格式（大写、不带分隔符）的交易对符号
+func (e *Exchange) FormatSymbol(pair currency.Pair) (string, error) {
+	return string(types.PairToSymbol(pair)), nil
+}
+
+// CheckRateLimit 不做限制
+func (e *Exchange) CheckRateLimit() error {
+	return nil
+}
@@ -0,0 +1,156 @@
+package ipmanager
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"math/rand/v2"
+	"net"
+	"strings"
+	"time"
+)
+
+// maxDNSMessageSize UDP DNS响应的最大读取长度
+const maxDNSMessageSize = 4096
+
+// errDNSMessage 响应无法按最简格式解析（截断、格式异常等），调用方应回退到标准解析器
+var errDNSMessage = errors.New("unsupported DNS response")
+
+// queryA 通过UDP向指定DNS服务器查询域名的A记录，返回IPv4地址和应答记录中最小的TTL。
+// 标准库的net.Resolver不暴露TTL，这里直接构造和解析DNS报文
+func queryA(ctx context.Context, hostname, dnsServer string) ([]string, time.Duration, error) {
+	id := uint16(rand.Uint32())
+	query, err := buildAQuery(id, hostname)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", dnsServer)
+	if err != nil {
+		return nil, 0, err
+	}
+	defer conn.Close()
+	if deadline, ok := ctx.Deadline(); ok {
+		conn.SetDeadline(deadline)
+	}
+
+	if _, err := conn.Write(query); err != nil {
+		return nil, 0, err
+	}
+
+	buf := make([]byte, maxDNSMessageSize)
+	for {
+		n, err := conn.Read(buf)
+		if err != nil {
+			return nil, 0, err
+		}
+		// 忽略ID不匹配的报文（可能是之前超时查询的迟到响应）
+		if n >= 2 && binary.BigEndian.Uint16(buf) != id {
+			continue
+		}
+		return parseAResponse(buf[:n])
+	}
+}
+
+// buildAQuery 构造递归查询A记录的DNS请求报文
+func buildAQuery(id uint16, hostname string) ([]byte, error) {
+	msg := make([]byte, 12, 12+len(hostname)+6)
+	binary.BigEndian.PutUint16(msg[0:], id)
+	binary.BigEndian.PutUint16(msg[2:], 0x0100) // RD
+	binary.BigEndian.PutUint16(msg[4:], 1)      // QDCOUNT
+
+	for _, label := range strings.Split(strings.TrimSuffix(hostname, "."), ".") {
+		if len(label) == 0 || len(label) > 63 {
+			return nil, fmt.Errorf("invalid hostname %q", hostname)
+		}
+		msg = append(msg, byte(len(label)))
+		msg = append(msg, label...)
+	}
+	msg = append(msg, 0)
+	msg = binary.BigEndian.AppendUint16(msg, dnsTypeA)
+	msg = binary.BigEndian.AppendUint16(msg, 1) // IN
+	return msg, nil
+}
+
+// parseAResponse 解析DNS响应报文中的A记录，跳过CNAME等其他记录
+func parseAResponse(msg []byte) ([]string, time.Duration, error) {
+	if len(msg) < 12 {
+		return nil, 0, fmt.Errorf("%w: short message", errDNSMessage)
+	}
+	flags := binary.BigEndian.Uint16(msg[2:])
+	if flags&0x8000 == 0 {
+		return nil, 0, fmt.Errorf("%w: not a response", errDNSMessage)
+	}
+	if flags&0x0200 != 0 {
+		return nil, 0, fmt.Errorf("%w: truncated", errDNSMessage)
+	}
+	if rcode := flags & 0x000f; rcode != 0 {
+		return nil, 0, fmt.Errorf("DNS server returned rcode %d", rcode)
+	}
+	qdCount := int(binary.BigEndian.Uint16(msg[4:]))
+	anCount := int(binary.BigEndian.Uint16(msg[6:]))
+
+	off := 12
+	var ok bool
+	for range qdCount {
+		if off, ok = skipDNSName(msg, off); !ok || off+4 > len(msg) {
+			return nil, 0, fmt.Errorf("%w: malformed question", errDNSMessage)
+		}
+		off += 4
+	}
+
+	var ips []string
+	var ttl time.Duration
+	for range anCount {
+		if off, ok = skipDNSName(msg, off); !ok || off+10 > len(msg) {
+			return nil, 0, fmt.Errorf("%w: malformed answer", errDNSMessage)
+		}
+		rrType := binary.BigEndian.Uint16(msg[off:])
+		rrClass := binary.BigEndian.Uint16(msg[off+2:])
+		rrTTL := time.Duration(binary.BigEndian.Uint32(msg[off+4:])) * time.Second
+		rdLen := int(binary.BigEndian.Uint16(msg[off+8:]))
+		off += 10
+		if off+rdLen > len(msg) {
+			return nil, 0, fmt.Errorf("%w: malformed answer", errDNSMessage)
+		}
+		if rrType == dnsTypeA && rrClass == 1 && rdLen == net.IPv4len {
+			ips = append(ips, net.IP(msg[off:off+rdLen]).String())
+			ttl = minTTL(ttl, rrTTL)
+		}
+		off += rdLen
+	}
+	return ips, ttl, nil
+}
+
+// skipDNSName 跳过报文中off处的域名（支持压缩指针），返回其后的偏移
+func skipDNSName(msg []byte, off int) (int, bool) {
+	for off < len(msg) {
+		length := int(msg[off])
+		switch {
+		case length == 0:
+			return off + 1, true
+		case length&0xc0 == 0xc0:
+			if off+2 > len(msg) {
+				return 0, false
+			}
+			return off + 2, true
+		case length&0xc0 != 0:
+			return 0, false
+		}
+		off += 1 + length
+	}
+	return 0, false
+}
+
+// minTTL 返回两个TTL中较小的一个，0表示未知，不参与比较
+func minTTL(a, b time.Duration) time.Duration {
+	if a <= 0 {
+		return b
+	}
+	if b <= 0 {
+		return a
+	}
+	return min(a, b)
+}
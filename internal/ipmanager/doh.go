@@ -0,0 +1,108 @@
+package ipmanager
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// DefaultDoHServers 默认的DNS-over-HTTPS服务器，均支持JSON格式的查询接口
+var DefaultDoHServers = []string{
+	"https://dns.google/resolve",           // Google DoH
+	"https://cloudflare-dns.com/dns-query", // Cloudflare DoH
+}
+
+// DNS记录类型
+const (
+	dnsTypeA    = 1
+	dnsTypeAAAA = 28
+)
+
+// maxDoHResponseSize DoH响应体的最大读取长度
+const maxDoHResponseSize = 64 << 10
+
+// dohResponse DoH JSON接口的响应（Google和Cloudflare格式相同）
+type dohResponse struct {
+	Status int `json:"Status"` // DNS响应码，0为NOERROR
+	Answer []struct {
+		Name string `json:"name"`
+		Type int    `json:"type"`
+		TTL  int    `json:"TTL"`
+		Data string `json:"data"`
+	} `json:"Answer"`
+}
+
+// resolveWithDoH 使用DNS-over-HTTPS服务器的JSON接口解析域名的A记录，
+// 用于UDP DNS被屏蔽或劫持的网络，同时返回记录中最小的TTL
+func (m *Manager) resolveWithDoH(ctx context.Context, hostname, server string) ([]string, time.Duration, error) {
+	m.getLogger().Debugf("Resolving %s using DoH server %s", hostname, server)
+
+	ctx, cancel := context.WithTimeout(ctx, m.dnsTimeout)
+	defer cancel()
+
+	endpoint, err := url.Parse(server)
+	if err != nil {
+		return nil, 0, fmt.Errorf("invalid DoH server %q: %w", server, err)
+	}
+	query := endpoint.Query()
+	query.Set("name", hostname)
+	query.Set("type", "A")
+	endpoint.RawQuery = query.Encode()
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, endpoint.String(), nil)
+	if err != nil {
+		return nil, 0, fmt.Errorf("failed to create DoH request: %w", err)
+	}
+	// Cloudflare需要通过Accept头选择JSON格式
+	req.Header.Set("Accept", "application/dns-json")
+
+	resp, err := m.dohClient.Do(req)
+	if err != nil {
+		m.getLogger().Warnf("DoH resolution failed for %s using %s: %v", hostname, server, err)
+		return nil, 0, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, 0, fmt.Errorf("DoH server %s returned HTTP %d", server, resp.StatusCode)
+	}
+
+	var result dohResponse
+	if err := json.NewDecoder(io.LimitReader(resp.Body, maxDoHResponseSize)).Decode(&result); err != nil {
+		return nil, 0, fmt.Errorf("failed to decode DoH response from %s: %w", server, err)
+	}
+	if result.Status != 0 {
+		return nil, 0, fmt.Errorf("DoH server %s returned DNS status %d for %s", server, result.Status, hostname)
+	}
+
+	ips, ttl := parseDoHAnswers(result)
+	if len(ips) == 0 {
+		return nil, 0, fmt.Errorf("no IPv4 addresses found for %s using DoH %s", hostname, server)
+	}
+
+	m.getLogger().Infof("Successfully resolved %s to %v using DoH %s (ttl: %v)", hostname, ips, server, ttl)
+	return ips, ttl, nil
+}
+
+// parseDoHAnswers 从应答中提取A/AAAA记录的地址，跳过CNAME等其他记录；
+// 与UDP DNS解析一致，只使用IPv4地址，并返回这些记录中最小的TTL
+func parseDoHAnswers(result dohResponse) ([]string, time.Duration) {
+	var ips []string
+	var ttl time.Duration
+	for _, answer := range result.Answer {
+		if answer.Type != dnsTypeA && answer.Type != dnsTypeAAAA {
+			continue
+		}
+		ip := net.ParseIP(answer.Data)
+		if ip == nil || ip.To4() == nil {
+			continue
+		}
+		ips = append(ips, ip.String())
+		ttl = minTTL(ttl, time.Duration(answer.TTL)*time.Second)
+	}
+	return ips, ttl
+}
@@ -0,0 +1,17 @@
+package ipmanager
+
+import "errors"
+
+// 导出的哨兵错误，调用方可使用errors.Is进行判断
+var (
+	// ErrNoAvailableIPs 没有可用的IP地址
+	ErrNoAvailableIPs = errors.New("no available IPs")
+	// ErrManagerNotRunning IP管理器未运行
+	ErrManagerNotRunning = errors.New("IP manager not running")
+	// ErrManagerAlreadyRunning IP管理器已在运行
+	ErrManagerAlreadyRunning = errors.New("IP manager is already running")
+	// ErrNotEnoughHealthyIPs 等待超时仍未达到要求的健康IP数量
+	ErrNotEnoughHealthyIPs = errors.New("not enough healthy IPs")
+	// ErrInvalidIP IP地址格式无效
+	ErrInvalidIP = errors.New("invalid IP address")
+)
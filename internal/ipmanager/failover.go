@@ -0,0 +1,74 @@
+package ipmanager
+
+import (
+	"maps"
+	"time"
+)
+
+// failoverStats IP故障转移和请求成功的统计（由Manager.mu保护）
+type failoverStats struct {
+	total        uint64               // 故障转移总次数
+	pairs        map[string]uint64    // 按"原IP->新IP"统计的故障转移次数
+	lastFailover time.Time            // 最近一次故障转移时间
+	lastSuccess  map[string]time.Time // 每个IP最近一次请求成功的时间
+}
+
+// failoverPairKey 故障转移IP对的统计键
+func failoverPairKey(from, to string) string {
+	return from + "->" + to
+}
+
+// recordFailoverLocked 记录一次故障转移（调用时需要持有锁）
+func (m *Manager) recordFailoverLocked(from, to string) {
+	if m.failover.pairs == nil {
+		m.failover.pairs = make(map[string]uint64)
+	}
+	m.failover.total++
+	m.failover.pairs[failoverPairKey(from, to)]++
+	m.failover.lastFailover = time.Now()
+}
+
+// ReportSuccess 记录通过指定IP的请求或连接成功，用于在状态中展示每个IP距最近一次成功的时间
+func (m *Manager) ReportSuccess(ip string) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if indexOf(m.ips, ip) < 0 {
+		return
+	}
+	if m.failover.lastSuccess == nil {
+		m.failover.lastSuccess = make(map[string]time.Time)
+	}
+	m.failover.lastSuccess[ip] = time.Now()
+}
+
+// GetFailoverStats 获取故障转移统计：总次数、按IP对的次数和最近一次故障转移时间
+func (m *Manager) GetFailoverStats() map[string]interface{} {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	stats := map[string]interface{}{
+		"hostname": m.hostname,
+		"total":    m.failover.total,
+		"pairs":    maps.Clone(m.failover.pairs),
+	}
+	if !m.failover.lastFailover.IsZero() {
+		stats["last_failover"] = m.failover.lastFailover.Format("2006-01-02 15:04:05")
+	}
+	return stats
+}
+
+// sinceLastSuccess 返回当前IP列表中每个IP距最近一次请求成功的时间，从未成功的IP不包含在内
+func (m *Manager) sinceLastSuccess() map[string]string {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	now := time.Now()
+	result := make(map[string]string, len(m.failover.lastSuccess))
+	for _, ip := range m.ips {
+		if t, ok := m.failover.lastSuccess[ip]; ok {
+			result[ip] = now.Sub(t).Round(time.Second).String()
+		}
+	}
+	return result
+}
@@ -4,13 +4,14 @@ package ipmanager
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"net"
+	"net/http"
 	"sort"
 	"sync"
+	"sync/atomic"
 	"time"
-
-	"github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
 )
 
 // IPInfo 存储IP地址及其延迟信息
@@ -24,38 +25,78 @@ type IPInfo struct {
 // Manager 管理域名对应的IP地址列表
 type Manager struct {
 	mu         sync.RWMutex
-	ips        []string  // 保持向后兼容
-	ipInfos    []*IPInfo // 新的IP信息列表
-	currentIdx int
+	ips        []string      // 保持向后兼容
+	ipInfos    []*IPInfo     // 新的IP信息列表
+	currentIP  string        // 故障转移时当前使用的IP，按IP值记录，IP列表排序或更新后不需要重置
+	rrCounter  atomic.Uint64 // 轮询计数器
 	hostname   string
 	updateChan chan struct{}
 	stopChan   chan struct{}
 	isRunning  bool
+	wg         sync.WaitGroup // 更新、延迟检测等后台协程，Stop时等待退出
+	spawnMu    sync.Mutex     // 保护stopped，保证Stop开始等待后不再有新的后台协程加入wg
+	stopped    bool           // 已调用Stop，之后不再启动后台协程
+
+	// IP列表更新监听器
+	listenersMu    sync.Mutex
+	listeners      map[uint64]func(ips []string)
+	nextListenerID uint64
+
+	// 故障转移统计
+	failover failoverStats
 
 	// 配置选项
 	updateInterval time.Duration
 	dnsServers     []string
+	dohServers     []string     // DNS-over-HTTPS服务器
+	preferDoH      bool         // 只使用DoH解析
+	dohClient      *http.Client // DoH查询使用的HTTP客户端
 	dnsTimeout     time.Duration
+	retryMinDelay  time.Duration // 更新失败后的首次重试延迟
+	retryMaxDelay  time.Duration // 更新失败后的最大重试延迟
+	staticIPs      []string      // 静态IP列表，设置后不再进行DNS解析
+	minHealthyIPs  int           // 就绪所需的最少健康IP数量
+	readyTimeout   time.Duration // 启动时等待健康IP的超时时间
+	dnsTTL         time.Duration // 最近一次解析结果中最小的TTL，0表示未知
 
 	// 延迟检测配置
 	enableLatencyCheck   bool          // 是否启用延迟检测
 	latencyCheckInterval time.Duration // 延迟检测间隔
 	latencyTimeout       time.Duration // 延迟检测超时
 	latencyPort          string        // 用于延迟检测的端口
+	latencyConcurrency   int           // 延迟检测的最大并发连接数
+	latencyCheckDeadline time.Duration // 一轮延迟检测的总时限
+
+	logger Logger
 }
 
 // Config IP管理器配置
 type Config struct {
 	Hostname       string        // 要解析的域名
-	UpdateInterval time.Duration // 更新间隔，默认5分钟
+	UpdateInterval time.Duration // 更新间隔，默认5分钟；DNS记录的TTL更短时按TTL更新
 	DNSServers     []string      // DNS服务器列表
 	DNSTimeout     time.Duration // DNS查询超时时间，默认5秒
+	DoHServers     []string      // DNS-over-HTTPS服务器（JSON接口），UDP DNS全部解析失败时使用，为空时不使用DoH
+	PreferDoH      bool          // 只使用DoH解析，不再尝试UDP DNS（用于UDP DNS被屏蔽或劫持的网络）
+	StaticIPs      []string      // 静态IP列表，设置后禁用DNS解析（仍会进行延迟检测）
+
+	// 就绪配置：Start等待至少MinHealthyIPs个IP通过延迟检测（未启用延迟检测时为解析到的IP数量）
+	MinHealthyIPs int           // 就绪所需的最少健康IP数量，0表示不等待
+	ReadyTimeout  time.Duration // 等待健康IP的超时时间，默认30秒，超时后Start返回错误
+
+	// 更新失败重试配置（解析不到任何IP时按指数退避快速重试，而不是等待完整的更新间隔）
+	RetryMinDelay time.Duration // 首次重试延迟，默认2秒
+	RetryMaxDelay time.Duration // 最大重试延迟，默认1分钟（不超过更新间隔）
 
 	// 延迟检测配置
 	EnableLatencyCheck   bool          // 是否启用延迟检测，默认true
 	LatencyCheckInterval time.Duration // 延迟检测间隔，默认30秒
 	LatencyTimeout       time.Duration // 延迟检测超时，默认3秒
 	LatencyPort          string        // 用于延迟检测的端口，默认443
+	LatencyConcurrency   int           // 延迟检测的最大并发连接数，默认3
+	LatencyCheckDeadline time.Duration // 一轮延迟检测的总时限，默认为检测间隔的一半，超时未检测的IP保留原有状态
+
+	Logger Logger // 日志实现，默认输出到cryptotrader的log包
 }
 
 // DefaultConfig 返回默认配置
@@ -69,12 +110,19 @@ func DefaultConfig(hostname string) *Config {
 			"208.67.222.222:53", // OpenDNS
 		},
 		DNSTimeout: 5 * time.Second,
+		DoHServers: DefaultDoHServers,
+
+		// 更新失败重试默认配置
+		RetryMinDelay: 2 * time.Second,
+		RetryMaxDelay: time.Minute,
 
 		// 延迟检测默认配置
 		EnableLatencyCheck:   true,
 		LatencyCheckInterval: 60 * time.Second, // 增加检测间隔，减少干扰
 		LatencyTimeout:       2 * time.Second,  // 减少超时时间
 		LatencyPort:          "80",             // HTTP端口，避免与HTTPS请求冲突
+		LatencyConcurrency:   3,                // 限制并发连接，避免触发入侵检测
+		LatencyCheckDeadline: 30 * time.Second, // 避免大量不可达IP拖慢整轮检测
 	}
 }
 
@@ -91,9 +139,30 @@ func New(config *Config) *Manager {
 	if config.DNSTimeout == 0 {
 		config.DNSTimeout = 5 * time.Second
 	}
+	if config.RetryMinDelay == 0 {
+		config.RetryMinDelay = 2 * time.Second
+	}
+	if config.RetryMaxDelay == 0 {
+		config.RetryMaxDelay = time.Minute
+	}
+	if config.RetryMaxDelay > config.UpdateInterval {
+		config.RetryMaxDelay = config.UpdateInterval
+	}
+	if config.RetryMinDelay > config.RetryMaxDelay {
+		config.RetryMinDelay = config.RetryMaxDelay
+	}
 	if len(config.DNSServers) == 0 {
 		config.DNSServers = DefaultConfig("").DNSServers
 	}
+	if config.PreferDoH && len(config.DoHServers) == 0 {
+		config.DoHServers = DefaultDoHServers
+	}
+	if config.Logger == nil {
+		config.Logger = defaultLogger
+	}
+	if config.ReadyTimeout <= 0 {
+		config.ReadyTimeout = 30 * time.Second
+	}
 	if config.LatencyCheckInterval == 0 {
 		config.LatencyCheckInterval = 30 * time.Second
 	}
@@ -103,20 +172,41 @@ func New(config *Config) *Manager {
 	if config.LatencyPort == "" {
 		config.LatencyPort = "80"
 	}
+	if config.LatencyConcurrency <= 0 {
+		config.LatencyConcurrency = 3
+	}
+	if config.LatencyCheckDeadline <= 0 {
+		config.LatencyCheckDeadline = config.LatencyCheckInterval / 2
+	}
+	if config.LatencyCheckDeadline < config.LatencyTimeout {
+		config.LatencyCheckDeadline = config.LatencyTimeout
+	}
 
 	return &Manager{
 		hostname:             config.Hostname,
 		ips:                  make([]string, 0),
 		ipInfos:              make([]*IPInfo, 0),
+		listeners:            make(map[uint64]func(ips []string)),
 		updateChan:           make(chan struct{}, 1),
 		stopChan:             make(chan struct{}),
 		updateInterval:       config.UpdateInterval,
 		dnsServers:           config.DNSServers,
+		dohServers:           config.DoHServers,
+		preferDoH:            config.PreferDoH,
+		dohClient:            &http.Client{Timeout: config.DNSTimeout},
 		dnsTimeout:           config.DNSTimeout,
+		retryMinDelay:        config.RetryMinDelay,
+		retryMaxDelay:        config.RetryMaxDelay,
+		staticIPs:            config.StaticIPs,
+		minHealthyIPs:        config.MinHealthyIPs,
+		readyTimeout:         config.ReadyTimeout,
 		enableLatencyCheck:   config.EnableLatencyCheck,
 		latencyCheckInterval: config.LatencyCheckInterval,
 		latencyTimeout:       config.LatencyTimeout,
 		latencyPort:          config.LatencyPort,
+		latencyConcurrency:   config.LatencyConcurrency,
+		latencyCheckDeadline: config.LatencyCheckDeadline,
+		logger:               config.Logger,
 	}
 }
 
@@ -125,42 +215,84 @@ func (m *Manager) Start(ctx context.Context) error {
 	m.mu.Lock()
 	if m.isRunning {
 		m.mu.Unlock()
-		return fmt.Errorf("IP manager is already running")
+		return fmt.Errorf("%w: %s", ErrManagerAlreadyRunning, m.hostname)
 	}
 	m.isRunning = true
 	m.mu.Unlock()
 
-	// 立即获取一次IP列表
-	if err := m.updateIPs(); err != nil {
-		log.Errorf(log.WebsocketMgr, "Failed to get initial IP list for %s: %v", m.hostname, err)
-		return err
-	}
+	if len(m.staticIPs) > 0 {
+		// 使用静态IP列表，不启动DNS解析
+		if err := m.SetIPs(m.staticIPs); err != nil {
+			m.getLogger().Errorf("Failed to set static IP list for %s: %v", m.hostname, err)
+			return err
+		}
+		m.getLogger().Infof("Using static IPs for %s, DNS resolution disabled", m.hostname)
+	} else {
+		// 立即获取一次IP列表
+		if err := m.updateIPs(ctx); err != nil {
+			m.getLogger().Errorf("Failed to get initial IP list for %s: %v", m.hostname, err)
+			return err
+		}
 
-	// 启动定时更新协程
-	go m.updateLoop(ctx)
+		// 启动定时更新协程
+		m.spawn(func() { m.updateLoop(ctx) })
+	}
 
 	// 如果启用延迟检测，启动延迟检测协程
 	if m.enableLatencyCheck {
-		go m.latencyCheckLoop(ctx)
-		log.Infof(log.WebsocketMgr, "Latency check enabled for hostname: %s", m.hostname)
+		m.spawn(func() { m.latencyCheckLoop(ctx) })
+		m.getLogger().Infof("Latency check enabled for hostname: %s", m.hostname)
+	}
+
+	// 等待足够数量的健康IP，避免只有一个（可能不可用的）IP时故障转移无IP可切换
+	if m.minHealthyIPs > 0 {
+		readyCtx, cancel := context.WithTimeout(ctx, m.readyTimeout)
+		defer cancel()
+		if err := m.WaitReady(readyCtx); err != nil {
+			m.getLogger().Errorf("IP Manager for %s is not ready: %v", m.hostname, err)
+			m.Stop()
+			return err
+		}
 	}
 
-	log.Infof(log.WebsocketMgr, "IP Manager started for hostname: %s", m.hostname)
+	m.getLogger().Infof("IP Manager started for hostname: %s", m.hostname)
 	return nil
 }
 
-// Stop 停止IP管理器
+// Stop 停止IP管理器，等待更新和延迟检测协程退出
 func (m *Manager) Stop() {
 	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if !m.isRunning {
+		m.mu.Unlock()
 		return
 	}
-
 	close(m.stopChan)
 	m.isRunning = false
-	log.Infof(log.WebsocketMgr, "IP Manager stopped for hostname: %s", m.hostname)
+	m.mu.Unlock()
+
+	// 与spawn使用同一把锁设置stopped，之后的spawn不再调用wg.Add，避免与wg.Wait并发
+	m.spawnMu.Lock()
+	m.stopped = true
+	m.spawnMu.Unlock()
+
+	// 后台协程退出前可能需要获取锁，释放锁后再等待
+	m.wg.Wait()
+	m.getLogger().Infof("IP Manager stopped for hostname: %s", m.hostname)
+}
+
+// spawn 启动由Stop等待退出的后台协程，已调用Stop时不再启动。
+// 调用方可能持有m.mu（如更新IP列表后触发延迟检测），因此使用单独的spawnMu
+func (m *Manager) spawn(fn func()) {
+	m.spawnMu.Lock()
+	defer m.spawnMu.Unlock()
+	if m.stopped {
+		return
+	}
+	m.wg.Add(1)
+	go func() {
+		defer m.wg.Done()
+		fn()
+	}()
 }
 
 // GetCurrentIP 获取当前可用的IP地址（优先返回延迟最低的IP）
@@ -169,14 +301,14 @@ func (m *Manager) GetCurrentIP() (string, error) {
 	defer m.mu.RUnlock()
 
 	if len(m.ips) == 0 {
-		return "", fmt.Errorf("no available IPs for hostname: %s", m.hostname)
+		return "", m.noIPsError()
 	}
 
 	// 如果启用了延迟检测且有延迟信息，返回延迟最低的可用IP
 	if m.enableLatencyCheck && len(m.ipInfos) > 0 {
 		for _, ipInfo := range m.ipInfos {
 			if ipInfo.Available {
-				log.Debugf(log.WebsocketMgr, "Using best latency IP: %s (latency: %v) for %s",
+				m.getLogger().Debugf("Using best latency IP: %s (latency: %v) for %s",
 					ipInfo.IP, ipInfo.Latency, m.hostname)
 				return ipInfo.IP, nil
 			}
@@ -184,28 +316,122 @@ func (m *Manager) GetCurrentIP() (string, error) {
 	}
 
 	// 回退到传统方式
-	ip := m.ips[m.currentIdx]
-	log.Debugf(log.WebsocketMgr, "Using IP: %s (index: %d/%d) for %s",
-		ip, m.currentIdx, len(m.ips)-1, m.hostname)
+	ip := m.currentIPLocked()
+	m.getLogger().Debugf("Using IP: %s (index: %d/%d) for %s",
+		ip, indexOf(m.ips, ip), len(m.ips)-1, m.hostname)
 	return ip, nil
 }
 
+// currentIPLocked 返回故障转移的当前IP，当前IP已不在列表中时返回列表中的第一个IP（调用时需要持有锁）
+func (m *Manager) currentIPLocked() string {
+	if indexOf(m.ips, m.currentIP) >= 0 {
+		return m.currentIP
+	}
+	return m.ips[0]
+}
+
+// failoverCandidates 返回故障转移的候选IP列表（调用时需要持有锁）。
+// 启用延迟检测时为按延迟排序的可用IP，没有可用IP或未启用延迟检测时为全部IP
+func (m *Manager) failoverCandidates() []string {
+	if m.enableLatencyCheck && len(m.ipInfos) > 0 {
+		candidates := make([]string, 0, len(m.ipInfos))
+		for _, ipInfo := range m.ipInfos {
+			if ipInfo.Available {
+				candidates = append(candidates, ipInfo.IP)
+			}
+		}
+		if len(candidates) > 0 {
+			return candidates
+		}
+	}
+	return m.ips
+}
+
+// indexOf 返回IP在列表中的位置，不存在时返回-1
+func indexOf(ips []string, ip string) int {
+	for i, candidate := range ips {
+		if candidate == ip {
+			return i
+		}
+	}
+	return -1
+}
+
+// GetRoundRobinIP 在延迟最低的前topK个可用IP中轮询选择一个IP（用于分散负载）
+// latencyBudget大于0时，只选择延迟不超过最佳IP延迟+latencyBudget的IP；
+// 未启用延迟检测或没有延迟信息时，在整个IP列表中轮询
+func (m *Manager) GetRoundRobinIP(topK int, latencyBudget time.Duration) (string, error) {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if len(m.ips) == 0 {
+		return "", m.noIPsError()
+	}
+
+	candidates := make([]string, 0, len(m.ips))
+	if m.enableLatencyCheck && len(m.ipInfos) > 0 {
+		// ipInfos已按可用性和延迟排序
+		var best time.Duration
+		for _, ipInfo := range m.ipInfos {
+			if !ipInfo.Available || (topK > 0 && len(candidates) >= topK) {
+				break
+			}
+			if len(candidates) == 0 {
+				best = ipInfo.Latency
+			} else if latencyBudget > 0 && ipInfo.Latency > best+latencyBudget {
+				break
+			}
+			candidates = append(candidates, ipInfo.IP)
+		}
+	} else {
+		candidates = append(candidates, m.ips...)
+		if topK > 0 && len(candidates) > topK {
+			candidates = candidates[:topK]
+		}
+	}
+
+	if len(candidates) == 0 {
+		return "", fmt.Errorf("%w for hostname: %s", ErrNoAvailableIPs, m.hostname)
+	}
+
+	idx := (m.rrCounter.Add(1) - 1) % uint64(len(candidates))
+	return candidates[idx], nil
+}
+
+// noIPsError 返回IP列表为空时的错误，未启动时返回ErrManagerNotRunning（调用时需要持有锁）
+func (m *Manager) noIPsError() error {
+	if !m.isRunning {
+		return fmt.Errorf("%w for hostname: %s", ErrManagerNotRunning, m.hostname)
+	}
+	return fmt.Errorf("%w for hostname: %s", ErrNoAvailableIPs, m.hostname)
+}
+
 // GetNextIP 获取下一个可用的IP地址（用于故障转移）
 func (m *Manager) GetNextIP() (string, error) {
 	m.mu.Lock()
 	defer m.mu.Unlock()
 
 	if len(m.ips) == 0 {
-		return "", fmt.Errorf("no available IPs for hostname: %s", m.hostname)
+		return "", m.noIPsError()
 	}
 
-	// 移动到下一个IP
-	m.currentIdx = (m.currentIdx + 1) % len(m.ips)
-	ip := m.ips[m.currentIdx]
+	// 在候选列表中移动到当前IP的下一个IP；尚未切换过或当前IP已不在候选列表中（被移除或不可用）时，
+	// 视为当前使用的是第一个候选IP（即GetCurrentIP返回的IP）
+	candidates := m.failoverCandidates()
+	idx := indexOf(candidates, m.currentIP)
+	if idx < 0 {
+		idx = 0
+	}
+	from := candidates[idx]
+	idx = (idx + 1) % len(candidates)
+	m.currentIP = candidates[idx]
+	if from != m.currentIP {
+		m.recordFailoverLocked(from, m.currentIP)
+	}
 
-	log.Infof(log.WebsocketMgr, "Switched to next IP: %s (index: %d/%d) for %s",
-		ip, m.currentIdx, len(m.ips)-1, m.hostname)
-	return ip, nil
+	m.getLogger().Infof("Switched to next IP: %s -> %s (index: %d/%d, failovers: %d) for %s",
+		from, m.currentIP, idx, len(candidates)-1, m.failover.total, m.hostname)
+	return m.currentIP, nil
 }
 
 // GetAllIPs 获取所有可用的IP地址
@@ -224,13 +450,111 @@ func (m *Manager) GetHostname() string {
 	return m.hostname
 }
 
+// SetIPs 手动设置IP列表（跳过DNS解析），会替换现有的IP列表
+func (m *Manager) SetIPs(ips []string) error {
+	if len(ips) == 0 {
+		return fmt.Errorf("%w: empty IP list for hostname: %s", ErrInvalidIP, m.hostname)
+	}
+
+	newIPs := make([]string, 0, len(ips))
+	ipSet := make(map[string]bool, len(ips))
+	for _, ip := range ips {
+		if err := validateIP(ip); err != nil {
+			return err
+		}
+		if !ipSet[ip] {
+			ipSet[ip] = true
+			newIPs = append(newIPs, ip)
+		}
+	}
+
+	m.mu.Lock()
+	oldIPs := m.ips
+	m.ips = newIPs
+	m.updateIPInfos(newIPs)
+	m.mu.Unlock()
+
+	m.getLogger().Infof("Set IP list for %s: %v (previous: %v)", m.hostname, newIPs, oldIPs)
+	m.notifyListeners(newIPs)
+	return nil
+}
+
+// AddIP 手动添加一个IP到列表（跳过DNS解析），已存在时忽略
+func (m *Manager) AddIP(ip string) error {
+	if err := validateIP(ip); err != nil {
+		return err
+	}
+
+	m.mu.Lock()
+	for _, existing := range m.ips {
+		if existing == ip {
+			m.mu.Unlock()
+			return nil
+		}
+	}
+
+	newIPs := make([]string, 0, len(m.ips)+1)
+	newIPs = append(newIPs, m.ips...)
+	newIPs = append(newIPs, ip)
+	m.ips = newIPs
+	m.updateIPInfos(newIPs)
+	m.mu.Unlock()
+
+	m.getLogger().Infof("Added IP %s for %s", ip, m.hostname)
+	m.notifyListeners(newIPs)
+	return nil
+}
+
+// validateIP 验证IP地址格式（只支持IPv4）
+func validateIP(ip string) error {
+	parsed := net.ParseIP(ip)
+	if parsed == nil || parsed.To4() == nil {
+		return fmt.Errorf("%w: %q is not an IPv4 address", ErrInvalidIP, ip)
+	}
+	return nil
+}
+
+// AddUpdateListener 注册IP列表更新监听器，每次IP列表更新后异步调用
+// 返回的函数用于取消注册
+func (m *Manager) AddUpdateListener(fn func(ips []string)) func() {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+
+	id := m.nextListenerID
+	m.nextListenerID++
+	m.listeners[id] = fn
+
+	return func() {
+		m.listenersMu.Lock()
+		defer m.listenersMu.Unlock()
+		delete(m.listeners, id)
+	}
+}
+
+// notifyListeners 通知所有监听器IP列表已更新（调用时不能持有m.mu）
+func (m *Manager) notifyListeners(ips []string) {
+	m.listenersMu.Lock()
+	defer m.listenersMu.Unlock()
+
+	for _, fn := range m.listeners {
+		snapshot := make([]string, len(ips))
+		copy(snapshot, ips)
+		go fn(snapshot)
+	}
+}
+
 // ForceUpdate 强制更新IP列表
 func (m *Manager) ForceUpdate() {
+	if len(m.staticIPs) > 0 {
+		m.getLogger().Debugf("Static IPs configured for %s, skipping forced update", m.hostname)
+		return
+	}
+
 	select {
 	case m.updateChan <- struct{}{}:
-		log.Debugf(log.WebsocketMgr, "Forced IP update requested for %s", m.hostname)
+		m.getLogger().Debugf("Forced IP update requested for %s", m.hostname)
 	default:
-		log.Debugf(log.WebsocketMgr, "IP update already pending for %s", m.hostname)
+		m.getLogger().Debugf("IP update already pending for %s", m.hostname)
 	}
 }
 
@@ -247,7 +571,7 @@ func (m *Manager) GetStatus() map[string]interface{} {
 		return map[string]interface{}{
 			"hostname": m.hostname,
 			"running":  false,
-			"error":    "IP manager not running",
+			"error":    ErrManagerNotRunning.Error(),
 		}
 	}
 
@@ -258,12 +582,20 @@ func (m *Manager) GetStatus() map[string]interface{} {
 		"hostname":              m.hostname,
 		"running":               true,
 		"current_ip":            currentIP,
-		"current_index":         m.currentIdx,
+		"current_index":         indexOf(allIPs, currentIP),
 		"all_ips":               allIPs,
 		"ip_count":              len(allIPs),
 		"update_interval":       m.updateInterval.String(),
+		"dns_ttl":               m.getDNSTTL().String(),
+		"next_refresh_interval": m.nextRefreshInterval().String(),
 		"dns_servers":           m.dnsServers,
+		"static_ips":            len(m.staticIPs) > 0,
 		"latency_check_enabled": m.enableLatencyCheck,
+		"ready":                 m.IsReady(),
+		"healthy_ip_count":      m.healthyIPCount(),
+		"min_healthy_ips":       m.minHealthyIPs,
+		"failover":              m.GetFailoverStats(),
+		"since_last_success":    m.sinceLastSuccess(),
 	}
 
 	// 添加延迟信息
@@ -289,59 +621,152 @@ func (m *Manager) GetStatus() map[string]interface{} {
 }
 
 // updateLoop 定时更新IP列表的主循环
+// 下一次更新在min(TTL, 更新间隔)后进行，TTL未知时使用更新间隔；
+// 更新失败（没有解析到任何IP）时按指数退避快速重试，成功后恢复正常的更新间隔
 func (m *Manager) updateLoop(ctx context.Context) {
-	ticker := time.NewTicker(m.updateInterval)
-	defer ticker.Stop()
+	refreshTimer := time.NewTimer(m.nextRefreshInterval())
+	defer refreshTimer.Stop()
+
+	// 失败重试定时器，仅在最近一次更新失败时启用
+	var retryTimer *time.Timer
+	var retryC <-chan time.Time
+	retryDelay := m.retryMinDelay
+	stopRetry := func() {
+		if retryTimer != nil {
+			retryTimer.Stop()
+			retryTimer, retryC = nil, nil
+		}
+	}
+	defer stopRetry()
+
+	update := func() {
+		stopRetry()
+		if err := m.updateIPs(ctx); err != nil {
+			m.getLogger().Errorf("Failed to update IP list for %s: %v, retrying in %v",
+				m.hostname, err, retryDelay)
+			retryTimer = time.NewTimer(retryDelay)
+			retryC = retryTimer.C
+			retryDelay = nextRetryDelay(retryDelay, m.retryMaxDelay)
+			refreshTimer.Reset(m.updateInterval)
+			return
+		}
+		retryDelay = m.retryMinDelay
+		next := m.nextRefreshInterval()
+		refreshTimer.Reset(next)
+		m.getLogger().Debugf("Next IP update for %s in %v", m.hostname, next)
+	}
 
 	for {
 		select {
 		case <-ctx.Done():
-			log.Debugf(log.WebsocketMgr, "IP Manager update loop stopped due to context cancellation for %s", m.hostname)
+			m.getLogger().Debugf("IP Manager update loop stopped due to context cancellation for %s", m.hostname)
 			return
 		case <-m.stopChan:
-			log.Debugf(log.WebsocketMgr, "IP Manager update loop stopped for %s", m.hostname)
+			m.getLogger().Debugf("IP Manager update loop stopped for %s", m.hostname)
 			return
-		case <-ticker.C:
-			log.Debugf(log.WebsocketMgr, "Scheduled IP update triggered for %s", m.hostname)
-			if err := m.updateIPs(); err != nil {
-				log.Errorf(log.WebsocketMgr, "Failed to update IP list for %s: %v", m.hostname, err)
-			}
+		case <-refreshTimer.C:
+			m.getLogger().Debugf("Scheduled IP update triggered for %s", m.hostname)
+			update()
+		case <-retryC:
+			m.getLogger().Debugf("Retrying failed IP update for %s", m.hostname)
+			update()
 		case <-m.updateChan:
-			log.Debugf(log.WebsocketMgr, "Manual IP update triggered for %s", m.hostname)
-			if err := m.updateIPs(); err != nil {
-				log.Errorf(log.WebsocketMgr, "Failed to update IP list for %s: %v", m.hostname, err)
-			}
+			m.getLogger().Debugf("Manual IP update triggered for %s", m.hostname)
+			update()
 		}
 	}
 }
 
+// minTTLRefreshInterval 按TTL调度更新时的最小间隔
+const minTTLRefreshInterval = 10 * time.Second
+
+// getDNSTTL 获取最近一次解析结果中最小的TTL，0表示未知
+func (m *Manager) getDNSTTL() time.Duration {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+	return m.dnsTTL
+}
+
+// nextRefreshInterval 根据最近一次解析结果的TTL计算下一次定时更新的间隔
+func (m *Manager) nextRefreshInterval() time.Duration {
+	return refreshInterval(m.getDNSTTL(), m.updateInterval)
+}
+
+// refreshInterval 返回min(TTL, 更新间隔)，TTL未知时使用更新间隔；
+// TTL过短时不低于minTTLRefreshInterval，避免频繁查询DNS
+func refreshInterval(ttl, updateInterval time.Duration) time.Duration {
+	if ttl <= 0 || ttl >= updateInterval {
+		return updateInterval
+	}
+	return min(max(ttl, minTTLRefreshInterval), updateInterval)
+}
+
+// nextRetryDelay 计算下一次失败重试的延迟（翻倍，不超过上限）
+func nextRetryDelay(current, maxDelay time.Duration) time.Duration {
+	next := current * 2
+	if next > maxDelay {
+		return maxDelay
+	}
+	return next
+}
+
 // updateIPs 更新IP列表
-func (m *Manager) updateIPs() error {
-	log.Debugf(log.WebsocketMgr, "Updating IP list for hostname: %s", m.hostname)
+func (m *Manager) updateIPs(ctx context.Context) error {
+	m.getLogger().Debugf("Updating IP list for hostname: %s", m.hostname)
 
 	var allIPs []string
 	ipSet := make(map[string]bool) // 用于去重
+	var resolvedTTL time.Duration  // 所有解析结果中最小的TTL，0表示未知
 
-	for _, dnsServer := range m.dnsServers {
-		ips, err := m.resolveWithDNS(m.hostname, dnsServer)
-		if err != nil {
-			log.Warnf(log.WebsocketMgr, "Failed to resolve %s with DNS %s: %v", m.hostname, dnsServer, err)
-			continue
+	if !m.preferDoH {
+		for _, dnsServer := range m.dnsServers {
+			// 管理器停止或context取消时不再继续解析
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			ips, ttl, err := m.resolveWithDNS(ctx, m.hostname, dnsServer)
+			if err != nil {
+				m.getLogger().Warnf("Failed to resolve %s with DNS %s: %v", m.hostname, dnsServer, err)
+				continue
+			}
+
+			// 处理解析到的IP列表
+			m.processResolvedIPs(ips, ipSet, &allIPs)
+			resolvedTTL = minTTL(resolvedTTL, ttl)
 		}
+	}
 
-		// 处理解析到的IP列表
-		m.processResolvedIPs(ips, ipSet, &allIPs)
+	// UDP DNS全部失败（可能被屏蔽或劫持）或配置为只使用DoH时，通过DNS-over-HTTPS解析
+	if len(allIPs) == 0 && len(m.dohServers) > 0 {
+		if !m.preferDoH {
+			m.getLogger().Warnf("UDP DNS resolution failed for %s, trying DNS-over-HTTPS", m.hostname)
+		}
+		for _, server := range m.dohServers {
+			if err := ctx.Err(); err != nil {
+				return err
+			}
+
+			ips, ttl, err := m.resolveWithDoH(ctx, m.hostname, server)
+			if err != nil {
+				m.getLogger().Warnf("Failed to resolve %s with DoH %s: %v", m.hostname, server, err)
+				continue
+			}
+			m.processResolvedIPs(ips, ipSet, &allIPs)
+			resolvedTTL = minTTL(resolvedTTL, ttl)
+		}
 	}
 	if len(allIPs) == 0 {
-		log.Warnf(log.WebsocketMgr, "!!! Failed to resolve any valid IPs for %s, trying fallback IPs", m.hostname)
+		m.getLogger().Warnf("!!! Failed to resolve any valid IPs for %s, trying fallback IPs", m.hostname)
 
 		// 使用已知的Binance API IP作为备用
 		fallbackIPs := m.getFallbackIPs()
 		if len(fallbackIPs) > 0 {
 			allIPs = fallbackIPs
-			log.Infof(log.WebsocketMgr, "Using fallback IPs for %s: %v", m.hostname, allIPs)
+			resolvedTTL = 0
+			m.getLogger().Infof("Using fallback IPs for %s: %v", m.hostname, allIPs)
 		} else {
-			return fmt.Errorf("failed to resolve any IPs for hostname: %s", m.hostname)
+			return fmt.Errorf("%w: failed to resolve any IPs for hostname: %s", ErrNoAvailableIPs, m.hostname)
 		}
 	}
 
@@ -349,25 +774,48 @@ func (m *Manager) updateIPs() error {
 	m.mu.Lock()
 	oldIPs := m.ips
 	m.ips = allIPs
+	m.dnsTTL = resolvedTTL
 
 	// 更新ipInfos列表
 	m.updateIPInfos(allIPs)
-
-	// 如果当前索引超出范围，重置为0
-	if m.currentIdx >= len(m.ips) {
-		m.currentIdx = 0
-	}
 	m.mu.Unlock()
 
-	log.Infof(log.WebsocketMgr, "Updated IP list for %s: %v (previous: %v)",
+	m.getLogger().Infof("Updated IP list for %s: %v (previous: %v)",
 		m.hostname, allIPs, oldIPs)
+	m.notifyListeners(allIPs)
 	return nil
 }
 
-// resolveWithDNS 使用指定的DNS服务器解析域名
-func (m *Manager) resolveWithDNS(hostname, dnsServer string) ([]string, error) {
-	log.Debugf(log.WebsocketMgr, "Resolving %s using DNS server %s", hostname, dnsServer)
+// resolveWithDNS 使用指定的DNS服务器解析域名，返回IPv4地址和记录的TTL（未知时为0）
+func (m *Manager) resolveWithDNS(ctx context.Context, hostname, dnsServer string) ([]string, time.Duration, error) {
+	m.getLogger().Debugf("Resolving %s using DNS server %s", hostname, dnsServer)
+
+	ctx, cancel := context.WithTimeout(ctx, m.dnsTimeout)
+	defer cancel()
 
+	// 优先直接查询A记录以获取TTL，响应被截断或格式不支持时回退到标准解析器（此时TTL未知）
+	result, ttl, err := queryA(ctx, hostname, dnsServer)
+	if errors.Is(err, errDNSMessage) {
+		m.getLogger().Debugf("Falling back to system resolver for %s using %s: %v", hostname, dnsServer, err)
+		result, err = m.lookupWithResolver(ctx, hostname, dnsServer)
+		ttl = 0
+	}
+	if err != nil {
+		m.getLogger().Warnf("DNS resolution failed for %s using %s: %v", hostname, dnsServer, err)
+		return nil, 0, err
+	}
+
+	// 验证解析结果的合理性
+	if len(result) == 0 {
+		return nil, 0, fmt.Errorf("no IPv4 addresses found for %s using DNS %s", hostname, dnsServer)
+	}
+
+	m.getLogger().Infof("Successfully resolved %s to %v using DNS %s (ttl: %v)", hostname, result, dnsServer, ttl)
+	return result, ttl, nil
+}
+
+// lookupWithResolver 使用标准库解析器通过指定DNS服务器解析域名
+func (m *Manager) lookupWithResolver(ctx context.Context, hostname, dnsServer string) ([]string, error) {
 	resolver := &net.Resolver{
 		PreferGo: true,
 		Dial: func(ctx context.Context, network, address string) (net.Conn, error) {
@@ -379,12 +827,8 @@ func (m *Manager) resolveWithDNS(hostname, dnsServer string) ([]string, error) {
 		},
 	}
 
-	ctx, cancel := context.WithTimeout(context.Background(), m.dnsTimeout)
-	defer cancel()
-
 	ips, err := resolver.LookupIPAddr(ctx, hostname)
 	if err != nil {
-		log.Warnf(log.WebsocketMgr, "DNS resolution failed for %s using %s: %v", hostname, dnsServer, err)
 		return nil, err
 	}
 
@@ -394,16 +838,9 @@ func (m *Manager) resolveWithDNS(hostname, dnsServer string) ([]string, error) {
 		if ip.IP.To4() != nil {
 			ipStr := ip.IP.String()
 			result = append(result, ipStr)
-			log.Debugf(log.WebsocketMgr, "Resolved %s to %s using DNS %s", hostname, ipStr, dnsServer)
+			m.getLogger().Debugf("Resolved %s to %s using DNS %s", hostname, ipStr, dnsServer)
 		}
 	}
-
-	// 验证解析结果的合理性
-	if len(result) == 0 {
-		return nil, fmt.Errorf("no IPv4 addresses found for %s using DNS %s", hostname, dnsServer)
-	}
-
-	log.Infof(log.WebsocketMgr, "Successfully resolved %s to %v using DNS %s", hostname, result, dnsServer)
 	return result, nil
 }
 
@@ -413,11 +850,11 @@ func (m *Manager) processResolvedIPs(ips []string, ipSet map[string]bool, allIPs
 		if !ipSet[ip] && m.isValidBinanceIP(ip) {
 			ipSet[ip] = true
 			*allIPs = append(*allIPs, ip)
-			log.Debugf(log.WebsocketMgr, "Added valid IP %s for %s", ip, m.hostname)
+			m.getLogger().Debugf("Added valid IP %s for %s", ip, m.hostname)
 		} else if ipSet[ip] {
-			log.Debugf(log.WebsocketMgr, "Skipping duplicate IP %s", ip)
+			m.getLogger().Debugf("Skipping duplicate IP %s", ip)
 		} else {
-			log.Warnf(log.WebsocketMgr, "Skipping invalid IP %s for %s", ip, m.hostname)
+			m.getLogger().Warnf("Skipping invalid IP %s for %s", ip, m.hostname)
 		}
 	}
 }
@@ -445,7 +882,7 @@ func (m *Manager) isValidBinanceIP(ip string) bool {
 			continue
 		}
 		if network.Contains(ipAddr) {
-			log.Warnf(log.WebsocketMgr, "IP %s appears to be in invalid range %s, skipping", ip, cidr)
+			m.getLogger().Warnf("IP %s appears to be in invalid range %s, skipping", ip, cidr)
 			return false
 		}
 	}
@@ -475,7 +912,8 @@ func (m *Manager) getFallbackIPs() []string {
 	}
 }
 
-// updateIPInfos 更新IP信息列表（调用时需要持有锁）
+// updateIPInfos 更新IP信息列表（调用时需要持有m.mu写锁）。
+// 启用延迟检测时会异步触发一次检测，检测goroutine会在当前持锁方释放后自行获取锁
 func (m *Manager) updateIPInfos(newIPs []string) {
 	// 创建新的IP信息映射
 	newIPInfos := make([]*IPInfo, 0, len(newIPs))
@@ -506,14 +944,22 @@ func (m *Manager) updateIPInfos(newIPs []string) {
 
 	// 如果启用延迟检测，立即触发一次延迟检测
 	if m.enableLatencyCheck {
-		go m.checkLatencyForAllIPs()
+		m.spawn(m.checkLatencyForAllIPs)
 	}
 }
 
 // latencyCheckLoop 延迟检测主循环
 func (m *Manager) latencyCheckLoop(ctx context.Context) {
-	// 初始延迟，避免启动时立即检测
-	time.Sleep(5 * time.Second)
+	// 初始延迟，避免启动时立即检测；停止时不再等待
+	initialDelay := time.NewTimer(5 * time.Second)
+	defer initialDelay.Stop()
+	select {
+	case <-ctx.Done():
+		return
+	case <-m.stopChan:
+		return
+	case <-initialDelay.C:
+	}
 
 	ticker := time.NewTicker(m.latencyCheckInterval)
 	defer ticker.Stop()
@@ -521,15 +967,15 @@ func (m *Manager) latencyCheckLoop(ctx context.Context) {
 	for {
 		select {
 		case <-ctx.Done():
-			log.Debugf(log.WebsocketMgr, "Latency check loop stopped due to context cancellation for %s", m.hostname)
+			m.getLogger().Debugf("Latency check loop stopped due to context cancellation for %s", m.hostname)
 			return
 		case <-m.stopChan:
-			log.Debugf(log.WebsocketMgr, "Latency check loop stopped for %s", m.hostname)
+			m.getLogger().Debugf("Latency check loop stopped for %s", m.hostname)
 			return
 		case <-ticker.C:
-			log.Debugf(log.WebsocketMgr, "Scheduled latency check triggered for %s", m.hostname)
+			m.getLogger().Debugf("Scheduled latency check triggered for %s", m.hostname)
 			// 在单独的goroutine中执行延迟检测，避免阻塞
-			go m.checkLatencyForAllIPs()
+			m.spawn(m.checkLatencyForAllIPs)
 		}
 	}
 }
@@ -545,45 +991,86 @@ func (m *Manager) checkLatencyForAllIPs() {
 		return
 	}
 
-	log.Debugf(log.WebsocketMgr, "Checking latency for %d IPs of %s", len(ipInfos), m.hostname)
+	m.getLogger().Debugf("Checking latency for %d IPs of %s", len(ipInfos), m.hostname)
+
+	// 管理器停止时中断检测，不等待慢速或不可达的IP超时
+	stopCtx, stop := context.WithCancel(context.Background())
+	defer stop()
+	go func() {
+		select {
+		case <-m.stopChan:
+			stop()
+		case <-stopCtx.Done():
+		}
+	}()
+	ctx := stopCtx
+
+	// 整轮检测的总时限，避免大量慢速或不可达的IP使检测持续到下一个检测周期
+	if m.latencyCheckDeadline > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, m.latencyCheckDeadline)
+		defer cancel()
+	}
 
 	// 使用带缓冲的channel控制并发数，避免过多连接
-	semaphore := make(chan struct{}, 3) // 最多3个并发连接
+	concurrency := m.latencyConcurrency
+	if concurrency <= 0 {
+		concurrency = 3
+	}
+	semaphore := make(chan struct{}, concurrency)
 	var wg sync.WaitGroup
+	var skipped atomic.Int32
 
 	for _, ipInfo := range ipInfos {
 		wg.Add(1)
 		go func(info *IPInfo) {
 			defer wg.Done()
 
-			// 获取信号量
-			semaphore <- struct{}{}
+			// 获取信号量，超过总时限时放弃检测
+			select {
+			case semaphore <- struct{}{}:
+			case <-ctx.Done():
+				skipped.Add(1)
+				return
+			}
 			defer func() { <-semaphore }()
 
-			latency, err := m.measureLatency(info.IP)
+			latency, err := m.measureLatency(ctx, info.IP)
+			if err != nil && ctx.Err() != nil {
+				// 因总时限被中断的检测不能说明IP不可用，保留原有状态
+				skipped.Add(1)
+				return
+			}
 
 			m.mu.Lock()
 			info.LastPing = time.Now()
 			if err != nil {
 				info.Available = false
 				info.Latency = time.Duration(0)
-				log.Debugf(log.WebsocketMgr, "IP %s is unavailable: %v", info.IP, err)
+				m.getLogger().Debugf("IP %s is unavailable: %v", info.IP, err)
 			} else {
 				info.Available = true
 				info.Latency = latency
-				log.Debugf(log.WebsocketMgr, "IP %s latency: %v", info.IP, latency)
+				m.getLogger().Debugf("IP %s latency: %v", info.IP, latency)
 			}
 			m.mu.Unlock()
 		}(ipInfo)
 	}
 	wg.Wait()
 
+	if n := skipped.Load(); n > 0 && errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		m.getLogger().Warnf("Latency check for %s exceeded deadline %v, %d IPs were not checked",
+			m.hostname, m.latencyCheckDeadline, n)
+	}
+
 	// 按延迟排序IP列表
+	m.mu.Lock()
 	m.sortIPsByLatency()
+	m.mu.Unlock()
 }
 
 // measureLatency 测量到指定IP的网络延迟
-func (m *Manager) measureLatency(ip string) (time.Duration, error) {
+func (m *Manager) measureLatency(ctx context.Context, ip string) (time.Duration, error) {
 	start := time.Now()
 
 	// 创建专用的拨号器，避免与HTTP客户端冲突
@@ -593,7 +1080,7 @@ func (m *Manager) measureLatency(ip string) (time.Duration, error) {
 	}
 
 	// 使用TCP连接测试延迟
-	conn, err := dialer.Dial("tcp", net.JoinHostPort(ip, m.latencyPort))
+	conn, err := dialer.DialContext(ctx, "tcp", net.JoinHostPort(ip, m.latencyPort))
 	if err != nil {
 		return 0, err
 	}
@@ -605,17 +1092,14 @@ func (m *Manager) measureLatency(ip string) (time.Duration, error) {
 	return latency, nil
 }
 
-// sortIPsByLatency 按延迟对IP进行排序（调用时需要持有锁）
+// sortIPsByLatency 按延迟对IP进行排序（调用时需要持有m.mu写锁，函数内部不加锁）
 func (m *Manager) sortIPsByLatency() {
-	m.mu.Lock()
-	defer m.mu.Unlock()
-
 	if !m.enableLatencyCheck || len(m.ipInfos) == 0 {
 		return
 	}
 
-	// 按延迟排序，可用的IP优先，然后按延迟从低到高排序
-	sort.Slice(m.ipInfos, func(i, j int) bool {
+	// 按延迟排序，可用的IP优先，然后按延迟从低到高排序（稳定排序，不可用的IP保持原有顺序）
+	sort.SliceStable(m.ipInfos, func(i, j int) bool {
 		ipA, ipB := m.ipInfos[i], m.ipInfos[j]
 
 		// 可用的IP优先
@@ -639,11 +1123,8 @@ func (m *Manager) sortIPsByLatency() {
 	}
 	m.ips = newIPs
 
-	// 重置当前索引
-	m.currentIdx = 0
-
 	if len(m.ipInfos) > 0 && m.ipInfos[0].Available {
-		log.Infof(log.WebsocketMgr, "Best IP for %s: %s (latency: %v)",
+		m.getLogger().Infof("Best IP for %s: %s (latency: %v)",
 			m.hostname, m.ipInfos[0].IP, m.ipInfos[0].Latency)
 	}
 }
@@ -656,9 +1137,9 @@ func (m *Manager) GetBestIP() (string, time.Duration, error) {
 	if !m.enableLatencyCheck || len(m.ipInfos) == 0 {
 		// 回退到传统方式
 		if len(m.ips) == 0 {
-			return "", 0, fmt.Errorf("no available IPs for hostname: %s", m.hostname)
+			return "", 0, m.noIPsError()
 		}
-		return m.ips[m.currentIdx], 0, nil
+		return m.currentIPLocked(), 0, nil
 	}
 
 	for _, ipInfo := range m.ipInfos {
@@ -667,7 +1148,7 @@ func (m *Manager) GetBestIP() (string, time.Duration, error) {
 		}
 	}
 
-	return "", 0, fmt.Errorf("no available IPs for hostname: %s", m.hostname)
+	return "", 0, fmt.Errorf("%w for hostname: %s", ErrNoAvailableIPs, m.hostname)
 }
 
 // GetAllIPsWithLatency 获取所有IP及其延迟信息
@@ -695,10 +1176,10 @@ func (m *Manager) GetAllIPsWithLatency() []*IPInfo {
 // ForceLatencyCheck 强制执行一次延迟检测
 func (m *Manager) ForceLatencyCheck() {
 	if !m.enableLatencyCheck {
-		log.Warnf(log.WebsocketMgr, "Latency check is disabled for %s", m.hostname)
+		m.getLogger().Warnf("Latency check is disabled for %s", m.hostname)
 		return
 	}
 
-	log.Debugf(log.WebsocketMgr, "Forced latency check requested for %s", m.hostname)
-	go m.checkLatencyForAllIPs()
+	m.getLogger().Debugf("Forced latency check requested for %s", m.hostname)
+	m.spawn(m.checkLatencyForAllIPs)
 }
@@ -2,6 +2,9 @@ package ipmanager
 
 import (
 	"context"
+	"fmt"
+	"net"
+	"sync"
 	"testing"
 	"time"
 )
@@ -127,7 +130,7 @@ func TestMeasureLatency(t *testing.T) {
 	manager := New(config)
 
 	// 测试可达的IP
-	latency, err := manager.measureLatency("8.8.8.8")
+	latency, err := manager.measureLatency(context.Background(), "8.8.8.8")
 	if err != nil {
 		t.Errorf("测量延迟失败: %v", err)
 	}
@@ -141,7 +144,7 @@ func TestMeasureLatency(t *testing.T) {
 	}
 
 	// 测试不可达的IP（使用一个肯定不存在的私有地址）
-	_, err = manager.measureLatency("10.255.255.254") // 私有地址，通常不可达
+	_, err = manager.measureLatency(context.Background(), "10.255.255.254") // 私有地址，通常不可达
 	if err == nil {
 		t.Log("警告: 测试IP可能可达，这在某些网络环境下是正常的")
 	}
@@ -158,7 +161,9 @@ func TestSortIPsByLatency(t *testing.T) {
 		},
 	}
 
+	manager.mu.Lock()
 	manager.sortIPsByLatency()
+	manager.mu.Unlock()
 
 	// 检查排序结果：可用的IP应该在前面，按延迟从低到高排序
 	expected := []string{"4.4.4.4", "2.2.2.2", "1.1.1.1", "3.3.3.3"}
@@ -181,6 +186,120 @@ func TestSortIPsByLatency(t *testing.T) {
 	}
 }
 
+// TestConcurrentUpdateAndSort 并发更新IP列表、排序和读取，配合 -race 检测锁的使用是否正确
+func TestConcurrentUpdateAndSort(t *testing.T) {
+	// 本地监听一个端口作为延迟检测目标，127.0.0.1可连接，其余回环地址连接会被拒绝
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("监听本地端口失败: %v", err)
+	}
+	defer listener.Close()
+	go func() {
+		for {
+			conn, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			conn.Close()
+		}
+	}()
+	_, port, _ := net.SplitHostPort(listener.Addr().String())
+
+	manager := New(&Config{
+		Hostname:           "example.com",
+		EnableLatencyCheck: true,
+		LatencyTimeout:     200 * time.Millisecond,
+		LatencyPort:        port,
+	})
+
+	var wg sync.WaitGroup
+	for i := 0; i < 4; i++ {
+		wg.Add(1)
+		go func(worker int) {
+			defer wg.Done()
+			for j := 0; j < 20; j++ {
+				switch (worker + j) % 4 {
+				case 0:
+					_ = manager.SetIPs([]string{"127.0.0.2", "127.0.0.1", "127.0.0.3"})
+				case 1:
+					_ = manager.AddIP(fmt.Sprintf("127.0.0.%d", 4+j%3))
+				case 2:
+					manager.mu.Lock()
+					manager.sortIPsByLatency()
+					manager.mu.Unlock()
+				case 3:
+					manager.ForceLatencyCheck()
+				}
+				_, _ = manager.GetCurrentIP()
+				_, _ = manager.GetNextIP()
+				_, _, _ = manager.GetBestIP()
+				_ = manager.GetAllIPsWithLatency()
+			}
+		}(i)
+	}
+	wg.Wait()
+
+	// 同步执行一次延迟检测，只有127.0.0.1可用，排序后应排在最前
+	if err := manager.SetIPs([]string{"127.0.0.2", "127.0.0.1", "127.0.0.3"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+	manager.checkLatencyForAllIPs()
+
+	bestIP, _, err := manager.GetBestIP()
+	if err != nil {
+		t.Fatalf("获取最佳IP失败: %v", err)
+	}
+	if bestIP != "127.0.0.1" {
+		t.Errorf("最佳IP不正确，期望 127.0.0.1，实际 %s", bestIP)
+	}
+	if ips := manager.GetAllIPs(); len(ips) != 3 || ips[0] != "127.0.0.1" {
+		t.Errorf("IP列表应该按延迟排序，实际: %v", ips)
+	}
+}
+
+func TestLatencyConcurrencyConfig(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com", LatencyCheckInterval: 10 * time.Second})
+	if manager.latencyConcurrency != 3 {
+		t.Errorf("默认并发数应该是3，实际 %d", manager.latencyConcurrency)
+	}
+	if manager.latencyCheckDeadline != 5*time.Second {
+		t.Errorf("默认总时限应该是检测间隔的一半，实际 %v", manager.latencyCheckDeadline)
+	}
+
+	manager = New(&Config{Hostname: "example.com", LatencyConcurrency: 8, LatencyCheckDeadline: 20 * time.Second})
+	if manager.latencyConcurrency != 8 || manager.latencyCheckDeadline != 20*time.Second {
+		t.Errorf("配置未生效: 并发数 %d，总时限 %v", manager.latencyConcurrency, manager.latencyCheckDeadline)
+	}
+}
+
+func TestLatencyCheckDeadline(t *testing.T) {
+	manager := &Manager{
+		hostname:             "example.com",
+		enableLatencyCheck:   true,
+		latencyTimeout:       2 * time.Second,
+		latencyPort:          "80",
+		latencyConcurrency:   1,
+		latencyCheckDeadline: time.Nanosecond,
+		ips:                  []string{"1.1.1.1", "2.2.2.2"},
+		ipInfos: []*IPInfo{
+			{IP: "1.1.1.1", Latency: 100 * time.Millisecond, Available: true},
+			{IP: "2.2.2.2", Available: false},
+		},
+	}
+
+	// 超过总时限后应立即返回，未完成检测的IP保留原有状态
+	start := time.Now()
+	manager.checkLatencyForAllIPs()
+	if elapsed := time.Since(start); elapsed > time.Second {
+		t.Errorf("超过总时限后应立即返回，实际耗时 %v", elapsed)
+	}
+
+	infos := manager.GetAllIPsWithLatency()
+	if !infos[0].Available || infos[0].Latency != 100*time.Millisecond || infos[1].Available {
+		t.Errorf("未完成检测的IP应保留原有状态: %+v %+v", infos[0], infos[1])
+	}
+}
+
 func TestGetBestIP(t *testing.T) {
 	manager := &Manager{
 		enableLatencyCheck: true,
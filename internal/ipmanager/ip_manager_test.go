@@ -0,0 +1,647 @@
+package ipmanager
+
+import (
+	"context"
+	"encoding/binary"
+	"errors"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func TestSetIPs(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com"})
+
+	if err := manager.SetIPs([]string{"1.1.1.1", "2.2.2.2", "1.1.1.1"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+	if ips := manager.GetAllIPs(); len(ips) != 2 || ips[0] != "1.1.1.1" || ips[1] != "2.2.2.2" {
+		t.Errorf("IP列表应该去重并保持顺序，实际: %v", ips)
+	}
+
+	// 无效的IP不应该修改现有列表
+	for _, invalid := range []string{"not-an-ip", "256.1.1.1", "::1", ""} {
+		if err := manager.SetIPs([]string{"3.3.3.3", invalid}); err == nil {
+			t.Errorf("IP %q 应该被拒绝", invalid)
+		}
+	}
+	if ips := manager.GetAllIPs(); len(ips) != 2 {
+		t.Errorf("无效输入不应修改IP列表，实际: %v", ips)
+	}
+
+	if err := manager.SetIPs(nil); err == nil {
+		t.Error("空IP列表应该返回错误")
+	}
+}
+
+func TestAddIP(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com"})
+
+	if err := manager.AddIP("1.1.1.1"); err != nil {
+		t.Fatalf("添加IP失败: %v", err)
+	}
+	if err := manager.AddIP("1.1.1.1"); err != nil {
+		t.Fatalf("重复添加IP不应返回错误: %v", err)
+	}
+	if err := manager.AddIP("2.2.2.2"); err != nil {
+		t.Fatalf("添加IP失败: %v", err)
+	}
+	if err := manager.AddIP("invalid"); err == nil {
+		t.Error("无效IP应该返回错误")
+	}
+
+	if ips := manager.GetAllIPs(); len(ips) != 2 {
+		t.Errorf("应该有2个IP，实际: %v", ips)
+	}
+
+	ip, err := manager.GetCurrentIP()
+	if err != nil || ip != "1.1.1.1" {
+		t.Errorf("当前IP应该是1.1.1.1，实际: %s (err: %v)", ip, err)
+	}
+}
+
+func TestStaticIPs(t *testing.T) {
+	manager := New(&Config{
+		Hostname:  "example.com",
+		StaticIPs: []string{"1.1.1.1", "2.2.2.2"},
+	})
+
+	// 使用静态IP时不进行DNS解析，离线环境下也应启动成功
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("启动管理器失败: %v", err)
+	}
+	defer manager.Stop()
+
+	if ips := manager.GetAllIPs(); len(ips) != 2 {
+		t.Errorf("应该使用静态IP列表，实际: %v", ips)
+	}
+
+	// 强制更新不应覆盖静态IP
+	manager.ForceUpdate()
+	if ips := manager.GetAllIPs(); len(ips) != 2 {
+		t.Errorf("强制更新后仍应使用静态IP列表，实际: %v", ips)
+	}
+
+	invalid := New(&Config{Hostname: "example.com", StaticIPs: []string{"bad"}})
+	if err := invalid.Start(context.Background()); err == nil {
+		invalid.Stop()
+		t.Error("无效的静态IP应该导致启动失败")
+	}
+}
+
+func TestSentinelErrors(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com"})
+
+	if _, err := manager.GetCurrentIP(); !errors.Is(err, ErrManagerNotRunning) {
+		t.Errorf("未启动且没有IP时应返回ErrManagerNotRunning，实际: %v", err)
+	}
+	if _, err := manager.GetNextIP(); !errors.Is(err, ErrManagerNotRunning) {
+		t.Errorf("未启动且没有IP时应返回ErrManagerNotRunning，实际: %v", err)
+	}
+	if err := manager.AddIP("bad"); !errors.Is(err, ErrInvalidIP) {
+		t.Errorf("无效IP应返回ErrInvalidIP，实际: %v", err)
+	}
+
+	manager.mu.Lock()
+	manager.isRunning = true
+	manager.mu.Unlock()
+
+	if _, _, err := manager.GetBestIP(); !errors.Is(err, ErrNoAvailableIPs) {
+		t.Errorf("运行中但没有IP时应返回ErrNoAvailableIPs，实际: %v", err)
+	}
+	if err := manager.Start(context.Background()); !errors.Is(err, ErrManagerAlreadyRunning) {
+		t.Errorf("重复启动应返回ErrManagerAlreadyRunning，实际: %v", err)
+	}
+}
+
+func TestRetryDelayBackoff(t *testing.T) {
+	manager := New(&Config{
+		Hostname:       "example.com",
+		UpdateInterval: 30 * time.Second,
+		RetryMinDelay:  time.Second,
+		RetryMaxDelay:  time.Hour,
+	})
+
+	// 最大重试延迟不应超过正常的更新间隔
+	if manager.retryMaxDelay != 30*time.Second {
+		t.Errorf("最大重试延迟应被限制为更新间隔，实际: %v", manager.retryMaxDelay)
+	}
+
+	delay := manager.retryMinDelay
+	expected := []time.Duration{2 * time.Second, 4 * time.Second, 8 * time.Second, 16 * time.Second, 30 * time.Second, 30 * time.Second}
+	for i, want := range expected {
+		delay = nextRetryDelay(delay, manager.retryMaxDelay)
+		if delay != want {
+			t.Errorf("第%d次重试延迟应为%v，实际: %v", i+1, want, delay)
+		}
+	}
+}
+
+func TestUpdateLoopStopsOnContextCancel(t *testing.T) {
+	manager := New(&Config{
+		Hostname:      "example.invalid",
+		DNSServers:    []string{"127.0.0.1:1"},
+		DNSTimeout:    100 * time.Millisecond,
+		RetryMinDelay: 10 * time.Millisecond,
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan struct{})
+	go func() {
+		manager.updateLoop(ctx)
+		close(done)
+	}()
+
+	// 触发一次失败的更新，进入快速重试状态
+	manager.ForceUpdate()
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+
+	select {
+	case <-done:
+	case <-time.After(2 * time.Second):
+		t.Fatal("context取消后更新循环应退出")
+	}
+}
+
+func TestGetRoundRobinIP(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com", EnableLatencyCheck: true})
+	manager.ips = []string{"1.1.1.1", "2.2.2.2", "3.3.3.3", "4.4.4.4"}
+	manager.ipInfos = []*IPInfo{
+		{IP: "1.1.1.1", Latency: 10 * time.Millisecond, Available: true},
+		{IP: "2.2.2.2", Latency: 20 * time.Millisecond, Available: true},
+		{IP: "3.3.3.3", Latency: 200 * time.Millisecond, Available: true},
+		{IP: "4.4.4.4", Available: false},
+	}
+
+	// 延迟预算内只有前两个IP参与轮询
+	counts := make(map[string]int)
+	for i := 0; i < 10; i++ {
+		ip, err := manager.GetRoundRobinIP(3, 50*time.Millisecond)
+		if err != nil {
+			t.Fatalf("轮询获取IP失败: %v", err)
+		}
+		counts[ip]++
+	}
+	if counts["1.1.1.1"] != 5 || counts["2.2.2.2"] != 5 || len(counts) != 2 {
+		t.Errorf("应在延迟预算内的IP之间平均轮询，实际: %v", counts)
+	}
+
+	// 不限制延迟预算时，前topK个可用IP都参与轮询，不可用的IP被排除
+	counts = make(map[string]int)
+	for i := 0; i < 9; i++ {
+		ip, _ := manager.GetRoundRobinIP(4, 0)
+		counts[ip]++
+	}
+	if len(counts) != 3 || counts["4.4.4.4"] != 0 {
+		t.Errorf("应在所有可用IP之间轮询，实际: %v", counts)
+	}
+}
+
+func TestGetNextIPFollowsCurrentIP(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com"})
+	if err := manager.SetIPs([]string{"1.1.1.1", "2.2.2.2", "3.3.3.3"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+
+	// 按列表顺序轮换
+	for _, want := range []string{"2.2.2.2", "3.3.3.3", "1.1.1.1", "2.2.2.2"} {
+		if ip, err := manager.GetNextIP(); err != nil || ip != want {
+			t.Fatalf("下一个IP应该是%s，实际: %s (err: %v)", want, ip, err)
+		}
+	}
+
+	// IP列表顺序变化后，从当前IP的新位置继续，不会跳过或重复
+	if err := manager.SetIPs([]string{"3.3.3.3", "2.2.2.2", "1.1.1.1"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+	if ip, _ := manager.GetCurrentIP(); ip != "2.2.2.2" {
+		t.Errorf("更新列表后当前IP应保持为2.2.2.2，实际: %s", ip)
+	}
+	if ip, _ := manager.GetNextIP(); ip != "1.1.1.1" {
+		t.Errorf("下一个IP应该是1.1.1.1，实际: %s", ip)
+	}
+
+	// 当前IP被移除后从列表第一个IP开始
+	if err := manager.SetIPs([]string{"4.4.4.4", "5.5.5.5"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+	if ip, _ := manager.GetCurrentIP(); ip != "4.4.4.4" {
+		t.Errorf("当前IP被移除后应回退到第一个IP，实际: %s", ip)
+	}
+	if ip, _ := manager.GetNextIP(); ip != "5.5.5.5" {
+		t.Errorf("下一个IP应该是5.5.5.5，实际: %s", ip)
+	}
+}
+
+func TestGetNextIPSkipsUnavailable(t *testing.T) {
+	manager := &Manager{
+		hostname:           "example.com",
+		enableLatencyCheck: true,
+		ips:                []string{"1.1.1.1", "2.2.2.2", "3.3.3.3"},
+		ipInfos: []*IPInfo{
+			{IP: "1.1.1.1", Latency: 10 * time.Millisecond, Available: true},
+			{IP: "2.2.2.2", Latency: 20 * time.Millisecond, Available: true},
+			{IP: "3.3.3.3", Available: false},
+		},
+	}
+
+	// 启用延迟检测时只在可用的IP之间切换
+	for _, want := range []string{"2.2.2.2", "1.1.1.1", "2.2.2.2"} {
+		if ip, _ := manager.GetNextIP(); ip != want {
+			t.Fatalf("下一个IP应该是%s，实际: %s", want, ip)
+		}
+	}
+
+	// 重新排序不会改变当前IP
+	manager.ipInfos[0].Latency = 30 * time.Millisecond
+	manager.mu.Lock()
+	manager.sortIPsByLatency()
+	manager.mu.Unlock()
+	if ip, _ := manager.GetNextIP(); ip != "1.1.1.1" {
+		t.Errorf("排序后应从当前IP 2.2.2.2 切换到1.1.1.1，实际: %s", ip)
+	}
+}
+
+func TestResolveWithDoH(t *testing.T) {
+	var queries []string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		queries = append(queries, r.URL.Query().Get("name")+"/"+r.URL.Query().Get("type")+"/"+r.Header.Get("Accept"))
+		w.Header().Set("Content-Type", "application/dns-json")
+		w.Write([]byte(`{"Status":0,"Answer":[
+			{"name":"api.example.com.","type":5,"TTL":60,"data":"edge.example.net."},
+			{"name":"edge.example.net.","type":1,"TTL":60,"data":"203.0.113.10"},
+			{"name":"edge.example.net.","type":1,"TTL":60,"data":"203.0.113.10"},
+			{"name":"edge.example.net.","type":1,"TTL":60,"data":"31.13.1.1"},
+			{"name":"edge.example.net.","type":1,"TTL":60,"data":"203.0.113.11"}]}`))
+	}))
+	defer server.Close()
+
+	// 只使用DoH时不尝试UDP DNS，解析结果经过去重和有效性校验
+	manager := New(&Config{Hostname: "api.example.com", PreferDoH: true, DoHServers: []string{server.URL}, DNSTimeout: time.Second})
+	if err := manager.updateIPs(context.Background()); err != nil {
+		t.Fatalf("DoH解析失败: %v", err)
+	}
+	if ips := manager.GetAllIPs(); len(ips) != 2 || ips[0] != "203.0.113.10" || ips[1] != "203.0.113.11" {
+		t.Errorf("应跳过CNAME、重复和无效IP，实际: %v", ips)
+	}
+	if len(queries) != 1 || queries[0] != "api.example.com/A/application/dns-json" {
+		t.Errorf("DoH查询参数不正确: %v", queries)
+	}
+	if ttl := manager.getDNSTTL(); ttl != time.Minute {
+		t.Errorf("应记录DoH应答中的TTL，实际: %v", ttl)
+	}
+
+	// UDP DNS失败时回退到DoH
+	queries = nil
+	manager = New(&Config{Hostname: "api.example.com", DNSServers: []string{"127.0.0.1:1"}, DoHServers: []string{server.URL}, DNSTimeout: 500 * time.Millisecond})
+	if err := manager.updateIPs(context.Background()); err != nil {
+		t.Fatalf("回退到DoH解析失败: %v", err)
+	}
+	if len(queries) != 1 || len(manager.GetAllIPs()) != 2 {
+		t.Errorf("UDP DNS失败时应使用DoH解析，查询: %v, IP: %v", queries, manager.GetAllIPs())
+	}
+}
+
+func TestResolveWithDoHErrors(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		switch r.URL.Path {
+		case "/nxdomain":
+			w.Write([]byte(`{"Status":3}`))
+		case "/ipv6":
+			w.Write([]byte(`{"Status":0,"Answer":[{"name":"api.example.com.","type":28,"TTL":60,"data":"2001:db8::1"}]}`))
+		default:
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	defer server.Close()
+
+	manager := New(&Config{Hostname: "api.example.com", DNSTimeout: time.Second})
+	for _, path := range []string{"/nxdomain", "/ipv6", "/error"} {
+		if ips, _, err := manager.resolveWithDoH(context.Background(), "api.example.com", server.URL+path); err == nil {
+			t.Errorf("%s 应返回错误，实际: %v", path, ips)
+		}
+	}
+}
+
+// startTestDNSServer 启动返回固定应答的UDP DNS服务器：一条CNAME和若干TTL不同的A记录
+func startTestDNSServer(t *testing.T, ttls map[string]uint32) string {
+	conn, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatalf("启动测试DNS服务器失败: %v", err)
+	}
+	t.Cleanup(func() { conn.Close() })
+
+	go func() {
+		buf := make([]byte, 512)
+		for {
+			n, addr, err := conn.ReadFrom(buf)
+			if err != nil {
+				return
+			}
+			query := buf[:n]
+			resp := append([]byte{}, query[:2]...)
+			resp = append(resp, 0x81, 0x80, 0, 1, 0, byte(len(ttls)+1), 0, 0, 0, 0)
+			resp = append(resp, query[12:]...)
+			// CNAME记录，指向问题中的域名
+			resp = append(resp, 0xc0, 12, 0, 5, 0, 1, 0, 0, 0, 60, 0, 2, 0xc0, 12)
+			for ip, ttl := range ttls {
+				resp = append(resp, 0xc0, 12, 0, 1, 0, 1)
+				resp = binary.BigEndian.AppendUint32(resp, ttl)
+				resp = append(resp, 0, 4)
+				resp = append(resp, net.ParseIP(ip).To4()...)
+			}
+			conn.WriteTo(resp, addr)
+		}
+	}()
+	return conn.LocalAddr().String()
+}
+
+func TestDNSTTLRefresh(t *testing.T) {
+	server := startTestDNSServer(t, map[string]uint32{"203.0.113.10": 300, "203.0.113.11": 45})
+
+	manager := New(&Config{Hostname: "api.example.com", DNSServers: []string{server}, UpdateInterval: 5 * time.Minute, DNSTimeout: time.Second})
+	if err := manager.updateIPs(context.Background()); err != nil {
+		t.Fatalf("DNS解析失败: %v", err)
+	}
+	if ips := manager.GetAllIPs(); len(ips) != 2 {
+		t.Errorf("应解析到2个IP并跳过CNAME，实际: %v", ips)
+	}
+	if ttl := manager.getDNSTTL(); ttl != 45*time.Second {
+		t.Errorf("应记录最小的TTL 45s，实际: %v", ttl)
+	}
+	if next := manager.nextRefreshInterval(); next != 45*time.Second {
+		t.Errorf("下一次更新应在TTL到期时进行，实际: %v", next)
+	}
+}
+
+func TestRefreshInterval(t *testing.T) {
+	tests := []struct {
+		ttl, interval, want time.Duration
+	}{
+		{0, 5 * time.Minute, 5 * time.Minute},                 // TTL未知时使用更新间隔
+		{time.Hour, 5 * time.Minute, 5 * time.Minute},         // TTL较长时不超过更新间隔
+		{time.Minute, 5 * time.Minute, time.Minute},           // TTL较短时按TTL更新
+		{time.Second, 5 * time.Minute, minTTLRefreshInterval}, // TTL过短时不低于最小间隔
+		{time.Second, 5 * time.Second, 5 * time.Second},       // 最小间隔不超过更新间隔
+	}
+	for _, tt := range tests {
+		if got := refreshInterval(tt.ttl, tt.interval); got != tt.want {
+			t.Errorf("refreshInterval(%v, %v) 应为 %v，实际: %v", tt.ttl, tt.interval, tt.want, got)
+		}
+	}
+}
+
+func TestFailoverStats(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com"})
+	if err := manager.SetIPs([]string{"1.1.1.1", "2.2.2.2"}); err != nil {
+		t.Fatalf("设置IP列表失败: %v", err)
+	}
+
+	manager.GetNextIP()
+	manager.GetNextIP()
+	manager.GetNextIP()
+
+	stats := manager.GetFailoverStats()
+	if stats["total"] != uint64(3) {
+		t.Errorf("故障转移总次数应为3，实际: %v", stats["total"])
+	}
+	pairs := stats["pairs"].(map[string]uint64)
+	if pairs["1.1.1.1->2.2.2.2"] != 2 || pairs["2.2.2.2->1.1.1.1"] != 1 {
+		t.Errorf("按IP对的故障转移次数不正确: %v", pairs)
+	}
+
+	// 只记录当前IP列表中的IP
+	manager.ReportSuccess("2.2.2.2")
+	manager.ReportSuccess("9.9.9.9")
+	since := manager.sinceLastSuccess()
+	if _, ok := since["2.2.2.2"]; !ok || len(since) != 1 {
+		t.Errorf("应只包含请求成功过的IP，实际: %v", since)
+	}
+}
+
+func TestWaitReady(t *testing.T) {
+	// 健康IP数量不足时Start超时返回错误并停止管理器
+	manager := New(&Config{Hostname: "example.com", StaticIPs: []string{"1.1.1.1"}, MinHealthyIPs: 2, ReadyTimeout: 300 * time.Millisecond})
+	if err := manager.Start(context.Background()); !errors.Is(err, ErrNotEnoughHealthyIPs) {
+		t.Fatalf("健康IP不足时应返回ErrNotEnoughHealthyIPs，实际: %v", err)
+	}
+	if manager.IsRunning() || manager.IsReady() {
+		t.Error("未就绪时管理器应被停止")
+	}
+
+	manager = New(&Config{Hostname: "example.com", StaticIPs: []string{"1.1.1.1", "2.2.2.2"}, MinHealthyIPs: 2, ReadyTimeout: time.Second})
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("健康IP足够时应启动成功: %v", err)
+	}
+	defer manager.Stop()
+	if !manager.IsReady() {
+		t.Error("健康IP足够时应处于就绪状态")
+	}
+}
+
+func TestAcquireStartsOutsideRegistryLock(t *testing.T) {
+	// 启动缓慢（最终失败）的域名不应阻塞其他域名的获取
+	slowConfig := &Config{Hostname: "slow.example.com", StaticIPs: []string{"1.1.1.1"}, MinHealthyIPs: 2, ReadyTimeout: 500 * time.Millisecond}
+	slowErr := make(chan error, 2)
+	for i := 0; i < 2; i++ {
+		go func() {
+			_, err := Acquire(slowConfig)
+			slowErr <- err
+		}()
+	}
+	time.Sleep(50 * time.Millisecond)
+
+	start := time.Now()
+	fast, err := Acquire(&Config{Hostname: "fast.example.com", StaticIPs: []string{"2.2.2.2"}})
+	if err != nil {
+		t.Fatalf("获取共享管理器失败: %v", err)
+	}
+	if elapsed := time.Since(start); elapsed > 300*time.Millisecond {
+		t.Errorf("其他域名的启动不应阻塞获取，耗时: %v", elapsed)
+	}
+
+	// 以不同配置获取同一域名时复用已有管理器
+	again, err := Acquire(&Config{Hostname: "fast.example.com", StaticIPs: []string{"3.3.3.3"}})
+	if err != nil {
+		t.Fatalf("获取共享管理器失败: %v", err)
+	}
+	if again != fast {
+		t.Error("同一域名应返回同一个共享管理器")
+	}
+
+	// 同一域名的并发调用方都收到启动失败的错误，且失败的条目被移除
+	for i := 0; i < 2; i++ {
+		if err := <-slowErr; !errors.Is(err, ErrNotEnoughHealthyIPs) {
+			t.Errorf("启动失败时应返回ErrNotEnoughHealthyIPs，实际: %v", err)
+		}
+	}
+	if _, ok := GetReadiness()["slow.example.com"]; ok {
+		t.Error("启动失败的管理器应从注册表中移除")
+	}
+
+	Release(again)
+	if _, ok := GetReadiness()["fast.example.com"]; !ok {
+		t.Error("仍有引用时管理器应保留在注册表中")
+	}
+	Release(fast)
+	if _, ok := GetReadiness()["fast.example.com"]; ok {
+		t.Error("引用计数归零后管理器应从注册表中移除")
+	}
+	if fast.IsRunning() {
+		t.Error("引用计数归零后管理器应被停止")
+	}
+}
+
+func TestHealthyIPCountRequiresLatencyCheck(t *testing.T) {
+	manager := New(&Config{Hostname: "example.com", EnableLatencyCheck: true, MinHealthyIPs: 1})
+	manager.ipInfos = []*IPInfo{
+		{IP: "1.1.1.1", Available: true},
+		{IP: "2.2.2.2", Available: true, LastPing: time.Now()},
+		{IP: "3.3.3.3", Available: false, LastPing: time.Now()},
+	}
+
+	// 尚未完成检测和检测不可用的IP都不算健康
+	if count := manager.healthyIPCount(); count != 1 {
+		t.Errorf("健康IP数量应为1，实际: %d", count)
+	}
+}
+
+// testLogger 记录日志内容的测试Logger
+type testLogger struct {
+	mu       sync.Mutex
+	messages []string
+}
+
+func (l *testLogger) record(format string, args ...any) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.messages = append(l.messages, fmt.Sprintf(format, args...))
+}
+
+func (l *testLogger) Debugf(format string, args ...any) { l.record(format, args...) }
+func (l *testLogger) Infof(format string, args ...any)  { l.record(format, args...) }
+func (l *testLogger) Warnf(format string, args ...any)  { l.record(format, args...) }
+func (l *testLogger) Errorf(format string, args ...any) { l.record(format, args...) }
+
+func TestCustomLogger(t *testing.T) {
+	logger := &testLogger{}
+	manager := New(&Config{Hostname: "example.com", StaticIPs: []string{"1.1.1.1"}, Logger: logger})
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	manager.Stop()
+
+	logger.mu.Lock()
+	defer logger.mu.Unlock()
+	found := false
+	for _, msg := range logger.messages {
+		if msg == "IP Manager started for hostname: example.com" {
+			found = true
+		}
+	}
+	if !found {
+		t.Errorf("自定义Logger应收到启动日志，实际: %v", logger.messages)
+	}
+}
+
+// waitForGoroutines 等待协程数量回落到baseline以内，超时后输出仍在运行的协程堆栈
+func waitForGoroutines(t *testing.T, baseline int, timeout time.Duration) {
+	t.Helper()
+	deadline := time.Now().Add(timeout)
+	for runtime.NumGoroutine() > baseline {
+		if time.Now().After(deadline) {
+			buf := make([]byte, 1<<20)
+			n := runtime.Stack(buf, true)
+			t.Fatalf("停止后仍有协程未退出: %d > %d\n%s", runtime.NumGoroutine(), baseline, buf[:n])
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
+
+func TestStopLeavesNoGoroutines(t *testing.T) {
+	baseline := runtime.NumGoroutine()
+
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/dns-json")
+		w.Write([]byte(`{"Status":0,"Answer":[{"name":"api.example.com.","type":1,"TTL":60,"data":"203.0.113.10"}]}`))
+	}))
+
+	// 启动更新循环和延迟检测循环，并触发一轮延迟检测（TEST-NET地址不可达，检测会持续到超时）
+	manager := New(&Config{
+		Hostname:             "api.example.com",
+		PreferDoH:            true,
+		DoHServers:           []string{server.URL},
+		DNSTimeout:           time.Second,
+		EnableLatencyCheck:   true,
+		LatencyCheckInterval: 10 * time.Millisecond,
+		LatencyTimeout:       10 * time.Second,
+		LatencyCheckDeadline: 10 * time.Second,
+	})
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+	manager.ForceUpdate()
+	manager.ForceLatencyCheck()
+	time.Sleep(50 * time.Millisecond)
+
+	// Stop应中断进行中的延迟检测和初始延迟，并等待全部后台协程退出
+	start := time.Now()
+	manager.Stop()
+	if elapsed := time.Since(start); elapsed > 2*time.Second {
+		t.Errorf("Stop应中断进行中的延迟检测，实际耗时: %v", elapsed)
+	}
+
+	server.Close()
+	waitForGoroutines(t, baseline, 2*time.Second)
+}
+
+func TestNoSpawnAfterStop(t *testing.T) {
+	manager := New(&Config{
+		Hostname:           "api.example.com",
+		StaticIPs:          []string{"203.0.113.10"},
+		EnableLatencyCheck: true,
+		LatencyTimeout:     time.Second,
+	})
+	if err := manager.Start(context.Background()); err != nil {
+		t.Fatalf("启动失败: %v", err)
+	}
+
+	// Stop等待后台协程期间持续触发延迟检测：Stop之后仍能启动协程时，Stop会一直等待新加入的协程
+	deadline := time.After(200 * time.Millisecond)
+	var wg sync.WaitGroup
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		for {
+			select {
+			case <-deadline:
+				return
+			default:
+				manager.ForceLatencyCheck()
+				time.Sleep(time.Millisecond)
+			}
+		}
+	}()
+	time.Sleep(10 * time.Millisecond)
+	start := time.Now()
+	manager.Stop()
+	if elapsed := time.Since(start); elapsed > 100*time.Millisecond {
+		t.Errorf("Stop之后不应再启动后台协程，Stop耗时: %v", elapsed)
+	}
+	wg.Wait()
+
+	// Stop之后不再启动后台协程
+	var ran atomic.Bool
+	manager.spawn(func() { ran.Store(true) })
+	time.Sleep(20 * time.Millisecond)
+	if ran.Load() {
+		t.Error("Stop之后不应再启动后台协程")
+	}
+}
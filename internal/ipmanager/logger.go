@@ -0,0 +1,32 @@
+package ipmanager
+
+import "github.com/mooyang-code/data-miner/pkg/cryptotrader/log"
+
+// Logger IP管理器使用的日志接口，可通过Config.Logger注入自定义实现
+type Logger interface {
+	Debugf(format string, args ...any)
+	Infof(format string, args ...any)
+	Warnf(format string, args ...any)
+	Errorf(format string, args ...any)
+}
+
+// subLogger 默认日志实现，输出到cryptotrader的log包
+type subLogger struct {
+	sl *log.SubLogger
+}
+
+func (l subLogger) Debugf(format string, args ...any) { log.Debugf(l.sl, format, args...) }
+func (l subLogger) Infof(format string, args ...any)  { log.Infof(l.sl, format, args...) }
+func (l subLogger) Warnf(format string, args ...any)  { log.Warnf(l.sl, format, args...) }
+func (l subLogger) Errorf(format string, args ...any) { log.Errorf(l.sl, format, args...) }
+
+// defaultLogger 未注入日志实现时使用的默认日志
+var defaultLogger Logger = subLogger{sl: log.WebsocketMgr}
+
+// getLogger 返回注入的日志实现，未设置时（如未经New构造的Manager）回退到defaultLogger
+func (m *Manager) getLogger() Logger {
+	if m.logger == nil {
+		return defaultLogger
+	}
+	return m.logger
+}
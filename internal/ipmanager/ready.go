@@ -0,0 +1,60 @@
+package ipmanager
+
+import (
+	"context"
+	"fmt"
+	"time"
+)
+
+// readyPollInterval 等待就绪时检查健康IP数量的间隔
+const readyPollInterval = 200 * time.Millisecond
+
+// healthyIPCount 返回健康IP的数量：启用延迟检测时为已通过检测的可用IP，否则为解析到的IP
+func (m *Manager) healthyIPCount() int {
+	m.mu.RLock()
+	defer m.mu.RUnlock()
+
+	if !m.enableLatencyCheck {
+		return len(m.ips)
+	}
+	count := 0
+	for _, ipInfo := range m.ipInfos {
+		// 新解析的IP默认可用，需要至少完成一次检测才算健康
+		if ipInfo.Available && !ipInfo.LastPing.IsZero() {
+			count++
+		}
+	}
+	return count
+}
+
+// IsReady 管理器是否在运行且健康IP数量达到MinHealthyIPs（未设置时至少需要一个IP）
+func (m *Manager) IsReady() bool {
+	if !m.IsRunning() {
+		return false
+	}
+	if m.minHealthyIPs <= 0 {
+		return len(m.GetAllIPs()) > 0
+	}
+	return m.healthyIPCount() >= m.minHealthyIPs
+}
+
+// WaitReady 等待健康IP数量达到MinHealthyIPs，ctx结束时仍未达到则返回ErrNotEnoughHealthyIPs
+func (m *Manager) WaitReady(ctx context.Context) error {
+	ticker := time.NewTicker(readyPollInterval)
+	defer ticker.Stop()
+
+	for {
+		if m.IsReady() {
+			m.getLogger().Infof("IP Manager for %s is ready with %d healthy IPs", m.hostname, m.healthyIPCount())
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return fmt.Errorf("%w for hostname %s: %d/%d", ErrNotEnoughHealthyIPs,
+				m.hostname, m.healthyIPCount(), m.minHealthyIPs)
+		case <-m.stopChan:
+			return fmt.Errorf("%w for hostname: %s", ErrManagerNotRunning, m.hostname)
+		case <-ticker.C:
+		}
+	}
+}
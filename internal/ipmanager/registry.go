@@ -0,0 +1,127 @@
+package ipmanager
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sync"
+)
+
+// sharedManager 注册表中的共享IP管理器及其引用计数
+type sharedManager struct {
+	manager  *Manager
+	config   Config // 创建时的配置，用于发现之后以不同配置获取同一域名的调用方
+	refCount int
+
+	ready chan struct{} // 启动完成（无论成功与否）后关闭
+	err   error         // 启动失败的错误，ready关闭后有效
+}
+
+// 按域名共享的IP管理器注册表
+var (
+	registryMu sync.Mutex
+	registry   = make(map[string]*sharedManager)
+)
+
+// Acquire 获取指定域名的共享IP管理器，引用计数加一
+// 同一域名的多个客户端复用同一个管理器（及其DNS解析和延迟检测结果）；
+// 首次获取时按config创建并启动，之后的config不生效，与首次不同时输出警告。
+// 启动（DNS解析和延迟检测）在注册表锁之外进行，同一域名的并发调用方等待同一次启动，不阻塞其他域名。
+// 使用完毕后必须调用Release，不要直接调用共享管理器的Stop。
+func Acquire(config *Config) (*Manager, error) {
+	if config == nil || config.Hostname == "" {
+		return nil, fmt.Errorf("hostname is required for shared IP manager")
+	}
+
+	registryMu.Lock()
+	if entry, ok := registry[config.Hostname]; ok {
+		entry.refCount++
+		registryMu.Unlock()
+
+		<-entry.ready
+		if entry.err != nil {
+			return nil, entry.err
+		}
+		if !sameConfig(entry.config, *config) {
+			entry.manager.getLogger().Warnf("Shared IP manager for %s already exists with a different config, the new config is ignored", config.Hostname)
+		}
+		entry.manager.getLogger().Debugf("Reusing shared IP manager for %s", config.Hostname)
+		return entry.manager, nil
+	}
+
+	entry := &sharedManager{config: *config, refCount: 1, ready: make(chan struct{})}
+	entry.manager = New(config)
+	registry[config.Hostname] = entry
+	registryMu.Unlock()
+
+	// 共享管理器的生命周期由引用计数控制，不绑定任何调用方的context
+	if err := entry.manager.Start(context.Background()); err != nil {
+		registryMu.Lock()
+		entry.err = err
+		if registry[config.Hostname] == entry {
+			delete(registry, config.Hostname)
+		}
+		registryMu.Unlock()
+		close(entry.ready)
+		return nil, err
+	}
+	close(entry.ready)
+
+	entry.manager.getLogger().Infof("Created shared IP manager for %s", config.Hostname)
+	return entry.manager, nil
+}
+
+// sameConfig 比较两个配置是否相同（不比较日志实现）
+func sameConfig(a, b Config) bool {
+	a.Logger, b.Logger = nil, nil
+	return reflect.DeepEqual(a, b)
+}
+
+// Release 释放共享IP管理器，引用计数归零时停止管理器并从注册表中移除
+func Release(m *Manager) {
+	if m == nil {
+		return
+	}
+
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	entry, ok := registry[m.hostname]
+	if !ok || entry.manager != m {
+		m.getLogger().Warnf("IP manager for %s is not registered, ignoring release", m.hostname)
+		return
+	}
+
+	entry.refCount--
+	if entry.refCount > 0 {
+		m.getLogger().Debugf("Released shared IP manager for %s (refs: %d)", m.hostname, entry.refCount)
+		return
+	}
+
+	delete(registry, m.hostname)
+	m.Stop()
+}
+
+// GetFailoverStats 获取所有共享IP管理器的故障转移统计，按域名索引
+func GetFailoverStats() map[string]interface{} {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	stats := make(map[string]interface{}, len(registry))
+	for hostname, entry := range registry {
+		stats[hostname] = entry.manager.GetFailoverStats()
+	}
+	return stats
+}
+
+// GetReadiness 获取所有共享IP管理器是否就绪，按域名索引
+func GetReadiness() map[string]bool {
+	registryMu.Lock()
+	defer registryMu.Unlock()
+
+	readiness := make(map[string]bool, len(registry))
+	for hostname, entry := range registry {
+		readiness[hostname] = entry.manager.IsReady()
+	}
+	return readiness
+}
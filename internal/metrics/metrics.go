@@ -0,0 +1,29 @@
+// Package metrics 提供进程内共享的Prometheus指标注册表，各模块把指标注册到Registry，
+// 监控服务通过 GET /metrics 以Prometheus文本格式暴露
+package metrics
+
+import (
+	"net/http"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+// Namespace 所有指标名称的前缀
+const Namespace = "data_miner"
+
+// Registry 共享的指标注册表，默认包含Go运行时和进程指标
+var Registry = prometheus.NewRegistry()
+
+func init() {
+	Registry.MustRegister(
+		collectors.NewGoCollector(),
+		collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}),
+	)
+}
+
+// Handler 返回以Prometheus文本格式输出Registry中所有指标的HTTP处理器
+func Handler() http.Handler {
+	return promhttp.HandlerFor(Registry, promhttp.HandlerOpts{Registry: Registry})
+}
@@ -0,0 +1,27 @@
+package metrics
+
+import (
+	"io"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+func TestHandlerExposesRegisteredMetrics(t *testing.T) {
+	counter := prometheus.NewCounter(prometheus.CounterOpts{Namespace: Namespace, Name: "test_events_total", Help: "Test counter."})
+	Registry.MustRegister(counter)
+	defer Registry.Unregister(counter)
+	counter.Add(3)
+
+	rec := httptest.NewRecorder()
+	Handler().ServeHTTP(rec, httptest.NewRequest("GET", "/metrics", nil))
+	body, _ := io.ReadAll(rec.Body)
+
+	for _, want := range []string{"data_miner_test_events_total 3", "go_goroutines"} {
+		if !strings.Contains(string(body), want) {
+			t.Errorf("/metrics应包含%q", want)
+		}
+	}
+}
@@ -0,0 +1,263 @@
+// Package replay 提供历史数据回放功能，将文件存储输出的数据按原有节奏重新送入数据回调，
+// 用于回测和离线测试存储链路，下游代码与实时采集时完全相同
+package replay
+
+import (
+	"bufio"
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/klauspost/compress/zstd"
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// maxLineSize 单行记录的最大长度（订单簿等数据单行可能较大）
+const maxLineSize = 16 * 1024 * 1024
+
+// supportedExtensions 支持回放的文件扩展名（NDJSON格式）
+var supportedExtensions = map[string]bool{
+	".ndjson": true,
+	".jsonl":  true,
+	".json":   true,
+}
+
+// Config 回放配置
+type Config struct {
+	Paths []string // 要回放的文件或目录，目录下的文件按名称顺序回放
+	Speed float64  // 回放速度倍数：0表示尽可能快，1表示按原始时间间隔，10表示10倍速
+}
+
+// Stats 回放统计信息
+type Stats struct {
+	Files   int // 已回放的文件数
+	Records int // 已回放的记录数
+	Skipped int // 无法解析而跳过的记录数
+	Errors  int // 回调返回错误的次数
+}
+
+// Player 数据回放器
+type Player struct {
+	config    Config
+	logger    *zap.Logger
+	callbacks []types.DataCallback
+
+	lastTimestamp time.Time // 上一条记录的数据时间
+	lastEmit      time.Time // 上一条记录的回放时间
+}
+
+// NewPlayer 创建数据回放器
+func NewPlayer(config Config, logger *zap.Logger) *Player {
+	if logger == nil {
+		logger = zap.NewNop()
+	}
+	return &Player{
+		config: config,
+		logger: logger,
+	}
+}
+
+// AddCallback 添加数据回调，回放的每条数据会依次传给所有回调
+func (p *Player) AddCallback(callback types.DataCallback) {
+	p.callbacks = append(p.callbacks, callback)
+}
+
+// Run 回放所有文件，直到回放完成或ctx被取消
+func (p *Player) Run(ctx context.Context) (Stats, error) {
+	var stats Stats
+
+	files, err := p.collectFiles()
+	if err != nil {
+		return stats, err
+	}
+
+	p.lastTimestamp, p.lastEmit = time.Time{}, time.Time{}
+	for _, file := range files {
+		if err := p.replayFile(ctx, file, &stats); err != nil {
+			return stats, err
+		}
+		stats.Files++
+	}
+
+	p.logger.Info("数据回放完成",
+		zap.Int("files", stats.Files),
+		zap.Int("records", stats.Records),
+		zap.Int("skipped", stats.Skipped),
+		zap.Int("errors", stats.Errors))
+	return stats, nil
+}
+
+// collectFiles 收集要回放的文件，目录会被递归展开并按路径排序
+func (p *Player) collectFiles() ([]string, error) {
+	if len(p.config.Paths) == 0 {
+		return nil, fmt.Errorf("no replay paths configured")
+	}
+
+	var files []string
+	for _, path := range p.config.Paths {
+		info, err := os.Stat(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to stat replay path %s: %w", path, err)
+		}
+		if !info.IsDir() {
+			if err := checkExtension(path); err != nil {
+				return nil, err
+			}
+			files = append(files, path)
+			continue
+		}
+
+		var dirFiles []string
+		err = filepath.WalkDir(path, func(file string, d os.DirEntry, err error) error {
+			if err != nil {
+				return err
+			}
+			if !d.IsDir() && checkExtension(file) == nil {
+				dirFiles = append(dirFiles, file)
+			}
+			return nil
+		})
+		if err != nil {
+			return nil, fmt.Errorf("failed to walk replay directory %s: %w", path, err)
+		}
+		sort.Strings(dirFiles)
+		files = append(files, dirFiles...)
+	}
+	return files, nil
+}
+
+// checkExtension 检查文件格式是否支持回放，压缩的文件（.gz、.zst）按去掉压缩扩展名后的格式检查
+func checkExtension(file string) error {
+	ext := strings.ToLower(filepath.Ext(file))
+	switch ext {
+	case ".gz", ".zst":
+		return checkExtension(strings.TrimSuffix(file, filepath.Ext(file)))
+	}
+	if ext == ".parquet" {
+		return fmt.Errorf("parquet replay is not supported yet: %s", file)
+	}
+	if !supportedExtensions[ext] {
+		return fmt.Errorf("unsupported replay file format: %s", file)
+	}
+	return nil
+}
+
+// replayFile 回放单个NDJSON文件
+func (p *Player) replayFile(ctx context.Context, file string, stats *Stats) error {
+	f, err := os.Open(file)
+	if err != nil {
+		return fmt.Errorf("failed to open replay file %s: %w", file, err)
+	}
+	defer f.Close()
+
+	var r io.Reader = f
+	switch strings.ToLower(filepath.Ext(file)) {
+	case ".gz":
+		zr, err := gzip.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to open gzip replay file %s: %w", file, err)
+		}
+		defer zr.Close()
+		r = zr
+	case ".zst":
+		zr, err := zstd.NewReader(f)
+		if err != nil {
+			return fmt.Errorf("failed to open zstd replay file %s: %w", file, err)
+		}
+		defer zr.Close()
+		r = zr
+	}
+
+	p.logger.Info("开始回放文件", zap.String("file", file))
+	return p.replayReader(ctx, file, r, stats)
+}
+
+// replayReader 逐行读取NDJSON记录并回放
+func (p *Player) replayReader(ctx context.Context, name string, r io.Reader, stats *Stats) error {
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 64*1024), maxLineSize)
+
+	line := 0
+	for scanner.Scan() {
+		line++
+		raw := scanner.Bytes()
+		if len(strings.TrimSpace(string(raw))) == 0 {
+			continue
+		}
+
+		var record types.DataRecord
+		if err := json.Unmarshal(raw, &record); err != nil {
+			stats.Skipped++
+			p.logger.Warn("跳过无法解析的回放记录", zap.String("file", name), zap.Int("line", line), zap.Error(err))
+			continue
+		}
+		data, err := record.Decode()
+		if err != nil {
+			stats.Skipped++
+			p.logger.Warn("跳过无法解析的回放记录", zap.String("file", name), zap.Int("line", line), zap.Error(err))
+			continue
+		}
+
+		if err := p.wait(ctx, data.GetTimestamp()); err != nil {
+			return err
+		}
+		p.emit(data, stats)
+	}
+	if err := scanner.Err(); err != nil {
+		return fmt.Errorf("failed to read replay file %s: %w", name, err)
+	}
+	return nil
+}
+
+// wait 按回放速度等待到该记录应回放的时间；时间戳倒退或Speed为0时不等待
+func (p *Player) wait(ctx context.Context, timestamp time.Time) error {
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+
+	defer func() {
+		p.lastTimestamp = timestamp
+		p.lastEmit = time.Now()
+	}()
+	if p.config.Speed <= 0 || p.lastTimestamp.IsZero() || !timestamp.After(p.lastTimestamp) {
+		return nil
+	}
+
+	gap := time.Duration(float64(timestamp.Sub(p.lastTimestamp)) / p.config.Speed)
+	delay := time.Until(p.lastEmit.Add(gap))
+	if delay <= 0 {
+		return nil
+	}
+
+	timer := time.NewTimer(delay)
+	defer timer.Stop()
+	select {
+	case <-timer.C:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	}
+}
+
+// emit 将数据传给所有回调，回调错误只记录不中断回放
+func (p *Player) emit(data types.MarketData, stats *Stats) {
+	stats.Records++
+	for _, callback := range p.callbacks {
+		if err := callback(data); err != nil {
+			stats.Errors++
+			p.logger.Error("回放数据回调失败",
+				zap.String("symbol", string(data.GetSymbol())),
+				zap.String("type", string(data.GetDataType())),
+				zap.Error(err))
+		}
+	}
+}
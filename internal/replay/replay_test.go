@@ -0,0 +1,135 @@
+package replay
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// writeRecords 将市场数据写入NDJSON文件
+func writeRecords(t *testing.T, path string, lines ...interface{}) {
+	t.Helper()
+	f, err := os.Create(path)
+	if err != nil {
+		t.Fatalf("创建文件失败: %v", err)
+	}
+	defer f.Close()
+
+	encoder := json.NewEncoder(f)
+	for _, line := range lines {
+		if data, ok := line.(types.MarketData); ok {
+			record, err := types.NewDataRecord(data)
+			if err != nil {
+				t.Fatalf("编码记录失败: %v", err)
+			}
+			line = record
+		}
+		if err := encoder.Encode(line); err != nil {
+			t.Fatalf("写入记录失败: %v", err)
+		}
+	}
+}
+
+func TestPlayerReplaysRecords(t *testing.T) {
+	dir := t.TempDir()
+	start := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	writeRecords(t, filepath.Join(dir, "01.ndjson"),
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 42000, Timestamp: start},
+		map[string]string{"type": "unknown"},
+		&types.Kline{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Interval: "1m", OpenTime: start.Add(time.Minute), ClosePrice: 42100},
+	)
+	writeRecords(t, filepath.Join(dir, "02.ndjson"),
+		&types.Trade{Exchange: types.ExchangeBinance, Symbol: "ETHUSDT", ID: "1", Price: 2200, Timestamp: start.Add(2 * time.Minute)},
+	)
+	if err := os.WriteFile(filepath.Join(dir, "notes.txt"), []byte("ignored"), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	player := NewPlayer(Config{Paths: []string{dir}}, nil)
+	var received []types.MarketData
+	player.AddCallback(func(data types.MarketData) error {
+		received = append(received, data)
+		if data.GetDataType() == types.DataTypeTrades {
+			return errors.New("存储失败")
+		}
+		return nil
+	})
+
+	stats, err := player.Run(context.Background())
+	if err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if stats.Files != 2 || stats.Records != 3 || stats.Skipped != 1 || stats.Errors != 1 {
+		t.Errorf("统计信息不正确: %+v", stats)
+	}
+	if len(received) != 3 {
+		t.Fatalf("期望回调3次，实际%d次", len(received))
+	}
+
+	ticker, ok := received[0].(*types.Ticker)
+	if !ok || ticker.Price != 42000 || !ticker.Timestamp.Equal(start) {
+		t.Errorf("ticker数据不正确: %+v", received[0])
+	}
+	if kline, ok := received[1].(*types.Kline); !ok || kline.ClosePrice != 42100 {
+		t.Errorf("kline数据不正确: %+v", received[1])
+	}
+	if trade, ok := received[2].(*types.Trade); !ok || trade.Symbol != "ETHUSDT" {
+		t.Errorf("trade数据不正确: %+v", received[2])
+	}
+}
+
+func TestPlayerSpeed(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "ticker.jsonl")
+	start := time.Now()
+	writeRecords(t, path,
+		&types.Ticker{Symbol: "BTCUSDT", Timestamp: start},
+		&types.Ticker{Symbol: "BTCUSDT", Timestamp: start.Add(time.Second)},
+		&types.Ticker{Symbol: "BTCUSDT", Timestamp: start.Add(2 * time.Second)},
+	)
+
+	// 20倍速回放，2秒的数据应在约100毫秒内回放完成
+	player := NewPlayer(Config{Paths: []string{path}, Speed: 20}, nil)
+	begin := time.Now()
+	if _, err := player.Run(context.Background()); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	if elapsed := time.Since(begin); elapsed < 90*time.Millisecond || elapsed > time.Second {
+		t.Errorf("回放耗时不符合预期: %v", elapsed)
+	}
+
+	// 取消ctx后应停止回放
+	ctx, cancel := context.WithCancel(context.Background())
+	player = NewPlayer(Config{Paths: []string{path}, Speed: 0.001}, nil)
+	player.AddCallback(func(types.MarketData) error {
+		cancel()
+		return nil
+	})
+	if _, err := player.Run(ctx); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望返回context.Canceled，实际: %v", err)
+	}
+}
+
+func TestPlayerUnsupportedFormat(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "data.parquet")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if _, err := NewPlayer(Config{Paths: []string{path}}, nil).Run(context.Background()); err == nil {
+		t.Error("期望parquet文件返回错误")
+	}
+
+	// 压缩文件按去掉压缩扩展名后的格式检查
+	path = filepath.Join(t.TempDir(), "data.parquet.zst")
+	if err := os.WriteFile(path, nil, 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+	if _, err := NewPlayer(Config{Paths: []string{path}}, nil).Run(context.Background()); err == nil {
+		t.Error("期望zstd压缩的parquet文件返回错误")
+	}
+}
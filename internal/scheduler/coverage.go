@@ -0,0 +1,134 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// defaultStaleThreshold 交易对最近一次成功采集超过该时长未更新时视为数据缺失
+const defaultStaleThreshold = 10 * time.Minute
+
+// coverageKey 交易对采集成功记录的键
+type coverageKey struct {
+	exchange types.Exchange
+	dataType types.DataType
+	symbol   types.Symbol
+}
+
+// jobCoverage 任务最近一次执行时期望采集的交易对，值为交易对开始被期望采集的时间
+type jobCoverage struct {
+	exchange types.Exchange
+	dataType types.DataType
+	symbols  map[types.Symbol]time.Time
+}
+
+// StaleSymbol 最近一次成功采集过旧（或从未成功）的交易对
+type StaleSymbol struct {
+	Job           string         `json:"job"`            // 期望采集该交易对的任务
+	Exchange      types.Exchange `json:"exchange"`       // 交易所
+	DataType      types.DataType `json:"data_type"`      // 数据类型
+	Symbol        types.Symbol   `json:"symbol"`         // 交易对
+	LastSuccess   time.Time      `json:"last_success"`   // 最近一次成功采集的时间，从未成功时为零值
+	ExpectedSince time.Time      `json:"expected_since"` // 开始被任务期望采集的时间
+}
+
+// coverageTracker 按交易对记录最近一次成功采集的时间，并与各任务期望采集的交易对比较，
+// 找出总体成功率掩盖的部分缺失（交易对下架、部分接口失败等）
+type coverageTracker struct {
+	mu       sync.RWMutex
+	success  map[coverageKey]time.Time
+	expected map[string]*jobCoverage // 按任务名称索引
+}
+
+// newCoverageTracker 创建交易对覆盖率跟踪器
+func newCoverageTracker() *coverageTracker {
+	return &coverageTracker{
+		success:  make(map[coverageKey]time.Time),
+		expected: make(map[string]*jobCoverage),
+	}
+}
+
+// expect 记录任务本次执行期望采集的交易对，替换上一次的列表；仍在列表中的交易对保留开始被期望的时间
+func (c *coverageTracker) expect(job string, exchange types.Exchange, dataType types.DataType, symbols []types.Symbol, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	previous := c.expected[job]
+	current := &jobCoverage{
+		exchange: exchange,
+		dataType: dataType,
+		symbols:  make(map[types.Symbol]time.Time, len(symbols)),
+	}
+	for _, symbol := range symbols {
+		since := now
+		if previous != nil && previous.exchange == exchange && previous.dataType == dataType {
+			if t, ok := previous.symbols[symbol]; ok {
+				since = t
+			}
+		}
+		current.symbols[symbol] = since
+	}
+	c.expected[job] = current
+}
+
+// markSuccess 记录一条成功采集的数据
+func (c *coverageTracker) markSuccess(data types.MarketData, now time.Time) {
+	key := coverageKey{exchange: data.GetExchange(), dataType: data.GetDataType(), symbol: data.GetSymbol()}
+	c.mu.Lock()
+	c.success[key] = now
+	c.mu.Unlock()
+}
+
+// stale 返回期望采集但最近一次成功采集早于now-threshold的交易对，从未成功的交易对从开始被期望时计算；
+// skip返回true的任务不参与检查。结果按任务和交易对排序
+func (c *coverageTracker) stale(now time.Time, threshold time.Duration, skip func(job string) bool) []StaleSymbol {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+
+	var result []StaleSymbol
+	for job, coverage := range c.expected {
+		if skip != nil && skip(job) {
+			continue
+		}
+		for symbol, since := range coverage.symbols {
+			lastSuccess := c.success[coverageKey{exchange: coverage.exchange, dataType: coverage.dataType, symbol: symbol}]
+			reference := lastSuccess
+			if reference.IsZero() {
+				reference = since
+			}
+			if now.Sub(reference) <= threshold {
+				continue
+			}
+			result = append(result, StaleSymbol{
+				Job:           job,
+				Exchange:      coverage.exchange,
+				DataType:      coverage.dataType,
+				Symbol:        symbol,
+				LastSuccess:   lastSuccess,
+				ExpectedSince: since,
+			})
+		}
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Job != result[j].Job {
+			return result[i].Job < result[j].Job
+		}
+		return result[i].Symbol < result[j].Symbol
+	})
+	return result
+}
+
+// expectedCount 返回各任务期望采集的交易对总数
+func (c *coverageTracker) expectedCount() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	count := 0
+	for _, coverage := range c.expected {
+		count += len(coverage.symbols)
+	}
+	return count
+}
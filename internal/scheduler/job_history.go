@@ -0,0 +1,47 @@
+package scheduler
+
+import "time"
+
+// defaultJobHistorySize 每个任务默认保留的执行记录数
+const defaultJobHistorySize = 20
+
+// JobRun 一次任务执行记录
+type JobRun struct {
+	StartedAt time.Time     `json:"started_at"`      // 开始时间
+	Duration  time.Duration `json:"duration"`        // 执行耗时
+	Error     string        `json:"error,omitempty"` // 执行失败时的错误信息，成功时为空
+}
+
+// jobHistory 固定容量的任务执行记录环形缓冲区，写满后覆盖最早的记录（由调度器的锁保护）
+type jobHistory struct {
+	runs []JobRun
+	next int  // 下一条记录写入的位置
+	full bool // 是否已写满
+}
+
+// newJobHistory 创建指定容量的执行记录缓冲区，size不大于0时使用默认容量
+func newJobHistory(size int) *jobHistory {
+	if size <= 0 {
+		size = defaultJobHistorySize
+	}
+	return &jobHistory{runs: make([]JobRun, size)}
+}
+
+// add 追加一条执行记录
+func (h *jobHistory) add(run JobRun) {
+	h.runs[h.next] = run
+	h.next = (h.next + 1) % len(h.runs)
+	if h.next == 0 {
+		h.full = true
+	}
+}
+
+// list 按时间从早到晚返回执行记录的副本
+func (h *jobHistory) list() []JobRun {
+	if !h.full {
+		return append([]JobRun(nil), h.runs[:h.next]...)
+	}
+	result := make([]JobRun, 0, len(h.runs))
+	result = append(result, h.runs[h.next:]...)
+	return append(result, h.runs[:h.next]...)
+}
@@ -0,0 +1,71 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+const (
+	// defaultKlineLimit 非增量模式每次获取的K线数量
+	defaultKlineLimit = 100
+	// maxKlineLimit 单次请求K线的最大数量（Binance上限为1000）
+	maxKlineLimit = 1000
+)
+
+// klineCursorKey 增量采集K线的游标键
+type klineCursorKey struct {
+	exchange types.Exchange
+	symbol   types.Symbol
+	interval string
+}
+
+// klineCursor 记录每个交易对/周期最后一根已收盘K线的收盘时间，增量模式下只获取之后的K线
+type klineCursor struct {
+	mu        sync.Mutex
+	lastClose map[klineCursorKey]time.Time
+}
+
+// newKlineCursor 创建K线游标
+func newKlineCursor() *klineCursor {
+	return &klineCursor{
+		lastClose: make(map[klineCursorKey]time.Time),
+	}
+}
+
+// get 获取最后一根已收盘K线的收盘时间
+func (c *klineCursor) get(key klineCursorKey) (time.Time, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	lastClose, ok := c.lastClose[key]
+	return lastClose, ok
+}
+
+// advance 根据本次获取的K线推进游标。未收盘的K线不推进游标，下次仍会重新获取以得到最终数据
+func (c *klineCursor) advance(key klineCursorKey, klines []types.Kline, now time.Time) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	for _, kline := range klines {
+		if kline.CloseTime.After(now) {
+			continue
+		}
+		if kline.CloseTime.After(c.lastClose[key]) {
+			c.lastClose[key] = kline.CloseTime
+		}
+	}
+}
+
+// klineLimitSince 计算从startTime到now需要获取的K线数量（包含当前未收盘的一根），
+// 间隔过长时限制为maxKlineLimit，剩余的K线在后续运行中补齐
+func klineLimitSince(startTime, now time.Time, interval string) int {
+	duration, err := types.KlineIntervalDuration(interval)
+	if err != nil || !now.After(startTime) {
+		return defaultKlineLimit
+	}
+	limit := int(now.Sub(startTime)/duration) + 1
+	if limit > maxKlineLimit {
+		return maxKlineLimit
+	}
+	return limit
+}
@@ -0,0 +1,143 @@
+package scheduler
+
+import (
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// lastValueKey 最新值缓存的键，interval只用于K线
+type lastValueKey struct {
+	exchange types.Exchange
+	dataType types.DataType
+	symbol   types.Symbol
+	interval string
+}
+
+// lastValue 缓存的最新数据及写入缓存的时间
+type lastValue struct {
+	data      types.MarketData
+	updatedAt time.Time
+}
+
+// LastValueCache 按交易对缓存调度器最近获取的数据，无需请求API即可查询最新行情。
+// 返回的是数据副本，但订单簿等数据内部的切片与缓存共享，调用方不应修改
+type LastValueCache struct {
+	mu     sync.RWMutex
+	values map[lastValueKey]lastValue
+}
+
+// NewLastValueCache 创建最新值缓存
+func NewLastValueCache() *LastValueCache {
+	return &LastValueCache{
+		values: make(map[lastValueKey]lastValue),
+	}
+}
+
+// Update 写入一条数据，数据时间早于已缓存的数据时忽略（避免乱序的批量结果覆盖较新的数据）
+func (c *LastValueCache) Update(data types.MarketData) {
+	key, ok := lastValueKeyOf(data)
+	if !ok {
+		return
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if old, exists := c.values[key]; exists && data.GetTimestamp().Before(old.data.GetTimestamp()) {
+		return
+	}
+	c.values[key] = lastValue{data: data, updatedAt: time.Now()}
+}
+
+// GetLastTicker 获取交易对最新的ticker及其写入缓存的时间
+func (c *LastValueCache) GetLastTicker(exchange types.Exchange, symbol types.Symbol) (*types.Ticker, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeTicker, symbol: symbol})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	ticker := *value.data.(*types.Ticker)
+	return &ticker, value.updatedAt, true
+}
+
+// GetLastOrderbook 获取交易对最新的订单簿及其写入缓存的时间
+func (c *LastValueCache) GetLastOrderbook(exchange types.Exchange, symbol types.Symbol) (*types.Orderbook, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeOrderbook, symbol: symbol})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	orderbook := *value.data.(*types.Orderbook)
+	return &orderbook, value.updatedAt, true
+}
+
+// GetLastTrade 获取交易对最新的成交及其写入缓存的时间
+func (c *LastValueCache) GetLastTrade(exchange types.Exchange, symbol types.Symbol) (*types.Trade, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeTrades, symbol: symbol})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	trade := *value.data.(*types.Trade)
+	return &trade, value.updatedAt, true
+}
+
+// GetLastKline 获取交易对指定周期最新的K线及其写入缓存的时间
+func (c *LastValueCache) GetLastKline(exchange types.Exchange, symbol types.Symbol, interval string) (*types.Kline, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeKlines, symbol: symbol, interval: interval})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	kline := *value.data.(*types.Kline)
+	return &kline, value.updatedAt, true
+}
+
+// GetLastAvgPrice 获取交易对最新的平均价格及其写入缓存的时间
+func (c *LastValueCache) GetLastAvgPrice(exchange types.Exchange, symbol types.Symbol) (*types.AvgPrice, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeAvgPrice, symbol: symbol})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	avgPrice := *value.data.(*types.AvgPrice)
+	return &avgPrice, value.updatedAt, true
+}
+
+// GetLastStats24h 获取交易对最新的24小时统计及其写入缓存的时间
+func (c *LastValueCache) GetLastStats24h(exchange types.Exchange, symbol types.Symbol) (*types.Stats24h, time.Time, bool) {
+	value, ok := c.get(lastValueKey{exchange: exchange, dataType: types.DataTypeStats24h, symbol: symbol})
+	if !ok {
+		return nil, time.Time{}, false
+	}
+	stats := *value.data.(*types.Stats24h)
+	return &stats, value.updatedAt, true
+}
+
+// Len 返回缓存的条目数
+func (c *LastValueCache) Len() int {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	return len(c.values)
+}
+
+// get 获取缓存条目
+func (c *LastValueCache) get(key lastValueKey) (lastValue, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	value, ok := c.values[key]
+	return value, ok
+}
+
+// lastValueKeyOf 计算数据的缓存键，只缓存指针类型的已知数据类型
+func lastValueKeyOf(data types.MarketData) (lastValueKey, bool) {
+	key := lastValueKey{
+		exchange: data.GetExchange(),
+		dataType: data.GetDataType(),
+		symbol:   data.GetSymbol(),
+	}
+	switch v := data.(type) {
+	case *types.Kline:
+		key.interval = v.Interval
+	case *types.Ticker, *types.Orderbook, *types.Trade, *types.AvgPrice, *types.Stats24h:
+	default:
+		return key, false
+	}
+	return key, true
+}
@@ -0,0 +1,140 @@
+package scheduler
+
+import (
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// 交易对隔离的默认配置
+const (
+	defaultQuarantineThreshold    = 3               // 连续失败3次后开始隔离
+	defaultQuarantineBaseCooldown = 5 * time.Minute // 首次隔离5分钟
+	defaultQuarantineMaxCooldown  = time.Hour       // 隔离时长上限
+)
+
+// quarantineKey 隔离记录的键，K线按周期分别记录
+type quarantineKey struct {
+	exchange types.Exchange
+	symbol   types.Symbol
+	interval string
+}
+
+// quarantineEntry 交易对的连续失败记录
+type quarantineEntry struct {
+	failures  int
+	until     time.Time // 隔离结束时间，未隔离时为零值
+	lastError string
+}
+
+// QuarantinedSymbol 因连续失败被暂时跳过的交易对
+type QuarantinedSymbol struct {
+	Exchange  types.Exchange `json:"exchange"`           // 交易所
+	Symbol    types.Symbol   `json:"symbol"`             // 交易对
+	Interval  string         `json:"interval,omitempty"` // K线周期
+	Failures  int            `json:"failures"`           // 连续失败次数
+	Until     time.Time      `json:"until"`              // 隔离结束时间，之后重新尝试一次
+	LastError string         `json:"last_error"`         // 最近一次失败的错误信息
+}
+
+// symbolQuarantine 记录交易对的连续失败次数，连续失败达到阈值后在冷却时间内跳过该交易对，
+// 冷却时间随失败次数指数增长；冷却结束后重新尝试，成功即恢复
+type symbolQuarantine struct {
+	threshold    int
+	baseCooldown time.Duration
+	maxCooldown  time.Duration
+
+	mu      sync.Mutex
+	entries map[quarantineKey]*quarantineEntry
+}
+
+// newSymbolQuarantine 根据配置创建交易对隔离，未配置的项使用默认值
+func newSymbolQuarantine(config types.QuarantineConfig) *symbolQuarantine {
+	threshold := config.FailureThreshold
+	if threshold == 0 {
+		threshold = defaultQuarantineThreshold
+	}
+	baseCooldown := timeoutOrDefault(config.BaseCooldown, defaultQuarantineBaseCooldown)
+	maxCooldown := timeoutOrDefault(config.MaxCooldown, defaultQuarantineMaxCooldown)
+	return &symbolQuarantine{
+		threshold:    threshold,
+		baseCooldown: baseCooldown,
+		maxCooldown:  max(maxCooldown, baseCooldown),
+		entries:      make(map[quarantineKey]*quarantineEntry),
+	}
+}
+
+// skip 判断交易对当前是否处于隔离期
+func (q *symbolQuarantine) skip(key quarantineKey, now time.Time) bool {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[key]
+	return ok && now.Before(entry.until)
+}
+
+// failure 记录一次失败，连续失败达到阈值时开始隔离，返回本次的隔离时长（未隔离时为0）
+func (q *symbolQuarantine) failure(key quarantineKey, err error, now time.Time) time.Duration {
+	if q.threshold < 0 {
+		return 0
+	}
+
+	q.mu.Lock()
+	defer q.mu.Unlock()
+	entry, ok := q.entries[key]
+	if !ok {
+		entry = &quarantineEntry{}
+		q.entries[key] = entry
+	}
+	entry.failures++
+	entry.lastError = err.Error()
+	if entry.failures < q.threshold {
+		return 0
+	}
+
+	// 达到阈值后每多失败一次冷却时间翻倍
+	cooldown := q.baseCooldown
+	for i := q.threshold; i < entry.failures && cooldown < q.maxCooldown; i++ {
+		cooldown *= 2
+	}
+	cooldown = min(cooldown, q.maxCooldown)
+	entry.until = now.Add(cooldown)
+	return cooldown
+}
+
+// success 记录一次成功，清除失败记录
+func (q *symbolQuarantine) success(key quarantineKey) {
+	q.mu.Lock()
+	delete(q.entries, key)
+	q.mu.Unlock()
+}
+
+// list 返回当前处于隔离期的交易对，按交易对和周期排序
+func (q *symbolQuarantine) list(now time.Time) []QuarantinedSymbol {
+	q.mu.Lock()
+	defer q.mu.Unlock()
+
+	result := make([]QuarantinedSymbol, 0)
+	for key, entry := range q.entries {
+		if !now.Before(entry.until) {
+			continue
+		}
+		result = append(result, QuarantinedSymbol{
+			Exchange:  key.exchange,
+			Symbol:    key.symbol,
+			Interval:  key.interval,
+			Failures:  entry.failures,
+			Until:     entry.until,
+			LastError: entry.lastError,
+		})
+	}
+
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Symbol != result[j].Symbol {
+			return result[i].Symbol < result[j].Symbol
+		}
+		return result[i].Interval < result[j].Interval
+	})
+	return result
+}
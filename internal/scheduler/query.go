@@ -0,0 +1,80 @@
+package scheduler
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"go.uber.org/zap"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// LastValues 返回调度器的最新值缓存
+func (s *Scheduler) LastValues() *LastValueCache {
+	return s.lastValues
+}
+
+// GetTicker 查询交易对的最新ticker：缓存中的数据在maxAge内时直接返回，否则请求API并更新缓存。
+// maxAge为0时总是请求API；查询结果不会传给数据回调
+func (s *Scheduler) GetTicker(ctx context.Context, exchangeName string, symbol types.Symbol, maxAge time.Duration) (*types.Ticker, error) {
+	exchange, err := s.getExchange(exchangeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if ticker, updatedAt, ok := s.lastValues.GetLastTicker(exchange.GetName(), symbol); ok && time.Since(updatedAt) <= maxAge {
+		return ticker, nil
+	}
+
+	// 与调度任务共享同一个频控管理器的权重预算
+	if err := s.rateLimitMgr.Reserve(ctx, s.rateLimitMgr.EstimateWeight(string(types.DataTypeTicker), 1)); err != nil {
+		return nil, fmt.Errorf("failed to reserve weight for ticker %s: %w", symbol, err)
+	}
+	ticker, err := exchange.GetTicker(ctx, symbol)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get ticker for %s: %w", symbol, err)
+	}
+	s.lastValues.Update(ticker)
+	s.logger.Debug("ticker缓存未命中，已从API获取",
+		zap.String("exchange", exchangeName),
+		zap.String("symbol", string(symbol)))
+	return ticker, nil
+}
+
+// GetLatestKline 查询交易对指定周期的最新K线：缓存中的数据在maxAge内时直接返回，否则请求API并更新缓存
+func (s *Scheduler) GetLatestKline(ctx context.Context, exchangeName string, symbol types.Symbol, interval string, maxAge time.Duration) (*types.Kline, error) {
+	exchange, err := s.getExchange(exchangeName)
+	if err != nil {
+		return nil, err
+	}
+
+	if kline, updatedAt, ok := s.lastValues.GetLastKline(exchange.GetName(), symbol, interval); ok && time.Since(updatedAt) <= maxAge {
+		return kline, nil
+	}
+
+	if err := s.rateLimitMgr.Reserve(ctx, s.rateLimitMgr.EstimateWeight(string(types.DataTypeKlines), 1)); err != nil {
+		return nil, fmt.Errorf("failed to reserve weight for klines %s: %w", symbol, err)
+	}
+	klines, err := exchange.GetKlines(ctx, symbol, interval, 1)
+	if err != nil {
+		return nil, fmt.Errorf("failed to get klines for %s: %w", symbol, err)
+	}
+	if len(klines) == 0 {
+		return nil, fmt.Errorf("no kline data found for symbol %s", symbol)
+	}
+	kline := klines[len(klines)-1]
+	s.lastValues.Update(&kline)
+	return &kline, nil
+}
+
+// getExchange 获取已注册的交易所
+func (s *Scheduler) getExchange(exchangeName string) (types.ExchangeInterface, error) {
+	s.mutex.RLock()
+	defer s.mutex.RUnlock()
+	exchange, exists := s.exchanges[exchangeName]
+	if !exists {
+		return nil, fmt.Errorf("exchange %s not found", exchangeName)
+	}
+	return exchange, nil
+}
@@ -24,8 +24,13 @@ type RateLimitManager struct {
 
 	// 状态跟踪
 	lastWeightCheck time.Time
-	currentWeight   int
+	window          *weightWindow // 滚动1分钟窗口内的权重使用记录
 	serverTime      int64
+
+	// 降级处理
+	assumeFull        bool  // 获取真实权重失败时假设权重已用满
+	weightUnavailable bool  // 最近一次获取真实权重是否失败（此时只能使用本地估算）
+	weightErrors      int64 // 获取真实权重失败的累计次数
 }
 
 // NewRateLimitManager 创建新的频控管理器
@@ -36,76 +41,199 @@ func NewRateLimitManager(logger *zap.Logger) *RateLimitManager {
 		safetyThreshold:    0.9,   // 90%安全阈值
 		batchSize:          80,    // 每批80个交易对
 		lastWeightCheck:    time.Now(),
-		currentWeight:      0,
+		window:             newWeightWindow(binanceWeightWindow),
 	}
 }
 
+// SetAssumeFullOnError 设置获取真实权重失败时是否假设权重已用满。开启后权重信息不可用期间，
+// 只要窗口内有本地记录的权重就等待其全部滑出，相当于每个窗口只执行一批请求
+func (r *RateLimitManager) SetAssumeFullOnError(enabled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.assumeFull = enabled
+}
+
 // CheckAndWaitIfNeeded 检查权重使用情况，如果需要则等待
 func (r *RateLimitManager) CheckAndWaitIfNeeded(ctx context.Context, exchange types.ExchangeInterface) error {
+	return r.CheckAndWaitForWeight(ctx, exchange, 0)
+}
+
+// CheckAndWaitForWeight 检查滚动窗口内已使用权重加上即将使用的权重是否超过安全阈值，超过时等待足够的权重滑出窗口
+// 查询权重和等待期间不持有锁，避免阻塞Reserve、GetStatus等调用
+func (r *RateLimitManager) CheckAndWaitForWeight(ctx context.Context, exchange types.ExchangeInterface, needed int) error {
+	// 更新权重信息
+	r.refreshWeight(ctx, exchange)
+
+	// 检查是否超过安全阈值；真实权重不可用且开启了保守模式时，按窗口内权重已用满处理
 	r.mu.Lock()
-	defer r.mu.Unlock()
+	currentWeight := r.window.sum(time.Now())
+	assumeFull := r.assumeFull && r.weightUnavailable && currentWeight > 0
+	if !assumeFull && currentWeight+needed <= r.weightBudget() {
+		r.mu.Unlock()
+		return nil
+	}
 
-	// 尝试获取权重信息
-	if binanceExchange, ok := exchange.(interface {
-		GetTimeAndWeight(ctx context.Context) (int64, int, error)
-	}); ok {
-		serverTime, weight, err := binanceExchange.GetTimeAndWeight(ctx)
-		if err != nil {
-			r.logger.Warn("获取权重信息失败，使用本地估算", zap.Error(err))
-		} else {
-			r.currentWeight = weight
-			r.serverTime = serverTime
-			r.lastWeightCheck = time.Now()
-			
-			r.logger.Debug("权重检查",
-				zap.Int("current_weight", weight),
-				zap.Int("max_weight", r.maxWeightPerMinute),
-				zap.Float64("usage_percent", float64(weight)/float64(r.maxWeightPerMinute)*100))
-		}
+	// 计算需要等待的时间
+	waitTime := r.calculateWaitTime(needed)
+	if assumeFull {
+		waitTime = r.calculateWaitTime(r.weightBudget())
 	}
+	r.mu.Unlock()
 
-	// 检查是否超过安全阈值
-	if float64(r.currentWeight) > float64(r.maxWeightPerMinute)*r.safetyThreshold {
-		// 计算需要等待的时间
-		waitTime := r.calculateWaitTime()
+	// 限制最大等待时间，避免长时间阻塞
+	maxWaitTime := 90 * time.Second
+	if waitTime > maxWaitTime {
+		waitTime = maxWaitTime
+	}
 
-		// 限制最大等待时间，避免长时间阻塞
-		maxWaitTime := 90 * time.Second
-		if waitTime > maxWaitTime {
-			waitTime = maxWaitTime
+	r.logger.Info("权重使用接近限制，等待权重滑出窗口",
+		zap.Int("current_weight", currentWeight),
+		zap.Int("needed_weight", needed),
+		zap.Int("max_weight", r.maxWeightPerMinute),
+		zap.Bool("assume_full", assumeFull),
+		zap.Duration("wait_time", waitTime),
+		zap.Duration("max_wait_time", maxWaitTime))
+
+	// 等待足够的权重滑出窗口，过期的记录在之后的统计中自动排除
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-time.After(waitTime):
+	}
+	return nil
+}
+
+// Reserve 为调度批处理之外的即时请求（如查询API）预留权重：滚动窗口内已使用的权重加上weight
+// 超过安全阈值时阻塞到足够的权重滑出窗口。预留的权重记入窗口，1分钟后自动失效；
+// 预留后请求未实际发出时应调用Release归还
+func (r *RateLimitManager) Reserve(ctx context.Context, weight int) error {
+	if weight <= 0 {
+		return nil
+	}
+
+	for {
+		r.mu.Lock()
+		budget := r.weightBudget()
+		if weight > budget {
+			r.mu.Unlock()
+			return fmt.Errorf("requested weight %d exceeds per-minute budget %d", weight, budget)
 		}
+		now := time.Now()
+		currentWeight := r.window.sum(now)
+		if currentWeight+weight <= budget {
+			r.window.add(now, weight)
+			r.mu.Unlock()
+			return nil
+		}
+		waitTime := r.calculateWaitTime(weight)
+		r.mu.Unlock()
 
-		r.logger.Info("权重使用接近限制，等待下一分钟",
-			zap.Int("current_weight", r.currentWeight),
-			zap.Int("max_weight", r.maxWeightPerMinute),
-			zap.Duration("wait_time", waitTime),
-			zap.Duration("max_wait_time", maxWaitTime))
+		r.logger.Debug("权重预算不足，等待权重滑出窗口后预留",
+			zap.Int("current_weight", currentWeight),
+			zap.Int("reserve_weight", weight),
+			zap.Int("budget", budget),
+			zap.Duration("wait_time", waitTime))
 
-		// 等待到下一分钟
 		select {
 		case <-ctx.Done():
 			return ctx.Err()
 		case <-time.After(waitTime):
-			// 重置权重计数
-			r.currentWeight = 0
-			r.lastWeightCheck = time.Now()
 		}
 	}
+}
 
-	return nil
+// Release 归还预留但未使用的权重，从最近的记录中扣减；已滑出窗口的预留无需归还
+func (r *RateLimitManager) Release(weight int) {
+	if weight <= 0 {
+		return
+	}
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.window.release(weight)
+}
+
+// weightBudget 安全阈值内每分钟可用的权重（调用时需要持有锁）
+func (r *RateLimitManager) weightBudget() int {
+	return int(float64(r.maxWeightPerMinute) * r.safetyThreshold)
 }
 
-// calculateWaitTime 计算需要等待的时间
-func (r *RateLimitManager) calculateWaitTime() time.Duration {
-	now := time.Now()
-	
-	// 计算到下一分钟的时间
-	nextMinute := now.Truncate(time.Minute).Add(time.Minute)
-	waitTime := nextMinute.Sub(now)
-	
-	// 添加一些缓冲时间
-	waitTime += 2 * time.Second
-	
+// refreshWeight 用服务端返回的已使用权重校准滚动窗口，网络请求期间不持有锁
+// 优先使用最近一次REST响应头中的权重（窗口时长内有效），否则单独查询服务器时间和权重
+func (r *RateLimitManager) refreshWeight(ctx context.Context, exchange types.ExchangeInterface) {
+	if weight, updatedAt, ok := observedWeight(exchange); ok && time.Since(updatedAt) < binanceWeightWindow {
+		r.mu.Lock()
+		r.window.sync(updatedAt, weight)
+		r.lastWeightCheck = updatedAt
+		r.weightUnavailable = false
+		r.mu.Unlock()
+		return
+	}
+
+	binanceExchange, ok := exchange.(interface {
+		GetTimeAndWeight(ctx context.Context) (int64, int, error)
+	})
+	if !ok {
+		return
+	}
+
+	serverTime, weight, err := binanceExchange.GetTimeAndWeight(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if err != nil {
+		// 窗口内保留了上次成功校准之后每批请求的估算权重，失败时以此作为保守估计
+		r.weightUnavailable = true
+		r.weightErrors++
+		r.logger.Warn("获取权重信息失败，使用本地估算",
+			zap.Int("estimated_weight", r.window.sum(time.Now())),
+			zap.Bool("assume_full", r.assumeFull),
+			zap.Error(err))
+		return
+	}
+	r.weightUnavailable = false
+	r.lastWeightCheck = time.Now()
+	r.window.sync(r.lastWeightCheck, weight)
+	r.serverTime = serverTime
+
+	r.logger.Debug("权重检查",
+		zap.Int("current_weight", weight),
+		zap.Int("max_weight", r.maxWeightPerMinute),
+		zap.Float64("usage_percent", float64(weight)/float64(r.maxWeightPerMinute)*100))
+}
+
+// observedWeight 获取交易所最近一次响应头中记录的已使用权重
+func observedWeight(exchange types.ExchangeInterface) (int, time.Time, bool) {
+	tracker, ok := exchange.(interface {
+		GetUsedWeight() (int, time.Time)
+	})
+	if !ok {
+		return 0, time.Time{}, false
+	}
+	weight, updatedAt := tracker.GetUsedWeight()
+	return weight, updatedAt, !updatedAt.IsZero()
+}
+
+// orderbookDepthWeight 获取交易所指定深度的单个orderbook请求权重，交易所未提供时使用默认深度的权重
+func orderbookDepthWeight(exchange types.ExchangeInterface, depth int) int {
+	weigher, ok := exchange.(interface {
+		OrderbookDepthWeight(depth int) int
+	})
+	if !ok {
+		return defaultOrderbookWeight
+	}
+	if weight := weigher.OrderbookDepthWeight(depth); weight > 0 {
+		return weight
+	}
+	return defaultOrderbookWeight
+}
+
+// calculateWaitTime 计算滚动窗口内腾出needed权重需要等待的时间（调用时需要持有锁）
+func (r *RateLimitManager) calculateWaitTime(needed int) time.Duration {
+	waitTime := r.window.waitTime(time.Now(), needed, r.weightBudget())
+
+	// 添加一些缓冲时间，避免本地与服务端的计时误差
+	waitTime += time.Second
+
 	return waitTime
 }
 
@@ -122,35 +250,79 @@ func (r *RateLimitManager) EstimateWeight(operation string, count int) int {
 	case "klines":
 		return count * 2 // 每个K线请求权重为2
 	case "ticker":
+		// 使用symbols参数批量请求，1-20个交易对权重为2
 		if count <= 20 {
-			return count * 1 // 单个ticker权重为1
+			return 2
 		} else if count <= 100 {
 			return 40 // 批量ticker权重为40
 		} else {
 			return 80 // 全部ticker权重为80
 		}
 	case "orderbook":
-		return count * 10 // 每个orderbook权重为10
+		return count * defaultOrderbookWeight // 默认深度
 	case "trades":
 		return count * 1 // 每个trades权重为1
+	case "avg_price":
+		return count * 2 // 每个平均价格请求权重为2
+	case "price":
+		return 4 // 全量最新价格请求权重为4，与交易对数量无关
+	case "stats_24h":
+		return r.EstimateWeight("ticker", count) // 与ticker使用相同的24小时统计接口
 	default:
 		return count * 1 // 默认权重
 	}
 }
 
-// ProcessInBatches 分批处理交易对
+// defaultOrderbookWeight 默认深度（100）的orderbook请求权重，交易所未提供按深度计算的权重时使用
+const defaultOrderbookWeight = 5
+
+// EstimateOrderbookWeight 估算指定深度的orderbook请求权重（深度5000的权重远高于深度100）
+func (r *RateLimitManager) EstimateOrderbookWeight(exchange types.ExchangeInterface, count, depth int) int {
+	return count * orderbookDepthWeight(exchange, depth)
+}
+
+// OrderbookBatchSize 计算指定深度下每批请求的orderbook数量，保证单批权重不超过安全阈值内的权重预算
+// （如深度100每个交易对权重5，每批最多80个；深度5000每个交易对权重250，每批最多4个）
+func (r *RateLimitManager) OrderbookBatchSize(exchange types.ExchangeInterface, depth int) int {
+	weight := orderbookDepthWeight(exchange, depth)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	budget := r.weightBudget()
+	size := budget / weight
+	if size > r.batchSize {
+		size = r.batchSize
+	}
+	if size < 1 {
+		size = 1
+	}
+	return size
+}
+
+// ProcessInBatches 分批处理交易对（按K线请求估算权重）
 func (r *RateLimitManager) ProcessInBatches(ctx context.Context, symbols []types.Symbol, 
 	exchange types.ExchangeInterface, processor func([]types.Symbol) error) error {
-	
+	return r.ProcessInWeightedBatches(ctx, symbols, exchange, r.GetBatchSize(), r.EstimateWeight("klines", 1), processor)
+}
+
+// ProcessInWeightedBatches 按指定批大小分批处理交易对，weightPerSymbol为每个交易对请求的估算权重，
+// 每批开始前检查已使用权重加上本批权重是否超过安全阈值
+func (r *RateLimitManager) ProcessInWeightedBatches(ctx context.Context, symbols []types.Symbol,
+	exchange types.ExchangeInterface, batchSize, weightPerSymbol int, processor func([]types.Symbol) error) error {
+
 	totalSymbols := len(symbols)
 	if totalSymbols == 0 {
 		return nil
 	}
+	if batchSize <= 0 {
+		batchSize = r.GetBatchSize()
+	}
 
-	batchSize := r.GetBatchSize()
 	r.logger.Info("开始分批处理",
 		zap.Int("total_symbols", totalSymbols),
 		zap.Int("batch_size", batchSize),
+		zap.Int("weight_per_symbol", weightPerSymbol),
 		zap.Int("estimated_batches", (totalSymbols+batchSize-1)/batchSize))
 
 	for i := 0; i < totalSymbols; i += batchSize {
@@ -172,7 +344,8 @@ func (r *RateLimitManager) ProcessInBatches(ctx context.Context, symbols []types
 		totalBatches := (totalSymbols + batchSize - 1) / batchSize
 
 		// 检查并等待权重限制
-		if err := r.CheckAndWaitIfNeeded(ctx, exchange); err != nil {
+		estimatedWeight := weightPerSymbol * len(batch)
+		if err := r.CheckAndWaitForWeight(ctx, exchange, estimatedWeight); err != nil {
 			r.logger.Error("权重检查失败",
 				zap.Int("batch_num", batchNum),
 				zap.Error(err))
@@ -198,17 +371,23 @@ func (r *RateLimitManager) ProcessInBatches(ctx context.Context, symbols []types
 		}
 		batchDuration := time.Since(batchStartTime)
 
-		// 更新权重估算
-		estimatedWeight := r.EstimateWeight("klines", len(batch))
+		// 更新权重：批次期间有响应头记录的实际权重时用其校准，否则按估算值记入窗口
 		r.mu.Lock()
-		r.currentWeight += estimatedWeight
+		if weight, updatedAt, ok := observedWeight(exchange); ok && updatedAt.After(batchStartTime) {
+			r.window.add(batchStartTime, estimatedWeight)
+			r.window.sync(updatedAt, weight)
+			r.lastWeightCheck = updatedAt
+		} else {
+			r.window.add(time.Now(), estimatedWeight)
+		}
+		totalWeight := r.window.sum(time.Now())
 		r.mu.Unlock()
 
 		r.logger.Debug("批次处理完成",
 			zap.Int("batch_num", batchNum),
 			zap.Duration("batch_duration", batchDuration),
 			zap.Int("estimated_weight_used", estimatedWeight),
-			zap.Int("total_estimated_weight", r.currentWeight))
+			zap.Int("total_estimated_weight", totalWeight))
 
 		// 如果不是最后一批，添加小延迟避免过于频繁的请求
 		if end < totalSymbols {
@@ -220,9 +399,12 @@ func (r *RateLimitManager) ProcessInBatches(ctx context.Context, symbols []types
 		}
 	}
 
+	r.mu.RLock()
+	finalWeight := r.window.sum(time.Now())
+	r.mu.RUnlock()
 	r.logger.Info("分批处理完成",
 		zap.Int("total_symbols", totalSymbols),
-		zap.Int("final_estimated_weight", r.currentWeight))
+		zap.Int("final_estimated_weight", finalWeight))
 
 	return nil
 }
@@ -232,13 +414,18 @@ func (r *RateLimitManager) GetStatus() map[string]interface{} {
 	r.mu.RLock()
 	defer r.mu.RUnlock()
 
+	currentWeight := r.window.sum(time.Now())
 	return map[string]interface{}{
 		"max_weight_per_minute": r.maxWeightPerMinute,
-		"current_weight":        r.currentWeight,
+		"current_weight":        currentWeight,
 		"safety_threshold":      r.safetyThreshold,
 		"batch_size":           r.batchSize,
 		"last_weight_check":    r.lastWeightCheck,
 		"server_time":          r.serverTime,
-		"usage_percent":        float64(r.currentWeight) / float64(r.maxWeightPerMinute) * 100,
+		"window_entries":       len(r.window.entries),
+		"weight_unavailable":   r.weightUnavailable,
+		"weight_errors":        r.weightErrors,
+		"assume_full_on_error": r.assumeFull,
+		"usage_percent":        float64(currentWeight) / float64(r.maxWeightPerMinute) * 100,
 	}
 }
@@ -3,6 +3,7 @@ package scheduler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"sync"
 	"time"
@@ -25,8 +26,24 @@ type Scheduler struct {
 	mutex           sync.RWMutex
 	config          *types.Config // 添加配置字段
 	rateLimitMgr    *RateLimitManager // 频控管理器
+	lastValues      *LastValueCache   // 最近获取的数据，供查询时避免重复请求API
+	tradeDedup      *tradeDeduper     // 过滤轮询时重复返回的成交
+	klineCursor     *klineCursor      // 增量采集K线的游标
+	coverage        *coverageTracker  // 各交易对最近一次成功采集的时间
+	quarantine      *symbolQuarantine // 连续失败的交易对，冷却期内跳过
+	manualRuns      sync.WaitGroup    // RunJobNow触发的执行，停止时等待完成
+	stopped         bool              // 已停止，不再接受手动触发
 }
 
+var (
+	// ErrJobNotFound 任务不存在
+	ErrJobNotFound = errors.New("job not found")
+	// ErrJobRunning 任务正在执行
+	ErrJobRunning = errors.New("job is already running")
+	// ErrSchedulerStopped 调度器已停止
+	ErrSchedulerStopped = errors.New("scheduler is stopped")
+)
+
 // JobInfo 任务信息
 type JobInfo struct {
 	Config     types.JobConfig
@@ -37,6 +54,10 @@ type JobInfo struct {
 	RunCount   int64
 	ErrorCount int64
 	LastError  string
+	Paused     bool     // 已暂停，cron条目已移除，暂停期间执行中的任务结束后状态为stopped
+	History    []JobRun // 最近的执行记录（按时间从早到晚），只在GetJobStatus返回的副本中填充
+
+	history *jobHistory
 }
 
 // JobStatus 任务状态
@@ -51,14 +72,35 @@ const (
 
 // New 创建新的调度器
 func New(logger *zap.Logger, exchanges map[string]types.ExchangeInterface, callback types.DataCallback, config *types.Config) *Scheduler {
+	lastValues := NewLastValueCache()
+	coverage := newCoverageTracker()
+	rateLimitMgr := NewRateLimitManager(logger)
+	var quarantineConfig types.QuarantineConfig
+	if config != nil {
+		rateLimitMgr.SetAssumeFullOnError(config.Scheduler.RateLimit.AssumeFullOnError)
+		quarantineConfig = config.Scheduler.Quarantine
+	}
 	return &Scheduler{
-		cron:         cron.New(cron.WithSeconds()),
-		logger:       logger,
-		exchanges:    exchanges,
-		callback:     callback,
+		cron:      cron.New(cron.WithSeconds()),
+		logger:    logger,
+		exchanges: exchanges,
+		// 所有获取到的数据先写入最新值缓存并记录采集成功，再交给下游回调
+		callback: func(data types.MarketData) error {
+			lastValues.Update(data)
+			coverage.markSuccess(data, time.Now())
+			if callback == nil {
+				return nil
+			}
+			return callback(data)
+		},
 		jobs:         make(map[string]*JobInfo),
 		config:       config,
-		rateLimitMgr: NewRateLimitManager(logger),
+		rateLimitMgr: rateLimitMgr,
+		lastValues:   lastValues,
+		tradeDedup:   newTradeDeduper(),
+		klineCursor:  newKlineCursor(),
+		coverage:     coverage,
+		quarantine:   newSymbolQuarantine(quarantineConfig),
 	}
 }
 
@@ -73,6 +115,16 @@ func (s *Scheduler) AddJob(jobConfig types.JobConfig) error {
 		return fmt.Errorf("exchange %s not found", jobConfig.Exchange)
 	}
 
+	// 检查资产类型是否有效，以及交易所是否支持该资产类型
+	assetType, err := parseJobAsset(jobConfig)
+	if err != nil {
+		return err
+	}
+	if checker, ok := exchange.(interface{ IsAssetSupported(asset.Item) bool }); ok && !checker.IsAssetSupported(assetType) {
+		return fmt.Errorf("exchange %s does not support asset %s for job %s: %w",
+			jobConfig.Exchange, assetType, jobConfig.Name, asset.ErrNotSupported)
+	}
+
 	// 创建任务处理函数
 	jobFunc := s.createJobFunc(jobConfig, exchange)
 
@@ -89,86 +141,212 @@ func (s *Scheduler) AddJob(jobConfig types.JobConfig) error {
 		Status:     JobStatusPending,
 		RunCount:   0,
 		ErrorCount: 0,
+		history:    newJobHistory(s.getJobHistorySize()),
 	}
 
 	s.logger.Info("任务已添加",
 		zap.String("name", jobConfig.Name),
 		zap.String("cron", jobConfig.Cron),
 		zap.String("exchange", jobConfig.Exchange),
+		zap.String("asset", assetType.String()),
 		zap.String("dataType", jobConfig.DataType))
 
 	return nil
 }
 
-// createJobFunc 创建任务执行函数
+// createJobFunc 创建任务执行函数，上一次执行（包括手动触发的执行）未结束时跳过本次调度
 func (s *Scheduler) createJobFunc(jobConfig types.JobConfig, exchange types.ExchangeInterface) func() {
 	return func() {
 		s.mutex.Lock()
 		jobInfo := s.jobs[jobConfig.Name]
-		jobInfo.Status = JobStatusRunning
-		jobInfo.LastRun = time.Now()
-		jobInfo.RunCount++
+		if jobInfo.Status == JobStatusRunning {
+			s.mutex.Unlock()
+			s.logger.Warn("任务仍在执行，跳过本次调度", zap.String("job", jobConfig.Name))
+			return
+		}
+		startJobRun(jobInfo)
 		s.mutex.Unlock()
 
-		s.logger.Debug("开始执行任务",
-			zap.String("job", jobConfig.Name),
-			zap.String("dataType", jobConfig.DataType))
+		s.runJob(jobInfo, exchange)
+	}
+}
 
-		// 执行任务
-		err := s.executeJob(jobConfig, exchange)
+// RunJobNow 立即在后台执行一次任务，不影响cron调度；任务正在执行时返回ErrJobRunning
+func (s *Scheduler) RunJobNow(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
 
-		s.mutex.Lock()
-		if err != nil {
-			jobInfo.Status = JobStatusFailed
-			jobInfo.ErrorCount++
-			jobInfo.LastError = err.Error()
-			s.logger.Error("任务执行失败",
-				zap.String("job", jobConfig.Name),
-				zap.Error(err))
-		} else {
-			jobInfo.Status = JobStatusPending
-			jobInfo.LastError = ""
-			s.logger.Debug("任务执行成功",
-				zap.String("job", jobConfig.Name))
-		}
-		s.mutex.Unlock()
+	if s.stopped {
+		return ErrSchedulerStopped
+	}
+	jobInfo, exists := s.jobs[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if jobInfo.Status == JobStatusRunning {
+		return fmt.Errorf("%w: %s", ErrJobRunning, name)
+	}
+	exchange := s.exchanges[jobInfo.Config.Exchange]
+	startJobRun(jobInfo)
+
+	s.logger.Info("手动触发任务", zap.String("job", name))
+	s.manualRuns.Add(1)
+	go func() {
+		defer s.manualRuns.Done()
+		s.runJob(jobInfo, exchange)
+	}()
+	return nil
+}
+
+// PauseJob 暂停任务：移除cron条目但保留任务信息，状态为stopped，执行中的任务会继续执行完成
+func (s *Scheduler) PauseJob(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobInfo, exists := s.jobs[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if jobInfo.Paused {
+		return nil
+	}
+
+	s.cron.Remove(jobInfo.EntryID)
+	jobInfo.Paused = true
+	jobInfo.EntryID = 0
+	jobInfo.NextRun = time.Time{}
+	if jobInfo.Status != JobStatusRunning {
+		jobInfo.Status = JobStatusStopped
 	}
+	s.logger.Info("任务已暂停", zap.String("job", name))
+	return nil
+}
+
+// ResumeJob 恢复暂停的任务：重新添加cron条目并重新计算下次运行时间
+func (s *Scheduler) ResumeJob(name string) error {
+	s.mutex.Lock()
+	defer s.mutex.Unlock()
+
+	jobInfo, exists := s.jobs[name]
+	if !exists {
+		return fmt.Errorf("%w: %s", ErrJobNotFound, name)
+	}
+	if !jobInfo.Paused {
+		return nil
+	}
+
+	exchange := s.exchanges[jobInfo.Config.Exchange]
+	entryID, err := s.cron.AddFunc(jobInfo.Config.Cron, s.createJobFunc(jobInfo.Config, exchange))
+	if err != nil {
+		return fmt.Errorf("failed to add cron job: %w", err)
+	}
+	jobInfo.Paused = false
+	jobInfo.EntryID = entryID
+	jobInfo.NextRun = s.cron.Entry(entryID).Next
+	if jobInfo.Status != JobStatusRunning {
+		jobInfo.Status = JobStatusPending
+	}
+	s.logger.Info("任务已恢复", zap.String("job", name), zap.Time("next_run", jobInfo.NextRun))
+	return nil
+}
+
+// startJobRun 标记任务开始执行（调用时需要持有锁）
+func startJobRun(jobInfo *JobInfo) {
+	jobInfo.Status = JobStatusRunning
+	jobInfo.LastRun = time.Now()
+	jobInfo.RunCount++
+}
+
+// runJob 执行任务并记录结果，调用前需要已通过startJobRun标记开始执行
+func (s *Scheduler) runJob(jobInfo *JobInfo, exchange types.ExchangeInterface) {
+	jobConfig := jobInfo.Config
+	s.logger.Debug("开始执行任务",
+		zap.String("job", jobConfig.Name),
+		zap.String("dataType", jobConfig.DataType))
+
+	// 执行任务
+	startedAt := time.Now()
+	err := s.executeJob(jobConfig, exchange)
+	run := JobRun{StartedAt: startedAt, Duration: time.Since(startedAt)}
+
+	s.mutex.Lock()
+	if err != nil {
+		run.Error = err.Error()
+		jobInfo.Status = JobStatusFailed
+		jobInfo.ErrorCount++
+		jobInfo.LastError = err.Error()
+		s.logger.Error("任务执行失败",
+			zap.String("job", jobConfig.Name),
+			zap.Error(err))
+	} else {
+		jobInfo.Status = JobStatusPending
+		jobInfo.LastError = ""
+		s.logger.Debug("任务执行成功",
+			zap.String("job", jobConfig.Name))
+	}
+	if jobInfo.Paused {
+		// 执行期间被暂停，保持停止状态
+		jobInfo.Status = JobStatusStopped
+	}
+	jobInfo.history.add(run)
+	s.mutex.Unlock()
 }
 
 // executeJob 执行具体的任务
 func (s *Scheduler) executeJob(jobConfig types.JobConfig, exchange types.ExchangeInterface) error {
+	// 每次运行只解析一次交易对（通配符需要查询交易对缓存），超时计算、覆盖率记录和数据获取共用同一份结果
+	dataType := types.DataType(jobConfig.DataType)
+	symbols := s.getSymbolsForJob(jobConfig, dataType)
+
 	// 根据数据类型设置不同的超时时间
-	timeout := s.getTimeoutForDataType(jobConfig.DataType)
+	timeout := s.getTimeoutForJob(jobConfig, symbols)
 	ctx, cancel := context.WithTimeout(context.Background(), timeout)
 	defer cancel()
 
+	// 记录本次期望采集的交易对，用于发现长时间没有数据的交易对
+	s.coverage.expect(jobConfig.Name, exchange.GetName(), dataType, symbols, time.Now())
+
 	// 根据数据类型执行不同的操作
-	switch types.DataType(jobConfig.DataType) {
+	switch dataType {
 	case types.DataTypeTicker:
-		return s.executeTicker(ctx, jobConfig, exchange)
+		return s.executeTicker(ctx, jobConfig, exchange, symbols)
 	case types.DataTypeOrderbook:
-		return s.executeOrderbook(ctx, jobConfig, exchange)
+		return s.executeOrderbook(ctx, jobConfig, exchange, symbols)
 	case types.DataTypeTrades:
-		return s.executeTrades(ctx, jobConfig, exchange)
+		return s.executeTrades(ctx, jobConfig, exchange, symbols)
 	case types.DataTypeKlines:
-		return s.executeKlines(ctx, jobConfig, exchange)
+		return s.executeKlines(ctx, jobConfig, exchange, symbols)
+	case types.DataTypeAvgPrice:
+		return s.executeAvgPrice(ctx, jobConfig, exchange, symbols)
+	case types.DataTypeStats24h:
+		return s.executeStats24h(ctx, jobConfig, exchange, symbols)
 	default:
 		return fmt.Errorf("unsupported data type: %s", jobConfig.DataType)
 	}
 }
 
 // executeTicker 执行ticker数据获取任务
-func (s *Scheduler) executeTicker(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface) error {
-	// 获取配置中的symbols
-	symbols := s.getSymbolsForExchange(jobConfig.Exchange, types.DataTypeTicker)
+func (s *Scheduler) executeTicker(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
 	if len(symbols) == 0 {
 		return fmt.Errorf("no symbols configured for ticker data")
 	}
 
-	// 批量获取ticker数据
-	tickers, err := exchange.GetMultipleTickers(ctx, symbols)
-	if err != nil {
-		return fmt.Errorf("failed to get tickers: %v", err)
+	// 交易对数量较多时，改用一次全量价格请求代替权重较高的24小时行情接口
+	var tickers []types.Ticker
+	var err error
+	threshold := s.getBulkPriceThreshold(jobConfig.Exchange)
+	if provider, ok := exchange.(types.PriceProvider); ok && threshold > 0 && len(symbols) >= threshold {
+		s.logger.Debug("使用全量价格接口获取ticker",
+			zap.String("job", jobConfig.Name),
+			zap.Int("symbols", len(symbols)),
+			zap.Int("threshold", threshold))
+		tickers, err = provider.GetMultiplePrices(ctx, symbols)
+	} else {
+		// 批量获取ticker数据
+		tickers, err = exchange.GetMultipleTickers(ctx, symbols)
+	}
+	if err := s.checkBatchError(ctx, types.DataTypeTicker, len(tickers), err); err != nil {
+		return fmt.Errorf("failed to get tickers: %w", err)
 	}
 
 	// 调用回调函数处理数据
@@ -182,53 +360,155 @@ func (s *Scheduler) executeTicker(ctx context.Context, jobConfig types.JobConfig
 	return nil
 }
 
-// executeOrderbook 执行orderbook数据获取任务
-func (s *Scheduler) executeOrderbook(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface) error {
-	symbols := s.getSymbolsForExchange(jobConfig.Exchange, types.DataTypeOrderbook)
+// executeAvgPrice 执行平均价格数据获取任务（每个交易对一次请求，按频控分批处理）
+func (s *Scheduler) executeAvgPrice(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
+	provider, ok := exchange.(types.StatsProvider)
+	if !ok {
+		return fmt.Errorf("exchange %s does not support avg_price data", jobConfig.Exchange)
+	}
+
 	if len(symbols) == 0 {
-		return fmt.Errorf("no symbols configured for orderbook data")
+		return fmt.Errorf("no symbols configured for avg_price data")
 	}
 
-	depth := s.getDepthForExchange(jobConfig.Exchange)
+	weightPerSymbol := s.rateLimitMgr.EstimateWeight(string(types.DataTypeAvgPrice), 1)
+	return s.rateLimitMgr.ProcessInWeightedBatches(ctx, symbols, exchange, s.rateLimitMgr.GetBatchSize(), weightPerSymbol,
+		func(batch []types.Symbol) error {
+			for _, symbol := range batch {
+				avgPrice, err := provider.GetAvgPrice(ctx, symbol)
+				if err != nil {
+					if ctx.Err() != nil {
+						return ctx.Err()
+					}
+					s.logger.Error("获取平均价格失败",
+						zap.String("symbol", string(symbol)),
+						zap.Error(err))
+					continue
+				}
+
+				if err := s.callback(avgPrice); err != nil {
+					s.logger.Error("处理平均价格数据失败",
+						zap.String("symbol", string(symbol)),
+						zap.Error(err))
+				}
+			}
+			return nil
+		})
+}
 
-	// 批量获取orderbook数据
-	orderbooks, err := exchange.GetMultipleOrderbooks(ctx, symbols, depth)
-	if err != nil {
-		return fmt.Errorf("failed to get orderbooks: %v", err)
+// executeStats24h 执行24小时统计数据获取任务
+func (s *Scheduler) executeStats24h(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
+	provider, ok := exchange.(types.StatsProvider)
+	if !ok {
+		return fmt.Errorf("exchange %s does not support stats_24h data", jobConfig.Exchange)
+	}
+
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols configured for stats_24h data")
+	}
+
+	// 批量获取24小时统计数据
+	stats, err := provider.GetMultipleStats24h(ctx, symbols)
+	if err := s.checkBatchError(ctx, types.DataTypeStats24h, len(stats), err); err != nil {
+		return fmt.Errorf("failed to get 24h stats: %w", err)
 	}
 
 	// 调用回调函数处理数据
-	for _, orderbook := range orderbooks {
-		if err := s.callback(&orderbook); err != nil {
-			s.logger.Error("处理orderbook数据失败",
-				zap.String("symbol", string(orderbook.Symbol)),
+	for _, stat := range stats {
+		if err := s.callback(&stat); err != nil {
+			s.logger.Error("处理24小时统计数据失败",
+				zap.String("symbol", string(stat.Symbol)),
 				zap.Error(err))
 		}
 	}
 	return nil
 }
 
+// executeOrderbook 执行orderbook数据获取任务
+func (s *Scheduler) executeOrderbook(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
+	if len(symbols) == 0 {
+		return fmt.Errorf("no symbols configured for orderbook data")
+	}
+
+	depth := s.getDepthForExchange(jobConfig.Exchange)
+
+	// 订单簿权重随深度增加（深度5000每个交易对权重250），按深度计算批大小，保证单批权重不超过预算
+	batchSize := s.rateLimitMgr.OrderbookBatchSize(exchange, depth)
+	weightPerSymbol := s.rateLimitMgr.EstimateOrderbookWeight(exchange, 1, depth)
+
+	return s.rateLimitMgr.ProcessInWeightedBatches(ctx, symbols, exchange, batchSize, weightPerSymbol,
+		func(batch []types.Symbol) error {
+			// 批量获取orderbook数据
+			orderbooks, err := exchange.GetMultipleOrderbooks(ctx, batch, depth)
+			if err := s.checkBatchError(ctx, types.DataTypeOrderbook, len(orderbooks), err); err != nil {
+				return fmt.Errorf("failed to get orderbooks: %w", err)
+			}
+
+			// 调用回调函数处理数据
+			for _, orderbook := range orderbooks {
+				if err := s.callback(&orderbook); err != nil {
+					s.logger.Error("处理orderbook数据失败",
+						zap.String("symbol", string(orderbook.Symbol)),
+						zap.Error(err))
+				}
+			}
+			return nil
+		})
+}
+
+// checkBatchError 处理批量请求的错误：部分交易对失败（*types.BatchError）时记录失败的交易对并返回nil，
+// 以便继续处理成功的结果（succeeded为成功结果数）；全部交易对失败、ctx已取消或其他错误原样返回
+func (s *Scheduler) checkBatchError(ctx context.Context, dataType types.DataType, succeeded int, err error) error {
+	var batchErr *types.BatchError
+	if !errors.As(err, &batchErr) || succeeded == 0 || ctx.Err() != nil {
+		return err
+	}
+
+	for _, symbol := range batchErr.Symbols() {
+		s.logger.Warn("批量获取数据时部分交易对失败",
+			zap.String("data_type", string(dataType)),
+			zap.String("symbol", string(symbol)),
+			zap.Error(batchErr.Errors[symbol]))
+	}
+	return nil
+}
+
 // executeTrades 执行trades数据获取任务
-func (s *Scheduler) executeTrades(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface) error {
-	symbols := s.getSymbolsForExchange(jobConfig.Exchange, types.DataTypeTrades)
+func (s *Scheduler) executeTrades(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
 	if len(symbols) == 0 {
 		return fmt.Errorf("no symbols configured for trades data")
 	}
 
+	// 逐个交易对的结果汇总输出，详情只在Trace级别输出
+	summary := s.newSymbolLogSummary(types.DataTypeTrades, "", len(symbols))
+	defer summary.log()
+
 	// 为每个symbol获取trades数据
 	for _, symbol := range symbols {
 		trades, err := exchange.GetTrades(ctx, symbol, 100) // 默认获取100条
 		if err != nil {
-			s.logger.Error("获取trades数据失败",
+			summary.failed(symbol, err)
+			summary.trace("获取trades数据失败",
 				zap.String("symbol", string(symbol)),
 				zap.Error(err))
 			continue
 		}
+		summary.succeeded()
+
+		// 每次轮询返回的最近成交与上次有重叠，只输出新成交
+		newTrades := s.tradeDedup.filter(trades)
+		if skipped := len(trades) - len(newTrades); skipped > 0 {
+			summary.trace("过滤重复的trade数据",
+				zap.String("symbol", string(symbol)),
+				zap.Int("total", len(trades)),
+				zap.Int("skipped", skipped))
+		}
 
 		// 调用回调函数处理数据
-		for _, trade := range trades {
+		for _, trade := range newTrades {
 			if err := s.callback(&trade); err != nil {
-				s.logger.Error("处理trade数据失败",
+				summary.callbackFailed(err)
+				summary.trace("处理trade数据失败",
 					zap.String("symbol", string(trade.Symbol)),
 					zap.Error(err))
 			}
@@ -238,9 +518,8 @@ func (s *Scheduler) executeTrades(ctx context.Context, jobConfig types.JobConfig
 }
 
 // executeKlines 执行klines数据获取任务（智能频控版本）
-func (s *Scheduler) executeKlines(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface) error {
+func (s *Scheduler) executeKlines(ctx context.Context, jobConfig types.JobConfig, exchange types.ExchangeInterface, symbols []types.Symbol) error {
 	s.logger.Info("执行klines数据获取任务（智能频控）")
-	symbols := s.getSymbolsForExchange(jobConfig.Exchange, types.DataTypeKlines)
 	intervals := s.getIntervalsForExchange(jobConfig.Exchange)
 
 	if len(symbols) == 0 {
@@ -250,18 +529,28 @@ func (s *Scheduler) executeKlines(ctx context.Context, jobConfig types.JobConfig
 		intervals = []string{"1m"} // 默认1分钟
 	}
 
+	// 增量模式需要交易所支持按起始时间获取K线
+	incremental := s.isKlinesIncremental(jobConfig.Exchange)
+	if _, ok := exchange.(types.KlineRangeProvider); incremental && !ok {
+		s.logger.Warn("交易所不支持按起始时间获取K线，使用全量模式", zap.String("exchange", jobConfig.Exchange))
+		incremental = false
+	}
+
 	s.logger.Info("开始智能批量获取K线数据",
 		zap.Int("total_symbols", len(symbols)),
-		zap.Strings("intervals", intervals))
+		zap.Strings("intervals", intervals),
+		zap.Bool("incremental", incremental))
 
 	// 为每个interval分别处理
 	for _, interval := range intervals {
 		s.logger.Info("处理K线间隔", zap.String("interval", interval))
 
-		// 使用频控管理器分批处理
+		// 使用频控管理器分批处理，逐个交易对的结果按周期汇总输出
+		summary := s.newSymbolLogSummary(types.DataTypeKlines, interval, len(symbols))
 		err := s.rateLimitMgr.ProcessInBatches(ctx, symbols, exchange, func(batch []types.Symbol) error {
-			return s.processBatchKlines(ctx, batch, interval, exchange)
+			return s.processBatchKlines(ctx, batch, interval, exchange, incremental, summary)
 		})
+		summary.log()
 
 		if err != nil {
 			s.logger.Error("批量处理K线数据失败",
@@ -275,10 +564,12 @@ func (s *Scheduler) executeKlines(ctx context.Context, jobConfig types.JobConfig
 	return nil
 }
 
-// processBatchKlines 处理一批K线数据
-func (s *Scheduler) processBatchKlines(ctx context.Context, symbols []types.Symbol, interval string, exchange types.ExchangeInterface) error {
+// processBatchKlines 处理一批K线数据，增量模式下只获取上次采集之后的K线。
+// 逐个交易对的结果记入summary，详情只在Trace级别或交易对连续失败达到隔离阈值时输出
+func (s *Scheduler) processBatchKlines(ctx context.Context, symbols []types.Symbol, interval string, exchange types.ExchangeInterface, incremental bool, summary *symbolLogSummary) error {
 	successCount := 0
 	errorCount := 0
+	skippedCount := 0
 
 	for i, symbol := range symbols {
 		// 检查上下文是否已取消
@@ -294,27 +585,54 @@ func (s *Scheduler) processBatchKlines(ctx context.Context, symbols []types.Symb
 		default:
 		}
 
+		// 连续失败的交易对在冷却期内跳过
+		key := quarantineKey{exchange: exchange.GetName(), symbol: symbol, interval: interval}
+		if s.quarantine.skip(key, time.Now()) {
+			skippedCount++
+			summary.skip()
+			continue
+		}
+
 		// 为单个API调用设置较短的超时时间
 		apiCtx, apiCancel := context.WithTimeout(ctx, 30*time.Second)
-		klines, err := exchange.GetKlines(apiCtx, symbol, interval, 100) // 默认获取100条
+		klines, err := s.fetchKlines(apiCtx, symbol, interval, exchange, incremental)
 		apiCancel()
 
 		if err != nil {
 			errorCount++
-			s.logger.Error("获取klines数据失败",
+			summary.failed(symbol, err)
+			summary.trace("获取klines数据失败",
 				zap.String("symbol", string(symbol)),
 				zap.String("interval", interval),
 				zap.Int("symbol_index", i+1),
 				zap.Int("total_symbols", len(symbols)),
 				zap.Error(err))
+			// 任务本身超时或取消导致的失败不计入交易对的连续失败
+			if ctx.Err() == nil {
+				if cooldown := s.quarantine.failure(key, err, time.Now()); cooldown > 0 {
+					s.logger.Warn("交易对连续获取失败，暂时跳过",
+						zap.String("symbol", string(symbol)),
+						zap.String("interval", interval),
+						zap.Duration("cooldown", cooldown),
+						zap.Error(err))
+				}
+			}
 			continue
 		}
 
 		successCount++
+		summary.succeeded()
+		s.quarantine.success(key)
+		if incremental {
+			cursorKey := klineCursorKey{exchange: exchange.GetName(), symbol: symbol, interval: interval}
+			s.klineCursor.advance(cursorKey, klines, time.Now())
+		}
+
 		// 调用回调函数处理数据
 		for _, kline := range klines {
 			if err := s.callback(&kline); err != nil {
-				s.logger.Error("处理kline数据失败",
+				summary.callbackFailed(err)
+				summary.trace("处理kline数据失败",
 					zap.String("symbol", string(kline.Symbol)),
 					zap.String("interval", kline.Interval),
 					zap.Error(err))
@@ -322,15 +640,29 @@ func (s *Scheduler) processBatchKlines(ctx context.Context, symbols []types.Symb
 		}
 	}
 
-	s.logger.Debug("批次K线处理完成",
+	summary.trace("批次K线处理完成",
 		zap.String("interval", interval),
 		zap.Int("total", len(symbols)),
 		zap.Int("success", successCount),
-		zap.Int("errors", errorCount))
+		zap.Int("errors", errorCount),
+		zap.Int("skipped", skippedCount))
 
 	return nil
 }
 
+// fetchKlines 获取K线：增量模式且已有游标时从上次最后一根已收盘K线之后开始获取，否则获取最近100根
+func (s *Scheduler) fetchKlines(ctx context.Context, symbol types.Symbol, interval string, exchange types.ExchangeInterface, incremental bool) ([]types.Kline, error) {
+	if incremental {
+		key := klineCursorKey{exchange: exchange.GetName(), symbol: symbol, interval: interval}
+		if lastClose, ok := s.klineCursor.get(key); ok {
+			startTime := lastClose.Add(time.Millisecond)
+			limit := klineLimitSince(startTime, time.Now(), interval)
+			return exchange.(types.KlineRangeProvider).GetKlinesSince(ctx, symbol, interval, startTime, limit)
+		}
+	}
+	return exchange.GetKlines(ctx, symbol, interval, defaultKlineLimit)
+}
+
 // Start 启动调度器
 func (s *Scheduler) Start() error {
 	s.cron.Start()
@@ -338,12 +670,22 @@ func (s *Scheduler) Start() error {
 	return nil
 }
 
-// Stop 停止调度器
+// Stop 停止调度器，等待执行中的任务（包括手动触发的任务）完成直到ctx结束
 func (s *Scheduler) Stop(ctx context.Context) error {
+	s.mutex.Lock()
+	s.stopped = true
+	s.mutex.Unlock()
+
 	stopCtx := s.cron.Stop()
+	done := make(chan struct{})
+	go func() {
+		<-stopCtx.Done()
+		s.manualRuns.Wait()
+		close(done)
+	}()
 
 	select {
-	case <-stopCtx.Done():
+	case <-done:
 		s.logger.Info("调度器已停止")
 		return nil
 	case <-ctx.Done():
@@ -372,11 +714,42 @@ func (s *Scheduler) GetJobStatus() map[string]*JobInfo {
 			RunCount:   job.RunCount,
 			ErrorCount: job.ErrorCount,
 			LastError:  job.LastError,
+			Paused:     job.Paused,
+			History:    job.history.list(),
 		}
 	}
 	return result
 }
 
+// StaleSymbols 返回各任务期望采集、但最近一次成功采集早于threshold之前的交易对（从未成功的从开始被期望时计算），
+// threshold不大于0时使用配置的stale_threshold。暂停的任务不参与检查
+func (s *Scheduler) StaleSymbols(threshold time.Duration) []StaleSymbol {
+	if threshold <= 0 {
+		threshold = s.getStaleThreshold()
+	}
+	return s.coverage.stale(time.Now(), threshold, func(job string) bool {
+		s.mutex.RLock()
+		defer s.mutex.RUnlock()
+		jobInfo, exists := s.jobs[job]
+		return !exists || jobInfo.Paused
+	})
+}
+
+// GetCoverageStatus 获取交易对覆盖情况：期望采集的交易对数和按配置阈值判定的缺失数据交易对数
+func (s *Scheduler) GetCoverageStatus() map[string]interface{} {
+	threshold := s.getStaleThreshold()
+	return map[string]interface{}{
+		"expected_symbols": s.coverage.expectedCount(),
+		"stale_symbols":    len(s.StaleSymbols(threshold)),
+		"stale_threshold":  threshold.String(),
+	}
+}
+
+// GetQuarantinedSymbols 获取因连续失败当前被暂时跳过的交易对
+func (s *Scheduler) GetQuarantinedSymbols() []QuarantinedSymbol {
+	return s.quarantine.list(time.Now())
+}
+
 // GetRateLimitStatus 获取频控状态
 func (s *Scheduler) GetRateLimitStatus() map[string]interface{} {
 	if s.rateLimitMgr == nil {
@@ -387,8 +760,31 @@ func (s *Scheduler) GetRateLimitStatus() map[string]interface{} {
 	return s.rateLimitMgr.GetStatus()
 }
 
+// parseJobAsset 解析任务配置的资产类型，未配置时默认为现货
+func parseJobAsset(jobConfig types.JobConfig) (asset.Item, error) {
+	if jobConfig.Asset == "" {
+		return asset.Spot, nil
+	}
+
+	assetType, err := asset.New(jobConfig.Asset)
+	if err != nil {
+		return asset.Empty, fmt.Errorf("invalid asset for job %s: %w", jobConfig.Name, err)
+	}
+	return assetType, nil
+}
+
+// getSymbolsForJob 根据任务配置（交易所、资产类型）获取交易对列表
+func (s *Scheduler) getSymbolsForJob(jobConfig types.JobConfig, dataType types.DataType) []types.Symbol {
+	assetType, err := parseJobAsset(jobConfig)
+	if err != nil {
+		s.logger.Error("解析任务资产类型失败", zap.String("job", jobConfig.Name), zap.Error(err))
+		return []types.Symbol{}
+	}
+	return s.getSymbolsForExchange(jobConfig.Exchange, dataType, assetType)
+}
+
 // getSymbolsForExchange 从配置中获取交易对列表
-func (s *Scheduler) getSymbolsForExchange(exchangeName string, dataType types.DataType) []types.Symbol {
+func (s *Scheduler) getSymbolsForExchange(exchangeName string, dataType types.DataType, assetType asset.Item) []types.Symbol {
 	if s.config == nil {
 		s.logger.Warn("配置为空，使用默认交易对")
 		return []types.Symbol{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
@@ -396,7 +792,9 @@ func (s *Scheduler) getSymbolsForExchange(exchangeName string, dataType types.Da
 
 	switch exchangeName {
 	case "binance":
-		return s.getBinanceSymbols(dataType)
+		return s.getBinanceSymbols(dataType, assetType)
+	case "kraken":
+		return s.getKrakenSymbols(dataType)
 	default:
 		s.logger.Warn("不支持的交易所", zap.String("exchange", exchangeName))
 		return []types.Symbol{}
@@ -404,29 +802,35 @@ func (s *Scheduler) getSymbolsForExchange(exchangeName string, dataType types.Da
 }
 
 // getBinanceSymbols 获取Binance交易对列表
-func (s *Scheduler) getBinanceSymbols(dataType types.DataType) []types.Symbol {
+func (s *Scheduler) getBinanceSymbols(dataType types.DataType, assetType asset.Item) []types.Symbol {
 	binanceConfig := s.config.Exchanges.Binance
 
 	var configSymbols []string
+	var filter types.SymbolFilter
 	switch dataType {
 	case types.DataTypeTicker:
-		configSymbols = binanceConfig.DataTypes.Ticker.Symbols
+		configSymbols, filter = binanceConfig.DataTypes.Ticker.Symbols, binanceConfig.DataTypes.Ticker.SymbolFilter
 	case types.DataTypeOrderbook:
-		configSymbols = binanceConfig.DataTypes.Orderbook.Symbols
+		configSymbols, filter = binanceConfig.DataTypes.Orderbook.Symbols, binanceConfig.DataTypes.Orderbook.SymbolFilter
 	case types.DataTypeTrades:
-		configSymbols = binanceConfig.DataTypes.Trades.Symbols
+		configSymbols, filter = binanceConfig.DataTypes.Trades.Symbols, binanceConfig.DataTypes.Trades.SymbolFilter
 	case types.DataTypeKlines:
-		configSymbols = binanceConfig.DataTypes.Klines.Symbols
+		configSymbols, filter = binanceConfig.DataTypes.Klines.Symbols, binanceConfig.DataTypes.Klines.SymbolFilter
+	case types.DataTypeAvgPrice:
+		configSymbols, filter = binanceConfig.DataTypes.AvgPrice.Symbols, binanceConfig.DataTypes.AvgPrice.SymbolFilter
+	case types.DataTypeStats24h:
+		configSymbols, filter = binanceConfig.DataTypes.Stats24h.Symbols, binanceConfig.DataTypes.Stats24h.SymbolFilter
 	default:
 		s.logger.Warn("不支持的数据类型", zap.String("dataType", string(dataType)))
 		return []types.Symbol{}
 	}
 
-	// 如果配置中包含"*"，则从cache中获取所有可用交易对
+	// 如果配置中包含"*"，则从cache中获取所有可用交易对，并按计价币种和包含/排除模式过滤
 	if len(configSymbols) == 1 && configSymbols[0] == "*" {
 		s.logger.Debug("从cache获取所有交易对",
-			zap.String("dataType", string(dataType)))
-		return s.getTradablePairsFromCache(dataType)
+			zap.String("dataType", string(dataType)),
+			zap.String("asset", assetType.String()))
+		return s.getTradablePairsFromCache(dataType, assetType, filter)
 	}
 
 	// 转换为Symbol类型
@@ -444,8 +848,39 @@ func (s *Scheduler) getBinanceSymbols(dataType types.DataType) []types.Symbol {
 	return symbols
 }
 
-// getTradablePairsFromCache 从cache中获取可交易的交易对
-func (s *Scheduler) getTradablePairsFromCache(dataType types.DataType) []types.Symbol {
+// getKrakenSymbols 获取Kraken交易对列表，Kraken没有交易对缓存，只使用配置中明确的交易对
+func (s *Scheduler) getKrakenSymbols(dataType types.DataType) []types.Symbol {
+	krakenConfig := s.config.Exchanges.Kraken
+
+	var configSymbols []string
+	switch dataType {
+	case types.DataTypeTicker:
+		configSymbols = krakenConfig.DataTypes.Ticker.Symbols
+	case types.DataTypeOrderbook:
+		configSymbols = krakenConfig.DataTypes.Orderbook.Symbols
+	case types.DataTypeTrades:
+		configSymbols = krakenConfig.DataTypes.Trades.Symbols
+	case types.DataTypeKlines:
+		configSymbols = krakenConfig.DataTypes.Klines.Symbols
+	default:
+		s.logger.Warn("Kraken不支持的数据类型", zap.String("dataType", string(dataType)))
+		return []types.Symbol{}
+	}
+
+	if len(configSymbols) == 1 && configSymbols[0] == "*" {
+		s.logger.Warn("Kraken不支持通配符交易对，请明确配置交易对", zap.String("dataType", string(dataType)))
+		return []types.Symbol{}
+	}
+
+	symbols := make([]types.Symbol, 0, len(configSymbols))
+	for _, symbol := range configSymbols {
+		symbols = append(symbols, types.Symbol(symbol))
+	}
+	return symbols
+}
+
+// getTradablePairsFromCache 从cache中获取指定资产类型可交易的交易对，并按filter过滤
+func (s *Scheduler) getTradablePairsFromCache(dataType types.DataType, assetType asset.Item, filter types.SymbolFilter) []types.Symbol {
 	// 检查配置中的fetch_from_api开关
 	if s.config == nil || !s.config.Exchanges.Binance.TradablePairs.FetchFromAPI {
 		s.logger.Warn("fetch_from_api配置未启用，跳过从缓存获取交易对",
@@ -471,21 +906,36 @@ func (s *Scheduler) getTradablePairsFromCache(dataType types.DataType) []types.S
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
-	// 从cache获取现货交易对
-	pairs, err := binanceInterface.GetTradablePairsFromCache(ctx, asset.Spot)
+	// 从cache获取对应资产类型的交易对
+	pairs, err := binanceInterface.GetTradablePairsFromCache(ctx, assetType)
 	if err != nil {
-		s.logger.Error("从cache获取交易对失败", zap.Error(err))
+		s.logger.Error("从cache获取交易对失败",
+			zap.String("asset", assetType.String()),
+			zap.Error(err))
 		return []types.Symbol{}
 	}
 
+	// 按计价币种和包含/排除模式过滤（计价币种取自Pair.Quote，不依赖符号拆分）
+	filtered := filter.ApplyPairs(pairs)
+	if !filter.IsEmpty() {
+		s.logger.Info("按过滤条件筛选交易对",
+			zap.String("dataType", string(dataType)),
+			zap.Strings("quote_assets", filter.QuoteAssets),
+			zap.Strings("include", filter.IncludePatterns),
+			zap.Strings("exclude", filter.ExcludePatterns),
+			zap.Int("total", len(pairs)),
+			zap.Int("matched", len(filtered)))
+	}
+
 	// 转换为Symbol类型
-	symbols := make([]types.Symbol, 0, len(pairs))
-	for _, pair := range pairs {
-		symbols = append(symbols, types.Symbol(pair.String()))
+	symbols := make([]types.Symbol, 0, len(filtered))
+	for _, pair := range filtered {
+		symbols = append(symbols, types.PairToSymbol(pair))
 	}
 
 	s.logger.Info("从cache获取交易对成功",
 		zap.String("dataType", string(dataType)),
+		zap.String("asset", assetType.String()),
 		zap.Int("count", len(symbols)),
 		zap.Bool("fetch_from_api", s.config.Exchanges.Binance.TradablePairs.FetchFromAPI))
 
@@ -501,11 +951,44 @@ func (s *Scheduler) getDepthForExchange(exchangeName string) int {
 	switch exchangeName {
 	case "binance":
 		return s.config.Exchanges.Binance.DataTypes.Orderbook.Depth
+	case "kraken":
+		if depth := s.config.Exchanges.Kraken.DataTypes.Orderbook.Depth; depth > 0 {
+			return depth
+		}
+		return 20 // 默认深度
 	default:
 		return 20 // 默认深度
 	}
 }
 
+// getBulkPriceThreshold 获取改用全量价格接口的交易对数量阈值，0表示不启用
+func (s *Scheduler) getBulkPriceThreshold(exchangeName string) int {
+	if s.config == nil {
+		return 0
+	}
+
+	switch exchangeName {
+	case "binance":
+		return s.config.Exchanges.Binance.DataTypes.Ticker.BulkPriceThreshold
+	default:
+		return 0
+	}
+}
+
+// isKlinesIncremental 是否启用K线增量采集
+func (s *Scheduler) isKlinesIncremental(exchangeName string) bool {
+	if s.config == nil {
+		return false
+	}
+
+	switch exchangeName {
+	case "binance":
+		return s.config.Exchanges.Binance.DataTypes.Klines.Incremental
+	default:
+		return false
+	}
+}
+
 // getIntervalsForExchange 获取K线时间间隔
 func (s *Scheduler) getIntervalsForExchange(exchangeName string) []string {
 	if s.config == nil {
@@ -519,28 +1002,87 @@ func (s *Scheduler) getIntervalsForExchange(exchangeName string) []string {
 			return []string{"1m"} // 默认1分钟
 		}
 		return intervals
+	case "kraken":
+		intervals := s.config.Exchanges.Kraken.DataTypes.Klines.Intervals
+		if len(intervals) == 0 {
+			return []string{"1m"} // 默认1分钟
+		}
+		return intervals
 	default:
 		return []string{"1m", "5m", "1h"} // 默认间隔
 	}
 }
 
-// getTimeoutForDataType 根据数据类型获取超时时间
+// getJobHistorySize 获取每个任务保留的执行记录数
+func (s *Scheduler) getJobHistorySize() int {
+	if s.config == nil {
+		return defaultJobHistorySize
+	}
+	return s.config.Scheduler.JobHistorySize
+}
+
+// 各数据类型任务的默认超时时间
+const (
+	defaultKlinesTimeout    = 5 * time.Minute // K线数据需要更长时间，因为可能有多个间隔和大量交易对
+	defaultTickerTimeout    = 2 * time.Minute // Ticker和24小时统计数据相对简单
+	defaultAvgPriceTimeout  = 3 * time.Minute // 平均价格每个交易对一次请求，交易对多时需要分批
+	defaultOrderbookTimeout = 3 * time.Minute // Orderbook数据中等复杂度
+	defaultTradesTimeout    = 3 * time.Minute // Trades数据中等复杂度
+	defaultJobTimeout       = 2 * time.Minute // 其他数据类型
+)
+
+// getStaleThreshold 获取判定交易对数据缺失的阈值，未配置时默认10分钟
+func (s *Scheduler) getStaleThreshold() time.Duration {
+	if s.config == nil {
+		return defaultStaleThreshold
+	}
+	return timeoutOrDefault(s.config.Scheduler.StaleThreshold, defaultStaleThreshold)
+}
+
+// getTimeoutForJob 获取任务的超时时间，配置了klines_per_series时K线任务按交易对数×周期数放大（symbols为本次运行已解析的交易对）
+func (s *Scheduler) getTimeoutForJob(jobConfig types.JobConfig, symbols []types.Symbol) time.Duration {
+	timeout := s.getTimeoutForDataType(jobConfig.DataType)
+	if types.DataType(jobConfig.DataType) != types.DataTypeKlines || s.config == nil {
+		return timeout
+	}
+
+	perSeries := s.config.Scheduler.Timeouts.KlinesPerSeries
+	if perSeries <= 0 {
+		return timeout
+	}
+	series := len(symbols) * len(s.getIntervalsForExchange(jobConfig.Exchange))
+	return max(timeout, perSeries*time.Duration(series))
+}
+
+// getTimeoutForDataType 根据数据类型获取超时时间，未配置时使用默认值
 func (s *Scheduler) getTimeoutForDataType(dataType string) time.Duration {
+	var timeouts types.JobTimeoutConfig
+	if s.config != nil {
+		timeouts = s.config.Scheduler.Timeouts
+	}
+
 	switch types.DataType(dataType) {
 	case types.DataTypeKlines:
-		// K线数据需要更长时间，因为可能有多个间隔和大量交易对
-		return 5 * time.Minute
+		return timeoutOrDefault(timeouts.Klines, defaultKlinesTimeout)
 	case types.DataTypeTicker:
-		// Ticker数据相对简单
-		return 2 * time.Minute
+		return timeoutOrDefault(timeouts.Ticker, defaultTickerTimeout)
+	case types.DataTypeStats24h:
+		return timeoutOrDefault(timeouts.Stats24h, defaultTickerTimeout)
+	case types.DataTypeAvgPrice:
+		return timeoutOrDefault(timeouts.AvgPrice, defaultAvgPriceTimeout)
 	case types.DataTypeOrderbook:
-		// Orderbook数据中等复杂度
-		return 3 * time.Minute
+		return timeoutOrDefault(timeouts.Orderbook, defaultOrderbookTimeout)
 	case types.DataTypeTrades:
-		// Trades数据中等复杂度
-		return 3 * time.Minute
+		return timeoutOrDefault(timeouts.Trades, defaultTradesTimeout)
 	default:
-		// 默认超时时间
-		return 2 * time.Minute
+		return timeoutOrDefault(timeouts.Default, defaultJobTimeout)
+	}
+}
+
+// timeoutOrDefault 配置的超时时间大于0时使用配置值，否则使用默认值
+func timeoutOrDefault(configured, fallback time.Duration) time.Duration {
+	if configured > 0 {
+		return configured
 	}
+	return fallback
 }
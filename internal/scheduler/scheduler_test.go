@@ -0,0 +1,1095 @@
+package scheduler
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	"go.uber.org/zap/zaptest/observer"
+
+	"github.com/mooyang-code/data-miner/internal/exchanges/asset"
+	"github.com/mooyang-code/data-miner/internal/exchanges/binance"
+	"github.com/mooyang-code/data-miner/internal/exchanges/kraken"
+	"github.com/mooyang-code/data-miner/internal/exchanges/mock"
+	"github.com/mooyang-code/data-miner/internal/types"
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// newTestScheduler 创建使用模拟交易所的调度器，回调收到的数据记录在返回的切片中
+func newTestScheduler(t *testing.T, exchange *mock.Exchange, config *types.Config) (*Scheduler, func() []types.MarketData) {
+	t.Helper()
+
+	var mu sync.Mutex
+	var received []types.MarketData
+	callback := func(data types.MarketData) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, data)
+		return nil
+	}
+
+	exchanges := map[string]types.ExchangeInterface{"binance": exchange}
+	s := New(zap.NewNop(), exchanges, callback, config)
+	return s, func() []types.MarketData {
+		mu.Lock()
+		defer mu.Unlock()
+		return append([]types.MarketData(nil), received...)
+	}
+}
+
+func TestExecuteKlinesWithMock(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetKlines, "XRPUSDT", errors.New("symbol not found"))
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "XRPUSDT", "SOLUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m", "5m"}
+
+	s, received := newTestScheduler(t, exchange, config)
+	s.rateLimitMgr.batchSize = 2
+
+	job := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines)}
+	if err := s.executeKlines(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeKlines)); err != nil {
+		t.Fatalf("executeKlines失败: %v", err)
+	}
+
+	// 每个间隔请求5个交易对，其中XRPUSDT失败，每个成功的交易对返回100根K线
+	if calls := exchange.CallCount(mock.MethodGetKlines); calls != 10 {
+		t.Errorf("期望GetKlines调用10次，实际%d次", calls)
+	}
+	data := received()
+	if len(data) != 2*4*100 {
+		t.Fatalf("期望回调%d次，实际%d次", 2*4*100, len(data))
+	}
+	for _, item := range data {
+		kline, ok := item.(*types.Kline)
+		if !ok {
+			t.Fatalf("期望*types.Kline，实际%T", item)
+		}
+		if kline.Symbol == "XRPUSDT" {
+			t.Errorf("失败的交易对不应该产生回调数据")
+		}
+	}
+
+	// 5个交易对按每批2个分为3批，每个间隔每批检查一次权重
+	if calls := exchange.CallCount(mock.MethodGetTimeAndWeight); calls != 2*3 {
+		t.Errorf("期望权重检查%d次，实际%d次", 2*3, calls)
+	}
+
+	// 交易对按配置顺序分批处理
+	symbols := exchange.CalledSymbols(mock.MethodGetKlines)
+	want := []types.Symbol{"BTCUSDT", "ETHUSDT", "BNBUSDT", "XRPUSDT", "SOLUSDT"}
+	for i, symbol := range want {
+		if symbols[i] != symbol {
+			t.Errorf("第%d个请求的交易对期望%s，实际%s", i+1, symbol, symbols[i])
+		}
+	}
+}
+
+func TestExecuteOrderbookPartialFailure(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetMultipleOrderbooks, "ETHUSDT", errors.New("timeout"))
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Orderbook.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+	config.Exchanges.Binance.DataTypes.Orderbook.Depth = 5
+
+	s, received := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "orderbook", Exchange: "binance", DataType: string(types.DataTypeOrderbook)}
+	if err := s.executeOrderbook(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeOrderbook)); err != nil {
+		t.Fatalf("部分交易对失败时不应返回错误: %v", err)
+	}
+
+	data := received()
+	if len(data) != 2 {
+		t.Fatalf("期望回调2次，实际%d次", len(data))
+	}
+	if orderbook := data[0].(*types.Orderbook); orderbook.Symbol != "BTCUSDT" || len(orderbook.Bids) != 5 {
+		t.Errorf("订单簿数据不正确: %+v", orderbook)
+	}
+
+	// 整个请求失败时返回错误
+	exchange.SetError(mock.MethodGetMultipleOrderbooks, "", errors.New("service unavailable"))
+	if err := s.executeOrderbook(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeOrderbook)); err == nil {
+		t.Error("期望整个请求失败时返回错误")
+	}
+}
+
+func TestExecuteBatchAllSymbolsFailed(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetMultipleTickers, "BTCUSDT", errors.New("timeout"))
+	exchange.SetError(mock.MethodGetMultipleTickers, "ETHUSDT", errors.New("timeout"))
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT", "ETHUSDT"}
+
+	s, received := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker)}
+
+	// 全部交易对失败时返回错误，任务不应被记为成功
+	var batchErr *types.BatchError
+	err := s.executeTicker(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeTicker))
+	if !errors.As(err, &batchErr) || len(batchErr.Errors) != 2 {
+		t.Fatalf("全部交易对失败时期望返回*types.BatchError，实际: %v", err)
+	}
+	if data := received(); len(data) != 0 {
+		t.Errorf("不应回调数据，实际%d次", len(data))
+	}
+
+	// ctx已取消时即使有部分结果也返回错误
+	exchange.SetError(mock.MethodGetMultipleTickers, "ETHUSDT", nil)
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+	if err := s.executeTicker(ctx, job, exchange, s.getSymbolsForJob(job, types.DataTypeTicker)); err == nil {
+		t.Error("ctx已取消时期望返回错误")
+	}
+}
+
+func TestExecuteTickerBulkPrice(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetMultiplePrices, "XRPUSDT", errors.New("no price data"))
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT", "ETHUSDT", "XRPUSDT"}
+	config.Exchanges.Binance.DataTypes.Ticker.BulkPriceThreshold = 3
+
+	s, received := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker)}
+
+	// 交易对数量达到阈值时使用全量价格接口
+	if err := s.executeTicker(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeTicker)); err != nil {
+		t.Fatalf("部分交易对失败时不应返回错误: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetMultiplePrices); calls != 1 {
+		t.Errorf("期望GetMultiplePrices调用1次，实际%d次", calls)
+	}
+	if calls := exchange.CallCount(mock.MethodGetMultipleTickers); calls != 0 {
+		t.Errorf("达到阈值时不应调用GetMultipleTickers，实际%d次", calls)
+	}
+	if data := received(); len(data) != 2 {
+		t.Errorf("期望回调2次，实际%d次", len(data))
+	}
+
+	// 交易对数量低于阈值时仍使用24小时行情接口
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT", "ETHUSDT"}
+	if err := s.executeTicker(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeTicker)); err != nil {
+		t.Fatalf("executeTicker失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetMultipleTickers); calls != 1 {
+		t.Errorf("期望GetMultipleTickers调用1次，实际%d次", calls)
+	}
+	if calls := exchange.CallCount(mock.MethodGetMultiplePrices); calls != 1 {
+		t.Errorf("低于阈值时不应调用GetMultiplePrices，实际共%d次", calls)
+	}
+}
+
+func TestExecuteAvgPriceAndStats24h(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetAvgPrice, "ETHUSDT", errors.New("invalid symbol"))
+	exchange.SetError(mock.MethodGetMultipleStats24h, "BNBUSDT", errors.New("timeout"))
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.AvgPrice.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+	config.Exchanges.Binance.DataTypes.Stats24h.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+
+	s, received := newTestScheduler(t, exchange, config)
+
+	// 平均价格逐个交易对请求，失败的交易对不影响其他交易对
+	job := types.JobConfig{Name: "avg_price", Exchange: "binance", DataType: string(types.DataTypeAvgPrice)}
+	if err := s.executeAvgPrice(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeAvgPrice)); err != nil {
+		t.Fatalf("executeAvgPrice失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetAvgPrice); calls != 3 {
+		t.Errorf("期望GetAvgPrice调用3次，实际%d次", calls)
+	}
+	data := received()
+	if len(data) != 2 {
+		t.Fatalf("期望回调2次，实际%d次", len(data))
+	}
+	if avgPrice, ok := data[0].(*types.AvgPrice); !ok || avgPrice.Symbol != "BTCUSDT" || avgPrice.Price != 100 {
+		t.Errorf("平均价格数据不正确: %+v", data[0])
+	}
+
+	// 24小时统计批量请求，部分交易对失败时不返回错误
+	job = types.JobConfig{Name: "stats_24h", Exchange: "binance", DataType: string(types.DataTypeStats24h)}
+	if err := s.executeStats24h(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeStats24h)); err != nil {
+		t.Fatalf("部分交易对失败时不应返回错误: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetMultipleStats24h); calls != 1 {
+		t.Errorf("期望GetMultipleStats24h调用1次，实际%d次", calls)
+	}
+	data = received()[2:]
+	if len(data) != 2 {
+		t.Fatalf("期望回调2次，实际%d次", len(data))
+	}
+	if stats, ok := data[1].(*types.Stats24h); !ok || stats.Symbol != "ETHUSDT" || stats.GetDataType() != types.DataTypeStats24h {
+		t.Errorf("24小时统计数据不正确: %+v", data[1])
+	}
+}
+
+func TestRateLimitManagerWaitsWhenWeightHigh(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetWeight(1190)
+
+	r := NewRateLimitManager(zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 权重超过安全阈值时需要等待，ctx已取消时立即返回
+	if err := r.CheckAndWaitIfNeeded(ctx, exchange); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望返回context.Canceled，实际: %v", err)
+	}
+
+	exchange.SetWeight(100)
+	if err := r.CheckAndWaitIfNeeded(context.Background(), exchange); err != nil {
+		t.Errorf("权重较低时不应等待: %v", err)
+	}
+	if status := r.GetStatus(); status["current_weight"] != 100 {
+		t.Errorf("期望当前权重为100，实际%v", status["current_weight"])
+	}
+}
+
+func TestCheckAndWaitReleasesLockWhileWaiting(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetWeight(1190)
+
+	r := NewRateLimitManager(zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- r.CheckAndWaitIfNeeded(ctx, exchange) }()
+
+	// 等待期间不持有锁，其他调用不被阻塞
+	deadline := time.Now().Add(time.Second)
+	for r.GetStatus()["current_weight"] != 1190 {
+		if time.Now().After(deadline) {
+			t.Fatal("等待权重期间GetStatus未返回校准后的权重")
+		}
+		time.Sleep(5 * time.Millisecond)
+	}
+	r.Release(1000)
+
+	cancel()
+	select {
+	case err := <-done:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("期望返回context.Canceled，实际: %v", err)
+		}
+	case <-time.After(time.Second):
+		t.Fatal("ctx取消后CheckAndWaitIfNeeded未返回")
+	}
+}
+
+// pairsCacheExchange 在模拟交易所基础上提供按资产类型返回交易对的缓存
+type pairsCacheExchange struct {
+	*mock.Exchange
+	pairs     map[asset.Item]currency.Pairs
+	requested []asset.Item
+}
+
+func (e *pairsCacheExchange) GetTradablePairsFromCache(_ context.Context, assetType asset.Item) (currency.Pairs, error) {
+	e.requested = append(e.requested, assetType)
+	return e.pairs[assetType], nil
+}
+
+func TestJobAssetRouting(t *testing.T) {
+	exchange := &pairsCacheExchange{
+		Exchange: mock.New(types.ExchangeBinance),
+		pairs: map[asset.Item]currency.Pairs{
+			asset.Spot:   {currency.NewPair(currency.BTC, currency.USDT), currency.NewPair(currency.ETH, currency.USDT)},
+			asset.Margin: {currency.NewPair(currency.BTC, currency.USDT)},
+		},
+	}
+	exchange.SetSupportedAssets(asset.Spot, asset.Margin)
+
+	config := &types.Config{}
+	config.Exchanges.Binance.TradablePairs.FetchFromAPI = true
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"*"}
+	s := New(zap.NewNop(), map[string]types.ExchangeInterface{"binance": exchange}, nil, config)
+
+	// 未配置资产类型时默认使用现货
+	spotJob := types.JobConfig{Name: "spot", Exchange: "binance", DataType: string(types.DataTypeTicker), Cron: "@every 1m"}
+	if symbols := s.getSymbolsForJob(spotJob, types.DataTypeTicker); len(symbols) != 2 {
+		t.Errorf("现货任务期望2个交易对，实际: %v", symbols)
+	}
+
+	marginJob := spotJob
+	marginJob.Name, marginJob.Asset = "margin", "margin"
+	if symbols := s.getSymbolsForJob(marginJob, types.DataTypeTicker); len(symbols) != 1 || symbols[0] != "BTCUSDT" {
+		t.Errorf("杠杆任务期望交易对[BTCUSDT]，实际: %v", symbols)
+	}
+	if len(exchange.requested) != 2 || exchange.requested[0] != asset.Spot || exchange.requested[1] != asset.Margin {
+		t.Errorf("请求的资产类型不正确: %v", exchange.requested)
+	}
+
+	if err := s.AddJob(marginJob); err != nil {
+		t.Errorf("添加杠杆任务失败: %v", err)
+	}
+
+	// 交易所不支持的资产类型和无效的资产类型在添加任务时返回错误
+	crossJob := spotJob
+	crossJob.Name, crossJob.Asset = "cross", "cross_margin"
+	if err := s.AddJob(crossJob); !errors.Is(err, asset.ErrNotSupported) {
+		t.Errorf("期望返回asset.ErrNotSupported，实际: %v", err)
+	}
+	invalidJob := spotJob
+	invalidJob.Name, invalidJob.Asset = "invalid", "options"
+	if err := s.AddJob(invalidJob); err == nil {
+		t.Error("无效的资产类型应该返回错误")
+	}
+	if _, exists := s.jobs["cross"]; exists {
+		t.Error("添加失败的任务不应被保存")
+	}
+}
+
+func TestWildcardSymbolFilter(t *testing.T) {
+	exchange := &pairsCacheExchange{
+		Exchange: mock.New(types.ExchangeBinance),
+		pairs: map[asset.Item]currency.Pairs{
+			asset.Spot: {
+				currency.NewPair(currency.BTC, currency.USDT),
+				currency.NewPair(currency.NewCode("BTCUP"), currency.USDT),
+				currency.NewPair(currency.ETH, currency.BTC),
+				currency.NewPair(currency.ETH, currency.USDT),
+			},
+		},
+	}
+
+	config := &types.Config{}
+	config.Exchanges.Binance.TradablePairs.FetchFromAPI = true
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"*"}
+	config.Exchanges.Binance.DataTypes.Klines.QuoteAssets = []string{"USDT"}
+	config.Exchanges.Binance.DataTypes.Klines.ExcludePatterns = []string{"*UPUSDT"}
+	s := New(zap.NewNop(), map[string]types.ExchangeInterface{"binance": exchange}, nil, config)
+
+	// ["*"]展开后只保留USDT计价的非杠杆代币交易对
+	job := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines)}
+	symbols := s.getSymbolsForJob(job, types.DataTypeKlines)
+	if len(symbols) != 2 || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" {
+		t.Errorf("期望交易对[BTCUSDT ETHUSDT]，实际: %v", symbols)
+	}
+
+	// 明确配置的交易对不受过滤影响
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUPUSDT"}
+	if symbols := s.getSymbolsForJob(job, types.DataTypeKlines); len(symbols) != 1 {
+		t.Errorf("明确配置的交易对不应被过滤，实际: %v", symbols)
+	}
+}
+
+func TestExecuteJobResolvesSymbolsOnce(t *testing.T) {
+	exchange := &pairsCacheExchange{
+		Exchange: mock.New(types.ExchangeBinance),
+		pairs: map[asset.Item]currency.Pairs{
+			asset.Spot: {currency.NewPair(currency.BTC, currency.USDT), currency.NewPair(currency.ETH, currency.USDT)},
+		},
+	}
+
+	config := &types.Config{}
+	config.Exchanges.Binance.TradablePairs.FetchFromAPI = true
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"*"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m"}
+	config.Scheduler.Timeouts.KlinesPerSeries = time.Second
+	s := New(zap.NewNop(), map[string]types.ExchangeInterface{"binance": exchange}, func(data types.MarketData) error { return nil }, config)
+
+	// 超时计算、覆盖率记录和数据获取共用一次解析结果
+	job := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines)}
+	if err := s.executeJob(job, exchange); err != nil {
+		t.Fatalf("executeJob失败: %v", err)
+	}
+	if len(exchange.requested) != 1 {
+		t.Errorf("每次运行应只解析一次交易对，实际解析%d次", len(exchange.requested))
+	}
+	if got := exchange.CallCount(mock.MethodGetKlines); got != 2 {
+		t.Errorf("期望获取2个交易对的K线，实际调用%d次", got)
+	}
+}
+
+func TestKrakenJob(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/0/public/Ticker" || r.URL.Query().Get("pair") != "XBTUSD,ETHUSD" {
+			t.Errorf("unexpected request: %s", r.URL)
+		}
+		w.Write([]byte(`{"error":[],"result":{
+			"XXBTZUSD":{"a":["101","1","1"],"b":["99","1","1"],"c":["100","1"],"v":["1","2"],"p":["100","100"],"t":[1,2],"l":["90","80"],"h":["110","120"],"o":"80"},
+			"XETHZUSD":{"a":["11","1","1"],"b":["9","1","1"],"c":["10","1"],"v":["1","2"],"p":["10","10"],"t":[1,2],"l":["9","8"],"h":["11","12"],"o":"10"}}}`))
+	}))
+	defer server.Close()
+
+	config := &types.Config{}
+	config.Exchanges.Kraken = types.KrakenConfig{Enabled: true, APIURL: server.URL}
+	config.Exchanges.Kraken.DataTypes.Ticker.Symbols = []string{"BTCUSD", "ETHUSD"}
+
+	exchange, err := kraken.New()
+	if err != nil {
+		t.Fatalf("创建Kraken失败: %v", err)
+	}
+	defer exchange.Close()
+	if err := exchange.Initialize(config.Exchanges.Kraken); err != nil {
+		t.Fatalf("初始化Kraken失败: %v", err)
+	}
+
+	var mu sync.Mutex
+	var received []types.MarketData
+	callback := func(data types.MarketData) error {
+		mu.Lock()
+		defer mu.Unlock()
+		received = append(received, data)
+		return nil
+	}
+	s := New(zap.NewNop(), map[string]types.ExchangeInterface{"kraken": exchange}, callback, config)
+
+	job := types.JobConfig{Name: "kraken_ticker", Exchange: "kraken", DataType: string(types.DataTypeTicker), Cron: "@every 1m"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("添加Kraken任务失败: %v", err)
+	}
+	if err := s.executeJob(job, exchange); err != nil {
+		t.Fatalf("执行Kraken任务失败: %v", err)
+	}
+
+	mu.Lock()
+	defer mu.Unlock()
+	if len(received) != 2 {
+		t.Fatalf("期望回调2次，实际%d次", len(received))
+	}
+	for _, data := range received {
+		if data.GetExchange() != types.ExchangeKraken || data.GetDataType() != types.DataTypeTicker {
+			t.Errorf("数据来源不正确: %+v", data)
+		}
+	}
+
+	// Kraken没有交易对缓存，通配符不展开
+	config.Exchanges.Kraken.DataTypes.Ticker.Symbols = []string{"*"}
+	if symbols := s.getSymbolsForJob(job, types.DataTypeTicker); len(symbols) != 0 {
+		t.Errorf("Kraken通配符不应展开，实际: %v", symbols)
+	}
+}
+
+// depthWeightedExchange 按Binance规则提供订单簿深度权重的模拟交易所
+type depthWeightedExchange struct {
+	*mock.Exchange
+}
+
+func (e *depthWeightedExchange) OrderbookDepthWeight(depth int) int {
+	return binance.OrderbookDepthWeight(depth)
+}
+
+func TestExecuteOrderbookDepthAwareBatches(t *testing.T) {
+	exchange := &depthWeightedExchange{Exchange: mock.New(types.ExchangeBinance)}
+	r := NewRateLimitManager(zap.NewNop())
+	for depth, want := range map[int]int{20: 80, 500: 43, 1000: 21, 5000: 4} {
+		if got := r.OrderbookBatchSize(exchange, depth); got != want {
+			t.Errorf("深度%d的批大小期望%d，实际%d", depth, want, got)
+		}
+	}
+	// 未提供深度权重的交易所按默认深度的权重计算
+	if got := r.OrderbookBatchSize(exchange.Exchange, 5000); got != 80 {
+		t.Errorf("未提供深度权重时批大小期望80，实际%d", got)
+	}
+
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Orderbook.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT", "XRPUSDT", "SOLUSDT", "ADAUSDT"}
+	config.Exchanges.Binance.DataTypes.Orderbook.Depth = 5000
+
+	s, received := newTestScheduler(t, exchange.Exchange, config)
+	job := types.JobConfig{Name: "orderbook", Exchange: "binance", DataType: string(types.DataTypeOrderbook)}
+	if err := s.executeOrderbook(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeOrderbook)); err != nil {
+		t.Fatalf("executeOrderbook失败: %v", err)
+	}
+
+	// 深度5000每个交易对权重250，6个交易对分为4个和2个两批
+	if calls := exchange.CallCount(mock.MethodGetMultipleOrderbooks); calls != 2 {
+		t.Errorf("期望分2批请求，实际%d批", calls)
+	}
+	if data := received(); len(data) != 6 {
+		t.Errorf("期望回调6次，实际%d次", len(data))
+	}
+}
+
+func TestCheckAndWaitForWeight(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetWeight(600)
+
+	r := NewRateLimitManager(zap.NewNop())
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 已使用权重加上本次所需权重超过安全阈值（1080）时需要等待
+	if err := r.CheckAndWaitForWeight(ctx, exchange, 500); !errors.Is(err, context.Canceled) {
+		t.Errorf("期望返回context.Canceled，实际: %v", err)
+	}
+	if err := r.CheckAndWaitForWeight(ctx, exchange, 400); err != nil {
+		t.Errorf("权重预算足够时不应等待: %v", err)
+	}
+}
+
+func TestReserveWeight(t *testing.T) {
+	r := NewRateLimitManager(zap.NewNop())
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 安全阈值内的预算为1080，预留后剩余预算不足时需要等待
+	if err := r.Reserve(context.Background(), 1000); err != nil {
+		t.Fatalf("预算足够时预留失败: %v", err)
+	}
+	if err := r.Reserve(canceled, 100); !errors.Is(err, context.Canceled) {
+		t.Errorf("预算不足时应等待，期望返回context.Canceled，实际: %v", err)
+	}
+
+	// 归还未使用的权重后可以再次预留
+	r.Release(1000)
+	if err := r.Reserve(canceled, 100); err != nil {
+		t.Errorf("归还后预留失败: %v", err)
+	}
+	if err := r.Reserve(context.Background(), 2000); err == nil {
+		t.Error("超过每分钟预算的预留应返回错误")
+	}
+
+	// 滑出滚动窗口的权重不再计入
+	r.mu.Lock()
+	r.window.entries = []weightEntry{{at: time.Now().Add(-61 * time.Second), weight: 1080}}
+	r.mu.Unlock()
+	if err := r.Reserve(canceled, 500); err != nil {
+		t.Errorf("旧权重滑出窗口后预留失败: %v", err)
+	}
+	if status := r.GetStatus(); status["current_weight"] != 500 {
+		t.Errorf("期望当前权重为500，实际%v", status["current_weight"])
+	}
+}
+
+func TestWeightWindow(t *testing.T) {
+	now := time.Now()
+	w := newWeightWindow(binanceWeightWindow)
+	w.add(now.Add(-70*time.Second), 300) // 已滑出窗口
+	w.add(now.Add(-50*time.Second), 600)
+	w.add(now.Add(-10*time.Second), 400)
+
+	if got := w.sum(now); got != 1000 {
+		t.Errorf("期望窗口内权重为1000，实际%d", got)
+	}
+	// 需要200权重时超出预算120，等待最早的600权重滑出窗口（10秒后）
+	if got := w.waitTime(now, 200, 1080); got != 10*time.Second {
+		t.Errorf("期望等待10秒，实际%v", got)
+	}
+	// 需要700权重时两条记录都需要滑出（50秒后）
+	if got := w.waitTime(now, 700, 1080); got != 50*time.Second {
+		t.Errorf("期望等待50秒，实际%v", got)
+	}
+	if got := w.waitTime(now, 80, 1080); got != 0 {
+		t.Errorf("预算足够时不应等待，实际%v", got)
+	}
+
+	// 服务端权重低于本地记录时从最早的记录开始扣减
+	w.sync(now, 500)
+	if got := w.sum(now); got != 500 || len(w.entries) != 2 || w.entries[0].weight != 100 {
+		t.Errorf("校准后权重不正确: %d, %+v", got, w.entries)
+	}
+	// 服务端权重高于本地记录时补记差额
+	w.sync(now, 800)
+	if got := w.sum(now); got != 800 {
+		t.Errorf("期望校准后权重为800，实际%d", got)
+	}
+
+	// 归还时从最近的记录扣减
+	w.release(350)
+	if got := w.sum(now); got != 450 {
+		t.Errorf("期望归还后权重为450，实际%d", got)
+	}
+}
+
+func TestWeightUnavailableDegradation(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetTimeAndWeight, "", errors.New("weight endpoint unavailable"))
+
+	canceled, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	// 获取权重失败时使用窗口内的本地估算：估算权重在预算内时不等待
+	r := NewRateLimitManager(zap.NewNop())
+	r.mu.Lock()
+	r.window.add(time.Now(), 100)
+	r.mu.Unlock()
+	if err := r.CheckAndWaitForWeight(canceled, exchange, 10); err != nil {
+		t.Errorf("本地估算在预算内时不应等待: %v", err)
+	}
+	if err := r.CheckAndWaitForWeight(canceled, exchange, 1000); !errors.Is(err, context.Canceled) {
+		t.Errorf("本地估算超过预算时应等待，实际: %v", err)
+	}
+	status := r.GetStatus()
+	if status["weight_unavailable"] != true || status["weight_errors"] != int64(2) || status["current_weight"] != 100 {
+		t.Errorf("降级状态不正确: %v", status)
+	}
+
+	// 开启保守模式后，窗口内有任何估算权重都需要等待其滑出
+	r.SetAssumeFullOnError(true)
+	if err := r.CheckAndWaitForWeight(canceled, exchange, 10); !errors.Is(err, context.Canceled) {
+		t.Errorf("保守模式下应等待，实际: %v", err)
+	}
+
+	// 窗口为空时无需等待；恢复获取真实权重后退出降级
+	r = NewRateLimitManager(zap.NewNop())
+	r.SetAssumeFullOnError(true)
+	if err := r.CheckAndWaitForWeight(canceled, exchange, 10); err != nil {
+		t.Errorf("窗口为空时不应等待: %v", err)
+	}
+	exchange.SetError(mock.MethodGetTimeAndWeight, "", nil)
+	exchange.SetWeight(50)
+	if err := r.CheckAndWaitForWeight(canceled, exchange, 10); err != nil {
+		t.Errorf("权重恢复后不应等待: %v", err)
+	}
+	if status := r.GetStatus(); status["weight_unavailable"] != false || status["current_weight"] != 50 {
+		t.Errorf("恢复后状态不正确: %v", status)
+	}
+}
+
+func TestJobHistory(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	config := &types.Config{}
+	config.Scheduler.JobHistorySize = 2
+
+	s, _ := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker), Cron: "0 * * * * *"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("添加任务失败: %v", err)
+	}
+	run := s.createJobFunc(job, exchange)
+
+	// 未配置交易对时执行失败
+	run()
+	history := s.GetJobStatus()["ticker"].History
+	if len(history) != 1 || !strings.Contains(history[0].Error, "no symbols") || history[0].StartedAt.IsZero() {
+		t.Fatalf("执行记录不正确: %+v", history)
+	}
+
+	// 成功一次后再失败一次，超过容量时丢弃最早的记录
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT"}
+	run()
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = nil
+	run()
+
+	status := s.GetJobStatus()["ticker"]
+	if status.RunCount != 3 || status.ErrorCount != 2 || len(status.History) != 2 {
+		t.Fatalf("任务状态不正确: %+v", status)
+	}
+	if status.History[0].Error != "" || status.History[1].Error == "" {
+		t.Errorf("期望保留最近的成功和失败记录（从早到晚），实际: %+v", status.History)
+	}
+	if status.History[1].StartedAt.Before(status.History[0].StartedAt) {
+		t.Errorf("执行记录应按时间从早到晚排列: %+v", status.History)
+	}
+}
+
+func TestRunJobNow(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetLatency(100 * time.Millisecond)
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT"}
+
+	s, received := newTestScheduler(t, exchange, config)
+	// 每年执行一次，测试期间不会被cron触发
+	job := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker), Cron: "0 0 0 1 1 *"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("添加任务失败: %v", err)
+	}
+
+	if err := s.RunJobNow("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("不存在的任务应返回ErrJobNotFound，实际: %v", err)
+	}
+	if err := s.RunJobNow("ticker"); err != nil {
+		t.Fatalf("手动触发失败: %v", err)
+	}
+	// 执行中再次触发被拒绝，cron调度也跳过
+	if err := s.RunJobNow("ticker"); !errors.Is(err, ErrJobRunning) {
+		t.Errorf("执行中的任务应返回ErrJobRunning，实际: %v", err)
+	}
+	s.createJobFunc(job, exchange)()
+
+	// 停止时等待手动触发的执行完成
+	if err := s.Stop(context.Background()); err != nil {
+		t.Fatalf("停止调度器失败: %v", err)
+	}
+	status := s.GetJobStatus()["ticker"]
+	if status.RunCount != 1 || status.Status != JobStatusPending || len(status.History) != 1 {
+		t.Errorf("任务状态不正确: %+v", status)
+	}
+	if len(received()) == 0 {
+		t.Error("手动触发的任务应产生数据")
+	}
+	if err := s.RunJobNow("ticker"); !errors.Is(err, ErrSchedulerStopped) {
+		t.Errorf("停止后触发应返回ErrSchedulerStopped，实际: %v", err)
+	}
+}
+
+func TestPauseResumeJob(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	s, _ := newTestScheduler(t, exchange, &types.Config{})
+	job := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker), Cron: "0 0 0 1 1 *"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("添加任务失败: %v", err)
+	}
+	s.Start()
+	defer s.Stop(context.Background())
+
+	if err := s.PauseJob("missing"); !errors.Is(err, ErrJobNotFound) {
+		t.Errorf("不存在的任务应返回ErrJobNotFound，实际: %v", err)
+	}
+	if err := s.PauseJob("ticker"); err != nil {
+		t.Fatalf("暂停任务失败: %v", err)
+	}
+	status := s.GetJobStatus()["ticker"]
+	if !status.Paused || status.Status != JobStatusStopped || !status.NextRun.IsZero() || len(s.cron.Entries()) != 0 {
+		t.Errorf("暂停后状态不正确: %+v, 条目数%d", status, len(s.cron.Entries()))
+	}
+
+	// 暂停的任务仍可手动触发，执行后保持停止状态
+	if err := s.RunJobNow("ticker"); err != nil {
+		t.Fatalf("手动触发失败: %v", err)
+	}
+	s.manualRuns.Wait()
+	if status := s.GetJobStatus()["ticker"]; status.Status != JobStatusStopped || status.RunCount != 1 {
+		t.Errorf("暂停期间执行后应保持stopped: %+v", status)
+	}
+
+	if err := s.ResumeJob("ticker"); err != nil {
+		t.Fatalf("恢复任务失败: %v", err)
+	}
+	status = s.GetJobStatus()["ticker"]
+	if status.Paused || status.Status != JobStatusPending || status.NextRun.IsZero() || len(s.cron.Entries()) != 1 {
+		t.Errorf("恢复后状态不正确: %+v", status)
+	}
+}
+
+func TestJobTimeouts(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT", "ETHUSDT", "BNBUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m", "5m"}
+	s, _ := newTestScheduler(t, exchange, config)
+
+	klines := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines)}
+	ticker := types.JobConfig{Name: "ticker", Exchange: "binance", DataType: string(types.DataTypeTicker)}
+	klinesSymbols := s.getSymbolsForJob(klines, types.DataTypeKlines)
+	tickerSymbols := s.getSymbolsForJob(ticker, types.DataTypeTicker)
+
+	// 未配置时使用默认值
+	if got := s.getTimeoutForJob(klines, klinesSymbols); got != defaultKlinesTimeout {
+		t.Errorf("K线默认超时应为%v，实际%v", defaultKlinesTimeout, got)
+	}
+	if got := s.getTimeoutForJob(ticker, tickerSymbols); got != defaultTickerTimeout {
+		t.Errorf("ticker默认超时应为%v，实际%v", defaultTickerTimeout, got)
+	}
+
+	config.Scheduler.Timeouts.Ticker = 30 * time.Second
+	config.Scheduler.Timeouts.Klines = time.Minute
+	if got := s.getTimeoutForJob(ticker, tickerSymbols); got != 30*time.Second {
+		t.Errorf("ticker超时应使用配置值，实际%v", got)
+	}
+
+	// 按交易对数×周期数放大，不低于配置的K线超时
+	config.Scheduler.Timeouts.KlinesPerSeries = 5 * time.Second
+	if got := s.getTimeoutForJob(klines, klinesSymbols); got != time.Minute {
+		t.Errorf("规模较小时应使用K线超时，实际%v", got)
+	}
+	config.Scheduler.Timeouts.KlinesPerSeries = 20 * time.Second
+	if got := s.getTimeoutForJob(klines, klinesSymbols); got != 2*time.Minute {
+		t.Errorf("3个交易对×2个周期应放大到2分钟，实际%v", got)
+	}
+}
+
+func TestStaleSymbols(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetKlines, "XRPUSDT", errors.New("symbol not found"))
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT", "XRPUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m"}
+
+	s, _ := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines), Cron: "0 0 0 1 1 *"}
+	if err := s.AddJob(job); err != nil {
+		t.Fatalf("添加任务失败: %v", err)
+	}
+	s.createJobFunc(job, exchange)()
+
+	// 刚开始期望采集时不算缺失
+	if stale := s.StaleSymbols(time.Minute); len(stale) != 0 {
+		t.Errorf("宽限期内不应有缺失的交易对: %+v", stale)
+	}
+
+	// 阈值之后，从未成功的XRPUSDT被列出，BTCUSDT在阈值内成功过
+	later := time.Now().Add(2 * time.Minute)
+	s.coverage.markSuccess(&types.Kline{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Interval: "1m"}, later)
+	stale := s.coverage.stale(later, time.Minute, nil)
+	if len(stale) != 1 || stale[0].Symbol != "XRPUSDT" || !stale[0].LastSuccess.IsZero() || stale[0].Job != "klines" {
+		t.Fatalf("缺失的交易对不正确: %+v", stale)
+	}
+
+	// 不再期望采集的交易对不再列出
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT"}
+	s.createJobFunc(job, exchange)()
+	for _, symbol := range s.coverage.stale(later, time.Minute, nil) {
+		if symbol.Symbol == "XRPUSDT" {
+			t.Errorf("移除的交易对不应列出: %+v", symbol)
+		}
+	}
+
+	// 暂停的任务不参与检查
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"XRPUSDT"}
+	s.createJobFunc(job, exchange)()
+	if err := s.PauseJob("klines"); err != nil {
+		t.Fatalf("暂停任务失败: %v", err)
+	}
+	if stale := s.StaleSymbols(time.Nanosecond); len(stale) != 0 {
+		t.Errorf("暂停的任务不应参与检查: %+v", stale)
+	}
+	if status := s.GetCoverageStatus(); status["expected_symbols"] != 1 {
+		t.Errorf("覆盖状态不正确: %+v", status)
+	}
+}
+
+func TestSymbolQuarantine(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetKlines, "XRPUSDT", errors.New("invalid symbol"))
+	config := &types.Config{}
+	config.Scheduler.Quarantine = types.QuarantineConfig{FailureThreshold: 2, BaseCooldown: time.Minute, MaxCooldown: 3 * time.Minute}
+	s, _ := newTestScheduler(t, exchange, config)
+	symbols := []types.Symbol{"BTCUSDT", "XRPUSDT"}
+
+	// 连续失败2次后隔离，隔离期间不再请求
+	for i := 0; i < 4; i++ {
+		if err := s.processBatchKlines(context.Background(), symbols, "1m", exchange, false, s.newSymbolLogSummary(types.DataTypeKlines, "1m", len(symbols))); err != nil {
+			t.Fatalf("processBatchKlines失败: %v", err)
+		}
+	}
+	if calls := exchange.CallCount(mock.MethodGetKlines); calls != 6 {
+		t.Errorf("隔离后应跳过XRPUSDT，期望6次请求，实际%d", calls)
+	}
+	quarantined := s.GetQuarantinedSymbols()
+	if len(quarantined) != 1 || quarantined[0].Symbol != "XRPUSDT" || quarantined[0].Interval != "1m" || quarantined[0].Failures != 2 {
+		t.Fatalf("隔离列表不正确: %+v", quarantined)
+	}
+
+	// 冷却时间指数增长并受上限限制
+	key := quarantineKey{exchange: types.ExchangeBinance, symbol: "XRPUSDT", interval: "1m"}
+	now := time.Now()
+	for _, want := range []time.Duration{2 * time.Minute, 3 * time.Minute, 3 * time.Minute} {
+		if got := s.quarantine.failure(key, errors.New("invalid symbol"), now); got != want {
+			t.Errorf("期望冷却%v，实际%v", want, got)
+		}
+	}
+
+	// 冷却结束后重新尝试，成功即恢复
+	exchange.SetError(mock.MethodGetKlines, "XRPUSDT", nil)
+	if !s.quarantine.skip(key, now) || s.quarantine.skip(key, now.Add(4*time.Minute)) {
+		t.Error("冷却期内应跳过，冷却结束后应重新尝试")
+	}
+	s.quarantine.entries[key].until = time.Now().Add(-time.Second)
+	if err := s.processBatchKlines(context.Background(), symbols, "1m", exchange, false, s.newSymbolLogSummary(types.DataTypeKlines, "1m", len(symbols))); err != nil {
+		t.Fatalf("processBatchKlines失败: %v", err)
+	}
+	if len(s.GetQuarantinedSymbols()) != 0 || len(s.quarantine.entries) != 0 {
+		t.Errorf("成功后应清除失败记录: %+v", s.quarantine.entries)
+	}
+}
+
+func TestSymbolLogSummary(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	exchange.SetError(mock.MethodGetKlines, "XRPUSDT", errors.New("invalid symbol"))
+	exchange.SetError(mock.MethodGetKlines, "DOGEUSDT", errors.New("invalid symbol"))
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT", "ETHUSDT", "XRPUSDT", "DOGEUSDT", "BNBUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m"}
+	job := types.JobConfig{Name: "klines", Exchange: "binance"}
+
+	for _, tc := range []struct {
+		level      zapcore.Level
+		perSymbols int // 逐个交易对的失败日志数
+	}{
+		{zapcore.DebugLevel, 0},
+		{TraceLevel, 2},
+	} {
+		s, _ := newTestScheduler(t, exchange, config)
+		core, logs := observer.New(tc.level)
+		s.logger = zap.New(core)
+		if err := s.executeKlines(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeKlines)); err != nil {
+			t.Fatalf("executeKlines失败: %v", err)
+		}
+
+		if got := logs.FilterMessage("获取klines数据失败").Len(); got != tc.perSymbols {
+			t.Errorf("级别%v: 期望%d条逐个交易对的失败日志，实际%d", tc.level, tc.perSymbols, got)
+		}
+		summaries := logs.FilterMessage("交易对处理汇总").All()
+		if len(summaries) != 1 || summaries[0].Level != zapcore.WarnLevel {
+			t.Fatalf("级别%v: 期望一条Warn级别的汇总日志，实际%+v", tc.level, summaries)
+		}
+		fields := summaries[0].ContextMap()
+		if fields["processed"] != int64(5) || fields["total"] != int64(5) || fields["errors"] != int64(2) {
+			t.Errorf("汇总字段不正确: %+v", fields)
+		}
+		if failed, ok := fields["failed_symbols"].([]interface{}); !ok || len(failed) != 2 {
+			t.Errorf("汇总应列出失败的交易对: %+v", fields["failed_symbols"])
+		}
+	}
+}
+
+func TestLastValueCache(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Ticker.Symbols = []string{"BTCUSDT", "ETHUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m"}
+
+	s, _ := newTestScheduler(t, exchange, config)
+	ctx := context.Background()
+	tickerJob := types.JobConfig{Name: "ticker", Exchange: "binance"}
+	klinesJob := types.JobConfig{Name: "klines", Exchange: "binance"}
+	if err := s.executeTicker(ctx, tickerJob, exchange, s.getSymbolsForJob(tickerJob, types.DataTypeTicker)); err != nil {
+		t.Fatalf("executeTicker失败: %v", err)
+	}
+	if err := s.executeKlines(ctx, klinesJob, exchange, s.getSymbolsForJob(klinesJob, types.DataTypeKlines)); err != nil {
+		t.Fatalf("executeKlines失败: %v", err)
+	}
+
+	// 调度器获取的数据写入缓存，K线保留最新的一根
+	cache := s.LastValues()
+	ticker, updatedAt, ok := cache.GetLastTicker(types.ExchangeBinance, "ETHUSDT")
+	if !ok || ticker.Price != 100 || time.Since(updatedAt) > time.Minute {
+		t.Errorf("ticker缓存不正确: %+v, %v, %v", ticker, updatedAt, ok)
+	}
+	kline, _, ok := cache.GetLastKline(types.ExchangeBinance, "BTCUSDT", "1m")
+	if !ok || !kline.OpenTime.Equal(mock.BaseTime.Add(99*time.Minute)) {
+		t.Errorf("K线缓存应为最新的一根: %+v", kline)
+	}
+	if _, _, ok := cache.GetLastKline(types.ExchangeBinance, "BTCUSDT", "5m"); ok {
+		t.Error("未获取的周期不应命中缓存")
+	}
+
+	// 时间更早的数据不覆盖缓存
+	cache.Update(&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "ETHUSDT", Price: 1, Timestamp: mock.BaseTime.Add(-time.Hour)})
+	if ticker, _, _ := cache.GetLastTicker(types.ExchangeBinance, "ETHUSDT"); ticker.Price != 100 {
+		t.Errorf("旧数据不应覆盖缓存，实际价格%v", ticker.Price)
+	}
+
+	// 缓存在maxAge内时查询不请求API
+	if _, err := s.GetTicker(ctx, "binance", "ETHUSDT", time.Minute); err != nil {
+		t.Fatalf("查询ticker失败: %v", err)
+	}
+	if _, err := s.GetLatestKline(ctx, "binance", "BTCUSDT", "1m", time.Minute); err != nil {
+		t.Fatalf("查询K线失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetTicker); calls != 0 {
+		t.Errorf("命中缓存时不应请求API，实际%d次", calls)
+	}
+
+	// 未命中或已过期时请求API并更新缓存
+	if _, err := s.GetTicker(ctx, "binance", "SOLUSDT", time.Minute); err != nil {
+		t.Fatalf("查询ticker失败: %v", err)
+	}
+	if _, err := s.GetTicker(ctx, "binance", "ETHUSDT", 0); err != nil {
+		t.Fatalf("查询ticker失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetTicker); calls != 2 {
+		t.Errorf("期望请求API 2次，实际%d次", calls)
+	}
+	if _, _, ok := cache.GetLastTicker(types.ExchangeBinance, "SOLUSDT"); !ok {
+		t.Error("API查询结果应写入缓存")
+	}
+	if _, err := s.GetTicker(ctx, "kraken", "ETHUSDT", time.Minute); err == nil {
+		t.Error("未注册的交易所应返回错误")
+	}
+}
+
+func TestExecuteTradesDeduplicates(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Trades.Symbols = []string{"BTCUSDT", "ETHUSDT"}
+
+	s, received := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "trades", Exchange: "binance", DataType: string(types.DataTypeTrades)}
+
+	// 模拟交易所每次返回相同的100条成交，第二次轮询不应重复输出
+	for i := 0; i < 2; i++ {
+		if err := s.executeTrades(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeTrades)); err != nil {
+			t.Fatalf("executeTrades失败: %v", err)
+		}
+	}
+	if data := received(); len(data) != 2*100 {
+		t.Errorf("期望回调%d次，实际%d次", 2*100, len(data))
+	}
+
+	// 部分重叠时只输出ID更大的成交，无法解析的ID不参与去重
+	trades := func(ids ...string) []types.Trade {
+		result := make([]types.Trade, len(ids))
+		for i, id := range ids {
+			result[i] = types.Trade{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", ID: id}
+		}
+		return result
+	}
+	dedup := newTradeDeduper()
+	if got := dedup.filter(trades("10", "11", "12")); len(got) != 3 {
+		t.Errorf("首次轮询应输出全部成交，实际%d条", len(got))
+	}
+	got := dedup.filter(trades("11", "12", "13", "14", "abc"))
+	if len(got) != 3 || got[0].ID != "13" || got[1].ID != "14" || got[2].ID != "abc" {
+		t.Errorf("期望输出13、14和abc，实际: %+v", got)
+	}
+}
+
+func TestExecuteKlinesIncremental(t *testing.T) {
+	exchange := mock.New(types.ExchangeBinance)
+	config := &types.Config{}
+	config.Exchanges.Binance.DataTypes.Klines.Symbols = []string{"BTCUSDT"}
+	config.Exchanges.Binance.DataTypes.Klines.Intervals = []string{"1m"}
+	config.Exchanges.Binance.DataTypes.Klines.Incremental = true
+
+	s, received := newTestScheduler(t, exchange, config)
+	job := types.JobConfig{Name: "klines", Exchange: "binance", DataType: string(types.DataTypeKlines)}
+
+	// 首次运行没有游标，获取最近100根
+	if err := s.executeKlines(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeKlines)); err != nil {
+		t.Fatalf("executeKlines失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetKlines); calls != 1 || len(received()) != 100 {
+		t.Fatalf("首次运行期望获取100根K线，实际调用%d次、回调%d次", calls, len(received()))
+	}
+	key := klineCursorKey{exchange: types.ExchangeBinance, symbol: "BTCUSDT", interval: "1m"}
+	if lastClose, ok := s.klineCursor.get(key); !ok || !lastClose.Equal(mock.BaseTime.Add(100*time.Minute-time.Millisecond)) {
+		t.Errorf("游标应为最后一根K线的收盘时间，实际%v", lastClose)
+	}
+
+	// 之后只获取游标之后的K线
+	s.klineCursor.lastClose[key] = mock.BaseTime.Add(97*time.Minute - time.Millisecond)
+	if err := s.executeKlines(context.Background(), job, exchange, s.getSymbolsForJob(job, types.DataTypeKlines)); err != nil {
+		t.Fatalf("executeKlines失败: %v", err)
+	}
+	if calls := exchange.CallCount(mock.MethodGetKlinesSince); calls != 1 {
+		t.Errorf("期望增量获取1次，实际%d次", calls)
+	}
+	data := received()[100:]
+	if len(data) != 3 || !data[0].(*types.Kline).OpenTime.Equal(mock.BaseTime.Add(97*time.Minute)) {
+		t.Errorf("期望获取游标之后的3根K线，实际%d根", len(data))
+	}
+
+	// 未收盘的K线不推进游标
+	now := mock.BaseTime.Add(time.Hour)
+	cursor := newKlineCursor()
+	cursor.advance(key, []types.Kline{
+		{CloseTime: now.Add(-time.Minute)},
+		{CloseTime: now.Add(time.Minute)},
+	}, now)
+	if lastClose, _ := cursor.get(key); !lastClose.Equal(now.Add(-time.Minute)) {
+		t.Errorf("未收盘的K线不应推进游标，实际%v", lastClose)
+	}
+
+	// 请求数量按间隔计算并限制上限
+	if limit := klineLimitSince(now.Add(-10*time.Minute), now, "1m"); limit != 11 {
+		t.Errorf("期望获取11根K线，实际%d", limit)
+	}
+	if limit := klineLimitSince(now.Add(-48*time.Hour), now, "1m"); limit != maxKlineLimit {
+		t.Errorf("期望限制为%d根，实际%d", maxKlineLimit, limit)
+	}
+}
@@ -0,0 +1,111 @@
+package scheduler
+
+import (
+	"sync"
+
+	"go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// TraceLevel 比Debug更详细的日志级别，输出逐个交易对、逐条数据的处理详情（配置log_level: trace启用）
+const TraceLevel = zapcore.DebugLevel - 1
+
+// maxSampledSymbols 汇总日志中列出的失败交易对数量上限
+const maxSampledSymbols = 10
+
+// symbolLogSummary 汇总一次任务中逐个交易对的处理结果，任务结束时输出一行汇总
+// （如"已处理1843/2000，错误12"），逐个交易对的详情只在Trace级别输出，避免交易对较多时日志量过大
+type symbolLogSummary struct {
+	logger   *zap.Logger
+	dataType types.DataType
+	interval string
+	total    int
+
+	mu             sync.Mutex
+	processed      int
+	success        int
+	errors         int
+	skipped        int
+	callbackErrors int
+	failedSymbols  []string // 获取失败的交易对，最多maxSampledSymbols个
+	lastError      error
+}
+
+// newSymbolLogSummary 创建逐个交易对处理结果的汇总，total为任务的交易对总数
+func (s *Scheduler) newSymbolLogSummary(dataType types.DataType, interval string, total int) *symbolLogSummary {
+	return &symbolLogSummary{
+		logger:   s.logger,
+		dataType: dataType,
+		interval: interval,
+		total:    total,
+	}
+}
+
+// trace 在Trace级别输出逐个交易对的详情
+func (l *symbolLogSummary) trace(msg string, fields ...zap.Field) {
+	if ce := l.logger.Check(TraceLevel, msg); ce != nil {
+		ce.Write(fields...)
+	}
+}
+
+// succeeded 记录交易对处理成功
+func (l *symbolLogSummary) succeeded() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.processed++
+	l.success++
+}
+
+// skip 记录交易对因隔离被跳过
+func (l *symbolLogSummary) skip() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.skipped++
+}
+
+// failed 记录交易对获取失败
+func (l *symbolLogSummary) failed(symbol types.Symbol, err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.processed++
+	l.errors++
+	l.lastError = err
+	if len(l.failedSymbols) < maxSampledSymbols {
+		l.failedSymbols = append(l.failedSymbols, string(symbol))
+	}
+}
+
+// callbackFailed 记录一条数据的回调处理失败
+func (l *symbolLogSummary) callbackFailed(err error) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	l.callbackErrors++
+	l.lastError = err
+}
+
+// log 输出汇总日志，有错误时使用Warn级别，否则使用Debug级别
+func (l *symbolLogSummary) log() {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	fields := []zap.Field{
+		zap.String("data_type", string(l.dataType)),
+		zap.Int("processed", l.processed),
+		zap.Int("total", l.total),
+		zap.Int("success", l.success),
+		zap.Int("errors", l.errors),
+		zap.Int("skipped", l.skipped),
+		zap.Int("callback_errors", l.callbackErrors),
+	}
+	if l.interval != "" {
+		fields = append(fields, zap.String("interval", l.interval))
+	}
+	if l.errors == 0 && l.callbackErrors == 0 {
+		l.logger.Debug("交易对处理汇总", fields...)
+		return
+	}
+	fields = append(fields, zap.Strings("failed_symbols", l.failedSymbols), zap.NamedError("last_error", l.lastError))
+	l.logger.Warn("交易对处理汇总", fields...)
+}
@@ -0,0 +1,55 @@
+package scheduler
+
+import (
+	"strconv"
+	"sync"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// tradeKey 成交去重的键
+type tradeKey struct {
+	exchange types.Exchange
+	symbol   types.Symbol
+}
+
+// tradeDeduper 记录每个交易对已输出的最大成交ID，过滤轮询时重复返回的成交。
+// 依赖交易所成交ID单调递增（如Binance），无法解析为整数的ID不参与去重
+type tradeDeduper struct {
+	mu       sync.Mutex
+	lastSeen map[tradeKey]int64
+}
+
+// newTradeDeduper 创建成交去重器
+func newTradeDeduper() *tradeDeduper {
+	return &tradeDeduper{
+		lastSeen: make(map[tradeKey]int64),
+	}
+}
+
+// filter 返回比上次已输出成交更新的成交，并更新该交易对的最大成交ID
+func (d *tradeDeduper) filter(trades []types.Trade) []types.Trade {
+	if len(trades) == 0 {
+		return trades
+	}
+
+	d.mu.Lock()
+	defer d.mu.Unlock()
+
+	result := trades[:0:0]
+	for _, trade := range trades {
+		id, err := strconv.ParseInt(trade.ID, 10, 64)
+		if err != nil {
+			result = append(result, trade)
+			continue
+		}
+
+		key := tradeKey{exchange: trade.Exchange, symbol: trade.Symbol}
+		if last, ok := d.lastSeen[key]; ok && id <= last {
+			continue
+		}
+		d.lastSeen[key] = id
+		result = append(result, trade)
+	}
+	return result
+}
@@ -0,0 +1,129 @@
+package scheduler
+
+import "time"
+
+// binanceWeightWindow Binance按滚动的1分钟窗口统计请求权重
+const binanceWeightWindow = time.Minute
+
+// weightEntry 一次权重使用记录
+type weightEntry struct {
+	at     time.Time
+	weight int
+}
+
+// weightWindow 滚动窗口权重统计：按时间记录每次权重使用，超过窗口长度的记录不再计入。
+// 非并发安全，由调用方加锁
+type weightWindow struct {
+	size    time.Duration
+	entries []weightEntry // 按时间升序
+}
+
+// newWeightWindow 创建指定长度的滚动窗口
+func newWeightWindow(size time.Duration) *weightWindow {
+	return &weightWindow{size: size}
+}
+
+// add 在at时刻记录一次权重使用
+func (w *weightWindow) add(at time.Time, weight int) {
+	if weight <= 0 {
+		return
+	}
+	w.evict(time.Now())
+
+	// 记录时间通常为当前时间，从尾部向前查找插入位置以保持升序
+	i := len(w.entries)
+	for i > 0 && w.entries[i-1].at.After(at) {
+		i--
+	}
+	w.entries = append(w.entries, weightEntry{})
+	copy(w.entries[i+1:], w.entries[i:])
+	w.entries[i] = weightEntry{at: at, weight: weight}
+}
+
+// release 归还最近记录的权重（用于预留后未实际发出的请求）
+func (w *weightWindow) release(weight int) {
+	for i := len(w.entries) - 1; i >= 0 && weight > 0; i-- {
+		d := min(weight, w.entries[i].weight)
+		w.entries[i].weight -= d
+		weight -= d
+	}
+	w.compact()
+}
+
+// sync 用服务端在at时刻返回的已使用权重校准本地记录：at之前的本地记录总和大于服务端值时从最早的记录开始扣减，
+// 小于时在at时刻补记差额（如同一IP下其他进程或未经频控的请求）
+func (w *weightWindow) sync(at time.Time, observed int) {
+	w.evict(time.Now())
+
+	local, n := 0, 0
+	for n < len(w.entries) && !w.entries[n].at.After(at) {
+		local += w.entries[n].weight
+		n++
+	}
+	if local < observed {
+		w.add(at, observed-local)
+		return
+	}
+
+	excess := local - observed
+	for i := 0; i < n && excess > 0; i++ {
+		d := min(excess, w.entries[i].weight)
+		w.entries[i].weight -= d
+		excess -= d
+	}
+	w.compact()
+}
+
+// sum 返回now时刻窗口内的权重总和
+func (w *weightWindow) sum(now time.Time) int {
+	cutoff := now.Add(-w.size)
+	total := 0
+	for _, e := range w.entries {
+		if e.at.After(cutoff) {
+			total += e.weight
+		}
+	}
+	return total
+}
+
+// waitTime 计算窗口内权重加上needed不超过budget需要等待的时间，即足够多的记录滑出窗口的时刻；
+// needed本身超过budget时等待整个窗口
+func (w *weightWindow) waitTime(now time.Time, needed, budget int) time.Duration {
+	excess := w.sum(now) + needed - budget
+	if excess <= 0 {
+		return 0
+	}
+
+	cutoff := now.Add(-w.size)
+	for _, e := range w.entries {
+		if !e.at.After(cutoff) {
+			continue
+		}
+		excess -= e.weight
+		if excess <= 0 {
+			return e.at.Add(w.size).Sub(now)
+		}
+	}
+	return w.size
+}
+
+// evict 移除now时刻已滑出窗口的记录
+func (w *weightWindow) evict(now time.Time) {
+	cutoff := now.Add(-w.size)
+	i := 0
+	for i < len(w.entries) && !w.entries[i].at.After(cutoff) {
+		i++
+	}
+	w.entries = w.entries[i:]
+}
+
+// compact 移除权重已扣减为0的记录
+func (w *weightWindow) compact() {
+	kept := w.entries[:0]
+	for _, e := range w.entries {
+		if e.weight > 0 {
+			kept = append(kept, e)
+		}
+	}
+	w.entries = kept
+}
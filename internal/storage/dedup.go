@@ -0,0 +1,141 @@
+package storage
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// dedupKey 去重时区分数据序列的键，成交的interval为空
+type dedupKey struct {
+	exchange types.Exchange
+	symbol   types.Symbol
+	interval string
+}
+
+// tradeDedupWindow 每个交易对按成交ID去重的窗口大小：记录最大成交ID之前这么多个ID范围内已写入的成交，
+// 覆盖REST单次拉取的成交数（Binance最多1000条）。ID早于窗口的成交无法判断是否已写入，按重复丢弃
+const tradeDedupWindow = 2000
+
+// DedupSink 在多个采集来源（如hybrid模式下的REST拉取和WebSocket推送）共用的存储输出前去重：
+// 成交按每个交易对最近tradeDedupWindow个ID范围内已写入的成交ID集合过滤，REST补回的、ID小于WebSocket
+// 最新成交但未写入过的成交仍会写入；已收盘K线按每个交易对和周期已写入的最大开盘时间过滤。
+// 来源切换的重叠窗口内两种来源返回的同一成交或K线只写入一次。未收盘K线和其他数据类型直接写入
+type DedupSink struct {
+	sink Sink
+	now  func() time.Time // 判断K线是否已收盘，测试中可替换
+
+	mu      sync.Mutex
+	trades  map[dedupKey]*tradeIDs // 已写入的成交ID
+	klines  map[dedupKey]time.Time // 已写入的最大已收盘K线开盘时间
+	dropped int64                  // 被过滤的重复数据数
+}
+
+// NewDedupSink 创建去重存储输出，通过去重的数据写入sink
+func NewDedupSink(sink Sink) *DedupSink {
+	return &DedupSink{
+		sink:   sink,
+		now:    time.Now,
+		trades: make(map[dedupKey]*tradeIDs),
+		klines: make(map[dedupKey]time.Time),
+	}
+}
+
+// Write 写入一条市场数据，已由其他来源写入过的成交和已收盘K线直接丢弃
+func (s *DedupSink) Write(data types.MarketData) error {
+	if !s.admit(data) {
+		return nil
+	}
+	return s.sink.Write(data)
+}
+
+// Flush 将缓冲中的数据写出到持久存储
+func (s *DedupSink) Flush(ctx context.Context) error {
+	return s.sink.Flush(ctx)
+}
+
+// Close 关闭存储
+func (s *DedupSink) Close() error {
+	return s.sink.Close()
+}
+
+// Dropped 返回被过滤的重复数据数
+func (s *DedupSink) Dropped() int64 {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.dropped
+}
+
+// admit 判断数据是否写入并更新去重状态
+func (s *DedupSink) admit(data types.MarketData) bool {
+	switch d := data.(type) {
+	case *types.Trade:
+		return s.admitTrade(d)
+	case *types.Kline:
+		return s.admitKline(d)
+	}
+	return true
+}
+
+// tradeIDs 单个交易对在去重窗口内已写入的成交ID
+type tradeIDs struct {
+	max  int64              // 已写入的最大成交ID
+	seen map[int64]struct{} // 已写入的成交ID，不早于max-tradeDedupWindow
+}
+
+// admitTrade 成交ID已写入过，或早于最大成交ID超过tradeDedupWindow时视为重复，
+// 依赖交易所成交ID单调递增（如Binance），无法解析为整数的ID不参与去重
+func (s *DedupSink) admitTrade(trade *types.Trade) bool {
+	id, err := strconv.ParseInt(trade.ID, 10, 64)
+	if err != nil {
+		return true
+	}
+
+	key := dedupKey{exchange: trade.Exchange, symbol: trade.Symbol}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	ids, ok := s.trades[key]
+	if !ok {
+		ids = &tradeIDs{max: id, seen: make(map[int64]struct{})}
+		s.trades[key] = ids
+	}
+	if _, dup := ids.seen[id]; dup || id <= ids.max-tradeDedupWindow {
+		s.dropped++
+		return false
+	}
+
+	ids.seen[id] = struct{}{}
+	if id > ids.max {
+		ids.max = id
+	}
+	// 集合超过窗口两倍时清理窗口外的ID，均摊清理开销
+	if len(ids.seen) > 2*tradeDedupWindow {
+		for seen := range ids.seen {
+			if seen <= ids.max-tradeDedupWindow {
+				delete(ids.seen, seen)
+			}
+		}
+	}
+	return true
+}
+
+// admitKline 已收盘K线的开盘时间不晚于已写入的最大开盘时间时视为重复；
+// 未收盘K线（收盘时间晚于当前时间）是同一根K线的中间状态，不参与去重
+func (s *DedupSink) admitKline(kline *types.Kline) bool {
+	if kline.CloseTime.After(s.now()) {
+		return true
+	}
+
+	key := dedupKey{exchange: kline.Exchange, symbol: kline.Symbol, interval: kline.Interval}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if last, ok := s.klines[key]; ok && !kline.OpenTime.After(last) {
+		s.dropped++
+		return false
+	}
+	s.klines[key] = kline.OpenTime
+	return true
+}
@@ -0,0 +1,117 @@
+package storage
+
+import (
+	"context"
+	"slices"
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// memorySink 记录写入数据的存储输出
+type memorySink struct {
+	written []types.MarketData
+}
+
+func (m *memorySink) Write(data types.MarketData) error {
+	m.written = append(m.written, data)
+	return nil
+}
+
+func (m *memorySink) Flush(ctx context.Context) error { return nil }
+
+func (m *memorySink) Close() error { return nil }
+
+func TestDedupSinkSourceSwitchOverlap(t *testing.T) {
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	mem := &memorySink{}
+	sink := NewDedupSink(mem)
+	sink.now = func() time.Time { return base.Add(3 * time.Minute) }
+
+	trade := func(id string) *types.Trade {
+		return &types.Trade{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", ID: id}
+	}
+	kline := func(minute int) *types.Kline {
+		open := base.Add(time.Duration(minute) * time.Minute)
+		return &types.Kline{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Interval: "1m",
+			OpenTime: open, CloseTime: open.Add(time.Minute - time.Millisecond)}
+	}
+
+	// REST拉取阶段：写入成交100-102和已收盘K线0、1
+	rest := []types.MarketData{trade("100"), trade("101"), trade("102"), kline(0), kline(1)}
+	// 切回WebSocket的重叠窗口：REST最后一次拉取与WebSocket推送交错，返回已写入的成交和K线
+	overlap := []types.MarketData{
+		trade("101"), trade("102"), // WebSocket推送了REST已写入的成交
+		kline(1),               // WebSocket推送了REST已写入的已收盘K线
+		trade("103"),           // WebSocket推送的新成交
+		trade("103"), kline(2), // REST最后一次拉取返回WebSocket已写入的成交，以及新收盘的K线
+		kline(2),           // WebSocket随后推送同一根已收盘K线
+		kline(3), kline(3), // 未收盘K线的中间状态不去重
+		trade("abc"), trade("abc"), // 无法解析为整数的ID不去重
+	}
+	for _, d := range append(rest, overlap...) {
+		if err := sink.Write(d); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+
+	var tradeIDs []string
+	var klineMinutes []int
+	for _, d := range mem.written {
+		switch v := d.(type) {
+		case *types.Trade:
+			tradeIDs = append(tradeIDs, v.ID)
+		case *types.Kline:
+			klineMinutes = append(klineMinutes, int(v.OpenTime.Sub(base)/time.Minute))
+		}
+	}
+	if want := []string{"100", "101", "102", "103", "abc", "abc"}; !slices.Equal(tradeIDs, want) {
+		t.Errorf("写入的成交ID = %v，期望 %v", tradeIDs, want)
+	}
+	if want := []int{0, 1, 2, 3, 3}; !slices.Equal(klineMinutes, want) {
+		t.Errorf("写入的K线 = %v，期望 %v", klineMinutes, want)
+	}
+	if got := sink.Dropped(); got != 5 {
+		t.Errorf("过滤的重复数据数 = %d，期望 5", got)
+	}
+
+	// WebSocket重连时漏推的成交由REST补回：ID小于已写入的最大成交ID但未写入过，仍然写入
+	before := len(mem.written)
+	for _, id := range []string{"110", "105", "104", "105", "110"} {
+		sink.Write(trade(id))
+	}
+	if got := len(mem.written) - before; got != 3 {
+		t.Errorf("补回的成交应写入3条，实际写入 %d 条", got)
+	}
+
+	// 早于去重窗口的成交无法判断是否已写入，按重复丢弃；窗口外的ID被清理
+	for id := 111; id <= 110+2*tradeDedupWindow+1; id++ {
+		sink.Write(trade(strconv.Itoa(id)))
+	}
+	dropped := sink.Dropped()
+	sink.Write(trade("106"))
+	if sink.Dropped() != dropped+1 {
+		t.Error("早于去重窗口的成交应被丢弃")
+	}
+	key := dedupKey{exchange: types.ExchangeBinance, symbol: "BTCUSDT"}
+	if n := len(sink.trades[key].seen); n > 2*tradeDedupWindow {
+		t.Errorf("去重窗口内的成交ID数 = %d，不应超过 %d", n, 2*tradeDedupWindow)
+	}
+
+	// 不同交易对、不同周期和其他数据类型互不影响
+	others := []types.MarketData{
+		&types.Trade{Exchange: types.ExchangeBinance, Symbol: "ETHUSDT", ID: "100"},
+		&types.Kline{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Interval: "5m", OpenTime: base},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 42000, Timestamp: base},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 42000, Timestamp: base},
+	}
+	before = len(mem.written)
+	for _, d := range others {
+		sink.Write(d)
+	}
+	if got := len(mem.written) - before; got != len(others) {
+		t.Errorf("其他序列应全部写入，实际写入 %d 条", got)
+	}
+}
@@ -0,0 +1,334 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// fileExtension 文件存储输出的文件扩展名，与回放支持的格式一致
+const fileExtension = ".ndjson"
+
+// 文件压缩方式
+const (
+	CompressionNone = "none"
+	CompressionGzip = "gzip"
+	CompressionZstd = "zstd"
+)
+
+// compressedExtensions 各压缩方式压缩后追加的扩展名
+var compressedExtensions = map[string]string{
+	CompressionGzip: ".gz",
+	CompressionZstd: ".zst",
+}
+
+// openFile 打开的文件及正在写入它的交易对数
+type openFile struct {
+	file  *os.File
+	users int
+}
+
+// seriesKey 一个交易对的一种数据，同一时刻只写入一个文件
+type seriesKey struct {
+	exchange types.Exchange
+	dataType types.DataType
+	symbol   types.Symbol
+}
+
+// FileSink 文件存储：按分区模板（默认 {exchange}/{dataType}/{symbol}/{UTC日期}）写入NDJSON记录，
+// 交易对切换到下一个分区时关闭不再写入的文件。启用压缩时写入中的文件保持未压缩，
+// 文件关闭（切换分区或关闭存储）后在锁外压缩为 .ndjson.gz 或 .ndjson.zst 并删除原文件，
+// 已存在的压缩文件以追加gzip成员（zstd帧）的方式合并
+type FileSink struct {
+	basePath    string
+	partitioner *Partitioner
+	compression string
+
+	mu          sync.Mutex
+	files       map[string]*openFile     // 按文件路径索引打开的文件
+	current     map[seriesKey]string     // 每个交易对当前写入的文件路径
+	compressing map[string]chan struct{} // 已关闭、正在压缩的文件，压缩完成前不重新打开
+	closed      bool
+}
+
+// NewFileSink 创建文件存储，basePath为空时使用./data，partitioner为nil时按天分区；
+// compression为none（或空）、gzip或zstd
+func NewFileSink(basePath string, partitioner *Partitioner, compression string) (*FileSink, error) {
+	if basePath == "" {
+		basePath = "./data"
+	}
+	switch compression {
+	case "", CompressionNone, CompressionGzip, CompressionZstd:
+	default:
+		return nil, fmt.Errorf("unsupported compression: %q", compression)
+	}
+	if partitioner == nil {
+		partitioner = &Partitioner{template: DefaultDailyPartition}
+	}
+	if err := os.MkdirAll(basePath, 0o755); err != nil {
+		return nil, fmt.Errorf("failed to create storage directory %s: %w", basePath, err)
+	}
+	return &FileSink{
+		basePath:    basePath,
+		partitioner: partitioner,
+		compression: compression,
+		files:       make(map[string]*openFile),
+		current:     make(map[seriesKey]string),
+		compressing: make(map[string]chan struct{}),
+	}, nil
+}
+
+// Write 将数据以DataRecord格式追加到对应文件，交易对切换分区时在释放锁后压缩旧文件
+func (s *FileSink) Write(data types.MarketData) error {
+	record, err := types.NewDataRecord(data)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data record: %w", err)
+	}
+	line = append(line, '\n')
+
+	series := seriesKey{exchange: data.GetExchange(), dataType: data.GetDataType(), symbol: data.GetSymbol()}
+	path := filepath.Join(s.basePath, filepath.FromSlash(s.partitioner.Key(data))+fileExtension)
+
+	s.mu.Lock()
+	// 文件正在压缩时等待压缩完成再重新打开，避免写入即将删除的原文件
+	for {
+		if s.closed {
+			s.mu.Unlock()
+			return ErrClosed
+		}
+		done, ok := s.compressing[path]
+		if !ok {
+			break
+		}
+		s.mu.Unlock()
+		<-done
+		s.mu.Lock()
+	}
+
+	file, toCompress, err := s.fileFor(series, path)
+	if err == nil {
+		if _, writeErr := file.Write(line); writeErr != nil {
+			err = fmt.Errorf("failed to write %s: %w", path, writeErr)
+		}
+	}
+	s.mu.Unlock()
+
+	// 旧文件压缩失败时保留原文件，下次关闭时重试，不影响本次写入的结果
+	if toCompress != "" {
+		s.compressFile(toCompress)
+	}
+	return err
+}
+
+// Flush 将打开的文件同步到磁盘，已关闭的文件在关闭时已写出
+func (s *FileSink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	var errs []error
+	for path, f := range s.files {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := f.file.Sync(); err != nil {
+			errs = append(errs, fmt.Errorf("failed to sync %s: %w", path, err))
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有打开的文件，启用压缩时在释放锁后压缩
+func (s *FileSink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+
+	var firstErr error
+	var toCompress []string
+	for path, f := range s.files {
+		compress, err := s.closeFile(path, f)
+		if err != nil && firstErr == nil {
+			firstErr = err
+		}
+		if compress {
+			toCompress = append(toCompress, path)
+		}
+		delete(s.files, path)
+	}
+	clear(s.current)
+	s.mu.Unlock()
+
+	for _, path := range toCompress {
+		if err := s.compressFile(path); err != nil && firstErr == nil {
+			firstErr = err
+		}
+	}
+
+	// 等待Write中正在进行的压缩完成
+	s.mu.Lock()
+	pending := make([]chan struct{}, 0, len(s.compressing))
+	for _, done := range s.compressing {
+		pending = append(pending, done)
+	}
+	s.mu.Unlock()
+	for _, done := range pending {
+		<-done
+	}
+	return firstErr
+}
+
+// fileFor 获取交易对应写入的文件，交易对切换文件时释放旧文件，没有交易对写入的文件被关闭（调用时需要持有锁）。
+// toCompress非空时为已关闭、需要在释放锁后压缩的旧文件
+func (s *FileSink) fileFor(series seriesKey, path string) (file *os.File, toCompress string, err error) {
+	if current, ok := s.current[series]; ok {
+		if current == path {
+			return s.files[path].file, "", nil
+		}
+		toCompress = s.release(current)
+		delete(s.current, series)
+	}
+
+	f, ok := s.files[path]
+	if !ok {
+		if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+			return nil, toCompress, fmt.Errorf("failed to create directory %s: %w", filepath.Dir(path), err)
+		}
+		file, err := os.OpenFile(path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644)
+		if err != nil {
+			return nil, toCompress, fmt.Errorf("failed to open %s: %w", path, err)
+		}
+		f = &openFile{file: file}
+		s.files[path] = f
+	}
+	f.users++
+	s.current[series] = path
+	return f.file, toCompress, nil
+}
+
+// release 交易对不再写入文件，没有交易对写入时关闭文件，返回需要压缩的文件路径（调用时需要持有锁）
+func (s *FileSink) release(path string) string {
+	f, ok := s.files[path]
+	if !ok {
+		return ""
+	}
+	f.users--
+	if f.users > 0 {
+		return ""
+	}
+	delete(s.files, path)
+	if compress, _ := s.closeFile(path, f); compress {
+		return path
+	}
+	return ""
+}
+
+// closeFile 关闭文件，启用压缩时标记文件正在压缩，返回true表示调用方需要在释放锁后调用compressFile（调用时需要持有锁）
+func (s *FileSink) closeFile(path string, f *openFile) (bool, error) {
+	if err := f.file.Close(); err != nil {
+		return false, fmt.Errorf("failed to close %s: %w", path, err)
+	}
+	if _, ok := compressedExtensions[s.compression]; !ok {
+		return false, nil
+	}
+	s.compressing[path] = make(chan struct{})
+	return true, nil
+}
+
+// compressFile 压缩已关闭的文件，完成后允许重新打开（不能持有锁调用）
+func (s *FileSink) compressFile(path string) error {
+	err := compressFile(path, s.compression)
+
+	s.mu.Lock()
+	close(s.compressing[path])
+	delete(s.compressing, path)
+	s.mu.Unlock()
+	return err
+}
+
+// compressFile 将文件压缩后追加到已有的压缩文件并删除原文件。
+// 先把已有压缩文件和新压缩的内容写入临时文件，同步到磁盘后原子替换压缩文件，再删除原文件；
+// 任何一步失败时压缩文件和原文件都保持不变，重试时不会重复追加数据
+func compressFile(path, compression string) error {
+	target := path + compressedExtensions[compression]
+	tmp, err := os.CreateTemp(filepath.Dir(target), filepath.Base(target)+".*.tmp")
+	if err != nil {
+		return fmt.Errorf("failed to create temp file for %s: %w", target, err)
+	}
+	defer os.Remove(tmp.Name()) // 重命名成功后为空操作
+
+	err = writeCompressed(tmp, path, target, compression)
+	if syncErr := tmp.Sync(); err == nil {
+		err = syncErr
+	}
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err != nil {
+		return fmt.Errorf("failed to compress %s: %w", path, err)
+	}
+	if err := os.Rename(tmp.Name(), target); err != nil {
+		return fmt.Errorf("failed to replace %s: %w", target, err)
+	}
+	return os.Remove(path)
+}
+
+// writeCompressed 将已有的压缩文件原样复制到dst，再追加path压缩后的内容
+// （gzip多成员、zstd多帧拼接后仍是合法的压缩流）
+func writeCompressed(dst io.Writer, path, target, compression string) error {
+	existing, err := os.Open(target)
+	switch {
+	case err == nil:
+		_, err = io.Copy(dst, existing)
+		existing.Close()
+		if err != nil {
+			return err
+		}
+	case !os.IsNotExist(err):
+		return err
+	}
+
+	src, err := os.Open(path)
+	if err != nil {
+		return err
+	}
+	defer src.Close()
+
+	zw, err := newCompressWriter(dst, compression)
+	if err != nil {
+		return err
+	}
+	if _, err := io.Copy(zw, src); err != nil {
+		zw.Close()
+		return err
+	}
+	return zw.Close()
+}
+
+// newCompressWriter 创建对应压缩方式的写入器
+func newCompressWriter(w io.Writer, compression string) (io.WriteCloser, error) {
+	switch compression {
+	case CompressionGzip:
+		return gzip.NewWriter(w), nil
+	case CompressionZstd:
+		return zstd.NewWriter(w)
+	default:
+		return nil, fmt.Errorf("unsupported compression: %q", compression)
+	}
+}
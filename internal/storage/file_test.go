@@ -0,0 +1,383 @@
+package storage
+
+import (
+	"compress/gzip"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/replay"
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+func TestFileSinkWritesReplayableFiles(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, nil, "")
+	if err != nil {
+		t.Fatalf("创建文件存储失败: %v", err)
+	}
+
+	day1 := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Minute)
+	data := []types.MarketData{
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 42000, Timestamp: day1},
+		&types.Kline{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Interval: "1m", OpenTime: day1, ClosePrice: 42100},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 42200, Timestamp: day2},
+	}
+	for _, d := range data {
+		if err := sink.Write(d); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	if err := sink.Write(data[0]); !errors.Is(err, ErrClosed) {
+		t.Errorf("关闭后写入应返回ErrClosed，实际: %v", err)
+	}
+
+	// 按交易所/数据类型/交易对/UTC日期分文件
+	for _, path := range []string{
+		"binance/ticker/BTCUSDT/2024-01-01.ndjson",
+		"binance/ticker/BTCUSDT/2024-01-02.ndjson",
+		"binance/klines/BTCUSDT/2024-01-01.ndjson",
+	} {
+		if _, err := os.Stat(filepath.Join(dir, path)); err != nil {
+			t.Errorf("期望文件%s存在: %v", path, err)
+		}
+	}
+
+	// 写入的文件可以直接回放
+	player := replay.NewPlayer(replay.Config{Paths: []string{dir}}, nil)
+	var received []types.MarketData
+	player.AddCallback(func(data types.MarketData) error {
+		received = append(received, data)
+		return nil
+	})
+	stats, err := player.Run(context.Background())
+	if err != nil || stats.Records != 3 || stats.Skipped != 0 {
+		t.Fatalf("回放结果不正确: %+v, %v", stats, err)
+	}
+	if kline, ok := received[0].(*types.Kline); !ok || kline.ClosePrice != 42100 {
+		t.Errorf("回放的K线不正确: %+v", received[0])
+	}
+}
+
+func TestNewStorage(t *testing.T) {
+	if sink, err := New(types.StorageConfig{}); sink != nil || err != nil {
+		t.Errorf("未启用存储时应返回nil: %v, %v", sink, err)
+	}
+
+	config := types.StorageConfig{File: types.FileStorageConfig{Enabled: true, BasePath: t.TempDir(), Format: "csv"}}
+	if _, err := New(config); err == nil {
+		t.Error("不支持的格式应返回错误")
+	}
+
+	config.File.Format = "json"
+	sink, err := New(config)
+	if err != nil {
+		t.Fatalf("创建存储失败: %v", err)
+	}
+	if _, ok := sink.(*FileSink); !ok {
+		t.Errorf("期望创建文件存储，实际%T", sink)
+	}
+	sink.Close()
+}
+
+func TestFileSinkPartitioning(t *testing.T) {
+	start := time.Date(2024, 1, 1, 22, 30, 0, 0, time.UTC)
+	data := []types.MarketData{
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 1, Timestamp: start},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "ETHUSDT", Price: 2, Timestamp: start.Add(10 * time.Minute)},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 3, Timestamp: start.Add(45 * time.Minute)},
+		&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: 4, Timestamp: start.Add(95 * time.Minute)},
+	}
+
+	tests := []struct {
+		name   string
+		config types.FileStorageConfig
+		want   map[string]int // 文件路径 -> 记录数
+	}{
+		{
+			name:   "按小时分区并加前缀",
+			config: types.FileStorageConfig{PartitionBy: PartitionHourly, Prefix: "/raw/market/"},
+			want: map[string]int{
+				"raw/market/binance/ticker/BTCUSDT/2024-01-01/22.ndjson": 1,
+				"raw/market/binance/ticker/ETHUSDT/2024-01-01/22.ndjson": 1,
+				"raw/market/binance/ticker/BTCUSDT/2024-01-01/23.ndjson": 1,
+				"raw/market/binance/ticker/BTCUSDT/2024-01-02/00.ndjson": 1,
+			},
+		},
+		{
+			name:   "自定义模板，多个交易对写入同一文件",
+			config: types.FileStorageConfig{Partition: "{year}/{month}/{day}/{exchange}-{dataType}"},
+			want: map[string]int{
+				"2024/01/01/binance-ticker.ndjson": 3,
+				"2024/01/02/binance-ticker.ndjson": 1,
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dir := t.TempDir()
+			partitioner, err := NewPartitioner(tt.config.PartitionBy, tt.config.Partition, tt.config.Prefix)
+			if err != nil {
+				t.Fatalf("创建分区器失败: %v", err)
+			}
+			sink, err := NewFileSink(dir, partitioner, "")
+			if err != nil {
+				t.Fatalf("创建文件存储失败: %v", err)
+			}
+			for _, d := range data {
+				if err := sink.Write(d); err != nil {
+					t.Fatalf("写入失败: %v", err)
+				}
+			}
+			// 切换分区后只保留每个交易对当前写入的文件
+			if len(sink.files) > 2 {
+				t.Errorf("切换分区后应关闭旧文件，当前打开%d个", len(sink.files))
+			}
+			if err := sink.Close(); err != nil {
+				t.Fatalf("关闭失败: %v", err)
+			}
+
+			got := make(map[string]int)
+			filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+				if err != nil || d.IsDir() {
+					return err
+				}
+				content, err := os.ReadFile(path)
+				if err != nil {
+					return err
+				}
+				rel, _ := filepath.Rel(dir, path)
+				got[filepath.ToSlash(rel)] = strings.Count(string(content), "\n")
+				return nil
+			})
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("文件分布不正确:\n期望 %v\n实际 %v", tt.want, got)
+			}
+		})
+	}
+}
+
+func TestNewPartitionerValidation(t *testing.T) {
+	invalid := []types.FileStorageConfig{
+		{PartitionBy: "minute"},
+		{PartitionBy: PartitionHourly, Partition: "{exchange}/{date}"},
+		{Partition: "{exchange}/{unknown}"},
+		{Partition: "../{exchange}/{date}"},
+		{Partition: "{exchange}//{date}"},
+		{Prefix: "raw/../../etc"},
+	}
+	for _, config := range invalid {
+		if _, err := NewPartitioner(config.PartitionBy, config.Partition, config.Prefix); err == nil {
+			t.Errorf("配置%+v应返回错误", config)
+		}
+	}
+
+	partitioner, err := NewPartitioner("", "", "")
+	if err != nil {
+		t.Fatalf("默认配置创建失败: %v", err)
+	}
+	ticker := &types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Timestamp: time.Date(2024, 3, 5, 1, 0, 0, 0, time.FixedZone("UTC+8", 8*3600))}
+	if key := partitioner.Key(ticker); key != "binance/ticker/BTCUSDT/2024-03-04" {
+		t.Errorf("默认按UTC日期分区，实际%s", key)
+	}
+}
+
+func TestFileSinkCompression(t *testing.T) {
+	for compression, ext := range compressedExtensions {
+		t.Run(compression, func(t *testing.T) {
+			dir := t.TempDir()
+			day := time.Date(2024, 1, 1, 12, 0, 0, 0, time.UTC)
+
+			// 两次运行写入同一天的文件，压缩文件以追加gzip成员（zstd帧）的方式合并
+			for run := 0; run < 2; run++ {
+				sink, err := NewFileSink(dir, nil, compression)
+				if err != nil {
+					t.Fatalf("创建文件存储失败: %v", err)
+				}
+				for i := 0; i < 3; i++ {
+					ticker := &types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: float64(run*3 + i), Timestamp: day.Add(time.Duration(run*3+i) * time.Minute)}
+					if err := sink.Write(ticker); err != nil {
+						t.Fatalf("写入失败: %v", err)
+					}
+				}
+				// 写入中的文件保持未压缩
+				if _, err := os.Stat(filepath.Join(dir, "binance/ticker/BTCUSDT/2024-01-01.ndjson")); err != nil {
+					t.Errorf("写入中的文件应未压缩: %v", err)
+				}
+				if err := sink.Close(); err != nil {
+					t.Fatalf("关闭失败: %v", err)
+				}
+			}
+
+			if _, err := os.Stat(filepath.Join(dir, "binance/ticker/BTCUSDT/2024-01-01.ndjson")); !os.IsNotExist(err) {
+				t.Errorf("压缩后应删除原文件: %v", err)
+			}
+			if _, err := os.Stat(filepath.Join(dir, "binance/ticker/BTCUSDT/2024-01-01.ndjson"+ext)); err != nil {
+				t.Errorf("应生成%s压缩文件: %v", ext, err)
+			}
+			if prices := replayPrices(t, dir); !reflect.DeepEqual(prices, []float64{0, 1, 2, 3, 4, 5}) {
+				t.Errorf("回放的数据不正确: %v", prices)
+			}
+		})
+	}
+}
+
+func TestFileSinkCompressionRotation(t *testing.T) {
+	dir := t.TempDir()
+	sink, err := NewFileSink(dir, nil, CompressionZstd)
+	if err != nil {
+		t.Fatalf("创建文件存储失败: %v", err)
+	}
+	day1 := time.Date(2024, 1, 1, 23, 59, 0, 0, time.UTC)
+	day2 := day1.Add(2 * time.Minute)
+	for i, ts := range []time.Time{day1, day2, day1, day2} {
+		// 切换分区时压缩旧文件，迟到的数据重新打开旧分区后再次压缩合并
+		if err := sink.Write(&types.Ticker{Exchange: types.ExchangeBinance, Symbol: "BTCUSDT", Price: float64(i), Timestamp: ts}); err != nil {
+			t.Fatalf("写入失败: %v", err)
+		}
+	}
+	if _, err := os.Stat(filepath.Join(dir, "binance/ticker/BTCUSDT/2024-01-01.ndjson.zst")); err != nil {
+		t.Errorf("切换分区后旧文件应已压缩: %v", err)
+	}
+	if err := sink.Close(); err != nil {
+		t.Fatalf("关闭失败: %v", err)
+	}
+	if prices := replayPrices(t, dir); !reflect.DeepEqual(prices, []float64{0, 2, 1, 3}) {
+		t.Errorf("回放的数据不正确: %v", prices)
+	}
+}
+
+func TestCompressFileFailureKeepsSource(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "data.ndjson")
+	line := `{"type":"ticker"}` + "\n"
+	if err := os.WriteFile(path, []byte(line), 0o644); err != nil {
+		t.Fatalf("写入文件失败: %v", err)
+	}
+
+	// 压缩文件的位置被目录占用，压缩失败
+	if err := os.Mkdir(path+".gz", 0o755); err != nil {
+		t.Fatalf("创建目录失败: %v", err)
+	}
+	if err := compressFile(path, CompressionGzip); err == nil {
+		t.Fatal("压缩文件无法写入时应返回错误")
+	}
+	if data, err := os.ReadFile(path); err != nil || string(data) != line {
+		t.Fatalf("压缩失败时应保留原文件: %q, %v", data, err)
+	}
+
+	// 重试时只写入一次原文件的内容，不留下临时文件
+	if err := os.Remove(path + ".gz"); err != nil {
+		t.Fatalf("删除目录失败: %v", err)
+	}
+	if err := compressFile(path, CompressionGzip); err != nil {
+		t.Fatalf("重试压缩失败: %v", err)
+	}
+	entries, err := os.ReadDir(dir)
+	if err != nil || len(entries) != 1 || entries[0].Name() != "data.ndjson.gz" {
+		t.Fatalf("压缩后目录中应只有压缩文件: %v, %v", entries, err)
+	}
+	f, err := os.Open(path + ".gz")
+	if err != nil {
+		t.Fatalf("打开压缩文件失败: %v", err)
+	}
+	defer f.Close()
+	zr, err := gzip.NewReader(f)
+	if err != nil {
+		t.Fatalf("读取压缩文件失败: %v", err)
+	}
+	if data, err := io.ReadAll(zr); err != nil || string(data) != line {
+		t.Errorf("压缩文件内容不正确: %q, %v", data, err)
+	}
+}
+
+// replayPrices 回放目录下的文件，返回行情价格
+func replayPrices(t *testing.T, dir string) []float64 {
+	t.Helper()
+	player := replay.NewPlayer(replay.Config{Paths: []string{dir}}, nil)
+	var prices []float64
+	player.AddCallback(func(data types.MarketData) error {
+		prices = append(prices, data.(*types.Ticker).Price)
+		return nil
+	})
+	if _, err := player.Run(context.Background()); err != nil {
+		t.Fatalf("回放失败: %v", err)
+	}
+	return prices
+}
+
+// BenchmarkFileSinkCompression 模拟100个交易对一天的1分钟K线，比较各压缩方式的磁盘占用
+func BenchmarkFileSinkCompression(b *testing.B) {
+	const symbols, minutes = 100, 24 * 60
+	day := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	klines := make([]types.Kline, 0, symbols*minutes)
+	for m := 0; m < minutes; m++ {
+		for s := 0; s < symbols; s++ {
+			price := 100 + float64(s) + float64(m%60)*0.01
+			openTime := day.Add(time.Duration(m) * time.Minute)
+			klines = append(klines, types.Kline{
+				Exchange: types.ExchangeBinance, Symbol: types.Symbol(fmt.Sprintf("SYM%03dUSDT", s)), Interval: "1m",
+				OpenTime: openTime, CloseTime: openTime.Add(time.Minute - time.Millisecond),
+				OpenPrice: price, HighPrice: price * 1.001, LowPrice: price * 0.999, ClosePrice: price * 1.0005,
+				Volume: 1234.5 + float64(m), TradeCount: int64(100 + m%50), TakerVolume: 600 + float64(m%100),
+			})
+		}
+	}
+
+	for _, compression := range []string{CompressionNone, CompressionGzip, CompressionZstd} {
+		b.Run(compression, func(b *testing.B) {
+			var diskBytes int64
+			for i := 0; i < b.N; i++ {
+				dir := b.TempDir()
+				sink, err := NewFileSink(dir, nil, compression)
+				if err != nil {
+					b.Fatalf("创建文件存储失败: %v", err)
+				}
+				for j := range klines {
+					if err := sink.Write(&klines[j]); err != nil {
+						b.Fatalf("写入失败: %v", err)
+					}
+				}
+				if err := sink.Close(); err != nil {
+					b.Fatalf("关闭失败: %v", err)
+				}
+				diskBytes = dirSize(b, dir)
+			}
+			b.ReportMetric(float64(diskBytes)/float64(len(klines)), "disk-B/record")
+			b.ReportMetric(float64(diskBytes)/(1<<20), "disk-MB")
+		})
+	}
+}
+
+// dirSize 统计目录下所有文件的大小
+func dirSize(tb testing.TB, dir string) int64 {
+	tb.Helper()
+	var size int64
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		info, err := d.Info()
+		if err != nil {
+			return err
+		}
+		size += info.Size()
+		return nil
+	})
+	if err != nil {
+		tb.Fatalf("统计目录大小失败: %v", err)
+	}
+	return size
+}
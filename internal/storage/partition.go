@@ -0,0 +1,109 @@
+package storage
+
+import (
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// 分区粒度
+const (
+	PartitionDaily  = "day"  // 按UTC日期分文件
+	PartitionHourly = "hour" // 按UTC小时分文件
+)
+
+// 默认分区模板
+const (
+	DefaultDailyPartition  = "{exchange}/{dataType}/{symbol}/{date}"
+	DefaultHourlyPartition = "{exchange}/{dataType}/{symbol}/{date}/{hour}"
+)
+
+// placeholderPattern 分区模板中的占位符
+var placeholderPattern = regexp.MustCompile(`\{[^{}]*\}`)
+
+// partitionPlaceholders 支持的占位符，时间均为数据时间戳的UTC时间
+var partitionPlaceholders = map[string]bool{
+	"{exchange}": true, // 交易所
+	"{dataType}": true, // 数据类型
+	"{symbol}":   true, // 交易对
+	"{date}":     true, // 日期，如2024-01-02
+	"{year}":     true, // 年，如2024
+	"{month}":    true, // 月，如01
+	"{day}":      true, // 日，如02
+	"{hour}":     true, // 小时，如15
+}
+
+// Partitioner 按模板计算数据写入的文件路径（相对于存储根目录，使用/分隔，不含扩展名）
+type Partitioner struct {
+	prefix   string
+	template string
+}
+
+// NewPartitioner 创建分区器：granularity为分区粒度（day、hour，默认day），未配置模板时按分区粒度使用默认模板，
+// 按小时分区时模板必须包含{hour}，避免同一天不同小时的数据写入同一文件；prefix为键前缀
+func NewPartitioner(granularity, template, prefix string) (*Partitioner, error) {
+	if granularity == "" {
+		granularity = PartitionDaily
+	}
+	if granularity != PartitionDaily && granularity != PartitionHourly {
+		return nil, fmt.Errorf("unsupported partition granularity: %q", granularity)
+	}
+
+	if template == "" {
+		template = DefaultDailyPartition
+		if granularity == PartitionHourly {
+			template = DefaultHourlyPartition
+		}
+	}
+	for _, placeholder := range placeholderPattern.FindAllString(template, -1) {
+		if !partitionPlaceholders[placeholder] {
+			return nil, fmt.Errorf("unknown placeholder %s in partition template %q", placeholder, template)
+		}
+	}
+	if granularity == PartitionHourly && !strings.Contains(template, "{hour}") {
+		return nil, fmt.Errorf("hourly partition template must contain {hour}: %q", template)
+	}
+	if err := validateKeyPath(template); err != nil {
+		return nil, fmt.Errorf("invalid partition template: %w", err)
+	}
+
+	prefix = strings.Trim(prefix, "/")
+	if prefix != "" {
+		if err := validateKeyPath(prefix); err != nil {
+			return nil, fmt.Errorf("invalid partition prefix: %w", err)
+		}
+	}
+	return &Partitioner{prefix: prefix, template: template}, nil
+}
+
+// Key 计算数据所在文件的键，如 raw/binance/ticker/BTCUSDT/2024-01-02
+func (p *Partitioner) Key(data types.MarketData) string {
+	t := data.GetTimestamp().UTC()
+	replacer := strings.NewReplacer(
+		"{exchange}", string(data.GetExchange()),
+		"{dataType}", string(data.GetDataType()),
+		"{symbol}", string(data.GetSymbol()),
+		"{date}", t.Format("2006-01-02"),
+		"{year}", t.Format("2006"),
+		"{month}", t.Format("01"),
+		"{day}", t.Format("02"),
+		"{hour}", t.Format("15"),
+	)
+	return path.Join(p.prefix, replacer.Replace(p.template))
+}
+
+// validateKeyPath 检查路径为相对路径且不包含空段、.或..，避免写到存储目录之外
+func validateKeyPath(key string) error {
+	if strings.HasPrefix(key, "/") {
+		return fmt.Errorf("%q must be relative", key)
+	}
+	for _, segment := range strings.Split(key, "/") {
+		if segment == "" || segment == "." || segment == ".." {
+			return fmt.Errorf("%q contains an empty, . or .. segment", key)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,318 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// S3存储的默认配置
+const (
+	defaultS3Region        = "us-east-1"
+	defaultS3PartSizeMB    = 8                // 分片大小，S3要求除最后一片外不小于5MB
+	minS3PartSizeMB        = 5                // 分片大小下限
+	defaultS3FlushInterval = 10 * time.Minute // 对象最长缓冲时间
+	defaultS3MaxRetries    = 3                // 临时错误的重试次数
+	s3RequestTimeout       = 2 * time.Minute  // 单次上传请求的超时时间
+	ndjsonContentType      = "application/x-ndjson"
+)
+
+// s3Object 正在缓冲的对象：缓冲区超过分片大小时以分片上传，结束时上传剩余数据并完成上传
+type s3Object struct {
+	key      string
+	buf      bytes.Buffer
+	uploadID string          // 已开始分片上传时的上传ID
+	parts    []completedPart // 已上传的分片
+	openedAt time.Time
+	users    int // 正在写入该对象的交易对数
+}
+
+// S3Sink S3兼容对象存储：按与文件存储相同的分区模板组织对象键，每个分区的数据缓冲后上传为NDJSON对象。
+// 对象键为 {prefix}/{分区}-{实例启动时间}-{序号}.ndjson，进程重启或同一分区多次上传时不会覆盖已有对象。
+// 分区切换、缓冲超过flush_interval或关闭时完成对象上传；上传在写入时同步进行，期间的写入会等待
+type S3Sink struct {
+	client        *s3Client
+	partitioner   *Partitioner
+	partSize      int
+	flushInterval time.Duration
+	instance      string // 实例启动时间，区分不同进程写入的对象
+
+	mu      sync.Mutex
+	seq     int
+	objects map[string]*s3Object // 按分区键索引正在缓冲的对象
+	current map[seriesKey]string // 每个交易对当前写入的分区键
+	closed  bool
+	done    chan struct{}
+	wg      sync.WaitGroup
+}
+
+// NewS3Sink 根据配置创建S3存储，并在后台定期上传缓冲超过flush_interval的对象
+func NewS3Sink(config types.S3StorageConfig, partitioner *Partitioner) (*S3Sink, error) {
+	if config.Bucket == "" {
+		return nil, fmt.Errorf("s3 bucket is required")
+	}
+	region := config.Region
+	if region == "" {
+		region = defaultS3Region
+	}
+	rawEndpoint := config.Endpoint
+	if rawEndpoint == "" {
+		rawEndpoint = "https://s3." + region + ".amazonaws.com"
+	}
+	endpoint, err := url.Parse(rawEndpoint)
+	if err != nil || endpoint.Scheme == "" || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid s3 endpoint %q", rawEndpoint)
+	}
+
+	partSizeMB := config.PartSizeMB
+	if partSizeMB == 0 {
+		partSizeMB = defaultS3PartSizeMB
+	}
+	if partSizeMB < minS3PartSizeMB {
+		return nil, fmt.Errorf("s3 part size must be at least %dMB, got %dMB", minS3PartSizeMB, partSizeMB)
+	}
+	maxRetries := config.MaxRetries
+	if maxRetries == 0 {
+		maxRetries = defaultS3MaxRetries
+	}
+	flushInterval := config.FlushInterval
+	if flushInterval <= 0 {
+		flushInterval = defaultS3FlushInterval
+	}
+	if partitioner == nil {
+		partitioner = &Partitioner{template: DefaultDailyPartition}
+	}
+
+	s := &S3Sink{
+		client: &s3Client{
+			endpoint:     endpoint,
+			bucket:       config.Bucket,
+			region:       region,
+			accessKey:    config.AccessKey,
+			secretKey:    config.SecretKey,
+			sessionToken: config.SessionToken,
+			pathStyle:    config.PathStyle,
+			maxRetries:   uint(max(maxRetries, 0)),
+			httpClient:   &http.Client{Timeout: s3RequestTimeout},
+		},
+		partitioner:   partitioner,
+		partSize:      partSizeMB << 20,
+		flushInterval: flushInterval,
+		instance:      time.Now().UTC().Format("20060102T150405Z"),
+		objects:       make(map[string]*s3Object),
+		current:       make(map[seriesKey]string),
+		done:          make(chan struct{}),
+	}
+	s.wg.Add(1)
+	go s.flushLoop()
+	return s, nil
+}
+
+// Write 将数据以DataRecord格式追加到对应分区的对象，缓冲区超过分片大小时上传一个分片
+func (s *S3Sink) Write(data types.MarketData) error {
+	record, err := types.NewDataRecord(data)
+	if err != nil {
+		return err
+	}
+	line, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("failed to marshal data record: %w", err)
+	}
+
+	series := seriesKey{exchange: data.GetExchange(), dataType: data.GetDataType(), symbol: data.GetSymbol()}
+	key := s.partitioner.Key(data)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return ErrClosed
+	}
+
+	// 旧对象上传失败时数据仍写入新对象，旧对象保留等待后台重试
+	object, finalizeErr := s.objectFor(series, key)
+	object.buf.Write(line)
+	object.buf.WriteByte('\n')
+	if object.buf.Len() >= s.partSize {
+		if err := s.uploadPart(context.Background(), object); err != nil {
+			return errors.Join(finalizeErr, err)
+		}
+	}
+	return finalizeErr
+}
+
+// Close 停止后台上传，完成所有缓冲中对象的上传
+func (s *S3Sink) Close() error {
+	s.mu.Lock()
+	if s.closed {
+		s.mu.Unlock()
+		return nil
+	}
+	s.closed = true
+	close(s.done)
+	s.mu.Unlock()
+	s.wg.Wait()
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	var errs []error
+	for key, object := range s.objects {
+		if err := s.finalize(context.Background(), object); err != nil {
+			errs = append(errs, err)
+			s.abort(object)
+		}
+		delete(s.objects, key)
+	}
+	clear(s.current)
+	return errors.Join(errs...)
+}
+
+// Flush 完成所有缓冲中对象的上传，交易对的下一条数据写入同一分区的新对象；
+// 上传失败的对象保留，由后台或下一次Flush重试
+func (s *S3Sink) Flush(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.closed {
+		return nil
+	}
+	return s.flushObjects(ctx, func(*s3Object) bool { return true })
+}
+
+// flushLoop 定期完成缓冲超过flush_interval的对象，避免按天分区时数据长时间只在内存中
+func (s *S3Sink) flushLoop() {
+	defer s.wg.Done()
+	ticker := time.NewTicker(min(s.flushInterval, time.Minute))
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-s.done:
+			return
+		case now := <-ticker.C:
+			s.mu.Lock()
+			// 上传失败时保留对象，下次重试
+			s.flushObjects(context.Background(), func(object *s3Object) bool {
+				return now.Sub(object.openedAt) >= s.flushInterval
+			})
+			s.mu.Unlock()
+		}
+	}
+}
+
+// flushObjects 完成due返回true的对象的上传，完成后交易对的下一条数据写入同一分区的新对象；
+// 上传失败的对象保留以便重试，返回所有失败的错误（调用时需要持有锁）
+func (s *S3Sink) flushObjects(ctx context.Context, due func(object *s3Object) bool) error {
+	var errs []error
+	for key, object := range s.objects {
+		if !due(object) {
+			continue
+		}
+		if err := ctx.Err(); err != nil {
+			return errors.Join(append(errs, err)...)
+		}
+		if err := s.finalize(ctx, object); err != nil {
+			errs = append(errs, err)
+			continue
+		}
+		delete(s.objects, key)
+		for series, current := range s.current {
+			if current == key {
+				delete(s.current, series)
+			}
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// objectFor 获取交易对应写入的对象，交易对切换分区且旧对象没有交易对写入时完成旧对象的上传，
+// 上传失败时返回错误并保留旧对象由后台重试（调用时需要持有锁）
+func (s *S3Sink) objectFor(series seriesKey, key string) (*s3Object, error) {
+	var finalizeErr error
+	if current, ok := s.current[series]; ok {
+		if current == key {
+			return s.objects[key], nil
+		}
+		delete(s.current, series)
+		if old, ok := s.objects[current]; ok {
+			old.users--
+			if old.users <= 0 {
+				if finalizeErr = s.finalize(context.Background(), old); finalizeErr == nil {
+					delete(s.objects, current)
+				}
+			}
+		}
+	}
+
+	object, ok := s.objects[key]
+	if !ok {
+		s.seq++
+		object = &s3Object{
+			key:      key + "-" + s.instance + "-" + strconv.Itoa(s.seq) + fileExtension,
+			openedAt: time.Now(),
+		}
+		s.objects[key] = object
+	}
+	object.users++
+	s.current[series] = key
+	return object, finalizeErr
+}
+
+// uploadPart 将缓冲区作为一个分片上传，第一次上传时开始分片上传（调用时需要持有锁）
+func (s *S3Sink) uploadPart(parent context.Context, object *s3Object) error {
+	ctx, cancel := context.WithTimeout(parent, s3RequestTimeout)
+	defer cancel()
+
+	if object.uploadID == "" {
+		uploadID, err := s.client.createMultipartUpload(ctx, object.key, ndjsonContentType)
+		if err != nil {
+			return err
+		}
+		object.uploadID = uploadID
+	}
+	partNumber := len(object.parts) + 1
+	etag, err := s.client.uploadPart(ctx, object.key, object.uploadID, partNumber, object.buf.Bytes())
+	if err != nil {
+		return err
+	}
+	object.parts = append(object.parts, completedPart{PartNumber: partNumber, ETag: etag})
+	object.buf.Reset()
+	return nil
+}
+
+// finalize 上传剩余数据并完成对象：未开始分片上传时直接上传整个对象，否则上传最后一片并完成分片上传（调用时需要持有锁）
+func (s *S3Sink) finalize(parent context.Context, object *s3Object) error {
+	ctx, cancel := context.WithTimeout(parent, s3RequestTimeout)
+	defer cancel()
+
+	if object.uploadID == "" {
+		if object.buf.Len() == 0 {
+			return nil
+		}
+		return s.client.putObject(ctx, object.key, object.buf.Bytes(), ndjsonContentType)
+	}
+
+	if object.buf.Len() > 0 {
+		if err := s.uploadPart(ctx, object); err != nil {
+			return err
+		}
+	}
+	return s.client.completeMultipartUpload(ctx, object.key, object.uploadID, object.parts)
+}
+
+// abort 放弃对象未完成的分片上传，释放S3上已上传的分片（调用时需要持有锁）
+func (s *S3Sink) abort(object *s3Object) {
+	if object.uploadID == "" {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), s3RequestTimeout)
+	defer cancel()
+	s.client.abortMultipartUpload(ctx, object.key, object.uploadID)
+}
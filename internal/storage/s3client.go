@@ -0,0 +1,294 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/xml"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/avast/retry-go/v4"
+)
+
+// s3Client 最小的S3兼容对象存储客户端（AWS S3、MinIO等），只实现写入对象需要的接口，使用签名V4认证
+type s3Client struct {
+	endpoint     *url.URL
+	bucket       string
+	region       string
+	accessKey    string
+	secretKey    string
+	sessionToken string
+	pathStyle    bool
+	maxRetries   uint
+	httpClient   *http.Client
+}
+
+// s3Error S3返回的错误
+type s3Error struct {
+	StatusCode int    `xml:"-"`
+	Code       string `xml:"Code"`
+	Message    string `xml:"Message"`
+}
+
+func (e *s3Error) Error() string {
+	return fmt.Sprintf("s3 error (status %d): %s: %s", e.StatusCode, e.Code, e.Message)
+}
+
+// completedPart 已上传的分片
+type completedPart struct {
+	PartNumber int    `xml:"PartNumber"`
+	ETag       string `xml:"ETag"`
+}
+
+// putObject 上传整个对象
+func (c *s3Client) putObject(ctx context.Context, key string, body []byte, contentType string) error {
+	header := http.Header{"Content-Type": []string{contentType}}
+	_, err := c.do(ctx, http.MethodPut, key, nil, header, body)
+	return err
+}
+
+// createMultipartUpload 开始分片上传，返回上传ID
+func (c *s3Client) createMultipartUpload(ctx context.Context, key, contentType string) (string, error) {
+	header := http.Header{"Content-Type": []string{contentType}}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploads": {""}}, header, nil)
+	if err != nil {
+		return "", err
+	}
+	var result struct {
+		UploadID string `xml:"UploadId"`
+	}
+	if err := xml.Unmarshal(resp.body, &result); err != nil || result.UploadID == "" {
+		return "", fmt.Errorf("invalid create multipart upload response for %s: %s", key, resp.body)
+	}
+	return result.UploadID, nil
+}
+
+// uploadPart 上传一个分片，返回分片的ETag
+func (c *s3Client) uploadPart(ctx context.Context, key, uploadID string, partNumber int, body []byte) (string, error) {
+	query := url.Values{"partNumber": {strconv.Itoa(partNumber)}, "uploadId": {uploadID}}
+	resp, err := c.do(ctx, http.MethodPut, key, query, nil, body)
+	if err != nil {
+		return "", err
+	}
+	etag := resp.header.Get("ETag")
+	if etag == "" {
+		return "", fmt.Errorf("missing ETag for part %d of %s", partNumber, key)
+	}
+	return etag, nil
+}
+
+// completeMultipartUpload 完成分片上传
+func (c *s3Client) completeMultipartUpload(ctx context.Context, key, uploadID string, parts []completedPart) error {
+	sort.Slice(parts, func(i, j int) bool { return parts[i].PartNumber < parts[j].PartNumber })
+	body, err := xml.Marshal(struct {
+		XMLName xml.Name        `xml:"CompleteMultipartUpload"`
+		Parts   []completedPart `xml:"Part"`
+	}{Parts: parts})
+	if err != nil {
+		return fmt.Errorf("failed to marshal complete multipart upload request: %w", err)
+	}
+
+	header := http.Header{"Content-Type": []string{"application/xml"}}
+	resp, err := c.do(ctx, http.MethodPost, key, url.Values{"uploadId": {uploadID}}, header, body)
+	if err != nil {
+		return err
+	}
+	// 完成分片上传可能在返回200后的响应体中报告错误
+	if bytes.Contains(resp.body, []byte("<Error>")) {
+		return parseS3Error(http.StatusOK, resp.body)
+	}
+	return nil
+}
+
+// abortMultipartUpload 放弃分片上传，释放已上传的分片
+func (c *s3Client) abortMultipartUpload(ctx context.Context, key, uploadID string) error {
+	_, err := c.do(ctx, http.MethodDelete, key, url.Values{"uploadId": {uploadID}}, nil, nil)
+	return err
+}
+
+// s3Response 成功的响应
+type s3Response struct {
+	header http.Header
+	body   []byte
+}
+
+// do 发送签名请求，网络错误、5xx和429等临时错误按指数退避重试
+func (c *s3Client) do(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*s3Response, error) {
+	var result *s3Response
+	err := retry.Do(
+		func() error {
+			resp, err := c.doOnce(ctx, method, key, query, header, body)
+			if err != nil {
+				return err
+			}
+			result = resp
+			return nil
+		},
+		retry.Attempts(c.maxRetries+1),
+		retry.Delay(500*time.Millisecond),
+		retry.DelayType(retry.BackOffDelay),
+		retry.Context(ctx),
+		retry.LastErrorOnly(true),
+		retry.RetryIf(isTransientS3Error),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("s3 %s %s failed: %w", method, key, err)
+	}
+	return result, nil
+}
+
+// doOnce 发送一次签名请求
+func (c *s3Client) doOnce(ctx context.Context, method, key string, query url.Values, header http.Header, body []byte) (*s3Response, error) {
+	req, err := http.NewRequestWithContext(ctx, method, c.objectURL(key, query), bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("failed to create request: %w", err)
+	}
+	for name, values := range header {
+		req.Header[name] = values
+	}
+	req.ContentLength = int64(len(body))
+	c.sign(req, body, time.Now())
+
+	resp, err := c.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	respBody, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return nil, parseS3Error(resp.StatusCode, respBody)
+	}
+	return &s3Response{header: resp.Header, body: respBody}, nil
+}
+
+// objectURL 对象的URL，路径风格为 {endpoint}/{bucket}/{key}，否则为 {bucket}.{host}/{key}
+func (c *s3Client) objectURL(key string, query url.Values) string {
+	u := *c.endpoint
+	objectPath := "/" + key
+	if c.pathStyle {
+		objectPath = "/" + c.bucket + objectPath
+	} else {
+		u.Host = c.bucket + "." + u.Host
+	}
+	base := strings.TrimSuffix(u.Path, "/")
+	u.Path = base + objectPath
+	u.RawPath = uriEncodePath(base) + uriEncodePath(objectPath)
+	u.RawQuery = canonicalQuery(query)
+	return u.String()
+}
+
+// uriEncodePath 按签名V4要求编码路径：除字母、数字、-_.~和/以外的字符都编码
+func uriEncodePath(path string) string {
+	var b strings.Builder
+	for i := 0; i < len(path); i++ {
+		ch := path[i]
+		if ch >= 'A' && ch <= 'Z' || ch >= 'a' && ch <= 'z' || ch >= '0' && ch <= '9' ||
+			ch == '-' || ch == '_' || ch == '.' || ch == '~' || ch == '/' {
+			b.WriteByte(ch)
+		} else {
+			fmt.Fprintf(&b, "%%%02X", ch)
+		}
+	}
+	return b.String()
+}
+
+// sign 使用AWS签名V4签名请求，签名包含host和请求中已设置的所有头
+func (c *s3Client) sign(req *http.Request, body []byte, now time.Time) {
+	payloadHash := sha256.Sum256(body)
+	amzDate := now.UTC().Format("20060102T150405Z")
+	date := amzDate[:8]
+
+	req.Header.Set("X-Amz-Date", amzDate)
+	req.Header.Set("X-Amz-Content-Sha256", hex.EncodeToString(payloadHash[:]))
+	if c.sessionToken != "" {
+		req.Header.Set("X-Amz-Security-Token", c.sessionToken)
+	}
+
+	headers := map[string]string{"host": req.URL.Host}
+	for name, values := range req.Header {
+		headers[strings.ToLower(name)] = strings.TrimSpace(strings.Join(values, ","))
+	}
+	names := make([]string, 0, len(headers))
+	for name := range headers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var canonicalHeaders strings.Builder
+	for _, name := range names {
+		canonicalHeaders.WriteString(name + ":" + headers[name] + "\n")
+	}
+	signedHeaders := strings.Join(names, ";")
+
+	canonicalRequest := strings.Join([]string{
+		req.Method,
+		req.URL.EscapedPath(),
+		req.URL.RawQuery,
+		canonicalHeaders.String(),
+		signedHeaders,
+		hex.EncodeToString(payloadHash[:]),
+	}, "\n")
+	requestHash := sha256.Sum256([]byte(canonicalRequest))
+
+	scope := date + "/" + c.region + "/s3/aws4_request"
+	stringToSign := "AWS4-HMAC-SHA256\n" + amzDate + "\n" + scope + "\n" + hex.EncodeToString(requestHash[:])
+
+	signingKey := hmacSHA256([]byte("AWS4"+c.secretKey), date)
+	signingKey = hmacSHA256(signingKey, c.region)
+	signingKey = hmacSHA256(signingKey, "s3")
+	signingKey = hmacSHA256(signingKey, "aws4_request")
+	signature := hex.EncodeToString(hmacSHA256(signingKey, stringToSign))
+
+	req.Header.Set("Authorization", fmt.Sprintf("AWS4-HMAC-SHA256 Credential=%s/%s, SignedHeaders=%s, Signature=%s",
+		c.accessKey, scope, signedHeaders, signature))
+}
+
+// hmacSHA256 计算HMAC-SHA256
+func hmacSHA256(key []byte, data string) []byte {
+	mac := hmac.New(sha256.New, key)
+	mac.Write([]byte(data))
+	return mac.Sum(nil)
+}
+
+// canonicalQuery 按签名V4要求编码查询参数：按键排序，空格编码为%20
+func canonicalQuery(query url.Values) string {
+	return strings.ReplaceAll(query.Encode(), "+", "%20")
+}
+
+// parseS3Error 解析S3的XML错误响应
+func parseS3Error(statusCode int, body []byte) error {
+	s3err := &s3Error{StatusCode: statusCode}
+	if err := xml.Unmarshal(body, s3err); err != nil || s3err.Code == "" {
+		s3err.Code = http.StatusText(statusCode)
+		s3err.Message = strings.TrimSpace(string(body))
+	}
+	return s3err
+}
+
+// isTransientS3Error 判断错误是否可以重试：网络错误、5xx、429以及S3的限流错误
+func isTransientS3Error(err error) bool {
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return false
+	}
+	var s3err *s3Error
+	if errors.As(err, &s3err) {
+		return s3err.StatusCode >= 500 || s3err.StatusCode == http.StatusTooManyRequests ||
+			s3err.Code == "SlowDown" || s3err.Code == "InternalError" || s3err.Code == "RequestTimeout"
+	}
+	var netErr net.Error
+	var urlErr *url.Error
+	return errors.As(err, &netErr) || errors.As(err, &urlErr) || errors.Is(err, io.ErrUnexpectedEOF)
+}
@@ -0,0 +1,130 @@
+// Package storage 提供市场数据的存储输出，文件存储写入的NDJSON记录可直接用于回放
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"github.com/mooyang-code/data-miner/internal/types"
+)
+
+// ErrClosed 存储已关闭
+var ErrClosed = errors.New("storage sink is closed")
+
+// Sink 市场数据存储输出
+type Sink interface {
+	// Write 写入一条市场数据
+	Write(data types.MarketData) error
+
+	// Flush 将缓冲中的数据写出到持久存储，不关闭存储，之后仍可继续写入；ctx结束时返回ctx.Err()
+	Flush(ctx context.Context) error
+
+	// Close 关闭存储，释放打开的文件等资源，关闭后的写入返回ErrClosed
+	Close() error
+}
+
+// New 根据存储配置创建存储输出，同时启用文件和S3存储时写入两者，未启用任何存储时返回nil
+func New(config types.StorageConfig) (Sink, error) {
+	var sinks []Sink
+	closeAll := func() {
+		for _, sink := range sinks {
+			sink.Close()
+		}
+	}
+
+	if config.File.Enabled {
+		sink, err := newFileSinkFromConfig(config.File)
+		if err != nil {
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+	if config.S3.Enabled {
+		sink, err := newS3SinkFromConfig(config.S3)
+		if err != nil {
+			closeAll()
+			return nil, err
+		}
+		sinks = append(sinks, sink)
+	}
+
+	switch len(sinks) {
+	case 0:
+		return nil, nil
+	case 1:
+		return sinks[0], nil
+	default:
+		return multiSink(sinks), nil
+	}
+}
+
+// newFileSinkFromConfig 根据文件存储配置创建文件存储
+func newFileSinkFromConfig(config types.FileStorageConfig) (Sink, error) {
+	if err := checkFormat(config.Format); err != nil {
+		return nil, err
+	}
+	partitioner, err := NewPartitioner(config.PartitionBy, config.Partition, config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewFileSink(config.BasePath, partitioner, config.Compression)
+}
+
+// newS3SinkFromConfig 根据S3存储配置创建S3存储
+func newS3SinkFromConfig(config types.S3StorageConfig) (Sink, error) {
+	if err := checkFormat(config.Format); err != nil {
+		return nil, err
+	}
+	partitioner, err := NewPartitioner(config.PartitionBy, config.Partition, config.Prefix)
+	if err != nil {
+		return nil, err
+	}
+	return NewS3Sink(config, partitioner)
+}
+
+// checkFormat 检查存储格式，目前只支持NDJSON
+func checkFormat(format string) error {
+	switch format {
+	case "", "json", "ndjson":
+		return nil
+	default:
+		return fmt.Errorf("unsupported storage format: %q", format)
+	}
+}
+
+// multiSink 同时写入多个存储
+type multiSink []Sink
+
+// Write 写入所有存储，返回所有失败的错误
+func (m multiSink) Write(data types.MarketData) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Write(data); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Flush 写出所有存储的缓冲数据，返回所有失败的错误
+func (m multiSink) Flush(ctx context.Context) error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Flush(ctx); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+// Close 关闭所有存储
+func (m multiSink) Close() error {
+	var errs []error
+	for _, sink := range m {
+		if err := sink.Close(); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
@@ -0,0 +1,65 @@
+package types
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+)
+
+// BatchError 批量请求中部分交易对失败时返回的错误
+// 批量方法返回该错误时，成功获取的结果仍会一并返回，调用方可以保存成功的数据并记录失败的交易对
+type BatchError struct {
+	Errors map[Symbol]error // 交易对 -> 失败原因
+}
+
+// NewBatchError 创建批量请求错误
+func NewBatchError() *BatchError {
+	return &BatchError{Errors: make(map[Symbol]error)}
+}
+
+// Add 记录交易对的失败原因，err为nil时忽略
+func (e *BatchError) Add(symbol Symbol, err error) {
+	if err == nil {
+		return
+	}
+	if e.Errors == nil {
+		e.Errors = make(map[Symbol]error)
+	}
+	e.Errors[symbol] = err
+}
+
+// Symbols 返回失败的交易对（按字母顺序排列）
+func (e *BatchError) Symbols() []Symbol {
+	symbols := make([]Symbol, 0, len(e.Errors))
+	for symbol := range e.Errors {
+		symbols = append(symbols, symbol)
+	}
+	sort.Slice(symbols, func(i, j int) bool { return symbols[i] < symbols[j] })
+	return symbols
+}
+
+// Error 实现error接口
+func (e *BatchError) Error() string {
+	parts := make([]string, 0, len(e.Errors))
+	for _, symbol := range e.Symbols() {
+		parts = append(parts, fmt.Sprintf("%s: %v", symbol, e.Errors[symbol]))
+	}
+	return fmt.Sprintf("%d symbol(s) failed: %s", len(e.Errors), strings.Join(parts, "; "))
+}
+
+// Unwrap 返回各交易对的错误，支持errors.Is/errors.As匹配
+func (e *BatchError) Unwrap() []error {
+	errs := make([]error, 0, len(e.Errors))
+	for _, symbol := range e.Symbols() {
+		errs = append(errs, e.Errors[symbol])
+	}
+	return errs
+}
+
+// ErrOrNil 没有失败的交易对时返回nil，否则返回自身
+func (e *BatchError) ErrOrNil() error {
+	if e == nil || len(e.Errors) == 0 {
+		return nil
+	}
+	return e
+}
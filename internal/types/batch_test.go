@@ -0,0 +1,32 @@
+package types
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestBatchError(t *testing.T) {
+	batchErr := NewBatchError()
+	if batchErr.ErrOrNil() != nil {
+		t.Fatal("expected nil error for empty batch")
+	}
+
+	batchErr.Add("ETHUSDT", errors.New("timeout"))
+	batchErr.Add("BTCUSDT", ErrInvalidSymbol)
+	batchErr.Add("BNBUSDT", nil)
+
+	err := batchErr.ErrOrNil()
+	if err == nil {
+		t.Fatal("expected error when symbols failed")
+	}
+	if !errors.Is(err, ErrInvalidSymbol) {
+		t.Errorf("expected errors.Is to match ErrInvalidSymbol, got %v", err)
+	}
+	if symbols := batchErr.Symbols(); len(symbols) != 2 || symbols[0] != "BTCUSDT" || symbols[1] != "ETHUSDT" {
+		t.Errorf("unexpected failed symbols: %v", symbols)
+	}
+	if !strings.HasPrefix(err.Error(), "2 symbol(s) failed: BTCUSDT") {
+		t.Errorf("unexpected error message: %s", err)
+	}
+}
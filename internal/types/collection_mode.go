@@ -0,0 +1,62 @@
+package types
+
+import (
+	"fmt"
+	"time"
+)
+
+// 数据采集模式
+const (
+	CollectionModeWebsocket = "websocket" // 只使用WebSocket推送
+	CollectionModeREST      = "rest"      // 只使用调度器定时REST拉取
+	CollectionModeHybrid    = "hybrid"    // WebSocket为主，不健康超过阈值时改用REST拉取，恢复后切回WebSocket
+)
+
+// DefaultHybridFallbackAfter hybrid模式下WebSocket持续不健康多久后切换到REST拉取
+const DefaultHybridFallbackAfter = time.Minute
+
+// StreamDataTypes 可通过WebSocket推送采集的数据类型，hybrid模式下这些类型同一时间只由一种来源采集
+var StreamDataTypes = []DataType{
+	DataTypeTicker,
+	DataTypeOrderbook,
+	DataTypeTrades,
+	DataTypeKlines,
+}
+
+// CollectionMode 返回采集模式，未配置mode时按use_websocket推断（兼容旧配置）
+func (c BinanceConfig) CollectionMode() string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	if c.UseWebsocket {
+		return CollectionModeWebsocket
+	}
+	return CollectionModeREST
+}
+
+// UsesWebsocket 返回采集模式是否需要建立WebSocket连接（websocket或hybrid）
+func (c BinanceConfig) UsesWebsocket() bool {
+	return c.CollectionMode() != CollectionModeREST
+}
+
+// FallbackThreshold 返回hybrid模式切换到REST拉取的不健康时长阈值，未配置时使用默认值
+func (c BinanceConfig) FallbackThreshold() time.Duration {
+	if c.HybridFallbackAfter > 0 {
+		return c.HybridFallbackAfter
+	}
+	return DefaultHybridFallbackAfter
+}
+
+// ValidateMode 校验采集模式
+func (c BinanceConfig) ValidateMode() error {
+	switch c.CollectionMode() {
+	case CollectionModeWebsocket, CollectionModeREST, CollectionModeHybrid:
+	default:
+		return fmt.Errorf("unsupported collection mode %q, expected %s, %s or %s",
+			c.Mode, CollectionModeWebsocket, CollectionModeREST, CollectionModeHybrid)
+	}
+	if c.HybridFallbackAfter < 0 {
+		return fmt.Errorf("hybrid_fallback_after must not be negative")
+	}
+	return nil
+}
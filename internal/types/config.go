@@ -34,18 +34,67 @@ type DatabaseConfig struct {
 // ExchangesConfig 交易所配置
 type ExchangesConfig struct {
 	Binance BinanceConfig `yaml:"binance"` // Binance交易所配置
+	Kraken  KrakenConfig  `yaml:"kraken"`  // Kraken交易所配置
+}
+
+// KrakenConfig Kraken交易所配置
+type KrakenConfig struct {
+	Enabled   bool            `yaml:"enabled"`    // 是否启用
+	APIURL    string          `yaml:"api_url"`    // API地址，默认https://api.kraken.com
+	DataTypes KrakenDataTypes `yaml:"data_types"` // 数据类型配置
+}
+
+// KrakenDataTypes Kraken数据类型配置，只支持现货公共行情，交易对需要明确配置（不支持["*"]）
+type KrakenDataTypes struct {
+	Ticker    TickerConfig    `yaml:"ticker"`    // 行情配置
+	Orderbook OrderbookConfig `yaml:"orderbook"` // 订单簿配置
+	Trades    TradesConfig    `yaml:"trades"`    // 交易配置
+	Klines    KlinesConfig    `yaml:"klines"`    // K线配置
 }
 
 // BinanceConfig Binance交易所配置
 type BinanceConfig struct {
-	Enabled       bool             `yaml:"enabled"`        // 是否启用
-	APIURL        string           `yaml:"api_url"`        // API地址
-	WebsocketURL  string           `yaml:"websocket_url"`  // WebSocket地址
-	APIKey        string           `yaml:"api_key"`        // API密钥
-	APISecret     string           `yaml:"api_secret"`     // API密钥
-	UseWebsocket  bool             `yaml:"use_websocket"`  // 是否使用websocket模式
-	DataTypes     BinanceDataTypes `yaml:"data_types"`     // 数据类型配置
-	TradablePairs TradablePairsConfig `yaml:"tradable_pairs"` // 可交易交易对配置
+	Enabled                     bool                     `yaml:"enabled"`                       // 是否启用
+	APIURL                      string                   `yaml:"api_url"`                       // API地址
+	WebsocketURL                string                   `yaml:"websocket_url"`                 // WebSocket地址
+	APIKey                      string                   `yaml:"api_key"`                       // API密钥
+	APISecret                   string                   `yaml:"api_secret"`                    // API密钥
+	UseWebsocket                bool                     `yaml:"use_websocket"`                 // 是否使用websocket模式（未配置mode时生效）
+	Mode                        string                   `yaml:"mode"`                          // 采集模式：websocket、rest或hybrid，未配置时按use_websocket选择websocket或rest
+	ProxyURL                    string                   `yaml:"proxy_url"`                     // 代理地址（http://、https://、socks5://），REST和WebSocket均经代理连接且不使用动态IP
+	DataTypes                   BinanceDataTypes         `yaml:"data_types"`                    // 数据类型配置
+	TradablePairs               TradablePairsConfig      `yaml:"tradable_pairs"`                // 可交易交易对配置
+	TimeSync                    TimeSyncConfig           `yaml:"time_sync"`                     // 服务器时间同步配置
+	TLS                         TLSConfig                `yaml:"tls"`                           // REST和WebSocket的TLS客户端配置
+	WebsocketReconnect          WebsocketReconnectConfig `yaml:"websocket_reconnect"`           // WebSocket断线重连退避配置
+	SubscribeConfirmTimeout     time.Duration            `yaml:"subscribe_confirm_timeout"`     // 订阅后等待交易所确认的超时时间，0表示不等待（默认，避免阻塞批量订阅）
+	HybridFallbackAfter         time.Duration            `yaml:"hybrid_fallback_after"`         // hybrid模式下WebSocket持续不健康超过该时长后改用REST拉取，默认1分钟
+	MaxInFlightRequests         int                      `yaml:"max_in_flight_requests"`        // REST客户端同时进行中的请求数上限，所有任务共享，0表示不限制
+	DisableWebsocketCompression bool                     `yaml:"disable_websocket_compression"` // 不请求WebSocket permessage-deflate压缩（默认请求）
+}
+
+// TLSConfig TLS客户端配置，用于经TLS检查代理连接时提供企业CA并正常校验证书
+type TLSConfig struct {
+	MinVersion  string `yaml:"min_version"`   // 最低TLS版本："1.2"或"1.3"，为空时使用默认值
+	RootCAsFile string `yaml:"root_cas_file"` // 额外信任的CA证书文件（PEM格式）
+	ServerName  string `yaml:"server_name"`   // 覆盖SNI及证书校验使用的主机名（用于分离式DNS）
+}
+
+// WebsocketReconnectConfig WebSocket断线重连配置，重连延迟按指数退避并做全抖动（在0到退避上限之间随机），
+// 避免大量连接同时断开后同步重连
+type WebsocketReconnectConfig struct {
+	BaseDelay   time.Duration `yaml:"base_delay"`   // 首次重连的退避上限，默认5秒
+	MaxDelay    time.Duration `yaml:"max_delay"`    // 退避上限的最大值，默认2分钟
+	MaxAttempts int           `yaml:"max_attempts"` // 最大重连次数，默认5次
+
+	LogThrottleInterval time.Duration `yaml:"log_throttle_interval"` // 重复的连接错误日志首次输出后，每隔该时间最多输出一次并附带被抑制的次数，默认30秒
+}
+
+// TimeSyncConfig 服务器时间同步配置
+type TimeSyncConfig struct {
+	Enabled    bool          `yaml:"enabled"`     // 是否启用服务器时间偏移监控
+	Interval   time.Duration `yaml:"interval"`    // 同步间隔，默认1分钟
+	RecvWindow time.Duration `yaml:"recv_window"` // 签名请求的recvWindow，默认5秒；偏移超过该值时告警
 }
 
 // BinanceDataTypes Binance数据类型配置
@@ -54,36 +103,51 @@ type BinanceDataTypes struct {
 	Orderbook OrderbookConfig `yaml:"orderbook"` // 订单簿配置
 	Trades    TradesConfig    `yaml:"trades"`    // 交易配置
 	Klines    KlinesConfig    `yaml:"klines"`    // K线配置
+	AvgPrice  TickerConfig    `yaml:"avg_price"` // 平均价格配置（与行情配置结构相同）
+	Stats24h  TickerConfig    `yaml:"stats_24h"` // 24小时统计配置（与行情配置结构相同）
 }
 
 // TickerConfig 行情配置
 type TickerConfig struct {
-	Enabled  bool     `yaml:"enabled"`  // 是否启用
-	Symbols  []string `yaml:"symbols"`  // 交易对列表
-	Interval string   `yaml:"interval"` // 更新间隔
+	Enabled            bool             `yaml:"enabled"`              // 是否启用
+	Symbols            []string         `yaml:"symbols"`              // 交易对列表
+	Interval           string           `yaml:"interval"`             // 更新间隔
+	BulkPriceThreshold int              `yaml:"bulk_price_threshold"` // 交易对数量达到该值时改用全量价格接口（只采集价格），0表示不启用
+	SymbolFilter       `yaml:",inline"` // ["*"]展开后的交易对过滤
 }
 
 // OrderbookConfig 订单簿配置
 type OrderbookConfig struct {
-	Enabled  bool     `yaml:"enabled"`  // 是否启用
-	Symbols  []string `yaml:"symbols"`  // 交易对列表
-	Depth    int      `yaml:"depth"`    // 深度
-	Interval string   `yaml:"interval"` // 更新间隔
+	Enabled     bool     `yaml:"enabled"`      // 是否启用
+	Symbols     []string `yaml:"symbols"`      // 交易对列表
+	Depth       int      `yaml:"depth"`        // 深度
+	Interval    string   `yaml:"interval"`     // 更新间隔
+	Concurrency int      `yaml:"concurrency"`  // 批量请求的并发数，默认5
+	Mode        string   `yaml:"mode"`         // WebSocket深度流模式：partial（有限档）或diff（增量），未配置时按depth推断
+	Levels      int      `yaml:"levels"`       // partial模式的档数：5、10或20，默认20
+	UpdateSpeed string   `yaml:"update_speed"` // WebSocket深度流推送频率（两种模式通用）：100ms或1000ms，默认100ms
+
+	SymbolFilter `yaml:",inline"` // ["*"]展开后的交易对过滤
 }
 
 // TradesConfig 交易数据配置
 type TradesConfig struct {
-	Enabled  bool     `yaml:"enabled"`  // 是否启用
-	Symbols  []string `yaml:"symbols"`  // 交易对列表
-	Interval string   `yaml:"interval"` // 更新间隔
+	Enabled      bool             `yaml:"enabled"`  // 是否启用
+	Symbols      []string         `yaml:"symbols"`  // 交易对列表
+	Interval     string           `yaml:"interval"` // 更新间隔
+	SymbolFilter `yaml:",inline"` // ["*"]展开后的交易对过滤
 }
 
 // KlinesConfig K线数据配置
 type KlinesConfig struct {
-	Enabled   bool     `yaml:"enabled"`   // 是否启用
-	Symbols   []string `yaml:"symbols"`   // 交易对列表
-	Intervals []string `yaml:"intervals"` // 时间间隔列表
-	Interval  string   `yaml:"interval"`  // 更新间隔
+	Enabled     bool     `yaml:"enabled"`      // 是否启用
+	Symbols     []string `yaml:"symbols"`      // 交易对列表
+	Intervals   []string `yaml:"intervals"`    // 时间间隔列表
+	Interval    string   `yaml:"interval"`     // 更新间隔
+	Incremental bool     `yaml:"incremental"`  // 增量模式：只获取上次采集的最后一根已收盘K线之后的K线
+	IncludeLive bool     `yaml:"include_live"` // REST获取时保留尚未收盘的K线，默认只返回已收盘K线
+
+	SymbolFilter `yaml:",inline"` // ["*"]展开后的交易对过滤
 }
 
 // TradablePairsConfig 可交易交易对配置
@@ -95,13 +159,45 @@ type TradablePairsConfig struct {
 	SupportedAssets    []string      `yaml:"supported_assets"`      // 支持的资产类型 ["spot", "margin"]
 	AutoUpdate         bool          `yaml:"auto_update"`           // 是否自动更新
 	SkipOnNetworkError bool          `yaml:"skip_on_network_error"` // 网络错误时是否跳过初始化
+
+	MissRefreshInterval time.Duration `yaml:"miss_refresh_interval"` // 查询的交易对不在缓存中时触发刷新的最小间隔，默认1分钟
+	RecheckOnMiss       bool          `yaml:"recheck_on_miss"`       // 未命中时是否同步刷新并重新检查，否则在后台刷新
 }
 
 // SchedulerConfig 调度器配置
 type SchedulerConfig struct {
-	Enabled           bool        `yaml:"enabled"`             // 是否启用
-	MaxConcurrentJobs int         `yaml:"max_concurrent_jobs"` // 最大并发任务数
-	Jobs              []JobConfig `yaml:"jobs"`                // 任务列表
+	Enabled           bool             `yaml:"enabled"`             // 是否启用
+	MaxConcurrentJobs int              `yaml:"max_concurrent_jobs"` // 最大并发任务数
+	JobHistorySize    int              `yaml:"job_history_size"`    // 每个任务保留的最近执行记录数，默认20
+	RateLimit         RateLimitConfig  `yaml:"rate_limit"`          // 频控配置
+	Timeouts          JobTimeoutConfig `yaml:"timeouts"`            // 各数据类型任务的超时时间
+	StaleThreshold    time.Duration    `yaml:"stale_threshold"`     // 交易对最近一次成功采集超过该时长视为数据缺失，默认10分钟
+	Quarantine        QuarantineConfig `yaml:"quarantine"`          // 连续失败交易对的隔离配置
+	Jobs              []JobConfig      `yaml:"jobs"`                // 任务列表
+}
+
+// JobTimeoutConfig 任务超时配置，未配置（为0）的数据类型使用默认值
+type JobTimeoutConfig struct {
+	Ticker          time.Duration `yaml:"ticker"`            // ticker任务超时，默认2分钟
+	Stats24h        time.Duration `yaml:"stats_24h"`         // 24小时统计任务超时，默认2分钟
+	AvgPrice        time.Duration `yaml:"avg_price"`         // 平均价格任务超时，默认3分钟
+	Orderbook       time.Duration `yaml:"orderbook"`         // 订单簿任务超时，默认3分钟
+	Trades          time.Duration `yaml:"trades"`            // 成交任务超时，默认3分钟
+	Klines          time.Duration `yaml:"klines"`            // K线任务超时，默认5分钟
+	KlinesPerSeries time.Duration `yaml:"klines_per_series"` // K线任务按规模放大超时：每个交易对×周期增加的时间，超时取klines与该值×交易对数×周期数中的较大者，默认0不放大
+	Default         time.Duration `yaml:"default"`           // 其他数据类型的任务超时，默认2分钟
+}
+
+// RateLimitConfig 调度器频控配置
+type RateLimitConfig struct {
+	AssumeFullOnError bool `yaml:"assume_full_on_error"` // 获取真实权重失败时假设权重已用满，等待窗口内的权重全部滑出后才继续请求；默认使用本地估算
+}
+
+// QuarantineConfig 连续失败交易对的隔离配置：连续失败达到阈值后暂时跳过该交易对，冷却时间随失败次数指数增长
+type QuarantineConfig struct {
+	FailureThreshold int           `yaml:"failure_threshold"` // 连续失败多少次后开始隔离，默认3，小于0时不隔离
+	BaseCooldown     time.Duration `yaml:"base_cooldown"`     // 首次隔离时长，之后每多失败一次翻倍，默认5分钟
+	MaxCooldown      time.Duration `yaml:"max_cooldown"`      // 隔离时长上限，默认1小时
 }
 
 // JobConfig 任务配置
@@ -109,20 +205,45 @@ type JobConfig struct {
 	Name     string `yaml:"name"`      // 任务名称
 	Exchange string `yaml:"exchange"`  // 交易所名称
 	DataType string `yaml:"data_type"` // 数据类型
+	Asset    string `yaml:"asset"`     // 资产类型（spot, margin, cross_margin），默认spot
 	Cron     string `yaml:"cron"`      // Cron表达式
 }
 
 // StorageConfig 存储配置
 type StorageConfig struct {
 	File  FileStorageConfig  `yaml:"file"`  // 文件存储配置
+	S3    S3StorageConfig    `yaml:"s3"`    // S3兼容对象存储配置
 	Cache CacheStorageConfig `yaml:"cache"` // 缓存存储配置
 }
 
 // FileStorageConfig 文件存储配置
 type FileStorageConfig struct {
-	Enabled  bool   `yaml:"enabled"`   // 是否启用
-	BasePath string `yaml:"base_path"` // 基础路径
-	Format   string `yaml:"format"`    // 文件格式
+	Enabled     bool   `yaml:"enabled"`      // 是否启用
+	BasePath    string `yaml:"base_path"`    // 基础路径
+	Format      string `yaml:"format"`       // 文件格式
+	PartitionBy string `yaml:"partition_by"` // 分区粒度（day、hour），默认day
+	Partition   string `yaml:"partition"`    // 分区模板，支持{exchange}、{dataType}、{symbol}、{date}、{year}、{month}、{day}、{hour}，默认按分区粒度选择
+	Prefix      string `yaml:"prefix"`       // 文件键前缀，如raw/market，便于与对象存储的键保持一致
+	Compression string `yaml:"compression"`  // 压缩方式（none、gzip、zstd），文件关闭后压缩并追加.gz或.zst扩展名，默认none
+}
+
+// S3StorageConfig S3兼容对象存储（AWS S3、MinIO等）配置，分区方式与文件存储相同
+type S3StorageConfig struct {
+	Enabled       bool          `yaml:"enabled"`        // 是否启用
+	Endpoint      string        `yaml:"endpoint"`       // 服务地址，如http://minio:9000，默认https://s3.{region}.amazonaws.com
+	Region        string        `yaml:"region"`         // 区域，默认us-east-1
+	Bucket        string        `yaml:"bucket"`         // 存储桶
+	AccessKey     string        `yaml:"access_key"`     // 访问密钥ID
+	SecretKey     string        `yaml:"secret_key"`     // 访问密钥
+	SessionToken  string        `yaml:"session_token"`  // 临时凭证的会话令牌，可选
+	PathStyle     bool          `yaml:"path_style"`     // 使用路径风格的URL（{endpoint}/{bucket}/{key}），MinIO通常需要开启
+	Format        string        `yaml:"format"`         // 对象格式，目前只支持json（NDJSON）
+	PartitionBy   string        `yaml:"partition_by"`   // 分区粒度（day、hour），默认day
+	Partition     string        `yaml:"partition"`      // 分区模板，与文件存储相同
+	Prefix        string        `yaml:"prefix"`         // 对象键前缀
+	PartSizeMB    int           `yaml:"part_size_mb"`   // 缓冲超过该大小时以分片上传，默认8MB，最小5MB
+	FlushInterval time.Duration `yaml:"flush_interval"` // 对象最长缓冲时间，超过后完成上传并开始新对象，默认10分钟
+	MaxRetries    int           `yaml:"max_retries"`    // 临时错误的重试次数，默认3，小于0时不重试
 }
 
 // CacheStorageConfig 缓存存储配置
@@ -134,7 +255,10 @@ type CacheStorageConfig struct {
 
 // MonitoringConfig 监控配置
 type MonitoringConfig struct {
-	Enabled         bool `yaml:"enabled"`           // 是否启用
-	MetricsPort     int  `yaml:"metrics_port"`      // 指标端口
-	HealthCheckPort int  `yaml:"health_check_port"` // 健康检查端口
+	Enabled         bool   `yaml:"enabled"`           // 是否启用
+	ListenAddr      string `yaml:"listen_addr"`       // 监听地址，默认127.0.0.1；对外提供服务时设置为0.0.0.0
+	MetricsPort     int    `yaml:"metrics_port"`      // 指标端口
+	HealthCheckPort int    `yaml:"health_check_port"` // 健康检查端口
+	EnablePprof     bool   `yaml:"enable_pprof"`      // 是否开启pprof性能分析接口（/debug/pprof/），默认关闭
+	PprofPort       int    `yaml:"pprof_port"`        // pprof端口，0或与指标端口相同时挂载在监控服务上
 }
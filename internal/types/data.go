@@ -9,17 +9,31 @@ import (
 type DataType string
 
 const (
-	DataTypeTicker    DataType = "ticker"    // 行情数据
-	DataTypeOrderbook DataType = "orderbook" // 订单簿数据
-	DataTypeTrades    DataType = "trades"    // 交易数据
-	DataTypeKlines    DataType = "klines"    // K线数据
+	DataTypeTicker     DataType = "ticker"      // 行情数据
+	DataTypeOrderbook  DataType = "orderbook"   // 订单簿数据
+	DataTypeTrades     DataType = "trades"      // 交易数据
+	DataTypeKlines     DataType = "klines"      // K线数据
+	DataTypeBookTicker DataType = "book_ticker" // 最优挂单数据
+	DataTypeAvgPrice   DataType = "avg_price"   // 平均价格数据
+	DataTypeStats24h   DataType = "stats_24h"   // 24小时统计数据
 )
 
+// ScheduledDataTypes 调度任务支持的数据类型（job配置中的data_type）
+var ScheduledDataTypes = []DataType{
+	DataTypeTicker,
+	DataTypeOrderbook,
+	DataTypeTrades,
+	DataTypeKlines,
+	DataTypeAvgPrice,
+	DataTypeStats24h,
+}
+
 // Exchange 交易所枚举
 type Exchange string
 
 const (
 	ExchangeBinance Exchange = "binance" // Binance交易所
+	ExchangeKraken  Exchange = "kraken"  // Kraken交易所
 )
 
 // Symbol 交易对符号
@@ -37,6 +51,18 @@ type Ticker struct {
 	Timestamp time.Time `json:"timestamp"`  // 时间戳
 }
 
+// BookTicker 最优挂单（最佳买卖价）数据
+type BookTicker struct {
+	Exchange    Exchange  `json:"exchange"`     // 交易所
+	Symbol      Symbol    `json:"symbol"`       // 交易对
+	UpdateID    int64     `json:"update_id"`    // 更新ID
+	BidPrice    float64   `json:"bid_price"`    // 最佳买价
+	BidQuantity float64   `json:"bid_quantity"` // 最佳买价数量
+	AskPrice    float64   `json:"ask_price"`    // 最佳卖价
+	AskQuantity float64   `json:"ask_quantity"` // 最佳卖价数量
+	Timestamp   time.Time `json:"timestamp"`    // 时间戳
+}
+
 // OrderbookEntry 订单簿条目
 type OrderbookEntry struct {
 	Price    float64 `json:"price"`    // 价格
@@ -79,6 +105,34 @@ type Kline struct {
 	TakerVolume float64   `json:"taker_volume"` // 主动买入成交量
 }
 
+// AvgPrice 平均价格数据（最近Minutes分钟的成交均价）
+type AvgPrice struct {
+	Exchange  Exchange  `json:"exchange"`  // 交易所
+	Symbol    Symbol    `json:"symbol"`    // 交易对
+	Minutes   int64     `json:"minutes"`   // 均价统计的分钟数
+	Price     float64   `json:"price"`     // 平均价格
+	Timestamp time.Time `json:"timestamp"` // 时间戳
+}
+
+// Stats24h 24小时滚动窗口统计数据
+type Stats24h struct {
+	Exchange           Exchange  `json:"exchange"`             // 交易所
+	Symbol             Symbol    `json:"symbol"`               // 交易对
+	PriceChange        float64   `json:"price_change"`         // 价格变化
+	PriceChangePercent float64   `json:"price_change_percent"` // 价格变化百分比
+	WeightedAvgPrice   float64   `json:"weighted_avg_price"`   // 加权平均价
+	PrevClosePrice     float64   `json:"prev_close_price"`     // 前收盘价
+	LastPrice          float64   `json:"last_price"`           // 最新价格
+	OpenPrice          float64   `json:"open_price"`           // 开盘价
+	HighPrice          float64   `json:"high_price"`           // 最高价
+	LowPrice           float64   `json:"low_price"`            // 最低价
+	Volume             float64   `json:"volume"`               // 成交量
+	QuoteVolume        float64   `json:"quote_volume"`         // 成交额
+	TradeCount         int64     `json:"trade_count"`          // 成交笔数
+	OpenTime           time.Time `json:"open_time"`            // 统计窗口开始时间
+	CloseTime          time.Time `json:"close_time"`           // 统计窗口结束时间
+}
+
 // MarketData 通用市场数据接口
 type MarketData interface {
 	GetExchange() Exchange   // 获取交易所
@@ -93,6 +147,12 @@ func (t *Ticker) GetSymbol() Symbol       { return t.Symbol }
 func (t *Ticker) GetTimestamp() time.Time { return t.Timestamp }
 func (t *Ticker) GetDataType() DataType   { return DataTypeTicker }
 
+// BookTicker实现MarketData接口
+func (b *BookTicker) GetExchange() Exchange   { return b.Exchange }
+func (b *BookTicker) GetSymbol() Symbol       { return b.Symbol }
+func (b *BookTicker) GetTimestamp() time.Time { return b.Timestamp }
+func (b *BookTicker) GetDataType() DataType   { return DataTypeBookTicker }
+
 // Orderbook实现MarketData接口
 func (o *Orderbook) GetExchange() Exchange   { return o.Exchange }
 func (o *Orderbook) GetSymbol() Symbol       { return o.Symbol }
@@ -111,5 +171,17 @@ func (k *Kline) GetSymbol() Symbol       { return k.Symbol }
 func (k *Kline) GetTimestamp() time.Time { return k.OpenTime }
 func (k *Kline) GetDataType() DataType   { return DataTypeKlines }
 
+// AvgPrice实现MarketData接口
+func (a *AvgPrice) GetExchange() Exchange   { return a.Exchange }
+func (a *AvgPrice) GetSymbol() Symbol       { return a.Symbol }
+func (a *AvgPrice) GetTimestamp() time.Time { return a.Timestamp }
+func (a *AvgPrice) GetDataType() DataType   { return DataTypeAvgPrice }
+
+// Stats24h实现MarketData接口
+func (s *Stats24h) GetExchange() Exchange   { return s.Exchange }
+func (s *Stats24h) GetSymbol() Symbol       { return s.Symbol }
+func (s *Stats24h) GetTimestamp() time.Time { return s.CloseTime }
+func (s *Stats24h) GetDataType() DataType   { return DataTypeStats24h }
+
 // DataCallback 数据回调函数类型
 type DataCallback func(data MarketData) error
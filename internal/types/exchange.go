@@ -26,6 +26,7 @@ type ExchangeInterface interface {
 	GetKlines(ctx context.Context, symbol Symbol, interval string, limit int) ([]Kline, error)
 
 	// GetMultipleTickers 批量获取行情数据
+	// 部分交易对失败时返回成功的结果和*BatchError，批量方法都遵循该约定
 	GetMultipleTickers(ctx context.Context, symbols []Symbol) ([]Ticker, error)
 	// GetMultipleOrderbooks 批量获取订单簿数据
 	GetMultipleOrderbooks(ctx context.Context, symbols []Symbol, depth int) ([]Orderbook, error)
@@ -51,6 +52,29 @@ type ExchangeInterface interface {
 	GetRateLimit() *RateLimit
 	// CheckRateLimit 检查速率限制
 	CheckRateLimit() error
+
+	// SymbolFormatter 按交易所规则格式化交易对符号
+	SymbolFormatter
+}
+
+// StatsProvider 提供平均价格和24小时统计数据的交易所（可选能力，调度器通过类型断言使用）
+type StatsProvider interface {
+	// GetAvgPrice 获取单个交易对的平均价格
+	GetAvgPrice(ctx context.Context, symbol Symbol) (*AvgPrice, error)
+	// GetMultipleStats24h 批量获取24小时统计数据，部分交易对失败时返回成功的结果和*BatchError
+	GetMultipleStats24h(ctx context.Context, symbols []Symbol) ([]Stats24h, error)
+}
+
+// PriceProvider 支持一次请求获取所有交易对最新价格的交易所（可选能力，交易对较多时替代权重较高的24小时行情接口）
+type PriceProvider interface {
+	// GetMultiplePrices 批量获取最新价格，返回的Ticker只包含价格字段，部分交易对失败时返回成功的结果和*BatchError
+	GetMultiplePrices(ctx context.Context, symbols []Symbol) ([]Ticker, error)
+}
+
+// KlineRangeProvider 支持按起始时间获取K线的交易所（可选能力，用于增量采集K线）
+type KlineRangeProvider interface {
+	// GetKlinesSince 获取开盘时间不早于startTime的K线，最多limit根
+	GetKlinesSince(ctx context.Context, symbol Symbol, interval string, startTime time.Time, limit int) ([]Kline, error)
 }
 
 // RateLimit 速率限制结构
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// BinanceKlineIntervals Binance支持的K线周期
+var BinanceKlineIntervals = []string{
+	"1s", "1m", "3m", "5m", "15m", "30m", "1h", "2h", "4h", "6h", "8h", "12h", "1d", "3d", "1w", "1M",
+}
+
+// klineIntervalUnits K线周期单位对应的时长，月按30天近似
+var klineIntervalUnits = map[byte]time.Duration{
+	's': time.Second,
+	'm': time.Minute,
+	'h': time.Hour,
+	'd': 24 * time.Hour,
+	'w': 7 * 24 * time.Hour,
+	'M': 30 * 24 * time.Hour,
+}
+
+// KlineIntervalDuration 将K线周期（如"1m"、"4h"、"1d"、"1M"）转换为时长
+func KlineIntervalDuration(interval string) (time.Duration, error) {
+	if len(interval) < 2 {
+		return 0, fmt.Errorf("invalid kline interval %q", interval)
+	}
+	unit, ok := klineIntervalUnits[interval[len(interval)-1]]
+	if !ok {
+		return 0, fmt.Errorf("invalid kline interval %q: unknown unit", interval)
+	}
+	n, err := strconv.Atoi(interval[:len(interval)-1])
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid kline interval %q", interval)
+	}
+	return time.Duration(n) * unit, nil
+}
+
+// ValidateBinanceKlineInterval 校验K线周期是否为Binance支持的周期，不支持时返回列出有效值的错误
+func ValidateBinanceKlineInterval(interval string) error {
+	if !slices.Contains(BinanceKlineIntervals, interval) {
+		return fmt.Errorf("unsupported Binance kline interval %q, valid values: %s",
+			interval, strings.Join(BinanceKlineIntervals, ","))
+	}
+	return nil
+}
+
+// ValidateIntervals 校验配置的K线周期均为Binance支持的周期
+func (c KlinesConfig) ValidateIntervals() error {
+	for _, interval := range c.Intervals {
+		if err := ValidateBinanceKlineInterval(interval); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,54 @@
+package types
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestKlineIntervalDuration(t *testing.T) {
+	valid := map[string]time.Duration{
+		"1s":  time.Second,
+		"1m":  time.Minute,
+		"15m": 15 * time.Minute,
+		"4h":  4 * time.Hour,
+		"1d":  24 * time.Hour,
+		"1w":  7 * 24 * time.Hour,
+		"1M":  30 * 24 * time.Hour,
+	}
+	for interval, want := range valid {
+		if got, err := KlineIntervalDuration(interval); err != nil || got != want {
+			t.Errorf("KlineIntervalDuration(%q) = %v, %v; want %v", interval, got, err, want)
+		}
+	}
+
+	for _, interval := range []string{"", "m", "0m", "-1h", "1x", "1.5h"} {
+		if _, err := KlineIntervalDuration(interval); err == nil {
+			t.Errorf("expected error for interval %q", interval)
+		}
+	}
+}
+
+func TestValidateBinanceKlineInterval(t *testing.T) {
+	for _, interval := range BinanceKlineIntervals {
+		if err := ValidateBinanceKlineInterval(interval); err != nil {
+			t.Errorf("expected %q to be valid, got %v", interval, err)
+		}
+	}
+
+	for _, interval := range []string{"", "1hr", "2m", "1D", "10m", "1y"} {
+		err := ValidateBinanceKlineInterval(interval)
+		if err == nil {
+			t.Errorf("expected error for interval %q", interval)
+			continue
+		}
+		if !strings.Contains(err.Error(), "1s,1m,3m") {
+			t.Errorf("expected error to list valid intervals, got %v", err)
+		}
+	}
+
+	config := KlinesConfig{Intervals: []string{"1m", "1hr"}}
+	if err := config.ValidateIntervals(); err == nil || !strings.Contains(err.Error(), `"1hr"`) {
+		t.Errorf("expected invalid interval 1hr to be reported, got %v", err)
+	}
+}
@@ -0,0 +1,59 @@
+package types
+
+import (
+	"fmt"
+	"slices"
+)
+
+// 订单簿WebSocket深度流模式
+const (
+	OrderbookModePartial = "partial" // 有限档深度流：每条消息为前N档的完整快照
+	OrderbookModeDiff    = "diff"    // 增量深度流：推送全部档位的变化，需要REST快照维护本地订单簿
+)
+
+// DefaultOrderbookLevels 有限档深度流的默认档数
+const DefaultOrderbookLevels = 20
+
+// OrderbookLevels 有限档深度流支持的档数
+var OrderbookLevels = []int{5, 10, 20}
+
+// StreamMode 返回深度流模式，未配置时按depth推断（兼容旧配置）：5/10/20为partial，其他为diff
+func (c OrderbookConfig) StreamMode() string {
+	if c.Mode != "" {
+		return c.Mode
+	}
+	if slices.Contains(OrderbookLevels, c.Depth) {
+		return OrderbookModePartial
+	}
+	return OrderbookModeDiff
+}
+
+// StreamLevels 返回有限档深度流的档数，未配置时使用depth（为5/10/20时）或默认的20档
+func (c OrderbookConfig) StreamLevels() int {
+	if c.Levels != 0 {
+		return c.Levels
+	}
+	if slices.Contains(OrderbookLevels, c.Depth) {
+		return c.Depth
+	}
+	return DefaultOrderbookLevels
+}
+
+// StreamDepth 返回订阅深度流使用的深度参数：partial模式为档数，diff模式为0（订阅增量深度流）
+func (c OrderbookConfig) StreamDepth() int {
+	if c.StreamMode() == OrderbookModeDiff {
+		return 0
+	}
+	return c.StreamLevels()
+}
+
+// ValidateStream 校验深度流模式和档数
+func (c OrderbookConfig) ValidateStream() error {
+	if c.Mode != "" && c.Mode != OrderbookModePartial && c.Mode != OrderbookModeDiff {
+		return fmt.Errorf("unsupported orderbook mode %q, expected %s or %s", c.Mode, OrderbookModePartial, OrderbookModeDiff)
+	}
+	if c.Levels != 0 && !slices.Contains(OrderbookLevels, c.Levels) {
+		return fmt.Errorf("unsupported orderbook levels %d, expected one of %v", c.Levels, OrderbookLevels)
+	}
+	return nil
+}
@@ -0,0 +1,41 @@
+package types
+
+import "testing"
+
+func TestOrderbookStreamSettings(t *testing.T) {
+	tests := []struct {
+		name   string
+		config OrderbookConfig
+		mode   string
+		levels int
+		depth  int
+	}{
+		{"inferred partial", OrderbookConfig{Depth: 10}, OrderbookModePartial, 10, 10},
+		{"inferred diff", OrderbookConfig{Depth: 100}, OrderbookModeDiff, 20, 0},
+		{"explicit partial", OrderbookConfig{Mode: OrderbookModePartial, Depth: 100}, OrderbookModePartial, 20, 20},
+		{"explicit levels", OrderbookConfig{Mode: OrderbookModePartial, Levels: 5, Depth: 20}, OrderbookModePartial, 5, 5},
+		{"explicit diff", OrderbookConfig{Mode: OrderbookModeDiff, Depth: 20}, OrderbookModeDiff, 20, 0},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.config.StreamMode(); got != tt.mode {
+				t.Errorf("StreamMode() = %q, want %q", got, tt.mode)
+			}
+			if got := tt.config.StreamLevels(); got != tt.levels {
+				t.Errorf("StreamLevels() = %d, want %d", got, tt.levels)
+			}
+			if got := tt.config.StreamDepth(); got != tt.depth {
+				t.Errorf("StreamDepth() = %d, want %d", got, tt.depth)
+			}
+		})
+	}
+
+	for _, config := range []OrderbookConfig{{Mode: "snapshot"}, {Levels: 50}} {
+		if err := config.ValidateStream(); err == nil {
+			t.Errorf("Expected error for %+v", config)
+		}
+	}
+	if err := (OrderbookConfig{Mode: OrderbookModeDiff, Levels: 10}).ValidateStream(); err != nil {
+		t.Errorf("Unexpected error: %v", err)
+	}
+}
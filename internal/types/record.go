@@ -0,0 +1,50 @@
+package types
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// DataRecord 市场数据的持久化记录格式（NDJSON中的一行），
+// 通过Type区分具体的数据类型，文件存储写入与回放读取都使用该格式
+type DataRecord struct {
+	Type DataType        `json:"type"` // 数据类型
+	Data json.RawMessage `json:"data"` // 数据内容
+}
+
+// NewDataRecord 将市场数据转换为持久化记录
+func NewDataRecord(data MarketData) (*DataRecord, error) {
+	raw, err := json.Marshal(data)
+	if err != nil {
+		return nil, fmt.Errorf("failed to marshal %s data: %w", data.GetDataType(), err)
+	}
+	return &DataRecord{Type: data.GetDataType(), Data: raw}, nil
+}
+
+// Decode 将持久化记录还原为对应类型的市场数据
+func (r *DataRecord) Decode() (MarketData, error) {
+	var data MarketData
+	switch r.Type {
+	case DataTypeTicker:
+		data = &Ticker{}
+	case DataTypeBookTicker:
+		data = &BookTicker{}
+	case DataTypeOrderbook:
+		data = &Orderbook{}
+	case DataTypeTrades:
+		data = &Trade{}
+	case DataTypeKlines:
+		data = &Kline{}
+	case DataTypeAvgPrice:
+		data = &AvgPrice{}
+	case DataTypeStats24h:
+		data = &Stats24h{}
+	default:
+		return nil, fmt.Errorf("unsupported data type: %q", r.Type)
+	}
+
+	if err := json.Unmarshal(r.Data, data); err != nil {
+		return nil, fmt.Errorf("failed to unmarshal %s data: %w", r.Type, err)
+	}
+	return data, nil
+}
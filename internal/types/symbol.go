@@ -0,0 +1,86 @@
+package types
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// ErrInvalidSymbol 无效的交易对符号
+var ErrInvalidSymbol = errors.New("invalid symbol")
+
+// symbolDelimiters 交易对符号中支持的分隔符（如BTC/USDT、BTC-USDT、BTC_USDT）
+var symbolDelimiters = []string{"/", "-", "_"}
+
+// knownQuoteAssets 拆分不带分隔符的交易对符号时使用的计价币种，按长度从长到短排列以优先匹配较长的代码
+var knownQuoteAssets = []string{
+	"FDUSD", "USDT", "USDC", "BUSD", "TUSD", "USDP", "DAI", "USD", "EUR", "GBP", "TRY", "BRL",
+	"BTC", "ETH", "BNB",
+}
+
+// ToPair 将交易对符号转换为currency.Pair
+// 带分隔符的符号（BTC/USDT）按分隔符拆分；不带分隔符的符号（BTCUSDT）按已知的计价币种后缀拆分，
+// 都不匹配时退化为前3个字符作为基础币种
+func (s Symbol) ToPair() (currency.Pair, error) {
+	raw := strings.ToUpper(strings.TrimSpace(string(s)))
+	if len(raw) < 3 {
+		return currency.EMPTYPAIR, fmt.Errorf("%w: %q", ErrInvalidSymbol, string(s))
+	}
+
+	for _, delimiter := range symbolDelimiters {
+		if base, quote, found := strings.Cut(raw, delimiter); found {
+			if base == "" || quote == "" {
+				return currency.EMPTYPAIR, fmt.Errorf("%w: %q", ErrInvalidSymbol, string(s))
+			}
+			return currency.NewPairFromStrings(base, quote)
+		}
+	}
+
+	for _, quote := range knownQuoteAssets {
+		if len(raw) > len(quote) && strings.HasSuffix(raw, quote) {
+			return currency.NewPairFromStrings(raw[:len(raw)-len(quote)], quote)
+		}
+	}
+
+	pair, err := currency.NewPairFromString(raw)
+	if err != nil {
+		return currency.EMPTYPAIR, fmt.Errorf("%w: %q: %v", ErrInvalidSymbol, string(s), err)
+	}
+	return pair, nil
+}
+
+// Normalize 返回统一格式（大写、不带分隔符）的交易对符号，如BTC/USDT -> BTCUSDT
+func (s Symbol) Normalize() Symbol {
+	pair, err := s.ToPair()
+	if err != nil {
+		return Symbol(strings.ToUpper(strings.TrimSpace(string(s))))
+	}
+	return PairToSymbol(pair)
+}
+
+// SymbolFormatter 按交易所规则将交易对格式化为该交易所使用的符号（如Binance为BTCUSDT，Kraken为XBTUSDT），
+// 共享代码需要交易所原生符号时应通过它格式化，而不是假定某个交易所的拼接规则
+type SymbolFormatter interface {
+	// FormatSymbol 将交易对格式化为交易所使用的符号
+	FormatSymbol(pair currency.Pair) (string, error)
+}
+
+// FormatSymbols 使用交易所的格式化规则批量格式化交易对，结果与pairs顺序一致，任一交易对失败时返回错误
+func FormatSymbols(formatter SymbolFormatter, pairs currency.Pairs) ([]string, error) {
+	symbols := make([]string, 0, len(pairs))
+	for _, pair := range pairs {
+		symbol, err := formatter.FormatSymbol(pair)
+		if err != nil {
+			return nil, fmt.Errorf("format %s: %w", pair, err)
+		}
+		symbols = append(symbols, symbol)
+	}
+	return symbols, nil
+}
+
+// PairToSymbol 将currency.Pair转换为统一格式（大写、不带分隔符）的交易对符号
+func PairToSymbol(pair currency.Pair) Symbol {
+	return Symbol(pair.Base.Upper().String() + pair.Quote.Upper().String())
+}
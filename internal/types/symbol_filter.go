@@ -0,0 +1,88 @@
+package types
+
+import (
+	"fmt"
+	"path"
+	"strings"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+// SymbolFilter 交易对过滤配置，用于过滤["*"]展开后的交易对列表，避免采集杠杆代币等无用交易对浪费权重。
+// 模式使用glob语法（*匹配任意字符，?匹配单个字符，[ABC]匹配字符集），大小写不敏感，如"*USDT"、"*UP*"
+type SymbolFilter struct {
+	IncludePatterns []string `yaml:"include_patterns"` // 只保留匹配任一模式的交易对，为空时不限制
+	ExcludePatterns []string `yaml:"exclude_patterns"` // 排除匹配任一模式的交易对，优先于include_patterns
+	QuoteAssets     []string `yaml:"quote_assets"`     // 只保留计价币种在列表中的交易对（如["USDT"]），为空时不限制
+}
+
+// IsEmpty 是否未配置任何过滤条件
+func (f SymbolFilter) IsEmpty() bool {
+	return len(f.IncludePatterns) == 0 && len(f.ExcludePatterns) == 0 && len(f.QuoteAssets) == 0
+}
+
+// Validate 检查过滤模式是否为合法的glob模式
+func (f SymbolFilter) Validate() error {
+	for _, patterns := range [][]string{f.IncludePatterns, f.ExcludePatterns} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(strings.ToUpper(pattern), ""); err != nil {
+				return fmt.Errorf("invalid symbol pattern %q: %w", pattern, err)
+			}
+		}
+	}
+	return nil
+}
+
+// Match 判断交易对符号是否通过模式过滤：不匹配任何排除模式，且匹配任一包含模式（未配置包含模式时视为匹配）。
+// 只检查包含/排除模式，计价币种需要通过MatchPair检查
+func (f SymbolFilter) Match(symbol string) bool {
+	symbol = strings.ToUpper(symbol)
+	if matchAny(f.ExcludePatterns, symbol) {
+		return false
+	}
+	return len(f.IncludePatterns) == 0 || matchAny(f.IncludePatterns, symbol)
+}
+
+// MatchQuote 判断计价币种是否在quote_assets中（未配置时视为匹配）
+func (f SymbolFilter) MatchQuote(quote currency.Code) bool {
+	if len(f.QuoteAssets) == 0 {
+		return true
+	}
+	for _, asset := range f.QuoteAssets {
+		if strings.EqualFold(strings.TrimSpace(asset), quote.String()) {
+			return true
+		}
+	}
+	return false
+}
+
+// MatchPair 判断交易对是否通过所有过滤条件。计价币种直接取自Pair.Quote，
+// 不依赖从符号字符串中拆分计价币种，避免ETHBTC、BTCUPUSDT等符号的歧义
+func (f SymbolFilter) MatchPair(pair currency.Pair) bool {
+	return f.MatchQuote(pair.Quote) && f.Match(string(PairToSymbol(pair)))
+}
+
+// ApplyPairs 返回通过过滤的交易对，保持原有顺序
+func (f SymbolFilter) ApplyPairs(pairs currency.Pairs) currency.Pairs {
+	if f.IsEmpty() {
+		return pairs
+	}
+
+	result := make(currency.Pairs, 0, len(pairs))
+	for _, pair := range pairs {
+		if f.MatchPair(pair) {
+			result = append(result, pair)
+		}
+	}
+	return result
+}
+
+// matchAny 判断交易对是否匹配任一模式，非法模式视为不匹配
+func matchAny(patterns []string, symbol string) bool {
+	for _, pattern := range patterns {
+		if matched, err := path.Match(strings.ToUpper(pattern), symbol); err == nil && matched {
+			return true
+		}
+	}
+	return false
+}
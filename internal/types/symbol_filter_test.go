@@ -0,0 +1,69 @@
+package types
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/mooyang-code/data-miner/pkg/cryptotrader/currency"
+)
+
+func TestSymbolFilterApplyPairs(t *testing.T) {
+	var pairs currency.Pairs
+	for _, symbol := range []Symbol{"BTCUSDT", "ETHUSDT", "BTCUPUSDT", "ETHDOWNUSDT", "ETHBTC", "BNBBULLUSDT", "BNBFDUSD"} {
+		pair, err := symbol.ToPair()
+		if err != nil {
+			t.Fatalf("%s: unexpected error: %v", symbol, err)
+		}
+		pairs = append(pairs, pair)
+	}
+
+	tests := []struct {
+		name   string
+		filter SymbolFilter
+		want   []Symbol
+	}{
+		{"empty", SymbolFilter{},
+			[]Symbol{"BTCUSDT", "ETHUSDT", "BTCUPUSDT", "ETHDOWNUSDT", "ETHBTC", "BNBBULLUSDT", "BNBFDUSD"}},
+		{"include", SymbolFilter{IncludePatterns: []string{"*usdt"}},
+			[]Symbol{"BTCUSDT", "ETHUSDT", "BTCUPUSDT", "ETHDOWNUSDT", "BNBBULLUSDT"}},
+		{"exclude", SymbolFilter{ExcludePatterns: []string{"*UPUSDT", "*DOWNUSDT", "*BULLUSDT", "*BEARUSDT"}},
+			[]Symbol{"BTCUSDT", "ETHUSDT", "ETHBTC", "BNBFDUSD"}},
+		{"exclude wins", SymbolFilter{IncludePatterns: []string{"*USDT"}, ExcludePatterns: []string{"*UPUSDT", "ETH*"}},
+			[]Symbol{"BTCUSDT", "BNBBULLUSDT"}},
+		{"character class", SymbolFilter{IncludePatterns: []string{"[BE]TCUSDT", "???BTC"}},
+			[]Symbol{"BTCUSDT", "ETHBTC"}},
+		{"quote", SymbolFilter{QuoteAssets: []string{"btc", "FDUSD"}},
+			[]Symbol{"ETHBTC", "BNBFDUSD"}},
+		{"quote and exclude", SymbolFilter{QuoteAssets: []string{"USDT"}, ExcludePatterns: []string{"*UPUSDT", "*DOWNUSDT", "*BULLUSDT"}},
+			[]Symbol{"BTCUSDT", "ETHUSDT"}},
+	}
+	for _, tt := range tests {
+		var got []Symbol
+		for _, pair := range tt.filter.ApplyPairs(pairs) {
+			got = append(got, PairToSymbol(pair))
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: got %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestSymbolFilterMatchQuote(t *testing.T) {
+	// 计价币种取自Pair.Quote：BTCUSDT的计价币种是USDT而不是BTC
+	filter := SymbolFilter{QuoteAssets: []string{"BTC"}}
+	if filter.MatchPair(currency.NewPair(currency.BTC, currency.USDT)) {
+		t.Error("BTC/USDT should not match quote BTC")
+	}
+	if !filter.MatchPair(currency.NewPair(currency.ETH, currency.BTC)) {
+		t.Error("ETH/BTC should match quote BTC")
+	}
+}
+
+func TestSymbolFilterValidate(t *testing.T) {
+	if err := (SymbolFilter{IncludePatterns: []string{"*USDT"}, ExcludePatterns: []string{"*UP*"}}).Validate(); err != nil {
+		t.Errorf("unexpected error: %v", err)
+	}
+	if err := (SymbolFilter{ExcludePatterns: []string{"[BTC"}}).Validate(); err == nil {
+		t.Error("expected error for malformed pattern")
+	}
+}
@@ -0,0 +1,40 @@
+package types
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestSymbolToPair(t *testing.T) {
+	tests := []struct {
+		symbol Symbol
+		base   string
+		quote  string
+	}{
+		{"BTCUSDT", "BTC", "USDT"},
+		{"btc/usdt", "BTC", "USDT"},
+		{"BTC-USD", "BTC", "USD"},
+		{"DOGEUSDT", "DOGE", "USDT"},
+		{"ETHBTC", "ETH", "BTC"},
+		{"BTCFDUSD", "BTC", "FDUSD"},
+	}
+	for _, tt := range tests {
+		pair, err := tt.symbol.ToPair()
+		if err != nil {
+			t.Errorf("%s: unexpected error: %v", tt.symbol, err)
+			continue
+		}
+		if pair.Base.Upper().String() != tt.base || pair.Quote.Upper().String() != tt.quote {
+			t.Errorf("%s: got %s/%s, want %s/%s", tt.symbol, pair.Base, pair.Quote, tt.base, tt.quote)
+		}
+		if got := PairToSymbol(pair); got != tt.symbol.Normalize() {
+			t.Errorf("%s: PairToSymbol = %s, Normalize = %s", tt.symbol, got, tt.symbol.Normalize())
+		}
+	}
+
+	for _, invalid := range []Symbol{"", "BT", "/USDT", "BTC/"} {
+		if _, err := invalid.ToPair(); !errors.Is(err, ErrInvalidSymbol) {
+			t.Errorf("%q: expected ErrInvalidSymbol, got %v", invalid, err)
+		}
+	}
+}
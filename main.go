@@ -2,6 +2,7 @@ package main
 
 import (
 	"context"
+	"errors"
 	"flag"
 	"fmt"
 	"os"
@@ -22,6 +23,9 @@ var (
 	configPath = flag.String("config", "./config/config.yaml", "配置文件路径")
 	version    = flag.Bool("version", false, "显示版本信息")
 	help       = flag.Bool("help", false, "显示帮助信息")
+
+	selfTest        = flag.Bool("selftest", false, "对每个启用的交易所和数据类型发起一次请求/订阅，输出结果后退出")
+	selfTestTimeout = flag.Duration("selftest-timeout", app.DefaultSelfTestTimeout, "自检每项检查的超时时间")
 )
 
 func main() {
@@ -62,6 +66,12 @@ func main() {
 		logger.Fatal("data-miner service系统初始化失败", zap.Error(err))
 	}
 
+	if *selfTest {
+		code := runSelfTest(logger, config, components)
+		logger.Sync()
+		os.Exit(code)
+	}
+
 	logger.Info("系统初始化完成，开始启动应用程序...")
 
 	// 启动应用程序
@@ -70,10 +80,39 @@ func main() {
 	}
 }
 
+// selfTestCloseTimeout 自检结束后关闭交易所连接的时间预算
+const selfTestCloseTimeout = 5 * time.Second
+
+// runSelfTest 执行自检并输出报告，关闭交易所连接后返回进程退出码：全部通过为0，否则为1
+func runSelfTest(logger *zap.Logger, config *types.Config, components *app.SystemComponents) int {
+	logger.Info("开始自检...", zap.Duration("timeout", *selfTestTimeout))
+
+	tester := app.NewSelfTester(logger, config, components.Exchanges, *selfTestTimeout)
+	results := tester.Run(context.Background())
+	failed := app.PrintSelfTestReport(os.Stdout, results)
+
+	ctx, cancel := context.WithTimeout(context.Background(), selfTestCloseTimeout)
+	defer cancel()
+	if err := components.CloseExchanges(ctx); err != nil {
+		logger.Warn("关闭交易所失败", zap.Error(err))
+	}
+
+	if len(results) == 0 {
+		fmt.Println("没有可自检的交易所或数据类型，请检查配置")
+		return 1
+	}
+	if failed > 0 {
+		return 1
+	}
+	return 0
+}
+
 // initLogger 初始化日志配置
 func initLogger(level string) (*zap.Logger, error) {
 	var zapLevel zapcore.Level
 	switch level {
+	case "trace":
+		zapLevel = scheduler.TraceLevel
 	case "debug":
 		zapLevel = zapcore.DebugLevel
 	case "info":
@@ -93,6 +132,13 @@ func initLogger(level string) (*zap.Logger, error) {
 	config.Encoding = "console"
 	config.EncoderConfig.TimeKey = "time"
 	config.EncoderConfig.EncodeTime = zapcore.ISO8601TimeEncoder
+	config.EncoderConfig.EncodeLevel = func(l zapcore.Level, enc zapcore.PrimitiveArrayEncoder) {
+		if l == scheduler.TraceLevel {
+			enc.AppendString("trace")
+			return
+		}
+		zapcore.LowercaseLevelEncoder(l, enc)
+	}
 	return config.Build()
 }
 
@@ -104,12 +150,23 @@ func startApplication(logger *zap.Logger, config *types.Config,
 
 	// 初始化各个管理器
 	schedulerManager := app.NewSchedulerManager(logger)
-	serviceManager := app.NewServiceManager(logger)
+	serviceManager := app.NewServiceManager(logger, components)
 	websocketManager := app.NewWebsocketManager(logger)
 
-	logger.Info("管理器初始化完成，开始启动WebSocket...")
+	logger.Info("管理器初始化完成，开始启动服务...")
+
+	// 启动服务（存储、监控和即时查询API），存储需要在采集数据前就绪
+	if err := serviceManager.Start(config); err != nil {
+		return fmt.Errorf("启动服务失败: %w", err)
+	}
+
+	logger.Info("服务启动完成，开始启动WebSocket...")
 
-	// 启动WebSocket连接（如果启用）
+	// 启动WebSocket连接（如果启用）。hybrid模式下推送数据与调度器共用存储输出，写入前去重；
+	// websocket模式下推送数据仍只记录日志
+	if config.Exchanges.Binance.CollectionMode() == types.CollectionModeHybrid {
+		websocketManager.SetSink(serviceManager.Sink())
+	}
 	if err := websocketManager.Start(config, components.Exchanges); err != nil {
 		logger.Error("启动WebSocket失败", zap.Error(err))
 	}
@@ -117,59 +174,81 @@ func startApplication(logger *zap.Logger, config *types.Config,
 	logger.Info("WebSocket启动完成，开始设置调度器...")
 
 	// 设置调度器
+	schedulerManager.SetSink(serviceManager.Sink())
 	sched, err := schedulerManager.Setup(config, components.Exchanges)
 	if err != nil {
+		serviceManager.Stop(context.Background())
 		return fmt.Errorf("设置调度器失败: %w", err)
 	}
-
-	logger.Info("调度器设置完成，开始启动服务...")
-
-	// 启动服务
-	if err := serviceManager.Start(config); err != nil {
-		return fmt.Errorf("启动服务失败: %w", err)
-	}
+	serviceManager.SetScheduler(sched)
 
 	logger.Info("所有服务启动完成，进入等待状态...")
 
 	// 等待关闭信号并优雅关闭
-	waitForShutdown(logger, sched, components)
+	waitForShutdown(logger, schedulerManager, websocketManager, serviceManager, components)
 	return nil
 }
 
-// waitForShutdown 等待关闭信号并优雅关闭
-func waitForShutdown(logger *zap.Logger, sched *scheduler.Scheduler,
-	components *app.SystemComponents) {
+// waitForShutdown 等待关闭信号并优雅关闭，收到SIGHUP时重新加载配置并重建调度任务
+func waitForShutdown(logger *zap.Logger, schedulerManager *app.SchedulerManager, websocketManager *app.WebsocketManager,
+	serviceManager *app.ServiceManager, components *app.SystemComponents) {
 
 	sigChan := make(chan os.Signal, 1)
-	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM)
+	signal.Notify(sigChan, syscall.SIGINT, syscall.SIGTERM, syscall.SIGHUP)
 
 	logger.Info("数据采集器启动完成，等待退出信号...")
-	<-sigChan
+	for sig := range sigChan {
+		if sig == syscall.SIGHUP {
+			reloadScheduler(logger, schedulerManager, serviceManager)
+			continue
+		}
+		break
+	}
 	logger.Info("收到退出信号，正在优雅关闭...")
 
-	gracefulShutdown(logger, sched, components)
+	gracefulShutdown(logger, schedulerManager, websocketManager, serviceManager, components)
 	logger.Info("程序已退出")
 }
 
-// gracefulShutdown 执行优雅关闭逻辑
-func gracefulShutdown(logger *zap.Logger, sched *scheduler.Scheduler,
-	components *app.SystemComponents) {
+// reloadScheduler 重新加载配置文件并按新的调度配置重建调度器，只有调度器配置会生效，
+// 加载或重建失败时保持原调度器继续运行
+func reloadScheduler(logger *zap.Logger, schedulerManager *app.SchedulerManager, serviceManager *app.ServiceManager) {
+	logger.Info("收到SIGHUP，重新加载调度配置...", zap.String("config", *configPath))
+
+	config, err := utils.LoadConfig(*configPath)
+	if err != nil {
+		logger.Error("重新加载配置失败，保持当前调度器", zap.Error(err))
+		return
+	}
 
 	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
 	defer cancel()
 
-	// 停止调度器
-	if sched != nil {
-		if err := sched.Stop(ctx); err != nil {
-			logger.Error("停止调度器失败", zap.Error(err))
-		} else {
-			logger.Info("调度器已停止")
-		}
+	sched, err := schedulerManager.Reload(ctx, config)
+	serviceManager.SetScheduler(sched)
+	if err != nil {
+		logger.Error("重载调度器失败", zap.Error(err))
+		return
 	}
+	logger.Info("调度配置重新加载完成")
+}
 
-	// 关闭系统组件
-	if err := components.Shutdown(); err != nil {
-		logger.Error("关闭系统组件失败", zap.Error(err))
+// gracefulShutdown 执行优雅关闭逻辑：按停止数据输入 → 停止调度器 → 写出存储缓冲 → 关闭存储 → 关闭交易所的顺序关闭，
+// 每一步有独立的时间预算，某一步超时不会占用后续步骤（尤其是存储落盘）的时间
+func gracefulShutdown(logger *zap.Logger, schedulerManager *app.SchedulerManager, websocketManager *app.WebsocketManager,
+	serviceManager *app.ServiceManager, components *app.SystemComponents) {
+
+	hooks := app.ShutdownHooks{
+		StopIntake: func(ctx context.Context) error {
+			return errors.Join(websocketManager.Stop(ctx), serviceManager.StopMonitoring(ctx))
+		},
+		StopScheduler:  schedulerManager.Stop,
+		FlushStorage:   serviceManager.FlushStorage,
+		CloseStorage:   serviceManager.CloseStorage,
+		CloseExchanges: components.CloseExchanges,
+	}
+	if err := app.RunShutdown(logger, hooks.Steps()); err != nil {
+		logger.Error("优雅关闭未完全完成", zap.Error(err))
 	}
 }
 
@@ -199,6 +278,10 @@ func showHelp() {
 	fmt.Println("选项:")
 	fmt.Println("  -config string")
 	fmt.Println("        配置文件路径 (默认 \"./config.yaml\")")
+	fmt.Println("  -selftest")
+	fmt.Println("        对每个启用的交易所和数据类型发起一次请求/订阅，输出结果后退出（全部通过时退出码为0）")
+	fmt.Println("  -selftest-timeout duration")
+	fmt.Println("        自检每项检查的超时时间 (默认 10s)")
 	fmt.Println("  -version")
 	fmt.Println("        显示版本信息")
 	fmt.Println("  -help")
@@ -4,6 +4,8 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"slices"
+	"time"
 
 	"github.com/mooyang-code/data-miner/internal/types"
 	"gopkg.in/yaml.v3"
@@ -56,18 +58,55 @@ func validateConfig(config *types.Config) error {
 		if config.Exchanges.Binance.WebsocketURL == "" {
 			return fmt.Errorf("Binance WebSocket URL不能为空")
 		}
+
+		dataTypes := config.Exchanges.Binance.DataTypes
+		filters := map[string]types.SymbolFilter{
+			"ticker":    dataTypes.Ticker.SymbolFilter,
+			"orderbook": dataTypes.Orderbook.SymbolFilter,
+			"trades":    dataTypes.Trades.SymbolFilter,
+			"klines":    dataTypes.Klines.SymbolFilter,
+			"avg_price": dataTypes.AvgPrice.SymbolFilter,
+			"stats_24h": dataTypes.Stats24h.SymbolFilter,
+		}
+		for name, filter := range filters {
+			if err := filter.Validate(); err != nil {
+				return fmt.Errorf("Binance %s交易对过滤配置无效: %w", name, err)
+			}
+		}
+		if err := dataTypes.Orderbook.ValidateStream(); err != nil {
+			return fmt.Errorf("Binance订单簿深度流配置无效: %w", err)
+		}
+		if err := dataTypes.Klines.ValidateIntervals(); err != nil {
+			return fmt.Errorf("Binance K线周期配置无效: %w", err)
+		}
+		if err := config.Exchanges.Binance.ValidateMode(); err != nil {
+			return fmt.Errorf("Binance采集模式配置无效: %w", err)
+		}
+		if config.Exchanges.Binance.CollectionMode() == types.CollectionModeHybrid && !config.Scheduler.Enabled {
+			return fmt.Errorf("Binance hybrid采集模式需要启用调度器（REST拉取作为备用来源）")
+		}
 	}
 
 	// 验证存储配置
 	if config.Storage.File.Enabled && config.Storage.File.BasePath == "" {
 		return fmt.Errorf("文件存储路径不能为空")
 	}
+	if config.Storage.S3.Enabled && config.Storage.S3.Bucket == "" {
+		return fmt.Errorf("S3存储桶不能为空")
+	}
 
 	// 验证调度器配置
 	if config.Scheduler.Enabled {
 		if config.Scheduler.MaxConcurrentJobs <= 0 {
 			return fmt.Errorf("最大并发任务数必须大于0")
 		}
+		timeouts := config.Scheduler.Timeouts
+		for _, timeout := range []time.Duration{timeouts.Ticker, timeouts.Stats24h, timeouts.AvgPrice,
+			timeouts.Orderbook, timeouts.Trades, timeouts.Klines, timeouts.KlinesPerSeries, timeouts.Default} {
+			if timeout < 0 {
+				return fmt.Errorf("任务超时时间不能为负数")
+			}
+		}
 
 		// 验证任务配置
 		for i, job := range config.Scheduler.Jobs {
@@ -80,6 +119,9 @@ func validateConfig(config *types.Config) error {
 			if job.DataType == "" {
 				return fmt.Errorf("第%d个任务的数据类型不能为空", i+1)
 			}
+			if !slices.Contains(types.ScheduledDataTypes, types.DataType(job.DataType)) {
+				return fmt.Errorf("第%d个任务的数据类型%q不支持，支持的类型: %v", i+1, job.DataType, types.ScheduledDataTypes)
+			}
 			if job.Cron == "" {
 				return fmt.Errorf("第%d个任务的Cron表达式不能为空", i+1)
 			}